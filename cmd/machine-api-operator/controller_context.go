@@ -13,8 +13,15 @@ import (
 // ControllerContext stores all the informers for a variety of kubernetes objects.
 type ControllerContext struct {
 	ClientBuilder *ClientBuilder
+	// GuestClientBuilder builds the clients of the cluster whose Machine API is managed,
+	// which differs from the ClientBuilder in the hosted control plane mode.
+	GuestClientBuilder *ClientBuilder
+
+	// MachineNamespace is the namespace of the Machine API resources in the guest cluster.
+	MachineNamespace string
 
 	KubeNamespacedInformerFactory informers.SharedInformerFactory
+	GuestKubeInformerFactory      informers.SharedInformerFactory
 	ConfigInformerFactory         configinformersv1.SharedInformerFactory
 	MachineInformerFactory        machineinformersv1beta1.SharedInformerFactory
 
@@ -27,19 +34,27 @@ type ControllerContext struct {
 	ResyncPeriod func() time.Duration
 }
 
-// CreateControllerContext creates the ControllerContext with the ClientBuilder.
-func CreateControllerContext(cb *ClientBuilder, stop <-chan struct{}, targetNamespace string) *ControllerContext {
+// CreateControllerContext creates the ControllerContext with the ClientBuilders. The operands are deployed in the
+// target namespace with the ClientBuilder, the Machine API of the guest cluster is managed with the guestCB.
+func CreateControllerContext(cb, guestCB *ClientBuilder, stop <-chan struct{}, targetNamespace, machineNamespace string) *ControllerContext {
 	kubeClient := cb.KubeClientOrDie("kube-shared-informer")
-	configClient := cb.OpenshiftClientOrDie("config-shared-informer")
-	machineClient := cb.MachineClientOrDie("machine-shared-informer")
+	configClient := guestCB.OpenshiftClientOrDie("config-shared-informer")
+	machineClient := guestCB.MachineClientOrDie("machine-shared-informer")
 
 	kubeNamespacedSharedInformer := informers.NewSharedInformerFactoryWithOptions(kubeClient, resyncPeriod()(), informers.WithNamespace(targetNamespace))
+	guestKubeSharedInformer := kubeNamespacedSharedInformer
+	if guestCB != cb {
+		guestKubeSharedInformer = informers.NewSharedInformerFactoryWithOptions(guestCB.KubeClientOrDie("guest-kube-shared-informer"), resyncPeriod()())
+	}
 	configSharedInformer := configinformersv1.NewSharedInformerFactoryWithOptions(configClient, resyncPeriod()())
-	machineSharedInformer := machineinformersv1beta1.NewSharedInformerFactoryWithOptions(machineClient, resyncPeriod()(), machineinformersv1beta1.WithNamespace(targetNamespace))
+	machineSharedInformer := machineinformersv1beta1.NewSharedInformerFactoryWithOptions(machineClient, resyncPeriod()(), machineinformersv1beta1.WithNamespace(machineNamespace))
 
 	return &ControllerContext{
 		ClientBuilder:                 cb,
+		GuestClientBuilder:            guestCB,
+		MachineNamespace:              machineNamespace,
 		KubeNamespacedInformerFactory: kubeNamespacedSharedInformer,
+		GuestKubeInformerFactory:      guestKubeSharedInformer,
 		ConfigInformerFactory:         configSharedInformer,
 		MachineInformerFactory:        machineSharedInformer,
 
@@ -13,6 +13,8 @@ import (
 	"github.com/openshift/machine-api-operator/pkg/metrics"
 	"github.com/openshift/machine-api-operator/pkg/operator"
 	"github.com/openshift/machine-api-operator/pkg/util"
+	"github.com/openshift/machine-api-operator/pkg/util/logging"
+	"github.com/openshift/machine-api-operator/pkg/util/profiling"
 	"github.com/openshift/machine-api-operator/pkg/version"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -20,6 +22,7 @@ import (
 	"github.com/spf13/pflag"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	coreclientsetv1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
@@ -47,16 +50,27 @@ var (
 	}
 
 	startOpts struct {
-		kubeconfig string
-		imagesFile string
+		kubeconfig      string
+		imagesFile      string
+		guestKubeconfig string
+		guestNamespace  string
 	}
+
+	secureMetrics    = &metrics.SecureServingOptions{}
+	loggingOptions   = &logging.Options{}
+	profilingOptions = &profiling.Options{}
 )
 
 func init() {
 	rootCmd.AddCommand(startCmd)
 	startCmd.PersistentFlags().StringVar(&startOpts.kubeconfig, "kubeconfig", "", "Kubeconfig file to access a remote cluster (testing only)")
 	startCmd.PersistentFlags().StringVar(&startOpts.imagesFile, "images-json", "", "images.json file for MAO.")
+	startCmd.PersistentFlags().StringVar(&startOpts.guestKubeconfig, "guest-kubeconfig", "", "Kubeconfig file of the guest cluster of a hosted control plane. When set, MAO and its operands run on the management cluster and manage the Machine API of the guest cluster.")
+	startCmd.PersistentFlags().StringVar(&startOpts.guestNamespace, "guest-namespace", "openshift-machine-api", "Namespace of the Machine API resources in the guest cluster, only used with --guest-kubeconfig.")
 
+	secureMetrics.AddFlags(flag.CommandLine)
+	loggingOptions.AddFlags(flag.CommandLine)
+	profilingOptions.AddFlags(flag.CommandLine)
 	klog.InitFlags(nil)
 	flag.Parse()
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
@@ -66,10 +80,22 @@ func runStartCmd(cmd *cobra.Command, args []string) error {
 	if err := flag.Set("logtostderr", "true"); err != nil {
 		return fmt.Errorf("failed to set logtostderr flag: %v", err)
 	}
+	if err := loggingOptions.Setup(); err != nil {
+		return err
+	}
 
 	// To help debugging, immediately log version
 	klog.Infof("Version: %+v", version.Version)
 
+	if profilingOptions.Enabled() {
+		// The profiles are served by all the replicas, not only by the leader.
+		go func() {
+			if err := profilingOptions.NewServer().Start(context.Background()); err != nil {
+				klog.Fatalf("Error serving profiles: %v", err)
+			}
+		}()
+	}
+
 	if startOpts.imagesFile == "" {
 		return errImagesJsonEmpty
 	}
@@ -78,8 +104,18 @@ func runStartCmd(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("error creating clients: %v", err)
 	}
+	// The guest cluster is the cluster itself, unless the operator runs in a hosted control plane.
+	guestCB, machineNamespace := cb, componentNamespace
+	if startOpts.guestKubeconfig != "" {
+		if guestCB, err = NewClientBuilder(startOpts.guestKubeconfig); err != nil {
+			return fmt.Errorf("error creating guest cluster clients: %v", err)
+		}
+		machineNamespace = startOpts.guestNamespace
+		klog.Infof("Running in the hosted control plane mode, managing namespace %q of the guest cluster", machineNamespace)
+	}
 	stopCh := make(chan struct{})
 
+	// The leader election lock is held in the cluster the operator runs in.
 	le := util.GetLeaderElectionConfig(cb.config, osconfigv1.LeaderElection{})
 
 	leaderelection.RunOrDie(context.TODO(), leaderelection.LeaderElectionConfig{
@@ -89,9 +125,10 @@ func runStartCmd(cmd *cobra.Command, args []string) error {
 		LeaseDuration: le.LeaseDuration.Duration,
 		Callbacks: leaderelection.LeaderCallbacks{
 			OnStartedLeading: func(ctx context.Context) {
-				ctrlCtx := CreateControllerContext(cb, stopCh, componentNamespace)
+				ctrlCtx := CreateControllerContext(cb, guestCB, stopCh, componentNamespace, machineNamespace)
 				startControllersOrDie(ctrlCtx)
 				ctrlCtx.KubeNamespacedInformerFactory.Start(ctrlCtx.Stop)
+				ctrlCtx.GuestKubeInformerFactory.Start(ctrlCtx.Stop)
 				ctrlCtx.ConfigInformerFactory.Start(ctrlCtx.Stop)
 				initMachineAPIInformers(ctrlCtx)
 				startMetricsCollectionAndServer(ctrlCtx)
@@ -144,18 +181,25 @@ func startControllersOrDie(ctx *ControllerContext) {
 		ctx.KubeNamespacedInformerFactory.Apps().V1().Deployments(),
 		ctx.KubeNamespacedInformerFactory.Apps().V1().DaemonSets(),
 		ctx.ConfigInformerFactory.Config().V1().FeatureGates(),
-		ctx.KubeNamespacedInformerFactory.Admissionregistration().V1().ValidatingWebhookConfigurations(),
-		ctx.KubeNamespacedInformerFactory.Admissionregistration().V1().MutatingWebhookConfigurations(),
+		ctx.GuestKubeInformerFactory.Admissionregistration().V1().ValidatingWebhookConfigurations(),
+		ctx.GuestKubeInformerFactory.Admissionregistration().V1().MutatingWebhookConfigurations(),
 		ctx.ConfigInformerFactory.Config().V1().Proxies(),
+		ctx.KubeNamespacedInformerFactory.Core().V1().ConfigMaps(),
 		ctx.ClientBuilder.KubeClientOrDie(componentName),
-		ctx.ClientBuilder.OpenshiftClientOrDie(componentName),
-		ctx.ClientBuilder.MachineClientOrDie(componentName),
+		ctx.GuestClientBuilder.OpenshiftClientOrDie(componentName),
+		ctx.GuestClientBuilder.MachineClientOrDie(componentName),
 		ctx.ClientBuilder.DynamicClientOrDie(componentName),
 		recorder,
 	)
 	if err != nil {
 		panic(fmt.Errorf("error creating operator: %v", err))
 	}
+	if ctx.GuestClientBuilder != ctx.ClientBuilder {
+		optr.EnableHostedControlPlane(operator.HostedControlPlane{
+			GuestNamespace:  ctx.MachineNamespace,
+			GuestKubeClient: ctx.GuestClientBuilder.KubeClientOrDie(componentName),
+		})
+	}
 
 	go optr.Run(1, ctx.Stop)
 }
@@ -166,8 +210,15 @@ func startMetricsCollectionAndServer(ctx *ControllerContext) {
 	machineMetricsCollector := metrics.NewMachineCollector(
 		machineInformer,
 		machinesetInformer,
-		componentNamespace)
+		ctx.MachineNamespace)
 	prometheus.MustRegister(machineMetricsCollector)
+	// The price table is optional, the cost of the MachineSets is only estimated when it exists.
+	costCollector := metrics.NewMachineSetCostCollector(
+		machinesetInformer,
+		ctx.KubeNamespacedInformerFactory.Core().V1().ConfigMaps(),
+		ctx.MachineNamespace,
+		componentNamespace)
+	prometheus.MustRegister(costCollector)
 	metricsPort := defaultMetricsPort
 	if port, ok := os.LookupEnv("METRICS_PORT"); ok {
 		v, err := strconv.Atoi(port)
@@ -176,6 +227,21 @@ func startMetricsCollectionAndServer(ctx *ControllerContext) {
 		}
 		metricsPort = v
 	}
+	if secureMetrics.Enabled() {
+		// The metrics are served to the clients authenticated and authorized by the apiserver, without a kube-rbac-proxy sidecar.
+		server, err := secureMetrics.NewServer(ctx.ClientBuilder.config, fmt.Sprintf(":%d", metricsPort), prometheus.DefaultGatherer)
+		if err != nil {
+			klog.Fatalf("Error creating metrics server: %v", err)
+		}
+		serverCtx, cancel := wait.ContextForChannel(ctx.Stop)
+		go func() {
+			defer cancel()
+			if err := server.Start(serverCtx); err != nil {
+				klog.Fatalf("Error serving metrics over TLS: %v", err)
+			}
+		}()
+		return
+	}
 	klog.V(4).Info("Starting server to serve prometheus metrics")
 	go startHTTPMetricServer(fmt.Sprintf("localhost:%d", metricsPort))
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"runtime"
@@ -8,6 +9,9 @@ import (
 	"github.com/openshift/machine-api-operator/pkg/controller/machinehealthcheck"
 	"github.com/openshift/machine-api-operator/pkg/metrics"
 	"github.com/openshift/machine-api-operator/pkg/util"
+	"github.com/openshift/machine-api-operator/pkg/util/logging"
+	"github.com/openshift/machine-api-operator/pkg/util/profiling"
+	"github.com/openshift/machine-api-operator/pkg/util/tracing"
 
 	osconfigv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
@@ -40,7 +44,7 @@ func main() {
 	watchNamespace := flag.String(
 		"namespace",
 		"",
-		"Namespace that the controller watches to reconcile machine-api objects. If unspecified, the controller watches for machine-api objects across all namespaces.",
+		"Comma separated namespaces that the controller watches to reconcile machine-api objects. If unspecified, the controller watches for machine-api objects across all namespaces.",
 	)
 
 	metricsAddress := flag.String(
@@ -74,8 +78,32 @@ func main() {
 		fmt.Sprintf("The duration that non-leader candidates will wait after observing a leadership renewal until attempting to acquire leadership of a led but unrenewed leader slot. This is effectively the maximum duration that a leader can be stopped before it is replaced by another candidate. This is only applicable if leader election is enabled. Default: (%s)", defaultLeaderElectionValues.LeaseDuration.Duration),
 	)
 
+	controllerEnabled := flag.Bool(
+		"controller-enabled",
+		true,
+		"Run the MachineHealthCheck controller. When disabled, Machines are not remediated and only the metrics and health endpoints are served.",
+	)
+
 	klog.InitFlags(nil)
+	secureMetrics := &metrics.SecureServingOptions{}
+	secureMetrics.AddFlags(flag.CommandLine)
+	loggingOptions := &logging.Options{}
+	loggingOptions.AddFlags(flag.CommandLine)
+	tracingOptions := &tracing.Options{}
+	tracingOptions.AddFlags(flag.CommandLine)
+	profilingOptions := &profiling.Options{}
+	profilingOptions.AddFlags(flag.CommandLine)
+	cacheOptions := &util.CacheOptions{}
+	cacheOptions.AddFlags(flag.CommandLine)
+	rateLimiterOptions := &util.RateLimiterOptions{}
+	rateLimiterOptions.AddFlags(flag.CommandLine)
+	leaderElectionPreference := &util.LeaderElectionPreference{}
+	leaderElectionPreference.AddFlags(flag.CommandLine)
+
 	flag.Parse()
+	if err := loggingOptions.Setup(); err != nil {
+		klog.Fatal(err)
+	}
 	printVersion()
 
 	// Get a config to talk to the apiserver
@@ -83,6 +111,9 @@ func main() {
 	if err != nil {
 		klog.Fatal(err)
 	}
+	if err := tracingOptions.Setup("machine-healthcheck-controller", cfg); err != nil {
+		klog.Fatal(err)
+	}
 
 	le := util.GetLeaderElectionConfig(cfg, osconfigv1.LeaderElection{
 		Disable:       !*leaderElect,
@@ -90,7 +121,7 @@ func main() {
 	})
 
 	opts := manager.Options{
-		MetricsBindAddress:      *metricsAddress,
+		MetricsBindAddress:      secureMetrics.ManagerBindAddress(*metricsAddress),
 		HealthProbeBindAddress:  *healthAddr,
 		LeaderElection:          *leaderElect,
 		LeaderElectionNamespace: *leaderElectResourceNamespace,
@@ -100,16 +131,42 @@ func main() {
 		RenewDeadline:           &le.RenewDeadline.Duration,
 	}
 
-	if *watchNamespace != "" {
-		opts.Namespace = *watchNamespace
-		klog.Infof("Watching machine-api objects only in namespace %q for reconciliation.", opts.Namespace)
+	watchNamespaces := util.ParseNamespaces(*watchNamespace)
+	cacheOptions.Namespaces = watchNamespaces
+	if len(watchNamespaces) > 0 {
+		klog.Infof("Watching machine-api objects only in namespaces %q for reconciliation.", watchNamespaces)
+	}
+	if err := util.ConfigureCache(&opts, *cacheOptions); err != nil {
+		klog.Fatal(err)
+	}
+	if *leaderElect && leaderElectionPreference.Enabled() {
+		lock, err := leaderElectionPreference.NewResourceLock(cfg, opts)
+		if err != nil {
+			klog.Fatal(err)
+		}
+		opts.LeaderElectionResourceLockInterface = lock
+	}
+
+	if err := rateLimiterOptions.Setup(context.Background(), cfg, cacheOptions.Namespaces); err != nil {
+		klog.Fatal(err)
 	}
+
 	// Create a new Cmd to provide shared dependencies and start components
 	mgr, err := manager.New(cfg, opts)
 	if err != nil {
 		klog.Fatal(err)
 	}
 
+	if err := secureMetrics.AddToManager(mgr, *metricsAddress); err != nil {
+		klog.Fatal(err)
+	}
+	if err := tracingOptions.AddToManager(mgr); err != nil {
+		klog.Fatal(err)
+	}
+	if err := profilingOptions.AddToManager(mgr); err != nil {
+		klog.Fatal(err)
+	}
+
 	klog.Infof("Registering Components.")
 
 	// Setup Scheme for all resources
@@ -118,8 +175,14 @@ func main() {
 	}
 
 	// Setup all Controllers
-	if err := controller.AddToManager(mgr, opts, machinehealthcheck.Add); err != nil {
-		klog.Fatal(err)
+	if *controllerEnabled {
+		if err := controller.AddToManager(mgr, opts, machinehealthcheck.AddWithOptions(machinehealthcheck.Options{
+			RateLimiter: rateLimiterOptions.NewRateLimiter(),
+		})); err != nil {
+			klog.Fatal(err)
+		}
+	} else {
+		klog.Info("MachineHealthCheck controller is disabled")
 	}
 
 	if err := mgr.AddReadyzCheck("ping", healthz.Ping); err != nil {
@@ -17,14 +17,17 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/klog/v2"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -34,11 +37,18 @@ import (
 	osconfigv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	"github.com/openshift/library-go/pkg/config/leaderelection"
+	bulkv1beta1 "github.com/openshift/machine-api-operator/pkg/apis/bulkoperation/v1beta1"
 	"github.com/openshift/machine-api-operator/pkg/controller"
+	"github.com/openshift/machine-api-operator/pkg/controller/bulkoperation"
 	"github.com/openshift/machine-api-operator/pkg/controller/machineset"
+	mscontrollerconfig "github.com/openshift/machine-api-operator/pkg/controller/machineset/config"
+	"github.com/openshift/machine-api-operator/pkg/controller/migration"
 	"github.com/openshift/machine-api-operator/pkg/metrics"
 	"github.com/openshift/machine-api-operator/pkg/operator"
 	"github.com/openshift/machine-api-operator/pkg/util"
+	"github.com/openshift/machine-api-operator/pkg/util/logging"
+	"github.com/openshift/machine-api-operator/pkg/util/profiling"
+	"github.com/openshift/machine-api-operator/pkg/util/tracing"
 	mapiwebhooks "github.com/openshift/machine-api-operator/pkg/webhooks"
 )
 
@@ -58,8 +68,10 @@ func main() {
 	if err := flag.Set("logtostderr", "true"); err != nil {
 		klog.Fatalf("failed to set logtostderr flag: %v", err)
 	}
+	configFile := flag.String("config", "",
+		"Path of a "+mscontrollerconfig.Kind+" file setting the flags which are not set on the command line. The changes of its logging verbosity are applied while running.")
 	watchNamespace := flag.String("namespace", "",
-		"Namespace that the controller watches to reconcile cluster-api objects. If unspecified, the controller watches for cluster-api objects across all namespaces.")
+		"Comma separated namespaces that the controller watches to reconcile cluster-api objects. If unspecified, the controller watches for cluster-api objects across all namespaces.")
 	metricsAddress := flag.String("metrics-bind-address", metrics.DefaultMachineSetMetricsAddress, "Address for hosting metrics")
 
 	webhookEnabled := flag.Bool("webhook-enabled", true,
@@ -71,6 +83,42 @@ func main() {
 	webhookCertdir := flag.String("webhook-cert-dir", defaultWebhookCertdir,
 		"Webhook cert dir, only used when webhook-enabled is true.")
 
+	webhookSelfSigned := flag.Bool("webhook-self-signed", false,
+		"Generate and rotate a self-signed serving certificate for the webhook server and inject its CA into the webhook configurations, for clusters without the service-ca operator. Only used when webhook-enabled is true.")
+
+	webhookRejectMissingSecrets := flag.Bool("webhook-reject-missing-secrets", false,
+		"Reject Machines and MachineSets referencing a user data or credentials secret which does not exist, instead of warning about them. Only used when webhook-enabled is true.")
+
+	awsMetadataServiceAuthentication := flag.String("aws-default-metadata-service-authentication", "",
+		"Metadata service authentication set in AWS providerSpecs which do not set it, either Required to enforce IMDSv2 or Optional. Left to the platform when empty. Only used when webhook-enabled is true.")
+
+	vsphereDeepValidation := flag.Bool("vsphere-deep-validation", false,
+		"Validate vSphere providerSpecs against vCenter, rejecting the ones referencing a template, folder, datastore, resource pool or network which does not exist. Only used when webhook-enabled is true.")
+
+	vsphereDeepValidationTimeout := flag.Duration("vsphere-deep-validation-timeout", 5*time.Second,
+		"Timeout for resolving the objects of a vSphere providerSpec in vCenter during admission, only used when vsphere-deep-validation is true.")
+
+	minControlPlaneMachines := flag.Int("min-control-plane-machines", 1,
+		"Minimum number of running control plane Machines which must remain after deleting a control plane Machine. Only used when webhook-enabled is true.")
+
+	deletionProtectionAllowedUsers := flag.String("deletion-protection-allowed-users", strings.Join(mapiwebhooks.DefaultDeletionProtectionAllowedUsers, ","),
+		"Comma-separated list of the users allowed to delete the Machines protected from deletion by the machine.openshift.io/deletion-protected annotation, e.g. the service accounts of the controllers scaling down MachineSets. Only used when webhook-enabled is true.")
+
+	webhookDryRunEstimates := flag.Bool("webhook-dry-run-estimates", false,
+		"Estimate whether the cloud has the capacity for the Machines created with a server side dry run, returning warnings for the resources which may be exhausted. Only supported on vSphere. Only used when webhook-enabled is true.")
+
+	webhookAllowHostAffinity := flag.Bool("webhook-allow-host-affinity", false,
+		"Admit the annotations pinning Machines to dedicated hosts or host groups, for machine controllers which place the instances on them. They are rejected otherwise. Only used when webhook-enabled is true.")
+
+	controllerEnabled := flag.Bool("controller-enabled", true,
+		"Run the MachineSet controller. When disabled, MachineSets are not reconciled and only the webhook, metrics and health endpoints are served.")
+
+	capiSync := flag.Bool("capi-sync", false,
+		"Mirror the MachineSets and Machines into Cluster API MachineDeployments and Machines, and hand them over to Cluster API following their authoritative-api annotation. Requires namespace to be set.")
+
+	capiNamespace := flag.String("capi-namespace", migration.DefaultClusterAPINamespace,
+		"Namespace of the Cluster API resources mirroring the MachineSets and Machines, only used when capi-sync is true.")
+
 	healthAddr := flag.String(
 		"health-addr",
 		":9441",
@@ -96,9 +144,93 @@ func main() {
 		fmt.Sprintf("The duration that non-leader candidates will wait after observing a leadership renewal until attempting to acquire leadership of a led but unrenewed leader slot. This is effectively the maximum duration that a leader can be stopped before it is replaced by another candidate. This is only applicable if leader election is enabled. Default: (%s)", defaultLeaderElectionValues.LeaseDuration.Duration),
 	)
 
+	machineSetConcurrency := flag.Int(
+		"machineset-concurrency",
+		1,
+		"The number of MachineSets that are allowed to reconcile concurrently.",
+	)
+
+	machineCreationQPS := flag.Float64(
+		"machine-creation-qps",
+		0,
+		"The number of machines that may be created per second across all MachineSets. Zero does not limit the creation rate.",
+	)
+
+	machineCreationBurst := flag.Int(
+		"machine-creation-burst",
+		10,
+		"The number of machines that may be created at once across all MachineSets, only used when machine-creation-qps is set.",
+	)
+
+	missingInstanceGracePeriod := flag.Duration(
+		"missing-instance-grace-period",
+		10*time.Minute,
+		"How long a machine whose instance disappeared remains Failed before its MachineSet deletes and replaces it. Zero does not replace such machines.",
+	)
+
+	syncPeriod := flag.Duration(
+		"sync-period",
+		10*time.Minute,
+		"The period of the full resyncs of the cache, which reconcile every object of the controllers relying on them.",
+	)
+
+	machineSetResyncPeriod := flag.Duration(
+		"machineset-resync-period",
+		0,
+		"How often a MachineSet is reconciled when neither it nor its Machines change. The MachineSet controller ignores the full resyncs of the cache and relies on watches instead. Defaults to sync-period when zero.",
+	)
+
+	cacheFullSecrets := flag.Bool("cache-full-secrets", false,
+		"Cache the full Secrets referenced by the Machines and MachineSets, rather than only their metadata. The controller and webhooks only check the Secrets exist, set it when a provider validating the providerSpecs reads them.")
+
+	secureMetrics := &metrics.SecureServingOptions{}
+	secureMetrics.AddFlags(flag.CommandLine)
+	loggingOptions := &logging.Options{}
+	loggingOptions.AddFlags(flag.CommandLine)
+	tracingOptions := &tracing.Options{}
+	tracingOptions.AddFlags(flag.CommandLine)
+	profilingOptions := &profiling.Options{}
+	profilingOptions.AddFlags(flag.CommandLine)
+	cacheOptions := &util.CacheOptions{}
+	cacheOptions.AddFlags(flag.CommandLine)
+	rateLimiterOptions := &util.RateLimiterOptions{}
+	rateLimiterOptions.AddFlags(flag.CommandLine)
+	leaderElectionPreference := &util.LeaderElectionPreference{}
+	leaderElectionPreference.AddFlags(flag.CommandLine)
+
 	flag.Parse()
-	if *watchNamespace != "" {
-		log.Printf("Watching cluster-api objects only in namespace %q for reconciliation.", *watchNamespace)
+	var controllerConfig *mscontrollerconfig.MachineSetControllerConfiguration
+	if *configFile != "" {
+		var err error
+		if controllerConfig, err = mscontrollerconfig.Load(*configFile); err != nil {
+			log.Fatal(err)
+		}
+		if err := controllerConfig.ApplyToFlagSet(flag.CommandLine); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := loggingOptions.Setup(); err != nil {
+		log.Fatal(err)
+	}
+	if *syncPeriod <= 0 {
+		klog.Fatalf("invalid sync-period %v: must be positive", *syncPeriod)
+	}
+	if *machineSetResyncPeriod < 0 {
+		klog.Fatalf("invalid machineset-resync-period %v: must not be negative", *machineSetResyncPeriod)
+	}
+	if *machineSetResyncPeriod == 0 {
+		*machineSetResyncPeriod = *syncPeriod
+	}
+	if *machineSetConcurrency < 1 {
+		klog.Fatalf("invalid machineset-concurrency %d: must be at least 1", *machineSetConcurrency)
+	}
+	watchNamespaces := util.ParseNamespaces(*watchNamespace)
+	cacheOptions.Namespaces = watchNamespaces
+	if *capiSync && len(watchNamespaces) != 1 {
+		klog.Fatalf("capi-sync requires the namespace of the Machine API resources to be set to a single namespace")
+	}
+	if len(watchNamespaces) > 0 {
+		log.Printf("Watching cluster-api objects only in namespaces %q for reconciliation.", watchNamespaces)
 	}
 
 	log.Printf("Registering Components.")
@@ -107,6 +239,9 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := tracingOptions.Setup("machineset-controller", cfg); err != nil {
+		log.Fatal(err)
+	}
 
 	le := util.GetLeaderElectionConfig(cfg, osconfigv1.LeaderElection{
 		Disable:       !*leaderElect,
@@ -114,11 +249,9 @@ func main() {
 	})
 
 	// Create a new Cmd to provide shared dependencies and start components
-	syncPeriod := 10 * time.Minute
 	opts := manager.Options{
-		MetricsBindAddress:      *metricsAddress,
-		SyncPeriod:              &syncPeriod,
-		Namespace:               *watchNamespace,
+		MetricsBindAddress:      secureMetrics.ManagerBindAddress(*metricsAddress),
+		SyncPeriod:              syncPeriod,
 		HealthProbeBindAddress:  *healthAddr,
 		LeaderElection:          *leaderElect,
 		LeaderElectionNamespace: *leaderElectResourceNamespace,
@@ -127,12 +260,45 @@ func main() {
 		RetryPeriod:             &le.RetryPeriod.Duration,
 		RenewDeadline:           &le.RenewDeadline.Duration,
 	}
+	if err := util.ConfigureCache(&opts, *cacheOptions); err != nil {
+		log.Fatal(err)
+	}
+	opts.Controller.GroupKindConcurrency = map[string]int{
+		machinev1.SchemeGroupVersion.WithKind("MachineSet").GroupKind().String(): *machineSetConcurrency,
+	}
+
+	if *leaderElect && leaderElectionPreference.Enabled() {
+		lock, err := leaderElectionPreference.NewResourceLock(cfg, opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.LeaderElectionResourceLockInterface = lock
+	}
+
+	if err := rateLimiterOptions.Setup(context.Background(), cfg, cacheOptions.Namespaces); err != nil {
+		log.Fatal(err)
+	}
 
 	mgr, err := manager.New(cfg, opts)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if err := secureMetrics.AddToManager(mgr, *metricsAddress); err != nil {
+		log.Fatal(err)
+	}
+	if err := tracingOptions.AddToManager(mgr); err != nil {
+		log.Fatal(err)
+	}
+	if err := profilingOptions.AddToManager(mgr); err != nil {
+		log.Fatal(err)
+	}
+	if controllerConfig != nil {
+		if err := mgr.Add(mscontrollerconfig.NewReloader(*configFile, controllerConfig)); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// Enable defaulting and validating webhooks
 	machineDefaulter, err := mapiwebhooks.NewMachineDefaulter()
 	if err != nil {
@@ -154,13 +320,66 @@ func main() {
 		log.Fatal(err)
 	}
 
+	machineHealthCheckValidator := mapiwebhooks.NewMachineHealthCheckValidator(mgr.GetClient())
+
+	machineValidator.SetMinControlPlaneMachines(*minControlPlaneMachines)
+	machineValidator.SetDeletionProtectionAllowedUsers(splitUsers(*deletionProtectionAllowedUsers))
+
+	switch *awsMetadataServiceAuthentication {
+	case "":
+	case machinev1.MetadataServiceAuthenticationRequired, machinev1.MetadataServiceAuthenticationOptional:
+		authentication := machinev1.MetadataServiceAuthentication(*awsMetadataServiceAuthentication)
+		machineDefaulter.DefaultAWSMetadataServiceAuthentication(authentication)
+		machineSetDefaulter.DefaultAWSMetadataServiceAuthentication(authentication)
+	default:
+		klog.Fatalf("invalid aws-default-metadata-service-authentication %q: must be either %s or %s", *awsMetadataServiceAuthentication,
+			machinev1.MetadataServiceAuthenticationRequired, machinev1.MetadataServiceAuthenticationOptional)
+	}
+
+	if *webhookRejectMissingSecrets {
+		machineValidator.RejectMissingSecrets()
+		machineSetValidator.RejectMissingSecrets()
+	}
+
+	if *cacheFullSecrets {
+		machineValidator.GetFullSecrets()
+		machineSetValidator.GetFullSecrets()
+	}
+
+	if *webhookDryRunEstimates {
+		machineValidator.EnableDryRunEstimates()
+	}
+
+	if *webhookAllowHostAffinity {
+		machineValidator.AllowHostAffinity()
+		machineSetValidator.AllowHostAffinity()
+	}
+
+	if *vsphereDeepValidation {
+		o := mapiwebhooks.VSphereDeepValidationOptions{Timeout: *vsphereDeepValidationTimeout}
+		machineValidator.EnableVSphereDeepValidation(o)
+		machineSetValidator.EnableVSphereDeepValidation(o)
+	}
+
 	if *webhookEnabled {
 		mgr.GetWebhookServer().Port = *webhookPort
 		mgr.GetWebhookServer().CertDir = *webhookCertdir
-		mgr.GetWebhookServer().Register(mapiwebhooks.DefaultMachineMutatingHookPath, &webhook.Admission{Handler: machineDefaulter})
-		mgr.GetWebhookServer().Register(mapiwebhooks.DefaultMachineValidatingHookPath, &webhook.Admission{Handler: machineValidator})
-		mgr.GetWebhookServer().Register(mapiwebhooks.DefaultMachineSetMutatingHookPath, &webhook.Admission{Handler: machineSetDefaulter})
-		mgr.GetWebhookServer().Register(mapiwebhooks.DefaultMachineSetValidatingHookPath, &webhook.Admission{Handler: machineSetValidator})
+		mgr.GetWebhookServer().Register(mapiwebhooks.DefaultMachineMutatingHookPath, &webhook.Admission{Handler: mapiwebhooks.NewInstrumentedHandler("machine-defaulter", machineDefaulter)})
+		mgr.GetWebhookServer().Register(mapiwebhooks.DefaultMachineValidatingHookPath, &webhook.Admission{Handler: mapiwebhooks.NewInstrumentedHandler("machine-validator", machineValidator)})
+		mgr.GetWebhookServer().Register(mapiwebhooks.DefaultMachineSetMutatingHookPath, &webhook.Admission{Handler: mapiwebhooks.NewInstrumentedHandler("machineset-defaulter", machineSetDefaulter)})
+		mgr.GetWebhookServer().Register(mapiwebhooks.DefaultMachineSetValidatingHookPath, &webhook.Admission{Handler: mapiwebhooks.NewInstrumentedHandler("machineset-validator", machineSetValidator)})
+		mgr.GetWebhookServer().Register(mapiwebhooks.DefaultMachineHealthCheckValidatingHookPath, &webhook.Admission{Handler: mapiwebhooks.NewInstrumentedHandler("machinehealthcheck-validator", machineHealthCheckValidator)})
+
+		if *webhookSelfSigned {
+			// The certificates must exist before the webhook server is started.
+			certs := mapiwebhooks.NewSelfSignedCertificates(*webhookCertdir, mgr.GetClient(), mgr.GetAPIReader())
+			if err := certs.Ensure(context.Background()); err != nil {
+				log.Fatal(err)
+			}
+			if err := mgr.Add(certs); err != nil {
+				log.Fatal(err)
+			}
+		}
 	}
 
 	log.Printf("Registering Components.")
@@ -169,10 +388,42 @@ func main() {
 	if err := machinev1.AddToScheme(mgr.GetScheme()); err != nil {
 		log.Fatal(err)
 	}
+	if err := bulkv1beta1.AddToScheme(mgr.GetScheme()); err != nil {
+		log.Fatal(err)
+	}
 
 	// Setup all Controllers
-	if err := controller.AddToManager(mgr, opts, machineset.Add); err != nil {
-		log.Fatal(err)
+	if *controllerEnabled {
+		controllers := []func(manager.Manager, manager.Options) error{
+			machineset.AddWithOptions(machineset.Options{
+				MachineCreationQPS:         *machineCreationQPS,
+				MachineCreationBurst:       *machineCreationBurst,
+				MissingInstanceGracePeriod: *missingInstanceGracePeriod,
+				MachineValidator:           machineValidator,
+				FullSecrets:                *cacheFullSecrets,
+				ResyncPeriod:               *machineSetResyncPeriod,
+				RateLimiter:                rateLimiterOptions.NewRateLimiter(),
+			}),
+			machineset.AddHibernation,
+			bulkoperation.Add,
+		}
+		if *capiSync {
+			if err := osconfigv1.AddToScheme(mgr.GetScheme()); err != nil {
+				log.Fatal(err)
+			}
+			if err := clusterv1.AddToScheme(mgr.GetScheme()); err != nil {
+				log.Fatal(err)
+			}
+			controllers = append(controllers, migration.AddWithOptions(migration.Options{
+				MachineAPINamespace: watchNamespaces[0],
+				ClusterAPINamespace: *capiNamespace,
+			}))
+		}
+		if err := controller.AddToManager(mgr, opts, controllers...); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		klog.Info("MachineSet controller is disabled")
 	}
 
 	if err := mgr.AddReadyzCheck("ping", healthz.Ping); err != nil {
@@ -188,3 +439,14 @@ func main() {
 	// Start the Cmd
 	log.Fatal(mgr.Start(signals.SetupSignalHandler()))
 }
+
+// splitUsers splits the comma-separated list of users, dropping empty ones.
+func splitUsers(value string) []string {
+	var users []string
+	for _, user := range strings.Split(value, ",") {
+		if user = strings.TrimSpace(user); user != "" {
+			users = append(users, user)
+		}
+	}
+	return users
+}
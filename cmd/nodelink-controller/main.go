@@ -1,16 +1,22 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"runtime"
+	"strings"
 
 	osconfigv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	"github.com/openshift/library-go/pkg/config/leaderelection"
 	"github.com/openshift/machine-api-operator/pkg/controller"
 	"github.com/openshift/machine-api-operator/pkg/controller/nodelink"
+	"github.com/openshift/machine-api-operator/pkg/metrics"
 	"github.com/openshift/machine-api-operator/pkg/util"
+	"github.com/openshift/machine-api-operator/pkg/util/logging"
+	"github.com/openshift/machine-api-operator/pkg/util/profiling"
+	"github.com/openshift/machine-api-operator/pkg/util/tracing"
 	sdkVersion "github.com/operator-framework/operator-sdk/version"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
@@ -37,7 +43,7 @@ func main() {
 	watchNamespace := flag.String(
 		"namespace",
 		"",
-		"Namespace that the controller watches to reconcile machine-api objects. If unspecified, the controller watches for machine-api objects across all namespaces.",
+		"Comma separated namespaces that the controller watches to reconcile machine-api objects. If unspecified, the controller watches for machine-api objects across all namespaces.",
 	)
 
 	leaderElectResourceNamespace := flag.String(
@@ -59,17 +65,56 @@ func main() {
 		fmt.Sprintf("The duration that non-leader candidates will wait after observing a leadership renewal until attempting to acquire leadership of a led but unrenewed leader slot. This is effectively the maximum duration that a leader can be stopped before it is replaced by another candidate. This is only applicable if leader election is enabled. Default: (%s)", defaultLeaderElectionValues.LeaseDuration.Duration),
 	)
 
+	metricsAddress := flag.String(
+		"metrics-bind-address",
+		metrics.DefaultNodeLinkMetricsAddress,
+		"Address for hosting metrics",
+	)
+
+	propagatedPrefixes := flag.String(
+		"propagated-prefixes",
+		nodelink.DefaultPropagatedPrefix,
+		"Comma-separated prefixes of the Machine labels and annotations which are continuously propagated to the Node, including their removal.",
+	)
+
+	controllerEnabled := flag.Bool(
+		"controller-enabled",
+		true,
+		"Run the nodelink controller. When disabled, Nodes are not linked to their Machines and only the metrics endpoint is served.",
+	)
+
 	klog.InitFlags(nil)
 	if err := flag.Set("logtostderr", "true"); err != nil {
 		klog.Fatalf("failed to set logtostderr flag: %v", err)
 	}
+	secureMetrics := &metrics.SecureServingOptions{}
+	secureMetrics.AddFlags(flag.CommandLine)
+	loggingOptions := &logging.Options{}
+	loggingOptions.AddFlags(flag.CommandLine)
+	tracingOptions := &tracing.Options{}
+	tracingOptions.AddFlags(flag.CommandLine)
+	profilingOptions := &profiling.Options{}
+	profilingOptions.AddFlags(flag.CommandLine)
+	cacheOptions := &util.CacheOptions{}
+	cacheOptions.AddFlags(flag.CommandLine)
+	rateLimiterOptions := &util.RateLimiterOptions{}
+	rateLimiterOptions.AddFlags(flag.CommandLine)
+	leaderElectionPreference := &util.LeaderElectionPreference{}
+	leaderElectionPreference.AddFlags(flag.CommandLine)
+
 	flag.Parse()
+	if err := loggingOptions.Setup(); err != nil {
+		klog.Fatal(err)
+	}
 
 	// Get a config to talk to the apiserver
 	cfg, err := config.GetConfig()
 	if err != nil {
 		klog.Fatal(err)
 	}
+	if err := tracingOptions.Setup("nodelink-controller", cfg); err != nil {
+		klog.Fatal(err)
+	}
 
 	le := util.GetLeaderElectionConfig(cfg, osconfigv1.LeaderElection{
 		Disable:       !*leaderElect,
@@ -77,8 +122,7 @@ func main() {
 	})
 
 	opts := manager.Options{
-		// Disable metrics serving
-		MetricsBindAddress:      "0",
+		MetricsBindAddress:      secureMetrics.ManagerBindAddress(*metricsAddress),
 		LeaderElection:          *leaderElect,
 		LeaderElectionNamespace: *leaderElectResourceNamespace,
 		LeaderElectionID:        "cluster-api-provider-nodelink-leader",
@@ -86,16 +130,42 @@ func main() {
 		RetryPeriod:             &le.RetryPeriod.Duration,
 		RenewDeadline:           &le.RenewDeadline.Duration,
 	}
-	if *watchNamespace != "" {
-		opts.Namespace = *watchNamespace
-		klog.Infof("Watching machine-api objects only in namespace %q for reconciliation.", opts.Namespace)
+	watchNamespaces := util.ParseNamespaces(*watchNamespace)
+	cacheOptions.Namespaces = watchNamespaces
+	if len(watchNamespaces) > 0 {
+		klog.Infof("Watching machine-api objects only in namespaces %q for reconciliation.", watchNamespaces)
+	}
+	if err := util.ConfigureCache(&opts, *cacheOptions); err != nil {
+		klog.Fatal(err)
+	}
+	if *leaderElect && leaderElectionPreference.Enabled() {
+		lock, err := leaderElectionPreference.NewResourceLock(cfg, opts)
+		if err != nil {
+			klog.Fatal(err)
+		}
+		opts.LeaderElectionResourceLockInterface = lock
+	}
+
+	if err := rateLimiterOptions.Setup(context.Background(), cfg, cacheOptions.Namespaces); err != nil {
+		klog.Fatal(err)
 	}
+
 	// Create a new Cmd to provide shared dependencies and start components
 	mgr, err := manager.New(cfg, opts)
 	if err != nil {
 		klog.Fatal(err)
 	}
 
+	if err := secureMetrics.AddToManager(mgr, *metricsAddress); err != nil {
+		klog.Fatal(err)
+	}
+	if err := tracingOptions.AddToManager(mgr); err != nil {
+		klog.Fatal(err)
+	}
+	if err := profilingOptions.AddToManager(mgr); err != nil {
+		klog.Fatal(err)
+	}
+
 	klog.Infof("Registering Components.")
 
 	// Setup Scheme for all resources
@@ -103,9 +173,18 @@ func main() {
 		klog.Fatal(err)
 	}
 
+	metrics.InitializeNodeLinkMetrics()
+
 	// Setup all Controllers
-	if err := controller.AddToManager(mgr, opts, nodelink.Add); err != nil {
-		klog.Fatal(err)
+	if *controllerEnabled {
+		if err := controller.AddToManager(mgr, opts, nodelink.AddWithOptions(nodelink.Options{
+			PropagatedPrefixes: splitPrefixes(*propagatedPrefixes),
+			RateLimiter:        rateLimiterOptions.NewRateLimiter(),
+		})); err != nil {
+			klog.Fatal(err)
+		}
+	} else {
+		klog.Info("Nodelink controller is disabled")
 	}
 
 	klog.Info("Starting the Cmd.")
@@ -115,3 +194,14 @@ func main() {
 		klog.Fatal(err)
 	}
 }
+
+// splitPrefixes splits the comma-separated list of prefixes, dropping empty ones.
+func splitPrefixes(value string) []string {
+	var prefixes []string
+	for _, prefix := range strings.Split(value, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
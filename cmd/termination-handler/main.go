@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/machine-api-operator/pkg/termination"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+)
+
+func main() {
+	platform := flag.String(
+		"platform",
+		"",
+		"Platform of the instance, either AWS, GCP or Azure, whose instance metadata service is polled for the termination notice.",
+	)
+
+	rebalanceRecommendationOnly := flag.Bool(
+		"rebalance-recommendation-only",
+		false,
+		"Only poll for the rebalance recommendations, when the termination notices are handled by the termination handler of the provider.",
+	)
+
+	nodeName := flag.String(
+		"node-name",
+		"",
+		"Name of the node of the instance, which is marked as terminating.",
+	)
+
+	// The operator sets the namespace on all the platforms.
+	_ = flag.String(
+		"namespace",
+		"",
+		"Namespace of the Machines. Unused, the Machine of the node is deleted by the nodelink controller.",
+	)
+
+	pollIntervalSeconds := flag.Int64(
+		"poll-interval-seconds",
+		5,
+		"Interval in seconds between the polls of the instance metadata service.",
+	)
+
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	if *nodeName == "" {
+		klog.Fatal("--node-name must be set")
+	}
+	newPoller := termination.NewPoller
+	if *rebalanceRecommendationOnly {
+		newPoller = termination.NewRebalanceRecommendationPoller
+	}
+	poller, err := newPoller(configv1.PlatformType(*platform))
+	if err != nil {
+		klog.Fatal(err)
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		klog.Fatal(err)
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		klog.Fatal(err)
+	}
+
+	ctx := signals.SetupSignalHandler()
+	handler := termination.NewHandler(client, poller, *nodeName, time.Duration(*pollIntervalSeconds)*time.Second)
+	if err := handler.Run(ctx); err != nil {
+		klog.Fatal(err)
+	}
+
+	// The daemonset restarts the handler when it exits, wait for the instance to be terminated instead.
+	<-ctx.Done()
+}
@@ -1,19 +1,25 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	"github.com/openshift/library-go/pkg/config/leaderelection"
+	"github.com/openshift/machine-api-operator/pkg/controller/fake"
 	capimachine "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	machine "github.com/openshift/machine-api-operator/pkg/controller/vsphere"
 	machinesetcontroller "github.com/openshift/machine-api-operator/pkg/controller/vsphere/machineset"
 	"github.com/openshift/machine-api-operator/pkg/metrics"
 	"github.com/openshift/machine-api-operator/pkg/util"
+	"github.com/openshift/machine-api-operator/pkg/util/logging"
+	"github.com/openshift/machine-api-operator/pkg/util/profiling"
+	"github.com/openshift/machine-api-operator/pkg/util/tracing"
 	"github.com/openshift/machine-api-operator/pkg/version"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
@@ -25,6 +31,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
+// vspherePlatformName is the name of the vSphere platform, selecting the vSphere actuator.
+const vspherePlatformName = "vsphere"
+
 func main() {
 	var printVersion bool
 	flag.BoolVar(&printVersion, "version", false, "print version and exit")
@@ -39,7 +48,7 @@ func main() {
 	watchNamespace := flag.String(
 		"namespace",
 		"",
-		"Namespace that the controller watches to reconcile machine-api objects. If unspecified, the controller watches for machine-api objects across all namespaces.",
+		"Comma separated namespaces that the controller watches to reconcile machine-api objects. If unspecified, the controller watches for machine-api objects across all namespaces.",
 	)
 
 	leaderElectResourceNamespace := flag.String(
@@ -76,14 +85,88 @@ func main() {
 		":9440",
 		"The address for health checking.",
 	)
+	stuckMachineThreshold := flag.Duration(
+		"stuck-machine-threshold",
+		metrics.DefaultStuckMachineThreshold,
+		"Duration after which the Machines in the Provisioning or Deleting phase are counted as stuck in the mapi_machine_stuck_in_phase metric.",
+	)
+	orphanGCMode := flag.String(
+		"orphan-gc-mode",
+		string(capimachine.OrphanGCModeAlert),
+		"What to do with the provider resources tagged with the cluster ID without a Machine for longer than the orphan GC TTL: alert only reports them in the mapi_orphaned_provider_resources metric, delete also deletes them. Only supported with the fake platform.",
+	)
+	orphanGCTTL := flag.Duration(
+		"orphan-gc-ttl",
+		capimachine.DefaultOrphanGCTTL,
+		"Duration after which the provider resources without a Machine are orphaned. Only supported with the fake platform.",
+	)
+	orphanGCInterval := flag.Duration(
+		"orphan-gc-interval",
+		capimachine.DefaultOrphanGCInterval,
+		"Interval between two collections of the orphaned provider resources. Only supported with the fake platform.",
+	)
+	platform := flag.String(
+		"platform",
+		vspherePlatformName,
+		fmt.Sprintf("The platform of the machine actuator: %s, or %s to simulate the instances of the Machines without a cloud provider, for local development and CI.", vspherePlatformName, fake.PlatformName),
+	)
+	fakeProvisioningDelay := flag.Duration(
+		"fake-provisioning-delay",
+		fake.DefaultProvisioningDelay,
+		"Duration it takes to provision a fake instance and register its Node, with the fake platform.",
+	)
+	fakeDeletionDelay := flag.Duration(
+		"fake-deletion-delay",
+		fake.DefaultDeletionDelay,
+		"Duration it takes to terminate a fake instance, with the fake platform.",
+	)
+	deletionBlockedThreshold := flag.Duration(
+		"deletion-blocked-threshold",
+		capimachine.DefaultDeletionBlockedThreshold,
+		"Duration after which what blocks the deletion of a Machine is reported in its DeletionBlocked condition and in the mapi_machine_deletion_blocked metric.",
+	)
+
+	secureMetrics := &metrics.SecureServingOptions{}
+	secureMetrics.AddFlags(flag.CommandLine)
+	loggingOptions := &logging.Options{}
+	loggingOptions.AddFlags(flag.CommandLine)
+	tracingOptions := &tracing.Options{}
+	tracingOptions.AddFlags(flag.CommandLine)
+	profilingOptions := &profiling.Options{}
+	profilingOptions.AddFlags(flag.CommandLine)
+	cacheOptions := &util.CacheOptions{}
+	cacheOptions.AddFlags(flag.CommandLine)
+	rateLimiterOptions := &util.RateLimiterOptions{}
+	rateLimiterOptions.AddFlags(flag.CommandLine)
+	leaderElectionPreference := &util.LeaderElectionPreference{}
+	leaderElectionPreference.AddFlags(flag.CommandLine)
+
 	flag.Parse()
+	if err := loggingOptions.Setup(); err != nil {
+		klog.Fatal(err)
+	}
 
 	if printVersion {
 		fmt.Println(version.String)
 		os.Exit(0)
 	}
 
+	if *platform != vspherePlatformName && *platform != fake.PlatformName {
+		klog.Fatalf("Unknown platform %q, must be %s or %s", *platform, vspherePlatformName, fake.PlatformName)
+	}
+	// The vSphere actuator does not list the provider resources, only the fake one collects the orphaned ones.
+	if *platform != fake.PlatformName {
+		flag.Visit(func(f *flag.Flag) {
+			if strings.HasPrefix(f.Name, "orphan-gc-") {
+				klog.Fatalf("The --%s flag is only supported with the %s platform", f.Name, fake.PlatformName)
+			}
+		})
+	}
+
 	cfg := config.GetConfigOrDie()
+	if err := tracingOptions.Setup("machine-controller", cfg); err != nil {
+		klog.Fatalf("Failed to set up tracing: %v", err)
+	}
 	syncPeriod := 10 * time.Minute
 
 	le := util.GetLeaderElectionConfig(cfg, configv1.LeaderElection{
@@ -92,20 +175,36 @@ func main() {
 	})
 
 	opts := manager.Options{
-		MetricsBindAddress:      *metricsAddress,
+		MetricsBindAddress:      secureMetrics.ManagerBindAddress(*metricsAddress),
 		HealthProbeBindAddress:  *healthAddr,
 		SyncPeriod:              &syncPeriod,
 		LeaderElection:          *leaderElect,
 		LeaderElectionNamespace: *leaderElectResourceNamespace,
-		LeaderElectionID:        "cluster-api-provider-vsphere-leader",
+		LeaderElectionID:        fmt.Sprintf("cluster-api-provider-%s-leader", *platform),
 		LeaseDuration:           &le.LeaseDuration.Duration,
 		RetryPeriod:             &le.RetryPeriod.Duration,
 		RenewDeadline:           &le.RenewDeadline.Duration,
 	}
 
-	if *watchNamespace != "" {
-		opts.Namespace = *watchNamespace
-		klog.Infof("Watching machine-api objects only in namespace %q for reconciliation.", opts.Namespace)
+	watchNamespaces := util.ParseNamespaces(*watchNamespace)
+	cacheOptions.Namespaces = watchNamespaces
+	if len(watchNamespaces) > 0 {
+		klog.Infof("Watching machine-api objects only in namespaces %q for reconciliation.", watchNamespaces)
+	}
+	if err := util.ConfigureCache(&opts, *cacheOptions); err != nil {
+		klog.Fatalf("Failed to configure the cache: %v", err)
+	}
+
+	if *leaderElect && leaderElectionPreference.Enabled() {
+		lock, err := leaderElectionPreference.NewResourceLock(cfg, opts)
+		if err != nil {
+			klog.Fatalf("Failed to set up leader election: %v", err)
+		}
+		opts.LeaderElectionResourceLockInterface = lock
+	}
+
+	if err := rateLimiterOptions.Setup(context.Background(), cfg, cacheOptions.Namespaces); err != nil {
+		klog.Fatal(err)
 	}
 
 	// Setup a Manager
@@ -114,17 +213,43 @@ func main() {
 		klog.Fatalf("Failed to set up overall controller manager: %v", err)
 	}
 
-	// Create a taskIDCache for create task IDs in case they are lost due to
-	// network error or stale cache.
-	taskIDCache := make(map[string]string)
+	if err := secureMetrics.AddToManager(mgr, *metricsAddress); err != nil {
+		klog.Fatalf("Failed to serve metrics over TLS: %v", err)
+	}
+	if err := tracingOptions.AddToManager(mgr); err != nil {
+		klog.Fatalf("Failed to export traces: %v", err)
+	}
+	if err := profilingOptions.AddToManager(mgr); err != nil {
+		klog.Fatalf("Failed to serve profiles: %v", err)
+	}
 
 	// Initialize machine actuator.
-	machineActuator := machine.NewActuator(machine.ActuatorParams{
-		Client:        mgr.GetClient(),
-		APIReader:     mgr.GetAPIReader(),
-		EventRecorder: mgr.GetEventRecorderFor("vspherecontroller"),
-		TaskIDCache:   taskIDCache,
-	})
+	var machineActuator capimachine.Actuator
+	if *platform == fake.PlatformName {
+		klog.Infof("Simulating the instances of the machines with the fake actuator")
+		fakeActuator := fake.NewActuator(fake.ActuatorParams{
+			Client:            mgr.GetClient(),
+			EventRecorder:     mgr.GetEventRecorderFor("fakecontroller"),
+			ProvisioningDelay: *fakeProvisioningDelay,
+			DeletionDelay:     *fakeDeletionDelay,
+		})
+		// The fake instances have no kubelet, the actuator heartbeats their nodes.
+		if err := mgr.Add(fakeActuator); err != nil {
+			klog.Fatal(err)
+		}
+		machineActuator = fakeActuator
+	} else {
+		// Create a taskIDCache for create task IDs in case they are lost due to
+		// network error or stale cache.
+		taskIDCache := make(map[string]string)
+
+		machineActuator = machine.NewActuator(machine.ActuatorParams{
+			Client:        mgr.GetClient(),
+			APIReader:     mgr.GetAPIReader(),
+			EventRecorder: mgr.GetEventRecorderFor("vspherecontroller"),
+			TaskIDCache:   taskIDCache,
+		})
+	}
 
 	if err := configv1.AddToScheme(mgr.GetScheme()); err != nil {
 		klog.Fatal(err)
@@ -138,18 +263,28 @@ func main() {
 		klog.Fatal(err)
 	}
 
-	if err := capimachine.AddWithActuator(mgr, machineActuator); err != nil {
+	if err := capimachine.AddWithActuatorOpts(mgr, machineActuator, capimachine.Options{
+		StuckMachineThreshold:    *stuckMachineThreshold,
+		OrphanGCMode:             capimachine.OrphanGCMode(*orphanGCMode),
+		OrphanGCTTL:              *orphanGCTTL,
+		OrphanGCInterval:         *orphanGCInterval,
+		DeletionBlockedThreshold: *deletionBlockedThreshold,
+		RateLimiter:              rateLimiterOptions.NewRateLimiter(),
+	}); err != nil {
 		klog.Fatal(err)
 	}
 
 	ctrl.SetLogger(klogr.New())
 	setupLog := ctrl.Log.WithName("setup")
-	if err = (&machinesetcontroller.Reconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("MachineSet"),
-	}).SetupWithManager(mgr, controller.Options{}); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "MachineSet")
-		os.Exit(1)
+	// The fake instances have no vSphere providerSpec to compute the capacity of the MachineSets from.
+	if *platform == vspherePlatformName {
+		if err = (&machinesetcontroller.Reconciler{
+			Client: mgr.GetClient(),
+			Log:    ctrl.Log.WithName("controllers").WithName("MachineSet"),
+		}).SetupWithManager(mgr, controller.Options{RateLimiter: rateLimiterOptions.NewRateLimiter()}); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "MachineSet")
+			os.Exit(1)
+		}
 	}
 
 	if err := mgr.AddReadyzCheck("ping", healthz.Ping); err != nil {
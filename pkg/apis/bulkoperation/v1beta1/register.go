@@ -0,0 +1,41 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1beta1 contains the BulkOperation API, which runs an operation on all the Machines matching a
+// label selector. It belongs to the machine.openshift.io group, next to the Machine API types of openshift/api.
+// +kubebuilder:object:generate=true
+// +groupName=machine.openshift.io
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the group version of the BulkOperation API.
+	GroupVersion = schema.GroupVersion{Group: "machine.openshift.io", Version: "v1beta1"}
+
+	// SchemeBuilder is used to add the BulkOperation API to a scheme.
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the BulkOperation API to a scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+func init() {
+	SchemeBuilder.Register(&BulkOperation{}, &BulkOperationList{})
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BulkOperationType is the operation run on the Machines of a BulkOperation.
+type BulkOperationType string
+
+const (
+	// BulkOperationDrain cordons and drains the nodes of the Machines, honouring their pre-drain lifecycle
+	// hooks and drain timeout. The nodes stay cordoned until the drain request of the Machines is removed.
+	BulkOperationDrain BulkOperationType = "Drain"
+	// BulkOperationMarkForDeletion marks the Machines to be deleted first when their MachineSets scale down.
+	BulkOperationMarkForDeletion BulkOperationType = "MarkForDeletion"
+	// BulkOperationPowerOff powers the instances of the Machines off.
+	BulkOperationPowerOff BulkOperationType = "PowerOff"
+	// BulkOperationPowerOn powers the instances of the Machines back on.
+	BulkOperationPowerOn BulkOperationType = "PowerOn"
+)
+
+// BulkOperationPhase is the phase of a BulkOperation.
+type BulkOperationPhase string
+
+const (
+	// BulkOperationPhaseRunning is the phase of a BulkOperation whose Machines are being operated.
+	BulkOperationPhaseRunning BulkOperationPhase = "Running"
+	// BulkOperationPhaseSucceeded is the phase of a BulkOperation completed on all of its Machines.
+	BulkOperationPhaseSucceeded BulkOperationPhase = "Succeeded"
+	// BulkOperationPhaseFailed is the phase of a BulkOperation which could not be completed on some of its Machines.
+	BulkOperationPhaseFailed BulkOperationPhase = "Failed"
+)
+
+// BulkOperationMachinePhase is the phase of the operation of a Machine.
+type BulkOperationMachinePhase string
+
+const (
+	// BulkOperationMachineInProgress is the phase of a Machine whose operation has not completed yet.
+	BulkOperationMachineInProgress BulkOperationMachinePhase = "InProgress"
+	// BulkOperationMachineCompleted is the phase of a Machine whose operation has completed.
+	BulkOperationMachineCompleted BulkOperationMachinePhase = "Completed"
+	// BulkOperationMachineFailed is the phase of a Machine whose operation could not be completed.
+	BulkOperationMachineFailed BulkOperationMachinePhase = "Failed"
+)
+
+// BulkOperationSpec defines the operation and the Machines it runs on.
+type BulkOperationSpec struct {
+	// Selector selects the Machines of the namespace of the BulkOperation. The Machines are selected once,
+	// when the operation starts.
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// Operation is the operation run on the Machines: Drain, MarkForDeletion, PowerOff or PowerOn.
+	// +kubebuilder:validation:Enum=Drain;MarkForDeletion;PowerOff;PowerOn
+	Operation BulkOperationType `json:"operation"`
+}
+
+// BulkOperationMachineStatus is the progress of the operation of a Machine.
+type BulkOperationMachineStatus struct {
+	// Name is the name of the Machine.
+	Name string `json:"name"`
+
+	// Phase is the phase of the operation of the Machine.
+	Phase BulkOperationMachinePhase `json:"phase"`
+
+	// Message is a human readable message about the operation of the Machine.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// BulkOperationStatus reports the progress of a BulkOperation.
+type BulkOperationStatus struct {
+	// Phase is the phase of the BulkOperation.
+	// +optional
+	Phase BulkOperationPhase `json:"phase,omitempty"`
+
+	// Message is a human readable message about the BulkOperation, e.g. why it failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// StartTime is when the Machines were selected.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the operation completed or failed on all of its Machines.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Total is the number of Machines selected.
+	// +optional
+	Total int32 `json:"total,omitempty"`
+
+	// Completed is the number of Machines whose operation has completed.
+	// +optional
+	Completed int32 `json:"completed,omitempty"`
+
+	// Failed is the number of Machines whose operation could not be completed.
+	// +optional
+	Failed int32 `json:"failed,omitempty"`
+
+	// Machines is the progress of the operation of each selected Machine.
+	// +optional
+	Machines []BulkOperationMachineStatus `json:"machines,omitempty"`
+}
+
+// BulkOperation runs an operation on all the Machines matching a label selector, and tracks its progress.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Operation",type="string",JSONPath=".spec.operation"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Total",type="integer",JSONPath=".status.total"
+// +kubebuilder:printcolumn:name="Completed",type="integer",JSONPath=".status.completed"
+// +kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=".status.failed"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type BulkOperation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BulkOperationSpec   `json:"spec,omitempty"`
+	Status BulkOperationStatus `json:"status,omitempty"`
+}
+
+// BulkOperationList contains a list of BulkOperations.
+// +kubebuilder:object:root=true
+type BulkOperationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BulkOperation `json:"items"`
+}
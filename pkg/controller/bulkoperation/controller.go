@@ -0,0 +1,311 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bulkoperation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	bulkv1beta1 "github.com/openshift/machine-api-operator/pkg/apis/bulkoperation/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-operator/pkg/controller/machineset"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+const controllerName = "bulkoperation_controller"
+
+// errPowerOffUnsupported fails the Machines whose machine controller reported that they cannot be powered off.
+var errPowerOffUnsupported = errors.New("powering off machines is not supported on this platform")
+
+// Add creates a new BulkOperation Controller and adds it to the Manager.
+// The controller runs the operation of each BulkOperation on the Machines matching its selector, through
+// the annotations of the Machines, and reports its progress in the status of the BulkOperation.
+func Add(mgr manager.Manager, opts manager.Options) error {
+	r := &ReconcileBulkOperation{
+		Client:   mgr.GetClient(),
+		recorder: mgr.GetEventRecorderFor(controllerName),
+	}
+
+	c, err := controller.New(controllerName, mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	if err := c.Watch(&source.Kind{Type: &bulkv1beta1.BulkOperation{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+	return c.Watch(&source.Kind{Type: &machinev1.Machine{}}, handler.EnqueueRequestsFromMapFunc(r.machineToBulkOperations))
+}
+
+// ReconcileBulkOperation reconciles BulkOperations.
+type ReconcileBulkOperation struct {
+	client.Client
+	recorder record.EventRecorder
+
+	// nowFunc is used to mock time in testing. It should be nil in production.
+	nowFunc func() time.Time
+}
+
+func (r *ReconcileBulkOperation) now() time.Time {
+	if r.nowFunc != nil {
+		return r.nowFunc()
+	}
+	return time.Now()
+}
+
+// machineToBulkOperations returns the running BulkOperations operating the Machine.
+func (r *ReconcileBulkOperation) machineToBulkOperations(o client.Object) []reconcile.Request {
+	operations := &bulkv1beta1.BulkOperationList{}
+	if err := r.List(context.Background(), operations, client.InNamespace(o.GetNamespace())); err != nil {
+		klog.Errorf("Unable to list BulkOperations: %v", err)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, op := range operations.Items {
+		if op.Status.Phase != bulkv1beta1.BulkOperationPhaseRunning {
+			continue
+		}
+		for _, machine := range op.Status.Machines {
+			if machine.Name == o.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&op)})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// Reconcile selects the Machines of a new BulkOperation, then runs its operation on each of them until it has
+// completed or failed on all of them.
+func (r *ReconcileBulkOperation) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	op := &bulkv1beta1.BulkOperation{}
+	if err := r.Get(ctx, request.NamespacedName, op); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if op.DeletionTimestamp != nil ||
+		op.Status.Phase == bulkv1beta1.BulkOperationPhaseSucceeded || op.Status.Phase == bulkv1beta1.BulkOperationPhaseFailed {
+		return reconcile.Result{}, nil
+	}
+
+	original := op.DeepCopy()
+	var err error
+	if op.Status.Phase == "" {
+		err = r.selectMachines(ctx, op)
+	}
+	if err == nil && op.Status.Phase == bulkv1beta1.BulkOperationPhaseRunning {
+		err = r.operateMachines(ctx, op)
+	}
+
+	if !equality.Semantic.DeepEqual(original.Status, op.Status) {
+		if updateErr := r.Status().Update(ctx, op); updateErr != nil {
+			return reconcile.Result{}, fmt.Errorf("could not update BulkOperation status: %w", updateErr)
+		}
+	}
+	return reconcile.Result{}, err
+}
+
+// selectMachines records the Machines matching the selector of the BulkOperation in its status, and starts it.
+func (r *ReconcileBulkOperation) selectMachines(ctx context.Context, op *bulkv1beta1.BulkOperation) error {
+	switch op.Spec.Operation {
+	case bulkv1beta1.BulkOperationDrain, bulkv1beta1.BulkOperationMarkForDeletion,
+		bulkv1beta1.BulkOperationPowerOff, bulkv1beta1.BulkOperationPowerOn:
+	default:
+		r.fail(op, "Unknown operation %q", op.Spec.Operation)
+		return nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&op.Spec.Selector)
+	if err != nil {
+		r.fail(op, "Invalid selector: %v", err)
+		return nil
+	}
+	machines := &machinev1.MachineList{}
+	if err := r.List(ctx, machines, client.InNamespace(op.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return fmt.Errorf("failed to list machines: %w", err)
+	}
+
+	var names []string
+	for _, m := range machines.Items {
+		names = append(names, m.Name)
+	}
+	sort.Strings(names)
+
+	op.Status.Phase = bulkv1beta1.BulkOperationPhaseRunning
+	op.Status.StartTime = &metav1.Time{Time: r.now()}
+	op.Status.Machines = nil
+	for _, name := range names {
+		op.Status.Machines = append(op.Status.Machines, bulkv1beta1.BulkOperationMachineStatus{
+			Name:  name,
+			Phase: bulkv1beta1.BulkOperationMachineInProgress,
+		})
+	}
+	klog.Infof("%v: running %s on %d machines", op.Name, op.Spec.Operation, len(names))
+	r.recorder.Eventf(op, corev1.EventTypeNormal, "Started", "Running %s on %d machines", op.Spec.Operation, len(names))
+	return nil
+}
+
+// operateMachines runs the operation on the Machines in progress, and completes the BulkOperation once
+// none is in progress anymore.
+func (r *ReconcileBulkOperation) operateMachines(ctx context.Context, op *bulkv1beta1.BulkOperation) error {
+	var errs []error
+	for i := range op.Status.Machines {
+		status := &op.Status.Machines[i]
+		if status.Phase != bulkv1beta1.BulkOperationMachineInProgress {
+			continue
+		}
+
+		m := &machinev1.Machine{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: op.Namespace, Name: status.Name}, m); err != nil {
+			if apierrors.IsNotFound(err) {
+				status.Phase = bulkv1beta1.BulkOperationMachineFailed
+				status.Message = "Machine not found"
+				continue
+			}
+			errs = append(errs, err)
+			continue
+		}
+		if m.DeletionTimestamp != nil && op.Spec.Operation != bulkv1beta1.BulkOperationMarkForDeletion {
+			status.Phase = bulkv1beta1.BulkOperationMachineFailed
+			status.Message = "Machine is being deleted"
+			continue
+		}
+
+		completed, err := r.operateMachine(ctx, op.Spec.Operation, m)
+		if errors.Is(err, errPowerOffUnsupported) {
+			status.Phase = bulkv1beta1.BulkOperationMachineFailed
+			status.Message = err.Error()
+			continue
+		}
+		if err != nil {
+			status.Message = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", m.Name, err))
+			continue
+		}
+		status.Message = ""
+		if completed {
+			status.Phase = bulkv1beta1.BulkOperationMachineCompleted
+		}
+	}
+
+	op.Status.Total = int32(len(op.Status.Machines))
+	op.Status.Completed = 0
+	op.Status.Failed = 0
+	for _, status := range op.Status.Machines {
+		switch status.Phase {
+		case bulkv1beta1.BulkOperationMachineCompleted:
+			op.Status.Completed++
+		case bulkv1beta1.BulkOperationMachineFailed:
+			op.Status.Failed++
+		}
+	}
+
+	if op.Status.Completed+op.Status.Failed == op.Status.Total {
+		if op.Status.Failed > 0 {
+			r.fail(op, "%s failed on %d of %d machines", op.Spec.Operation, op.Status.Failed, op.Status.Total)
+		} else {
+			op.Status.Phase = bulkv1beta1.BulkOperationPhaseSucceeded
+			op.Status.CompletionTime = &metav1.Time{Time: r.now()}
+			klog.Infof("%v: %s completed on %d machines", op.Name, op.Spec.Operation, op.Status.Total)
+			r.recorder.Eventf(op, corev1.EventTypeNormal, "Succeeded", "%s completed on %d machines", op.Spec.Operation, op.Status.Total)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to run %s on %d machines: %v", op.Spec.Operation, len(errs), errs)
+	}
+	return nil
+}
+
+// operateMachine requests the operation with the annotations of the Machine, and returns true once it has completed.
+func (r *ReconcileBulkOperation) operateMachine(ctx context.Context, operation bulkv1beta1.BulkOperationType, m *machinev1.Machine) (bool, error) {
+	switch operation {
+	case bulkv1beta1.BulkOperationDrain:
+		if err := r.annotate(ctx, m, machinecontroller.MachineDrainRequestedAnnotation, "true"); err != nil {
+			return false, err
+		}
+		drained := conditions.Get(m, machinev1.MachineDrained)
+		return drained != nil && drained.Status == corev1.ConditionTrue, nil
+	case bulkv1beta1.BulkOperationMarkForDeletion:
+		// The priority the Machine may already have amongst the Machines marked for deletion is kept.
+		if m.Annotations[machineset.DeleteNodeAnnotation] != "" {
+			return true, nil
+		}
+		return true, r.annotate(ctx, m, machineset.DeleteNodeAnnotation, "true")
+	case bulkv1beta1.BulkOperationPowerOff:
+		if err := r.annotate(ctx, m, machinecontroller.MachinePowerStateAnnotation, string(machinecontroller.MachinePowerStateOff)); err != nil {
+			return false, err
+		}
+		if machinecontroller.IsPowerOffUnsupported(m) {
+			return false, errPowerOffUnsupported
+		}
+		return pointer.StringDeref(m.Status.Phase, "") == machinecontroller.PhaseStopped, nil
+	case bulkv1beta1.BulkOperationPowerOn:
+		if err := r.annotate(ctx, m, machinecontroller.MachinePowerStateAnnotation, string(machinecontroller.MachinePowerStateOn)); err != nil {
+			return false, err
+		}
+		return pointer.StringDeref(m.Status.Phase, "") != machinecontroller.PhaseStopped, nil
+	}
+	return false, fmt.Errorf("unknown operation %q", operation)
+}
+
+// annotate sets the annotation of the Machine, unless it already has the value.
+func (r *ReconcileBulkOperation) annotate(ctx context.Context, m *machinev1.Machine, key, value string) error {
+	if m.Annotations[key] == value {
+		return nil
+	}
+	patch := client.MergeFrom(m.DeepCopy())
+	if m.Annotations == nil {
+		m.Annotations = map[string]string{}
+	}
+	m.Annotations[key] = value
+	if err := r.Patch(ctx, m, patch); err != nil {
+		return fmt.Errorf("failed to set %s annotation: %w", key, err)
+	}
+	return nil
+}
+
+// fail marks the BulkOperation as failed.
+func (r *ReconcileBulkOperation) fail(op *bulkv1beta1.BulkOperation, format string, args ...interface{}) {
+	op.Status.Phase = bulkv1beta1.BulkOperationPhaseFailed
+	op.Status.Message = fmt.Sprintf(format, args...)
+	op.Status.CompletionTime = &metav1.Time{Time: r.now()}
+	klog.Warningf("%v: %s", op.Name, op.Status.Message)
+	r.recorder.Event(op, corev1.EventTypeWarning, "Failed", op.Status.Message)
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bulkoperation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bulkv1beta1 "github.com/openshift/machine-api-operator/pkg/apis/bulkoperation/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-operator/pkg/controller/machineset"
+)
+
+func newMachine(name string, labels map[string]string) *machinev1.Machine {
+	return &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Labels:      labels,
+			Annotations: map[string]string{},
+		},
+	}
+}
+
+func newReconciler(g *WithT, now time.Time, objects ...client.Object) *ReconcileBulkOperation {
+	scheme := runtime.NewScheme()
+	g.Expect(machinev1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(bulkv1beta1.AddToScheme(scheme)).To(Succeed())
+
+	return &ReconcileBulkOperation{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		recorder: record.NewFakeRecorder(32),
+		nowFunc:  func() time.Time { return now },
+	}
+}
+
+func reconcileBulkOperation(g *WithT, r *ReconcileBulkOperation, op *bulkv1beta1.BulkOperation) {
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(op)})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.Get(context.TODO(), client.ObjectKeyFromObject(op), op)).To(Succeed())
+}
+
+func TestReconcileDrain(t *testing.T) {
+	g := NewWithT(t)
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+
+	worker1 := newMachine("worker-1", map[string]string{"pool": "gpu"})
+	worker2 := newMachine("worker-2", map[string]string{"pool": "gpu"})
+	other := newMachine("other", map[string]string{"pool": "cpu"})
+	op := &bulkv1beta1.BulkOperation{
+		ObjectMeta: metav1.ObjectMeta{Name: "drain-gpu", Namespace: "default"},
+		Spec: bulkv1beta1.BulkOperationSpec{
+			Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"pool": "gpu"}},
+			Operation: bulkv1beta1.BulkOperationDrain,
+		},
+	}
+	r := newReconciler(g, now, worker1, worker2, other, op)
+
+	// The selected machines are requested to be drained.
+	reconcileBulkOperation(g, r, op)
+	g.Expect(op.Status.Phase).To(Equal(bulkv1beta1.BulkOperationPhaseRunning))
+	g.Expect(op.Status.StartTime.Time).To(BeTemporally("==", now))
+	g.Expect(op.Status.Total).To(BeEquivalentTo(2))
+	g.Expect(op.Status.Completed).To(BeZero())
+	g.Expect(op.Status.Machines).To(Equal([]bulkv1beta1.BulkOperationMachineStatus{
+		{Name: "worker-1", Phase: bulkv1beta1.BulkOperationMachineInProgress},
+		{Name: "worker-2", Phase: bulkv1beta1.BulkOperationMachineInProgress},
+	}))
+	for _, m := range []*machinev1.Machine{worker1, worker2, other} {
+		g.Expect(r.Get(context.TODO(), client.ObjectKeyFromObject(m), m)).To(Succeed())
+	}
+	g.Expect(worker1.Annotations).To(HaveKeyWithValue(machinecontroller.MachineDrainRequestedAnnotation, "true"))
+	g.Expect(worker2.Annotations).To(HaveKeyWithValue(machinecontroller.MachineDrainRequestedAnnotation, "true"))
+	g.Expect(other.Annotations).ToNot(HaveKey(machinecontroller.MachineDrainRequestedAnnotation))
+
+	// The progress is tracked as the nodes get drained.
+	worker1.Status.Conditions = machinev1.Conditions{{Type: machinev1.MachineDrained, Status: corev1.ConditionTrue}}
+	g.Expect(r.Status().Update(context.TODO(), worker1)).To(Succeed())
+	reconcileBulkOperation(g, r, op)
+	g.Expect(op.Status.Phase).To(Equal(bulkv1beta1.BulkOperationPhaseRunning))
+	g.Expect(op.Status.Completed).To(BeEquivalentTo(1))
+
+	// The operation fails on the machines deleted meanwhile.
+	g.Expect(r.Delete(context.TODO(), worker2)).To(Succeed())
+	reconcileBulkOperation(g, r, op)
+	g.Expect(op.Status.Phase).To(Equal(bulkv1beta1.BulkOperationPhaseFailed))
+	g.Expect(op.Status.Message).To(Equal("Drain failed on 1 of 2 machines"))
+	g.Expect(op.Status.CompletionTime).ToNot(BeNil())
+	g.Expect(op.Status.Machines).To(Equal([]bulkv1beta1.BulkOperationMachineStatus{
+		{Name: "worker-1", Phase: bulkv1beta1.BulkOperationMachineCompleted},
+		{Name: "worker-2", Phase: bulkv1beta1.BulkOperationMachineFailed, Message: "Machine not found"},
+	}))
+}
+
+func TestReconcileOperations(t *testing.T) {
+	testCases := []struct {
+		name                string
+		operation           bulkv1beta1.BulkOperationType
+		annotations         map[string]string
+		phase               string
+		conditions          machinev1.Conditions
+		expectedAnnotations map[string]string
+		expectedPhase       bulkv1beta1.BulkOperationPhase
+	}{
+		{
+			name:                "mark for deletion",
+			operation:           bulkv1beta1.BulkOperationMarkForDeletion,
+			expectedAnnotations: map[string]string{machineset.DeleteNodeAnnotation: "true"},
+			expectedPhase:       bulkv1beta1.BulkOperationPhaseSucceeded,
+		},
+		{
+			name:                "mark for deletion keeps the deletion priority",
+			operation:           bulkv1beta1.BulkOperationMarkForDeletion,
+			annotations:         map[string]string{machineset.DeleteNodeAnnotation: "priority-3"},
+			expectedAnnotations: map[string]string{machineset.DeleteNodeAnnotation: "priority-3"},
+			expectedPhase:       bulkv1beta1.BulkOperationPhaseSucceeded,
+		},
+		{
+			name:                "power off",
+			operation:           bulkv1beta1.BulkOperationPowerOff,
+			phase:               machinev1.PhaseRunning,
+			expectedAnnotations: map[string]string{machinecontroller.MachinePowerStateAnnotation: "Off"},
+			expectedPhase:       bulkv1beta1.BulkOperationPhaseRunning,
+		},
+		{
+			name:                "power off stopped machines",
+			operation:           bulkv1beta1.BulkOperationPowerOff,
+			phase:               machinecontroller.PhaseStopped,
+			expectedAnnotations: map[string]string{machinecontroller.MachinePowerStateAnnotation: "Off"},
+			expectedPhase:       bulkv1beta1.BulkOperationPhaseSucceeded,
+		},
+		{
+			name:      "power off unsupported",
+			operation: bulkv1beta1.BulkOperationPowerOff,
+			phase:     machinev1.PhaseRunning,
+			conditions: machinev1.Conditions{{
+				Type:   machinecontroller.InstancePoweredOffCondition,
+				Status: corev1.ConditionFalse,
+				Reason: machinecontroller.PowerOffUnsupportedReason,
+			}},
+			expectedAnnotations: map[string]string{machinecontroller.MachinePowerStateAnnotation: "Off"},
+			expectedPhase:       bulkv1beta1.BulkOperationPhaseFailed,
+		},
+		{
+			name:                "power on",
+			operation:           bulkv1beta1.BulkOperationPowerOn,
+			phase:               machinev1.PhaseRunning,
+			annotations:         map[string]string{machinecontroller.MachinePowerStateAnnotation: "Off"},
+			expectedAnnotations: map[string]string{machinecontroller.MachinePowerStateAnnotation: "On"},
+			expectedPhase:       bulkv1beta1.BulkOperationPhaseSucceeded,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			m := newMachine("worker", map[string]string{"pool": "gpu"})
+			for key, value := range tc.annotations {
+				m.Annotations[key] = value
+			}
+			if tc.phase != "" {
+				m.Status.Phase = &tc.phase
+			}
+			m.Status.Conditions = tc.conditions
+			op := &bulkv1beta1.BulkOperation{
+				ObjectMeta: metav1.ObjectMeta{Name: "op", Namespace: "default"},
+				Spec: bulkv1beta1.BulkOperationSpec{
+					Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"pool": "gpu"}},
+					Operation: tc.operation,
+				},
+			}
+			r := newReconciler(g, time.Now(), m, op)
+
+			reconcileBulkOperation(g, r, op)
+			g.Expect(op.Status.Phase).To(Equal(tc.expectedPhase))
+			g.Expect(r.Get(context.TODO(), client.ObjectKeyFromObject(m), m)).To(Succeed())
+			for key, value := range tc.expectedAnnotations {
+				g.Expect(m.Annotations).To(HaveKeyWithValue(key, value))
+			}
+		})
+	}
+}
+
+func TestReconcileInvalidSelector(t *testing.T) {
+	g := NewWithT(t)
+
+	op := &bulkv1beta1.BulkOperation{
+		ObjectMeta: metav1.ObjectMeta{Name: "op", Namespace: "default"},
+		Spec: bulkv1beta1.BulkOperationSpec{
+			Selector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "pool", Operator: "Near"},
+			}},
+			Operation: bulkv1beta1.BulkOperationDrain,
+		},
+	}
+	r := newReconciler(g, time.Now(), op)
+
+	reconcileBulkOperation(g, r, op)
+	g.Expect(op.Status.Phase).To(Equal(bulkv1beta1.BulkOperationPhaseFailed))
+	g.Expect(op.Status.Message).To(HavePrefix("Invalid selector"))
+}
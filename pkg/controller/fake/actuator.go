@@ -0,0 +1,369 @@
+// Package fake implements a machine actuator simulating the instances of the Machines, without any cloud provider.
+// The instances are provisioned and terminated after a delay, and register a Node without a kubelet, so that the
+// controllers, the webhooks and the MachineHealthChecks can be exercised in kind or envtest clusters, without
+// cloud credentials. Failures are injected with annotations on the Machines.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+)
+
+const (
+	// PlatformName is the name of the fake platform, selecting the fake actuator.
+	PlatformName = "fake"
+
+	// ProviderIDPrefix is the prefix of the providerIDs of the fake instances, followed by the namespace and the name of their Machine.
+	ProviderIDPrefix = "fake:///"
+
+	// DefaultProvisioningDelay is the time it takes to provision a fake instance and register its Node.
+	DefaultProvisioningDelay = 10 * time.Second
+
+	// DefaultDeletionDelay is the time it takes to terminate a fake instance.
+	DefaultDeletionDelay = 10 * time.Second
+
+	// ProvisioningDelayAnnotation overrides the provisioning delay of the instance of the Machine, e.g. "2m".
+	ProvisioningDelayAnnotation = "fake.machine.openshift.io/provisioning-delay"
+
+	// DeletionDelayAnnotation overrides the deletion delay of the instance of the Machine, e.g. "2m".
+	DeletionDelayAnnotation = "fake.machine.openshift.io/deletion-delay"
+
+	// CreateErrorAnnotation makes the creation of the instance of the Machine fail with its value, which is retried.
+	CreateErrorAnnotation = "fake.machine.openshift.io/create-error"
+
+	// InvalidConfigurationAnnotation makes the creation of the instance of the Machine fail with its value as an
+	// invalid configuration, which fails the Machine.
+	InvalidConfigurationAnnotation = "fake.machine.openshift.io/invalid-configuration"
+
+	// UpdateErrorAnnotation makes the updates of the instance of the Machine fail with its value.
+	UpdateErrorAnnotation = "fake.machine.openshift.io/update-error"
+
+	// DeleteErrorAnnotation makes the deletion of the instance of the Machine fail with its value.
+	DeleteErrorAnnotation = "fake.machine.openshift.io/delete-error"
+
+	// NodeUnhealthyAnnotation, set to "true", makes the Node of the Machine not ready, e.g. to be remediated by a MachineHealthCheck.
+	NodeUnhealthyAnnotation = "fake.machine.openshift.io/node-unhealthy"
+
+	// InstanceTypeAnnotation sets the instance type of the fake instance of the Machine, e.g. "large". Changing it
+	// resizes the instance in place, when the Machine allows it.
+	InstanceTypeAnnotation = "fake.machine.openshift.io/instance-type"
+
+	// createdAtAnnotation and deletedAtAnnotation record when the fake instance was created, and deleted.
+	createdAtAnnotation = "fake.machine.openshift.io/created-at"
+	deletedAtAnnotation = "fake.machine.openshift.io/deleted-at"
+
+	// currentInstanceTypeAnnotation records the instance type of the fake instance, and rebootedAtAnnotation
+	// when it was last rebooted.
+	currentInstanceTypeAnnotation = "fake.machine.openshift.io/current-instance-type"
+	rebootedAtAnnotation          = "fake.machine.openshift.io/rebooted-at"
+
+	// The states of the fake instances, recorded in the instance state annotation of their Machine.
+	instanceStatePending     = "pending"
+	instanceStateRunning     = "running"
+	instanceStateStopped     = "stopped"
+	instanceStateTerminating = "terminating"
+	instanceStateTerminated  = "terminated"
+)
+
+var (
+	_ machinecontroller.Actuator                 = &Actuator{}
+	_ machinecontroller.ResizeActuator           = &Actuator{}
+	_ machinecontroller.RebootActuator           = &Actuator{}
+	_ machinecontroller.ProviderResourceActuator = &Actuator{}
+)
+
+// Actuator simulates the instances of the Machines. The state of the instances is kept in the annotations of
+// their Machine, for it to survive the restarts of the controller.
+type Actuator struct {
+	client            runtimeclient.Client
+	eventRecorder     record.EventRecorder
+	provisioningDelay time.Duration
+	deletionDelay     time.Duration
+
+	// nowFunc is used to mock time in testing. It should be nil in production.
+	nowFunc func() time.Time
+}
+
+// ActuatorParams holds parameter information for Actuator.
+type ActuatorParams struct {
+	Client            runtimeclient.Client
+	EventRecorder     record.EventRecorder
+	ProvisioningDelay time.Duration
+	DeletionDelay     time.Duration
+}
+
+// NewActuator returns an actuator.
+func NewActuator(params ActuatorParams) *Actuator {
+	return &Actuator{
+		client:            params.Client,
+		eventRecorder:     params.EventRecorder,
+		provisioningDelay: params.ProvisioningDelay,
+		deletionDelay:     params.DeletionDelay,
+	}
+}
+
+func (a *Actuator) now() time.Time {
+	if a.nowFunc != nil {
+		return a.nowFunc()
+	}
+	return time.Now()
+}
+
+// Create creates the fake instance of the machine, which is provisioned after the provisioning delay.
+func (a *Actuator) Create(ctx context.Context, machine *machinev1.Machine) error {
+	klog.Infof("%s: actuator creating fake instance", machine.GetName())
+	if msg := machine.Annotations[InvalidConfigurationAnnotation]; msg != "" {
+		return a.handleMachineError(machine, machinecontroller.InvalidMachineConfiguration("%s", msg), "Create")
+	}
+	if msg := machine.Annotations[CreateErrorAnnotation]; msg != "" {
+		return a.handleMachineError(machine, machinecontroller.CreateMachine("%s", msg), "Create")
+	}
+
+	providerID := ProviderIDPrefix + machine.Namespace + "/" + machine.Name
+	machineToBePatched := runtimeclient.MergeFrom(machine.DeepCopy())
+	machine.Spec.ProviderID = &providerID
+	setAnnotations(machine, map[string]string{
+		createdAtAnnotation:                                  a.now().UTC().Format(time.RFC3339),
+		currentInstanceTypeAnnotation:                        machine.Annotations[InstanceTypeAnnotation],
+		machinecontroller.MachineInstanceStateAnnotationName: instanceStatePending,
+	})
+	if err := a.patchMachine(ctx, machine, machineToBePatched); err != nil {
+		return fmt.Errorf("%s: failed to record fake instance: %w", machine.GetName(), err)
+	}
+
+	a.eventRecorder.Eventf(machine, corev1.EventTypeNormal, "Created", "Created fake instance %s", providerID)
+	return nil
+}
+
+// Exists returns true until the fake instance of the machine is terminated.
+func (a *Actuator) Exists(ctx context.Context, machine *machinev1.Machine) (bool, error) {
+	if machine.Annotations[createdAtAnnotation] == "" {
+		return false, nil
+	}
+	return machine.Annotations[machinecontroller.MachineInstanceStateAnnotationName] != instanceStateTerminated, nil
+}
+
+// Update registers the Node of the fake instance once it is provisioned, and reflects the health of the
+// machine in the Node. The Node of a stopped instance is left alone.
+func (a *Actuator) Update(ctx context.Context, machine *machinev1.Machine) error {
+	klog.Infof("%s: actuator updating fake instance", machine.GetName())
+	if msg := machine.Annotations[UpdateErrorAnnotation]; msg != "" {
+		return a.handleMachineError(machine, machinecontroller.UpdateMachine("%s", msg), "Update")
+	}
+
+	state := machine.Annotations[machinecontroller.MachineInstanceStateAnnotationName]
+	if state == instanceStatePending {
+		createdAt, err := time.Parse(time.RFC3339, machine.Annotations[createdAtAnnotation])
+		if err != nil {
+			return machinecontroller.UpdateMachine("invalid %s annotation: %v", createdAtAnnotation, err)
+		}
+		if a.now().Before(createdAt.Add(a.delay(machine, ProvisioningDelayAnnotation, a.provisioningDelay))) {
+			klog.V(3).Infof("%s: fake instance is being provisioned", machine.GetName())
+			return nil
+		}
+	}
+	if state != instanceStatePending && state != instanceStateRunning {
+		return nil
+	}
+
+	if err := a.reconcileNode(ctx, machine); err != nil {
+		return a.handleMachineError(machine, machinecontroller.UpdateMachine("failed to register node: %v", err), "Update")
+	}
+
+	machineToBePatched := runtimeclient.MergeFrom(machine.DeepCopy())
+	setAnnotations(machine, map[string]string{machinecontroller.MachineInstanceStateAnnotationName: instanceStateRunning})
+	machine.Status.Addresses = a.addresses(machine)
+	if err := a.patchMachine(ctx, machine, machineToBePatched); err != nil {
+		return fmt.Errorf("%s: failed to update fake instance: %w", machine.GetName(), err)
+	}
+	return nil
+}
+
+// Delete terminates the fake instance of the machine, which is terminated after the deletion delay.
+func (a *Actuator) Delete(ctx context.Context, machine *machinev1.Machine) error {
+	klog.Infof("%s: actuator deleting fake instance", machine.GetName())
+	if msg := machine.Annotations[DeleteErrorAnnotation]; msg != "" {
+		return a.handleMachineError(machine, machinecontroller.DeleteMachine("%s", msg), "Delete")
+	}
+
+	switch machine.Annotations[machinecontroller.MachineInstanceStateAnnotationName] {
+	case instanceStateTerminated:
+		return nil
+	case instanceStateTerminating:
+		deletedAt, err := time.Parse(time.RFC3339, machine.Annotations[deletedAtAnnotation])
+		if err != nil {
+			return machinecontroller.DeleteMachine("invalid %s annotation: %v", deletedAtAnnotation, err)
+		}
+		if a.now().Before(deletedAt.Add(a.delay(machine, DeletionDelayAnnotation, a.deletionDelay))) {
+			klog.V(3).Infof("%s: fake instance is being terminated", machine.GetName())
+			return nil
+		}
+		// The Node of a Machine is only deleted by the machine controller once it is linked to the Machine.
+		if err := a.client.Delete(ctx, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName(machine)}}); err != nil && !apierrors.IsNotFound(err) {
+			return a.handleMachineError(machine, machinecontroller.DeleteMachine("failed to delete node: %v", err), "Delete")
+		}
+		return a.setInstanceState(ctx, machine, instanceStateTerminated, nil)
+	}
+
+	if machine.Annotations[createdAtAnnotation] == "" {
+		// The instance was never created.
+		return nil
+	}
+	return a.setInstanceState(ctx, machine, instanceStateTerminating, map[string]string{
+		deletedAtAnnotation: a.now().UTC().Format(time.RFC3339),
+	})
+}
+
+// setInstanceState records the state of the fake instance in the annotations of the machine.
+func (a *Actuator) setInstanceState(ctx context.Context, machine *machinev1.Machine, state string, annotations map[string]string) error {
+	machineToBePatched := runtimeclient.MergeFrom(machine.DeepCopy())
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[machinecontroller.MachineInstanceStateAnnotationName] = state
+	setAnnotations(machine, annotations)
+	if err := a.client.Patch(ctx, machine, machineToBePatched); err != nil {
+		return fmt.Errorf("%s: failed to set fake instance state to %s: %w", machine.GetName(), state, err)
+	}
+	klog.Infof("%s: fake instance is %s", machine.GetName(), state)
+	return nil
+}
+
+// reconcileNode registers the Node of the fake instance, with a Ready condition reflecting the health of the machine,
+// and renews its Lease as a kubelet would. The Ready condition of the Node of a stopped instance is Unknown, as
+// its kubelet stopped posting the status of the Node.
+func (a *Actuator) reconcileNode(ctx context.Context, machine *machinev1.Machine) error {
+	node := &corev1.Node{}
+	err := a.client.Get(ctx, runtimeclient.ObjectKey{Name: nodeName(machine)}, node)
+	if apierrors.IsNotFound(err) {
+		node = &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: nodeName(machine),
+				Labels: map[string]string{
+					corev1.LabelHostname: nodeName(machine),
+				},
+			},
+			Spec: corev1.NodeSpec{
+				ProviderID: *machine.Spec.ProviderID,
+			},
+		}
+		if err := a.client.Create(ctx, node); err != nil {
+			return err
+		}
+		klog.Infof("%s: registered node %s", machine.GetName(), node.Name)
+	} else if err != nil {
+		return err
+	}
+
+	ready := corev1.NodeCondition{
+		Type:               corev1.NodeReady,
+		Status:             corev1.ConditionTrue,
+		Reason:             "KubeletReady",
+		Message:            "fake instance is ready",
+		LastHeartbeatTime:  metav1.NewTime(a.now()),
+		LastTransitionTime: metav1.NewTime(a.now()),
+	}
+	switch {
+	case machine.Annotations[machinecontroller.MachineInstanceStateAnnotationName] == instanceStateStopped:
+		ready.Status = corev1.ConditionUnknown
+		ready.Reason = "NodeStatusUnknown"
+		ready.Message = "fake instance is powered off"
+	case machine.Annotations[NodeUnhealthyAnnotation] == "true":
+		ready.Status = corev1.ConditionFalse
+		ready.Reason = "KubeletNotReady"
+		ready.Message = fmt.Sprintf("fake instance made unhealthy with the %s annotation", NodeUnhealthyAnnotation)
+	}
+
+	nodeToBePatched := runtimeclient.MergeFrom(node.DeepCopy())
+	conditions := []corev1.NodeCondition{ready}
+	for _, condition := range node.Status.Conditions {
+		switch {
+		case condition.Type != corev1.NodeReady:
+			conditions = append(conditions, condition)
+		case condition.Status == ready.Status:
+			ready.LastTransitionTime = condition.LastTransitionTime
+			conditions[0] = ready
+		}
+	}
+	node.Status.Conditions = conditions
+	node.Status.Addresses = a.addresses(machine)
+	if err := a.client.Status().Patch(ctx, node, nodeToBePatched); err != nil {
+		return err
+	}
+	if ready.Status == corev1.ConditionUnknown {
+		return nil
+	}
+	return a.renewNodeLease(ctx, node)
+}
+
+// addresses returns the addresses of the fake instance, with an internal IP derived from the name of its machine.
+func (a *Actuator) addresses(machine *machinev1.Machine) []corev1.NodeAddress {
+	h := fnv.New32a()
+	h.Write([]byte(machine.Namespace + "/" + machine.Name))
+	sum := h.Sum32()
+	ip := net.IPv4(10, byte(sum>>16), byte(sum>>8), byte(sum))
+	return []corev1.NodeAddress{
+		{Type: corev1.NodeInternalIP, Address: ip.String()},
+		{Type: corev1.NodeInternalDNS, Address: nodeName(machine)},
+		{Type: corev1.NodeHostName, Address: nodeName(machine)},
+	}
+}
+
+// delay returns the delay of the annotation of the machine, or the default one when it is not set or invalid.
+func (a *Actuator) delay(machine *machinev1.Machine, annotation string, defaultDelay time.Duration) time.Duration {
+	value, ok := machine.Annotations[annotation]
+	if !ok {
+		return defaultDelay
+	}
+	delay, err := time.ParseDuration(value)
+	if err != nil {
+		klog.Warningf("%s: invalid %s annotation %q, using %v: %v", machine.GetName(), annotation, value, defaultDelay, err)
+		return defaultDelay
+	}
+	return delay
+}
+
+// patchMachine patches the metadata, the spec and the status of the machine.
+func (a *Actuator) patchMachine(ctx context.Context, machine *machinev1.Machine, machineToBePatched runtimeclient.Patch) error {
+	statusCopy := *machine.Status.DeepCopy()
+	if err := a.client.Patch(ctx, machine, machineToBePatched); err != nil {
+		return err
+	}
+	machine.Status = statusCopy
+	return a.client.Status().Patch(ctx, machine, machineToBePatched)
+}
+
+// handleMachineError records an event for the error, and returns it.
+func (a *Actuator) handleMachineError(machine *machinev1.Machine, err error, eventAction string) error {
+	klog.Errorf("%v error: %v", machine.GetName(), err)
+	a.eventRecorder.Eventf(machine, corev1.EventTypeWarning, "Failed"+eventAction, "%v", err)
+	return err
+}
+
+// nodeName returns the name of the Node of the fake instance of the machine.
+func nodeName(machine *machinev1.Machine) string {
+	return machine.Name
+}
+
+// setAnnotations sets the annotations on the machine.
+func setAnnotations(machine *machinev1.Machine, annotations map[string]string) {
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	for k, v := range annotations {
+		machine.Annotations[k] = v
+	}
+}
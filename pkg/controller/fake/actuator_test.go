@@ -0,0 +1,183 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+)
+
+func init() {
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		klog.Fatal(err)
+	}
+}
+
+func newMachine(annotations map[string]string) *machinev1.Machine {
+	return &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "worker-a1b2c",
+			Namespace:   "openshift-machine-api",
+			Annotations: annotations,
+		},
+	}
+}
+
+func newTestActuator(machine *machinev1.Machine, now *time.Time) *Actuator {
+	a := NewActuator(ActuatorParams{
+		Client:            fakeclient.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(machine).Build(),
+		EventRecorder:     record.NewFakeRecorder(10),
+		ProvisioningDelay: DefaultProvisioningDelay,
+		DeletionDelay:     DefaultDeletionDelay,
+	})
+	a.nowFunc = func() time.Time { return *now }
+	return a
+}
+
+func TestActuatorLifecycle(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	now := time.Now().Truncate(time.Second)
+	machine := newMachine(nil)
+	a := newTestActuator(machine, &now)
+	node := &corev1.Node{}
+	nodeKey := runtimeclient.ObjectKey{Name: machine.Name}
+
+	exists, err := a.Exists(ctx, machine)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(exists).To(BeFalse())
+
+	// The instance is created, without a Node until it is provisioned.
+	g.Expect(a.Create(ctx, machine)).To(Succeed())
+	g.Expect(machine.Spec.ProviderID).To(HaveValue(Equal("fake:///openshift-machine-api/worker-a1b2c")))
+	g.Expect(machine.Annotations).To(HaveKeyWithValue(machinecontroller.MachineInstanceStateAnnotationName, instanceStatePending))
+	exists, err = a.Exists(ctx, machine)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(exists).To(BeTrue())
+
+	g.Expect(a.Update(ctx, machine)).To(Succeed())
+	g.Expect(apierrors.IsNotFound(a.client.Get(ctx, nodeKey, node))).To(BeTrue())
+
+	// Once provisioned, its Node is registered and ready.
+	now = now.Add(DefaultProvisioningDelay)
+	g.Expect(a.Update(ctx, machine)).To(Succeed())
+	g.Expect(machine.Annotations).To(HaveKeyWithValue(machinecontroller.MachineInstanceStateAnnotationName, instanceStateRunning))
+	g.Expect(machine.Status.Addresses).To(ContainElement(HaveField("Type", corev1.NodeInternalIP)))
+	g.Expect(a.client.Get(ctx, nodeKey, node)).To(Succeed())
+	g.Expect(node.Spec.ProviderID).To(Equal(*machine.Spec.ProviderID))
+	g.Expect(node.Status.Addresses).To(Equal(machine.Status.Addresses))
+	g.Expect(node.Status.Conditions).To(ConsistOf(SatisfyAll(
+		HaveField("Type", corev1.NodeReady),
+		HaveField("Status", corev1.ConditionTrue),
+	)))
+
+	// The Node is made unhealthy with the annotation.
+	machine.Annotations[NodeUnhealthyAnnotation] = "true"
+	g.Expect(a.Update(ctx, machine)).To(Succeed())
+	g.Expect(a.client.Get(ctx, nodeKey, node)).To(Succeed())
+	g.Expect(node.Status.Conditions).To(ConsistOf(SatisfyAll(
+		HaveField("Type", corev1.NodeReady),
+		HaveField("Status", corev1.ConditionFalse),
+	)))
+
+	// The instance is terminated after the deletion delay, along with its Node.
+	g.Expect(a.Delete(ctx, machine)).To(Succeed())
+	g.Expect(machine.Annotations).To(HaveKeyWithValue(machinecontroller.MachineInstanceStateAnnotationName, instanceStateTerminating))
+	exists, err = a.Exists(ctx, machine)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(exists).To(BeTrue())
+
+	now = now.Add(DefaultDeletionDelay)
+	g.Expect(a.Delete(ctx, machine)).To(Succeed())
+	exists, err = a.Exists(ctx, machine)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(exists).To(BeFalse())
+	g.Expect(apierrors.IsNotFound(a.client.Get(ctx, nodeKey, node))).To(BeTrue())
+}
+
+func TestActuatorDelayAnnotations(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	now := time.Now().Truncate(time.Second)
+	machine := newMachine(map[string]string{ProvisioningDelayAnnotation: "1m"})
+	a := newTestActuator(machine, &now)
+
+	g.Expect(a.Create(ctx, machine)).To(Succeed())
+	now = now.Add(DefaultProvisioningDelay)
+	g.Expect(a.Update(ctx, machine)).To(Succeed())
+	g.Expect(machine.Annotations).To(HaveKeyWithValue(machinecontroller.MachineInstanceStateAnnotationName, instanceStatePending))
+
+	now = now.Add(time.Minute)
+	g.Expect(a.Update(ctx, machine)).To(Succeed())
+	g.Expect(machine.Annotations).To(HaveKeyWithValue(machinecontroller.MachineInstanceStateAnnotationName, instanceStateRunning))
+}
+
+func TestActuatorInjectedFailures(t *testing.T) {
+	testCases := []struct {
+		name          string
+		annotation    string
+		call          func(context.Context, *Actuator, *machinev1.Machine) error
+		expectInvalid bool
+	}{
+		{
+			name:       "with a create error",
+			annotation: CreateErrorAnnotation,
+			call: func(ctx context.Context, a *Actuator, m *machinev1.Machine) error {
+				return a.Create(ctx, m)
+			},
+		},
+		{
+			name:       "with an invalid configuration",
+			annotation: InvalidConfigurationAnnotation,
+			call: func(ctx context.Context, a *Actuator, m *machinev1.Machine) error {
+				return a.Create(ctx, m)
+			},
+			expectInvalid: true,
+		},
+		{
+			name:       "with an update error",
+			annotation: UpdateErrorAnnotation,
+			call: func(ctx context.Context, a *Actuator, m *machinev1.Machine) error {
+				return a.Update(ctx, m)
+			},
+		},
+		{
+			name:       "with a delete error",
+			annotation: DeleteErrorAnnotation,
+			call: func(ctx context.Context, a *Actuator, m *machinev1.Machine) error {
+				return a.Delete(ctx, m)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			now := time.Now()
+			machine := newMachine(map[string]string{tc.annotation: "quota exceeded"})
+			a := newTestActuator(machine, &now)
+
+			err := tc.call(context.TODO(), a, machine)
+			g.Expect(err).To(MatchError("quota exceeded"))
+			g.Expect(err).To(BeAssignableToTypeOf(&machinecontroller.MachineError{}))
+			if tc.expectInvalid {
+				g.Expect(err.(*machinecontroller.MachineError).Reason).To(Equal(machinev1.InvalidConfigurationMachineError))
+			}
+		})
+	}
+}
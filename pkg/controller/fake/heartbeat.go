@@ -0,0 +1,114 @@
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+)
+
+const (
+	// HeartbeatInterval is the interval between the heartbeats of the Nodes of the running fake instances, the
+	// interval at which a kubelet renews its Lease.
+	HeartbeatInterval = 10 * time.Second
+
+	// nodeLeaseDurationSeconds is the duration of the Leases of the Nodes, the default one of a kubelet.
+	nodeLeaseDurationSeconds = 40
+)
+
+// Start implements the manager.Runnable interface. It heartbeats the Nodes of the running fake instances until
+// the context is done, so that the node lifecycle controller does not mark them as not ready between the reconciles
+// of their Machine. As a Runnable, it only runs on the leader.
+func (a *Actuator) Start(ctx context.Context) error {
+	klog.Infof("Heartbeating the nodes of the fake instances every %v", HeartbeatInterval)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := a.heartbeat(ctx); err != nil {
+			klog.Errorf("Failed to heartbeat the nodes of the fake instances: %v", err)
+		}
+	}, HeartbeatInterval)
+	return nil
+}
+
+// heartbeat refreshes the Ready condition and renews the Lease of the Nodes of the running fake instances.
+func (a *Actuator) heartbeat(ctx context.Context) error {
+	machineList := &machinev1.MachineList{}
+	if err := a.client.List(ctx, machineList); err != nil {
+		return fmt.Errorf("failed to list machines: %w", err)
+	}
+
+	var errs []error
+	for i := range machineList.Items {
+		machine := &machineList.Items[i]
+		if !isFakeInstance(machine) || machine.Annotations[machinecontroller.MachineInstanceStateAnnotationName] != instanceStateRunning {
+			continue
+		}
+		if err := a.reconcileNode(ctx, machine); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", machine.GetName(), err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// renewNodeLease renews the Lease of the Node in the kube-node-lease namespace, creating it when needed.
+// The Lease is patched without being read, so that the controller only needs to create and patch the Leases of
+// that namespace, see docs/examples/fake-platform-rbac.yaml.
+func (a *Actuator) renewNodeLease(ctx context.Context, node *corev1.Node) error {
+	renewTime := metav1.MicroTime{Time: a.now()}
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"renewTime": renewTime,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: corev1.NamespaceNodeLease,
+			Name:      node.Name,
+		},
+	}
+	if err := a.client.Patch(ctx, lease, runtimeclient.RawPatch(types.MergePatchType, patch)); !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	lease = &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: corev1.NamespaceNodeLease,
+			Name:      node.Name,
+			// The Lease is garbage collected with the Node, as the ones of the kubelets.
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: corev1.SchemeGroupVersion.String(),
+				Kind:       "Node",
+				Name:       node.Name,
+				UID:        node.UID,
+			}},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       pointer.String(node.Name),
+			LeaseDurationSeconds: pointer.Int32(nodeLeaseDurationSeconds),
+			RenewTime:            &renewTime,
+		},
+	}
+	return a.client.Create(ctx, lease)
+}
+
+// isFakeInstance returns true if the machine has a fake instance.
+func isFakeInstance(machine *machinev1.Machine) bool {
+	return machine.Spec.ProviderID != nil && strings.HasPrefix(*machine.Spec.ProviderID, ProviderIDPrefix)
+}
@@ -0,0 +1,81 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+)
+
+// newRunningInstance returns an actuator with the fake instance of the machine created and provisioned.
+func newRunningInstance(g *WithT, ctx context.Context, annotations map[string]string, now *time.Time) (*Actuator, *corev1.Node) {
+	machine := newMachine(annotations)
+	a := newTestActuator(machine, now)
+	g.Expect(a.Create(ctx, machine)).To(Succeed())
+	*now = now.Add(DefaultProvisioningDelay)
+	g.Expect(a.Update(ctx, machine)).To(Succeed())
+	g.Expect(machine.Annotations).To(HaveKeyWithValue(machinecontroller.MachineInstanceStateAnnotationName, instanceStateRunning))
+
+	node := &corev1.Node{}
+	g.Expect(a.client.Get(ctx, runtimeclient.ObjectKey{Name: machine.Name}, node)).To(Succeed())
+	return a, node
+}
+
+func TestHeartbeat(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	now := time.Now().Truncate(time.Second)
+	a, node := newRunningInstance(g, ctx, nil, &now)
+	nodeKey := runtimeclient.ObjectKeyFromObject(node)
+	leaseKey := runtimeclient.ObjectKey{Namespace: corev1.NamespaceNodeLease, Name: node.Name}
+
+	// The Lease of the Node is created with it, owned by the Node.
+	lease := &coordinationv1.Lease{}
+	g.Expect(a.client.Get(ctx, leaseKey, lease)).To(Succeed())
+	g.Expect(lease.Spec.HolderIdentity).To(HaveValue(Equal(node.Name)))
+	g.Expect(lease.Spec.LeaseDurationSeconds).To(HaveValue(BeEquivalentTo(nodeLeaseDurationSeconds)))
+	g.Expect(lease.Spec.RenewTime.Time).To(BeTemporally("==", now))
+	g.Expect(lease.OwnerReferences).To(ConsistOf(HaveField("Kind", "Node")))
+
+	// The heartbeats renew the Lease and refresh the Ready condition without reconciling the Machine.
+	transitionTime := node.Status.Conditions[0].LastTransitionTime
+	now = now.Add(time.Minute)
+	g.Expect(a.heartbeat(ctx)).To(Succeed())
+	g.Expect(a.client.Get(ctx, leaseKey, lease)).To(Succeed())
+	g.Expect(lease.Spec.RenewTime.Time).To(BeTemporally("==", now))
+	g.Expect(a.client.Get(ctx, nodeKey, node)).To(Succeed())
+	g.Expect(node.Status.Conditions).To(ConsistOf(SatisfyAll(
+		HaveField("Type", corev1.NodeReady),
+		HaveField("Status", corev1.ConditionTrue),
+		HaveField("LastHeartbeatTime.Time", BeTemporally("==", now)),
+		HaveField("LastTransitionTime", Equal(transitionTime)),
+	)))
+}
+
+func TestHeartbeatSkipsStoppedInstances(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	now := time.Now().Truncate(time.Second)
+	a, node := newRunningInstance(g, ctx, nil, &now)
+	machine := newMachine(nil)
+	g.Expect(a.client.Get(ctx, runtimeclient.ObjectKeyFromObject(machine), machine)).To(Succeed())
+	g.Expect(a.SetPowerState(ctx, machine, machinecontroller.MachinePowerStateOff)).To(Succeed())
+
+	leaseKey := runtimeclient.ObjectKey{Namespace: corev1.NamespaceNodeLease, Name: node.Name}
+	lease := &coordinationv1.Lease{}
+	g.Expect(a.client.Get(ctx, leaseKey, lease)).To(Succeed())
+	renewTime := lease.Spec.RenewTime.Time
+
+	now = now.Add(time.Minute)
+	g.Expect(a.heartbeat(ctx)).To(Succeed())
+	g.Expect(a.client.Get(ctx, leaseKey, lease)).To(Succeed())
+	g.Expect(lease.Spec.RenewTime.Time).To(BeTemporally("==", renewTime))
+}
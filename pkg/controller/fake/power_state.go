@@ -0,0 +1,67 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+)
+
+// SetPowerState stops or starts the fake instance of the machine. The Node of a stopped instance is not
+// heartbeated anymore, and its Ready condition is Unknown until the instance is started again.
+func (a *Actuator) SetPowerState(ctx context.Context, machine *machinev1.Machine, state machinecontroller.MachinePowerState) error {
+	klog.Infof("%s: actuator powering %s fake instance", machine.GetName(), state)
+	current := machine.Annotations[machinecontroller.MachineInstanceStateAnnotationName]
+	if current != instanceStateRunning && current != instanceStateStopped {
+		return fmt.Errorf("fake instance is %s", current)
+	}
+
+	target := instanceStateRunning
+	if state == machinecontroller.MachinePowerStateOff {
+		target = instanceStateStopped
+	}
+	if current != target {
+		if err := a.setInstanceState(ctx, machine, target, nil); err != nil {
+			return err
+		}
+	}
+	return a.reconcileNode(ctx, machine)
+}
+
+// NeedsResize returns true if the instance type set by the InstanceTypeAnnotation differs from the one of the
+// fake instance.
+func (a *Actuator) NeedsResize(ctx context.Context, machine *machinev1.Machine) (bool, error) {
+	return machine.Annotations[InstanceTypeAnnotation] != machine.Annotations[currentInstanceTypeAnnotation], nil
+}
+
+// Resize changes the instance type of the stopped fake instance to the one set by the InstanceTypeAnnotation.
+func (a *Actuator) Resize(ctx context.Context, machine *machinev1.Machine) error {
+	if state := machine.Annotations[machinecontroller.MachineInstanceStateAnnotationName]; state != instanceStateStopped {
+		return fmt.Errorf("fake instance is %s, it must be stopped to be resized", state)
+	}
+	instanceType := machine.Annotations[InstanceTypeAnnotation]
+	if err := a.setInstanceState(ctx, machine, instanceStateStopped, map[string]string{currentInstanceTypeAnnotation: instanceType}); err != nil {
+		return err
+	}
+	a.eventRecorder.Eventf(machine, corev1.EventTypeNormal, "Resized", "Resized fake instance to %q", instanceType)
+	return nil
+}
+
+// Reboot reboots the running fake instance of the machine, recording when it was rebooted.
+func (a *Actuator) Reboot(ctx context.Context, machine *machinev1.Machine) error {
+	if state := machine.Annotations[machinecontroller.MachineInstanceStateAnnotationName]; state != instanceStateRunning {
+		return fmt.Errorf("fake instance is %s, it must be running to be rebooted", state)
+	}
+	if err := a.setInstanceState(ctx, machine, instanceStateRunning, map[string]string{
+		rebootedAtAnnotation: a.now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		return err
+	}
+	a.eventRecorder.Eventf(machine, corev1.EventTypeNormal, "Rebooted", "Rebooted fake instance")
+	return nil
+}
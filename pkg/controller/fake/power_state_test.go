@@ -0,0 +1,102 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+)
+
+func TestSetPowerState(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	now := time.Now().Truncate(time.Second)
+	a, node := newRunningInstance(g, ctx, nil, &now)
+	nodeKey := runtimeclient.ObjectKeyFromObject(node)
+	machine := newMachine(nil)
+	g.Expect(a.client.Get(ctx, runtimeclient.ObjectKeyFromObject(machine), machine)).To(Succeed())
+
+	// The Node of the stopped instance is not ready anymore.
+	g.Expect(a.SetPowerState(ctx, machine, machinecontroller.MachinePowerStateOff)).To(Succeed())
+	g.Expect(machine.Annotations).To(HaveKeyWithValue(machinecontroller.MachineInstanceStateAnnotationName, instanceStateStopped))
+	exists, err := a.Exists(ctx, machine)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(exists).To(BeTrue())
+	g.Expect(a.client.Get(ctx, nodeKey, node)).To(Succeed())
+	g.Expect(node.Status.Conditions).To(ConsistOf(SatisfyAll(
+		HaveField("Type", corev1.NodeReady),
+		HaveField("Status", corev1.ConditionUnknown),
+	)))
+
+	// Powering off is idempotent, and the Node is left alone by the updates while the instance is stopped.
+	g.Expect(a.SetPowerState(ctx, machine, machinecontroller.MachinePowerStateOff)).To(Succeed())
+	g.Expect(a.Update(ctx, machine)).To(Succeed())
+	g.Expect(machine.Annotations).To(HaveKeyWithValue(machinecontroller.MachineInstanceStateAnnotationName, instanceStateStopped))
+
+	// The Node is ready again once the instance is started.
+	g.Expect(a.SetPowerState(ctx, machine, machinecontroller.MachinePowerStateOn)).To(Succeed())
+	g.Expect(machine.Annotations).To(HaveKeyWithValue(machinecontroller.MachineInstanceStateAnnotationName, instanceStateRunning))
+	g.Expect(a.client.Get(ctx, nodeKey, node)).To(Succeed())
+	g.Expect(node.Status.Conditions).To(ConsistOf(SatisfyAll(
+		HaveField("Type", corev1.NodeReady),
+		HaveField("Status", corev1.ConditionTrue),
+	)))
+
+	// Instances which are not provisioned cannot be powered off.
+	pending := newMachine(nil)
+	pending.Name = "worker-d3e4f"
+	g.Expect(a.client.Create(ctx, pending)).To(Succeed())
+	g.Expect(a.Create(ctx, pending)).To(Succeed())
+	g.Expect(a.SetPowerState(ctx, pending, machinecontroller.MachinePowerStateOff)).To(MatchError("fake instance is pending"))
+}
+
+func TestResize(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	now := time.Now().Truncate(time.Second)
+	a, _ := newRunningInstance(g, ctx, map[string]string{InstanceTypeAnnotation: "small"}, &now)
+	machine := newMachine(nil)
+	g.Expect(a.client.Get(ctx, runtimeclient.ObjectKeyFromObject(machine), machine)).To(Succeed())
+
+	needsResize, err := a.NeedsResize(ctx, machine)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(needsResize).To(BeFalse())
+
+	machine.Annotations[InstanceTypeAnnotation] = "large"
+	g.Expect(a.client.Update(ctx, machine)).To(Succeed())
+	needsResize, err = a.NeedsResize(ctx, machine)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(needsResize).To(BeTrue())
+
+	// The instance must be stopped to be resized.
+	g.Expect(a.Resize(ctx, machine)).To(MatchError("fake instance is running, it must be stopped to be resized"))
+	g.Expect(a.SetPowerState(ctx, machine, machinecontroller.MachinePowerStateOff)).To(Succeed())
+	g.Expect(a.Resize(ctx, machine)).To(Succeed())
+	g.Expect(machine.Annotations).To(HaveKeyWithValue(currentInstanceTypeAnnotation, "large"))
+	needsResize, err = a.NeedsResize(ctx, machine)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(needsResize).To(BeFalse())
+}
+
+func TestReboot(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	now := time.Now().Truncate(time.Second)
+	a, _ := newRunningInstance(g, ctx, nil, &now)
+	machine := newMachine(nil)
+	g.Expect(a.client.Get(ctx, runtimeclient.ObjectKeyFromObject(machine), machine)).To(Succeed())
+
+	g.Expect(a.Reboot(ctx, machine)).To(Succeed())
+	g.Expect(machine.Annotations).To(HaveKeyWithValue(rebootedAtAnnotation, now.UTC().Format(time.RFC3339)))
+
+	g.Expect(a.SetPowerState(ctx, machine, machinecontroller.MachinePowerStateOff)).To(Succeed())
+	g.Expect(a.Reboot(ctx, machine)).To(MatchError("fake instance is stopped, it must be running to be rebooted"))
+}
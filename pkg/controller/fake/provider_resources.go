@@ -0,0 +1,51 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+)
+
+// ListProviderResources lists the fake instances, from the Nodes they registered. Deleting a Machine without
+// its finalizer leaves its fake instance behind, to be collected as an orphan.
+func (a *Actuator) ListProviderResources(ctx context.Context) ([]machinecontroller.ProviderResource, error) {
+	nodeList := &corev1.NodeList{}
+	if err := a.client.List(ctx, nodeList); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	var resources []machinecontroller.ProviderResource
+	for _, node := range nodeList.Items {
+		if !strings.HasPrefix(node.Spec.ProviderID, ProviderIDPrefix) {
+			continue
+		}
+		resources = append(resources, machinecontroller.ProviderResource{
+			Kind:       machinecontroller.ProviderResourceInstance,
+			ID:         node.Name,
+			ProviderID: node.Spec.ProviderID,
+			// The provider ID of a fake instance ends with the name of its machine.
+			MachineName:  path.Base(node.Spec.ProviderID),
+			CreationTime: node.CreationTimestamp.Time,
+		})
+	}
+	return resources, nil
+}
+
+// DeleteProviderResource terminates the fake instance, deleting its Node. The Lease of the Node is
+// garbage collected with it.
+func (a *Actuator) DeleteProviderResource(ctx context.Context, resource machinecontroller.ProviderResource) error {
+	if resource.Kind != machinecontroller.ProviderResourceInstance {
+		return fmt.Errorf("unsupported provider resource kind %q", resource.Kind)
+	}
+	if err := a.client.Delete(ctx, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: resource.ID}}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete node %s: %w", resource.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+)
+
+func TestProviderResources(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	now := time.Now().Truncate(time.Second)
+	a, node := newRunningInstance(g, ctx, nil, &now)
+	// The Nodes which are not of fake instances are ignored.
+	g.Expect(a.client.Create(ctx, &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "kind-control-plane"},
+		Spec:       corev1.NodeSpec{ProviderID: "kind://docker/kind/kind-control-plane"},
+	})).To(Succeed())
+
+	resources, err := a.ListProviderResources(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(resources).To(ConsistOf(machinecontroller.ProviderResource{
+		Kind:         machinecontroller.ProviderResourceInstance,
+		ID:           node.Name,
+		ProviderID:   "fake:///openshift-machine-api/worker-a1b2c",
+		MachineName:  "worker-a1b2c",
+		CreationTime: node.CreationTimestamp.Time,
+	}))
+
+	g.Expect(a.DeleteProviderResource(ctx, resources[0])).To(Succeed())
+	g.Expect(apierrors.IsNotFound(a.client.Get(ctx, runtimeclient.ObjectKeyFromObject(node), node))).To(BeTrue())
+	// Deleting is idempotent.
+	g.Expect(a.DeleteProviderResource(ctx, resources[0])).To(Succeed())
+	g.Expect(a.DeleteProviderResource(ctx, machinecontroller.ProviderResource{Kind: machinecontroller.ProviderResourceDisk, ID: "disk"})).
+		To(MatchError(`unsupported provider resource kind "Disk"`))
+}
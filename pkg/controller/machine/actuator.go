@@ -18,6 +18,7 @@ package machine
 
 import (
 	"context"
+	"errors"
 
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 )
@@ -34,3 +35,59 @@ type Actuator interface {
 	// Checks if the machine currently exists.
 	Exists(context.Context, *machinev1.Machine) (bool, error)
 }
+
+// PowerStateActuator is optionally implemented by Actuators which can power the
+// instance of a machine off and back on without deleting it.
+type PowerStateActuator interface {
+	// SetPowerState powers the machine on or off.
+	SetPowerState(context.Context, *machinev1.Machine, MachinePowerState) error
+}
+
+// ResizeActuator is optionally implemented by PowerStateActuators which can change the
+// instance type of a stopped instance, letting the instance of a machine be resized in place.
+type ResizeActuator interface {
+	PowerStateActuator
+	// NeedsResize returns true if the instance type of the providerSpec differs from the one of the instance.
+	NeedsResize(context.Context, *machinev1.Machine) (bool, error)
+	// Resize changes the instance type of the stopped instance to the one of the providerSpec.
+	Resize(context.Context, *machinev1.Machine) error
+}
+
+// RebootActuator is optionally implemented by PowerStateActuators which can
+// reboot the instance of a machine through the cloud provider.
+type RebootActuator interface {
+	PowerStateActuator
+	// Reboot reboots the instance of the machine.
+	Reboot(context.Context, *machinev1.Machine) error
+}
+
+// ProviderResourceActuator is optionally implemented by Actuators which can list the resources
+// created for the machines of the cluster, letting the resources leaked without a machine be collected.
+type ProviderResourceActuator interface {
+	// ListProviderResources lists the instances, network interfaces and disks tagged with the cluster ID.
+	ListProviderResources(context.Context) ([]ProviderResource, error)
+	// DeleteProviderResource deletes the resource.
+	DeleteProviderResource(context.Context, ProviderResource) error
+}
+
+// wrappingActuator is implemented by the actuators wrapping the actuator of the platform. They implement all the
+// optional actuator interfaces, and pass the calls through when the wrapped actuator supports them.
+type wrappingActuator interface {
+	Unwrap() Actuator
+}
+
+// unwrapActuator returns the actuator of the platform wrapped by the actuator, which tells the optional actuator
+// interfaces supported by the platform.
+func unwrapActuator(actuator Actuator) Actuator {
+	for {
+		w, ok := actuator.(wrappingActuator)
+		if !ok {
+			return actuator
+		}
+		actuator = w.Unwrap()
+	}
+}
+
+// errUnsupportedOperation is returned by the wrapping actuators when the wrapped actuator does not implement the
+// optional actuator interface of the operation.
+var errUnsupportedOperation = errors.New("operation not supported by the actuator")
@@ -21,12 +21,16 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	"github.com/openshift/machine-api-operator/pkg/metrics"
 	"github.com/openshift/machine-api-operator/pkg/util"
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
 	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+	"github.com/openshift/machine-api-operator/pkg/util/logging"
+	"github.com/openshift/machine-api-operator/pkg/util/tracing"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -35,19 +39,22 @@ import (
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const (
 	NodeNameEnvVar = "NODE_NAME"
-	requeueAfter   = 30 * time.Second
+	// requeueAfter is also the minimum nodeStartupTimeout of the MachineHealthChecks, see pkg/webhooks.
+	requeueAfter = 30 * time.Second
 
 	// ExcludeNodeDrainingAnnotation annotation explicitly skips node draining if set
 	ExcludeNodeDrainingAnnotation = "machine.openshift.io/exclude-node-draining"
@@ -67,6 +74,14 @@ const (
 	// MachineInterruptibleInstanceLabelName as annotaiton name for interruptible instances
 	MachineInterruptibleInstanceLabelName = "machine.openshift.io/interruptible-instance"
 
+	// InstanceCreateFailedReason is the reason of the InstanceExists condition of a Machine whose instance
+	// failed to be created, the message of the condition holding the error of the cloud provider.
+	InstanceCreateFailedReason = "InstanceCreateFailed"
+
+	// InstanceDeleteFailedReason is the reason of the InstanceExists condition of a deleting Machine whose instance
+	// failed to be deleted, the message of the condition holding the error of the cloud provider.
+	InstanceDeleteFailedReason = "InstanceDeleteFailed"
+
 	// Hardcoded instance state set on machine failure
 	unknownInstanceState = "Unknown"
 
@@ -75,8 +90,41 @@ const (
 
 var DefaultActuator Actuator
 
+// Options are the options of the machine controller.
+type Options struct {
+	// StuckMachineThreshold is the duration after which the Machines in the Provisioning or Deleting phase
+	// are counted in the mapi_machine_stuck_in_phase metric. It defaults to metrics.DefaultStuckMachineThreshold.
+	StuckMachineThreshold time.Duration
+
+	// OrphanGCMode is what is done with the provider resources orphaned for longer than OrphanGCTTL, when the
+	// Actuator implements ProviderResourceActuator. It defaults to OrphanGCModeAlert.
+	OrphanGCMode OrphanGCMode
+	// OrphanGCTTL is the duration after which the provider resources without a Machine are orphaned.
+	// It defaults to DefaultOrphanGCTTL.
+	OrphanGCTTL time.Duration
+	// OrphanGCInterval is the interval between two collections of the orphaned provider resources.
+	// It defaults to DefaultOrphanGCInterval.
+	OrphanGCInterval time.Duration
+
+	// DeletionBlockedThreshold is the duration after which the deletion of a Machine is diagnosed, and reported
+	// in its DeletionBlocked condition. It defaults to DefaultDeletionBlockedThreshold.
+	DeletionBlockedThreshold time.Duration
+
+	// RateLimiter limits the requeues of the Machines by the machine controller. The default rate limiter of the
+	// controllers is used when nil. The drain controller keeps its own rate limiter.
+	RateLimiter workqueue.RateLimiter
+}
+
 func AddWithActuator(mgr manager.Manager, actuator Actuator) error {
-	if err := add(mgr, newReconciler(mgr, actuator), "machine-controller"); err != nil {
+	return AddWithActuatorOpts(mgr, actuator, Options{})
+}
+
+// AddWithActuatorOpts adds the machine controllers with the actuator and the options to the manager.
+func AddWithActuatorOpts(mgr manager.Manager, actuator Actuator, opts Options) error {
+	if err := addWithOpts(mgr, controller.Options{
+		Reconciler:  newReconciler(mgr, actuator),
+		RateLimiter: opts.RateLimiter,
+	}, "machine-controller"); err != nil {
 		return err
 	}
 	if err := addWithOpts(mgr, controller.Options{
@@ -85,17 +133,42 @@ func AddWithActuator(mgr manager.Manager, actuator Actuator) error {
 	}, "machine-drain-controller"); err != nil {
 		return err
 	}
+	if err := add(mgr, newDeletionBlockedController(mgr, opts.DeletionBlockedThreshold), "machine-deletion-blocked-controller"); err != nil {
+		return err
+	}
+
+	threshold := opts.StuckMachineThreshold
+	if threshold <= 0 {
+		threshold = metrics.DefaultStuckMachineThreshold
+	}
+	if err := crmetrics.Registry.Register(metrics.NewStuckMachineCollector(mgr.GetClient(), threshold)); err != nil {
+		return fmt.Errorf("error registering stuck machine metrics: %w", err)
+	}
+	if err := crmetrics.Registry.Register(metrics.NewDeletionBlockedCollector(mgr.GetClient())); err != nil {
+		return fmt.Errorf("error registering deletion blocked metrics: %w", err)
+	}
+
+	if resourceActuator, ok := actuator.(ProviderResourceActuator); ok {
+		collector, err := newOrphanCollector(mgr.GetClient(), resourceActuator, opts)
+		if err != nil {
+			return err
+		}
+		if err := mgr.Add(collector); err != nil {
+			return fmt.Errorf("error adding orphaned provider resource collector: %w", err)
+		}
+	}
 	return nil
 }
 
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager, actuator Actuator) reconcile.Reconciler {
+	eventRecorder := mgr.GetEventRecorderFor("machine-controller")
 	r := &ReconcileMachine{
 		Client:        mgr.GetClient(),
-		eventRecorder: mgr.GetEventRecorderFor("machine-controller"),
+		eventRecorder: eventRecorder,
 		config:        mgr.GetConfig(),
 		scheme:        mgr.GetScheme(),
-		actuator:      actuator,
+		actuator:      newTracingActuator(newExternalMachineActuator(actuator, eventRecorder)),
 	}
 	return r
 }
@@ -107,6 +180,11 @@ func add(mgr manager.Manager, r reconcile.Reconciler, controllerName string) err
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
 func addWithOpts(mgr manager.Manager, opts controller.Options, controllerName string) error {
+	opts.Reconciler = tracing.NewReconciler(controllerName, opts.Reconciler)
+	if opts.LogConstructor == nil {
+		opts.LogConstructor = logging.NewLogConstructor(mgr.GetLogger(), controllerName, "machine")
+	}
+
 	// Create a new controller
 	c, err := controller.New(controllerName, mgr, opts)
 	if err != nil {
@@ -132,6 +210,9 @@ type ReconcileMachine struct {
 
 	// nowFunc is used to mock time in testing. It should be nil in production.
 	nowFunc func() time.Time
+
+	// phaseTimes holds the time the Machines entered their current phase, keyed by UID.
+	phaseTimes sync.Map
 }
 
 // Reconcile reads that state of the cluster for a Machine object and makes changes based on the state read
@@ -153,6 +234,10 @@ func (r *ReconcileMachine) Reconcile(ctx context.Context, request reconcile.Requ
 
 	// Implement controller logic here
 	machineName := m.GetName()
+	if annotations.IsClusterAPIAuthoritative(m) {
+		klog.V(3).Infof("%v: Machine is reconciled by Cluster API, skipping", machineName)
+		return reconcile.Result{}, nil
+	}
 	klog.Infof("%v: reconciling Machine", machineName)
 
 	// Get the original state of conditions now so that they can be used to calculate the patch later.
@@ -186,6 +271,10 @@ func (r *ReconcileMachine) Reconcile(ctx context.Context, request reconcile.Requ
 		}
 	}
 
+	if err := r.reconcileInterruption(ctx, m); err != nil {
+		klog.Warningf("%v: failed to check if the node is terminating: %v", machineName, err)
+	}
+
 	if !m.ObjectMeta.DeletionTimestamp.IsZero() {
 		if err := r.updateStatus(ctx, m, machinev1.PhaseDeleting, nil, originalConditions); err != nil {
 			return reconcile.Result{}, err
@@ -198,19 +287,27 @@ func (r *ReconcileMachine) Reconcile(ctx context.Context, request reconcile.Requ
 		}
 
 		klog.Infof("%v: reconciling machine triggers delete", machineName)
+		// remove the lifecycle hooks which have been blocking the deletion for longer than their timeout,
+		// and requeue when the next one expires.
+		hookExpiry, err := r.expireLifecycleHooks(ctx, m)
+		if err != nil {
+			klog.Errorf("%v: failed to expire lifecycle hooks: %v", machineName, err)
+			return reconcile.Result{}, err
+		}
+
 		// check if machine was already drained
 		drainedCondition := conditions.Get(m, machinev1.MachineDrained)
 		if drainedCondition == nil || drainedCondition.Status != corev1.ConditionTrue {
 			klog.Infof("%s: waiting for node to be drained before deleting instance", machineName)
 			// this will requeue and proceed when drain controller will set the condition
-			return reconcile.Result{}, nil
+			return reconcile.Result{RequeueAfter: hookExpiry}, nil
 		}
 
 		// pre-term.delete lifecycle hook
 		// Return early without error, will requeue if/when the hook owner removes the annotation.
 		if len(m.Spec.LifecycleHooks.PreTerminate) > 0 {
 			klog.Infof("%v: not deleting machine: lifecycle blocked by pre-terminate hook", machineName)
-			return reconcile.Result{}, nil
+			return reconcile.Result{RequeueAfter: hookExpiry}, nil
 		}
 
 		if err := r.actuator.Delete(ctx, m); err != nil {
@@ -222,8 +319,29 @@ func (r *ReconcileMachine) Reconcile(ctx context.Context, request reconcile.Requ
 			// was sent and before a list of node addresses was set.
 			if len(m.Status.Addresses) > 0 || !isInvalidMachineConfigurationError(err) {
 				klog.Errorf("%v: failed to delete machine: %v", machineName, err)
+				var requeueAfterError *RequeueAfterError
+				if !errors.As(err, &requeueAfterError) {
+					// The error is recorded so that the deletion blocked by the cloud provider can be diagnosed.
+					deletingConditions := m.Status.Conditions.DeepCopy()
+					conditions.Set(m, &machinev1.Condition{
+						Type:    machinev1.InstanceExistsCondition,
+						Status:  corev1.ConditionTrue,
+						Reason:  InstanceDeleteFailedReason,
+						Message: fmt.Sprintf("Failed to delete instance: %v", err),
+					})
+					if patchErr := r.updateStatus(ctx, m, machinev1.PhaseDeleting, nil, deletingConditions); patchErr != nil {
+						klog.Errorf("%v: error patching status: %v", machineName, patchErr)
+					}
+				}
 				return delayIfRequeueAfterError(err)
 			}
+		} else if instanceExistsCondition := conditions.Get(m, machinev1.InstanceExistsCondition); instanceExistsCondition != nil && instanceExistsCondition.Reason == InstanceDeleteFailedReason {
+			// The instance is being terminated, the previous delete error no longer blocks the deletion.
+			deletingConditions := m.Status.Conditions.DeepCopy()
+			conditions.MarkTrue(m, machinev1.InstanceExistsCondition)
+			if err := r.updateStatus(ctx, m, machinev1.PhaseDeleting, nil, deletingConditions); err != nil {
+				klog.Errorf("%v: error patching status: %v", machineName, err)
+			}
 		}
 
 		instanceExists, err := r.actuator.Exists(ctx, m)
@@ -293,6 +411,51 @@ func (r *ReconcileMachine) Reconcile(ctx context.Context, request reconcile.Requ
 		// Mark the instance exists condition true after actuator update else the update may overwrite changes
 		conditions.MarkTrue(m, machinev1.InstanceExistsCondition)
 
+		if err := r.syncInstanceMetadata(ctx, m); err != nil {
+			klog.Errorf("%v: error syncing instance metadata: %v, retrying in %v seconds", machineName, err, requeueAfter)
+			if patchErr := r.updateStatus(ctx, m, pointer.StringDeref(m.Status.Phase, ""), nil, originalConditions); patchErr != nil {
+				klog.Errorf("%v: error patching status: %v", machineName, patchErr)
+			}
+
+			return reconcile.Result{RequeueAfter: requeueAfter}, nil
+		}
+
+		resizing, err := r.reconcileResize(ctx, m)
+		if err != nil {
+			klog.Errorf("%v: error resizing machine: %v, retrying in %v seconds", machineName, err, requeueAfter)
+			if patchErr := r.updateStatus(ctx, m, pointer.StringDeref(m.Status.Phase, ""), nil, originalConditions); patchErr != nil {
+				klog.Errorf("%v: error patching status: %v", machineName, patchErr)
+			}
+
+			return reconcile.Result{RequeueAfter: requeueAfter}, nil
+		}
+		if resizing {
+			// Requeue until the node is drained and the instance resized
+			return reconcile.Result{RequeueAfter: requeueAfter}, r.updateStatus(ctx, m, pointer.StringDeref(m.Status.Phase, ""), nil, originalConditions)
+		}
+
+		if err := r.reconcileRestart(ctx, m); err != nil {
+			klog.Errorf("%v: error restarting machine: %v, retrying in %v seconds", machineName, err, requeueAfter)
+			return reconcile.Result{RequeueAfter: requeueAfter}, nil
+		}
+
+		stopped, draining, err := r.reconcilePowerState(ctx, m)
+		if err != nil {
+			klog.Errorf("%v: error setting machine power state: %v, retrying in %v seconds", machineName, err, requeueAfter)
+			if patchErr := r.updateStatus(ctx, m, pointer.StringDeref(m.Status.Phase, ""), nil, originalConditions); patchErr != nil {
+				klog.Errorf("%v: error patching status: %v", machineName, patchErr)
+			}
+
+			return reconcile.Result{RequeueAfter: requeueAfter}, nil
+		}
+		if draining {
+			// Requeue until the node is drained and the instance powered off
+			return reconcile.Result{RequeueAfter: requeueAfter}, r.updateStatus(ctx, m, pointer.StringDeref(m.Status.Phase, ""), nil, originalConditions)
+		}
+		if stopped {
+			return reconcile.Result{}, r.updateStatus(ctx, m, PhaseStopped, nil, originalConditions)
+		}
+
 		if !machineIsProvisioned(m) {
 			klog.Errorf("%v: instance exists but providerID or addresses has not been given to the machine yet, requeuing", machineName)
 			if patchErr := r.updateStatus(ctx, m, pointer.StringDeref(m.Status.Phase, ""), nil, originalConditions); patchErr != nil {
@@ -303,6 +466,16 @@ func (r *ReconcileMachine) Reconcile(ctx context.Context, request reconcile.Requ
 		}
 
 		if !machineHasNode(m) {
+			if err := r.checkNodeStartupTimeout(m); err != nil {
+				var machineError *MachineError
+				if errors.As(err, &machineError) {
+					klog.Warningf("%v: %v, failing machine", machineName, err)
+					r.eventRecorder.Eventf(m, corev1.EventTypeWarning, "NodeStartupTimeout", err.Error())
+					return reconcile.Result{}, r.updateStatus(ctx, m, machinev1.PhaseFailed, err, originalConditions)
+				}
+				klog.Warningf("%v: ignoring node startup timeout: %v", machineName, err)
+			}
+
 			// Requeue until we reach running phase
 			if err := r.updateStatus(ctx, m, machinev1.PhaseProvisioned, nil, originalConditions); err != nil {
 				return reconcile.Result{}, err
@@ -347,7 +520,7 @@ func (r *ReconcileMachine) Reconcile(ctx context.Context, request reconcile.Requ
 	}
 
 	klog.Infof("%v: reconciling machine triggers idempotent create", machineName)
-	if err := r.actuator.Create(ctx, m); err != nil {
+	if err := r.createInstance(ctx, m); err != nil {
 		klog.Warningf("%v: failed to create machine: %v", machineName, err)
 		if isInvalidMachineConfigurationError(err) {
 			if err := r.updateStatus(ctx, m, machinev1.PhaseFailed, err, originalConditions); err != nil {
@@ -355,6 +528,20 @@ func (r *ReconcileMachine) Reconcile(ctx context.Context, request reconcile.Requ
 			}
 			return reconcile.Result{}, nil
 		}
+
+		var requeueAfterError *RequeueAfterError
+		if !errors.As(err, &requeueAfterError) {
+			// The error is recorded so that the failed creation shows in the status of the Machine and of its MachineSet.
+			conditions.Set(m, conditions.FalseCondition(
+				machinev1.InstanceExistsCondition,
+				InstanceCreateFailedReason,
+				machinev1.ConditionSeverityWarning,
+				"Failed to create instance: %v", err,
+			))
+			if patchErr := r.updateStatus(ctx, m, pointer.StringDeref(m.Status.Phase, ""), nil, originalConditions); patchErr != nil {
+				klog.Errorf("%v: error patching status: %v", machineName, patchErr)
+			}
+		}
 		return delayIfRequeueAfterError(err)
 	}
 
@@ -400,7 +587,8 @@ func isInvalidMachineConfigurationError(err error) bool {
 // machine conditions so that the diff can be calculated properly within this function.
 func (r *ReconcileMachine) updateStatus(ctx context.Context, machine *machinev1.Machine, phase string, failureCause error, originalConditions []machinev1.Condition) error {
 	phaseChanged := false
-	if pointer.StringDeref(machine.Status.Phase, "") != phase {
+	previousPhase := pointer.StringDeref(machine.Status.Phase, "")
+	if previousPhase != phase {
 		klog.V(3).Infof("%v: going into phase %q", machine.GetName(), phase)
 
 		phaseChanged = true
@@ -470,16 +658,34 @@ func (r *ReconcileMachine) updateStatus(ctx context.Context, machine *machinev1.
 	// entries when there are failures.
 	// Only update when there is a change to the phase to avoid duplicating entries for
 	// individual machines.
-	if phaseChanged && phase != machinev1.PhaseDeleting {
-		// Apart from deleting, update the transition metric
-		// Deleting would always end up in the infinite bucket
-		timeElapsed := r.now().Sub(machine.GetCreationTimestamp().Time).Seconds()
-		metrics.MachinePhaseTransitionSeconds.With(map[string]string{"phase": phase}).Observe(timeElapsed)
+	if phaseChanged {
+		r.observePhaseTransition(machine, previousPhase, phase)
 	}
 
 	return nil
 }
 
+// observePhaseTransition updates the transition metric with the time the Machine spent in its previous phase.
+// The time a Machine entered its phase is only known to the controller which set it, so the first transition
+// of a Machine after a restart of the controller is not recorded, unless the Machine had no phase yet.
+func (r *ReconcileMachine) observePhaseTransition(machine *machinev1.Machine, from, to string) {
+	// Deleting would always end up in the infinite bucket and has no next phase
+	if to == machinev1.PhaseDeleting {
+		r.phaseTimes.Delete(machine.GetUID())
+		return
+	}
+
+	now := r.now()
+	entered, known := machine.GetCreationTimestamp().Time, from == ""
+	if t, ok := r.phaseTimes.Load(machine.GetUID()); ok && !known {
+		entered, known = t.(time.Time), true
+	}
+	r.phaseTimes.Store(machine.GetUID(), now)
+	if known {
+		metrics.ObserveMachinePhaseTransition(from, to, now.Sub(entered))
+	}
+}
+
 func (r *ReconcileMachine) patchFailedMachineInstanceAnnotation(ctx context.Context, machine *machinev1.Machine) error {
 	baseToPatch := client.MergeFrom(machine.DeepCopy())
 	if machine.Annotations == nil {
@@ -540,8 +746,8 @@ func validateMachine(m *machinev1.Machine) field.ErrorList {
 		errors = append(errors, field.Invalid(fldPath.Child("labels"), m.Labels, fmt.Sprintf("missing %v label.", machinev1.MachineClusterIDLabel)))
 	}
 
-	// validate provider config is set
-	if m.Spec.ProviderSpec.Value == nil {
+	// validate provider config is set, external machines have none
+	if m.Spec.ProviderSpec.Value == nil && !annotations.IsExternalMachine(m) {
 		errors = append(errors, field.Invalid(fldPath.Child("spec").Child("providerspec"), m.Spec.ProviderSpec, "value field must be set"))
 	}
 
@@ -26,7 +26,10 @@ import (
 
 	. "github.com/onsi/gomega"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/metrics"
 	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -1193,3 +1196,55 @@ func TestSetLifecycleHookConditions(t *testing.T) {
 		})
 	}
 }
+
+func TestObservePhaseTransition(t *testing.T) {
+	g := NewWithT(t)
+
+	created := time.Now().Add(-time.Hour)
+	now := created
+	machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+		Name:              "phase-transition",
+		UID:               "phase-transition-uid",
+		CreationTimestamp: metav1.NewTime(created),
+	}}
+	r := &ReconcileMachine{nowFunc: func() time.Time { return now }}
+
+	// observed returns the number and the sum of the observations of a transition.
+	observed := func(from, to string) (uint64, float64) {
+		metric := &dto.Metric{}
+		g.Expect(metrics.MachinePhaseTransitionSeconds.WithLabelValues(from, to).(prometheus.Metric).Write(metric)).To(Succeed())
+		return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+	}
+	expectObservation := func(from, to string, seconds float64, transition func()) {
+		count, sum := observed(from, to)
+		transition()
+		newCount, newSum := observed(from, to)
+		g.Expect(newCount-count).To(BeEquivalentTo(1), "expected a %s to %s transition", from, to)
+		g.Expect(newSum-sum).To(BeNumerically("~", seconds), "unexpected %s to %s transition time", from, to)
+	}
+
+	now = created.Add(30 * time.Second)
+	expectObservation("", machinev1.PhaseProvisioning, 30, func() {
+		r.observePhaseTransition(machine, "", machinev1.PhaseProvisioning)
+	})
+	now = now.Add(2 * time.Minute)
+	expectObservation(machinev1.PhaseProvisioning, machinev1.PhaseProvisioned, 120, func() {
+		r.observePhaseTransition(machine, machinev1.PhaseProvisioning, machinev1.PhaseProvisioned)
+	})
+
+	// After a restart, the time the Machine entered its phase is not known.
+	restarted := &ReconcileMachine{nowFunc: func() time.Time { return now }}
+	count, _ := observed(machinev1.PhaseProvisioned, machinev1.PhaseRunning)
+	restarted.observePhaseTransition(machine, machinev1.PhaseProvisioned, machinev1.PhaseRunning)
+	g.Expect(observed(machinev1.PhaseProvisioned, machinev1.PhaseRunning)).To(Equal(count))
+
+	now = now.Add(5 * time.Minute)
+	expectObservation(machinev1.PhaseProvisioned, machinev1.PhaseRunning, 300, func() {
+		r.observePhaseTransition(machine, machinev1.PhaseProvisioned, machinev1.PhaseRunning)
+	})
+
+	// Deleting has no next phase, the Machine is forgotten.
+	r.observePhaseTransition(machine, machinev1.PhaseRunning, machinev1.PhaseDeleting)
+	_, ok := r.phaseTimes.Load(machine.GetUID())
+	g.Expect(ok).To(BeFalse())
+}
@@ -0,0 +1,160 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+const (
+	// DeletionBlockedCondition is set on the Machines deleting for longer than the deletion blocked threshold,
+	// its reason being what blocks the deletion and its message naming the culprit.
+	DeletionBlockedCondition machinev1.ConditionType = "DeletionBlocked"
+
+	// DeletionBlockedByFinalizerReason is used when the Machine controller is done with the Machine, and
+	// the deletion waits for the finalizers of other controllers.
+	DeletionBlockedByFinalizerReason = "Finalizer"
+	// DeletionBlockedByPreDrainHookReason is used when the drain of the Machine waits for its pre-drain hooks.
+	DeletionBlockedByPreDrainHookReason = "PreDrainHook"
+	// DeletionBlockedByDrainReason is used when the node of the Machine is not drained yet.
+	DeletionBlockedByDrainReason = "Drain"
+	// DeletionBlockedByPreTerminateHookReason is used when the termination of the instance waits for the
+	// pre-terminate hooks of the Machine.
+	DeletionBlockedByPreTerminateHookReason = "PreTerminateHook"
+	// DeletionBlockedByProviderErrorReason is used when the cloud provider failed to delete the instance.
+	DeletionBlockedByProviderErrorReason = "ProviderError"
+	// DeletionBlockedByInstanceTerminationReason is used when the instance is still being terminated.
+	DeletionBlockedByInstanceTerminationReason = "InstanceTermination"
+
+	// DefaultDeletionBlockedThreshold is the default duration after which the deletion of a Machine is diagnosed.
+	DefaultDeletionBlockedThreshold = 10 * time.Minute
+
+	// deletionBlockedRecheckInterval is the interval between two diagnoses of a blocked deletion, as the Machine
+	// is not updated when, e.g., the cloud provider keeps failing with the same error.
+	deletionBlockedRecheckInterval = 5 * time.Minute
+)
+
+// deletionBlockedController diagnoses the deletions of Machines lasting for longer than the threshold, and
+// reports what blocks them in the DeletionBlocked condition of the Machines.
+type deletionBlockedController struct {
+	client.Client
+
+	threshold time.Duration
+
+	// nowFunc is used to mock time in testing. It should be nil in production.
+	nowFunc func() time.Time
+}
+
+// newDeletionBlockedController returns a new reconcile.Reconciler for machine-deletion-blocked-controller.
+func newDeletionBlockedController(mgr manager.Manager, threshold time.Duration) reconcile.Reconciler {
+	if threshold <= 0 {
+		threshold = DefaultDeletionBlockedThreshold
+	}
+	return &deletionBlockedController{
+		Client:    mgr.GetClient(),
+		threshold: threshold,
+	}
+}
+
+func (d *deletionBlockedController) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	m := &machinev1.Machine{}
+	if err := d.Client.Get(ctx, request.NamespacedName, m); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if m.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+
+	if deleting := d.now().Sub(m.DeletionTimestamp.Time); deleting < d.threshold {
+		return reconcile.Result{RequeueAfter: d.threshold - deleting}, nil
+	}
+
+	original := m.DeepCopy()
+	reason, message := diagnoseDeletion(m)
+	conditions.Set(m, &machinev1.Condition{
+		Type:     DeletionBlockedCondition,
+		Status:   corev1.ConditionTrue,
+		Reason:   reason,
+		Severity: machinev1.ConditionSeverityWarning,
+		Message:  message,
+	})
+	if !equality.Semantic.DeepEqual(original.Status.Conditions, m.Status.Conditions) {
+		klog.Infof("%v: deletion blocked: %s", m.Name, message)
+		// The conditions are also set by the machine controller, which must not be overwritten.
+		patch := client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})
+		if err := d.Client.Status().Patch(ctx, m, patch); err != nil {
+			if apierrors.IsNotFound(err) {
+				return reconcile.Result{}, nil
+			}
+			return reconcile.Result{}, fmt.Errorf("failed to set %s condition: %w", DeletionBlockedCondition, err)
+		}
+	}
+	return reconcile.Result{RequeueAfter: deletionBlockedRecheckInterval}, nil
+}
+
+// diagnoseDeletion returns the reason and the message of the DeletionBlocked condition of the deleting Machine,
+// following the steps of its deletion by the Machine controller.
+func diagnoseDeletion(m *machinev1.Machine) (string, string) {
+	if !util.Contains(m.Finalizers, machinev1.MachineFinalizer) {
+		return DeletionBlockedByFinalizerReason, fmt.Sprintf("Waiting for finalizers %s", strings.Join(m.Finalizers, ", "))
+	}
+
+	if len(m.Spec.LifecycleHooks.PreDrain) > 0 {
+		return DeletionBlockedByPreDrainHookReason, fmt.Sprintf("Waiting for pre-drain hooks %s", formatLifecycleHooks(m.Spec.LifecycleHooks.PreDrain))
+	}
+
+	drainedCondition := conditions.Get(m, machinev1.MachineDrained)
+	if drainedCondition == nil || drainedCondition.Status != corev1.ConditionTrue {
+		message := "Waiting for the node to be drained"
+		if m.Status.NodeRef != nil {
+			message = fmt.Sprintf("Waiting for node %s to be drained", m.Status.NodeRef.Name)
+		}
+		if drainedCondition != nil && drainedCondition.Message != "" {
+			message = fmt.Sprintf("%s: %s", message, drainedCondition.Message)
+		}
+		return DeletionBlockedByDrainReason, message
+	}
+
+	if len(m.Spec.LifecycleHooks.PreTerminate) > 0 {
+		return DeletionBlockedByPreTerminateHookReason, fmt.Sprintf("Waiting for pre-terminate hooks %s", formatLifecycleHooks(m.Spec.LifecycleHooks.PreTerminate))
+	}
+
+	if instanceExistsCondition := conditions.Get(m, machinev1.InstanceExistsCondition); instanceExistsCondition != nil && instanceExistsCondition.Reason == InstanceDeleteFailedReason {
+		return DeletionBlockedByProviderErrorReason, instanceExistsCondition.Message
+	}
+
+	return DeletionBlockedByInstanceTerminationReason, "Waiting for the instance to be terminated"
+}
+
+// formatLifecycleHooks lists the lifecycle hooks along with their owners.
+func formatLifecycleHooks(hooks []machinev1.LifecycleHook) string {
+	formatted := make([]string, 0, len(hooks))
+	for _, hook := range hooks {
+		formatted = append(formatted, fmt.Sprintf("%s (owned by %s)", hook.Name, hook.Owner))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func (d *deletionBlockedController) now() time.Time {
+	if d.nowFunc != nil {
+		return d.nowFunc()
+	}
+	return time.Now()
+}
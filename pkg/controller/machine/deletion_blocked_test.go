@@ -0,0 +1,190 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+// testDeleteErrorActuator fails to delete the instances with its error, when set.
+type testDeleteErrorActuator struct {
+	*TestActuator
+	deleteErr error
+}
+
+func (a *testDeleteErrorActuator) Delete(ctx context.Context, m *machinev1.Machine) error {
+	if a.deleteErr != nil {
+		return a.deleteErr
+	}
+	return a.TestActuator.Delete(ctx, m)
+}
+
+func TestDiagnoseDeletion(t *testing.T) {
+	hook := machinev1.LifecycleHook{Name: "migrate", Owner: "storage-operator"}
+	drained := conditions.TrueCondition(machinev1.MachineDrained)
+	drainError := conditions.FalseCondition(machinev1.MachineDrained, machinev1.MachineDrainError, machinev1.ConditionSeverityWarning, "could not drain machine: cannot evict pod as it would violate the pod's disruption budget")
+
+	cases := []struct {
+		name            string
+		finalizers      []string
+		preDrain        []machinev1.LifecycleHook
+		preTerminate    []machinev1.LifecycleHook
+		conditions      machinev1.Conditions
+		nodeRef         *corev1.ObjectReference
+		expectedReason  string
+		expectedMessage string
+	}{
+		{
+			name:            "with foreign finalizers",
+			finalizers:      []string{"example.com/backup"},
+			expectedReason:  DeletionBlockedByFinalizerReason,
+			expectedMessage: "Waiting for finalizers example.com/backup",
+		},
+		{
+			name:            "with pre-drain hooks",
+			preDrain:        []machinev1.LifecycleHook{hook},
+			expectedReason:  DeletionBlockedByPreDrainHookReason,
+			expectedMessage: "Waiting for pre-drain hooks migrate (owned by storage-operator)",
+		},
+		{
+			name:            "with a drain error",
+			conditions:      machinev1.Conditions{*drainError},
+			nodeRef:         &corev1.ObjectReference{Name: "node-1"},
+			expectedReason:  DeletionBlockedByDrainReason,
+			expectedMessage: "Waiting for node node-1 to be drained: could not drain machine: cannot evict pod as it would violate the pod's disruption budget",
+		},
+		{
+			name:            "with pre-terminate hooks",
+			preTerminate:    []machinev1.LifecycleHook{hook},
+			conditions:      machinev1.Conditions{*drained},
+			expectedReason:  DeletionBlockedByPreTerminateHookReason,
+			expectedMessage: "Waiting for pre-terminate hooks migrate (owned by storage-operator)",
+		},
+		{
+			name: "with a provider error",
+			conditions: machinev1.Conditions{*drained, {
+				Type:    machinev1.InstanceExistsCondition,
+				Status:  corev1.ConditionTrue,
+				Reason:  InstanceDeleteFailedReason,
+				Message: "Failed to delete instance: permission denied",
+			}},
+			expectedReason:  DeletionBlockedByProviderErrorReason,
+			expectedMessage: "Failed to delete instance: permission denied",
+		},
+		{
+			name:            "with an instance being terminated",
+			conditions:      machinev1.Conditions{*drained, *conditions.TrueCondition(machinev1.InstanceExistsCondition)},
+			expectedReason:  DeletionBlockedByInstanceTerminationReason,
+			expectedMessage: "Waiting for the instance to be terminated",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			m := getMachine("machine", machinev1.PhaseDeleting)
+			m.Finalizers = tc.finalizers
+			if m.Finalizers == nil {
+				m.Finalizers = []string{machinev1.MachineFinalizer}
+			}
+			m.Spec.LifecycleHooks.PreDrain = tc.preDrain
+			m.Spec.LifecycleHooks.PreTerminate = tc.preTerminate
+			m.Status.Conditions = tc.conditions
+			m.Status.NodeRef = tc.nodeRef
+
+			reason, message := diagnoseDeletion(m)
+			g.Expect(reason).To(Equal(tc.expectedReason))
+			g.Expect(message).To(Equal(tc.expectedMessage))
+		})
+	}
+}
+
+func TestDeletionBlockedReconcile(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Now().Truncate(time.Second)
+	deletionTimestamp := metav1.NewTime(now.Add(-4 * time.Minute))
+	m := getMachine("machine", machinev1.PhaseDeleting)
+	m.DeletionTimestamp = &deletionTimestamp
+	m.Finalizers = []string{machinev1.MachineFinalizer}
+	m.Spec.LifecycleHooks.PreDrain = []machinev1.LifecycleHook{{Name: "migrate", Owner: "storage-operator"}}
+
+	d := &deletionBlockedController{
+		Client:    fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(m).Build(),
+		threshold: 10 * time.Minute,
+		nowFunc:   func() time.Time { return now },
+	}
+	request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(m)}
+
+	// Before the threshold, the Machine is requeued when it is reached.
+	result, err := d.Reconcile(context.TODO(), request)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(6 * time.Minute))
+	got := &machinev1.Machine{}
+	g.Expect(d.Client.Get(context.TODO(), request.NamespacedName, got)).To(Succeed())
+	g.Expect(conditions.Get(got, DeletionBlockedCondition)).To(BeNil())
+
+	// After the threshold, what blocks the deletion is reported.
+	now = now.Add(10 * time.Minute)
+	result, err = d.Reconcile(context.TODO(), request)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(deletionBlockedRecheckInterval))
+	g.Expect(d.Client.Get(context.TODO(), request.NamespacedName, got)).To(Succeed())
+	g.Expect(conditions.Get(got, DeletionBlockedCondition)).To(HaveField("Reason", DeletionBlockedByPreDrainHookReason))
+}
+
+func TestDeleteInstanceError(t *testing.T) {
+	g := NewWithT(t)
+
+	deletionTimestamp := metav1.Now()
+	m := getMachine("machine", machinev1.PhaseDeleting)
+	m.DeletionTimestamp = &deletionTimestamp
+	m.Finalizers = []string{machinev1.MachineFinalizer}
+	m.Status.Conditions = machinev1.Conditions{*conditions.TrueCondition(machinev1.MachineDrained)}
+
+	actuator := &testDeleteErrorActuator{TestActuator: newTestActuator(), deleteErr: errors.New("permission denied")}
+	actuator.ExistsValue = true
+	r := &ReconcileMachine{
+		Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(m).Build(),
+		scheme:        scheme.Scheme,
+		eventRecorder: record.NewFakeRecorder(10),
+		actuator:      actuator,
+	}
+	request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(m)}
+
+	// The delete error of the cloud provider is recorded in the InstanceExists condition.
+	_, err := r.Reconcile(context.TODO(), request)
+	g.Expect(err).To(MatchError("permission denied"))
+	got := &machinev1.Machine{}
+	g.Expect(r.Client.Get(context.TODO(), request.NamespacedName, got)).To(Succeed())
+	g.Expect(conditions.Get(got, machinev1.InstanceExistsCondition)).To(SatisfyAll(
+		HaveField("Reason", InstanceDeleteFailedReason),
+		HaveField("Message", "Failed to delete instance: permission denied"),
+	))
+
+	// It is cleared once the instance is being terminated.
+	actuator.deleteErr = nil
+	_, err = r.Reconcile(context.TODO(), request)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.Client.Get(context.TODO(), request.NamespacedName, got)).To(Succeed())
+	g.Expect(conditions.Get(got, machinev1.InstanceExistsCondition)).To(SatisfyAll(
+		HaveField("Status", corev1.ConditionTrue),
+		HaveField("Reason", ""),
+	))
+}
@@ -79,7 +79,16 @@ func (d *machineDrainController) Reconcile(ctx context.Context, request reconcil
 	existingDrainedCondition := conditions.Get(m, machinev1.MachineDrained)
 	alreadyDrained := existingDrainedCondition != nil && existingDrainedCondition.Status == corev1.ConditionTrue
 
-	if !m.ObjectMeta.DeletionTimestamp.IsZero() && pointer.StringDeref(m.Status.Phase, "") == machinev1.PhaseDeleting && !alreadyDrained {
+	deleting := !m.ObjectMeta.DeletionTimestamp.IsZero() && pointer.StringDeref(m.Status.Phase, "") == machinev1.PhaseDeleting
+	if isDrainRequested(m) && !deleting && !isDrainingForResize(m) && !isDrainingForPowerOff(m) && !alreadyDrained && m.Status.NodeRef == nil {
+		// A drain requested before the Machine has a node, e.g. for a standby Machine, waits for the node to
+		// cordon it as soon as it joins. The Machine is reconciled again when its node is linked.
+		klog.V(4).Infof("%v: drain requested, waiting for the node of the machine", m.Name)
+		return reconcile.Result{}, nil
+	}
+	// The node is drained before the Machine is deleted, before its instance is resized in place or powered off,
+	// or on request.
+	if (deleting || isDrainingForResize(m) || isDrainingForPowerOff(m) || isDrainRequested(m)) && !alreadyDrained {
 		drainFinishedCondition := conditions.TrueCondition(machinev1.MachineDrained)
 
 		if _, exists := m.ObjectMeta.Annotations[ExcludeNodeDrainingAnnotation]; !exists && m.Status.NodeRef != nil {
@@ -90,20 +99,46 @@ func (d *machineDrainController) Reconcile(ctx context.Context, request reconcil
 				d.eventRecorder.Eventf(m, corev1.EventTypeNormal, "DrainBlocked", "Drain blocked by pre-drain hook")
 				return reconcile.Result{}, nil
 			}
+
+			drainTimeout, err := getDrainTimeout(m)
+			if err != nil {
+				klog.Warningf("%v: ignoring drain timeout: %v", m.Name, err)
+			}
+			var drainStarted time.Time
+			if drainTimeout > 0 {
+				if drainStarted, err = d.drainStarted(ctx, m); err != nil {
+					return reconcile.Result{}, err
+				}
+			}
+
 			d.eventRecorder.Eventf(m, corev1.EventTypeNormal, "DrainProceeds", "Node drain proceeds")
 			if err := d.drainNode(ctx, m); err != nil {
 				klog.Errorf("%v: failed to drain node for machine: %v", m.Name, err)
-				conditions.Set(m, conditions.FalseCondition(
-					machinev1.MachineDrained,
-					machinev1.MachineDrainError,
-					machinev1.ConditionSeverityWarning,
-					"could not drain machine: %v", err,
-				))
-				d.eventRecorder.Eventf(m, corev1.EventTypeNormal, "DrainRequeued", "Node drain requeued: %v", err.Error())
-				return delayIfRequeueAfterError(err)
+				if drainTimeout == 0 || time.Since(drainStarted) < drainTimeout {
+					conditions.Set(m, conditions.FalseCondition(
+						machinev1.MachineDrained,
+						machinev1.MachineDrainError,
+						machinev1.ConditionSeverityWarning,
+						"could not drain machine: %v", err,
+					))
+					d.eventRecorder.Eventf(m, corev1.EventTypeNormal, "DrainRequeued", "Node drain requeued: %v", err.Error())
+					// The error is recorded so that the blocked drain shows in the status of the Machine and of its MachineSet.
+					if updateErr := d.Client.Status().Update(ctx, m); updateErr != nil {
+						klog.Warningf("%v: could not record drain error: %v", m.Name, updateErr)
+					}
+					return delayIfRequeueAfterError(err)
+				}
+				if getDrainTimeoutPolicy(m) == DrainTimeoutPolicyMarkTimedOut {
+					return d.markDrainTimedOut(ctx, m, drainTimeout, err)
+				}
+
+				klog.Warningf("%v: drain timed out after %v, proceeding with deletion", m.Name, drainTimeout)
+				d.eventRecorder.Eventf(m, corev1.EventTypeWarning, "DrainTimedOut", "Node drain timed out after %v, proceeding with deletion", drainTimeout)
+				drainFinishedCondition.Message = fmt.Sprintf("Drain timed out after %v", drainTimeout)
+			} else {
+				d.eventRecorder.Eventf(m, corev1.EventTypeNormal, "DrainSucceeded", "Node drain succeeded")
+				drainFinishedCondition.Message = "Drain finished successfully"
 			}
-			d.eventRecorder.Eventf(m, corev1.EventTypeNormal, "DrainSucceeded", "Node drain succeeded")
-			drainFinishedCondition.Message = "Drain finished successfully"
 		} else {
 			d.eventRecorder.Eventf(m, corev1.EventTypeNormal, "DrainSkipped", "Node drain skipped")
 			drainFinishedCondition.Message = "Node drain skipped"
@@ -117,6 +152,10 @@ func (d *machineDrainController) Reconcile(ctx context.Context, request reconcil
 		return reconcile.Result{}, nil
 	}
 
+	if alreadyDrained && drainRequestRemoved(m) {
+		return d.releaseDrainRequest(ctx, m)
+	}
+
 	return reconcile.Result{}, nil
 }
 
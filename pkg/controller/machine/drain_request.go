@@ -0,0 +1,47 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+// MachineDrainRequestedAnnotation requests the node of a Machine to be cordoned and drained when set to "true",
+// e.g. by a BulkOperation. The drain completes when the MachineDrained condition of the Machine is True.
+// Removing the annotation uncordons the node, which is drained again when the Machine is deleted.
+const MachineDrainRequestedAnnotation = "machine.openshift.io/drain-requested"
+
+// isDrainRequested returns true if the node of the Machine is requested to be drained.
+func isDrainRequested(m *machinev1.Machine) bool {
+	return m.Annotations[MachineDrainRequestedAnnotation] == "true"
+}
+
+// drainRequestRemoved returns true if the node of the Machine was drained on a request which has since been
+// removed, or before a power off of its instance which has since been powered back on: the drains before a
+// deletion or a resize are not released.
+func drainRequestRemoved(m *machinev1.Machine) bool {
+	return m.DeletionTimestamp.IsZero() && !isDrainingForResize(m) && !isDrainingForPowerOff(m) && !isDrainRequested(m)
+}
+
+// releaseDrainRequest uncordons the node drained on request, and removes the MachineDrained condition so that
+// the node is drained again when the Machine is deleted.
+func (d *machineDrainController) releaseDrainRequest(ctx context.Context, m *machinev1.Machine) (reconcile.Result, error) {
+	if m.Status.NodeRef != nil {
+		if err := uncordonNode(ctx, d.Client, m.Status.NodeRef.Name); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to uncordon node %q: %w", m.Status.NodeRef.Name, err)
+		}
+	}
+	klog.Infof("%v: drain request removed, uncordoned node", m.Name)
+
+	conditions.Delete(m, machinev1.MachineDrained)
+	if err := d.Client.Status().Update(ctx, m); err != nil {
+		return reconcile.Result{}, fmt.Errorf("could not update machine status: %w", err)
+	}
+	return reconcile.Result{}, nil
+}
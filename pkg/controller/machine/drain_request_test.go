@@ -0,0 +1,104 @@
+package machine
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+func TestReleaseDrainRequest(t *testing.T) {
+	cases := []struct {
+		name             string
+		requested        bool
+		poweredOff       bool
+		expectedDrained  bool
+		expectedCordoned bool
+	}{
+		{
+			name:             "with a drain request",
+			requested:        true,
+			expectedDrained:  true,
+			expectedCordoned: true,
+		},
+		{
+			name: "with a removed drain request",
+		},
+		{
+			name:             "with a powered off machine",
+			poweredOff:       true,
+			expectedDrained:  true,
+			expectedCordoned: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			m := getMachine("machine", machinev1.PhaseRunning)
+			if tc.requested {
+				m.Annotations[MachineDrainRequestedAnnotation] = "true"
+			}
+			if tc.poweredOff {
+				conditions.MarkTrue(m, InstancePoweredOffCondition)
+			}
+			conditions.MarkTrue(m, machinev1.MachineDrained)
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: m.Status.NodeRef.Name},
+				Spec:       corev1.NodeSpec{Unschedulable: true},
+			}
+
+			d := &machineDrainController{
+				Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(m, node).Build(),
+				scheme:        scheme.Scheme,
+				eventRecorder: record.NewFakeRecorder(10),
+			}
+
+			_, err := d.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(m)})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			g.Expect(d.Client.Get(context.TODO(), client.ObjectKeyFromObject(m), m)).To(Succeed())
+			if tc.expectedDrained {
+				g.Expect(conditions.Get(m, machinev1.MachineDrained)).To(HaveField("Status", corev1.ConditionTrue))
+			} else {
+				g.Expect(conditions.Get(m, machinev1.MachineDrained)).To(BeNil())
+			}
+			g.Expect(d.Client.Get(context.TODO(), client.ObjectKeyFromObject(node), node)).To(Succeed())
+			g.Expect(node.Spec.Unschedulable).To(Equal(tc.expectedCordoned))
+		})
+	}
+}
+
+func TestDrainRequestWithoutNode(t *testing.T) {
+	g := NewWithT(t)
+
+	m := getMachine("machine", machinev1.PhaseProvisioned)
+	m.Status.NodeRef = nil
+	m.Annotations[MachineDrainRequestedAnnotation] = "true"
+
+	d := &machineDrainController{
+		Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(m).Build(),
+		scheme:        scheme.Scheme,
+		eventRecorder: record.NewFakeRecorder(10),
+	}
+
+	_, err := d.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(m)})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// The node is drained once it joins, rather than the drain being skipped.
+	g.Expect(d.Client.Get(context.TODO(), client.ObjectKeyFromObject(m), m)).To(Succeed())
+	g.Expect(conditions.Get(m, machinev1.MachineDrained)).To(BeNil())
+}
@@ -0,0 +1,111 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+const (
+	// DrainTimeoutAnnotation sets how long the node of a deleting Machine may be drained,
+	// as a duration, e.g. "30m". After the timeout, the DrainTimeoutPolicyAnnotation
+	// decides what happens to the Machine. Drains are not time limited without it.
+	DrainTimeoutAnnotation = "machine.openshift.io/drain-timeout"
+
+	// DrainTimeoutPolicyAnnotation sets the policy applied once the drain timeout has expired,
+	// either DrainTimeoutPolicyProceed (the default) or DrainTimeoutPolicyMarkTimedOut.
+	DrainTimeoutPolicyAnnotation = "machine.openshift.io/drain-timeout-policy"
+
+	// DrainStartedAnnotation records when the drain of the node started, the drain timeout is measured from it.
+	DrainStartedAnnotation = "machine.openshift.io/drain-started"
+
+	// DrainTimeoutPolicyProceed proceeds with the deletion of the Machine without waiting for the drain to finish.
+	DrainTimeoutPolicyProceed DrainTimeoutPolicy = "Proceed"
+	// DrainTimeoutPolicyMarkTimedOut keeps draining the node, and marks the MachineDrained condition
+	// with the DrainTimedOut reason so that the blocked deletion can be investigated.
+	DrainTimeoutPolicyMarkTimedOut DrainTimeoutPolicy = "MarkTimedOut"
+
+	// DrainTimedOutReason is the reason of the MachineDrained condition when the drain has timed out.
+	DrainTimedOutReason = "DrainTimedOut"
+)
+
+// DrainTimeoutPolicy is the policy applied once the drain timeout of a Machine has expired.
+type DrainTimeoutPolicy string
+
+// getDrainTimeout returns the drain timeout of the Machine, or 0 when it has none.
+func getDrainTimeout(m *machinev1.Machine) (time.Duration, error) {
+	value, ok := m.Annotations[DrainTimeoutAnnotation]
+	if !ok {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation: %v", DrainTimeoutAnnotation, err)
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("invalid %s annotation: timeout must not be negative", DrainTimeoutAnnotation)
+	}
+	return timeout, nil
+}
+
+// getDrainTimeoutPolicy returns the drain timeout policy of the Machine, defaulting to DrainTimeoutPolicyProceed.
+func getDrainTimeoutPolicy(m *machinev1.Machine) DrainTimeoutPolicy {
+	switch policy := DrainTimeoutPolicy(m.Annotations[DrainTimeoutPolicyAnnotation]); policy {
+	case "", DrainTimeoutPolicyProceed:
+		return DrainTimeoutPolicyProceed
+	case DrainTimeoutPolicyMarkTimedOut:
+		return policy
+	default:
+		klog.Warningf("%v: unknown %s annotation %q, defaulting to %s", m.Name, DrainTimeoutPolicyAnnotation, policy, DrainTimeoutPolicyProceed)
+		return DrainTimeoutPolicyProceed
+	}
+}
+
+// drainStarted returns when the drain of the node of the Machine started,
+// recording the current time on the Machine on the first drain attempt.
+func (d *machineDrainController) drainStarted(ctx context.Context, m *machinev1.Machine) (time.Time, error) {
+	if value, ok := m.Annotations[DrainStartedAnnotation]; ok {
+		started, err := time.Parse(time.RFC3339, value)
+		if err == nil {
+			return started, nil
+		}
+		klog.Warningf("%v: invalid %s annotation %q, restarting the drain timeout: %v", m.Name, DrainStartedAnnotation, value, err)
+	}
+
+	started := time.Now().UTC().Truncate(time.Second)
+	patch := client.MergeFrom(m.DeepCopy())
+	if m.Annotations == nil {
+		m.Annotations = map[string]string{}
+	}
+	m.Annotations[DrainStartedAnnotation] = started.Format(time.RFC3339)
+	if err := d.Client.Patch(ctx, m, patch); err != nil {
+		return time.Time{}, fmt.Errorf("could not record drain start: %w", err)
+	}
+	return started, nil
+}
+
+// markDrainTimedOut marks the MachineDrained condition of the Machine with the DrainTimedOut reason,
+// and requeues the drain. Unlike other drain errors, the condition is persisted straight away.
+func (d *machineDrainController) markDrainTimedOut(ctx context.Context, m *machinev1.Machine, timeout time.Duration, drainErr error) (reconcile.Result, error) {
+	klog.Warningf("%v: drain timed out after %v, waiting for the drain to finish", m.Name, timeout)
+	d.eventRecorder.Eventf(m, corev1.EventTypeWarning, "DrainTimedOut", "Node drain timed out after %v: %v", timeout, drainErr)
+	conditions.Set(m, conditions.FalseCondition(
+		machinev1.MachineDrained,
+		DrainTimedOutReason,
+		machinev1.ConditionSeverityError,
+		"drain timed out after %v: %v", timeout, drainErr,
+	))
+	if err := d.Client.Status().Update(ctx, m); err != nil {
+		return reconcile.Result{}, fmt.Errorf("could not update machine status: %w", err)
+	}
+	return delayIfRequeueAfterError(drainErr)
+}
@@ -0,0 +1,130 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+func TestGetDrainTimeout(t *testing.T) {
+	cases := []struct {
+		name            string
+		annotations     map[string]string
+		expectedTimeout time.Duration
+		expectedPolicy  DrainTimeoutPolicy
+		expectedError   string
+	}{
+		{
+			name:           "without drain timeout",
+			expectedPolicy: DrainTimeoutPolicyProceed,
+		},
+		{
+			name:            "with drain timeout",
+			annotations:     map[string]string{DrainTimeoutAnnotation: "30m"},
+			expectedTimeout: 30 * time.Minute,
+			expectedPolicy:  DrainTimeoutPolicyProceed,
+		},
+		{
+			name:            "with drain timeout policy",
+			annotations:     map[string]string{DrainTimeoutAnnotation: "1h", DrainTimeoutPolicyAnnotation: string(DrainTimeoutPolicyMarkTimedOut)},
+			expectedTimeout: time.Hour,
+			expectedPolicy:  DrainTimeoutPolicyMarkTimedOut,
+		},
+		{
+			name:            "with unknown drain timeout policy",
+			annotations:     map[string]string{DrainTimeoutAnnotation: "1h", DrainTimeoutPolicyAnnotation: "Wait"},
+			expectedTimeout: time.Hour,
+			expectedPolicy:  DrainTimeoutPolicyProceed,
+		},
+		{
+			name:           "with invalid drain timeout",
+			annotations:    map[string]string{DrainTimeoutAnnotation: "30"},
+			expectedPolicy: DrainTimeoutPolicyProceed,
+			expectedError:  "invalid machine.openshift.io/drain-timeout annotation: time: missing unit in duration \"30\"",
+		},
+		{
+			name:           "with negative drain timeout",
+			annotations:    map[string]string{DrainTimeoutAnnotation: "-30m"},
+			expectedPolicy: DrainTimeoutPolicyProceed,
+			expectedError:  "invalid machine.openshift.io/drain-timeout annotation: timeout must not be negative",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			m := getMachine("machine", machinev1.PhaseDeleting)
+			m.Annotations = tc.annotations
+
+			timeout, err := getDrainTimeout(m)
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+			g.Expect(timeout).To(Equal(tc.expectedTimeout))
+			g.Expect(getDrainTimeoutPolicy(m)).To(Equal(tc.expectedPolicy))
+		})
+	}
+}
+
+func TestDrainStarted(t *testing.T) {
+	g := NewWithT(t)
+
+	m := getMachine("machine", machinev1.PhaseDeleting)
+	d := &machineDrainController{
+		Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(m).Build(),
+		scheme:        scheme.Scheme,
+		eventRecorder: record.NewFakeRecorder(10),
+	}
+
+	started, err := d.drainStarted(context.TODO(), m)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(started).To(BeTemporally("~", time.Now(), 2*time.Second))
+
+	got := &machinev1.Machine{}
+	g.Expect(d.Client.Get(context.TODO(), client.ObjectKeyFromObject(m), got)).To(Succeed())
+	g.Expect(got.Annotations).To(HaveKeyWithValue(DrainStartedAnnotation, started.Format(time.RFC3339)))
+
+	// The drain start is only recorded once.
+	startedAgain, err := d.drainStarted(context.TODO(), got)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(startedAgain.Equal(started)).To(BeTrue())
+}
+
+func TestMarkDrainTimedOut(t *testing.T) {
+	g := NewWithT(t)
+
+	m := getMachine("machine", machinev1.PhaseDeleting)
+	recorder := record.NewFakeRecorder(10)
+	d := &machineDrainController{
+		Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(m).Build(),
+		scheme:        scheme.Scheme,
+		eventRecorder: recorder,
+	}
+
+	_, err := d.markDrainTimedOut(context.TODO(), m, time.Hour, errors.New("cannot evict pod as it would violate the pod's disruption budget"))
+	g.Expect(err).To(MatchError("cannot evict pod as it would violate the pod's disruption budget"))
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("DrainTimedOut")))
+
+	got := &machinev1.Machine{}
+	g.Expect(d.Client.Get(context.TODO(), client.ObjectKeyFromObject(m), got)).To(Succeed())
+	condition := conditions.Get(got, machinev1.MachineDrained)
+	g.Expect(condition).ToNot(BeNil())
+	g.Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+	g.Expect(condition.Reason).To(Equal(DrainTimedOutReason))
+	g.Expect(condition.Severity).To(Equal(machinev1.ConditionSeverityError))
+}
@@ -0,0 +1,114 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+)
+
+// newExternalMachineActuator returns an actuator handling the external machines, which represent pre-existing nodes,
+// without calling the cloud provider, and passing the other machines to the actuator of the platform.
+func newExternalMachineActuator(actuator Actuator, eventRecorder record.EventRecorder) Actuator {
+	return &externalMachineActuator{next: actuator, eventRecorder: eventRecorder}
+}
+
+// externalMachineActuator handles the external machines:
+//   - the instance of an external machine exists once its providerID is set, to be linked to its node;
+//   - it is never created, an external machine without a providerID is invalid;
+//   - its deletion, once the node is drained, waits until the decommission of the node is acknowledged.
+type externalMachineActuator struct {
+	next          Actuator
+	eventRecorder record.EventRecorder
+}
+
+func (a *externalMachineActuator) Unwrap() Actuator {
+	return a.next
+}
+
+func (a *externalMachineActuator) Create(ctx context.Context, m *machinev1.Machine) error {
+	if !annotations.IsExternalMachine(m) {
+		return a.next.Create(ctx, m)
+	}
+	return InvalidMachineConfiguration("external machine %s must have a providerID, the node it represents is not created by the Machine API", m.Name)
+}
+
+func (a *externalMachineActuator) Delete(ctx context.Context, m *machinev1.Machine) error {
+	if !annotations.IsExternalMachine(m) {
+		return a.next.Delete(ctx, m)
+	}
+	if annotations.IsDecommissioned(m) {
+		return nil
+	}
+	klog.Infof("%v: waiting for the decommission of the node of the external machine to be acknowledged with the %s annotation", m.Name, annotations.DecommissionedAnnotation)
+	a.eventRecorder.Eventf(m, corev1.EventTypeNormal, "DecommissionPending",
+		"The node has been drained, set the %s annotation to \"true\" once it is decommissioned", annotations.DecommissionedAnnotation)
+	return &RequeueAfterError{RequeueAfter: requeueAfter}
+}
+
+func (a *externalMachineActuator) Update(ctx context.Context, m *machinev1.Machine) error {
+	if !annotations.IsExternalMachine(m) {
+		return a.next.Update(ctx, m)
+	}
+	return nil
+}
+
+func (a *externalMachineActuator) Exists(ctx context.Context, m *machinev1.Machine) (bool, error) {
+	if !annotations.IsExternalMachine(m) {
+		return a.next.Exists(ctx, m)
+	}
+	if !m.DeletionTimestamp.IsZero() && annotations.IsDecommissioned(m) {
+		return false, nil
+	}
+	return pointer.StringDeref(m.Spec.ProviderID, "") != "", nil
+}
+
+func (a *externalMachineActuator) SetPowerState(ctx context.Context, m *machinev1.Machine, state MachinePowerState) error {
+	if annotations.IsExternalMachine(m) {
+		return fmt.Errorf("external machines can not be powered %s", state)
+	}
+	next, ok := a.next.(PowerStateActuator)
+	if !ok {
+		return errUnsupportedOperation
+	}
+	return next.SetPowerState(ctx, m, state)
+}
+
+func (a *externalMachineActuator) NeedsResize(ctx context.Context, m *machinev1.Machine) (bool, error) {
+	if annotations.IsExternalMachine(m) {
+		return false, nil
+	}
+	next, ok := a.next.(ResizeActuator)
+	if !ok {
+		return false, errUnsupportedOperation
+	}
+	return next.NeedsResize(ctx, m)
+}
+
+func (a *externalMachineActuator) Resize(ctx context.Context, m *machinev1.Machine) error {
+	if annotations.IsExternalMachine(m) {
+		return fmt.Errorf("external machines can not be resized")
+	}
+	next, ok := a.next.(ResizeActuator)
+	if !ok {
+		return errUnsupportedOperation
+	}
+	return next.Resize(ctx, m)
+}
+
+func (a *externalMachineActuator) Reboot(ctx context.Context, m *machinev1.Machine) error {
+	if annotations.IsExternalMachine(m) {
+		return fmt.Errorf("external machines can not be restarted")
+	}
+	next, ok := a.next.(RebootActuator)
+	if !ok {
+		return errUnsupportedOperation
+	}
+	return next.Reboot(ctx, m)
+}
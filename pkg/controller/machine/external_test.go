@@ -0,0 +1,108 @@
+package machine
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+)
+
+func TestExternalMachineActuator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("passes the other machines to the actuator of the platform", func(t *testing.T) {
+		g := NewWithT(t)
+
+		next := newTestActuator()
+		next.ExistsValue = true
+		actuator := newExternalMachineActuator(next, record.NewFakeRecorder(10))
+		m := getMachine("machine", machinev1.PhaseRunning)
+
+		g.Expect(actuator.Create(ctx, m)).To(Succeed())
+		g.Expect(actuator.Update(ctx, m)).To(Succeed())
+		g.Expect(actuator.Delete(ctx, m)).To(Succeed())
+		g.Expect(actuator.Exists(ctx, m)).To(BeTrue())
+		g.Expect(actuator.(PowerStateActuator).SetPowerState(ctx, m, MachinePowerStateOff)).To(Succeed())
+
+		g.Expect(next.CreateCallCount).To(BeEquivalentTo(1))
+		g.Expect(next.UpdateCallCount).To(BeEquivalentTo(1))
+		g.Expect(next.DeleteCallCount).To(BeEquivalentTo(1))
+		g.Expect(next.ExistsCallCount).To(BeEquivalentTo(1))
+		g.Expect(next.PowerState).To(Equal(MachinePowerStateOff))
+	})
+
+	t.Run("does not call the actuator of the platform for external machines", func(t *testing.T) {
+		g := NewWithT(t)
+
+		next := newTestActuator()
+		recorder := record.NewFakeRecorder(10)
+		actuator := newExternalMachineActuator(next, recorder)
+		m := getMachine("machine", machinev1.PhaseRunning)
+		m.Annotations[annotations.ExternalMachineAnnotation] = "true"
+
+		g.Expect(actuator.Exists(ctx, m)).To(BeFalse())
+		err := actuator.Create(ctx, m)
+		g.Expect(isInvalidMachineConfigurationError(err)).To(BeTrue())
+
+		m.Spec.ProviderID = pointer.String("baremetal:///worker-0")
+		g.Expect(actuator.Exists(ctx, m)).To(BeTrue())
+		g.Expect(actuator.Update(ctx, m)).To(Succeed())
+		g.Expect(actuator.(PowerStateActuator).SetPowerState(ctx, m, MachinePowerStateOff)).ToNot(Succeed())
+
+		// The deletion waits until the decommission of the node is acknowledged.
+		now := metav1.Now()
+		m.DeletionTimestamp = &now
+		g.Expect(actuator.Delete(ctx, m)).To(BeAssignableToTypeOf(&RequeueAfterError{}))
+		g.Expect(recorder.Events).To(Receive(HavePrefix("Normal DecommissionPending")))
+		g.Expect(actuator.Exists(ctx, m)).To(BeTrue())
+
+		m.Annotations[annotations.DecommissionedAnnotation] = "true"
+		g.Expect(actuator.Delete(ctx, m)).To(Succeed())
+		g.Expect(actuator.Exists(ctx, m)).To(BeFalse())
+
+		g.Expect(next.CreateCallCount).To(BeZero())
+		g.Expect(next.UpdateCallCount).To(BeZero())
+		g.Expect(next.DeleteCallCount).To(BeZero())
+		g.Expect(next.ExistsCallCount).To(BeZero())
+		g.Expect(next.PowerState).To(BeEmpty())
+	})
+}
+
+func TestWrappingActuatorsOptionalInterfaces(t *testing.T) {
+	ctx := context.Background()
+	m := getMachine("machine", machinev1.PhaseRunning)
+
+	t.Run("pass the calls through when the actuator of the platform supports them", func(t *testing.T) {
+		g := NewWithT(t)
+
+		next := &testRebootActuator{TestActuator: newTestActuator()}
+		actuator := newTracingActuator(newExternalMachineActuator(next, record.NewFakeRecorder(10)))
+
+		g.Expect(unwrapActuator(actuator)).To(BeIdenticalTo(next))
+		g.Expect(actuator.(PowerStateActuator).SetPowerState(ctx, m, MachinePowerStateOff)).To(Succeed())
+		g.Expect(actuator.(RebootActuator).Reboot(ctx, m)).To(Succeed())
+		g.Expect(next.PowerState).To(Equal(MachinePowerStateOff))
+		g.Expect(next.reboots).To(Equal(1))
+	})
+
+	t.Run("return an error when the actuator of the platform does not support them", func(t *testing.T) {
+		g := NewWithT(t)
+
+		next := struct{ Actuator }{newTestActuator()}
+		actuator := newTracingActuator(newExternalMachineActuator(next, record.NewFakeRecorder(10)))
+
+		_, supported := unwrapActuator(actuator).(PowerStateActuator)
+		g.Expect(supported).To(BeFalse())
+		g.Expect(actuator.(PowerStateActuator).SetPowerState(ctx, m, MachinePowerStateOff)).To(MatchError(errUnsupportedOperation))
+		g.Expect(actuator.(ResizeActuator).Resize(ctx, m)).To(MatchError(errUnsupportedOperation))
+		g.Expect(actuator.(RebootActuator).Reboot(ctx, m)).To(MatchError(errUnsupportedOperation))
+	})
+}
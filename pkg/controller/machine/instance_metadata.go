@@ -0,0 +1,145 @@
+package machine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+const (
+	// MachineInstanceIPsAnnotationName as annotation name for the comma-separated IPs assigned to a machine instance
+	MachineInstanceIPsAnnotationName = "machine.openshift.io/instance-ips"
+
+	// InstanceMetadataAvailableCondition reports whether the instance type, region and zone of the Machine are known.
+	InstanceMetadataAvailableCondition machinev1.ConditionType = "InstanceMetadataAvailable"
+
+	// InstanceMetadataIncompleteReason is the reason of the InstanceMetadataAvailable condition when some
+	// of the instance metadata could not be found in the provider spec or status.
+	InstanceMetadataIncompleteReason = "InstanceMetadataIncomplete"
+)
+
+// The provider spec and status fields holding the instance metadata, in order of precedence.
+// instanceType is used by AWS, machineType by GCP, vmSize by Azure and flavor by OpenStack.
+var (
+	instanceTypeFields = [][]string{{"instanceType"}, {"machineType"}, {"vmSize"}, {"flavor"}}
+	regionFields       = [][]string{{"placement", "region"}, {"region"}, {"location"}}
+	zoneFields         = [][]string{{"placement", "availabilityZone"}, {"zone"}, {"availabilityZone"}}
+)
+
+// instanceMetadata is the provider-agnostic metadata of the instance of a Machine.
+type instanceMetadata struct {
+	instanceType string
+	region       string
+	zone         string
+	ips          []string
+}
+
+// extractInstanceMetadata extracts the instance metadata of the Machine from its provider status,
+// falling back to its provider spec, and from its addresses.
+func extractInstanceMetadata(m *machinev1.Machine) instanceMetadata {
+	var sources []map[string]interface{}
+	for _, raw := range []*runtime.RawExtension{m.Status.ProviderStatus, m.Spec.ProviderSpec.Value} {
+		if raw == nil || len(raw.Raw) == 0 {
+			continue
+		}
+		source := map[string]interface{}{}
+		if err := json.Unmarshal(raw.Raw, &source); err != nil {
+			klog.V(3).Infof("%v: could not decode provider config for instance metadata: %v", m.GetName(), err)
+			continue
+		}
+		sources = append(sources, source)
+	}
+
+	metadata := instanceMetadata{
+		instanceType: lookupInstanceMetadataField(sources, instanceTypeFields),
+		region:       lookupInstanceMetadataField(sources, regionFields),
+		zone:         lookupInstanceMetadataField(sources, zoneFields),
+	}
+	for _, address := range m.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP || address.Type == corev1.NodeExternalIP {
+			metadata.ips = append(metadata.ips, address.Address)
+		}
+	}
+	sort.Strings(metadata.ips)
+	return metadata
+}
+
+// lookupInstanceMetadataField returns the first non-empty string found at one of the fields of the sources.
+func lookupInstanceMetadataField(sources []map[string]interface{}, fields [][]string) string {
+	for _, source := range sources {
+		for _, field := range fields {
+			if value, found, err := unstructured.NestedString(source, field...); err == nil && found && value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// syncInstanceMetadata surfaces the instance metadata of the Machine in its labels, which are shown by
+// `oc get machines`, and its annotations, and reports whether it is complete in the InstanceMetadataAvailable
+// condition. Labels already set, e.g. by the provider, are left untouched.
+func (r *ReconcileMachine) syncInstanceMetadata(ctx context.Context, m *machinev1.Machine) error {
+	metadata := extractInstanceMetadata(m)
+
+	updated := m.DeepCopy()
+	if updated.Labels == nil {
+		updated.Labels = map[string]string{}
+	}
+	for label, value := range map[string]string{
+		MachineInstanceTypeLabelName: metadata.instanceType,
+		MachineRegionLabelName:       metadata.region,
+		MachineAZLabelName:           metadata.zone,
+	} {
+		if _, ok := updated.Labels[label]; !ok && value != "" {
+			updated.Labels[label] = value
+		}
+	}
+	if len(metadata.ips) > 0 {
+		if updated.Annotations == nil {
+			updated.Annotations = map[string]string{}
+		}
+		updated.Annotations[MachineInstanceIPsAnnotationName] = strings.Join(metadata.ips, ",")
+	} else {
+		delete(updated.Annotations, MachineInstanceIPsAnnotationName)
+	}
+
+	if !equality.Semantic.DeepEqual(updated.ObjectMeta, m.ObjectMeta) {
+		// Patch a copy of the Machine so that the local changes to its status are not lost.
+		if err := r.Client.Patch(ctx, updated, client.MergeFrom(m)); err != nil {
+			return fmt.Errorf("could not patch instance metadata: %w", err)
+		}
+		m.ObjectMeta = updated.ObjectMeta
+	}
+
+	var missing []string
+	for _, label := range []string{MachineInstanceTypeLabelName, MachineRegionLabelName, MachineAZLabelName} {
+		if m.Labels[label] == "" {
+			missing = append(missing, strings.TrimPrefix(label, "machine.openshift.io/"))
+		}
+	}
+	if len(missing) > 0 {
+		conditions.Set(m, conditions.FalseCondition(
+			InstanceMetadataAvailableCondition,
+			InstanceMetadataIncompleteReason,
+			machinev1.ConditionSeverityInfo,
+			"Instance metadata not found in the provider spec or status: %s", strings.Join(missing, ", "),
+		))
+		return nil
+	}
+	conditions.MarkTrue(m, InstanceMetadataAvailableCondition)
+	return nil
+}
@@ -0,0 +1,110 @@
+package machine
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+func TestExtractInstanceMetadata(t *testing.T) {
+	cases := []struct {
+		name           string
+		providerSpec   string
+		providerStatus string
+		addresses      []corev1.NodeAddress
+		expected       instanceMetadata
+	}{
+		{
+			name:     "without provider config",
+			expected: instanceMetadata{},
+		},
+		{
+			name:         "with AWS provider spec",
+			providerSpec: `{"instanceType": "m5.large", "placement": {"region": "us-east-1", "availabilityZone": "us-east-1a"}}`,
+			expected:     instanceMetadata{instanceType: "m5.large", region: "us-east-1", zone: "us-east-1a"},
+		},
+		{
+			name:         "with Azure provider spec",
+			providerSpec: `{"vmSize": "Standard_D4s_v3", "location": "centralus", "zone": "1"}`,
+			expected:     instanceMetadata{instanceType: "Standard_D4s_v3", region: "centralus", zone: "1"},
+		},
+		{
+			name:           "with provider status taking precedence",
+			providerSpec:   `{"machineType": "n1-standard-4", "region": "us-central1", "zone": "us-central1-a"}`,
+			providerStatus: `{"zone": "us-central1-b"}`,
+			expected:       instanceMetadata{instanceType: "n1-standard-4", region: "us-central1", zone: "us-central1-b"},
+		},
+		{
+			name:         "with invalid provider spec",
+			providerSpec: `{"instanceType": 4}`,
+			expected:     instanceMetadata{},
+		},
+		{
+			name: "with addresses",
+			addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.2"},
+				{Type: corev1.NodeInternalDNS, Address: "ip-10-0-0-2.ec2.internal"},
+				{Type: corev1.NodeExternalIP, Address: "1.2.3.4"},
+			},
+			expected: instanceMetadata{ips: []string{"1.2.3.4", "10.0.0.2"}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			m := getMachine("machine", machinev1.PhaseRunning)
+			if tc.providerSpec != "" {
+				m.Spec.ProviderSpec.Value = &runtime.RawExtension{Raw: []byte(tc.providerSpec)}
+			}
+			if tc.providerStatus != "" {
+				m.Status.ProviderStatus = &runtime.RawExtension{Raw: []byte(tc.providerStatus)}
+			}
+			m.Status.Addresses = tc.addresses
+
+			g.Expect(extractInstanceMetadata(m)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestSyncInstanceMetadata(t *testing.T) {
+	g := NewWithT(t)
+
+	m := getMachine("machine", machinev1.PhaseRunning)
+	m.Labels[MachineRegionLabelName] = "provider-region"
+	m.Spec.ProviderSpec.Value = &runtime.RawExtension{Raw: []byte(`{"instanceType": "m5.large", "placement": {"region": "us-east-1"}}`)}
+	m.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.2"}}
+
+	r := &ReconcileMachine{
+		Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(m).Build(),
+		scheme:        scheme.Scheme,
+		eventRecorder: record.NewFakeRecorder(10),
+	}
+
+	g.Expect(r.syncInstanceMetadata(context.TODO(), m)).To(Succeed())
+
+	got := &machinev1.Machine{}
+	g.Expect(r.Client.Get(context.TODO(), client.ObjectKeyFromObject(m), got)).To(Succeed())
+	g.Expect(got.Labels).To(HaveKeyWithValue(MachineInstanceTypeLabelName, "m5.large"))
+	g.Expect(got.Labels).To(HaveKeyWithValue(MachineRegionLabelName, "provider-region"))
+	g.Expect(got.Labels).ToNot(HaveKey(MachineAZLabelName))
+	g.Expect(got.Annotations).To(HaveKeyWithValue(MachineInstanceIPsAnnotationName, "10.0.0.2"))
+
+	condition := conditions.Get(m, InstanceMetadataAvailableCondition)
+	g.Expect(condition).ToNot(BeNil())
+	g.Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+	g.Expect(condition.Reason).To(Equal(InstanceMetadataIncompleteReason))
+	g.Expect(condition.Message).To(Equal("Instance metadata not found in the provider spec or status: zone"))
+}
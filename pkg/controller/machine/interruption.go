@@ -0,0 +1,53 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/termination"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+// reconcileInterruption sets the Terminating condition of the Machine once the termination handler has set the
+// Terminating condition of its node, with the deadline of the termination, and records the interruption once.
+// The condition is persisted by the next status update of the Machine.
+func (r *ReconcileMachine) reconcileInterruption(ctx context.Context, m *machinev1.Machine) error {
+	if m.Status.NodeRef == nil {
+		return nil
+	}
+
+	node := &corev1.Node{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: m.Status.NodeRef.Name}, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get node %q: %w", m.Status.NodeRef.Name, err)
+	}
+
+	nodeCondition := termination.GetNodeTerminatingCondition(node)
+	if nodeCondition == nil {
+		return nil
+	}
+
+	if c := conditions.Get(m, termination.MachineConditionTerminating); c != nil && c.Status == corev1.ConditionTrue {
+		return nil
+	}
+
+	klog.Infof("%v: node %q is terminating: %s", m.GetName(), node.Name, nodeCondition.Message)
+	conditions.Set(m, &machinev1.Condition{
+		Type:     termination.MachineConditionTerminating,
+		Status:   corev1.ConditionTrue,
+		Severity: machinev1.ConditionSeverityWarning,
+		Reason:   termination.InterruptionReceivedReason,
+		Message:  nodeCondition.Message,
+	})
+	r.eventRecorder.Eventf(m, corev1.EventTypeWarning, termination.InterruptionReceivedReason, "Node %s: %s", node.Name, nodeCondition.Message)
+	return nil
+}
@@ -0,0 +1,103 @@
+package machine
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/termination"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+func TestReconcileInterruption(t *testing.T) {
+	const message = "The cloud provider terminates the instance at 2026-10-16T08:22:00Z"
+
+	testCases := []struct {
+		name           string
+		nodeConditions []corev1.NodeCondition
+		noNode         bool
+		expectMarked   bool
+	}{
+		{
+			name: "node is terminating",
+			nodeConditions: []corev1.NodeCondition{{
+				Type:    termination.NodeConditionTerminating,
+				Status:  corev1.ConditionTrue,
+				Reason:  termination.TerminationRequestedReason,
+				Message: message,
+			}},
+			expectMarked: true,
+		},
+		{
+			name: "node is not terminating",
+			nodeConditions: []corev1.NodeCondition{{
+				Type:   corev1.NodeReady,
+				Status: corev1.ConditionTrue,
+			}},
+		},
+		{
+			name:   "node is gone",
+			noNode: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			m := getMachine("machine", machinev1.PhaseRunning)
+			m.Spec.ProviderID = pointer.String("test:///machine")
+			objects := []client.Object{m}
+			if !tc.noNode {
+				objects = append(objects, &corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: m.Status.NodeRef.Name},
+					Status:     corev1.NodeStatus{Conditions: tc.nodeConditions},
+				})
+			}
+			actuator := newTestActuator()
+			actuator.ExistsValue = true
+			recorder := record.NewFakeRecorder(10)
+			r := &ReconcileMachine{
+				Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build(),
+				scheme:        scheme.Scheme,
+				eventRecorder: recorder,
+				actuator:      actuator,
+			}
+			request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(m)}
+
+			// The interruption is recorded once, however many times the Machine is reconciled.
+			for i := 0; i < 2; i++ {
+				_, err := r.Reconcile(context.TODO(), request)
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+
+			got := &machinev1.Machine{}
+			g.Expect(r.Client.Get(context.TODO(), request.NamespacedName, got)).To(Succeed())
+			if !tc.expectMarked {
+				g.Expect(conditions.Get(got, termination.MachineConditionTerminating)).To(BeNil())
+				g.Expect(recorder.Events).To(BeEmpty())
+				return
+			}
+			g.Expect(conditions.Get(got, termination.MachineConditionTerminating)).To(SatisfyAll(
+				HaveField("Status", corev1.ConditionTrue),
+				HaveField("Severity", machinev1.ConditionSeverityWarning),
+				HaveField("Reason", termination.InterruptionReceivedReason),
+				HaveField("Message", message),
+			))
+			g.Expect(recorder.Events).To(HaveLen(1))
+			g.Expect(<-recorder.Events).To(Equal("Warning InterruptionReceived Node foo: " + message))
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package machine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/metrics"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+const (
+	// LifecycleHookTimeoutsAnnotation sets how long the lifecycle hooks of a deleting Machine may block its
+	// deletion, as a JSON object of hook names to durations, e.g. {"migrate-workloads": "1h"}.
+	// Pre-drain hooks expire after the timeout from the deletion of the Machine, and pre-terminate hooks
+	// after the timeout from the drain of its node. Expired hooks are removed by the Machine controller.
+	LifecycleHookTimeoutsAnnotation = "machine.openshift.io/lifecycle-hook-timeouts"
+
+	preDrainHookStage     = "preDrain"
+	preTerminateHookStage = "preTerminate"
+)
+
+// getLifecycleHookTimeouts returns the lifecycle hook timeouts of the Machine, by hook name.
+func getLifecycleHookTimeouts(m *machinev1.Machine) (map[string]time.Duration, error) {
+	value, ok := m.Annotations[LifecycleHookTimeoutsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	parsed := map[string]metav1.Duration{}
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", LifecycleHookTimeoutsAnnotation, err)
+	}
+	timeouts := map[string]time.Duration{}
+	for name, timeout := range parsed {
+		if timeout.Duration < 0 {
+			return nil, fmt.Errorf("invalid %s annotation: timeout of hook %q must not be negative", LifecycleHookTimeoutsAnnotation, name)
+		}
+		timeouts[name] = timeout.Duration
+	}
+	return timeouts, nil
+}
+
+// expiredLifecycleHooks splits the hooks blocking since the given time into the ones still active and
+// the ones whose timeout has expired, along with the time until the next active hook expires.
+func expiredLifecycleHooks(hooks []machinev1.LifecycleHook, timeouts map[string]time.Duration, since, now time.Time) ([]machinev1.LifecycleHook, []machinev1.LifecycleHook, time.Duration) {
+	var active, expired []machinev1.LifecycleHook
+	var nextExpiry time.Duration
+	for _, hook := range hooks {
+		timeout, ok := timeouts[hook.Name]
+		if !ok {
+			active = append(active, hook)
+			continue
+		}
+
+		remaining := since.Add(timeout).Sub(now)
+		if remaining <= 0 {
+			expired = append(expired, hook)
+			continue
+		}
+		active = append(active, hook)
+		if nextExpiry == 0 || remaining < nextExpiry {
+			nextExpiry = remaining
+		}
+	}
+	return active, expired, nextExpiry
+}
+
+// expireLifecycleHooks removes the lifecycle hooks of the deleting Machine whose timeout has expired.
+// It returns the time until the next lifecycle hook expires, or 0 when none will.
+func (r *ReconcileMachine) expireLifecycleHooks(ctx context.Context, m *machinev1.Machine) (time.Duration, error) {
+	timeouts, err := getLifecycleHookTimeouts(m)
+	if err != nil {
+		klog.Warningf("%v: ignoring lifecycle hook timeouts: %v", m.Name, err)
+		return 0, nil
+	}
+	if len(timeouts) == 0 {
+		return 0, nil
+	}
+
+	now := r.now()
+	preDrain, expiredPreDrain, nextExpiry := expiredLifecycleHooks(m.Spec.LifecycleHooks.PreDrain, timeouts, m.DeletionTimestamp.Time, now)
+
+	// Pre-terminate hooks only block the deletion once the node has been drained.
+	preTerminate := m.Spec.LifecycleHooks.PreTerminate
+	var expiredPreTerminate []machinev1.LifecycleHook
+	if drainedCondition := conditions.Get(m, machinev1.MachineDrained); drainedCondition != nil && drainedCondition.Status == corev1.ConditionTrue {
+		var nextPreTerminateExpiry time.Duration
+		preTerminate, expiredPreTerminate, nextPreTerminateExpiry = expiredLifecycleHooks(preTerminate, timeouts, drainedCondition.LastTransitionTime.Time, now)
+		if nextExpiry == 0 || (nextPreTerminateExpiry > 0 && nextPreTerminateExpiry < nextExpiry) {
+			nextExpiry = nextPreTerminateExpiry
+		}
+	}
+
+	if len(expiredPreDrain) == 0 && len(expiredPreTerminate) == 0 {
+		return nextExpiry, nil
+	}
+
+	m.Spec.LifecycleHooks.PreDrain = preDrain
+	m.Spec.LifecycleHooks.PreTerminate = preTerminate
+	if err := r.Client.Update(ctx, m); err != nil {
+		return 0, fmt.Errorf("failed to remove expired lifecycle hooks: %w", err)
+	}
+
+	for _, expired := range []struct {
+		stage string
+		hooks []machinev1.LifecycleHook
+	}{{preDrainHookStage, expiredPreDrain}, {preTerminateHookStage, expiredPreTerminate}} {
+		stage := expired.stage
+		for _, hook := range expired.hooks {
+			klog.Warningf("%v: removed %s lifecycle hook %q owned by %q after its timeout of %v", m.Name, stage, hook.Name, hook.Owner, timeouts[hook.Name])
+			r.eventRecorder.Eventf(m, corev1.EventTypeWarning, "LifecycleHookExpired",
+				"Removed %s lifecycle hook %q owned by %q after its timeout of %v", stage, hook.Name, hook.Owner, timeouts[hook.Name])
+			metrics.ObserveMachineLifecycleHookExpired(m.Namespace, hook.Name, hook.Owner, stage)
+		}
+	}
+	return nextExpiry, nil
+}
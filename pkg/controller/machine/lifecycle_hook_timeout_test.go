@@ -0,0 +1,127 @@
+package machine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+)
+
+func TestExpireLifecycleHooks(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	preDrainHook := machinev1.LifecycleHook{Name: "pre-drain", Owner: "pre-drain-owner"}
+	preTerminateHook := machinev1.LifecycleHook{Name: "pre-terminate", Owner: "pre-terminate-owner"}
+	otherHook := machinev1.LifecycleHook{Name: "other", Owner: "other-owner"}
+
+	cases := []struct {
+		name                 string
+		timeouts             string
+		deleted              time.Duration
+		drained              *time.Duration
+		expectedPreDrain     []machinev1.LifecycleHook
+		expectedPreTerminate []machinev1.LifecycleHook
+		expectedNextExpiry   time.Duration
+		expectedEvents       int
+	}{
+		{
+			name:                 "without timeouts",
+			deleted:              24 * time.Hour,
+			expectedPreDrain:     []machinev1.LifecycleHook{preDrainHook, otherHook},
+			expectedPreTerminate: []machinev1.LifecycleHook{preTerminateHook},
+		},
+		{
+			name:                 "with invalid timeouts",
+			timeouts:             `{"pre-drain": "1"}`,
+			deleted:              24 * time.Hour,
+			expectedPreDrain:     []machinev1.LifecycleHook{preDrainHook, otherHook},
+			expectedPreTerminate: []machinev1.LifecycleHook{preTerminateHook},
+		},
+		{
+			name:                 "with pre-drain hook before its timeout",
+			timeouts:             `{"pre-drain": "1h"}`,
+			deleted:              20 * time.Minute,
+			expectedPreDrain:     []machinev1.LifecycleHook{preDrainHook, otherHook},
+			expectedPreTerminate: []machinev1.LifecycleHook{preTerminateHook},
+			expectedNextExpiry:   40 * time.Minute,
+		},
+		{
+			name:                 "with pre-drain hook after its timeout",
+			timeouts:             `{"pre-drain": "1h", "pre-terminate": "1h"}`,
+			deleted:              2 * time.Hour,
+			expectedPreDrain:     []machinev1.LifecycleHook{otherHook},
+			expectedPreTerminate: []machinev1.LifecycleHook{preTerminateHook},
+			expectedEvents:       1,
+		},
+		{
+			name:                 "with pre-terminate hook before its timeout",
+			timeouts:             `{"pre-terminate": "1h"}`,
+			deleted:              2 * time.Hour,
+			drained:              durationPtr(30 * time.Minute),
+			expectedPreDrain:     []machinev1.LifecycleHook{preDrainHook, otherHook},
+			expectedPreTerminate: []machinev1.LifecycleHook{preTerminateHook},
+			expectedNextExpiry:   30 * time.Minute,
+		},
+		{
+			name:               "with all hooks after their timeout",
+			timeouts:           `{"pre-drain": "1h", "pre-terminate": "1h", "other": "30m"}`,
+			deleted:            3 * time.Hour,
+			drained:            durationPtr(2 * time.Hour),
+			expectedPreDrain:   nil,
+			expectedNextExpiry: 0,
+			expectedEvents:     3,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			m := getMachine("machine", machinev1.PhaseDeleting)
+			m.DeletionTimestamp = &metav1.Time{Time: now.Add(-tc.deleted)}
+			if tc.timeouts != "" {
+				m.Annotations[LifecycleHookTimeoutsAnnotation] = tc.timeouts
+			}
+			m.Spec.LifecycleHooks.PreDrain = []machinev1.LifecycleHook{preDrainHook, otherHook}
+			m.Spec.LifecycleHooks.PreTerminate = []machinev1.LifecycleHook{preTerminateHook}
+			if tc.drained != nil {
+				m.Status.Conditions = machinev1.Conditions{{
+					Type:               machinev1.MachineDrained,
+					Status:             corev1.ConditionTrue,
+					LastTransitionTime: metav1.Time{Time: now.Add(-*tc.drained)},
+				}}
+			}
+
+			recorder := record.NewFakeRecorder(10)
+			r := &ReconcileMachine{
+				Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(m).Build(),
+				scheme:        scheme.Scheme,
+				eventRecorder: recorder,
+				nowFunc:       func() time.Time { return now },
+			}
+
+			nextExpiry, err := r.expireLifecycleHooks(context.TODO(), m)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(nextExpiry).To(Equal(tc.expectedNextExpiry))
+			g.Expect(recorder.Events).To(HaveLen(tc.expectedEvents))
+
+			got := &machinev1.Machine{}
+			g.Expect(r.Client.Get(context.TODO(), client.ObjectKeyFromObject(m), got)).To(Succeed())
+			g.Expect(got.Spec.LifecycleHooks.PreDrain).To(Equal(tc.expectedPreDrain))
+			g.Expect(got.Spec.LifecycleHooks.PreTerminate).To(Equal(tc.expectedPreTerminate))
+		})
+	}
+}
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
@@ -0,0 +1,76 @@
+package machine
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+const (
+	// NodeStartupTimeoutAnnotation sets how long the node of a Machine may take to register once its instance
+	// exists, as a duration, e.g. "20m". After the timeout, the Machine goes into the Failed phase with the
+	// NodeStartupTimeoutMachineError reason, so that a MachineHealthCheck or its MachineSet can replace it.
+	// MachineSets set it on their Machines through the annotations of their template.
+	// Node startups are not time limited without it.
+	NodeStartupTimeoutAnnotation = "machine.openshift.io/node-startup-timeout"
+
+	// NodeStartupTimeoutMachineError is the error reason of the Machines whose node did not register in time.
+	NodeStartupTimeoutMachineError machinev1.MachineStatusError = "NodeStartupTimeout"
+)
+
+// getNodeStartupTimeout returns the node startup timeout of the Machine, or 0 when it has none.
+func getNodeStartupTimeout(m *machinev1.Machine) (time.Duration, error) {
+	value, ok := m.Annotations[NodeStartupTimeoutAnnotation]
+	if !ok {
+		return 0, nil
+	}
+	timeout, err := parseNodeStartupTimeout(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation: %v", NodeStartupTimeoutAnnotation, err)
+	}
+	return timeout, nil
+}
+
+// ValidateNodeStartupTimeout returns an error when the value of the NodeStartupTimeoutAnnotation is invalid.
+func ValidateNodeStartupTimeout(value string) error {
+	_, err := parseNodeStartupTimeout(value)
+	return err
+}
+
+// parseNodeStartupTimeout parses the value of the NodeStartupTimeoutAnnotation.
+func parseNodeStartupTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("timeout must be greater than zero")
+	}
+	return timeout, nil
+}
+
+// checkNodeStartupTimeout returns an error when the node of the Machine did not register within the node
+// startup timeout, measured from the time the instance of the Machine was found to exist.
+func (r *ReconcileMachine) checkNodeStartupTimeout(m *machinev1.Machine) error {
+	timeout, err := getNodeStartupTimeout(m)
+	if err != nil || timeout == 0 {
+		return err
+	}
+
+	instanceExists := conditions.Get(m, machinev1.InstanceExistsCondition)
+	if instanceExists == nil || instanceExists.Status != corev1.ConditionTrue {
+		return nil
+	}
+	if waited := r.now().Sub(instanceExists.LastTransitionTime.Time); waited >= timeout {
+		return &MachineError{
+			Reason:  NodeStartupTimeoutMachineError,
+			Message: fmt.Sprintf("Node did not register within the node startup timeout of %v", timeout),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package machine
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+)
+
+func TestCheckNodeStartupTimeout(t *testing.T) {
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name                string
+		annotations         map[string]string
+		instanceExistsSince time.Duration
+		expectedReason      machinev1.MachineStatusError
+		expectedError       string
+	}{
+		{
+			name:                "without node startup timeout",
+			instanceExistsSince: time.Hour,
+		},
+		{
+			name:                "within the node startup timeout",
+			annotations:         map[string]string{NodeStartupTimeoutAnnotation: "20m"},
+			instanceExistsSince: 10 * time.Minute,
+		},
+		{
+			name:                "after the node startup timeout",
+			annotations:         map[string]string{NodeStartupTimeoutAnnotation: "20m"},
+			instanceExistsSince: 20 * time.Minute,
+			expectedReason:      NodeStartupTimeoutMachineError,
+			expectedError:       "Node did not register within the node startup timeout of 20m0s",
+		},
+		{
+			name:                "with invalid node startup timeout",
+			annotations:         map[string]string{NodeStartupTimeoutAnnotation: "0s"},
+			instanceExistsSince: time.Hour,
+			expectedError:       "invalid machine.openshift.io/node-startup-timeout annotation: timeout must be greater than zero",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			m := getMachine("machine", machinev1.PhaseProvisioned)
+			m.Annotations = tc.annotations
+			m.Status.Conditions = machinev1.Conditions{{
+				Type:               machinev1.InstanceExistsCondition,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(now.Add(-tc.instanceExistsSince)),
+			}}
+
+			r := &ReconcileMachine{nowFunc: func() time.Time { return now }}
+			err := r.checkNodeStartupTimeout(m)
+			if tc.expectedError == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(MatchError(tc.expectedError))
+			if tc.expectedReason != "" {
+				g.Expect(err).To(BeAssignableToTypeOf(&MachineError{}))
+				g.Expect(err.(*MachineError).Reason).To(Equal(tc.expectedReason))
+			}
+		})
+	}
+}
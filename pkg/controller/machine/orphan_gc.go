@@ -0,0 +1,176 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/machine-api-operator/pkg/metrics"
+)
+
+// ProviderResourceKind is the kind of a resource of the cloud provider.
+type ProviderResourceKind string
+
+const (
+	// ProviderResourceInstance is the kind of the instances.
+	ProviderResourceInstance ProviderResourceKind = "Instance"
+	// ProviderResourceNetworkInterface is the kind of the network interfaces.
+	ProviderResourceNetworkInterface ProviderResourceKind = "NetworkInterface"
+	// ProviderResourceDisk is the kind of the disks.
+	ProviderResourceDisk ProviderResourceKind = "Disk"
+)
+
+// ProviderResource is a resource of the cloud provider created for a Machine.
+type ProviderResource struct {
+	// Kind is the kind of the resource.
+	Kind ProviderResourceKind
+	// ID is the ID of the resource in the cloud provider.
+	ID string
+	// ProviderID is the provider ID of the instance, as set in the spec of its Machine. It is empty for
+	// the resources which are not instances.
+	ProviderID string
+	// MachineName is the name of the Machine the resource was created for, as found in its tags.
+	MachineName string
+	// CreationTime is when the resource was created.
+	CreationTime time.Time
+}
+
+// OrphanGCMode is what the Machine controller does with the orphaned provider resources.
+type OrphanGCMode string
+
+const (
+	// OrphanGCModeAlert only reports the orphaned provider resources in the mapi_orphaned_provider_resources metric.
+	OrphanGCModeAlert OrphanGCMode = "alert"
+	// OrphanGCModeDelete also deletes the orphaned provider resources.
+	OrphanGCModeDelete OrphanGCMode = "delete"
+
+	// DefaultOrphanGCInterval is the default interval between two collections of the orphaned provider resources.
+	DefaultOrphanGCInterval = 30 * time.Minute
+	// DefaultOrphanGCTTL is the default duration after which the provider resources without a Machine are orphaned.
+	// It leaves time to the Machine controller to set the provider ID of the Machines whose instance is created.
+	DefaultOrphanGCTTL = 24 * time.Hour
+)
+
+// orphanCollector periodically compares the provider resources tagged with the cluster ID with the Machines,
+// and reports, or deletes, the ones left without a Machine for longer than the TTL, e.g. after a crash of the
+// Machine controller between the creation of an instance and the update of its Machine.
+type orphanCollector struct {
+	client   client.Reader
+	actuator ProviderResourceActuator
+	mode     OrphanGCMode
+	ttl      time.Duration
+	interval time.Duration
+
+	// nowFunc is used to mock time in testing. It should be nil in production.
+	nowFunc func() time.Time
+}
+
+func newOrphanCollector(client client.Reader, actuator ProviderResourceActuator, opts Options) (*orphanCollector, error) {
+	c := &orphanCollector{
+		client:   client,
+		actuator: actuator,
+		mode:     opts.OrphanGCMode,
+		ttl:      opts.OrphanGCTTL,
+		interval: opts.OrphanGCInterval,
+	}
+	switch c.mode {
+	case "":
+		c.mode = OrphanGCModeAlert
+	case OrphanGCModeAlert, OrphanGCModeDelete:
+	default:
+		return nil, fmt.Errorf("invalid orphan GC mode %q, expected %q or %q", c.mode, OrphanGCModeAlert, OrphanGCModeDelete)
+	}
+	if c.ttl <= 0 {
+		c.ttl = DefaultOrphanGCTTL
+	}
+	if c.interval <= 0 {
+		c.interval = DefaultOrphanGCInterval
+	}
+	return c, nil
+}
+
+// Start implements the manager.Runnable interface. It collects the orphaned provider resources until the
+// context is done. As a Runnable, it only runs on the leader.
+func (c *orphanCollector) Start(ctx context.Context) error {
+	klog.Infof("Collecting orphaned provider resources every %v in %s mode", c.interval, c.mode)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := c.collect(ctx); err != nil {
+			klog.Errorf("Failed to collect orphaned provider resources: %v", err)
+		}
+	}, c.interval)
+	return nil
+}
+
+// collect reports the provider resources orphaned for longer than the TTL and deletes them in delete mode.
+func (c *orphanCollector) collect(ctx context.Context) error {
+	resources, err := c.actuator.ListProviderResources(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list provider resources: %w", err)
+	}
+
+	// The Machines are listed after the resources, so that the Machines of the resources created meanwhile are found.
+	machineList := &machinev1.MachineList{}
+	if err := c.client.List(ctx, machineList); err != nil {
+		return fmt.Errorf("failed to list machines: %w", err)
+	}
+	machineNames := make(map[string]bool, len(machineList.Items))
+	providerIDs := make(map[string]bool, len(machineList.Items))
+	for _, m := range machineList.Items {
+		machineNames[m.Name] = true
+		if m.Spec.ProviderID != nil && *m.Spec.ProviderID != "" {
+			providerIDs[*m.Spec.ProviderID] = true
+		}
+	}
+
+	orphans := map[ProviderResourceKind]int{
+		ProviderResourceInstance:         0,
+		ProviderResourceNetworkInterface: 0,
+		ProviderResourceDisk:             0,
+	}
+	var errs []error
+	for _, resource := range resources {
+		// The resources which cannot be attributed to a Machine, e.g. the bootstrap instance, are not collected.
+		if resource.MachineName == "" && resource.ProviderID == "" {
+			continue
+		}
+		if machineNames[resource.MachineName] || providerIDs[resource.ProviderID] {
+			continue
+		}
+		if c.now().Sub(resource.CreationTime) < c.ttl {
+			continue
+		}
+
+		orphans[resource.Kind]++
+		if c.mode != OrphanGCModeDelete {
+			klog.Warningf("Found orphaned %s %s of machine %q, created at %v", resource.Kind, resource.ID, resource.MachineName, resource.CreationTime)
+			continue
+		}
+
+		klog.Infof("Deleting orphaned %s %s of machine %q, created at %v", resource.Kind, resource.ID, resource.MachineName, resource.CreationTime)
+		if err := c.actuator.DeleteProviderResource(ctx, resource); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %s %s: %w", resource.Kind, resource.ID, err))
+			continue
+		}
+		orphans[resource.Kind]--
+		metrics.OrphanedProviderResourcesDeletedTotal.With(prometheus.Labels{"kind": string(resource.Kind)}).Inc()
+	}
+
+	for kind, count := range orphans {
+		metrics.OrphanedProviderResources.With(prometheus.Labels{"kind": string(kind)}).Set(float64(count))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (c *orphanCollector) now() time.Time {
+	if c.nowFunc != nil {
+		return c.nowFunc()
+	}
+	return time.Now()
+}
@@ -0,0 +1,111 @@
+package machine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/metrics"
+)
+
+var _ ProviderResourceActuator = &testProviderResourceActuator{}
+
+type testProviderResourceActuator struct {
+	*TestActuator
+	resources []ProviderResource
+	deleted   []string
+}
+
+func (a *testProviderResourceActuator) ListProviderResources(context.Context) ([]ProviderResource, error) {
+	return a.resources, nil
+}
+
+func (a *testProviderResourceActuator) DeleteProviderResource(_ context.Context, resource ProviderResource) error {
+	a.deleted = append(a.deleted, resource.ID)
+	return nil
+}
+
+func TestCollectOrphans(t *testing.T) {
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	old := now.Add(-2 * DefaultOrphanGCTTL)
+
+	machine := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "machine", Namespace: "default"},
+		Spec:       machinev1.MachineSpec{ProviderID: pointer.String("aws:///us-east-1a/i-machine")},
+	}
+	resources := []ProviderResource{
+		{Kind: ProviderResourceInstance, ID: "i-machine", ProviderID: "aws:///us-east-1a/i-machine", CreationTime: old},
+		{Kind: ProviderResourceDisk, ID: "vol-machine", MachineName: "machine", CreationTime: old},
+		{Kind: ProviderResourceInstance, ID: "i-leaked", ProviderID: "aws:///us-east-1a/i-leaked", MachineName: "deleted", CreationTime: old},
+		{Kind: ProviderResourceNetworkInterface, ID: "eni-leaked", MachineName: "deleted", CreationTime: old},
+		{Kind: ProviderResourceInstance, ID: "i-creating", MachineName: "creating", CreationTime: now.Add(-time.Minute)},
+		{Kind: ProviderResourceInstance, ID: "i-bootstrap", CreationTime: old},
+	}
+
+	cases := []struct {
+		name            string
+		mode            OrphanGCMode
+		expectedDeleted []string
+		expectedOrphans map[ProviderResourceKind]float64
+	}{
+		{
+			name: "in alert mode",
+			mode: OrphanGCModeAlert,
+			expectedOrphans: map[ProviderResourceKind]float64{
+				ProviderResourceInstance:         1,
+				ProviderResourceNetworkInterface: 1,
+				ProviderResourceDisk:             0,
+			},
+		},
+		{
+			name:            "in delete mode",
+			mode:            OrphanGCModeDelete,
+			expectedDeleted: []string{"i-leaked", "eni-leaked"},
+			expectedOrphans: map[ProviderResourceKind]float64{
+				ProviderResourceInstance:         0,
+				ProviderResourceNetworkInterface: 0,
+				ProviderResourceDisk:             0,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			actuator := &testProviderResourceActuator{TestActuator: newTestActuator(), resources: resources}
+			c, err := newOrphanCollector(
+				fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(machine).Build(),
+				actuator,
+				Options{OrphanGCMode: tc.mode},
+			)
+			g.Expect(err).ToNot(HaveOccurred())
+			c.nowFunc = func() time.Time { return now }
+
+			g.Expect(c.collect(context.TODO())).To(Succeed())
+			g.Expect(actuator.deleted).To(Equal(tc.expectedDeleted))
+			for kind, expected := range tc.expectedOrphans {
+				m := &dto.Metric{}
+				g.Expect(metrics.OrphanedProviderResources.WithLabelValues(string(kind)).Write(m)).To(Succeed())
+				g.Expect(m.GetGauge().GetValue()).To(Equal(expected), "kind %s", kind)
+			}
+		})
+	}
+}
+
+func TestNewOrphanCollectorInvalidMode(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := newOrphanCollector(nil, &testProviderResourceActuator{}, Options{OrphanGCMode: "purge"})
+	g.Expect(err).To(MatchError(`invalid orphan GC mode "purge", expected "alert" or "delete"`))
+}
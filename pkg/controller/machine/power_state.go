@@ -0,0 +1,133 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+const (
+	// MachinePowerStateAnnotation sets the desired power state of a Machine, either MachinePowerStateOn
+	// (the default) or MachinePowerStateOff. Powered off Machines keep their instance and go into the
+	// PhaseStopped phase, their node is drained beforehand and uncordoned once they are powered back on.
+	// It requires an Actuator implementing PowerStateActuator.
+	MachinePowerStateAnnotation = "machine.openshift.io/power-state"
+
+	// MachinePowerStateOn is the power state of a running Machine.
+	MachinePowerStateOn MachinePowerState = "On"
+	// MachinePowerStateOff is the power state of a stopped Machine.
+	MachinePowerStateOff MachinePowerState = "Off"
+
+	// PhaseStopped is the phase of a Machine whose instance has been powered off.
+	PhaseStopped = "Stopped"
+
+	// InstancePoweredOffCondition is False while the node of a Machine is drained before its instance is
+	// powered off, and True while the instance is powered off.
+	InstancePoweredOffCondition machinev1.ConditionType = "InstancePoweredOff"
+
+	// PowerOffDrainingReason is the reason of the InstancePoweredOffCondition while the node of the Machine
+	// is drained.
+	PowerOffDrainingReason = "Draining"
+
+	// PowerOffUnsupportedReason is the reason of the InstancePoweredOffCondition while the Machine is to be
+	// powered off, but its Actuator does not implement PowerStateActuator.
+	PowerOffUnsupportedReason = "Unsupported"
+)
+
+// MachinePowerState is the power state of the instance of a Machine.
+type MachinePowerState string
+
+// getMachinePowerState returns the desired power state of the Machine, defaulting to MachinePowerStateOn.
+func getMachinePowerState(m *machinev1.Machine) MachinePowerState {
+	switch state := MachinePowerState(m.Annotations[MachinePowerStateAnnotation]); state {
+	case "", MachinePowerStateOn:
+		return MachinePowerStateOn
+	case MachinePowerStateOff:
+		return state
+	default:
+		klog.Warningf("%v: unknown %s annotation %q, defaulting to %s", m.Name, MachinePowerStateAnnotation, state, MachinePowerStateOn)
+		return MachinePowerStateOn
+	}
+}
+
+// machineIsStopped returns true if the Machine is in the PhaseStopped phase.
+func machineIsStopped(m *machinev1.Machine) bool {
+	return pointer.StringDeref(m.Status.Phase, "") == PhaseStopped
+}
+
+// isDrainingForPowerOff returns true if the node of the Machine is drained before its instance is powered off,
+// or stays drained while the instance is powered off.
+func isDrainingForPowerOff(m *machinev1.Machine) bool {
+	return conditions.Get(m, InstancePoweredOffCondition) != nil && !IsPowerOffUnsupported(m)
+}
+
+// IsPowerOffUnsupported returns true if the Machine is to be powered off, but its Actuator does not support it.
+func IsPowerOffUnsupported(m *machinev1.Machine) bool {
+	condition := conditions.Get(m, InstancePoweredOffCondition)
+	return condition != nil && condition.Status == corev1.ConditionFalse && condition.Reason == PowerOffUnsupportedReason
+}
+
+// reconcilePowerState powers the instance of the Machine off or on when its desired power state changes.
+// The node is cordoned and drained by the drain controller before the instance is powered off, and
+// uncordoned by it once the instance is powered back on.
+// It returns true if the Machine is powered off, and true while its node is drained before powering it off.
+func (r *ReconcileMachine) reconcilePowerState(ctx context.Context, m *machinev1.Machine) (bool, bool, error) {
+	state := getMachinePowerState(m)
+	if (state == MachinePowerStateOff) == machineIsStopped(m) {
+		if state == MachinePowerStateOn && IsPowerOffUnsupported(m) {
+			conditions.Delete(m, InstancePoweredOffCondition)
+		}
+		return machineIsStopped(m), false, nil
+	}
+
+	powerStateActuator, ok := r.actuator.(PowerStateActuator)
+	if _, supported := unwrapActuator(r.actuator).(PowerStateActuator); !ok || !supported {
+		// The condition reports the unsupported power off to the users, and to the bulk operations.
+		if state == MachinePowerStateOff && !IsPowerOffUnsupported(m) {
+			klog.Warningf("%v: the actuator does not support powering off machines, ignoring %s annotation", m.Name, MachinePowerStateAnnotation)
+			r.eventRecorder.Eventf(m, corev1.EventTypeWarning, "PowerStateUnsupported", "Powering off machines is not supported on this platform")
+			conditions.MarkFalse(m, InstancePoweredOffCondition, PowerOffUnsupportedReason, machinev1.ConditionSeverityWarning,
+				"Powering off machines is not supported on this platform")
+		}
+		return false, false, nil
+	}
+
+	if state == MachinePowerStateOff {
+		drainedCondition := conditions.Get(m, machinev1.MachineDrained)
+		if drainedCondition == nil || drainedCondition.Status != corev1.ConditionTrue {
+			if !isDrainingForPowerOff(m) {
+				klog.Infof("%v: draining node before powering off machine", m.Name)
+				r.eventRecorder.Eventf(m, corev1.EventTypeNormal, "PowerOffStarted", "Draining node before powering off machine")
+				conditions.MarkFalse(m, InstancePoweredOffCondition, PowerOffDrainingReason, machinev1.ConditionSeverityInfo,
+					"Draining node before powering off the instance")
+			} else {
+				klog.Infof("%v: waiting for node to be drained before powering off machine", m.Name)
+			}
+			return false, true, nil
+		}
+	}
+
+	if err := powerStateActuator.SetPowerState(ctx, m, state); err != nil {
+		r.eventRecorder.Eventf(m, corev1.EventTypeWarning, "FailedSetPowerState", "Failed to power %s machine: %v", state, err)
+		return machineIsStopped(m), false, fmt.Errorf("failed to power %s machine: %w", state, err)
+	}
+
+	if state == MachinePowerStateOff {
+		klog.Infof("%v: powered off machine", m.Name)
+		r.eventRecorder.Eventf(m, corev1.EventTypeNormal, "PoweredOff", "Machine powered off")
+		conditions.MarkTrue(m, InstancePoweredOffCondition)
+		return true, false, nil
+	}
+	klog.Infof("%v: powered on machine", m.Name)
+	r.eventRecorder.Eventf(m, corev1.EventTypeNormal, "PoweredOn", "Machine powered on")
+	// The drain controller uncordons the node once the condition is removed.
+	conditions.Delete(m, InstancePoweredOffCondition)
+	return false, false, nil
+}
@@ -0,0 +1,177 @@
+package machine
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+func TestReconcilePowerState(t *testing.T) {
+	cases := []struct {
+		name                     string
+		powerState               string
+		phase                    string
+		drained                  bool
+		expectedStopped          bool
+		expectedDraining         bool
+		expectedPowerState       MachinePowerState
+		expectedPoweredOffStatus corev1.ConditionStatus
+		expectedEvents           int
+	}{
+		{
+			name:  "running machine",
+			phase: machinev1.PhaseRunning,
+		},
+		{
+			name:                     "draining a running machine before powering it off",
+			powerState:               string(MachinePowerStateOff),
+			phase:                    machinev1.PhaseRunning,
+			expectedDraining:         true,
+			expectedPoweredOffStatus: corev1.ConditionFalse,
+			expectedEvents:           1,
+		},
+		{
+			name:                     "powering off a drained machine",
+			powerState:               string(MachinePowerStateOff),
+			phase:                    machinev1.PhaseRunning,
+			drained:                  true,
+			expectedStopped:          true,
+			expectedPowerState:       MachinePowerStateOff,
+			expectedPoweredOffStatus: corev1.ConditionTrue,
+			expectedEvents:           1,
+		},
+		{
+			name:            "stopped machine",
+			powerState:      string(MachinePowerStateOff),
+			phase:           PhaseStopped,
+			expectedStopped: true,
+		},
+		{
+			name:               "powering on a stopped machine",
+			powerState:         string(MachinePowerStateOn),
+			phase:              PhaseStopped,
+			drained:            true,
+			expectedPowerState: MachinePowerStateOn,
+			expectedEvents:     1,
+		},
+		{
+			name:               "powering on a stopped machine without power state",
+			phase:              PhaseStopped,
+			expectedPowerState: MachinePowerStateOn,
+			expectedEvents:     1,
+		},
+		{
+			name:       "unknown power state",
+			powerState: "Hibernate",
+			phase:      machinev1.PhaseRunning,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			m := getMachine("machine", tc.phase)
+			if tc.powerState != "" {
+				m.Annotations[MachinePowerStateAnnotation] = tc.powerState
+			}
+			if tc.drained {
+				conditions.MarkTrue(m, machinev1.MachineDrained)
+			}
+			if tc.phase == PhaseStopped {
+				conditions.MarkTrue(m, InstancePoweredOffCondition)
+			}
+
+			actuator := newTestActuator()
+			recorder := record.NewFakeRecorder(10)
+			r := &ReconcileMachine{
+				Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(m).Build(),
+				scheme:        scheme.Scheme,
+				eventRecorder: recorder,
+				actuator:      actuator,
+			}
+
+			stopped, draining, err := r.reconcilePowerState(context.TODO(), m)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(stopped).To(Equal(tc.expectedStopped))
+			g.Expect(draining).To(Equal(tc.expectedDraining))
+			g.Expect(actuator.PowerState).To(Equal(tc.expectedPowerState))
+			g.Expect(recorder.Events).To(HaveLen(tc.expectedEvents))
+			if tc.expectedPoweredOffStatus != "" {
+				g.Expect(conditions.Get(m, InstancePoweredOffCondition)).To(HaveField("Status", tc.expectedPoweredOffStatus))
+			} else if tc.expectedPowerState == MachinePowerStateOn {
+				g.Expect(conditions.Get(m, InstancePoweredOffCondition)).To(BeNil())
+			}
+		})
+	}
+}
+
+func TestReconcilePowerStateUnsupported(t *testing.T) {
+	g := NewWithT(t)
+
+	m := getMachine("machine", machinev1.PhaseRunning)
+	m.Annotations[MachinePowerStateAnnotation] = string(MachinePowerStateOff)
+
+	recorder := record.NewFakeRecorder(10)
+	r := &ReconcileMachine{
+		Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(m).Build(),
+		scheme:        scheme.Scheme,
+		eventRecorder: recorder,
+		actuator:      struct{ Actuator }{newTestActuator()},
+	}
+
+	stopped, draining, err := r.reconcilePowerState(context.TODO(), m)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(stopped).To(BeFalse())
+	g.Expect(draining).To(BeFalse())
+	// The node is not drained when the instance can not be powered off, which is reported once.
+	g.Expect(IsPowerOffUnsupported(m)).To(BeTrue())
+	g.Expect(isDrainingForPowerOff(m)).To(BeFalse())
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("PowerStateUnsupported")))
+	g.Expect(pointer.StringDeref(m.Status.Phase, "")).To(Equal(machinev1.PhaseRunning))
+
+	_, _, err = r.reconcilePowerState(context.TODO(), m)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(recorder.Events).ToNot(Receive())
+
+	// The condition is removed once the machine is to be powered on.
+	m.Annotations[MachinePowerStateAnnotation] = string(MachinePowerStateOn)
+	_, _, err = r.reconcilePowerState(context.TODO(), m)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(conditions.Get(m, InstancePoweredOffCondition)).To(BeNil())
+}
+
+func TestDrainBeforePowerOff(t *testing.T) {
+	g := NewWithT(t)
+
+	m := getMachine("machine", machinev1.PhaseRunning)
+	m.Status.NodeRef = nil
+	m.Annotations[MachinePowerStateAnnotation] = string(MachinePowerStateOff)
+	conditions.MarkFalse(m, InstancePoweredOffCondition, PowerOffDrainingReason, machinev1.ConditionSeverityInfo, "Draining")
+
+	d := &machineDrainController{
+		Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(m).Build(),
+		scheme:        scheme.Scheme,
+		eventRecorder: record.NewFakeRecorder(10),
+	}
+
+	_, err := d.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(m)})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Without a node, the drain is skipped and the instance can be powered off right away.
+	g.Expect(d.Client.Get(context.TODO(), client.ObjectKeyFromObject(m), m)).To(Succeed())
+	g.Expect(conditions.Get(m, machinev1.MachineDrained)).To(HaveField("Status", corev1.ConditionTrue))
+}
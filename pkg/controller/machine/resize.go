@@ -0,0 +1,131 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+const (
+	// InstanceResizedCondition is False while the instance of a Machine is being resized in place, and True
+	// once the last resize has completed.
+	InstanceResizedCondition machinev1.ConditionType = "InstanceResized"
+
+	// ResizeDrainingReason is the reason of the InstanceResizedCondition while the node of the Machine is
+	// drained, before the instance is powered off.
+	ResizeDrainingReason = "Draining"
+)
+
+// isDrainingForResize returns true if the node of the Machine is to be drained before its instance is resized.
+func isDrainingForResize(m *machinev1.Machine) bool {
+	condition := conditions.Get(m, InstanceResizedCondition)
+	return condition != nil && condition.Status == corev1.ConditionFalse && condition.Reason == ResizeDrainingReason
+}
+
+// reconcileResize resizes the instance of the Machine in place when its instance type changes and the Machine
+// allows it: the node is cordoned and drained by the drain controller, then the instance is powered off,
+// resized and powered back on, and the node uncordoned. The instance of a stopped Machine is resized right
+// away and stays powered off.
+// It returns true while the resize is in progress.
+func (r *ReconcileMachine) reconcileResize(ctx context.Context, m *machinev1.Machine) (bool, error) {
+	resizeActuator, ok := r.actuator.(ResizeActuator)
+	if _, supported := unwrapActuator(r.actuator).(ResizeActuator); !ok || !supported {
+		return false, nil
+	}
+
+	if !isDrainingForResize(m) {
+		if !annotations.IsInPlaceResizeAllowed(m) {
+			return false, nil
+		}
+		needsResize, err := resizeActuator.NeedsResize(ctx, m)
+		if err != nil {
+			return false, fmt.Errorf("failed to check if machine needs resize: %w", err)
+		}
+		if !needsResize {
+			return false, nil
+		}
+
+		if machineIsStopped(m) {
+			if err := resizeActuator.Resize(ctx, m); err != nil {
+				r.eventRecorder.Eventf(m, corev1.EventTypeWarning, "FailedResize", "Failed to resize machine: %v", err)
+				return false, fmt.Errorf("failed to resize machine: %w", err)
+			}
+			klog.Infof("%v: resized stopped machine", m.Name)
+			r.eventRecorder.Eventf(m, corev1.EventTypeNormal, "Resized", "Machine resized")
+			conditions.MarkTrue(m, InstanceResizedCondition)
+			return false, nil
+		}
+
+		klog.Infof("%v: instance type changed, draining node before resizing machine", m.Name)
+		r.eventRecorder.Eventf(m, corev1.EventTypeNormal, "ResizeStarted", "Draining node before resizing machine")
+		conditions.MarkFalse(m, InstanceResizedCondition, ResizeDrainingReason, machinev1.ConditionSeverityInfo,
+			"Draining node before resizing the instance")
+		return true, nil
+	}
+
+	drainedCondition := conditions.Get(m, machinev1.MachineDrained)
+	if drainedCondition == nil || drainedCondition.Status != corev1.ConditionTrue {
+		klog.Infof("%v: waiting for node to be drained before resizing machine", m.Name)
+		return true, nil
+	}
+
+	if err := r.resizeInstance(ctx, resizeActuator, m); err != nil {
+		r.eventRecorder.Eventf(m, corev1.EventTypeWarning, "FailedResize", "Failed to resize machine: %v", err)
+		return true, err
+	}
+
+	if m.Status.NodeRef != nil {
+		if err := uncordonNode(ctx, r.Client, m.Status.NodeRef.Name); err != nil {
+			return true, fmt.Errorf("failed to uncordon node %q: %w", m.Status.NodeRef.Name, err)
+		}
+	}
+
+	klog.Infof("%v: resized machine", m.Name)
+	r.eventRecorder.Eventf(m, corev1.EventTypeNormal, "Resized", "Machine resized")
+	// The node is drained again when the Machine is deleted or resized next.
+	conditions.Delete(m, machinev1.MachineDrained)
+	conditions.MarkTrue(m, InstanceResizedCondition)
+	return false, nil
+}
+
+// resizeInstance powers the instance off, resizes it and powers it back on. Each step is idempotent, so that
+// a failed resize is retried from the start.
+func (r *ReconcileMachine) resizeInstance(ctx context.Context, resizeActuator ResizeActuator, m *machinev1.Machine) error {
+	if err := resizeActuator.SetPowerState(ctx, m, MachinePowerStateOff); err != nil {
+		return fmt.Errorf("failed to power off machine: %w", err)
+	}
+	if err := resizeActuator.Resize(ctx, m); err != nil {
+		return fmt.Errorf("failed to resize machine: %w", err)
+	}
+	if err := resizeActuator.SetPowerState(ctx, m, MachinePowerStateOn); err != nil {
+		return fmt.Errorf("failed to power on machine: %w", err)
+	}
+	return nil
+}
+
+// uncordonNode marks the node cordoned by the drain as schedulable again.
+func uncordonNode(ctx context.Context, c client.Client, name string) error {
+	node := &corev1.Node{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			klog.V(2).Infof("Node %q not found", name)
+			return nil
+		}
+		return err
+	}
+	if !node.Spec.Unschedulable {
+		return nil
+	}
+	patch := client.MergeFrom(node.DeepCopy())
+	node.Spec.Unschedulable = false
+	return c.Patch(ctx, node, patch)
+}
@@ -0,0 +1,134 @@
+package machine
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+var _ ResizeActuator = &testResizeActuator{}
+
+type testResizeActuator struct {
+	*TestActuator
+	needsResize bool
+	powerStates []MachinePowerState
+	resizes     int
+}
+
+func (a *testResizeActuator) SetPowerState(ctx context.Context, m *machinev1.Machine, state MachinePowerState) error {
+	a.powerStates = append(a.powerStates, state)
+	return a.TestActuator.SetPowerState(ctx, m, state)
+}
+
+func (a *testResizeActuator) NeedsResize(context.Context, *machinev1.Machine) (bool, error) {
+	return a.needsResize, nil
+}
+
+func (a *testResizeActuator) Resize(context.Context, *machinev1.Machine) error {
+	a.resizes++
+	a.needsResize = false
+	return nil
+}
+
+func TestReconcileResize(t *testing.T) {
+	g := NewWithT(t)
+
+	m := getMachine("machine", machinev1.PhaseRunning)
+	m.Annotations[annotations.AllowInPlaceResizeAnnotation] = "true"
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: m.Status.NodeRef.Name},
+		Spec:       corev1.NodeSpec{Unschedulable: true},
+	}
+
+	actuator := &testResizeActuator{TestActuator: newTestActuator(), needsResize: true}
+	recorder := record.NewFakeRecorder(10)
+	r := &ReconcileMachine{
+		Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(m, node).Build(),
+		scheme:        scheme.Scheme,
+		eventRecorder: recorder,
+		actuator:      actuator,
+	}
+
+	// The node is drained first.
+	resizing, err := r.reconcileResize(context.TODO(), m)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(resizing).To(BeTrue())
+	g.Expect(isDrainingForResize(m)).To(BeTrue())
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("ResizeStarted")))
+
+	resizing, err = r.reconcileResize(context.TODO(), m)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(resizing).To(BeTrue())
+	g.Expect(actuator.resizes).To(BeZero())
+
+	// Then the instance is powered off, resized and powered back on, and the node uncordoned.
+	conditions.MarkTrue(m, machinev1.MachineDrained)
+	resizing, err = r.reconcileResize(context.TODO(), m)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(resizing).To(BeFalse())
+	g.Expect(actuator.resizes).To(Equal(1))
+	g.Expect(actuator.powerStates).To(Equal([]MachinePowerState{MachinePowerStateOff, MachinePowerStateOn}))
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("Resized")))
+
+	g.Expect(conditions.Get(m, machinev1.MachineDrained)).To(BeNil())
+	g.Expect(conditions.Get(m, InstanceResizedCondition)).To(HaveField("Status", corev1.ConditionTrue))
+
+	g.Expect(r.Client.Get(context.TODO(), client.ObjectKeyFromObject(node), node)).To(Succeed())
+	g.Expect(node.Spec.Unschedulable).To(BeFalse())
+}
+
+func TestReconcileResizeStoppedMachine(t *testing.T) {
+	g := NewWithT(t)
+
+	m := getMachine("machine", PhaseStopped)
+	m.Annotations[annotations.AllowInPlaceResizeAnnotation] = "true"
+
+	actuator := &testResizeActuator{TestActuator: newTestActuator(), needsResize: true}
+	r := &ReconcileMachine{
+		Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(m).Build(),
+		scheme:        scheme.Scheme,
+		eventRecorder: record.NewFakeRecorder(10),
+		actuator:      actuator,
+	}
+
+	// The instance of a stopped machine is resized without draining its node, and stays powered off.
+	resizing, err := r.reconcileResize(context.TODO(), m)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(resizing).To(BeFalse())
+	g.Expect(actuator.resizes).To(Equal(1))
+	g.Expect(actuator.powerStates).To(BeEmpty())
+	g.Expect(conditions.Get(m, InstanceResizedCondition)).To(HaveField("Status", corev1.ConditionTrue))
+}
+
+func TestReconcileResizeNotAllowed(t *testing.T) {
+	g := NewWithT(t)
+
+	m := getMachine("machine", machinev1.PhaseRunning)
+
+	actuator := &testResizeActuator{TestActuator: newTestActuator(), needsResize: true}
+	r := &ReconcileMachine{
+		Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(m).Build(),
+		scheme:        scheme.Scheme,
+		eventRecorder: record.NewFakeRecorder(10),
+		actuator:      actuator,
+	}
+
+	resizing, err := r.reconcileResize(context.TODO(), m)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(resizing).To(BeFalse())
+	g.Expect(actuator.resizes).To(BeZero())
+	g.Expect(conditions.Get(m, InstanceResizedCondition)).To(BeNil())
+}
@@ -0,0 +1,85 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+const (
+	// MachineRestartRequestedAnnotation requests the reboot of the instance of a Machine through the cloud provider.
+	// The request is handled once: the annotation is removed before the reboot, and the result recorded in the
+	// InstanceRestartedCondition. Its value is free-form, e.g. the reason of the request.
+	// It requires an Actuator implementing RebootActuator.
+	MachineRestartRequestedAnnotation = "machine.openshift.io/restart-requested"
+
+	// InstanceRestartedCondition records the result of the last restart requested for a Machine.
+	InstanceRestartedCondition machinev1.ConditionType = "InstanceRestarted"
+
+	// RestartSucceededReason is the reason of the InstanceRestartedCondition once the instance has been rebooted.
+	RestartSucceededReason = "RestartSucceeded"
+	// RestartFailedReason is the reason of the InstanceRestartedCondition when the instance could not be rebooted.
+	RestartFailedReason = "RestartFailed"
+)
+
+// reconcileRestart reboots the instance of the Machine once when a restart is requested with the
+// MachineRestartRequestedAnnotation.
+func (r *ReconcileMachine) reconcileRestart(ctx context.Context, m *machinev1.Machine) error {
+	request, ok := m.Annotations[MachineRestartRequestedAnnotation]
+	if !ok {
+		return nil
+	}
+
+	// Remove the request first, so that the instance is not rebooted again if the status fails to be updated.
+	// The patch resets the local status, which is restored afterwards.
+	status := m.Status.DeepCopy()
+	patch := client.MergeFrom(m.DeepCopy())
+	delete(m.Annotations, MachineRestartRequestedAnnotation)
+	if err := r.Client.Patch(ctx, m, patch); err != nil {
+		return fmt.Errorf("failed to remove %s annotation: %w", MachineRestartRequestedAnnotation, err)
+	}
+	m.Status = *status
+
+	rebootActuator, ok := r.actuator.(RebootActuator)
+	if _, supported := unwrapActuator(r.actuator).(RebootActuator); !ok || !supported {
+		klog.Warningf("%v: the actuator does not support restarting machines, ignoring %s annotation", m.Name, MachineRestartRequestedAnnotation)
+		r.eventRecorder.Eventf(m, corev1.EventTypeWarning, "RestartUnsupported", "Restarting machines is not supported on this platform")
+		conditions.MarkFalse(m, InstanceRestartedCondition, RestartFailedReason, machinev1.ConditionSeverityWarning,
+			"Restarting machines is not supported on this platform")
+		return nil
+	}
+
+	if machineIsStopped(m) {
+		r.eventRecorder.Eventf(m, corev1.EventTypeWarning, "FailedRestart", "Failed to restart machine: machine is powered off")
+		conditions.MarkFalse(m, InstanceRestartedCondition, RestartFailedReason, machinev1.ConditionSeverityWarning,
+			"Machine is powered off")
+		return nil
+	}
+
+	if err := rebootActuator.Reboot(ctx, m); err != nil {
+		klog.Errorf("%v: failed to restart machine: %v", m.Name, err)
+		r.eventRecorder.Eventf(m, corev1.EventTypeWarning, "FailedRestart", "Failed to restart machine: %v", err)
+		conditions.MarkFalse(m, InstanceRestartedCondition, RestartFailedReason, machinev1.ConditionSeverityWarning,
+			"Failed to restart machine: %v", err)
+		return nil
+	}
+
+	klog.Infof("%v: restarted machine, requested with %q", m.Name, request)
+	r.eventRecorder.Eventf(m, corev1.EventTypeNormal, "Restarted", "Machine restarted")
+	conditions.Set(m, &machinev1.Condition{
+		Type:   InstanceRestartedCondition,
+		Status: corev1.ConditionTrue,
+		Reason: RestartSucceededReason,
+		// The time of the restart makes the transition time of the condition change with every restart.
+		Message: fmt.Sprintf("Machine restarted at %s", r.now().UTC().Format(time.RFC3339)),
+	})
+	return nil
+}
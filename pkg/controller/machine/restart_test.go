@@ -0,0 +1,152 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+var _ RebootActuator = &testRebootActuator{}
+
+type testRebootActuator struct {
+	*TestActuator
+	reboots int
+	err     error
+}
+
+func (a *testRebootActuator) Reboot(context.Context, *machinev1.Machine) error {
+	a.reboots++
+	return a.err
+}
+
+func TestReconcileRestart(t *testing.T) {
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name              string
+		actuator          Actuator
+		phase             string
+		requested         bool
+		expectedReboots   int
+		expectedCondition *machinev1.Condition
+		expectedEvent     string
+	}{
+		{
+			name:     "without a restart request",
+			actuator: &testRebootActuator{TestActuator: newTestActuator()},
+			phase:    machinev1.PhaseRunning,
+		},
+		{
+			name:            "with a restart request",
+			actuator:        &testRebootActuator{TestActuator: newTestActuator()},
+			phase:           machinev1.PhaseRunning,
+			requested:       true,
+			expectedReboots: 1,
+			expectedCondition: &machinev1.Condition{
+				Type:    InstanceRestartedCondition,
+				Status:  corev1.ConditionTrue,
+				Reason:  RestartSucceededReason,
+				Message: "Machine restarted at 2026-10-16T08:00:00Z",
+			},
+			expectedEvent: "Normal Restarted Machine restarted",
+		},
+		{
+			name:            "with a failed restart",
+			actuator:        &testRebootActuator{TestActuator: newTestActuator(), err: errors.New("instance is busy")},
+			phase:           machinev1.PhaseRunning,
+			requested:       true,
+			expectedReboots: 1,
+			expectedCondition: &machinev1.Condition{
+				Type:     InstanceRestartedCondition,
+				Status:   corev1.ConditionFalse,
+				Reason:   RestartFailedReason,
+				Severity: machinev1.ConditionSeverityWarning,
+				Message:  "Failed to restart machine: instance is busy",
+			},
+			expectedEvent: "Warning FailedRestart Failed to restart machine: instance is busy",
+		},
+		{
+			name:      "with a stopped machine",
+			actuator:  &testRebootActuator{TestActuator: newTestActuator()},
+			phase:     PhaseStopped,
+			requested: true,
+			expectedCondition: &machinev1.Condition{
+				Type:     InstanceRestartedCondition,
+				Status:   corev1.ConditionFalse,
+				Reason:   RestartFailedReason,
+				Severity: machinev1.ConditionSeverityWarning,
+				Message:  "Machine is powered off",
+			},
+			expectedEvent: "Warning FailedRestart Failed to restart machine: machine is powered off",
+		},
+		{
+			name:      "with an actuator not supporting restarts",
+			actuator:  newTestActuator(),
+			phase:     machinev1.PhaseRunning,
+			requested: true,
+			expectedCondition: &machinev1.Condition{
+				Type:     InstanceRestartedCondition,
+				Status:   corev1.ConditionFalse,
+				Reason:   RestartFailedReason,
+				Severity: machinev1.ConditionSeverityWarning,
+				Message:  "Restarting machines is not supported on this platform",
+			},
+			expectedEvent: "Warning RestartUnsupported Restarting machines is not supported on this platform",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			m := getMachine("machine", tc.phase)
+			if tc.requested {
+				m.Annotations[MachineRestartRequestedAnnotation] = "kernel update"
+			}
+
+			recorder := record.NewFakeRecorder(10)
+			r := &ReconcileMachine{
+				Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(m).Build(),
+				scheme:        scheme.Scheme,
+				eventRecorder: recorder,
+				actuator:      tc.actuator,
+				nowFunc:       func() time.Time { return now },
+			}
+
+			g.Expect(r.reconcileRestart(context.TODO(), m)).To(Succeed())
+
+			if rebootActuator, ok := tc.actuator.(*testRebootActuator); ok {
+				g.Expect(rebootActuator.reboots).To(Equal(tc.expectedReboots))
+			}
+			condition := conditions.Get(m, InstanceRestartedCondition)
+			if tc.expectedCondition != nil {
+				g.Expect(condition).ToNot(BeNil())
+				g.Expect(*condition).To(conditions.MatchCondition(*tc.expectedCondition))
+			} else {
+				g.Expect(condition).To(BeNil())
+			}
+			if tc.expectedEvent != "" {
+				g.Expect(recorder.Events).To(Receive(Equal(tc.expectedEvent)))
+			}
+			g.Expect(recorder.Events).To(BeEmpty())
+
+			// The request is removed, so that the machine is only restarted once.
+			updated := &machinev1.Machine{}
+			g.Expect(r.Client.Get(context.TODO(), client.ObjectKeyFromObject(m), updated)).To(Succeed())
+			g.Expect(updated.Annotations).ToNot(HaveKey(MachineRestartRequestedAnnotation))
+		})
+	}
+}
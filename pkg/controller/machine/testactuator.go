@@ -21,9 +21,13 @@ import (
 	"sync"
 
 	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 var _ Actuator = &TestActuator{}
+var _ PowerStateActuator = &TestActuator{}
 
 type TestActuator struct {
 	unblock         chan string
@@ -36,6 +40,7 @@ type TestActuator struct {
 	UpdateCallCount int64
 	ExistsCallCount int64
 	ExistsValue     bool
+	PowerState      MachinePowerState
 	Lock            sync.Mutex
 }
 
@@ -90,6 +95,13 @@ func (a *TestActuator) Exists(context.Context, *machinev1.Machine) (bool, error)
 	return a.ExistsValue, nil
 }
 
+func (a *TestActuator) SetPowerState(ctx context.Context, machine *machinev1.Machine, state MachinePowerState) error {
+	a.Lock.Lock()
+	defer a.Lock.Unlock()
+	a.PowerState = state
+	return nil
+}
+
 func newTestActuator() *TestActuator {
 	ta := new(TestActuator)
 	ta.unblock = make(chan string)
@@ -99,3 +111,14 @@ func newTestActuator() *TestActuator {
 func (a *TestActuator) Unblock() {
 	close(a.unblock)
 }
+
+// NewTestReconciler returns a Machine reconciler using the client and the actuator, letting the tests of the other
+// controllers follow the requests they make to the Machine controller.
+func NewTestReconciler(c client.Client, actuator Actuator) reconcile.Reconciler {
+	return &ReconcileMachine{
+		Client:        c,
+		scheme:        c.Scheme(),
+		eventRecorder: record.NewFakeRecorder(100),
+		actuator:      actuator,
+	}
+}
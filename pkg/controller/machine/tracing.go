@@ -0,0 +1,113 @@
+package machine
+
+import (
+	"context"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/tracing"
+)
+
+// newTracingActuator returns an actuator recording a span for each operation of the actuator, the parent of
+// the spans of the cloud provider calls made by the operation.
+func newTracingActuator(actuator Actuator) Actuator {
+	return &tracingActuator{next: actuator}
+}
+
+type tracingActuator struct {
+	next Actuator
+}
+
+func (a *tracingActuator) Unwrap() Actuator {
+	return a.next
+}
+
+func (a *tracingActuator) Create(ctx context.Context, m *machinev1.Machine) error {
+	ctx, span := startActuatorSpan(ctx, "Create", m)
+	defer span.End()
+	err := a.next.Create(ctx, m)
+	span.RecordError(err)
+	return err
+}
+
+func (a *tracingActuator) Delete(ctx context.Context, m *machinev1.Machine) error {
+	ctx, span := startActuatorSpan(ctx, "Delete", m)
+	defer span.End()
+	err := a.next.Delete(ctx, m)
+	span.RecordError(err)
+	return err
+}
+
+func (a *tracingActuator) Update(ctx context.Context, m *machinev1.Machine) error {
+	ctx, span := startActuatorSpan(ctx, "Update", m)
+	defer span.End()
+	err := a.next.Update(ctx, m)
+	span.RecordError(err)
+	return err
+}
+
+func (a *tracingActuator) Exists(ctx context.Context, m *machinev1.Machine) (bool, error) {
+	ctx, span := startActuatorSpan(ctx, "Exists", m)
+	defer span.End()
+	exists, err := a.next.Exists(ctx, m)
+	span.SetAttributes(tracing.Bool("exists", exists))
+	span.RecordError(err)
+	return exists, err
+}
+
+func (a *tracingActuator) SetPowerState(ctx context.Context, m *machinev1.Machine, state MachinePowerState) error {
+	next, ok := a.next.(PowerStateActuator)
+	if !ok {
+		return errUnsupportedOperation
+	}
+	ctx, span := startActuatorSpan(ctx, "SetPowerState", m)
+	defer span.End()
+	span.SetAttributes(tracing.String("power_state", string(state)))
+	err := next.SetPowerState(ctx, m, state)
+	span.RecordError(err)
+	return err
+}
+
+func (a *tracingActuator) NeedsResize(ctx context.Context, m *machinev1.Machine) (bool, error) {
+	next, ok := a.next.(ResizeActuator)
+	if !ok {
+		return false, errUnsupportedOperation
+	}
+	ctx, span := startActuatorSpan(ctx, "NeedsResize", m)
+	defer span.End()
+	needsResize, err := next.NeedsResize(ctx, m)
+	span.SetAttributes(tracing.Bool("needs_resize", needsResize))
+	span.RecordError(err)
+	return needsResize, err
+}
+
+func (a *tracingActuator) Resize(ctx context.Context, m *machinev1.Machine) error {
+	next, ok := a.next.(ResizeActuator)
+	if !ok {
+		return errUnsupportedOperation
+	}
+	ctx, span := startActuatorSpan(ctx, "Resize", m)
+	defer span.End()
+	err := next.Resize(ctx, m)
+	span.RecordError(err)
+	return err
+}
+
+func (a *tracingActuator) Reboot(ctx context.Context, m *machinev1.Machine) error {
+	next, ok := a.next.(RebootActuator)
+	if !ok {
+		return errUnsupportedOperation
+	}
+	ctx, span := startActuatorSpan(ctx, "Reboot", m)
+	defer span.End()
+	err := next.Reboot(ctx, m)
+	span.RecordError(err)
+	return err
+}
+
+func startActuatorSpan(ctx context.Context, operation string, m *machinev1.Machine) (context.Context, *tracing.Span) {
+	return tracing.Start(ctx, "actuator "+operation,
+		tracing.String("machine", m.Name),
+		tracing.String("namespace", m.Namespace),
+	)
+}
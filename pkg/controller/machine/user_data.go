@@ -0,0 +1,171 @@
+package machine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+)
+
+const (
+	// UserDataTemplateAnnotation marks a user data secret whose userData key is a Go template. When set to "true"
+	// on the secret referenced by the providerSpec of a Machine, the template is rendered with the variables of
+	// the Machine into a secret owned by the Machine, which is passed to the provider instead.
+	UserDataTemplateAnnotation = "machine.openshift.io/user-data-template"
+
+	// userDataSecretKey is the key of the user data in the user data secrets.
+	userDataSecretKey = "userData"
+	// renderedUserDataSecretSuffix is the suffix of the name of the secrets holding the rendered user data of Machines.
+	// It differs from the -user-data suffix of the user data secrets of the cluster, e.g. worker-user-data, so that
+	// the secret rendered for a Machine named worker does not collide with them.
+	renderedUserDataSecretSuffix = "-rendered-user-data"
+)
+
+// UserDataTemplateVariables are the variables of a Machine available in the user data templates,
+// e.g. `{{ .Name }}` or `{{ index .Labels "node-role.kubernetes.io/infra" }}`.
+type UserDataTemplateVariables struct {
+	// Name is the name of the Machine.
+	Name string
+	// Namespace is the namespace of the Machine.
+	Namespace string
+	// Region is the region of the Machine, from its labels or its providerSpec.
+	Region string
+	// Zone is the availability zone of the Machine, from its labels or its providerSpec.
+	Zone string
+	// InstanceType is the instance type of the Machine, from its labels or its providerSpec.
+	InstanceType string
+	// Labels are the labels of the Machine.
+	Labels map[string]string
+	// Annotations are the annotations of the Machine.
+	Annotations map[string]string
+}
+
+// createInstance creates the instance of the Machine with the actuator, rendering its user data first when
+// the user data secret is a template.
+func (r *ReconcileMachine) createInstance(ctx context.Context, m *machinev1.Machine) error {
+	providerSpec, err := r.renderUserData(ctx, m)
+	if err != nil {
+		return err
+	}
+	if providerSpec == nil {
+		return r.actuator.Create(ctx, m)
+	}
+
+	// The rendered secret is only referenced while the instance is created, the Machine keeps the template.
+	original := m.Spec.ProviderSpec.Value
+	m.Spec.ProviderSpec.Value = providerSpec
+	defer func() { m.Spec.ProviderSpec.Value = original }()
+	return r.actuator.Create(ctx, m)
+}
+
+// renderUserData renders the user data template of the Machine into its rendered user data secret, and returns
+// its providerSpec referencing the rendered secret. It returns nil when the user data secret is not a template.
+func (r *ReconcileMachine) renderUserData(ctx context.Context, m *machinev1.Machine) (*runtime.RawExtension, error) {
+	if m.Spec.ProviderSpec.Value == nil || len(m.Spec.ProviderSpec.Value.Raw) == 0 {
+		return nil, nil
+	}
+	providerSpec := map[string]interface{}{}
+	if err := json.Unmarshal(m.Spec.ProviderSpec.Value.Raw, &providerSpec); err != nil {
+		// Invalid providerSpecs are reported by the actuator.
+		return nil, nil
+	}
+	secretName, _, _ := unstructured.NestedString(providerSpec, "userDataSecret", "name")
+	if secretName == "" {
+		return nil, nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: m.Namespace, Name: secretName}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			// Missing secrets are reported by the actuator.
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user data secret %q: %w", secretName, err)
+	}
+	if secret.Annotations[UserDataTemplateAnnotation] != "true" {
+		return nil, nil
+	}
+
+	userData, err := renderUserDataTemplate(secret.Data[userDataSecretKey], m)
+	if err != nil {
+		return nil, InvalidMachineConfiguration("failed to render user data template of secret %q: %v", secretName, err)
+	}
+
+	rendered := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.Name + renderedUserDataSecretSuffix,
+			Namespace: m.Namespace,
+		},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, rendered, func() error {
+		// Only the secret rendered for the Machine is updated, other secrets are never overwritten.
+		if rendered.ResourceVersion != "" && !metav1.IsControlledBy(rendered, m) {
+			return fmt.Errorf("secret %q already exists and is not owned by the machine", rendered.Name)
+		}
+		rendered.Data = map[string][]byte{}
+		for key, value := range secret.Data {
+			rendered.Data[key] = value
+		}
+		rendered.Data[userDataSecretKey] = userData
+		// The rendered secret is deleted with the Machine.
+		return controllerutil.SetControllerReference(m, rendered, r.scheme)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to write rendered user data secret %q: %w", rendered.Name, err)
+	}
+	klog.V(3).Infof("%v: rendered user data template of secret %q into secret %q", m.Name, secretName, rendered.Name)
+
+	if err := unstructured.SetNestedField(providerSpec, rendered.Name, "userDataSecret", "name"); err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(providerSpec)
+	if err != nil {
+		return nil, err
+	}
+	return &runtime.RawExtension{Raw: raw}, nil
+}
+
+// renderUserDataTemplate renders the user data template with the variables of the Machine.
+func renderUserDataTemplate(userData []byte, m *machinev1.Machine) ([]byte, error) {
+	tmpl, err := template.New("userData").Option("missingkey=error").Parse(string(userData))
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := extractInstanceMetadata(m)
+	variables := UserDataTemplateVariables{
+		Name:         m.Name,
+		Namespace:    m.Namespace,
+		Region:       metadata.region,
+		Zone:         metadata.zone,
+		InstanceType: metadata.instanceType,
+		Labels:       m.Labels,
+		Annotations:  m.Annotations,
+	}
+	for label, value := range map[string]*string{
+		MachineRegionLabelName:       &variables.Region,
+		MachineAZLabelName:           &variables.Zone,
+		MachineInstanceTypeLabelName: &variables.InstanceType,
+	} {
+		if m.Labels[label] != "" {
+			*value = m.Labels[label]
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, variables); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,156 @@
+package machine
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+)
+
+// testUserDataActuator records the user data secret referenced by the machines it creates.
+type testUserDataActuator struct {
+	*TestActuator
+	userDataSecret string
+}
+
+func (a *testUserDataActuator) Create(ctx context.Context, m *machinev1.Machine) error {
+	providerSpec := struct {
+		UserDataSecret struct {
+			Name string `json:"name"`
+		} `json:"userDataSecret"`
+	}{}
+	if err := json.Unmarshal(m.Spec.ProviderSpec.Value.Raw, &providerSpec); err != nil {
+		return err
+	}
+	a.userDataSecret = providerSpec.UserDataSecret.Name
+	return a.TestActuator.Create(ctx, m)
+}
+
+func TestCreateInstanceUserDataTemplate(t *testing.T) {
+	cases := []struct {
+		name                   string
+		machineName            string
+		template               bool
+		existingSecret         *corev1.Secret
+		userData               string
+		expectedUserDataSecret string
+		expectedUserData       string
+		expectedError          string
+		expectedInvalid        bool
+	}{
+		{
+			name:                   "with a user data secret",
+			userData:               "{{ .Name }}",
+			expectedUserDataSecret: "worker-user-data",
+		},
+		{
+			name:                   "with a user data template",
+			template:               true,
+			userData:               `{"hostname":"{{ .Name }}","zone":"{{ .Zone }}","role":"{{ index .Labels "role" }}"}`,
+			expectedUserDataSecret: "machine-rendered-user-data",
+			expectedUserData:       `{"hostname":"machine","zone":"us-east-1a","role":"infra"}`,
+		},
+		{
+			name:                   "with a machine named after the user data secret",
+			machineName:            "worker",
+			template:               true,
+			userData:               `{"hostname":"{{ .Name }}"}`,
+			expectedUserDataSecret: "worker-rendered-user-data",
+			expectedUserData:       `{"hostname":"worker"}`,
+		},
+		{
+			name:     "with a secret not owned by the machine named as its rendered user data secret",
+			template: true,
+			userData: "{{ .Name }}",
+			existingSecret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "machine-rendered-user-data"},
+			},
+			expectedError: `failed to write rendered user data secret "machine-rendered-user-data": secret "machine-rendered-user-data" already exists and is not owned by the machine`,
+		},
+		{
+			name:            "with an invalid user data template",
+			template:        true,
+			userData:        "{{ .Hostname }}",
+			expectedError:   `failed to render user data template of secret "worker-user-data": template: userData:1:3: executing "userData" at <.Hostname>: can't evaluate field Hostname in type machine.UserDataTemplateVariables`,
+			expectedInvalid: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			machineName := "machine"
+			if tc.machineName != "" {
+				machineName = tc.machineName
+			}
+			m := getMachine(machineName, machinev1.PhaseProvisioning)
+			m.Labels["role"] = "infra"
+			m.Spec.ProviderSpec.Value = &runtime.RawExtension{
+				Raw: []byte(`{"placement":{"availabilityZone":"us-east-1a"},"userDataSecret":{"name":"worker-user-data"}}`),
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-user-data", Namespace: m.Namespace},
+				Data: map[string][]byte{
+					"userData":          []byte(tc.userData),
+					"disableTemplating": []byte("true"),
+				},
+			}
+			if tc.template {
+				secret.Annotations = map[string]string{UserDataTemplateAnnotation: "true"}
+			}
+
+			objects := []runtime.Object{m, secret}
+			if tc.existingSecret != nil {
+				tc.existingSecret.Namespace = m.Namespace
+				objects = append(objects, tc.existingSecret)
+			}
+
+			actuator := &testUserDataActuator{TestActuator: newTestActuator()}
+			r := &ReconcileMachine{
+				Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(objects...).Build(),
+				scheme:        scheme.Scheme,
+				eventRecorder: record.NewFakeRecorder(10),
+				actuator:      actuator,
+			}
+			original := m.Spec.ProviderSpec.Value.DeepCopy()
+
+			err := r.createInstance(context.TODO(), m)
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+				g.Expect(isInvalidMachineConfigurationError(err)).To(Equal(tc.expectedInvalid))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(actuator.userDataSecret).To(Equal(tc.expectedUserDataSecret))
+			// The Machine keeps referencing the template.
+			g.Expect(m.Spec.ProviderSpec.Value).To(Equal(original))
+
+			if tc.expectedUserData == "" {
+				return
+			}
+			rendered := &corev1.Secret{}
+			g.Expect(r.Client.Get(context.TODO(), client.ObjectKey{Namespace: m.Namespace, Name: tc.expectedUserDataSecret}, rendered)).To(Succeed())
+			g.Expect(string(rendered.Data["userData"])).To(Equal(tc.expectedUserData))
+			g.Expect(string(rendered.Data["disableTemplating"])).To(Equal("true"))
+			g.Expect(metav1.IsControlledBy(rendered, m)).To(BeTrue())
+
+			// The user data template is left untouched.
+			template := &corev1.Secret{}
+			g.Expect(r.Client.Get(context.TODO(), client.ObjectKeyFromObject(secret), template)).To(Succeed())
+			g.Expect(string(template.Data["userData"])).To(Equal(tc.userData))
+			g.Expect(template.OwnerReferences).To(BeEmpty())
+		})
+	}
+}
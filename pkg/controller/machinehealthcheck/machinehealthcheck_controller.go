@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"github.com/openshift/machine-api-operator/pkg/util/annotations"
 	"github.com/openshift/machine-api-operator/pkg/util/conditions"
 	"github.com/openshift/machine-api-operator/pkg/util/external"
+	"github.com/openshift/machine-api-operator/pkg/util/tracing"
 	corev1 "k8s.io/api/core/v1"
 	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -24,27 +26,32 @@ import (
 	apimachineryutilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const (
-	machineAnnotationKey          = "machine.openshift.io/machine"
-	machineExternalAnnotationKey  = "host.metal3.io/external-remediation"
-	nodeMasterLabel               = "node-role.kubernetes.io/master"
-	machineRoleLabel              = "machine.openshift.io/cluster-api-machine-role"
-	machineMasterRole             = "master"
-	remediationStrategyAnnotation = "machine.openshift.io/remediation-strategy"
-	remediationStrategyExternal   = machinev1.RemediationStrategyType("external-baremetal")
-	defaultNodeStartupTimeout     = 10 * time.Minute
-	machineNodeNameIndex          = "machineNodeNameIndex"
-	controllerName                = "machinehealthcheck-controller"
+	machineAnnotationKey         = "machine.openshift.io/machine"
+	machineExternalAnnotationKey = "host.metal3.io/external-remediation"
+	nodeMasterLabel              = "node-role.kubernetes.io/master"
+	machineRoleLabel             = "machine.openshift.io/cluster-api-machine-role"
+	machineMasterRole            = "master"
+	remediationStrategyExternal  = machinev1.RemediationStrategyType("external-baremetal")
+	defaultNodeStartupTimeout    = 10 * time.Minute
+	machineNodeNameIndex         = "machineNodeNameIndex"
+	controllerName               = "machinehealthcheck-controller"
+
+	// machinePhaseStopped is the phase of machines powered off with the machine.openshift.io/power-state annotation,
+	// their node is expected to be unhealthy.
+	machinePhaseStopped = "Stopped"
 
 	// Event types
 	// EventRemediationRestricted is emitted in case when machine remediation
@@ -72,6 +79,18 @@ const (
 	// from processing it.
 	// TODO: move this annotation to the openshift/api package
 	PausedAnnotation = "cluster.x-k8s.io/paused"
+	// UnhealthyRangeAnnotation restricts remediation to when the number of unhealthy machines is within a range,
+	// e.g. "[1-5]". It takes precedence over maxUnhealthy, and avoids remediation storms during events affecting
+	// many machines at once, e.g. power outages, whose machines cannot all be remediated. Above the range, the
+	// MachineHealthCheck is short-circuited as with maxUnhealthy; below it, the unhealthy machines are left alone.
+	UnhealthyRangeAnnotation = "machine.openshift.io/unhealthy-range"
+	// RemediationStrategyAnnotation sets how the MachineHealthCheck remediates unhealthy machines other than
+	// deleting them, either "external-baremetal" or "Reboot".
+	RemediationStrategyAnnotation = "machine.openshift.io/remediation-strategy"
+)
+
+var (
+	unhealthyRangeRegexp = regexp.MustCompile(`^\[([0-9]+)-([0-9]+)\]$`)
 )
 
 var (
@@ -79,14 +98,27 @@ var (
 	disabledNodeStartupTimeout = metav1.Duration{Duration: 0}
 )
 
+// Options configures the MachineHealthCheck controller.
+type Options struct {
+	// RateLimiter limits the requeues of the MachineHealthChecks. The default rate limiter of the controllers is used when nil.
+	RateLimiter workqueue.RateLimiter
+}
+
 // Add creates a new MachineHealthCheck Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and start it when the Manager is started.
 func Add(mgr manager.Manager, opts manager.Options) error {
-	r, err := newReconciler(mgr, opts)
-	if err != nil {
-		return fmt.Errorf("error building reconciler: %v", err)
+	return AddWithOptions(Options{})(mgr, opts)
+}
+
+// AddWithOptions returns a function which adds a new MachineHealthCheck Controller, configured with the given options, to the Manager.
+func AddWithOptions(o Options) func(manager.Manager, manager.Options) error {
+	return func(mgr manager.Manager, opts manager.Options) error {
+		r, err := newReconciler(mgr, opts)
+		if err != nil {
+			return fmt.Errorf("error building reconciler: %v", err)
+		}
+		return add(mgr, r, r.mhcRequestsFromMachine, r.mhcRequestsFromNode, r.mhcRequestsFromMachineSet, o.RateLimiter)
 	}
-	return add(mgr, r, r.mhcRequestsFromMachine, r.mhcRequestsFromNode)
 }
 
 // newReconciler returns a new reconcile.Reconciler
@@ -104,6 +136,7 @@ func newReconciler(mgr manager.Manager, opts manager.Options) (*ReconcileMachine
 		scheme:    mgr.GetScheme(),
 		namespace: opts.Namespace,
 		recorder:  mgr.GetEventRecorderFor(controllerName),
+		apiReader: mgr.GetAPIReader(),
 	}, nil
 }
 
@@ -122,8 +155,8 @@ func indexMachineByNodeName(object client.Object) []string {
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
-func add(mgr manager.Manager, r reconcile.Reconciler, mapMachineToMHC, mapNodeToMHC handler.MapFunc) error {
-	c, err := controller.New(controllerName, mgr, controller.Options{Reconciler: r})
+func add(mgr manager.Manager, r reconcile.Reconciler, mapMachineToMHC, mapNodeToMHC, mapMachineSetToMHC handler.MapFunc, rateLimiter workqueue.RateLimiter) error {
+	c, err := controller.New(controllerName, mgr, controller.Options{Reconciler: tracing.NewReconciler(controllerName, r), RateLimiter: rateLimiter})
 	if err != nil {
 		return err
 	}
@@ -138,7 +171,13 @@ func add(mgr manager.Manager, r reconcile.Reconciler, mapMachineToMHC, mapNodeTo
 		return err
 	}
 
-	return c.Watch(&source.Kind{Type: &corev1.Node{}}, handler.EnqueueRequestsFromMapFunc(mapNodeToMHC))
+	err = c.Watch(&source.Kind{Type: &corev1.Node{}}, handler.EnqueueRequestsFromMapFunc(mapNodeToMHC))
+	if err != nil {
+		return err
+	}
+
+	// Only the annotations of the MachineSets, which override the health checks of their Machines, matter.
+	return c.Watch(&source.Kind{Type: &machinev1.MachineSet{}}, handler.EnqueueRequestsFromMapFunc(mapMachineSetToMHC), predicate.AnnotationChangedPredicate{})
 }
 
 var _ reconcile.Reconciler = &ReconcileMachineHealthCheck{}
@@ -151,12 +190,18 @@ type ReconcileMachineHealthCheck struct {
 	scheme    *runtime.Scheme
 	namespace string
 	recorder  record.EventRecorder
+	// apiReader reads objects outside of the watched namespace, e.g. the etcd guard pods
+	apiReader client.Reader
+	// backoff delays the repeated remediation of the same machine slot
+	backoff remediationBackoff
 }
 
 type target struct {
 	Machine machinev1.Machine
 	Node    *corev1.Node
 	MHC     machinev1.MachineHealthCheck
+	// MachineSet owns the Machine, its annotations override the health checks of the MachineHealthCheck.
+	MachineSet *machinev1.MachineSet
 }
 
 // Reconcile fetch all targets for a MachineHealthCheck request and does health checking for each of them
@@ -171,6 +216,7 @@ func (r *ReconcileMachineHealthCheck) Reconcile(ctx context.Context, request rec
 			metrics.DeleteMachineHealthCheckNodesCovered(request.NamespacedName.Name, request.NamespacedName.Namespace)
 			// We also need to revert short circuiting of such object so it doesn't overflow to a new object.
 			metrics.ObserveMachineHealthCheckShortCircuitDisabled(request.NamespacedName.Name, request.NamespacedName.Namespace)
+			metrics.DeleteMachineHealthCheckUnhealthyMachines(request.NamespacedName.Name, request.NamespacedName.Namespace)
 			return reconcile.Result{}, nil
 		}
 		klog.Errorf("Reconciling %s: failed to get MHC: %v", request.String(), err)
@@ -186,6 +232,12 @@ func (r *ReconcileMachineHealthCheck) Reconcile(ctx context.Context, request rec
 	// Create a base from which the MHC status patch will be calculated
 	mergeBase := client.MergeFrom(mhc.DeepCopy())
 
+	if value, ok := mhc.Annotations[UnhealthyRangeAnnotation]; ok {
+		if err := ValidateUnhealthyRange(value); err != nil {
+			klog.Warningf("Reconciling %s: ignoring the unhealthy range, falling back to maxUnhealthy: %v", request.String(), err)
+		}
+	}
+
 	// fetch all targets
 	klog.V(3).Infof("Reconciling %s: finding targets", request.String())
 	targets, err := r.getTargetsFromMHC(*mhc)
@@ -207,6 +259,7 @@ func (r *ReconcileMachineHealthCheck) Reconcile(ctx context.Context, request rec
 	mhc.Status.CurrentHealthy = &healthyCount
 	mhc.Status.ExpectedMachines = &totalTargets
 	unhealthyCount := totalTargets - healthyCount
+	metrics.ObserveMachineHealthCheckUnhealthyMachines(mhc.Name, mhc.Namespace, unhealthyCount)
 
 	// check MHC current health against MaxUnhealthy
 	if !isAllowedRemediation(mhc) {
@@ -222,6 +275,24 @@ func (r *ReconcileMachineHealthCheck) Reconcile(ctx context.Context, request rec
 			unhealthyCount,
 			mhc.Spec.MaxUnhealthy,
 		)
+		eventMessage := fmt.Sprintf("Remediation restricted due to exceeded number of unhealthy machines (total: %v, unhealthy: %v, maxUnhealthy: %v)",
+			totalTargets,
+			unhealthyCount,
+			mhc.Spec.MaxUnhealthy,
+		)
+		if _, _, ok := unhealthyRange(mhc); ok {
+			unhealthyRange := mhc.Annotations[UnhealthyRangeAnnotation]
+			message = fmt.Sprintf("Remediation is not allowed, the number of not started or unhealthy machines is above the unhealthy range (total: %v, unhealthy: %v, unhealthyRange: %v)",
+				totalTargets,
+				unhealthyCount,
+				unhealthyRange,
+			)
+			eventMessage = fmt.Sprintf("Remediation restricted due to number of unhealthy machines above the unhealthy range (total: %v, unhealthy: %v, unhealthyRange: %v)",
+				totalTargets,
+				unhealthyCount,
+				unhealthyRange,
+			)
+		}
 
 		// Remediation not allowed, the number of not started or unhealthy machines exceeds maxUnhealthy
 		mhc.Status.RemediationsAllowed = 0
@@ -238,14 +309,11 @@ func (r *ReconcileMachineHealthCheck) Reconcile(ctx context.Context, request rec
 			return reconcile.Result{}, err
 		}
 
-		r.recorder.Eventf(
+		r.recorder.Event(
 			mhc,
 			corev1.EventTypeWarning,
 			EventRemediationRestricted,
-			"Remediation restricted due to exceeded number of unhealthy machines (total: %v, unhealthy: %v, maxUnhealthy: %v)",
-			totalTargets,
-			unhealthyCount,
-			mhc.Spec.MaxUnhealthy,
+			eventMessage,
 		)
 		metrics.ObserveMachineHealthCheckShortCircuitEnabled(mhc.Name, mhc.Namespace)
 		return reconcile.Result{Requeue: true}, nil
@@ -258,14 +326,85 @@ func (r *ReconcileMachineHealthCheck) Reconcile(ctx context.Context, request rec
 	)
 	metrics.ObserveMachineHealthCheckShortCircuitDisabled(mhc.Name, mhc.Namespace)
 
+	// check the maintenance windows, unhealthy machines are only remediated inside of them
+	inWindow, nextWindow, err := inMaintenanceWindow(mhc, time.Now())
+	if err != nil || !inWindow {
+		previousCondition := conditions.Get(mhc, machinev1.RemediationAllowedCondition).DeepCopy()
+		if err != nil {
+			klog.Errorf("Reconciling %s: %v. Remediation is disabled", request.String(), err)
+			conditions.MarkFalse(mhc, machinev1.RemediationAllowedCondition, InvalidMaintenanceWindowsReason, machinev1.ConditionSeverityWarning, "%v", err)
+		} else {
+			klog.V(3).Infof("Reconciling %s: outside of maintenance windows, deferring remediation until %v", request.String(), nextWindow)
+			conditions.MarkFalse(mhc, machinev1.RemediationAllowedCondition, OutsideMaintenanceWindowReason, machinev1.ConditionSeverityInfo,
+				"Remediation is deferred until the next maintenance window opens at %s", nextWindow.Format(time.RFC3339))
+		}
+
+		if err := r.reconcileStatus(mergeBase, mhc); err != nil {
+			klog.Errorf("Reconciling %s: error patching status: %v", request.String(), err)
+			return reconcile.Result{}, err
+		}
+
+		// The deferral is only recorded on the machines when it starts or changes, not on every reconcile.
+		if deferralChanged(previousCondition, conditions.Get(mhc, machinev1.RemediationAllowedCondition)) {
+			for _, t := range needRemediationTargets {
+				r.recorder.Eventf(
+					&t.Machine,
+					corev1.EventTypeNormal,
+					EventRemediationDeferred,
+					"Remediation of machine %v deferred until the next maintenance window",
+					t.string(),
+				)
+			}
+		}
+		r.cleanEMR(ctx, currentHealthy, mhc)
+
+		if len(errList) > 0 {
+			return reconcile.Result{}, apimachineryutilerrors.NewAggregate(errList)
+		}
+		if !nextWindow.IsZero() {
+			nextCheckTimes = append(nextCheckTimes, time.Until(nextWindow))
+		}
+		return reconcile.Result{RequeueAfter: minDuration(nextCheckTimes)}, nil
+	}
+
+	// do not remediate machines while their number is below the unhealthy range
+	if belowUnhealthyRange(mhc) {
+		klog.V(3).Infof("Reconciling %s: unhealthy targets: %v, below the unhealthy range %s, skipping remediation",
+			request.String(),
+			unhealthyCount,
+			mhc.Annotations[UnhealthyRangeAnnotation],
+		)
+		needRemediationTargets = nil
+	}
+
+	// delay the remediation of machines replacing recently remediated ones, to avoid delete/create hot loops
+	needRemediationTargets, throttleDelays := r.throttleRemediations(mhc, needRemediationTargets, time.Now())
+	nextCheckTimes = append(nextCheckTimes, throttleDelays...)
+
+	// do not remediate control plane machines if it would break the etcd quorum
+	needRemediationTargets, blockedByQuorum, err := r.gateControlPlaneRemediations(ctx, mhc, needRemediationTargets)
+	if err != nil {
+		klog.Errorf("Reconciling %s: error checking etcd quorum: %v", request.String(), err)
+		errList = append(errList, err)
+	}
+	if blockedByQuorum {
+		nextCheckTimes = append(nextCheckTimes, quorumRecheckInterval)
+	}
+
 	conditions.MarkTrue(mhc, machinev1.RemediationAllowedCondition)
 	if err := r.reconcileStatus(mergeBase, mhc); err != nil {
 		klog.Errorf("Reconciling %s: error patching status: %v", request.String(), err)
 		return reconcile.Result{}, err
 	}
-	errList = append(errList, r.remediate(ctx, needRemediationTargets, mhc)...)
+	remediationErrs, records := r.remediate(ctx, needRemediationTargets, mhc)
+	errList = append(errList, remediationErrs...)
+	if err := r.recordRemediations(ctx, mhc, records); err != nil {
+		klog.Errorf("Reconciling %s: error recording remediation history: %v", request.String(), err)
+	}
 	// deletes External Machine Remediation for healthy machines - indicating remediation was successful
 	r.cleanEMR(ctx, currentHealthy, mhc)
+	// resets the reboot count of healthy machines - indicating the reboots were successful
+	r.cleanRebootState(ctx, currentHealthy)
 	// return values
 	if len(errList) > 0 {
 		requeueError := apimachineryutilerrors.NewAggregate(errList)
@@ -282,24 +421,36 @@ func (r *ReconcileMachineHealthCheck) Reconcile(ctx context.Context, request rec
 	return reconcile.Result{}, nil
 }
 
-func (r *ReconcileMachineHealthCheck) remediate(ctx context.Context, needRemediationTargets []target, m *machinev1.MachineHealthCheck) []error {
+// remediate remediates the unhealthy targets, it returns the remediation errors along with
+// the records of the remediations that were requested, to be added to the remediation history.
+func (r *ReconcileMachineHealthCheck) remediate(ctx context.Context, needRemediationTargets []target, m *machinev1.MachineHealthCheck) ([]error, []RemediationRecord) {
 	var errList []error
+	var records []RemediationRecord
 	// remediate unhealthy
 	for _, t := range needRemediationTargets {
 		klog.V(3).Infof("Reconciling %s: meet unhealthy criteria, triggers remediation", t.string())
+		var requested bool
+		var err error
 		if m.Spec.RemediationTemplate != nil {
-			if err := r.externalRemediation(ctx, m, t); err != nil {
+			if requested, err = r.externalRemediation(ctx, m, t); err != nil {
 				klog.Errorf("Reconciling %s: error external remediating: %v", t.string(), err)
 				errList = append(errList, err)
 			}
 		} else {
-			if err := r.internalRemediation(t); err != nil {
+			if requested, err = r.internalRemediation(t); err != nil {
 				klog.Errorf("Reconciling %s: error remediating: %v", t.string(), err)
 				errList = append(errList, err)
 			}
 		}
+		if requested {
+			now := time.Now()
+			r.backoff.observe(t, now)
+			record := newRemediationRecord(t, now, err)
+			metrics.ObserveMachineHealthCheckRemediation(m.Name, m.Namespace, record.Reason)
+			records = append(records, record)
+		}
 	}
-	return errList
+	return errList, records
 }
 
 // deletes EMR (External Machine Remediation) for healthy machines
@@ -328,16 +479,17 @@ func (r *ReconcileMachineHealthCheck) cleanEMR(ctx context.Context, currentHealt
 	}
 }
 
-func (r *ReconcileMachineHealthCheck) externalRemediation(ctx context.Context, m *machinev1.MachineHealthCheck, t target) error {
+// externalRemediation creates an external remediation request for the target, it returns true when the creation was requested.
+func (r *ReconcileMachineHealthCheck) externalRemediation(ctx context.Context, m *machinev1.MachineHealthCheck, t target) (bool, error) {
 	klog.V(3).Infof(" %s: start external remediation logic", t.string())
 	re, err := r.externalRemediationRequestExists(ctx, m, t.Machine.Name)
 	if err != nil {
-		return fmt.Errorf("error retrieving external remediation  %v %q for machine %q in namespace %q: %v", m.Spec.RemediationTemplate.GroupVersionKind(), m.Spec.RemediationTemplate.Name, t.Machine.Name, t.Machine.Namespace, err)
+		return false, fmt.Errorf("error retrieving external remediation  %v %q for machine %q in namespace %q: %v", m.Spec.RemediationTemplate.GroupVersionKind(), m.Spec.RemediationTemplate.Name, t.Machine.Name, t.Machine.Namespace, err)
 	}
 	// If external remediation request already exists,
 	// return early
 	if re {
-		return nil
+		return false, nil
 	}
 
 	cloneOwnerRef := &metav1.OwnerReference{
@@ -349,7 +501,7 @@ func (r *ReconcileMachineHealthCheck) externalRemediation(ctx context.Context, m
 	from, err := external.Get(ctx, r.client, m.Spec.RemediationTemplate, t.Machine.Namespace)
 	if err != nil {
 		conditions.MarkFalse(m, machinev1.ExternalRemediationTemplateAvailable, machinev1.ExternalRemediationTemplateNotFound, machinev1.ConditionSeverityError, err.Error())
-		return fmt.Errorf("error retrieving remediation template %v %q for machine %q in namespace %q: %v", m.Spec.RemediationTemplate.GroupVersionKind(), m.Spec.RemediationTemplate.Name, t.Machine.Name, t.Machine.Namespace, err)
+		return false, fmt.Errorf("error retrieving remediation template %v %q for machine %q in namespace %q: %v", m.Spec.RemediationTemplate.GroupVersionKind(), m.Spec.RemediationTemplate.Name, t.Machine.Name, t.Machine.Namespace, err)
 	}
 
 	generateTemplateInput := &external.GenerateTemplateInput{
@@ -360,7 +512,7 @@ func (r *ReconcileMachineHealthCheck) externalRemediation(ctx context.Context, m
 	}
 	to, err := external.GenerateTemplate(generateTemplateInput)
 	if err != nil {
-		return fmt.Errorf("failed to create template for remediation request %v %q for machine %q in namespace %q: %v", m.Spec.RemediationTemplate.GroupVersionKind(), m.Spec.RemediationTemplate.Name, t.Machine.Name, t.Machine.Namespace, err)
+		return false, fmt.Errorf("failed to create template for remediation request %v %q for machine %q in namespace %q: %v", m.Spec.RemediationTemplate.GroupVersionKind(), m.Spec.RemediationTemplate.Name, t.Machine.Name, t.Machine.Namespace, err)
 	}
 
 	// Set the Remediation Request to match the Machine name, the name is used to
@@ -375,9 +527,9 @@ func (r *ReconcileMachineHealthCheck) externalRemediation(ctx context.Context, m
 	// Create the external clone.
 	if err := r.client.Create(ctx, to); err != nil {
 		conditions.MarkFalse(m, machinev1.ExternalRemediationRequestAvailable, machinev1.ExternalRemediationRequestCreationFailed, machinev1.ConditionSeverityError, err.Error())
-		return fmt.Errorf("error creating remediation request for machine %q in namespace %q: %v", t.Machine.Name, t.Machine.Namespace, err)
+		return true, fmt.Errorf("error creating remediation request for machine %q in namespace %q: %v", t.Machine.Name, t.Machine.Namespace, err)
 	}
-	return nil
+	return true, nil
 }
 
 // getExternalRemediationRequest gets reference to External Remediation Request, unstructured object.
@@ -401,6 +553,12 @@ func (r *ReconcileMachineHealthCheck) externalRemediationRequestExists(ctx conte
 }
 
 func isAllowedRemediation(mhc *machinev1.MachineHealthCheck) bool {
+	if _, maxUnhealthy, ok := unhealthyRange(mhc); ok {
+		// If unhealthy is above the range, short circuit any further remediation. Below the range, remediation
+		// is skipped without short-circuiting, see belowUnhealthyRange.
+		return unhealthyMachineCount(mhc) <= maxUnhealthy
+	}
+
 	maxUnhealthy, err := getMaxUnhealthy(mhc)
 	if err != nil {
 		return false
@@ -410,7 +568,63 @@ func isAllowedRemediation(mhc *machinev1.MachineHealthCheck) bool {
 	return unhealthyMachineCount(mhc) <= maxUnhealthy
 }
 
+// belowUnhealthyRange returns true if the number of unhealthy machines is below the minimum of the
+// UnhealthyRangeAnnotation of the MachineHealthCheck. Such machines are not remediated, but the MachineHealthCheck
+// is not short-circuited either, as this is the steady state of a healthy cluster when the minimum is at least 1.
+func belowUnhealthyRange(mhc *machinev1.MachineHealthCheck) bool {
+	minUnhealthy, _, ok := unhealthyRange(mhc)
+	if !ok {
+		return false
+	}
+	return unhealthyMachineCount(mhc) < minUnhealthy
+}
+
+// unhealthyRange returns the bounds of the UnhealthyRangeAnnotation of the MachineHealthCheck, and false when
+// the annotation is not set or invalid, maxUnhealthy applying instead. Invalid ranges are rejected by the webhook.
+func unhealthyRange(mhc *machinev1.MachineHealthCheck) (int, int, bool) {
+	value, ok := mhc.Annotations[UnhealthyRangeAnnotation]
+	if !ok {
+		return 0, 0, false
+	}
+	minUnhealthy, maxUnhealthy, err := parseUnhealthyRange(value)
+	if err != nil {
+		return 0, 0, false
+	}
+	return minUnhealthy, maxUnhealthy, true
+}
+
+// ValidateUnhealthyRange returns an error when the value of the UnhealthyRangeAnnotation is invalid.
+func ValidateUnhealthyRange(value string) error {
+	_, _, err := parseUnhealthyRange(value)
+	return err
+}
+
+// parseUnhealthyRange returns the bounds of the value of the UnhealthyRangeAnnotation.
+func parseUnhealthyRange(value string) (int, int, error) {
+	parts := unhealthyRangeRegexp.FindStringSubmatch(value)
+	if parts == nil {
+		return 0, 0, fmt.Errorf("invalid %s annotation %q, expected a range of the form [min-max]", UnhealthyRangeAnnotation, value)
+	}
+
+	minUnhealthy, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid %s annotation %q: %v", UnhealthyRangeAnnotation, value, err)
+	}
+	maxUnhealthy, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid %s annotation %q: %v", UnhealthyRangeAnnotation, value, err)
+	}
+	if minUnhealthy > maxUnhealthy {
+		return 0, 0, fmt.Errorf("invalid %s annotation %q, min must not be greater than max", UnhealthyRangeAnnotation, value)
+	}
+	return minUnhealthy, maxUnhealthy, nil
+}
+
 func getMaxUnhealthy(mhc *machinev1.MachineHealthCheck) (int, error) {
+	if _, maxUnhealthy, ok := unhealthyRange(mhc); ok {
+		// The unhealthy range takes precedence over maxUnhealthy
+		return maxUnhealthy, nil
+	}
 	if mhc.Spec.MaxUnhealthy == nil {
 		// This value should be defaulted, but if not, 100% is the default
 		return derefInt(mhc.Status.ExpectedMachines), nil
@@ -447,6 +661,10 @@ func (r *ReconcileMachineHealthCheck) reconcileStatus(baseToPatch client.Patch,
 		return fmt.Errorf("failed to get value for maxUnhealthy: %v", err)
 	}
 	mhc.Status.RemediationsAllowed = int32(maxUnhealthy - unhealthyMachineCount(mhc))
+	if c := conditions.Get(mhc, machinev1.RemediationAllowedCondition); c != nil && c.Status == corev1.ConditionFalse {
+		// Remediation is currently disabled, e.g. outside of maintenance windows.
+		mhc.Status.RemediationsAllowed = 0
+	}
 	if mhc.Status.RemediationsAllowed < 0 {
 		mhc.Status.RemediationsAllowed = 0
 	}
@@ -511,11 +729,26 @@ func (r *ReconcileMachineHealthCheck) getTargetsFromMHC(mhc machinev1.MachineHea
 	}
 
 	var targets []target
+	machineSets := map[string]*machinev1.MachineSet{}
 	for k := range machines {
 		target := target{
 			MHC:     mhc,
 			Machine: machines[k],
 		}
+		if owner := metav1.GetControllerOf(&machines[k]); owner != nil {
+			ms, ok := machineSets[owner.Kind+"/"+owner.Name]
+			if !ok {
+				if ms, err = r.getMachineSetFromMachine(machines[k]); err != nil {
+					return nil, fmt.Errorf("error getting machineset: %v", err)
+				}
+				machineSets[owner.Kind+"/"+owner.Name] = ms
+			}
+			if isExcludedFromMachineHealthChecks(ms) {
+				klog.V(3).Infof("Reconciling %s/%s: machine %s excluded by machineset %s", mhc.Namespace, mhc.Name, machines[k].Name, ms.Name)
+				continue
+			}
+			target.MachineSet = ms
+		}
 		node, err := r.getNodeFromMachine(machines[k])
 		if err != nil {
 			if !apimachineryerrors.IsNotFound(err) {
@@ -626,12 +859,19 @@ func (r *ReconcileMachineHealthCheck) mhcRequestsFromMachine(o client.Object) []
 	return requests
 }
 
-func (r *ReconcileMachineHealthCheck) internalRemediation(t target) error {
+// internalRemediation deletes the Machine of the target, or requests its external remediation when
+// the remediation strategy is external. It returns true when the remediation was requested.
+func (r *ReconcileMachineHealthCheck) internalRemediation(t target) (bool, error) {
 	klog.Infof(" %s: start remediation logic", t.string())
 	if derefStringPointer(t.Machine.Status.Phase) != machinev1.PhaseFailed {
-		if remediationStrategy, ok := t.MHC.Annotations[remediationStrategyAnnotation]; ok {
-			if machinev1.RemediationStrategyType(remediationStrategy) == remediationStrategyExternal {
+		if remediationStrategy, ok := t.MHC.Annotations[RemediationStrategyAnnotation]; ok {
+			switch machinev1.RemediationStrategyType(remediationStrategy) {
+			case remediationStrategyExternal:
 				return t.remediationStrategyExternal(r)
+			case remediationStrategyReboot:
+				if t.shouldReboot() {
+					return t.remediationStrategyReboot(r)
+				}
 			}
 		}
 	}
@@ -645,7 +885,7 @@ func (r *ReconcileMachineHealthCheck) internalRemediation(t target) error {
 			t.string(),
 		)
 		klog.Infof("%s: no controller owner, skipping remediation", t.string())
-		return nil
+		return false, nil
 	}
 
 	key := client.ObjectKey{Namespace: t.Machine.Namespace, Name: t.Machine.Name}
@@ -653,14 +893,14 @@ func (r *ReconcileMachineHealthCheck) internalRemediation(t target) error {
 	if err := r.client.Get(context.TODO(), key, machine); err != nil {
 		if apimachineryerrors.IsNotFound(err) {
 			// Machine has already been deleted
-			return nil
+			return false, nil
 		}
-		return fmt.Errorf("%s: failed to get machine: %v", t.string(), err)
+		return false, fmt.Errorf("%s: failed to get machine: %v", t.string(), err)
 	}
 
 	if !machine.GetDeletionTimestamp().IsZero() {
 		// Delete already initiated
-		return nil
+		return false, nil
 	}
 
 	klog.Infof("%s: deleting", t.string())
@@ -673,7 +913,7 @@ func (r *ReconcileMachineHealthCheck) internalRemediation(t target) error {
 			t.string(),
 			err,
 		)
-		return fmt.Errorf("%s: failed to delete machine: %v", t.string(), err)
+		return true, fmt.Errorf("%s: failed to delete machine: %v", t.string(), err)
 	}
 	r.recorder.Eventf(
 		&t.Machine,
@@ -684,13 +924,15 @@ func (r *ReconcileMachineHealthCheck) internalRemediation(t target) error {
 	)
 	metrics.ObserveMachineHealthCheckRemediationSuccess(t.MHC.Name, t.MHC.Namespace)
 
-	return nil
+	return true, nil
 }
 
-func (t *target) remediationStrategyExternal(r *ReconcileMachineHealthCheck) error {
+// remediationStrategyExternal requests the external remediation of the target with an annotation,
+// it returns true when the remediation was requested.
+func (t *target) remediationStrategyExternal(r *ReconcileMachineHealthCheck) (bool, error) {
 	// we already have external annotation on the machine, stop reconcile
 	if externalRemediationAnnotationExists(&t.Machine) {
-		return nil
+		return false, nil
 	}
 
 	if t.Machine.Annotations == nil {
@@ -708,7 +950,7 @@ func (t *target) remediationStrategyExternal(r *ReconcileMachineHealthCheck) err
 			t.string(),
 			err,
 		)
-		return err
+		return true, err
 	}
 	r.recorder.Eventf(
 		&t.Machine,
@@ -717,7 +959,7 @@ func (t *target) remediationStrategyExternal(r *ReconcileMachineHealthCheck) err
 		"Requesting external remediation of node associated with machine %v",
 		t.string(),
 	)
-	return nil
+	return true, nil
 }
 
 func externalRemediationAnnotationExists(machine *machinev1.Machine) bool {
@@ -768,6 +1010,19 @@ func (t *target) needsRemediation(timeoutForMachineToHaveNode time.Duration) (bo
 		return true, time.Duration(0), nil
 	}
 
+	// machine has been powered off on purpose
+	if derefStringPointer(t.Machine.Status.Phase) == machinePhaseStopped {
+		klog.V(3).Infof("%s: healthy: machine phase is %q", t.string(), machinePhaseStopped)
+		return false, time.Duration(0), nil
+	}
+
+	// invalid overrides are rejected by the MachineSet webhook, those set before fall back to the MachineHealthCheck
+	overrides, err := getMachineHealthCheckOverrides(t.MachineSet)
+	if err != nil {
+		klog.Warningf("%s: ignoring overrides: %v", t.string(), err)
+	}
+	timeoutForMachineToHaveNode = overrides.nodeStartupTimeout(timeoutForMachineToHaveNode)
+
 	// the node has not been set yet
 	if t.Node == nil {
 		if timeoutForMachineToHaveNode.Seconds() == disabledNodeStartupTimeout.Seconds() {
@@ -795,7 +1050,7 @@ func (t *target) needsRemediation(timeoutForMachineToHaveNode time.Duration) (bo
 	}
 
 	// check conditions
-	for _, c := range t.MHC.Spec.UnhealthyConditions {
+	for _, c := range overrides.unhealthyConditions(t.MHC.Spec.UnhealthyConditions) {
 		now := time.Now()
 		nodeCondition := conditions.GetNodeCondition(t.Node, c.Type)
 
@@ -805,19 +1060,54 @@ func (t *target) needsRemediation(timeoutForMachineToHaveNode time.Duration) (bo
 			continue
 		}
 
+		// After a reboot, give the node the whole timeout to recover.
+		unhealthySince := nodeCondition.LastTransitionTime.Time
+		if rebooted := lastReboot(&t.Machine); rebooted.After(unhealthySince) {
+			unhealthySince = rebooted
+		}
+
 		// If the condition has been in the unhealthy state for longer than the
 		// timeout, return true with no requeue time.
-		if nodeCondition.LastTransitionTime.Add(c.Timeout.Duration).Before(now) {
+		if unhealthySince.Add(c.Timeout.Duration).Before(now) {
 			klog.V(3).Infof("%s: unhealthy: condition %v in state %v longer than %v", t.string(), c.Type, c.Status, c.Timeout)
 			return true, time.Duration(0), nil
 		}
 
-		durationUnhealthy := now.Sub(nodeCondition.LastTransitionTime.Time)
+		durationUnhealthy := now.Sub(unhealthySince)
 		nextCheck := c.Timeout.Duration - durationUnhealthy + time.Second
 		if nextCheck > 0 {
 			nextCheckTimes = append(nextCheckTimes, nextCheck)
 		}
 	}
+
+	// check taints
+	// invalid taints are rejected by the MachineHealthCheck webhook, those set before are ignored
+	unhealthyTaints, err := getUnhealthyTaints(&t.MHC)
+	if err != nil {
+		klog.Warningf("%s: ignoring unhealthy taints: %v", t.string(), err)
+	}
+	for _, u := range unhealthyTaints {
+		for _, taint := range t.Node.Spec.Taints {
+			if !u.matches(taint) {
+				continue
+			}
+
+			unhealthySince := taintedSince(taint)
+			if rebooted := lastReboot(&t.Machine); rebooted.After(unhealthySince) {
+				unhealthySince = rebooted
+			}
+
+			if unhealthySince.Add(u.Timeout.Duration).Before(now) {
+				klog.V(3).Infof("%s: unhealthy: taint %v present longer than %v", t.string(), taint.ToString(), u.Timeout)
+				return true, time.Duration(0), nil
+			}
+
+			nextCheck := u.Timeout.Duration - now.Sub(unhealthySince) + time.Second
+			if nextCheck > 0 {
+				nextCheckTimes = append(nextCheckTimes, nextCheck)
+			}
+		}
+	}
 	return false, minDuration(nextCheckTimes), nil
 }
 
@@ -9,13 +9,17 @@ import (
 	"testing"
 	"time"
 
+	"github.com/openshift/machine-api-operator/pkg/metrics"
 	"github.com/openshift/machine-api-operator/pkg/util/external"
 
 	. "github.com/onsi/gomega"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
 	"github.com/openshift/machine-api-operator/pkg/util/conditions"
 	maotesting "github.com/openshift/machine-api-operator/pkg/util/testing"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -228,10 +232,40 @@ func TestReconcile(t *testing.T) {
 	machineHealthCheckNegativeMaxUnhealthy.Spec.MaxUnhealthy = &negativeOne
 	machineHealthCheckNegativeMaxUnhealthy.Spec.NodeStartupTimeout = &metav1.Duration{Duration: nodeStartupTimeout}
 
+	machineHealthCheckOutsideMaintenanceWindow := maotesting.NewMachineHealthCheck("machineHealthCheckOutsideMaintenanceWindow")
+	machineHealthCheckOutsideMaintenanceWindow.Spec.NodeStartupTimeout = &metav1.Duration{Duration: nodeStartupTimeout}
+	machineHealthCheckOutsideMaintenanceWindow.Annotations = map[string]string{
+		MaintenanceWindowsAnnotation: `[{"schedule": "0 0 1 1 *", "duration": "1m"}]`,
+	}
+	nextMaintenanceWindow := time.Date(time.Now().UTC().Year()+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	machineHealthCheckInsideMaintenanceWindow := maotesting.NewMachineHealthCheck("machineHealthCheckInsideMaintenanceWindow")
+	machineHealthCheckInsideMaintenanceWindow.Spec.NodeStartupTimeout = &metav1.Duration{Duration: nodeStartupTimeout}
+	machineHealthCheckInsideMaintenanceWindow.Annotations = map[string]string{
+		MaintenanceWindowsAnnotation: `[{"schedule": "* * * * *", "duration": "1h"}]`,
+	}
+
+	machineHealthCheckInvalidMaintenanceWindow := maotesting.NewMachineHealthCheck("machineHealthCheckInvalidMaintenanceWindow")
+	machineHealthCheckInvalidMaintenanceWindow.Spec.NodeStartupTimeout = &metav1.Duration{Duration: nodeStartupTimeout}
+	machineHealthCheckInvalidMaintenanceWindow.Annotations = map[string]string{
+		MaintenanceWindowsAnnotation: `[{"schedule": "never", "duration": "1h"}]`,
+	}
+
+	machineHealthCheckUnhealthyRange := maotesting.NewMachineHealthCheck("machineHealthCheckUnhealthyRange")
+	machineHealthCheckUnhealthyRange.Spec.NodeStartupTimeout = &metav1.Duration{Duration: nodeStartupTimeout}
+	machineHealthCheckUnhealthyRange.Annotations = map[string]string{UnhealthyRangeAnnotation: "[1-3]"}
+
+	machineHealthCheckBelowUnhealthyRange := maotesting.NewMachineHealthCheck("machineHealthCheckBelowUnhealthyRange")
+	machineHealthCheckBelowUnhealthyRange.Spec.NodeStartupTimeout = &metav1.Duration{Duration: nodeStartupTimeout}
+	machineHealthCheckBelowUnhealthyRange.Annotations = map[string]string{UnhealthyRangeAnnotation: "[2-3]"}
+
 	machineHealthCheckPaused := maotesting.NewMachineHealthCheck("machineHealthCheck")
 	machineHealthCheckPaused.Annotations = make(map[string]string)
 	machineHealthCheckPaused.Annotations[PausedAnnotation] = "test"
 
+	machineHealthCheckMachinePaused := maotesting.NewMachineHealthCheck("machineHealthCheck")
+	machineHealthCheckMachinePaused.Annotations = map[string]string{annotations.MachinePausedAnnotation: ""}
+
 	// remediationExternal
 	nodeUnhealthyForTooLong := maotesting.NewNode("nodeUnhealthyForTooLong", false)
 	nodeUnhealthyForTooLong.Annotations = map[string]string{
@@ -281,6 +315,18 @@ func TestReconcile(t *testing.T) {
 			expectedEvents: []string{},
 			expectedStatus: &machinev1.MachineHealthCheckStatus{},
 		},
+		{
+			name:    "machine unhealthy, MHC paused with machine API annotation",
+			machine: machineUnhealthyForTooLong,
+			node:    nodeUnhealthyForTooLong,
+			mhc:     machineHealthCheckMachinePaused,
+			expected: expectedReconcile{
+				result: reconcile.Result{},
+				error:  false,
+			},
+			expectedEvents: []string{},
+			expectedStatus: &machinev1.MachineHealthCheckStatus{},
+		},
 		{
 			name:    "machine with node healthy",
 			machine: machineWithNodeHealthy,
@@ -484,6 +530,115 @@ func TestReconcile(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "machine unhealthy, outside of maintenance window",
+			machine: machineUnhealthyForTooLong,
+			node:    nodeUnhealthyForTooLong,
+			mhc:     machineHealthCheckOutsideMaintenanceWindow,
+			expected: expectedReconcile{
+				result: reconcile.Result{
+					RequeueAfter: time.Hour,
+				},
+				error: false,
+			},
+			expectedEvents: []string{EventRemediationDeferred},
+			expectedStatus: &machinev1.MachineHealthCheckStatus{
+				ExpectedMachines:    IntPtr(1),
+				CurrentHealthy:      IntPtr(0),
+				RemediationsAllowed: 0,
+				Conditions: machinev1.Conditions{
+					{
+						Type:     machinev1.RemediationAllowedCondition,
+						Status:   corev1.ConditionFalse,
+						Severity: machinev1.ConditionSeverityInfo,
+						Reason:   OutsideMaintenanceWindowReason,
+						Message:  fmt.Sprintf("Remediation is deferred until the next maintenance window opens at %s", nextMaintenanceWindow.Format(time.RFC3339)),
+					},
+				},
+			},
+		},
+		{
+			name:    "machine unhealthy, inside of maintenance window",
+			machine: machineUnhealthyForTooLong,
+			node:    nodeUnhealthyForTooLong,
+			mhc:     machineHealthCheckInsideMaintenanceWindow,
+			expected: expectedReconcile{
+				result: reconcile.Result{},
+				error:  false,
+			},
+			expectedEvents: []string{EventMachineDeleted},
+			expectedStatus: &machinev1.MachineHealthCheckStatus{
+				ExpectedMachines:    IntPtr(1),
+				CurrentHealthy:      IntPtr(0),
+				RemediationsAllowed: 0,
+				Conditions: machinev1.Conditions{
+					remediationAllowedCondition,
+				},
+			},
+		},
+		{
+			name:    "machine with node healthy, below the unhealthy range",
+			machine: machineWithNodeHealthy,
+			node:    nodeHealthy,
+			mhc:     machineHealthCheckUnhealthyRange,
+			expected: expectedReconcile{
+				result: reconcile.Result{},
+				error:  false,
+			},
+			expectedEvents: []string{},
+			expectedStatus: &machinev1.MachineHealthCheckStatus{
+				ExpectedMachines:    IntPtr(1),
+				CurrentHealthy:      IntPtr(1),
+				RemediationsAllowed: 3,
+				Conditions: machinev1.Conditions{
+					remediationAllowedCondition,
+				},
+			},
+		},
+		{
+			name:    "machine unhealthy, below the unhealthy range",
+			machine: machineUnhealthyForTooLong,
+			node:    nodeUnhealthyForTooLong,
+			mhc:     machineHealthCheckBelowUnhealthyRange,
+			expected: expectedReconcile{
+				result: reconcile.Result{},
+				error:  false,
+			},
+			expectedEvents: []string{},
+			expectedStatus: &machinev1.MachineHealthCheckStatus{
+				ExpectedMachines:    IntPtr(1),
+				CurrentHealthy:      IntPtr(0),
+				RemediationsAllowed: 2,
+				Conditions: machinev1.Conditions{
+					remediationAllowedCondition,
+				},
+			},
+		},
+		{
+			name:    "machine unhealthy with invalid maintenance windows",
+			machine: machineUnhealthyForTooLong,
+			node:    nodeUnhealthyForTooLong,
+			mhc:     machineHealthCheckInvalidMaintenanceWindow,
+			expected: expectedReconcile{
+				result: reconcile.Result{},
+				error:  false,
+			},
+			expectedEvents: []string{EventRemediationDeferred},
+			expectedStatus: &machinev1.MachineHealthCheckStatus{
+				ExpectedMachines:    IntPtr(1),
+				CurrentHealthy:      IntPtr(0),
+				RemediationsAllowed: 0,
+				Conditions: machinev1.Conditions{
+					{
+						Type:     machinev1.RemediationAllowedCondition,
+						Status:   corev1.ConditionFalse,
+						Severity: machinev1.ConditionSeverityWarning,
+						Reason:   InvalidMaintenanceWindowsReason,
+						Message:  `invalid machine.openshift.io/maintenance-windows annotation: window 0: invalid cron expression "never": expected 5 fields, found 1`,
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -495,6 +650,33 @@ func TestReconcile(t *testing.T) {
 	}
 }
 
+func TestReconcileRecordsDeferralOnce(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	node := maotesting.NewNode("nodeUnhealthyForTooLong", false)
+	node.Annotations = map[string]string{
+		machineAnnotationKey: fmt.Sprintf("%s/%s", namespace, "machineUnhealthyForTooLong"),
+	}
+	machine := maotesting.NewMachine("machineUnhealthyForTooLong", node.Name)
+	mhc := maotesting.NewMachineHealthCheck("machineHealthCheckOutsideMaintenanceWindow")
+	mhc.Annotations = map[string]string{
+		MaintenanceWindowsAnnotation: `[{"schedule": "0 0 1 1 *", "duration": "1m"}]`,
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	r := newFakeReconcilerWithCustomRecorder(recorder, mhc, machine, node)
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: mhc.Namespace, Name: mhc.Name}}
+
+	// The deferral is recorded when the remediation starts being deferred, not on the following reconciles.
+	for i := 0; i < 3; i++ {
+		_, err := r.Reconcile(ctx, request)
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	g.Expect(recorder.Events).To(HaveLen(1))
+	g.Expect(<-recorder.Events).To(ContainSubstring(EventRemediationDeferred))
+}
+
 func TestReconcileExternalRemediationTemplate(t *testing.T) {
 	ctx := context.Background()
 
@@ -668,7 +850,7 @@ func TestApplyRemediationExternal(t *testing.T) {
 		Machine: *machineUnhealthyForTooLong,
 		MHC:     machinev1.MachineHealthCheck{},
 	}
-	if err := target.remediationStrategyExternal(r); err != nil {
+	if _, err := target.remediationStrategyExternal(r); err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
 	assertEvents(
@@ -1541,6 +1723,7 @@ func TestGetNodeFromMachine(t *testing.T) {
 func TestNeedsRemediation(t *testing.T) {
 	knownDate := metav1.Time{Time: time.Date(1985, 06, 03, 0, 0, 0, 0, time.Local)}
 	machineFailed := machinev1.PhaseFailed
+	machineStopped := machinePhaseStopped
 	testCases := []struct {
 		testCase                    string
 		target                      *target
@@ -1885,6 +2068,31 @@ func TestNeedsRemediation(t *testing.T) {
 			expectedNextCheck:           time.Duration(0),
 			expectedError:               false,
 		},
+		{
+			testCase: "healthy: machine phase stopped",
+			target: &target{
+				Machine: machinev1.Machine{
+					TypeMeta: metav1.TypeMeta{Kind: "Machine"},
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations:     make(map[string]string),
+						Name:            "machine",
+						Namespace:       namespace,
+						Labels:          map[string]string{"foo": "bar"},
+						OwnerReferences: []metav1.OwnerReference{{Kind: "MachineSet"}},
+					},
+					Spec: machinev1.MachineSpec{},
+					Status: machinev1.MachineStatus{
+						Phase: &machineStopped,
+					},
+				},
+				Node: maotesting.NewNode("node", false),
+				MHC:  *maotesting.NewMachineHealthCheck("test"),
+			},
+			timeoutForMachineToHaveNode: defaultNodeStartupTimeout,
+			expectedNeedsRemediation:    false,
+			expectedNextCheck:           time.Duration(0),
+			expectedError:               false,
+		},
 		{
 			testCase: "healthy: meet conditions criteria but timeout",
 			target: &target{
@@ -2154,7 +2362,7 @@ func TestRemediate(t *testing.T) {
 			objects = append(objects, runtime.Object(&tc.target.Machine))
 			recorder := record.NewFakeRecorder(2)
 			r := newFakeReconcilerWithCustomRecorder(recorder, objects...)
-			if err := r.internalRemediation(*tc.target); (err != nil) != tc.expectedError {
+			if _, err := r.internalRemediation(*tc.target); (err != nil) != tc.expectedError {
 				t.Errorf("Case: %v. Got: %v, expected error: %v", tc.testCase, err, tc.expectedError)
 			}
 			assertEvents(t, tc.testCase, tc.expectedEvents, recorder.Events)
@@ -2178,6 +2386,42 @@ func TestRemediate(t *testing.T) {
 	}
 }
 
+func TestRemediateObservesRemediationMetrics(t *testing.T) {
+	g := NewWithT(t)
+
+	remediations := func() float64 {
+		m := &dto.Metric{}
+		g.Expect(metrics.MachineHealthCheckRemediationsTotal.WithLabelValues(MachineFailedReason, "remediation-metrics", namespace).Write(m)).To(Succeed())
+		return m.GetCounter().GetValue()
+	}
+	before := remediations()
+
+	mhc := maotesting.NewMachineHealthCheck("remediation-metrics")
+	machine := newMachineOwnedBy("machine", "", "machineset")
+	machine.Status.Phase = pointer.String(machinev1.PhaseFailed)
+	r := newFakeReconcilerWithCustomRecorder(record.NewFakeRecorder(2), mhc, machine)
+
+	errList, records := r.remediate(context.TODO(), []target{{Machine: *machine, MHC: *mhc}}, mhc)
+	g.Expect(errList).To(BeEmpty())
+	g.Expect(records).To(HaveLen(1))
+	g.Expect(remediations()).To(Equal(before + 1))
+}
+
+func TestReconcileDeletesMetricsOfDeletedMHC(t *testing.T) {
+	g := NewWithT(t)
+
+	metrics.ObserveMachineHealthCheckUnhealthyMachines("deleted", namespace, 2)
+	metrics.ObserveMachineHealthCheckShortCircuitEnabled("deleted", namespace)
+
+	r := newFakeReconciler()
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: "deleted"}})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	labels := prometheus.Labels{"mhc": "deleted", "namespace": namespace}
+	g.Expect(metrics.MachineHealthCheckUnhealthyMachines.Delete(labels)).To(BeFalse())
+	g.Expect(metrics.MachineHealthCheckShortCircuited.Delete(labels)).To(BeFalse())
+}
+
 func TestReconcileStatus(t *testing.T) {
 	testCases := []struct {
 		testCase            string
@@ -2922,6 +3166,109 @@ func TestGetMaxUnhealthy(t *testing.T) {
 	}
 }
 
+func TestUnhealthyRange(t *testing.T) {
+	maxUnhealthy := intstr.FromInt(1)
+
+	testCases := []struct {
+		name                 string
+		unhealthyRange       string
+		unhealthy            int
+		expectedAllowed      bool
+		expectedBelowRange   bool
+		expectedMaxUnhealthy int
+		expectedErr          string
+	}{
+		{
+			name:                 "without unhealthy machines",
+			unhealthyRange:       "[1-5]",
+			unhealthy:            0,
+			expectedAllowed:      true,
+			expectedBelowRange:   true,
+			expectedMaxUnhealthy: 5,
+		},
+		{
+			name:                 "below the range",
+			unhealthyRange:       "[2-5]",
+			unhealthy:            1,
+			expectedAllowed:      true,
+			expectedBelowRange:   true,
+			expectedMaxUnhealthy: 5,
+		},
+		{
+			name:                 "at the lower bound of the range",
+			unhealthyRange:       "[2-5]",
+			unhealthy:            2,
+			expectedAllowed:      true,
+			expectedMaxUnhealthy: 5,
+		},
+		{
+			name:                 "at the upper bound of the range, above maxUnhealthy",
+			unhealthyRange:       "[2-5]",
+			unhealthy:            5,
+			expectedAllowed:      true,
+			expectedMaxUnhealthy: 5,
+		},
+		{
+			name:                 "above the range",
+			unhealthyRange:       "[2-5]",
+			unhealthy:            6,
+			expectedAllowed:      false,
+			expectedMaxUnhealthy: 5,
+		},
+		{
+			name:                 "with an invalid range, within maxUnhealthy",
+			unhealthyRange:       "2-5",
+			unhealthy:            1,
+			expectedAllowed:      true,
+			expectedMaxUnhealthy: 1,
+			expectedErr:          `invalid machine.openshift.io/unhealthy-range annotation "2-5", expected a range of the form [min-max]`,
+		},
+		{
+			name:                 "with min greater than max, above maxUnhealthy",
+			unhealthyRange:       "[5-2]",
+			unhealthy:            2,
+			expectedAllowed:      false,
+			expectedMaxUnhealthy: 1,
+			expectedErr:          `invalid machine.openshift.io/unhealthy-range annotation "[5-2]", min must not be greater than max`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			expectedMachines := 10
+			currentHealthy := expectedMachines - tc.unhealthy
+			mhc := &machinev1.MachineHealthCheck{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{UnhealthyRangeAnnotation: tc.unhealthyRange},
+				},
+				Spec: machinev1.MachineHealthCheckSpec{
+					MaxUnhealthy: &maxUnhealthy,
+				},
+				Status: machinev1.MachineHealthCheckStatus{
+					ExpectedMachines: &expectedMachines,
+					CurrentHealthy:   &currentHealthy,
+				},
+			}
+
+			g.Expect(isAllowedRemediation(mhc)).To(Equal(tc.expectedAllowed))
+			g.Expect(belowUnhealthyRange(mhc)).To(Equal(tc.expectedBelowRange))
+
+			// An invalid range falls back to maxUnhealthy.
+			maxUnhealthy, err := getMaxUnhealthy(mhc)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(maxUnhealthy).To(Equal(tc.expectedMaxUnhealthy))
+
+			if tc.expectedErr != "" {
+				g.Expect(ValidateUnhealthyRange(tc.unhealthyRange)).To(MatchError(tc.expectedErr))
+			} else {
+				g.Expect(ValidateUnhealthyRange(tc.unhealthyRange)).To(Succeed())
+			}
+		})
+	}
+}
+
 func TestGetIntOrPercentValue(t *testing.T) {
 	int10 := intstr.FromInt(10)
 	percent20 := intstr.FromString("20%")
@@ -3066,11 +3413,13 @@ func (f fakeReconcilerBuilder) WithScheme(scheme *runtime.Scheme) fakeReconciler
 }
 
 func (f fakeReconcilerBuilder) Build() *ReconcileMachineHealthCheck {
+	fakeClient := f.fakeClientBuilder.Build()
 	return &ReconcileMachineHealthCheck{
-		client:    f.fakeClientBuilder.Build(),
+		client:    fakeClient,
 		scheme:    f.scheme,
 		namespace: f.namespace,
 		recorder:  f.recorder,
+		apiReader: fakeClient,
 	}
 }
 
@@ -3084,6 +3433,7 @@ func newFakeReconcilerWithCustomRecorder(recorder record.EventRecorder, initObje
 		scheme:    scheme.Scheme,
 		namespace: namespace,
 		recorder:  recorder,
+		apiReader: fakeClient,
 	}
 }
 
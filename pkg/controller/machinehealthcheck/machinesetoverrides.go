@@ -0,0 +1,165 @@
+package machinehealthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// ExcludeFromMachineHealthChecksAnnotation excludes the Machines of a MachineSet from all the
+	// MachineHealthChecks when set to "true". They are neither health checked nor counted in the
+	// expected machines and maxUnhealthy of the MachineHealthChecks matching them.
+	ExcludeFromMachineHealthChecksAnnotation = "machine.openshift.io/exclude-from-machine-health-checks"
+
+	// MachineHealthCheckOverridesAnnotation overrides the health checks of the Machines of a MachineSet, e.g.
+	// for the nodes which take longer to boot than the other nodes matched by a cluster-wide MachineHealthCheck.
+	// It is set on the MachineSet as a JSON object, e.g. {"nodeStartupTimeout": "30m", "unhealthyConditions":
+	// [{"type": "Ready", "status": "Unknown", "timeout": "15m"}]}. The nodeStartupTimeout replaces the one of the
+	// MachineHealthChecks, and each unhealthy condition replaces the condition of the same type and status of the
+	// MachineHealthChecks, or is added to them.
+	MachineHealthCheckOverridesAnnotation = "machine.openshift.io/machine-health-check-overrides"
+)
+
+// machineHealthCheckOverrides is the value of the MachineHealthCheckOverridesAnnotation.
+type machineHealthCheckOverrides struct {
+	// NodeStartupTimeout replaces the nodeStartupTimeout of the MachineHealthChecks when set, zero disables it.
+	NodeStartupTimeout *metav1.Duration `json:"nodeStartupTimeout,omitempty"`
+	// UnhealthyConditions are merged into the unhealthyConditions of the MachineHealthChecks, by type and status.
+	UnhealthyConditions []machinev1.UnhealthyCondition `json:"unhealthyConditions,omitempty"`
+}
+
+// isExcludedFromMachineHealthChecks returns true if the Machines of the MachineSet are not health checked.
+func isExcludedFromMachineHealthChecks(ms *machinev1.MachineSet) bool {
+	return ms != nil && ms.Annotations[ExcludeFromMachineHealthChecksAnnotation] == "true"
+}
+
+// getMachineHealthCheckOverrides returns the overrides of the health checks of the Machines of the MachineSet,
+// none when the MachineSet is nil, does not have the MachineHealthCheckOverridesAnnotation or it is invalid.
+func getMachineHealthCheckOverrides(ms *machinev1.MachineSet) (machineHealthCheckOverrides, error) {
+	if ms == nil {
+		return machineHealthCheckOverrides{}, nil
+	}
+	value, ok := ms.Annotations[MachineHealthCheckOverridesAnnotation]
+	if !ok {
+		return machineHealthCheckOverrides{}, nil
+	}
+
+	overrides, err := parseMachineHealthCheckOverrides(value)
+	if err != nil {
+		return machineHealthCheckOverrides{}, fmt.Errorf("invalid %s annotation of MachineSet %s: %w", MachineHealthCheckOverridesAnnotation, ms.Name, err)
+	}
+	return overrides, nil
+}
+
+// ValidateMachineHealthCheckOverrides returns an error when the value of the MachineHealthCheckOverridesAnnotation
+// is invalid.
+func ValidateMachineHealthCheckOverrides(value string) error {
+	_, err := parseMachineHealthCheckOverrides(value)
+	return err
+}
+
+// parseMachineHealthCheckOverrides parses the value of the MachineHealthCheckOverridesAnnotation.
+func parseMachineHealthCheckOverrides(value string) (machineHealthCheckOverrides, error) {
+	var overrides machineHealthCheckOverrides
+	if err := json.Unmarshal([]byte(value), &overrides); err != nil {
+		return overrides, err
+	}
+	if overrides.NodeStartupTimeout != nil && overrides.NodeStartupTimeout.Duration < 0 {
+		return overrides, fmt.Errorf("nodeStartupTimeout must not be negative")
+	}
+	for i, c := range overrides.UnhealthyConditions {
+		if c.Type == "" || c.Status == "" {
+			return overrides, fmt.Errorf("unhealthy condition %d: type and status must be set", i)
+		}
+		if c.Timeout.Duration < 0 {
+			return overrides, fmt.Errorf("unhealthy condition %d: timeout must not be negative", i)
+		}
+	}
+	return overrides, nil
+}
+
+// nodeStartupTimeout returns the node startup timeout of the Machines, given the one of the MachineHealthCheck.
+func (o machineHealthCheckOverrides) nodeStartupTimeout(timeout time.Duration) time.Duration {
+	if o.NodeStartupTimeout != nil {
+		return o.NodeStartupTimeout.Duration
+	}
+	return timeout
+}
+
+// unhealthyConditions returns the unhealthy conditions of the MachineHealthCheck merged with the overridden ones.
+func (o machineHealthCheckOverrides) unhealthyConditions(conditions []machinev1.UnhealthyCondition) []machinev1.UnhealthyCondition {
+	if len(o.UnhealthyConditions) == 0 {
+		return conditions
+	}
+
+	merged := make([]machinev1.UnhealthyCondition, 0, len(conditions)+len(o.UnhealthyConditions))
+	for _, c := range conditions {
+		if !o.overridesCondition(c) {
+			merged = append(merged, c)
+		}
+	}
+	return append(merged, o.UnhealthyConditions...)
+}
+
+// overridesCondition returns true if an overridden unhealthy condition has the type and status of the condition.
+func (o machineHealthCheckOverrides) overridesCondition(condition machinev1.UnhealthyCondition) bool {
+	for _, c := range o.UnhealthyConditions {
+		if c.Type == condition.Type && c.Status == condition.Status {
+			return true
+		}
+	}
+	return false
+}
+
+// getMachineSetFromMachine returns the MachineSet owning the Machine, nil when it is not owned by a MachineSet
+// or the MachineSet does not exist anymore.
+func (r *ReconcileMachineHealthCheck) getMachineSetFromMachine(machine machinev1.Machine) (*machinev1.MachineSet, error) {
+	owner := metav1.GetControllerOf(&machine)
+	if owner == nil || owner.Kind != "MachineSet" || owner.Name == "" {
+		return nil, nil
+	}
+
+	ms := &machinev1.MachineSet{}
+	if err := r.client.Get(context.TODO(), client.ObjectKey{Namespace: machine.Namespace, Name: owner.Name}, ms); err != nil {
+		if apimachineryerrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return ms, nil
+}
+
+// mhcRequestsFromMachineSet returns the MachineHealthChecks matching the Machines of the MachineSet, so that the
+// changes of its overrides are applied.
+func (r *ReconcileMachineHealthCheck) mhcRequestsFromMachineSet(o client.Object) []reconcile.Request {
+	klog.V(4).Infof("Getting MHC requests from machineset %q", namespacedName(o).String())
+	ms, ok := o.(*machinev1.MachineSet)
+	if !ok {
+		return nil
+	}
+	// The Machines of the MachineSet have the labels of its template.
+	machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Labels: ms.Spec.Template.Labels}}
+
+	mhcList := &machinev1.MachineHealthCheckList{}
+	if err := r.client.List(context.Background(), mhcList, client.InNamespace(ms.Namespace)); err != nil {
+		klog.Errorf("No-op: Unable to list mhc: %v", err)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for k := range mhcList.Items {
+		if hasMatchingLabels(&mhcList.Items[k], machine) {
+			requests = append(requests, reconcile.Request{NamespacedName: namespacedName(&mhcList.Items[k])})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,200 @@
+package machinehealthcheck
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	maotesting "github.com/openshift/machine-api-operator/pkg/util/testing"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newMachineSet(name string, annotations map[string]string) *machinev1.MachineSet {
+	return &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   maotesting.Namespace,
+			Annotations: annotations,
+		},
+		Spec: machinev1.MachineSetSpec{
+			Template: machinev1.MachineTemplateSpec{
+				ObjectMeta: machinev1.ObjectMeta{Labels: maotesting.FooBar()},
+			},
+		},
+	}
+}
+
+func newMachineOwnedBy(name, nodeName, machineSetName string) *machinev1.Machine {
+	machine := maotesting.NewMachine(name, nodeName)
+	machine.OwnerReferences = []metav1.OwnerReference{{Kind: "MachineSet", Name: machineSetName, Controller: pointer.Bool(true)}}
+	return machine
+}
+
+func TestGetMachineHealthCheckOverrides(t *testing.T) {
+	testCases := []struct {
+		name          string
+		annotation    *string
+		expected      machineHealthCheckOverrides
+		expectedError string
+	}{
+		{
+			name: "without overrides",
+		},
+		{
+			name:       "with overrides",
+			annotation: stringPtr(`{"nodeStartupTimeout": "30m", "unhealthyConditions": [{"type": "Ready", "status": "Unknown", "timeout": "15m"}]}`),
+			expected: machineHealthCheckOverrides{
+				NodeStartupTimeout: &metav1.Duration{Duration: 30 * time.Minute},
+				UnhealthyConditions: []machinev1.UnhealthyCondition{
+					{Type: corev1.NodeReady, Status: corev1.ConditionUnknown, Timeout: metav1.Duration{Duration: 15 * time.Minute}},
+				},
+			},
+		},
+		{
+			name:          "with invalid JSON",
+			annotation:    stringPtr(`30m`),
+			expectedError: "invalid machine.openshift.io/machine-health-check-overrides annotation of MachineSet gpu: invalid character 'm' after top-level value",
+		},
+		{
+			name:          "with negative node startup timeout",
+			annotation:    stringPtr(`{"nodeStartupTimeout": "-30m"}`),
+			expectedError: "invalid machine.openshift.io/machine-health-check-overrides annotation of MachineSet gpu: nodeStartupTimeout must not be negative",
+		},
+		{
+			name:          "without condition status",
+			annotation:    stringPtr(`{"unhealthyConditions": [{"type": "Ready", "timeout": "15m"}]}`),
+			expectedError: "invalid machine.openshift.io/machine-health-check-overrides annotation of MachineSet gpu: unhealthy condition 0: type and status must be set",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := newMachineSet("gpu", nil)
+			if tc.annotation != nil {
+				ms.Annotations = map[string]string{MachineHealthCheckOverridesAnnotation: *tc.annotation}
+			}
+
+			overrides, err := getMachineHealthCheckOverrides(ms)
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(overrides).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestNeedsRemediationWithMachineSetOverrides(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name              string
+		overrides         string
+		withoutNode       bool
+		expectedUnhealthy bool
+		expectedNextCheck time.Duration
+	}{
+		{
+			name:              "node not ready longer than the timeout of the MachineHealthCheck",
+			expectedUnhealthy: true,
+		},
+		{
+			name:              "node not ready shorter than the overridden timeout",
+			overrides:         `{"unhealthyConditions": [{"type": "Ready", "status": "Unknown", "timeout": "30m"}]}`,
+			expectedNextCheck: 20*time.Minute + time.Second,
+		},
+		{
+			name:              "node not ready with an overridden timeout of another status",
+			overrides:         `{"unhealthyConditions": [{"type": "Ready", "status": "False", "timeout": "30m"}]}`,
+			expectedUnhealthy: true,
+		},
+		{
+			name:              "machine without node longer than the node startup timeout of the MachineHealthCheck",
+			withoutNode:       true,
+			expectedUnhealthy: true,
+		},
+		{
+			name:              "machine without node shorter than the overridden node startup timeout",
+			overrides:         `{"nodeStartupTimeout": "30m"}`,
+			withoutNode:       true,
+			expectedNextCheck: 15*time.Minute + time.Second,
+		},
+		{
+			name:              "machine without node with invalid overrides",
+			overrides:         `{"nodeStartupTimeout": "-30m"}`,
+			withoutNode:       true,
+			expectedUnhealthy: true,
+		},
+		{
+			name:        "machine without node with the node startup timeout disabled",
+			overrides:   `{"nodeStartupTimeout": "0s"}`,
+			withoutNode: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			node := maotesting.NewNode("node", false)
+			node.Status.Conditions[0].LastTransitionTime = metav1.NewTime(now.Add(-10 * time.Minute))
+			machine := maotesting.NewMachine("machine", node.Name)
+			machine.Status.LastUpdated = &metav1.Time{Time: now.Add(-15 * time.Minute)}
+			ms := newMachineSet("gpu", nil)
+			if tc.overrides != "" {
+				ms.Annotations = map[string]string{MachineHealthCheckOverridesAnnotation: tc.overrides}
+			}
+			target := target{Machine: *machine, Node: node, MHC: *maotesting.NewMachineHealthCheck("mhc"), MachineSet: ms}
+			if tc.withoutNode {
+				target.Node = nil
+			}
+
+			unhealthy, nextCheck, err := target.needsRemediation(defaultNodeStartupTimeout)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(unhealthy).To(Equal(tc.expectedUnhealthy))
+			g.Expect(nextCheck).To(BeNumerically("~", tc.expectedNextCheck, 5*time.Second))
+		})
+	}
+}
+
+func TestGetTargetsFromMHCWithMachineSetOverrides(t *testing.T) {
+	g := NewWithT(t)
+
+	mhc := maotesting.NewMachineHealthCheck("mhc")
+	excluded := newMachineSet("gpu", map[string]string{ExcludeFromMachineHealthChecksAnnotation: "true"})
+	overridden := newMachineSet("worker", map[string]string{MachineHealthCheckOverridesAnnotation: `{"nodeStartupTimeout": "30m"}`})
+	r := newFakeReconciler(mhc, excluded, overridden,
+		newMachineOwnedBy("gpu-a", "", excluded.Name),
+		newMachineOwnedBy("worker-a", "", overridden.Name),
+		newMachineOwnedBy("orphan-a", "", "deleted"),
+	)
+
+	targets, err := r.getTargetsFromMHC(*mhc)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(targets).To(HaveLen(2))
+	for _, target := range targets {
+		switch target.Machine.Name {
+		case "worker-a":
+			g.Expect(target.MachineSet).ToNot(BeNil())
+			g.Expect(target.MachineSet.Name).To(Equal(overridden.Name))
+		case "orphan-a":
+			g.Expect(target.MachineSet).To(BeNil())
+		default:
+			t.Errorf("unexpected target %s", target.Machine.Name)
+		}
+	}
+
+	// The MachineHealthChecks matching the Machines of a MachineSet are reconciled when it changes.
+	requests := r.mhcRequestsFromMachineSet(overridden)
+	g.Expect(requests).To(ConsistOf(HaveField("NamespacedName", client.ObjectKeyFromObject(mhc))))
+	other := newMachineSet("other", nil)
+	other.Spec.Template.Labels = map[string]string{"no": "match"}
+	g.Expect(r.mhcRequestsFromMachineSet(other)).To(BeEmpty())
+}
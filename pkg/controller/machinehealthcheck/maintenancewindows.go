@@ -0,0 +1,123 @@
+package machinehealthcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/schedule"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// MaintenanceWindowsAnnotation restricts remediation by a MachineHealthCheck to maintenance windows.
+	// The MachineHealthCheck API does not have a field for them, so they are set with this annotation
+	// as a JSON list, e.g. [{"schedule": "0 22 * * 1-5", "duration": "6h", "timeZone": "Europe/Prague"}].
+	// Each window opens when its cron schedule fires and stays open for its duration.
+	// Outside of the windows, unhealthy machines are reported in the status but are not remediated.
+	MaintenanceWindowsAnnotation = "machine.openshift.io/maintenance-windows"
+
+	// OutsideMaintenanceWindowReason is used when remediation is deferred until the next maintenance window.
+	OutsideMaintenanceWindowReason = "OutsideMaintenanceWindow"
+	// InvalidMaintenanceWindowsReason is used when the maintenance windows of the MachineHealthCheck cannot be parsed.
+	InvalidMaintenanceWindowsReason = "InvalidMaintenanceWindows"
+
+	// EventRemediationDeferred is emitted when an unhealthy machine is not remediated
+	// because it is outside of the maintenance windows of the MachineHealthCheck
+	EventRemediationDeferred string = "RemediationDeferred"
+)
+
+// maintenanceWindow is a single entry of the MaintenanceWindowsAnnotation.
+type maintenanceWindow struct {
+	// Schedule is the cron schedule at which the window opens.
+	Schedule string `json:"schedule"`
+	// Duration is how long the window stays open.
+	Duration metav1.Duration `json:"duration"`
+	// TimeZone is the IANA time zone the schedule is evaluated in. Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+type parsedMaintenanceWindow struct {
+	schedule *schedule.Schedule
+	duration time.Duration
+	location *time.Location
+}
+
+// getMaintenanceWindows returns the maintenance windows of the MachineHealthCheck, or nil when it has none.
+func getMaintenanceWindows(mhc *machinev1.MachineHealthCheck) ([]parsedMaintenanceWindow, error) {
+	value, ok := mhc.Annotations[MaintenanceWindowsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	return parseMaintenanceWindows(value)
+}
+
+// ValidateMaintenanceWindows returns an error when the value of the MaintenanceWindowsAnnotation is invalid.
+func ValidateMaintenanceWindows(value string) error {
+	_, err := parseMaintenanceWindows(value)
+	return err
+}
+
+// parseMaintenanceWindows parses the value of the MaintenanceWindowsAnnotation.
+func parseMaintenanceWindows(value string) ([]parsedMaintenanceWindow, error) {
+	var windows []maintenanceWindow
+	if err := json.Unmarshal([]byte(value), &windows); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", MaintenanceWindowsAnnotation, err)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("invalid %s annotation: at least one maintenance window must be set", MaintenanceWindowsAnnotation)
+	}
+
+	parsed := make([]parsedMaintenanceWindow, 0, len(windows))
+	for i, w := range windows {
+		s, err := schedule.Parse(w.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: window %d: %w", MaintenanceWindowsAnnotation, i, err)
+		}
+		if w.Duration.Duration <= 0 {
+			return nil, fmt.Errorf("invalid %s annotation: window %d: duration must be positive", MaintenanceWindowsAnnotation, i)
+		}
+
+		location := time.UTC
+		if w.TimeZone != "" {
+			if location, err = time.LoadLocation(w.TimeZone); err != nil {
+				return nil, fmt.Errorf("invalid %s annotation: window %d: %w", MaintenanceWindowsAnnotation, i, err)
+			}
+		}
+
+		parsed = append(parsed, parsedMaintenanceWindow{schedule: s, duration: w.Duration.Duration, location: location})
+	}
+	return parsed, nil
+}
+
+// inMaintenanceWindow returns true if now is inside one of the maintenance windows of the MachineHealthCheck,
+// or if it has none. Otherwise, it also returns the time at which the next window opens.
+func inMaintenanceWindow(mhc *machinev1.MachineHealthCheck, now time.Time) (bool, time.Time, error) {
+	windows, err := getMaintenanceWindows(mhc)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	if windows == nil {
+		return true, time.Time{}, nil
+	}
+
+	var nextOpen time.Time
+	for _, w := range windows {
+		local := now.In(w.location)
+		if opened := w.schedule.Prev(local); !opened.IsZero() && local.Before(opened.Add(w.duration)) {
+			return true, time.Time{}, nil
+		}
+
+		if next := w.schedule.Next(local); !next.IsZero() && (nextOpen.IsZero() || next.Before(nextOpen)) {
+			nextOpen = next
+		}
+	}
+	return false, nextOpen, nil
+}
+
+// deferralChanged returns true when the RemediationAllowed condition deferring the remediation differs from the
+// previous one, e.g. when the remediation starts being deferred or the next window is a different one.
+func deferralChanged(previous, current *machinev1.Condition) bool {
+	return previous == nil || previous.Status != current.Status || previous.Reason != current.Reason || previous.Message != current.Message
+}
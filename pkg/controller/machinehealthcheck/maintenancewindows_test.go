@@ -0,0 +1,97 @@
+package machinehealthcheck
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInMaintenanceWindow(t *testing.T) {
+	// Wednesday
+	now := time.Date(2023, time.March, 15, 14, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name             string
+		annotation       *string
+		expectedInWindow bool
+		expectedNext     time.Time
+		expectedError    string
+	}{
+		{
+			name:             "without maintenance windows",
+			expectedInWindow: true,
+		},
+		{
+			name:             "inside a window",
+			annotation:       stringPtr(`[{"schedule": "0 14 * * *", "duration": "1h"}]`),
+			expectedInWindow: true,
+		},
+		{
+			name:         "after a window closed",
+			annotation:   stringPtr(`[{"schedule": "0 14 * * *", "duration": "30m"}]`),
+			expectedNext: time.Date(2023, time.March, 16, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "before the earliest of several windows",
+			annotation:   stringPtr(`[{"schedule": "0 22 * * 1-5", "duration": "6h"}, {"schedule": "0 16 * * 3", "duration": "1h"}]`),
+			expectedNext: time.Date(2023, time.March, 15, 16, 0, 0, 0, time.UTC),
+		},
+		{
+			name:             "inside a window opened the previous day",
+			annotation:       stringPtr(`[{"schedule": "0 22 * * *", "duration": "24h"}]`),
+			expectedInWindow: true,
+		},
+		{
+			name:         "in a time zone",
+			annotation:   stringPtr(`[{"schedule": "0 14 * * *", "duration": "1h", "timeZone": "America/New_York"}]`),
+			expectedNext: time.Date(2023, time.March, 15, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "with invalid JSON",
+			annotation:    stringPtr(`0 22 * * *`),
+			expectedError: "invalid machine.openshift.io/maintenance-windows annotation: invalid character '2' after top-level value",
+		},
+		{
+			name:          "without windows",
+			annotation:    stringPtr(`[]`),
+			expectedError: "invalid machine.openshift.io/maintenance-windows annotation: at least one maintenance window must be set",
+		},
+		{
+			name:          "without a duration",
+			annotation:    stringPtr(`[{"schedule": "0 22 * * *"}]`),
+			expectedError: "invalid machine.openshift.io/maintenance-windows annotation: window 0: duration must be positive",
+		},
+		{
+			name:          "with an invalid time zone",
+			annotation:    stringPtr(`[{"schedule": "0 22 * * *", "duration": "1h", "timeZone": "Mars/Olympus_Mons"}]`),
+			expectedError: "invalid machine.openshift.io/maintenance-windows annotation: window 0: unknown time zone Mars/Olympus_Mons",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mhc := &machinev1.MachineHealthCheck{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}}}
+			if tc.annotation != nil {
+				mhc.Annotations[MaintenanceWindowsAnnotation] = *tc.annotation
+			}
+
+			inWindow, next, err := inMaintenanceWindow(mhc, now)
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(inWindow).To(Equal(tc.expectedInWindow))
+			g.Expect(next.Equal(tc.expectedNext)).To(BeTrue(), "expected next window at %v, got %v", tc.expectedNext, next)
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
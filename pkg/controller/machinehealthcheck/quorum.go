@@ -0,0 +1,182 @@
+package machinehealthcheck
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// RemediationBlockedByQuorumCondition is true when the remediation of unhealthy control plane machines
+	// is blocked because removing them would break the etcd quorum.
+	RemediationBlockedByQuorumCondition machinev1.ConditionType = "RemediationBlockedByQuorum"
+
+	// EtcdQuorumAtRiskReason is used when remediating control plane machines would break the etcd quorum.
+	EtcdQuorumAtRiskReason = "EtcdQuorumAtRisk"
+	// EtcdQuorumUnknownReason is used when the etcd quorum could not be checked.
+	EtcdQuorumUnknownReason = "EtcdQuorumUnknown"
+
+	// EventRemediationBlockedByQuorum is emitted when the remediation of an unhealthy control plane
+	// machine is blocked because removing it would break the etcd quorum
+	EventRemediationBlockedByQuorum string = "RemediationBlockedByQuorum"
+
+	nodeControlPlaneLabel = "node-role.kubernetes.io/control-plane"
+
+	// etcdNamespace and etcdGuardPDBName identify the PodDisruptionBudget of the etcd guard pods,
+	// which are ready on the control plane nodes whose etcd member is healthy.
+	etcdNamespace    = "openshift-etcd"
+	etcdGuardPDBName = "etcd-guard-pdb"
+
+	// quorumRecheckInterval is how often blocked remediations are retried,
+	// changes to the etcd guard pods do not trigger reconciliation.
+	quorumRecheckInterval = time.Minute
+)
+
+// isControlPlane returns true if the target is a control plane machine.
+func (t *target) isControlPlane() bool {
+	if t.Machine.Labels[machineRoleLabel] == machineMasterRole {
+		return true
+	}
+	if t.Node == nil {
+		return false
+	}
+	_, master := t.Node.Labels[nodeMasterLabel]
+	_, controlPlane := t.Node.Labels[nodeControlPlaneLabel]
+	return master || controlPlane
+}
+
+// etcdQuorum tracks how many etcd members can be removed without breaking the quorum.
+type etcdQuorum struct {
+	// healthy is the number of healthy etcd members.
+	healthy int32
+	// desired is the number of healthy etcd members required for quorum.
+	desired int32
+	// healthyNodes are the nodes with a healthy etcd member.
+	healthyNodes map[string]bool
+}
+
+// getEtcdQuorum returns the quorum of etcd from its guard pods, or nil if the cluster does not have them.
+func (r *ReconcileMachineHealthCheck) getEtcdQuorum(ctx context.Context) (*etcdQuorum, error) {
+	pdb := &policyv1.PodDisruptionBudget{}
+	if err := r.apiReader.Get(ctx, client.ObjectKey{Namespace: etcdNamespace, Name: etcdGuardPDBName}, pdb); err != nil {
+		if apimachineryerrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get etcd guard PodDisruptionBudget: %v", err)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector of etcd guard PodDisruptionBudget: %v", err)
+	}
+	pods := &corev1.PodList{}
+	if err := r.apiReader.List(ctx, pods, client.InNamespace(etcdNamespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list etcd guard pods: %v", err)
+	}
+
+	quorum := &etcdQuorum{
+		healthy:      pdb.Status.CurrentHealthy,
+		desired:      pdb.Status.DesiredHealthy,
+		healthyNodes: map[string]bool{},
+	}
+	for _, pod := range pods.Items {
+		for _, c := range pod.Status.Conditions {
+			if c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue {
+				quorum.healthyNodes[pod.Spec.NodeName] = true
+			}
+		}
+	}
+	return quorum, nil
+}
+
+// remove returns true if the etcd member of the target, if any, can be removed without breaking the quorum.
+// The members of the targets allowed to be removed are no longer counted as healthy.
+func (q *etcdQuorum) remove(t target) bool {
+	if !q.healthyNodes[t.nodeName()] {
+		// Removing an unhealthy member does not reduce the quorum.
+		return true
+	}
+	if q.healthy-1 < q.desired {
+		return false
+	}
+	q.healthy--
+	delete(q.healthyNodes, t.nodeName())
+	return true
+}
+
+// gateControlPlaneRemediations returns the targets which can be remediated without breaking the etcd quorum,
+// along with whether some control plane targets were blocked. The RemediationBlockedByQuorum condition of
+// the MachineHealthCheck is updated accordingly.
+// When the quorum cannot be checked, all control plane targets are blocked.
+func (r *ReconcileMachineHealthCheck) gateControlPlaneRemediations(ctx context.Context, mhc *machinev1.MachineHealthCheck, targets []target) ([]target, bool, error) {
+	var controlPlane bool
+	for _, t := range targets {
+		controlPlane = controlPlane || t.isControlPlane()
+	}
+	if !controlPlane {
+		if conditions.Get(mhc, RemediationBlockedByQuorumCondition) != nil {
+			conditions.Set(mhc, &machinev1.Condition{Type: RemediationBlockedByQuorumCondition, Status: corev1.ConditionFalse})
+		}
+		return targets, false, nil
+	}
+
+	quorum, quorumErr := r.getEtcdQuorum(ctx)
+	if quorumErr == nil && quorum == nil {
+		klog.V(3).Infof("%s/%s: etcd guard PodDisruptionBudget not found, not checking the etcd quorum", mhc.Namespace, mhc.Name)
+		return targets, false, nil
+	}
+
+	var allowed []target
+	var blocked []string
+	for _, t := range targets {
+		if !t.isControlPlane() || (quorumErr == nil && quorum.remove(t)) {
+			allowed = append(allowed, t)
+			continue
+		}
+
+		klog.Warningf("%s: remediation blocked, removing the machine would break the etcd quorum", t.string())
+		r.recorder.Eventf(
+			&t.Machine,
+			corev1.EventTypeWarning,
+			EventRemediationBlockedByQuorum,
+			"Remediation of machine %v blocked, removing it would break the etcd quorum",
+			t.string(),
+		)
+		blocked = append(blocked, t.Machine.Name)
+	}
+
+	switch {
+	case quorumErr != nil:
+		conditions.Set(mhc, &machinev1.Condition{
+			Type:     RemediationBlockedByQuorumCondition,
+			Status:   corev1.ConditionTrue,
+			Severity: machinev1.ConditionSeverityWarning,
+			Reason:   EtcdQuorumUnknownReason,
+			Message:  fmt.Sprintf("Remediation of control plane machines is blocked, the etcd quorum could not be checked: %v", quorumErr),
+		})
+	case len(blocked) > 0:
+		sort.Strings(blocked)
+		conditions.Set(mhc, &machinev1.Condition{
+			Type:     RemediationBlockedByQuorumCondition,
+			Status:   corev1.ConditionTrue,
+			Severity: machinev1.ConditionSeverityWarning,
+			Reason:   EtcdQuorumAtRiskReason,
+			Message: fmt.Sprintf("Remediation is blocked for control plane machines whose removal would break the etcd quorum: %s",
+				strings.Join(blocked, ", ")),
+		})
+	default:
+		conditions.Set(mhc, &machinev1.Condition{Type: RemediationBlockedByQuorumCondition, Status: corev1.ConditionFalse})
+	}
+	return allowed, len(blocked) > 0, quorumErr
+}
@@ -0,0 +1,148 @@
+package machinehealthcheck
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+	maotesting "github.com/openshift/machine-api-operator/pkg/util/testing"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+func newEtcdGuardPDB(currentHealthy, desiredHealthy int32) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: etcdGuardPDBName, Namespace: etcdNamespace},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "guard"}},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{
+			CurrentHealthy: currentHealthy,
+			DesiredHealthy: desiredHealthy,
+		},
+	}
+}
+
+func newEtcdGuardPod(nodeName string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("etcd-guard-%s", nodeName),
+			Namespace: etcdNamespace,
+			Labels:    map[string]string{"app": "guard"},
+		},
+		Spec:   corev1.PodSpec{NodeName: nodeName},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}},
+	}
+}
+
+func newControlPlaneTarget(name string) target {
+	node := maotesting.NewNode(name, false)
+	node.Labels[nodeMasterLabel] = ""
+	machine := maotesting.NewMachine(name, node.Name)
+	machine.Labels[machineRoleLabel] = machineMasterRole
+	return target{Machine: *machine, Node: node}
+}
+
+func TestGateControlPlaneRemediations(t *testing.T) {
+	worker := target{Machine: *maotesting.NewMachine("worker", "worker"), Node: maotesting.NewNode("worker", false)}
+	master0 := newControlPlaneTarget("master-0")
+	master1 := newControlPlaneTarget("master-1")
+
+	testCases := []struct {
+		name              string
+		objects           []runtime.Object
+		targets           []target
+		expectedAllowed   []target
+		expectedBlocked   bool
+		expectedCondition *machinev1.Condition
+		expectedEvents    []string
+	}{
+		{
+			name:            "without control plane machines",
+			objects:         []runtime.Object{newEtcdGuardPDB(2, 2), newEtcdGuardPod("worker", true)},
+			targets:         []target{worker},
+			expectedAllowed: []target{worker},
+		},
+		{
+			name:            "without etcd guard PodDisruptionBudget",
+			targets:         []target{worker, master0},
+			expectedAllowed: []target{worker, master0},
+		},
+		{
+			name:              "with quorum kept",
+			objects:           []runtime.Object{newEtcdGuardPDB(3, 2), newEtcdGuardPod("master-0", true), newEtcdGuardPod("master-1", true), newEtcdGuardPod("master-2", true)},
+			targets:           []target{worker, master0},
+			expectedAllowed:   []target{worker, master0},
+			expectedCondition: &machinev1.Condition{Type: RemediationBlockedByQuorumCondition, Status: corev1.ConditionFalse},
+		},
+		{
+			name:              "with unhealthy etcd member",
+			objects:           []runtime.Object{newEtcdGuardPDB(2, 2), newEtcdGuardPod("master-0", false), newEtcdGuardPod("master-1", true), newEtcdGuardPod("master-2", true)},
+			targets:           []target{master0},
+			expectedAllowed:   []target{master0},
+			expectedCondition: &machinev1.Condition{Type: RemediationBlockedByQuorumCondition, Status: corev1.ConditionFalse},
+		},
+		{
+			name:            "with quorum broken",
+			objects:         []runtime.Object{newEtcdGuardPDB(2, 2), newEtcdGuardPod("master-0", true), newEtcdGuardPod("master-1", true), newEtcdGuardPod("master-2", false)},
+			targets:         []target{worker, master0},
+			expectedAllowed: []target{worker},
+			expectedBlocked: true,
+			expectedCondition: &machinev1.Condition{
+				Type:     RemediationBlockedByQuorumCondition,
+				Status:   corev1.ConditionTrue,
+				Severity: machinev1.ConditionSeverityWarning,
+				Reason:   EtcdQuorumAtRiskReason,
+				Message:  "Remediation is blocked for control plane machines whose removal would break the etcd quorum: master-0",
+			},
+			expectedEvents: []string{EventRemediationBlockedByQuorum},
+		},
+		{
+			name:            "with quorum broken by the second machine",
+			objects:         []runtime.Object{newEtcdGuardPDB(3, 2), newEtcdGuardPod("master-0", true), newEtcdGuardPod("master-1", true), newEtcdGuardPod("master-2", true)},
+			targets:         []target{master0, master1},
+			expectedAllowed: []target{master0},
+			expectedBlocked: true,
+			expectedCondition: &machinev1.Condition{
+				Type:     RemediationBlockedByQuorumCondition,
+				Status:   corev1.ConditionTrue,
+				Severity: machinev1.ConditionSeverityWarning,
+				Reason:   EtcdQuorumAtRiskReason,
+				Message:  "Remediation is blocked for control plane machines whose removal would break the etcd quorum: master-1",
+			},
+			expectedEvents: []string{EventRemediationBlockedByQuorum},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			recorder := record.NewFakeRecorder(10)
+			r := newFakeReconcilerWithCustomRecorder(recorder, tc.objects...)
+			mhc := maotesting.NewMachineHealthCheck("mhc")
+
+			allowed, blocked, err := r.gateControlPlaneRemediations(context.TODO(), mhc, tc.targets)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(allowed).To(Equal(tc.expectedAllowed))
+			g.Expect(blocked).To(Equal(tc.expectedBlocked))
+			assertEvents(t, tc.name, tc.expectedEvents, recorder.Events)
+
+			if tc.expectedCondition == nil {
+				g.Expect(conditions.Get(mhc, RemediationBlockedByQuorumCondition)).To(BeNil())
+				return
+			}
+			g.Expect(*conditions.Get(mhc, RemediationBlockedByQuorumCondition)).To(conditions.MatchCondition(*tc.expectedCondition))
+		})
+	}
+}
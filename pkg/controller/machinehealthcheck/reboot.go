@@ -0,0 +1,165 @@
+package machinehealthcheck
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// remediationStrategyReboot remediates unhealthy machines by requesting their provider to restart them,
+	// before falling back to deleting them when the reboots did not restore their health.
+	// It is enabled by setting the RemediationStrategyAnnotation of the MachineHealthCheck to "Reboot".
+	remediationStrategyReboot = machinev1.RemediationStrategyType("Reboot")
+
+	// MaxRebootsAnnotation sets the number of reboots after which the Reboot remediation strategy
+	// falls back to deleting the machine. Defaults to defaultMaxReboots.
+	MaxRebootsAnnotation = "machine.openshift.io/max-reboots"
+	defaultMaxReboots    = 3
+
+	// RebootCountAnnotation is the number of consecutive reboots requested by the MachineHealthCheck controller.
+	// It is removed once the machine is healthy again.
+	RebootCountAnnotation = "machine.openshift.io/reboot-count"
+	// LastRebootAnnotation is the time of the last reboot requested by the MachineHealthCheck controller.
+	// The timeouts of the unhealthy conditions start over from it, to give the node time to recover.
+	LastRebootAnnotation = "machine.openshift.io/last-reboot"
+
+	// EventRebootRequested is emitted when the reboot of an unhealthy machine was requested
+	EventRebootRequested string = "RebootRequested"
+	// EventRebootRequestFailed is emitted in case requesting the reboot of an unhealthy machine failed
+	EventRebootRequestFailed string = "RebootRequestFailed"
+)
+
+// getMaxReboots returns the number of reboots after which the machines of the MachineHealthCheck are deleted.
+func getMaxReboots(mhc *machinev1.MachineHealthCheck) int {
+	value, ok := mhc.Annotations[MaxRebootsAnnotation]
+	if !ok {
+		return defaultMaxReboots
+	}
+	maxReboots, err := parseMaxReboots(value)
+	if err != nil {
+		klog.Warningf("%s/%s: %v, using %d", mhc.Namespace, mhc.Name, err, defaultMaxReboots)
+		return defaultMaxReboots
+	}
+	return maxReboots
+}
+
+// ValidateMaxReboots returns an error when the value of the MaxRebootsAnnotation is invalid.
+func ValidateMaxReboots(value string) error {
+	_, err := parseMaxReboots(value)
+	return err
+}
+
+// parseMaxReboots parses the value of the MaxRebootsAnnotation.
+func parseMaxReboots(value string) (int, error) {
+	maxReboots, err := strconv.Atoi(value)
+	if err != nil || maxReboots < 0 {
+		return 0, fmt.Errorf("invalid %s annotation %q: must be a non-negative integer", MaxRebootsAnnotation, value)
+	}
+	return maxReboots, nil
+}
+
+// ValidateRemediationStrategy returns an error when the value of the RemediationStrategyAnnotation is not
+// a known remediation strategy.
+func ValidateRemediationStrategy(value string) error {
+	switch machinev1.RemediationStrategyType(value) {
+	case remediationStrategyExternal, remediationStrategyReboot:
+		return nil
+	}
+	return fmt.Errorf("invalid %s annotation %q: must be %q or %q", RemediationStrategyAnnotation, value, remediationStrategyExternal, remediationStrategyReboot)
+}
+
+// rebootCount returns the number of consecutive reboots requested for the machine.
+func rebootCount(machine *machinev1.Machine) int {
+	count, err := strconv.Atoi(machine.Annotations[RebootCountAnnotation])
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
+// lastReboot returns the time of the last reboot requested for the machine, or the zero time if there was none.
+func lastReboot(machine *machinev1.Machine) time.Time {
+	last, err := time.Parse(time.RFC3339, machine.Annotations[LastRebootAnnotation])
+	if err != nil {
+		return time.Time{}
+	}
+	return last
+}
+
+// shouldReboot returns true if the target should be remediated by rebooting it rather than deleting it.
+// Machines without a node, whose previous reboots did not restore their health, or which the provider
+// failed to reboot, are deleted.
+func (t *target) shouldReboot() bool {
+	if t.Node == nil || t.Node.UID == "" {
+		return false
+	}
+	if c := conditions.Get(&t.Machine, machinecontroller.InstanceRestartedCondition); c != nil && c.Reason == machinecontroller.RestartFailedReason {
+		klog.Infof("%s: the machine could not be rebooted: %s, falling back to deletion", t.string(), c.Message)
+		return false
+	}
+	if count, maxReboots := rebootCount(&t.Machine), getMaxReboots(&t.MHC); count >= maxReboots {
+		klog.Infof("%s: %d reboots did not restore the machine, falling back to deletion", t.string(), count)
+		return false
+	}
+	return true
+}
+
+// remediationStrategyReboot requests the Machine controller to restart the instance of the target through
+// the provider with the MachineRestartRequestedAnnotation, it returns true when the reboot was requested.
+func (t *target) remediationStrategyReboot(r *ReconcileMachineHealthCheck) (bool, error) {
+	if t.Machine.Annotations == nil {
+		t.Machine.Annotations = map[string]string{}
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	count := rebootCount(&t.Machine) + 1
+	klog.Infof("%s: requesting reboot %d", t.string(), count)
+	t.Machine.Annotations[machinecontroller.MachineRestartRequestedAnnotation] = fmt.Sprintf("MachineHealthCheck %s: reboot %d", t.MHC.Name, count)
+	t.Machine.Annotations[LastRebootAnnotation] = now
+	t.Machine.Annotations[RebootCountAnnotation] = strconv.Itoa(count)
+	if err := r.client.Update(context.TODO(), &t.Machine); err != nil {
+		r.recorder.Eventf(
+			&t.Machine,
+			corev1.EventTypeWarning,
+			EventRebootRequestFailed,
+			"Requesting reboot of machine %v failed: %v",
+			t.string(),
+			err,
+		)
+		return true, fmt.Errorf("%s: failed to request reboot: %v", t.string(), err)
+	}
+	r.recorder.Eventf(
+		&t.Machine,
+		corev1.EventTypeNormal,
+		EventRebootRequested,
+		"Requesting reboot %d of machine %v",
+		count,
+		t.string(),
+	)
+	return true, nil
+}
+
+// cleanRebootState removes the reboot count of healthy machines, their reboots restored their health.
+func (r *ReconcileMachineHealthCheck) cleanRebootState(ctx context.Context, currentHealthy []target) {
+	for _, t := range currentHealthy {
+		if _, ok := t.Machine.Annotations[RebootCountAnnotation]; !ok {
+			continue
+		}
+
+		base := client.MergeFrom(t.Machine.DeepCopy())
+		delete(t.Machine.Annotations, RebootCountAnnotation)
+		delete(t.Machine.Annotations, LastRebootAnnotation)
+		if err := r.client.Patch(ctx, &t.Machine, base); err != nil {
+			klog.Errorf("%s: failed to remove reboot state: %v", t.string(), err)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package machinehealthcheck
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+	maotesting "github.com/openshift/machine-api-operator/pkg/util/testing"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestRemediationStrategyReboot(t *testing.T) {
+	testCases := []struct {
+		name           string
+		rebootCount    *int
+		maxReboots     *string
+		withoutNode    bool
+		restartFailed  bool
+		expectedReboot bool
+		expectedEvents []string
+	}{
+		{
+			name:           "first reboot",
+			expectedReboot: true,
+			expectedEvents: []string{EventRebootRequested},
+		},
+		{
+			name:           "reboot after failed reboots",
+			rebootCount:    pointer.Int(2),
+			expectedReboot: true,
+			expectedEvents: []string{EventRebootRequested},
+		},
+		{
+			name:           "deletion after too many failed reboots",
+			rebootCount:    pointer.Int(3),
+			expectedEvents: []string{EventMachineDeleted},
+		},
+		{
+			name:           "deletion after too many failed reboots with max reboots",
+			rebootCount:    pointer.Int(1),
+			maxReboots:     pointer.String("1"),
+			expectedEvents: []string{EventMachineDeleted},
+		},
+		{
+			name:           "reboot with invalid max reboots",
+			rebootCount:    pointer.Int(1),
+			maxReboots:     pointer.String("one"),
+			expectedReboot: true,
+			expectedEvents: []string{EventRebootRequested},
+		},
+		{
+			name:           "deletion after a failed restart",
+			rebootCount:    pointer.Int(1),
+			restartFailed:  true,
+			expectedEvents: []string{EventMachineDeleted},
+		},
+		{
+			name:           "deletion without node",
+			withoutNode:    true,
+			expectedEvents: []string{EventMachineDeleted},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mhc := maotesting.NewMachineHealthCheck("mhc")
+			mhc.Annotations = map[string]string{RemediationStrategyAnnotation: string(remediationStrategyReboot)}
+			if tc.maxReboots != nil {
+				mhc.Annotations[MaxRebootsAnnotation] = *tc.maxReboots
+			}
+			node := maotesting.NewNode("node", false)
+			machine := maotesting.NewMachine("machine", node.Name)
+			if tc.rebootCount != nil {
+				machine.Annotations[RebootCountAnnotation] = strconv.Itoa(*tc.rebootCount)
+			}
+			if tc.restartFailed {
+				conditions.MarkFalse(machine, machinecontroller.InstanceRestartedCondition, machinecontroller.RestartFailedReason,
+					machinev1.ConditionSeverityWarning, "Restarting machines is not supported on this platform")
+			}
+			target := target{Machine: *machine, Node: node, MHC: *mhc}
+			if tc.withoutNode {
+				target.Node = nil
+			}
+
+			recorder := record.NewFakeRecorder(2)
+			r := newFakeReconcilerWithCustomRecorder(recorder, machine, node)
+			requested, err := r.internalRemediation(target)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(requested).To(BeTrue())
+			assertEvents(t, tc.name, tc.expectedEvents, recorder.Events)
+
+			got := &machinev1.Machine{}
+			err = r.client.Get(context.TODO(), client.ObjectKeyFromObject(machine), got)
+			if !tc.expectedReboot {
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), "expected machine to be deleted, got: %v", err)
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got.Annotations).To(HaveKeyWithValue(machinecontroller.MachineRestartRequestedAnnotation,
+				fmt.Sprintf("MachineHealthCheck mhc: reboot %d", derefInt(tc.rebootCount)+1)))
+			g.Expect(got.Annotations).To(HaveKey(LastRebootAnnotation))
+			g.Expect(rebootCount(got)).To(Equal(derefInt(tc.rebootCount) + 1))
+		})
+	}
+}
+
+type rebootActuator struct {
+	*machinecontroller.TestActuator
+	reboots int
+}
+
+func (a *rebootActuator) Reboot(context.Context, *machinev1.Machine) error {
+	a.reboots++
+	return nil
+}
+
+func TestRemediationStrategyRebootRestartsMachine(t *testing.T) {
+	g := NewWithT(t)
+
+	mhc := maotesting.NewMachineHealthCheck("mhc")
+	mhc.Annotations = map[string]string{RemediationStrategyAnnotation: string(remediationStrategyReboot)}
+	node := maotesting.NewNode("node", false)
+	machine := maotesting.NewMachine("machine", node.Name)
+	machine.Finalizers = []string{machinev1.MachineFinalizer}
+	machine.Labels[machinev1.MachineClusterIDLabel] = "cluster"
+	machine.Spec.ProviderSpec.Value = &runtime.RawExtension{Raw: []byte("{}")}
+	machine.Status.Phase = pointer.String(machinev1.PhaseRunning)
+	r := newFakeReconcilerWithCustomRecorder(record.NewFakeRecorder(1), mhc, machine, node)
+
+	requested, err := r.internalRemediation(target{Machine: *machine, Node: node, MHC: *mhc})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(requested).To(BeTrue())
+
+	// The Machine controller handles the request through the actuator of the platform.
+	actuator := &rebootActuator{TestActuator: &machinecontroller.TestActuator{ExistsValue: true}}
+	machineReconciler := machinecontroller.NewTestReconciler(r.client, actuator)
+	_, err = machineReconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(machine)})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(actuator.reboots).To(Equal(1))
+
+	got := &machinev1.Machine{}
+	g.Expect(r.client.Get(context.TODO(), client.ObjectKeyFromObject(machine), got)).To(Succeed())
+	g.Expect(got.Annotations).ToNot(HaveKey(machinecontroller.MachineRestartRequestedAnnotation))
+	g.Expect(rebootCount(got)).To(Equal(1))
+	condition := conditions.Get(got, machinecontroller.InstanceRestartedCondition)
+	g.Expect(condition).ToNot(BeNil())
+	g.Expect(condition.Reason).To(Equal(machinecontroller.RestartSucceededReason))
+}
+
+func TestNeedsRemediationAfterReboot(t *testing.T) {
+	g := NewWithT(t)
+
+	mhc := maotesting.NewMachineHealthCheck("mhc")
+	node := maotesting.NewNode("node", false)
+	machine := maotesting.NewMachine("machine", node.Name)
+	machine.Annotations[LastRebootAnnotation] = time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	target := target{Machine: *machine, Node: node, MHC: *mhc}
+
+	// The unhealthy condition timeout of 300s starts over from the reboot.
+	needsRemediation, nextCheck, err := target.needsRemediation(defaultNodeStartupTimeout)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(needsRemediation).To(BeFalse())
+	g.Expect(nextCheck).To(BeNumerically("~", 4*time.Minute+time.Second, 5*time.Second))
+
+	target.Machine.Annotations[LastRebootAnnotation] = time.Now().Add(-10 * time.Minute).UTC().Format(time.RFC3339)
+	needsRemediation, _, err = target.needsRemediation(defaultNodeStartupTimeout)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(needsRemediation).To(BeTrue())
+}
+
+func TestCleanRebootState(t *testing.T) {
+	g := NewWithT(t)
+
+	node := maotesting.NewNode("node", true)
+	machine := maotesting.NewMachine("machine", node.Name)
+	machine.Annotations[RebootCountAnnotation] = "2"
+	machine.Annotations[LastRebootAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	machine.Annotations["foo"] = "bar"
+	r := newFakeReconciler(machine, node)
+
+	r.cleanRebootState(context.TODO(), []target{{Machine: *machine, Node: node}})
+
+	got := &machinev1.Machine{}
+	g.Expect(r.client.Get(context.TODO(), client.ObjectKeyFromObject(machine), got)).To(Succeed())
+	g.Expect(got.Annotations).To(Equal(map[string]string{"foo": "bar"}))
+}
+
+func TestValidateRebootAnnotations(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(ValidateMaxReboots("0")).To(Succeed())
+	g.Expect(ValidateMaxReboots("-1")).To(MatchError(ContainSubstring("must be a non-negative integer")))
+	g.Expect(ValidateMaxReboots("one")).To(MatchError(ContainSubstring("must be a non-negative integer")))
+
+	g.Expect(ValidateRemediationStrategy("Reboot")).To(Succeed())
+	g.Expect(ValidateRemediationStrategy("external-baremetal")).To(Succeed())
+	g.Expect(ValidateRemediationStrategy("reboot")).To(MatchError(ContainSubstring(`must be "external-baremetal" or "Reboot"`)))
+}
@@ -0,0 +1,173 @@
+package machinehealthcheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// RemediationThrottledCondition is true when the remediation of unhealthy machines is delayed
+	// because the machines replacing them keep failing the health check.
+	RemediationThrottledCondition machinev1.ConditionType = "RemediationThrottled"
+
+	// RepeatedRemediationReason is used when remediation is delayed because the same machine slot was repeatedly remediated.
+	RepeatedRemediationReason = "RepeatedRemediation"
+
+	// EventRemediationThrottled is emitted when the remediation of an unhealthy machine
+	// is delayed because the same machine slot was repeatedly remediated
+	EventRemediationThrottled string = "RemediationThrottled"
+
+	// initialRemediationBackoff is the delay before remediating the first replacement of a remediated machine.
+	initialRemediationBackoff = time.Minute
+	// maxRemediationBackoff caps the delay between repeated remediations of the same machine slot.
+	maxRemediationBackoff = time.Hour
+	// remediationBackoffReset is how long after its last remediation the backoff of a machine slot is forgotten.
+	// It must be longer than maxRemediationBackoff, or slots would be forgotten while being throttled.
+	remediationBackoffReset = 2 * maxRemediationBackoff
+)
+
+// remediationBackoff tracks the remediations of machine slots, to delay exponentially the
+// remediation of machines replacing remediated ones.
+// A machine slot is the controller of the machines, usually a MachineSet: when a MachineSet keeps
+// creating machines failing the same health check, remediating each of them immediately would
+// result in a delete/create hot loop.
+// The zero value is ready to use.
+type remediationBackoff struct {
+	lock  sync.Mutex
+	slots map[string]*slotRemediations
+}
+
+// slotRemediations is the remediation state of a machine slot.
+type slotRemediations struct {
+	// count is the number of consecutive remediations of the slot.
+	count int
+	// last is when the slot was last remediated.
+	last time.Time
+}
+
+// remediationSlot returns the machine slot of the target, machines without a controller do not have one.
+func remediationSlot(t target) (string, bool) {
+	owner := metav1.GetControllerOf(&t.Machine)
+	if owner == nil {
+		return "", false
+	}
+	return fmt.Sprintf("%s/%s/%s", t.Machine.Namespace, owner.Kind, owner.Name), true
+}
+
+// isRepeated returns true if the remediation of the target repeats the last remediation of its slot,
+// i.e. the machine was created to replace the machine last remediated.
+func (s *slotRemediations) isRepeated(t target, now time.Time) bool {
+	return now.Sub(s.last) < remediationBackoffReset && t.Machine.CreationTimestamp.Time.After(s.last)
+}
+
+// backoff returns the delay between the last remediation of the slot and the next one.
+func (s *slotRemediations) backoff() time.Duration {
+	backoff := initialRemediationBackoff
+	for i := 1; i < s.count && backoff < maxRemediationBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRemediationBackoff {
+		return maxRemediationBackoff
+	}
+	return backoff
+}
+
+// delay returns how long the remediation of the target must still be delayed, or 0 if it can be remediated now.
+func (b *remediationBackoff) delay(t target, now time.Time) time.Duration {
+	slot, ok := remediationSlot(t)
+	if !ok {
+		return 0
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	s, ok := b.slots[slot]
+	if !ok || !s.isRepeated(t, now) {
+		return 0
+	}
+	if remaining := s.last.Add(s.backoff()).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// observe records the remediation of the target.
+func (b *remediationBackoff) observe(t target, now time.Time) {
+	slot, ok := remediationSlot(t)
+	if !ok {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.slots == nil {
+		b.slots = map[string]*slotRemediations{}
+	}
+	for key, s := range b.slots {
+		if now.Sub(s.last) >= remediationBackoffReset {
+			delete(b.slots, key)
+		}
+	}
+
+	s, ok := b.slots[slot]
+	if !ok || !s.isRepeated(t, now) {
+		s = &slotRemediations{}
+		b.slots[slot] = s
+	}
+	s.count++
+	s.last = now
+}
+
+// throttleRemediations returns the targets which can be remediated now, along with how long the
+// remediation of the other ones is delayed. The RemediationThrottled condition of the
+// MachineHealthCheck is updated accordingly.
+func (r *ReconcileMachineHealthCheck) throttleRemediations(mhc *machinev1.MachineHealthCheck, targets []target, now time.Time) ([]target, []time.Duration) {
+	var allowed []target
+	var delays []time.Duration
+	var throttled []string
+	for _, t := range targets {
+		delay := r.backoff.delay(t, now)
+		if delay == 0 {
+			allowed = append(allowed, t)
+			continue
+		}
+
+		klog.V(3).Infof("Reconciling %s: machine slot was repeatedly remediated, delaying remediation for %v", t.string(), delay)
+		r.recorder.Eventf(
+			&t.Machine,
+			corev1.EventTypeWarning,
+			EventRemediationThrottled,
+			"Remediation of machine %v delayed for %v, the machine it replaces was remediated recently",
+			t.string(),
+			delay.Round(time.Second),
+		)
+		delays = append(delays, delay)
+		throttled = append(throttled, t.Machine.Name)
+	}
+
+	if len(throttled) > 0 {
+		sort.Strings(throttled)
+		conditions.Set(mhc, &machinev1.Condition{
+			Type:     RemediationThrottledCondition,
+			Status:   corev1.ConditionTrue,
+			Severity: machinev1.ConditionSeverityWarning,
+			Reason:   RepeatedRemediationReason,
+			Message: fmt.Sprintf("Remediation is delayed for machines replacing recently remediated machines: %s",
+				strings.Join(throttled, ", ")),
+		})
+	} else if conditions.Get(mhc, RemediationThrottledCondition) != nil {
+		conditions.Set(mhc, &machinev1.Condition{Type: RemediationThrottledCondition, Status: corev1.ConditionFalse})
+	}
+	return allowed, delays
+}
@@ -0,0 +1,97 @@
+package machinehealthcheck
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+	maotesting "github.com/openshift/machine-api-operator/pkg/util/testing"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func newTargetCreatedAt(name string, created time.Time) target {
+	machine := maotesting.NewMachine(name, "")
+	machine.OwnerReferences[0].Name = "machineset"
+	machine.CreationTimestamp = metav1.NewTime(created)
+	return target{Machine: *machine, MHC: *maotesting.NewMachineHealthCheck("mhc")}
+}
+
+func TestRemediationBackoff(t *testing.T) {
+	g := NewWithT(t)
+
+	b := &remediationBackoff{}
+	now := time.Date(2023, time.March, 15, 14, 30, 0, 0, time.UTC)
+
+	first := newTargetCreatedAt("machine-0", now.Add(-24*time.Hour))
+	g.Expect(b.delay(first, now)).To(BeZero())
+	b.observe(first, now)
+
+	// Machines of the slot created before the remediation are not delayed.
+	g.Expect(b.delay(newTargetCreatedAt("machine-old", now.Add(-time.Hour)), now)).To(BeZero())
+
+	// Each replacement is delayed twice as long as the previous one.
+	expectedBackoffs := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 16 * time.Minute, 32 * time.Minute, time.Hour, time.Hour}
+	for i, expected := range expectedBackoffs {
+		replacement := newTargetCreatedAt("machine-replacement", now.Add(time.Second))
+		g.Expect(b.delay(replacement, now.Add(time.Second))).To(Equal(expected-time.Second), "replacement %d", i)
+
+		now = now.Add(expected)
+		g.Expect(b.delay(replacement, now)).To(BeZero(), "replacement %d", i)
+		b.observe(replacement, now)
+	}
+
+	// Machines of other slots are not delayed.
+	other := newTargetCreatedAt("machine-other", now.Add(time.Second))
+	other.Machine.OwnerReferences[0].Name = "other-machineset"
+	g.Expect(b.delay(other, now.Add(time.Second))).To(BeZero())
+
+	// Machines without a controller are not delayed.
+	orphan := newTargetCreatedAt("machine-orphan", now.Add(time.Second))
+	orphan.Machine.OwnerReferences = nil
+	g.Expect(b.delay(orphan, now.Add(time.Second))).To(BeZero())
+
+	// The backoff is forgotten once the slot was not remediated for a while.
+	later := now.Add(remediationBackoffReset)
+	replacement := newTargetCreatedAt("machine-replacement", now.Add(time.Second))
+	g.Expect(b.delay(replacement, later)).To(BeZero())
+	b.observe(replacement, later)
+	g.Expect(b.delay(newTargetCreatedAt("machine-replacement", later.Add(time.Second)), later.Add(time.Second))).To(Equal(time.Minute - time.Second))
+}
+
+func TestThrottleRemediations(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Date(2023, time.March, 15, 14, 30, 0, 0, time.UTC)
+	recorder := record.NewFakeRecorder(10)
+	r := newFakeReconcilerWithCustomRecorder(recorder)
+	remediated := newTargetCreatedAt("machine-0", now.Add(-24*time.Hour))
+	r.backoff.observe(remediated, now)
+
+	mhc := maotesting.NewMachineHealthCheck("mhc")
+	replacement := newTargetCreatedAt("machine-1", now.Add(time.Second))
+	unrelated := newTargetCreatedAt("machine-2", now.Add(-24*time.Hour))
+
+	allowed, delays := r.throttleRemediations(mhc, []target{replacement, unrelated}, now.Add(time.Second))
+	g.Expect(allowed).To(Equal([]target{unrelated}))
+	g.Expect(delays).To(Equal([]time.Duration{time.Minute - time.Second}))
+	assertEvents(t, "throttled", []string{EventRemediationThrottled}, recorder.Events)
+	g.Expect(*conditions.Get(mhc, RemediationThrottledCondition)).To(conditions.MatchCondition(machinev1.Condition{
+		Type:     RemediationThrottledCondition,
+		Status:   corev1.ConditionTrue,
+		Severity: machinev1.ConditionSeverityWarning,
+		Reason:   RepeatedRemediationReason,
+		Message:  "Remediation is delayed for machines replacing recently remediated machines: machine-1",
+	}))
+
+	allowed, delays = r.throttleRemediations(mhc, []target{replacement, unrelated}, now.Add(time.Minute))
+	g.Expect(allowed).To(Equal([]target{replacement, unrelated}))
+	g.Expect(delays).To(BeEmpty())
+	g.Expect(*conditions.Get(mhc, RemediationThrottledCondition)).To(conditions.MatchCondition(machinev1.Condition{
+		Type:   RemediationThrottledCondition,
+		Status: corev1.ConditionFalse,
+	}))
+}
@@ -0,0 +1,164 @@
+package machinehealthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// RemediationHistoryAnnotation records the remediations triggered by a MachineHealthCheck.
+	// The MachineHealthCheck status does not have a field for them, so the controller keeps them
+	// in this annotation as JSON: the total counters, along with the most recent remediations.
+	RemediationHistoryAnnotation = "machine.openshift.io/remediation-history"
+
+	// maxRecentRemediations is the number of recent remediations kept in the history.
+	maxRecentRemediations = 10
+
+	// RemediationSucceeded is the outcome of a remediation that was successfully requested.
+	RemediationSucceeded RemediationOutcome = "Succeeded"
+	// RemediationFailed is the outcome of a remediation that could not be requested.
+	RemediationFailed RemediationOutcome = "Failed"
+
+	// MachineFailedReason is recorded when the Machine was remediated because it is in the Failed phase.
+	MachineFailedReason = "MachineFailed"
+	// NodeStartupTimeoutReason is recorded when the Machine was remediated because it has no Node after the nodeStartupTimeout.
+	NodeStartupTimeoutReason = "NodeStartupTimeout"
+	// NodeNotFoundReason is recorded when the Machine was remediated because its Node no longer exists.
+	NodeNotFoundReason = "NodeNotFound"
+	// UnhealthyNodeReason is recorded when the Machine was remediated because of an unhealthy condition of its Node.
+	UnhealthyNodeReason = "UnhealthyNode"
+)
+
+// RemediationOutcome is the outcome of a remediation.
+type RemediationOutcome string
+
+// RemediationHistory is the content of the RemediationHistoryAnnotation.
+type RemediationHistory struct {
+	// TotalRemediations is the number of remediations triggered by the MachineHealthCheck.
+	TotalRemediations int64 `json:"totalRemediations"`
+	// SucceededRemediations is the number of remediations that were successfully requested.
+	SucceededRemediations int64 `json:"succeededRemediations"`
+	// FailedRemediations is the number of remediations that could not be requested.
+	FailedRemediations int64 `json:"failedRemediations"`
+	// RecentRemediations are the most recent remediations, oldest first.
+	RecentRemediations []RemediationRecord `json:"recentRemediations,omitempty"`
+}
+
+// RemediationRecord describes a single remediation.
+type RemediationRecord struct {
+	// Machine is the name of the remediated Machine.
+	Machine string `json:"machine"`
+	// Reason is why the Machine was considered unhealthy.
+	Reason string `json:"reason"`
+	// Timestamp is when the remediation was triggered.
+	Timestamp metav1.Time `json:"timestamp"`
+	// Outcome is the outcome of the remediation.
+	Outcome RemediationOutcome `json:"outcome"`
+	// Message details why the remediation failed.
+	Message string `json:"message,omitempty"`
+}
+
+// getRemediationHistory returns the remediation history of the MachineHealthCheck.
+// A history that cannot be decoded is discarded, as it is only informative.
+func getRemediationHistory(mhc *machinev1.MachineHealthCheck) *RemediationHistory {
+	history := &RemediationHistory{}
+	value, ok := mhc.Annotations[RemediationHistoryAnnotation]
+	if !ok {
+		return history
+	}
+	if err := json.Unmarshal([]byte(value), history); err != nil {
+		klog.Warningf("%s/%s: discarding invalid %s annotation: %v", mhc.Namespace, mhc.Name, RemediationHistoryAnnotation, err)
+		return &RemediationHistory{}
+	}
+	return history
+}
+
+// add adds the record to the history, dropping the oldest records above maxRecentRemediations.
+func (h *RemediationHistory) add(record RemediationRecord) {
+	h.TotalRemediations++
+	switch record.Outcome {
+	case RemediationSucceeded:
+		h.SucceededRemediations++
+	case RemediationFailed:
+		h.FailedRemediations++
+	}
+
+	h.RecentRemediations = append(h.RecentRemediations, record)
+	if len(h.RecentRemediations) > maxRecentRemediations {
+		h.RecentRemediations = h.RecentRemediations[len(h.RecentRemediations)-maxRecentRemediations:]
+	}
+}
+
+// newRemediationRecord returns the record of the remediation of the target.
+func newRemediationRecord(t target, now time.Time, err error) RemediationRecord {
+	record := RemediationRecord{
+		Machine:   t.Machine.Name,
+		Reason:    t.unhealthyReason(),
+		Timestamp: metav1.NewTime(now),
+		Outcome:   RemediationSucceeded,
+	}
+	if err != nil {
+		record.Outcome = RemediationFailed
+		record.Message = err.Error()
+	}
+	return record
+}
+
+// unhealthyReason returns why the target needs remediation, following the checks of needsRemediation.
+func (t *target) unhealthyReason() string {
+	if derefStringPointer(t.Machine.Status.Phase) == machinev1.PhaseFailed {
+		return MachineFailedReason
+	}
+	if t.Node == nil {
+		return NodeStartupTimeoutReason
+	}
+	if t.Node.UID == "" {
+		return NodeNotFoundReason
+	}
+	for _, c := range t.MHC.Spec.UnhealthyConditions {
+		if nodeCondition := conditions.GetNodeCondition(t.Node, c.Type); nodeCondition != nil && nodeCondition.Status == c.Status {
+			return fmt.Sprintf("%s: %s=%s", UnhealthyNodeReason, c.Type, c.Status)
+		}
+	}
+	if unhealthyTaints, err := getUnhealthyTaints(&t.MHC); err == nil {
+		for _, u := range unhealthyTaints {
+			for _, taint := range t.Node.Spec.Taints {
+				if u.matches(taint) {
+					return fmt.Sprintf("%s: taint %s", UnhealthyNodeReason, taint.ToString())
+				}
+			}
+		}
+	}
+	return UnhealthyNodeReason
+}
+
+// recordRemediations adds the records to the remediation history of the MachineHealthCheck.
+func (r *ReconcileMachineHealthCheck) recordRemediations(ctx context.Context, mhc *machinev1.MachineHealthCheck, records []RemediationRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	history := getRemediationHistory(mhc)
+	for _, record := range records {
+		history.add(record)
+	}
+	value, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to encode remediation history: %v", err)
+	}
+
+	base := client.MergeFrom(mhc.DeepCopy())
+	if mhc.Annotations == nil {
+		mhc.Annotations = map[string]string{}
+	}
+	mhc.Annotations[RemediationHistoryAnnotation] = string(value)
+	return r.client.Patch(ctx, mhc, base)
+}
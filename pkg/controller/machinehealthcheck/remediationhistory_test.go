@@ -0,0 +1,154 @@
+package machinehealthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	maotesting "github.com/openshift/machine-api-operator/pkg/util/testing"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestRemediationHistoryAdd(t *testing.T) {
+	g := NewWithT(t)
+
+	history := &RemediationHistory{}
+	for i := 0; i < maxRecentRemediations+2; i++ {
+		outcome := RemediationSucceeded
+		if i%3 == 0 {
+			outcome = RemediationFailed
+		}
+		history.add(RemediationRecord{Machine: fmt.Sprintf("machine-%d", i), Outcome: outcome})
+	}
+
+	g.Expect(history.TotalRemediations).To(BeEquivalentTo(12))
+	g.Expect(history.SucceededRemediations).To(BeEquivalentTo(8))
+	g.Expect(history.FailedRemediations).To(BeEquivalentTo(4))
+	g.Expect(history.RecentRemediations).To(HaveLen(maxRecentRemediations))
+	g.Expect(history.RecentRemediations[0].Machine).To(Equal("machine-2"))
+	g.Expect(history.RecentRemediations[maxRecentRemediations-1].Machine).To(Equal("machine-11"))
+}
+
+func TestGetRemediationHistory(t *testing.T) {
+	testCases := []struct {
+		name       string
+		annotation *string
+		expected   *RemediationHistory
+	}{
+		{
+			name:     "without history",
+			expected: &RemediationHistory{},
+		},
+		{
+			name:       "with history",
+			annotation: pointer.String(`{"totalRemediations":2,"succeededRemediations":1,"failedRemediations":1,"recentRemediations":[{"machine":"machine-1","reason":"MachineFailed","timestamp":null,"outcome":"Succeeded"}]}`),
+			expected: &RemediationHistory{
+				TotalRemediations:     2,
+				SucceededRemediations: 1,
+				FailedRemediations:    1,
+				RecentRemediations: []RemediationRecord{
+					{Machine: "machine-1", Reason: MachineFailedReason, Outcome: RemediationSucceeded},
+				},
+			},
+		},
+		{
+			name:       "with invalid history",
+			annotation: pointer.String(`{"totalRemediations":"many"}`),
+			expected:   &RemediationHistory{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mhc := maotesting.NewMachineHealthCheck("mhc")
+			if tc.annotation != nil {
+				mhc.Annotations = map[string]string{RemediationHistoryAnnotation: *tc.annotation}
+			}
+			g.Expect(getRemediationHistory(mhc)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestUnhealthyReason(t *testing.T) {
+	mhc := maotesting.NewMachineHealthCheck("mhc")
+	failedMachine := maotesting.NewMachine("machine", "node")
+	failedMachine.Status.Phase = pointer.String(machinev1.PhaseFailed)
+	notReadyNode := maotesting.NewNode("node", false)
+
+	testCases := []struct {
+		name     string
+		target   target
+		expected string
+	}{
+		{
+			name:     "machine failed",
+			target:   target{Machine: *failedMachine, Node: notReadyNode, MHC: *mhc},
+			expected: MachineFailedReason,
+		},
+		{
+			name:     "machine without node",
+			target:   target{Machine: *maotesting.NewMachine("machine", ""), MHC: *mhc},
+			expected: NodeStartupTimeoutReason,
+		},
+		{
+			name:     "node not found",
+			target:   target{Machine: *maotesting.NewMachine("machine", "node"), Node: &corev1.Node{}, MHC: *mhc},
+			expected: NodeNotFoundReason,
+		},
+		{
+			name:     "unhealthy node condition",
+			target:   target{Machine: *maotesting.NewMachine("machine", "node"), Node: notReadyNode, MHC: *mhc},
+			expected: "UnhealthyNode: Ready=Unknown",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(tc.target.unhealthyReason()).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestRecordRemediations(t *testing.T) {
+	g := NewWithT(t)
+
+	mhc := maotesting.NewMachineHealthCheck("mhc")
+	mhc.Annotations = map[string]string{
+		RemediationHistoryAnnotation: `{"totalRemediations":1,"succeededRemediations":1,"failedRemediations":0}`,
+	}
+	machine := maotesting.NewMachine("machine", "")
+	r := newFakeReconciler(mhc, machine)
+
+	now := time.Date(2023, time.March, 15, 14, 30, 0, 0, time.Local)
+	records := []RemediationRecord{
+		newRemediationRecord(target{Machine: *machine, MHC: *mhc}, now, nil),
+		newRemediationRecord(target{Machine: *machine, MHC: *mhc}, now, errors.New("delete failed")),
+	}
+	g.Expect(r.recordRemediations(context.TODO(), mhc, records)).To(Succeed())
+
+	got := &machinev1.MachineHealthCheck{}
+	g.Expect(r.client.Get(context.TODO(), client.ObjectKeyFromObject(mhc), got)).To(Succeed())
+
+	history := &RemediationHistory{}
+	g.Expect(json.Unmarshal([]byte(got.Annotations[RemediationHistoryAnnotation]), history)).To(Succeed())
+	g.Expect(history).To(Equal(&RemediationHistory{
+		TotalRemediations:     3,
+		SucceededRemediations: 2,
+		FailedRemediations:    1,
+		RecentRemediations: []RemediationRecord{
+			{Machine: "machine", Reason: NodeStartupTimeoutReason, Timestamp: metav1.NewTime(now), Outcome: RemediationSucceeded},
+			{Machine: "machine", Reason: NodeStartupTimeoutReason, Timestamp: metav1.NewTime(now), Outcome: RemediationFailed, Message: "delete failed"},
+		},
+	}))
+}
@@ -0,0 +1,87 @@
+package machinehealthcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// UnhealthyTaintsAnnotation extends the unhealthyConditions of a MachineHealthCheck with node taints,
+// so that the taints set by node problem remedy systems, e.g. from node-problem-detector signals, can
+// drive remediation. The MachineHealthCheck API does not have a field for them, so they are set with
+// this annotation as a JSON list, e.g. [{"key": "KernelDeadlock", "effect": "NoExecute", "timeout": "5m"}].
+// A node is unhealthy when it has had a matching taint for at least the timeout. The time a taint was
+// added is only recorded for NoExecute taints, other taints match regardless of the timeout.
+const UnhealthyTaintsAnnotation = "machine.openshift.io/unhealthy-taints"
+
+// unhealthyTaint is a single entry of the UnhealthyTaintsAnnotation.
+type unhealthyTaint struct {
+	// Key is the key of the taint.
+	Key string `json:"key"`
+	// Value is the value of the taint, any value matches when empty.
+	Value string `json:"value,omitempty"`
+	// Effect is the effect of the taint, any effect matches when empty.
+	Effect corev1.TaintEffect `json:"effect,omitempty"`
+	// Timeout is how long the node must have had the taint to be unhealthy.
+	Timeout metav1.Duration `json:"timeout"`
+}
+
+// getUnhealthyTaints returns the unhealthy taints of the MachineHealthCheck, or nil when it has none.
+func getUnhealthyTaints(mhc *machinev1.MachineHealthCheck) ([]unhealthyTaint, error) {
+	value, ok := mhc.Annotations[UnhealthyTaintsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	taints, err := parseUnhealthyTaints(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", UnhealthyTaintsAnnotation, err)
+	}
+	return taints, nil
+}
+
+// ValidateUnhealthyTaints returns an error when the value of the UnhealthyTaintsAnnotation is invalid.
+func ValidateUnhealthyTaints(value string) error {
+	_, err := parseUnhealthyTaints(value)
+	return err
+}
+
+// parseUnhealthyTaints parses the value of the UnhealthyTaintsAnnotation.
+func parseUnhealthyTaints(value string) ([]unhealthyTaint, error) {
+	var taints []unhealthyTaint
+	if err := json.Unmarshal([]byte(value), &taints); err != nil {
+		return nil, err
+	}
+	for i, taint := range taints {
+		if taint.Key == "" {
+			return nil, fmt.Errorf("taint %d: key must be set", i)
+		}
+		if taint.Timeout.Duration < 0 {
+			return nil, fmt.Errorf("taint %d: timeout must not be negative", i)
+		}
+	}
+	return taints, nil
+}
+
+// matches returns true if the taint of the node matches the unhealthy taint.
+func (u unhealthyTaint) matches(taint corev1.Taint) bool {
+	if taint.Key != u.Key {
+		return false
+	}
+	if u.Value != "" && taint.Value != u.Value {
+		return false
+	}
+	return u.Effect == "" || taint.Effect == u.Effect
+}
+
+// taintedSince returns since when the node has had the taint, or the zero time when it is unknown.
+func taintedSince(taint corev1.Taint) time.Time {
+	if taint.TimeAdded == nil {
+		return time.Time{}
+	}
+	return taint.TimeAdded.Time
+}
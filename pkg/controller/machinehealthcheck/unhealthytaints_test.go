@@ -0,0 +1,150 @@
+package machinehealthcheck
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	maotesting "github.com/openshift/machine-api-operator/pkg/util/testing"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetUnhealthyTaints(t *testing.T) {
+	testCases := []struct {
+		name          string
+		annotation    *string
+		expected      []unhealthyTaint
+		expectedError string
+	}{
+		{
+			name: "without unhealthy taints",
+		},
+		{
+			name:       "with unhealthy taints",
+			annotation: stringPtr(`[{"key": "KernelDeadlock", "effect": "NoExecute", "timeout": "5m"}, {"key": "ReadonlyFilesystem", "value": "true", "timeout": "0s"}]`),
+			expected: []unhealthyTaint{
+				{Key: "KernelDeadlock", Effect: corev1.TaintEffectNoExecute, Timeout: metav1.Duration{Duration: 5 * time.Minute}},
+				{Key: "ReadonlyFilesystem", Value: "true"},
+			},
+		},
+		{
+			name:          "with invalid JSON",
+			annotation:    stringPtr(`KernelDeadlock`),
+			expectedError: "invalid machine.openshift.io/unhealthy-taints annotation: invalid character 'K' looking for beginning of value",
+		},
+		{
+			name:          "without key",
+			annotation:    stringPtr(`[{"effect": "NoExecute", "timeout": "5m"}]`),
+			expectedError: "invalid machine.openshift.io/unhealthy-taints annotation: taint 0: key must be set",
+		},
+		{
+			name:          "with negative timeout",
+			annotation:    stringPtr(`[{"key": "KernelDeadlock", "timeout": "-5m"}]`),
+			expectedError: "invalid machine.openshift.io/unhealthy-taints annotation: taint 0: timeout must not be negative",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mhc := maotesting.NewMachineHealthCheck("mhc")
+			if tc.annotation != nil {
+				mhc.Annotations = map[string]string{UnhealthyTaintsAnnotation: *tc.annotation}
+			}
+
+			taints, err := getUnhealthyTaints(mhc)
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(taints).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestNeedsRemediationWithProblemDetectorSignals(t *testing.T) {
+	now := time.Now()
+	unhealthyConditions := []machinev1.UnhealthyCondition{
+		{Type: "KernelDeadlock", Status: corev1.ConditionTrue, Timeout: metav1.Duration{Duration: 5 * time.Minute}},
+	}
+	unhealthyTaints := `[{"key": "ReadonlyFilesystem", "effect": "NoExecute", "timeout": "5m"}, {"key": "FrequentKubeletRestart", "timeout": "5m"}]`
+
+	testCases := []struct {
+		name              string
+		conditions        []corev1.NodeCondition
+		taints            []corev1.Taint
+		expectedUnhealthy bool
+		expectedNextCheck time.Duration
+	}{
+		{
+			name: "healthy node",
+		},
+		{
+			name:              "custom condition longer than timeout",
+			conditions:        []corev1.NodeCondition{{Type: "KernelDeadlock", Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(now.Add(-10 * time.Minute))}},
+			expectedUnhealthy: true,
+		},
+		{
+			name:              "custom condition shorter than timeout",
+			conditions:        []corev1.NodeCondition{{Type: "KernelDeadlock", Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(now.Add(-4 * time.Minute))}},
+			expectedNextCheck: time.Minute + time.Second,
+		},
+		{
+			name:              "taint longer than timeout",
+			taints:            []corev1.Taint{{Key: "ReadonlyFilesystem", Effect: corev1.TaintEffectNoExecute, TimeAdded: &metav1.Time{Time: now.Add(-10 * time.Minute)}}},
+			expectedUnhealthy: true,
+		},
+		{
+			name:              "taint shorter than timeout",
+			taints:            []corev1.Taint{{Key: "ReadonlyFilesystem", Effect: corev1.TaintEffectNoExecute, TimeAdded: &metav1.Time{Time: now.Add(-3 * time.Minute)}}},
+			expectedNextCheck: 2*time.Minute + time.Second,
+		},
+		{
+			name:   "taint with another effect",
+			taints: []corev1.Taint{{Key: "ReadonlyFilesystem", Effect: corev1.TaintEffectNoSchedule}},
+		},
+		{
+			name:              "taint without time added",
+			taints:            []corev1.Taint{{Key: "FrequentKubeletRestart", Effect: corev1.TaintEffectNoSchedule}},
+			expectedUnhealthy: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mhc := maotesting.NewMachineHealthCheck("mhc")
+			mhc.Spec.UnhealthyConditions = unhealthyConditions
+			mhc.Annotations = map[string]string{UnhealthyTaintsAnnotation: unhealthyTaints}
+			node := maotesting.NewNode("node", true)
+			node.Status.Conditions = append(node.Status.Conditions, tc.conditions...)
+			node.Spec.Taints = tc.taints
+			target := target{Machine: *maotesting.NewMachine("machine", node.Name), Node: node, MHC: *mhc}
+
+			unhealthy, nextCheck, err := target.needsRemediation(defaultNodeStartupTimeout)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(unhealthy).To(Equal(tc.expectedUnhealthy))
+			g.Expect(nextCheck).To(BeNumerically("~", tc.expectedNextCheck, 5*time.Second))
+		})
+	}
+}
+
+func TestNeedsRemediationWithInvalidUnhealthyTaints(t *testing.T) {
+	g := NewWithT(t)
+
+	mhc := maotesting.NewMachineHealthCheck("mhc")
+	mhc.Annotations = map[string]string{UnhealthyTaintsAnnotation: `[{"effect": "NoExecute"}]`}
+	node := maotesting.NewNode("node", true)
+	node.Spec.Taints = []corev1.Taint{{Key: "KernelDeadlock", Effect: corev1.TaintEffectNoExecute}}
+	target := target{Machine: *maotesting.NewMachine("machine", node.Name), Node: node, MHC: *mhc}
+
+	// Invalid unhealthy taints are ignored, the node is health checked with the unhealthy conditions.
+	unhealthy, _, err := target.needsRemediation(defaultNodeStartupTimeout)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(unhealthy).To(BeFalse())
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinev1applyconfigurations "github.com/openshift/client-go/machine/applyconfigurations/machine/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fieldManager is the field manager used by the MachineSet controller for server-side apply.
+// Applying only the fields owned by the controller avoids conflicts, and the retries they
+// cause, with the webhooks, the autoscaler and other tools updating the same objects.
+const fieldManager = "machineset-controller"
+
+// machineSetStatusApplyConfiguration returns the apply configuration for the status of the MachineSet.
+func machineSetStatusApplyConfiguration(ms *machinev1.MachineSet, newStatus machinev1.MachineSetStatus) *machinev1applyconfigurations.MachineSetApplyConfiguration {
+	status := machinev1applyconfigurations.MachineSetStatus().
+		WithReplicas(newStatus.Replicas).
+		WithFullyLabeledReplicas(newStatus.FullyLabeledReplicas).
+		WithReadyReplicas(newStatus.ReadyReplicas).
+		WithAvailableReplicas(newStatus.AvailableReplicas).
+		WithObservedGeneration(newStatus.ObservedGeneration)
+	if newStatus.ErrorReason != nil {
+		status.WithErrorReason(*newStatus.ErrorReason)
+	}
+	if newStatus.ErrorMessage != nil {
+		status.WithErrorMessage(*newStatus.ErrorMessage)
+	}
+
+	return machinev1applyconfigurations.MachineSet(ms.Name, ms.Namespace).WithStatus(status)
+}
+
+// applyMachineSetStatus applies the status of the MachineSet using server-side apply.
+// The controller is the only writer of the status, so ownership of the fields is forced.
+// On success, ms is updated with the MachineSet returned by the API server.
+func applyMachineSetStatus(c client.Client, ms *machinev1.MachineSet, newStatus machinev1.MachineSetStatus) error {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(machineSetStatusApplyConfiguration(ms, newStatus))
+	if err != nil {
+		return fmt.Errorf("failed to convert status apply configuration: %w", err)
+	}
+
+	u := &unstructured.Unstructured{Object: obj}
+	patchOptions := &client.SubResourcePatchOptions{
+		PatchOptions: client.PatchOptions{
+			FieldManager: fieldManager,
+			Force:        pointer.Bool(true),
+		},
+	}
+	if err := c.Status().Patch(context.Background(), u, client.Apply, patchOptions); err != nil {
+		return err
+	}
+
+	updated := &machinev1.MachineSet{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, updated); err != nil {
+		return fmt.Errorf("failed to convert applied MachineSet: %w", err)
+	}
+	*ms = *updated
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// applyClient emulates server-side apply on top of the fake client, which does not support
+// apply patches, by creating missing objects and merging the applied fields into existing ones.
+type applyClient struct {
+	client.Client
+}
+
+func (c applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+
+	existing := obj.DeepCopyObject().(client.Object)
+	if err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), existing); apierrors.IsNotFound(err) {
+		return c.Client.Create(ctx, obj)
+	} else if err != nil {
+		return err
+	}
+	return c.Client.Patch(ctx, obj, client.Merge)
+}
+
+func (c applyClient) Status() client.SubResourceWriter {
+	return applyStatusWriter{SubResourceWriter: c.Client.Status()}
+}
+
+type applyStatusWriter struct {
+	client.SubResourceWriter
+}
+
+func (w applyStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	if patch.Type() == types.ApplyPatchType {
+		patch = client.Merge
+	}
+	return w.SubResourceWriter.Patch(ctx, obj, patch)
+}
+
+func TestMachineSetStatusApplyConfiguration(t *testing.T) {
+	g := NewWithT(t)
+
+	reason := machinev1.InvalidConfigurationMachineSetError
+	ms := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "machineset1", Namespace: "default"},
+		Spec:       machinev1.MachineSetSpec{Replicas: pointer.Int32(3)},
+	}
+	status := machinev1.MachineSetStatus{
+		Replicas:           2,
+		ReadyReplicas:      1,
+		ObservedGeneration: 4,
+		ErrorReason:        &reason,
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(machineSetStatusApplyConfiguration(ms, status))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Only the identity of the MachineSet and the fields owned by the controller are applied.
+	g.Expect(obj).To(HaveLen(4))
+	g.Expect(obj).To(HaveKeyWithValue("apiVersion", "machine.openshift.io/v1beta1"))
+	g.Expect(obj).To(HaveKeyWithValue("kind", "MachineSet"))
+	g.Expect(obj).To(HaveKeyWithValue("metadata", map[string]interface{}{"name": "machineset1", "namespace": "default"}))
+	g.Expect(obj).To(HaveKeyWithValue("status", map[string]interface{}{
+		"replicas":             int64(2),
+		"fullyLabeledReplicas": int64(0),
+		"readyReplicas":        int64(1),
+		"availableReplicas":    int64(0),
+		"observedGeneration":   int64(4),
+		"errorReason":          string(reason),
+	}))
+}
+
+func TestApplyMachineSetStatus(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(machinev1.AddToScheme(scheme.Scheme)).To(Succeed())
+
+	ms := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "machineset1",
+			Namespace:   "default",
+			Annotations: map[string]string{"foo": "bar"},
+		},
+		Spec: machinev1.MachineSetSpec{Replicas: pointer.Int32(3)},
+	}
+	c := applyClient{fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ms).Build()}
+
+	g.Expect(applyMachineSetStatus(c, ms, machinev1.MachineSetStatus{Replicas: 2})).To(Succeed())
+	g.Expect(ms.Status.Replicas).To(BeEquivalentTo(2))
+	g.Expect(ms.Annotations).To(HaveKeyWithValue("foo", "bar"))
+
+	got := &machinev1.MachineSet{}
+	g.Expect(c.Get(context.Background(), client.ObjectKeyFromObject(ms), got)).To(Succeed())
+	g.Expect(got.Status.Replicas).To(BeEquivalentTo(2))
+	g.Expect(got.Spec.Replicas).To(Equal(pointer.Int32(3)))
+}
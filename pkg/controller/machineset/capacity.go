@@ -0,0 +1,70 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/capacity"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reconcileCapacityAnnotations keeps the scale-from-zero annotations of the MachineSet in sync
+// with the instance type in its providerSpec, so that the cluster autoscaler can scale the
+// MachineSet up from zero replicas.
+// When the capacity cannot be determined, any existing annotations are left untouched so that
+// values set by the user, or by a provider specific controller, are preserved.
+func (r *ReconcileMachineSet) reconcileCapacityAnnotations(ctx context.Context, machineSet *machinev1.MachineSet) error {
+	c, err := capacity.FromProviderSpec(machineSet.Spec.Template.Spec.ProviderSpec.Value)
+	if err != nil {
+		return fmt.Errorf("failed to determine capacity: %w", err)
+	}
+	if c == nil {
+		klog.V(4).Infof("%v: unable to determine capacity from providerSpec, skipping scale from zero annotations", machineSet.Name)
+		return nil
+	}
+
+	desired := c.Annotations()
+	changed := false
+	for k, v := range desired {
+		if machineSet.Annotations[k] != v {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	patchBase := client.MergeFrom(machineSet.DeepCopy())
+	if machineSet.Annotations == nil {
+		machineSet.Annotations = make(map[string]string)
+	}
+	for k, v := range desired {
+		machineSet.Annotations[k] = v
+	}
+
+	klog.V(4).Infof("%v: updating scale from zero annotations to %v", machineSet.Name, desired)
+	if err := r.Client.Patch(ctx, machineSet, patchBase); err != nil {
+		return fmt.Errorf("failed to update scale from zero annotations: %w", err)
+	}
+
+	return nil
+}
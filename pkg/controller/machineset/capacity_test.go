@@ -0,0 +1,115 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/capacity"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileCapacityAnnotations(t *testing.T) {
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+
+	testCases := []struct {
+		name                string
+		providerSpec        string
+		annotations         map[string]string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:         "sets the annotations for a known instance type",
+			providerSpec: `{"kind":"AWSMachineProviderConfig","instanceType":"m5.xlarge"}`,
+			expectedAnnotations: map[string]string{
+				capacity.CPUKey:    "4",
+				capacity.MemoryKey: "16384",
+				capacity.GPUKey:    "0",
+			},
+		},
+		{
+			name:         "updates stale annotations and preserves others",
+			providerSpec: `{"kind":"AWSMachineProviderConfig","instanceType":"g4dn.xlarge"}`,
+			annotations: map[string]string{
+				capacity.CPUKey:    "2",
+				capacity.MemoryKey: "8192",
+				"foo":              "bar",
+			},
+			expectedAnnotations: map[string]string{
+				capacity.CPUKey:    "4",
+				capacity.MemoryKey: "16384",
+				capacity.GPUKey:    "1",
+				"foo":              "bar",
+			},
+		},
+		{
+			name:         "leaves annotations untouched for an unknown instance type",
+			providerSpec: `{"kind":"AWSMachineProviderConfig","instanceType":"unknown.large"}`,
+			annotations: map[string]string{
+				capacity.CPUKey:    "2",
+				capacity.MemoryKey: "8192",
+			},
+			expectedAnnotations: map[string]string{
+				capacity.CPUKey:    "2",
+				capacity.MemoryKey: "8192",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "machineset1",
+					Namespace:   "default",
+					Annotations: tc.annotations,
+				},
+				Spec: machinev1.MachineSetSpec{
+					Template: machinev1.MachineTemplateSpec{
+						Spec: machinev1.MachineSpec{
+							ProviderSpec: machinev1.ProviderSpec{
+								Value: &runtime.RawExtension{Raw: []byte(tc.providerSpec)},
+							},
+						},
+					},
+				},
+			}
+
+			r := &ReconcileMachineSet{
+				Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(ms).Build(),
+				scheme: scheme.Scheme,
+			}
+
+			g.Expect(r.reconcileCapacityAnnotations(context.Background(), ms)).To(Succeed())
+
+			got := &machinev1.MachineSet{}
+			g.Expect(r.Get(context.Background(), client.ObjectKeyFromObject(ms), got)).To(Succeed())
+			g.Expect(got.Annotations).To(Equal(tc.expectedAnnotations))
+		})
+	}
+}
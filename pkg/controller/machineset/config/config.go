@@ -0,0 +1,320 @@
+// Package config loads the configuration file of the machineset controller, a versioned ComponentConfig
+// style alternative to its command line flags.
+package config
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/machine-api-operator/pkg/util"
+	"github.com/openshift/machine-api-operator/pkg/util/logging"
+)
+
+const (
+	// APIVersion is the version of the configuration.
+	APIVersion = "config.machine.openshift.io/v1alpha1"
+	// Kind is the kind of the configuration.
+	Kind = "MachineSetControllerConfiguration"
+
+	// verbosityFlag is the flag of the verbosity of klog.
+	verbosityFlag = "v"
+
+	defaultReloadInterval = 10 * time.Second
+)
+
+// MachineSetControllerConfiguration configures the machineset controller. Each field sets the value of a flag,
+// the flags set on the command line take precedence. The fields which are not set leave the flags to their default.
+type MachineSetControllerConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Cache filters the resources cached and reconciled by the controllers.
+	Cache CacheConfiguration `json:"cache,omitempty"`
+	// LeaderElection configures the leader election of the replicas.
+	LeaderElection LeaderElectionConfiguration `json:"leaderElection,omitempty"`
+	// Webhook configures the defaulting and validating webhooks.
+	Webhook WebhookConfiguration `json:"webhook,omitempty"`
+	// Controller configures the MachineSet controller.
+	Controller ControllerConfiguration `json:"controller,omitempty"`
+	// RateLimiter configures the rate limits of the requeues of the MachineSets and of the API requests.
+	RateLimiter RateLimiterConfiguration `json:"rateLimiter,omitempty"`
+	// CAPISync configures the mirroring of the Machine API resources into Cluster API.
+	CAPISync CAPISyncConfiguration `json:"capiSync,omitempty"`
+	// Metrics configures the metrics server.
+	Metrics MetricsConfiguration `json:"metrics,omitempty"`
+	// Health configures the health probes server.
+	Health HealthConfiguration `json:"health,omitempty"`
+	// Logging configures the logs. A change of the verbosity is applied while running.
+	Logging LoggingConfiguration `json:"logging,omitempty"`
+}
+
+// CacheConfiguration sets the -namespace and -cache selector flags.
+type CacheConfiguration struct {
+	// Namespace is a comma separated list of namespaces.
+	Namespace     *string `json:"namespace,omitempty"`
+	LabelSelector *string `json:"labelSelector,omitempty"`
+	// ObjectLabelSelectors and ObjectFieldSelectors are the selectors of the ConfigMaps, Nodes and Secrets, keyed by kind.
+	ObjectLabelSelectors map[string]string `json:"objectLabelSelectors,omitempty"`
+	ObjectFieldSelectors map[string]string `json:"objectFieldSelectors,omitempty"`
+	// FullSecrets caches the full Secrets rather than only their metadata.
+	FullSecrets *bool `json:"fullSecrets,omitempty"`
+}
+
+// LeaderElectionConfiguration sets the -leader-elect flags.
+type LeaderElectionConfiguration struct {
+	LeaderElect       *bool            `json:"leaderElect,omitempty"`
+	ResourceNamespace *string          `json:"resourceNamespace,omitempty"`
+	LeaseDuration     *metav1.Duration `json:"leaseDuration,omitempty"`
+	// PreferredNodeSelector and PreferAPIServerNodes prefer the candidates running on some nodes.
+	PreferredNodeSelector *string `json:"preferredNodeSelector,omitempty"`
+	PreferAPIServerNodes  *bool   `json:"preferAPIServerNodes,omitempty"`
+}
+
+// WebhookConfiguration sets the -webhook flags, and the flags of the admission of the webhooks.
+type WebhookConfiguration struct {
+	Enabled                                 *bool                              `json:"enabled,omitempty"`
+	Port                                    *int                               `json:"port,omitempty"`
+	CertDir                                 *string                            `json:"certDir,omitempty"`
+	SelfSigned                              *bool                              `json:"selfSigned,omitempty"`
+	RejectMissingSecrets                    *bool                              `json:"rejectMissingSecrets,omitempty"`
+	DryRunEstimates                         *bool                              `json:"dryRunEstimates,omitempty"`
+	MinControlPlaneMachines                 *int                               `json:"minControlPlaneMachines,omitempty"`
+	AWSDefaultMetadataServiceAuthentication *string                            `json:"awsDefaultMetadataServiceAuthentication,omitempty"`
+	VSphereDeepValidation                   VSphereDeepValidationConfiguration `json:"vsphereDeepValidation,omitempty"`
+}
+
+// VSphereDeepValidationConfiguration sets the -vsphere-deep-validation flags.
+type VSphereDeepValidationConfiguration struct {
+	Enabled *bool            `json:"enabled,omitempty"`
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// ControllerConfiguration sets the flags of the MachineSet controller.
+type ControllerConfiguration struct {
+	Enabled                    *bool            `json:"enabled,omitempty"`
+	MachineSetConcurrency      *int             `json:"machineSetConcurrency,omitempty"`
+	MachineCreationQPS         *float64         `json:"machineCreationQPS,omitempty"`
+	MachineCreationBurst       *int             `json:"machineCreationBurst,omitempty"`
+	MissingInstanceGracePeriod *metav1.Duration `json:"missingInstanceGracePeriod,omitempty"`
+	// SyncPeriod is the period of the full resyncs of the cache, MachineSetResyncPeriod overrides it for the MachineSets.
+	SyncPeriod             *metav1.Duration `json:"syncPeriod,omitempty"`
+	MachineSetResyncPeriod *metav1.Duration `json:"machineSetResyncPeriod,omitempty"`
+}
+
+// RateLimiterConfiguration sets the -rate-limiter and -kube-api flags.
+type RateLimiterConfiguration struct {
+	BaseDelay  *metav1.Duration `json:"baseDelay,omitempty"`
+	MaxDelay   *metav1.Duration `json:"maxDelay,omitempty"`
+	QPS        *float64         `json:"qps,omitempty"`
+	BucketSize *int             `json:"bucketSize,omitempty"`
+	APIQPS     *float64         `json:"apiQPS,omitempty"`
+	APIBurst   *int             `json:"apiBurst,omitempty"`
+}
+
+// CAPISyncConfiguration sets the -capi flags.
+type CAPISyncConfiguration struct {
+	Enabled   *bool   `json:"enabled,omitempty"`
+	Namespace *string `json:"namespace,omitempty"`
+}
+
+// MetricsConfiguration sets the -metrics-bind-address flag.
+type MetricsConfiguration struct {
+	BindAddress *string `json:"bindAddress,omitempty"`
+}
+
+// HealthConfiguration sets the -health-addr flag.
+type HealthConfiguration struct {
+	BindAddress *string `json:"bindAddress,omitempty"`
+}
+
+// LoggingConfiguration sets the -logging-format and -v flags.
+type LoggingConfiguration struct {
+	Format    *string `json:"format,omitempty"`
+	Verbosity *int    `json:"verbosity,omitempty"`
+}
+
+// Load reads the configuration file. Unknown fields are rejected, to catch the typos.
+func Load(path string) (*MachineSetControllerConfiguration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading configuration file: %v", err)
+	}
+	config := &MachineSetControllerConfiguration{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("error decoding configuration file %s: %v", path, err)
+	}
+	if config.APIVersion != APIVersion || config.Kind != Kind {
+		return nil, fmt.Errorf("invalid configuration file %s: expected apiVersion %s and kind %s, got %q and %q", path, APIVersion, Kind, config.APIVersion, config.Kind)
+	}
+	return config, nil
+}
+
+// FlagValues returns the values of the flags set by the configuration, keyed by the names of the flags.
+func (c *MachineSetControllerConfiguration) FlagValues() map[string]string {
+	values := map[string]string{}
+	setString(values, "namespace", c.Cache.Namespace)
+	setString(values, "cache-label-selector", c.Cache.LabelSelector)
+	setKindSelectors(values, "cache-object-label-selector", c.Cache.ObjectLabelSelectors)
+	setKindSelectors(values, "cache-object-field-selector", c.Cache.ObjectFieldSelectors)
+	setBool(values, "cache-full-secrets", c.Cache.FullSecrets)
+
+	setBool(values, "leader-elect", c.LeaderElection.LeaderElect)
+	setString(values, "leader-elect-resource-namespace", c.LeaderElection.ResourceNamespace)
+	setDuration(values, "leader-elect-lease-duration", c.LeaderElection.LeaseDuration)
+	setString(values, "leader-elect-preferred-node-selector", c.LeaderElection.PreferredNodeSelector)
+	setBool(values, "leader-elect-prefer-apiserver-nodes", c.LeaderElection.PreferAPIServerNodes)
+
+	setBool(values, "webhook-enabled", c.Webhook.Enabled)
+	setInt(values, "webhook-port", c.Webhook.Port)
+	setString(values, "webhook-cert-dir", c.Webhook.CertDir)
+	setBool(values, "webhook-self-signed", c.Webhook.SelfSigned)
+	setBool(values, "webhook-reject-missing-secrets", c.Webhook.RejectMissingSecrets)
+	setBool(values, "webhook-dry-run-estimates", c.Webhook.DryRunEstimates)
+	setInt(values, "min-control-plane-machines", c.Webhook.MinControlPlaneMachines)
+	setString(values, "aws-default-metadata-service-authentication", c.Webhook.AWSDefaultMetadataServiceAuthentication)
+	setBool(values, "vsphere-deep-validation", c.Webhook.VSphereDeepValidation.Enabled)
+	setDuration(values, "vsphere-deep-validation-timeout", c.Webhook.VSphereDeepValidation.Timeout)
+
+	setBool(values, "controller-enabled", c.Controller.Enabled)
+	setInt(values, "machineset-concurrency", c.Controller.MachineSetConcurrency)
+	setFloat(values, "machine-creation-qps", c.Controller.MachineCreationQPS)
+	setInt(values, "machine-creation-burst", c.Controller.MachineCreationBurst)
+	setDuration(values, "missing-instance-grace-period", c.Controller.MissingInstanceGracePeriod)
+	setDuration(values, "sync-period", c.Controller.SyncPeriod)
+	setDuration(values, "machineset-resync-period", c.Controller.MachineSetResyncPeriod)
+
+	setDuration(values, "rate-limiter-base-delay", c.RateLimiter.BaseDelay)
+	setDuration(values, "rate-limiter-max-delay", c.RateLimiter.MaxDelay)
+	setFloat(values, "rate-limiter-qps", c.RateLimiter.QPS)
+	setInt(values, "rate-limiter-bucket-size", c.RateLimiter.BucketSize)
+	setFloat(values, "kube-api-qps", c.RateLimiter.APIQPS)
+	setInt(values, "kube-api-burst", c.RateLimiter.APIBurst)
+
+	setBool(values, "capi-sync", c.CAPISync.Enabled)
+	setString(values, "capi-namespace", c.CAPISync.Namespace)
+
+	setString(values, "metrics-bind-address", c.Metrics.BindAddress)
+	setString(values, "health-addr", c.Health.BindAddress)
+
+	setString(values, "logging-format", c.Logging.Format)
+	setInt(values, verbosityFlag, c.Logging.Verbosity)
+	return values
+}
+
+// ApplyToFlagSet sets the flags of the flag set to the values of the configuration, except the flags set
+// on the command line, which take precedence. It must be called after the flag set is parsed.
+func (c *MachineSetControllerConfiguration) ApplyToFlagSet(fs *flag.FlagSet) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	for name, value := range c.FlagValues() {
+		if explicit[name] {
+			klog.V(2).Infof("Flag -%s overrides the configuration file", name)
+			continue
+		}
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("configuration sets unknown flag -%s", name)
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid configuration of flag -%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Reloader reloads the configuration file periodically, and applies the changes of the verbosity of the logs.
+// It implements the manager.Runnable interface.
+type Reloader struct {
+	path      string
+	interval  time.Duration
+	verbosity *int
+}
+
+// NewReloader returns a reloader of the configuration file, loaded with the verbosity.
+func NewReloader(path string, config *MachineSetControllerConfiguration) *Reloader {
+	return &Reloader{path: path, interval: defaultReloadInterval, verbosity: config.Logging.Verbosity}
+}
+
+// NeedLeaderElection implements the manager.LeaderElectionRunnable interface, the logs of all the replicas are configured.
+func (r *Reloader) NeedLeaderElection() bool {
+	return false
+}
+
+// Start reloads the configuration file until the context is done.
+func (r *Reloader) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			r.reload()
+		}
+	}
+}
+
+// reload applies the verbosity of the configuration file when it changed since the last load. An invalid
+// configuration file is logged and ignored, the configuration is left as it is.
+func (r *Reloader) reload() {
+	config, err := Load(r.path)
+	if err != nil {
+		klog.Errorf("Error reloading configuration: %v", err)
+		return
+	}
+	verbosity := config.Logging.Verbosity
+	if verbosity == nil || (r.verbosity != nil && *r.verbosity == *verbosity) {
+		return
+	}
+	if err := logging.SetVerbosity(*verbosity); err != nil {
+		klog.Errorf("Error setting the verbosity of the logs to %d: %v", *verbosity, err)
+		return
+	}
+	r.verbosity = verbosity
+	klog.Infof("Set the verbosity of the logs to %d", *verbosity)
+}
+
+func setString(values map[string]string, name string, value *string) {
+	if value != nil {
+		values[name] = *value
+	}
+}
+
+func setBool(values map[string]string, name string, value *bool) {
+	if value != nil {
+		values[name] = strconv.FormatBool(*value)
+	}
+}
+
+func setInt(values map[string]string, name string, value *int) {
+	if value != nil {
+		values[name] = strconv.Itoa(*value)
+	}
+}
+
+func setFloat(values map[string]string, name string, value *float64) {
+	if value != nil {
+		values[name] = strconv.FormatFloat(*value, 'g', -1, 64)
+	}
+}
+
+func setDuration(values map[string]string, name string, value *metav1.Duration) {
+	if value != nil {
+		values[name] = value.Duration.String()
+	}
+}
+
+func setKindSelectors(values map[string]string, name string, selectors map[string]string) {
+	if len(selectors) > 0 {
+		kindSelectors := util.KindSelectors(selectors)
+		values[name] = kindSelectors.String()
+	}
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/klog/v2"
+)
+
+const testConfig = `apiVersion: config.machine.openshift.io/v1alpha1
+kind: MachineSetControllerConfiguration
+cache:
+  namespace: openshift-machine-api
+  objectLabelSelectors:
+    Secret: machine.openshift.io/owned
+    ConfigMap: app in (machine-api)
+  fullSecrets: true
+leaderElection:
+  leaderElect: true
+  leaseDuration: 137s
+webhook:
+  port: 8443
+  vsphereDeepValidation:
+    enabled: true
+    timeout: 10s
+controller:
+  machineSetConcurrency: 4
+  machineCreationQPS: 0.5
+  missingInstanceGracePeriod: 15m
+  machineSetResyncPeriod: 1h
+rateLimiter:
+  maxDelay: 5m
+  apiQPS: 50
+logging:
+  format: json
+  verbosity: 3
+`
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name          string
+		content       string
+		expectedError string
+	}{
+		{
+			name:    "with a valid configuration",
+			content: testConfig,
+		},
+		{
+			name:          "with an unknown field",
+			content:       testConfig + "webhookPort: 8443\n",
+			expectedError: `unknown field "webhookPort"`,
+		},
+		{
+			name:          "with an unknown kind",
+			content:       "apiVersion: config.machine.openshift.io/v1alpha1\nkind: ControllerManagerConfiguration\n",
+			expectedError: "expected apiVersion config.machine.openshift.io/v1alpha1 and kind MachineSetControllerConfiguration",
+		},
+		{
+			name:          "without a version",
+			content:       "kind: MachineSetControllerConfiguration\n",
+			expectedError: "expected apiVersion config.machine.openshift.io/v1alpha1 and kind MachineSetControllerConfiguration",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config, err := Load(writeConfig(t, tc.content))
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedError)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(config.FlagValues()).To(Equal(map[string]string{
+				"namespace":                       "openshift-machine-api",
+				"cache-object-label-selector":     "ConfigMap:app in (machine-api);Secret:machine.openshift.io/owned",
+				"cache-full-secrets":              "true",
+				"leader-elect":                    "true",
+				"leader-elect-lease-duration":     "2m17s",
+				"webhook-port":                    "8443",
+				"vsphere-deep-validation":         "true",
+				"vsphere-deep-validation-timeout": "10s",
+				"machineset-concurrency":          "4",
+				"machine-creation-qps":            "0.5",
+				"missing-instance-grace-period":   "15m0s",
+				"machineset-resync-period":        "1h0m0s",
+				"rate-limiter-max-delay":          "5m0s",
+				"kube-api-qps":                    "50",
+				"logging-format":                  "json",
+				"v":                               "3",
+			}))
+		})
+	}
+}
+
+func TestApplyToFlagSet(t *testing.T) {
+	g := NewWithT(t)
+
+	fs := flag.NewFlagSet("machineset", flag.ContinueOnError)
+	namespace := fs.String("namespace", "", "")
+	leaderElect := fs.Bool("leader-elect", false, "")
+	leaseDuration := fs.Duration("leader-elect-lease-duration", 0, "")
+	webhookPort := fs.Int("webhook-port", 9443, "")
+	webhookEnabled := fs.Bool("webhook-enabled", true, "")
+	concurrency := fs.Int("machineset-concurrency", 1, "")
+	g.Expect(fs.Parse([]string{"-machineset-concurrency=2", "-webhook-port=443"})).To(Succeed())
+
+	config, err := Load(writeConfig(t, `apiVersion: config.machine.openshift.io/v1alpha1
+kind: MachineSetControllerConfiguration
+cache:
+  namespace: openshift-machine-api
+leaderElection:
+  leaderElect: true
+  leaseDuration: 137s
+webhook:
+  port: 8443
+controller:
+  machineSetConcurrency: 4
+`))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(config.ApplyToFlagSet(fs)).To(Succeed())
+
+	g.Expect(*namespace).To(Equal("openshift-machine-api"))
+	g.Expect(*leaderElect).To(BeTrue())
+	g.Expect(*leaseDuration).To(Equal(137 * time.Second))
+	g.Expect(*webhookEnabled).To(BeTrue(), "expected the flags not set by the configuration to keep their default")
+	g.Expect(*webhookPort).To(Equal(443), "expected the flags set on the command line to take precedence")
+	g.Expect(*concurrency).To(Equal(2), "expected the flags set on the command line to take precedence")
+
+	config.Logging.Verbosity = new(int)
+	g.Expect(config.ApplyToFlagSet(fs)).To(MatchError("configuration sets unknown flag -v"))
+}
+
+func TestReloaderReload(t *testing.T) {
+	g := NewWithT(t)
+
+	verbosity := func() int {
+		for v := 10; v > 0; v-- {
+			if klog.V(klog.Level(v)).Enabled() {
+				return v
+			}
+		}
+		return 0
+	}
+	defer func() {
+		var level klog.Level
+		_ = level.Set("0")
+	}()
+
+	path := writeConfig(t, testConfig)
+	config, err := Load(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	r := NewReloader(path, config)
+
+	g.Expect(os.WriteFile(path, []byte(testConfig+"health:\n  bindAddress: :9441\n"), 0600)).To(Succeed())
+	r.reload()
+	g.Expect(verbosity()).To(Equal(0), "expected the verbosity to be left as it is when it did not change")
+
+	g.Expect(os.WriteFile(path, []byte(testConfig[:len(testConfig)-len("  verbosity: 3\n")]+"  verbosity: 5\n"), 0600)).To(Succeed())
+	r.reload()
+	g.Expect(verbosity()).To(Equal(5))
+
+	g.Expect(os.WriteFile(path, []byte("invalid"), 0600)).To(Succeed())
+	r.reload()
+	g.Expect(verbosity()).To(Equal(5), "expected an invalid configuration to be ignored")
+}
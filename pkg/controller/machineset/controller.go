@@ -27,6 +27,10 @@ import (
 
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	"github.com/openshift/machine-api-operator/pkg/util"
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+	"github.com/openshift/machine-api-operator/pkg/util/logging"
+	"github.com/openshift/machine-api-operator/pkg/util/nodeconfig"
+	"github.com/openshift/machine-api-operator/pkg/util/tracing"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -35,11 +39,13 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
@@ -58,30 +64,119 @@ var (
 	controllerName = "machineset_controller"
 )
 
+// ExcludeFromAdoptionAnnotation prevents MachineSets from adopting a Machine without a controller
+// when it is set to "true", even if the Machine matches their selector.
+const ExcludeFromAdoptionAnnotation = "machine.openshift.io/exclude-from-adoption"
+
+// ReplicasManagedByAnnotation marks a MachineSet whose Machines are created and deleted by an external
+// controller, such as an autoscaler, named by the value of the annotation.
+// The MachineSet controller then stops reconciling the number of replicas, replacing stuck Machines and
+// rolling out template changes, but still adopts Machines and reports the status of the MachineSet.
+const ReplicasManagedByAnnotation = "machine.openshift.io/replicas-managed-by"
+
+// errMachineCreationFailed is returned when the MachineSet fails to create Machines.
+var errMachineCreationFailed = errors.New("failed to create machines")
+
+// Options configures the MachineSet controller.
+type Options struct {
+	// MachineCreationQPS is the number of Machines that may be created per second across all MachineSets.
+	// Zero does not limit the creation rate.
+	MachineCreationQPS float64
+	// MachineCreationBurst is the number of Machines that may be created at once across all MachineSets,
+	// when MachineCreationQPS is set.
+	MachineCreationBurst int
+	// MissingInstanceGracePeriod is how long a Machine whose instance disappeared may remain Failed before
+	// it is deleted and replaced. Zero does not replace such Machines.
+	MissingInstanceGracePeriod time.Duration
+	// MachineValidator validates the machine template of a MachineSet before Machines are created from it.
+	// The secrets referenced by the template are checked even when it is not set.
+	MachineValidator MachineValidator
+	// FullSecrets gets the full Secrets referenced by the machine template when checking they exist, rather than
+	// only their metadata, so that they are not cached twice when the MachineValidator gets them too.
+	FullSecrets bool
+	// ResyncPeriod is how often a MachineSet is reconciled when neither it nor its Machines change.
+	// The periodic resyncs of the cache are ignored, the MachineSets are reconciled on the events of the watches
+	// and requeued when one of their Machines needs attention. Zero never resyncs the MachineSets.
+	ResyncPeriod time.Duration
+	// RateLimiter limits the requeues of the MachineSets. The default rate limiter of the controllers is used when nil.
+	RateLimiter workqueue.RateLimiter
+}
+
 // Add creates a new MachineSet Controller and adds it to the Manager with default RBAC.
 // The Manager will set fields on the Controller and Start it when the Manager is Started.
 func Add(mgr manager.Manager, opts manager.Options) error {
-	r := newReconciler(mgr)
-	return add(mgr, r, r.MachineToMachineSets)
+	return AddWithOptions(Options{})(mgr, opts)
+}
+
+// AddWithOptions returns a function which adds a new MachineSet Controller, configured with the given options, to the Manager.
+func AddWithOptions(o Options) func(manager.Manager, manager.Options) error {
+	return func(mgr manager.Manager, opts manager.Options) error {
+		r, err := newReconciler(mgr, o)
+		if err != nil {
+			return fmt.Errorf("error building reconciler: %v", err)
+		}
+		// The concurrency is configured through the manager options, in the same way as for controllers built with the builder.
+		concurrency := opts.Controller.GroupKindConcurrency[controllerKind.GroupKind().String()]
+		return add(mgr, r, r.MachineToMachineSets, &machineExpectationsHandler{expectations: r.expectations}, concurrency, o.RateLimiter)
+	}
 }
 
 // newReconciler returns a new reconcile.Reconciler.
-func newReconciler(mgr manager.Manager) *ReconcileMachineSet {
-	return &ReconcileMachineSet{Client: mgr.GetClient(), scheme: mgr.GetScheme(), recorder: mgr.GetEventRecorderFor(controllerName)}
+func newReconciler(mgr manager.Manager, o Options) (*ReconcileMachineSet, error) {
+	if err := mgr.GetCache().IndexField(context.TODO(),
+		&machinev1.Machine{},
+		machineOwnerIndex,
+		indexMachineByOwner,
+	); err != nil {
+		return nil, fmt.Errorf("error setting index fields: %v", err)
+	}
+
+	if err := mgr.GetCache().IndexField(context.TODO(),
+		&machinev1.MachineSet{},
+		machineSetSelectorIndex,
+		indexMachineSetBySelector,
+	); err != nil {
+		return nil, fmt.Errorf("error setting index fields: %v", err)
+	}
+
+	return &ReconcileMachineSet{
+		Client:           mgr.GetClient(),
+		apiReader:        mgr.GetAPIReader(),
+		scheme:           mgr.GetScheme(),
+		recorder:         mgr.GetEventRecorderFor(controllerName),
+		expectations:     newUIDTrackingExpectations(),
+		creationLimiter:  newCreationRateLimiter(o.MachineCreationQPS, o.MachineCreationBurst),
+		machineValidator: o.MachineValidator,
+		fullSecrets:      o.FullSecrets,
+
+		missingInstanceGracePeriod: o.MissingInstanceGracePeriod,
+		resyncPeriod:               o.ResyncPeriod,
+	}, nil
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler.
-func add(mgr manager.Manager, r reconcile.Reconciler, mapFn handler.MapFunc) error {
+// maxConcurrentReconciles defaults to 1 when it is not positive, and rateLimiter to the default rate limiter when nil.
+func add(mgr manager.Manager, r reconcile.Reconciler, mapFn handler.MapFunc, ownerHandler handler.EventHandler, maxConcurrentReconciles int, rateLimiter workqueue.RateLimiter) error {
 	// Create a new controller.
-	c, err := controller.New(controllerName, mgr, controller.Options{Reconciler: r})
+	c, err := controller.New(controllerName, mgr, controller.Options{
+		Reconciler:              tracing.NewReconciler(controllerName, r),
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		RateLimiter:             rateLimiter,
+		LogConstructor:          logging.NewLogConstructor(mgr.GetLogger(), controllerName, "machineset"),
+	})
 	if err != nil {
 		return err
 	}
 
+	// The periodic resyncs of the cache, which update the objects without changing them, are ignored:
+	// MachineSets are resynced on their own resync period instead.
+	ignoreResyncs := predicate.ResourceVersionChangedPredicate{}
+
 	// Watch for changes to MachineSet.
 	err = c.Watch(
 		&source.Kind{Type: &machinev1.MachineSet{}},
 		&handler.EnqueueRequestForObject{},
+		ignoreResyncs,
 	)
 	if err != nil {
 		return err
@@ -90,7 +185,8 @@ func add(mgr manager.Manager, r reconcile.Reconciler, mapFn handler.MapFunc) err
 	// Map Machine changes to MachineSets using ControllerRef.
 	err = c.Watch(
 		&source.Kind{Type: &machinev1.Machine{}},
-		&handler.EnqueueRequestForOwner{IsController: true, OwnerType: &machinev1.MachineSet{}},
+		ownerHandler,
+		ignoreResyncs,
 	)
 	if err != nil {
 		return err
@@ -100,6 +196,7 @@ func add(mgr manager.Manager, r reconcile.Reconciler, mapFn handler.MapFunc) err
 	return c.Watch(
 		&source.Kind{Type: &machinev1.Machine{}},
 		handler.EnqueueRequestsFromMapFunc(mapFn),
+		ignoreResyncs,
 	)
 }
 
@@ -108,6 +205,35 @@ type ReconcileMachineSet struct {
 	client.Client
 	scheme   *runtime.Scheme
 	recorder record.EventRecorder
+
+	// apiReader reads the Machines from the API server, bypassing the cache, before scaling down.
+	apiReader client.Reader
+
+	// expectations tracks the Machine creations and deletions each MachineSet
+	// is waiting to observe before it may scale again.
+	expectations *uidTrackingExpectations
+
+	// creationLimiter limits the rate at which Machines are created.
+	creationLimiter *creationRateLimiter
+
+	// invalidAnnotations tracks the invalid annotations of the MachineSets which have been reported.
+	invalidAnnotations invalidAnnotations
+
+	// machineValidator validates the machine template before Machines are created, if set.
+	machineValidator MachineValidator
+
+	// fullSecrets gets the full Secrets referenced by the machine template, rather than only their metadata.
+	fullSecrets bool
+
+	// missingInstanceGracePeriod is how long a Machine whose instance disappeared may remain Failed
+	// before it is replaced. Zero does not replace such Machines.
+	missingInstanceGracePeriod time.Duration
+
+	// resyncPeriod is how often a MachineSet is reconciled when nothing changes. Zero never resyncs.
+	resyncPeriod time.Duration
+
+	// nowFunc is used to mock time in testing. It should be nil in production.
+	nowFunc func() time.Time
 }
 
 func (r *ReconcileMachineSet) MachineToMachineSets(o client.Object) []reconcile.Request {
@@ -152,6 +278,9 @@ func (r *ReconcileMachineSet) Reconcile(ctx context.Context, request reconcile.R
 		if apierrors.IsNotFound(err) {
 			// Object not found, return.  Created objects are automatically garbage collected.
 			// For additional cleanup logic use finalizers.
+			r.expectations.DeleteExpectations(request.NamespacedName)
+			r.creationLimiter.forget(request.NamespacedName)
+			r.invalidAnnotations.forget(request.NamespacedName)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -164,6 +293,11 @@ func (r *ReconcileMachineSet) Reconcile(ctx context.Context, request reconcile.R
 		return reconcile.Result{}, nil
 	}
 
+	if annotations.IsClusterAPIAuthoritative(machineSet) {
+		klog.V(3).Infof("%v: MachineSet is reconciled by Cluster API, skipping", machineSet.Name)
+		return reconcile.Result{}, nil
+	}
+
 	result, err := r.reconcile(ctx, machineSet)
 	if err != nil {
 		klog.Errorf("Failed to reconcile MachineSet %q: %v", request.NamespacedName, err)
@@ -180,12 +314,6 @@ func (r *ReconcileMachineSet) reconcile(ctx context.Context, machineSet *machine
 		return reconcile.Result{}, err
 	}
 
-	allMachines := &machinev1.MachineList{}
-
-	if err := r.Client.List(context.Background(), allMachines, client.InNamespace(machineSet.Namespace)); err != nil {
-		return reconcile.Result{}, fmt.Errorf("failed to list machines: %w", err)
-	}
-
 	// Make sure that label selector can match template's labels.
 	// TODO(vincepri): Move to a validation (admission) webhook when supported.
 	selector, err := metav1.LabelSelectorAsSelector(&machineSet.Spec.Selector)
@@ -197,11 +325,19 @@ func (r *ReconcileMachineSet) reconcile(ctx context.Context, machineSet *machine
 		return reconcile.Result{}, fmt.Errorf("failed validation on MachineSet %q label selector, cannot match any machines ", machineSet.Name)
 	}
 
+	if err := r.reconcileCapacityAnnotations(ctx, machineSet); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	allMachines, err := r.getMachinesForMachineSet(ctx, machineSet, selector)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to list machines: %w", err)
+	}
+
 	// Filter out irrelevant machines (deleting/mismatch labels) and claim orphaned machines.
 	var machineNames []string
 	machineSetMachines := make(map[string]*machinev1.Machine)
-	for idx := range allMachines.Items {
-		machine := &allMachines.Items[idx]
+	for _, machine := range allMachines {
 		if shouldExcludeMachine(machineSet, machine) {
 			continue
 		}
@@ -224,7 +360,51 @@ func (r *ReconcileMachineSet) reconcile(ctx context.Context, machineSet *machine
 		filteredMachines = append(filteredMachines, machineSetMachines[machineName])
 	}
 
-	syncErr := r.syncReplicas(machineSet, filteredMachines)
+	// Standby Machines are not replicas of the MachineSet.
+	filteredMachines, standbyMachines := splitStandbyMachines(filteredMachines)
+
+	var syncErr error
+	var requeueAfter time.Duration
+	if managedBy, ok := machineSet.Annotations[ReplicasManagedByAnnotation]; ok {
+		klog.V(4).Infof("%v: replicas are managed by %q, not syncing replicas", machineSet.Name, managedBy)
+	} else if r.expectations.SatisfiedExpectations(client.ObjectKeyFromObject(machineSet)) {
+		// Standby Machines are promoted first, so that scaling up does not wait for new Machines to be provisioned.
+		filteredMachines, standbyMachines, syncErr = r.promoteStandbyMachines(ctx, machineSet, filteredMachines, standbyMachines)
+		if syncErr == nil {
+			// Machines stuck provisioning are deleted and left out so that syncing the replicas replaces them.
+			filteredMachines, requeueAfter, syncErr = r.deleteStuckMachines(machineSet, filteredMachines)
+		}
+		if syncErr == nil {
+			// So are Machines whose instance disappeared, once their grace period expires.
+			var gracePeriodExpiry time.Duration
+			filteredMachines, gracePeriodExpiry, syncErr = r.deleteMachinesWithMissingInstance(machineSet, filteredMachines)
+			if gracePeriodExpiry > 0 && (requeueAfter == 0 || gracePeriodExpiry < requeueAfter) {
+				requeueAfter = gracePeriodExpiry
+			}
+		}
+		if syncErr == nil {
+			// Outdated Machines are replaced in the same way when a rollout partition is set.
+			filteredMachines, syncErr = r.rolloutMachines(machineSet, filteredMachines)
+		}
+		if syncErr == nil {
+			syncErr = r.syncReplicas(machineSet, filteredMachines)
+		}
+		if syncErr == nil && machineSet.Spec.Replicas != nil && len(filteredMachines) == int(*machineSet.Spec.Replicas) {
+			// The standby Machines are only replenished once the MachineSet has its replicas.
+			syncErr = r.syncStandbyMachines(ctx, machineSet, filteredMachines, standbyMachines)
+		}
+
+		// Creations deferred by rate limiting are retried later rather than reported as a failure.
+		var rateLimitErr *creationRateLimitedError
+		if errors.As(syncErr, &rateLimitErr) {
+			syncErr = nil
+			if requeueAfter == 0 || rateLimitErr.retryAfter < requeueAfter {
+				requeueAfter = rateLimitErr.retryAfter
+			}
+		}
+	} else {
+		klog.V(4).Infof("%v: waiting for previous machine creations and deletions to be observed before syncing replicas", machineSet.Name)
+	}
 
 	ms := machineSet.DeepCopy()
 	newStatus := r.calculateStatus(ms, filteredMachines)
@@ -238,6 +418,23 @@ func (r *ReconcileMachineSet) reconcile(ctx context.Context, machineSet *machine
 		return reconcile.Result{}, fmt.Errorf("failed to update machine set status: %w", err)
 	}
 
+	// The drift is only reported, failing to report it does not fail the reconcile.
+	if err := r.reportTemplateDrift(ctx, updatedMS, filteredMachines); err != nil {
+		klog.Warningf("%v: failed to report the drift of the machines from the machine template: %v", updatedMS.Name, err)
+	}
+
+	// Nor does failing to record the changes of the replicas.
+	if err := r.recordReplicaChange(ctx, updatedMS); err != nil {
+		klog.Warningf("%v: failed to record the change of the replicas: %v", updatedMS.Name, err)
+	}
+
+	if err := updateMachineSetStatusAnnotations(r.Client, updatedMS, filteredMachines, syncErr); err != nil {
+		if syncErr != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to sync machines: %v. failed to update machine set status annotations: %w", syncErr, err)
+		}
+		return reconcile.Result{}, fmt.Errorf("failed to update machine set status annotations: %w", err)
+	}
+
 	if syncErr != nil {
 		return reconcile.Result{}, fmt.Errorf("failed to sync machines: %w", syncErr)
 	}
@@ -261,7 +458,13 @@ func (r *ReconcileMachineSet) reconcile(ctx context.Context, machineSet *machine
 		return reconcile.Result{Requeue: true}, nil
 	}
 
-	return reconcile.Result{}, nil
+	// Requeue when the next provisioning Machine reaches the provisioning timeout, when the grace period
+	// of the next Machine with a missing instance expires, or when creations deferred by rate limiting may be retried,
+	// and at the latest after the resync period.
+	if r.resyncPeriod > 0 && (requeueAfter == 0 || r.resyncPeriod < requeueAfter) {
+		requeueAfter = r.resyncPeriod
+	}
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
 }
 
 // syncReplicas essentially scales machine resources up and down.
@@ -277,27 +480,115 @@ func (r *ReconcileMachineSet) syncReplicas(ms *machinev1.MachineSet, machines []
 		klog.Infof("Too few replicas for %v %s/%s, need %d, creating %d",
 			controllerKind, ms.Namespace, ms.Name, *(ms.Spec.Replicas), diff)
 
+		// Check the machine template once before creating any Machines, rather than
+		// creating Machines that are bound to fail.
+		template, err := r.createMachine(ms)
+		if err != nil {
+			return err
+		}
+		if err := r.preflightMachine(template); err != nil {
+			klog.Warningf("%v: not creating machines: %v", ms.Name, err)
+			return err
+		}
+
+		msKey := client.ObjectKeyFromObject(ms)
+		perMinute := getMachineCreationRate(ms)
+		if hasRateLimitedMachines(machines) {
+			backoff := r.creationLimiter.recordRateLimited(msKey)
+			klog.Warningf("%v: machine creations are being throttled by the cloud provider, backing off for %v", ms.Name, backoff)
+		}
+
+		allowed, retryAfter := r.creationLimiter.allowedCreations(ms, perMinute, diff)
+		var rateLimitErr error
+		if allowed < diff {
+			klog.Infof("%v: creating %d of %d machines, deferring the rest by %v due to rate limiting", ms.Name, allowed, diff, retryAfter)
+			rateLimitErr = &creationRateLimitedError{deferred: diff - allowed, retryAfter: retryAfter}
+			diff = allowed
+		}
+		if diff == 0 {
+			return rateLimitErr
+		}
+
+		// Record the creations before making them so that a stale cache cannot
+		// cause this MachineSet to create the same Machines twice.
+		r.expectations.ExpectCreations(msKey, diff)
+
+		failureDomains, err := newFailureDomainPicker(ms, machines)
+		if err != nil {
+			// The creations will never be observed, drop the expectations.
+			r.expectations.DeleteExpectations(msKey)
+			return err
+		}
+		namer, err := newMachineNamer(r.Client, ms, machines)
+		if err != nil {
+			r.expectations.DeleteExpectations(msKey)
+			return err
+		}
+		hostGroups := newHostGroupPicker(ms, machines)
+
 		var machineList []*machinev1.Machine
 		var errstrings []string
+		var rateLimited bool
 		for i := 0; i < diff; i++ {
 			klog.Infof("Creating machine %d of %d, ( spec.replicas(%d) > currentMachineCount(%d) )",
 				i+1, diff, *(ms.Spec.Replicas), len(machines))
 
-			machine := r.createMachine(ms)
-			if err := r.Client.Create(context.Background(), machine); err != nil {
+			machine, err := r.createMachine(ms)
+			if err != nil {
+				klog.Errorf("Unable to build Machine: %v", err)
+				errstrings = append(errstrings, err.Error())
+				r.expectations.CreationObserved(msKey)
+				continue
+			}
+			if failureDomains != nil {
+				domain := failureDomains.next()
+				if err := setFailureDomain(machine, domain); err != nil {
+					klog.Errorf("Unable to set failure domain %q on Machine: %v", domain, err)
+					errstrings = append(errstrings, err.Error())
+					r.expectations.CreationObserved(msKey)
+					continue
+				}
+			}
+			if hostGroups != nil {
+				setHostGroup(machine, hostGroups.next())
+			}
+			if namer != nil {
+				if err := namer.setName(context.Background(), machine); err != nil {
+					klog.Errorf("Unable to name Machine: %v", err)
+					errstrings = append(errstrings, err.Error())
+					r.expectations.CreationObserved(msKey)
+					continue
+				}
+			}
+			if err := createMachineObject(r.Client, machine); err != nil {
 				klog.Errorf("Unable to create Machine %q: %v", machine.Name, err)
 				errstrings = append(errstrings, err.Error())
+				// The creation will never be observed, lower the expectations.
+				r.expectations.CreationObserved(msKey)
+				if apierrors.IsTooManyRequests(err) {
+					rateLimited = true
+				}
 				continue
 			}
 
 			machineList = append(machineList, machine)
 		}
 
+		if rateLimited {
+			backoff := r.creationLimiter.recordRateLimited(msKey)
+			klog.Warningf("%v: machine creations are being throttled, backing off for %v", ms.Name, backoff)
+		} else if len(errstrings) == 0 && !hasRateLimitedMachines(machines) {
+			r.creationLimiter.recordSuccess(msKey)
+		}
+
 		if len(errstrings) > 0 {
-			return errors.New(strings.Join(errstrings, "; "))
+			return fmt.Errorf("%w: %s", errMachineCreationFailed, strings.Join(errstrings, "; "))
 		}
 
-		return r.waitForMachineCreation(machineList)
+		if err := r.waitForMachineCreation(machineList); err != nil {
+			return err
+		}
+		return rateLimitErr
 	} else if diff > 0 {
 		klog.Infof("Too many replicas for %v %s/%s, need %d, deleting %d",
 			controllerKind, ms.Namespace, ms.Name, *(ms.Spec.Replicas), diff)
@@ -307,8 +598,31 @@ func (r *ReconcileMachineSet) syncReplicas(ms *machinev1.MachineSet, machines []
 			return err
 		}
 		klog.Infof("Found %s delete policy", ms.Spec.DeletePolicy)
-		// Choose which Machines to delete.
+		// Choose which Machines to delete, from their latest version so that the Machines the autoscaler
+		// marked for deletion when scaling down are the ones deleted.
+		machines, diff, err = r.refreshDeletionCandidates(context.Background(), ms, machines, diff)
+		if err != nil {
+			return err
+		}
+		if diff <= 0 {
+			return nil
+		}
 		machinesToDelete := getMachinesToDeletePrioritized(machines, diff, deletePriorityFunc)
+		if len(machinesToDelete) < diff {
+			klog.Warningf("%v: only %d of %d machines can be deleted, the others are protected by the %s annotation",
+				ms.Name, len(machinesToDelete), diff, ScaleDownDisabledAnnotation)
+			diff = len(machinesToDelete)
+		}
+		if diff == 0 {
+			return nil
+		}
+
+		msKey := client.ObjectKeyFromObject(ms)
+		var deleteUIDs []string
+		for _, machine := range machinesToDelete {
+			deleteUIDs = append(deleteUIDs, string(machine.UID))
+		}
+		r.expectations.ExpectDeletions(msKey, deleteUIDs)
 
 		// TODO: Add cap to limit concurrent delete calls.
 		errCh := make(chan error, diff)
@@ -320,6 +634,8 @@ func (r *ReconcileMachineSet) syncReplicas(ms *machinev1.MachineSet, machines []
 				err := r.Client.Delete(context.Background(), targetMachine)
 				if err != nil {
 					klog.Errorf("Unable to delete Machine %s: %v", targetMachine.Name, err)
+					// The deletion will never be observed, lower the expectations.
+					r.expectations.DeletionObserved(msKey, string(targetMachine.UID))
 					errCh <- err
 				}
 			}(machine)
@@ -342,8 +658,33 @@ func (r *ReconcileMachineSet) syncReplicas(ms *machinev1.MachineSet, machines []
 }
 
 // createMachine creates a machine resource.
-// the name of the newly created resource is going to be created by the API server, we set the generateName field
-func (r *ReconcileMachineSet) createMachine(machineSet *machinev1.MachineSet) *machinev1.Machine {
+// the name of the newly created resource is going to be created by the API server, we set the generateName field,
+// unless the MachineSet has a naming template
+func (r *ReconcileMachineSet) createMachine(machineSet *machinev1.MachineSet) (*machinev1.Machine, error) {
+	templateHash, err := computeTemplateHash(&machineSet.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	machineLabels := make(map[string]string, len(machineSet.Spec.Template.ObjectMeta.Labels)+1)
+	for k, v := range machineSet.Spec.Template.ObjectMeta.Labels {
+		machineLabels[k] = v
+	}
+	machineLabels[MachineTemplateHashLabel] = templateHash
+
+	// The labels and taints of the Nodes set on the MachineSet are reconciled onto the Nodes by the nodelink controller.
+	machineAnnotations := machineSet.Spec.Template.ObjectMeta.Annotations
+	if nodeAnnotations := getNodeConfigAnnotations(machineSet); len(nodeAnnotations) > 0 {
+		// The annotations are shared with the MachineSet template, replace them rather than modify them.
+		machineAnnotations = make(map[string]string, len(machineSet.Spec.Template.ObjectMeta.Annotations)+len(nodeAnnotations))
+		for k, v := range machineSet.Spec.Template.ObjectMeta.Annotations {
+			machineAnnotations[k] = v
+		}
+		for k, v := range nodeAnnotations {
+			machineAnnotations[k] = v
+		}
+	}
+
 	gv := machinev1.SchemeGroupVersion
 	machine := &machinev1.Machine{
 		TypeMeta: metav1.TypeMeta{
@@ -351,8 +692,8 @@ func (r *ReconcileMachineSet) createMachine(machineSet *machinev1.MachineSet) *m
 			APIVersion: gv.String(),
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Labels:      machineSet.Spec.Template.ObjectMeta.Labels,
-			Annotations: machineSet.Spec.Template.ObjectMeta.Annotations,
+			Labels:      machineLabels,
+			Annotations: machineAnnotations,
 		},
 		Spec: machineSet.Spec.Template.Spec,
 	}
@@ -360,7 +701,42 @@ func (r *ReconcileMachineSet) createMachine(machineSet *machinev1.MachineSet) *m
 	machine.ObjectMeta.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(machineSet, controllerKind)}
 	machine.Namespace = machineSet.Namespace
 
-	return machine
+	return machine, nil
+}
+
+// maxGeneratedNameAttempts is the number of names generated by the API server for a Machine before giving up.
+const maxGeneratedNameAttempts = 5
+
+// createMachineObject creates the Machine built by createMachine.
+// A Machine with a name fails to be created when the name is used, rather than overwriting the Machine using it.
+// A Machine without a name gets a name generated by the API server from its generateName, which may be used
+// by another Machine too; its creation is then retried with a new name.
+func createMachineObject(c client.Client, machine *machinev1.Machine) error {
+	var err error
+	for attempt := 0; attempt < maxGeneratedNameAttempts; attempt++ {
+		created := machine.DeepCopy()
+		if err = c.Create(context.Background(), created, client.FieldOwner(fieldManager)); err == nil {
+			*machine = *created
+			return nil
+		}
+		if machine.Name != "" || !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+		klog.V(3).Infof("Generated name of Machine %q is used, retrying with a new name", created.Name)
+	}
+	return err
+}
+
+// getNodeConfigAnnotations returns the annotations of the MachineSet setting the labels and taints of the Nodes
+// of its Machines.
+func getNodeConfigAnnotations(machineSet *machinev1.MachineSet) map[string]string {
+	nodeAnnotations := map[string]string{}
+	for _, key := range nodeconfig.Annotations {
+		if value, ok := machineSet.Annotations[key]; ok {
+			nodeAnnotations[key] = value
+		}
+	}
+	return nodeAnnotations
 }
 
 // shouldExcludeMachine returns true if the machine should be filtered out, false otherwise.
@@ -375,6 +751,12 @@ func shouldExcludeMachine(machineSet *machinev1.MachineSet, machine *machinev1.M
 		return true
 	}
 
+	// Ignore orphaned machines which opted out of adoption, they are not part of the machine set.
+	if metav1.GetControllerOf(machine) == nil && machine.Annotations[ExcludeFromAdoptionAnnotation] == "true" {
+		klog.V(4).Infof("%s is excluded from adoption by %v", machine.Name, machineSet.Name)
+		return true
+	}
+
 	if !hasMatchingLabels(machineSet, machine) {
 		return true
 	}
@@ -17,6 +17,7 @@ limitations under the License.
 package machineset
 
 import (
+	"context"
 	"reflect"
 	"testing"
 	"time"
@@ -24,6 +25,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -31,6 +33,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/openshift/machine-api-operator/pkg/util/nodeconfig"
 )
 
 var _ reconcile.Reconciler = &ReconcileMachineSet{}
@@ -131,7 +135,10 @@ func TestMachineSetToMachines(t *testing.T) {
 	}
 
 	r := &ReconcileMachineSet{
-		Client: fake.NewClientBuilder().WithRuntimeObjects(&m, &m2, &m3, machineSetList).Build(),
+		Client: fake.NewClientBuilder().
+			WithRuntimeObjects(&m, &m2, &m3, machineSetList).
+			WithIndex(&machinev1.MachineSet{}, machineSetSelectorIndex, indexMachineSetBySelector).
+			Build(),
 		scheme: scheme.Scheme,
 	}
 
@@ -188,6 +195,30 @@ func TestShouldExcludeMachine(t *testing.T) {
 			},
 			expected: false,
 		},
+		{
+			machineSet: machinev1.MachineSet{
+				Spec: machinev1.MachineSetSpec{
+					Selector: metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"foo": "bar",
+						},
+					},
+				},
+			},
+			machine: machinev1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "withExcludeFromAdoption",
+					Namespace: "test",
+					Labels: map[string]string{
+						"foo": "bar",
+					},
+					Annotations: map[string]string{
+						ExcludeFromAdoptionAnnotation: "true",
+					},
+				},
+			},
+			expected: true,
+		},
 		{
 			machineSet: machinev1.MachineSet{},
 			machine: machinev1.Machine{
@@ -278,8 +309,10 @@ var _ = Describe("MachineSet Reconcile", func() {
 		rec = record.NewFakeRecorder(32)
 
 		r = &ReconcileMachineSet{
-			scheme:   scheme.Scheme,
-			recorder: rec,
+			scheme:          scheme.Scheme,
+			recorder:        rec,
+			expectations:    newUIDTrackingExpectations(),
+			creationLimiter: newCreationRateLimiter(0, 0),
 		}
 	})
 
@@ -339,3 +372,246 @@ var _ = Describe("MachineSet Reconcile", func() {
 		})
 	})
 })
+
+func TestReconcileReplicasManagedBy(t *testing.T) {
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+
+	replicas := int32(2)
+	ms := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "machineset1",
+			Namespace:   "default",
+			Annotations: map[string]string{ReplicasManagedByAnnotation: "external-autoscaler"},
+		},
+		Spec: machinev1.MachineSetSpec{
+			Replicas: &replicas,
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			Template: machinev1.MachineTemplateSpec{
+				ObjectMeta: machinev1.ObjectMeta{Labels: map[string]string{"foo": "bar"}},
+			},
+		},
+	}
+	orphan := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "orphan",
+			Namespace: "default",
+			Labels:    map[string]string{"foo": "bar"},
+		},
+	}
+
+	r := &ReconcileMachineSet{
+		Client: applyClient{fake.NewClientBuilder().
+			WithScheme(scheme.Scheme).
+			WithObjects(ms, orphan).
+			WithIndex(&machinev1.Machine{}, machineOwnerIndex, indexMachineByOwner).
+			Build()},
+		scheme:          scheme.Scheme,
+		recorder:        record.NewFakeRecorder(32),
+		expectations:    newUIDTrackingExpectations(),
+		creationLimiter: newCreationRateLimiter(0, 0),
+	}
+
+	if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(ms)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	machines := &machinev1.MachineList{}
+	if err := r.List(ctx, machines); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(machines.Items) != 1 {
+		t.Fatalf("expected no machines to be created, got %d machines", len(machines.Items))
+	}
+	if !metav1.IsControlledBy(&machines.Items[0], ms) {
+		t.Errorf("expected the orphan machine to be adopted")
+	}
+
+	got := &machinev1.MachineSet{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(ms), got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Status.Replicas != 1 {
+		t.Errorf("expected status replicas to be 1, got %d", got.Status.Replicas)
+	}
+}
+
+func TestReconcileResyncPeriod(t *testing.T) {
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+
+	testCases := []struct {
+		name                 string
+		resyncPeriod         time.Duration
+		expectedRequeueAfter time.Duration
+	}{
+		{
+			name: "without a resync period",
+		},
+		{
+			name:                 "with a resync period",
+			resyncPeriod:         30 * time.Minute,
+			expectedRequeueAfter: 30 * time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			replicas := int32(0)
+			ms := &machinev1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "machineset1", Namespace: "default"},
+				Spec: machinev1.MachineSetSpec{
+					Replicas: &replicas,
+					Selector: metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+					Template: machinev1.MachineTemplateSpec{
+						ObjectMeta: machinev1.ObjectMeta{Labels: map[string]string{"foo": "bar"}},
+					},
+				},
+			}
+
+			r := &ReconcileMachineSet{
+				Client: applyClient{fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(ms).
+					WithIndex(&machinev1.Machine{}, machineOwnerIndex, indexMachineByOwner).
+					Build()},
+				scheme:          scheme.Scheme,
+				recorder:        record.NewFakeRecorder(32),
+				expectations:    newUIDTrackingExpectations(),
+				creationLimiter: newCreationRateLimiter(0, 0),
+				resyncPeriod:    tc.resyncPeriod,
+			}
+
+			result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(ms)})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.RequeueAfter != tc.expectedRequeueAfter {
+				t.Errorf("expected the MachineSet to be requeued after %v, got %v", tc.expectedRequeueAfter, result.RequeueAfter)
+			}
+		})
+	}
+}
+
+func TestCreateMachineNodeConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	templateAnnotations := map[string]string{"foo": "bar"}
+	ms := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "machineset1",
+			Namespace: "default",
+			Annotations: map[string]string{
+				nodeconfig.NodeLabelsAnnotation: "example.com/tier=gold",
+				nodeconfig.NodeTaintsAnnotation: "example.com/tier=gold:NoSchedule",
+				StandbyReplicasAnnotation:       "1",
+			},
+		},
+		Spec: machinev1.MachineSetSpec{
+			Template: machinev1.MachineTemplateSpec{
+				ObjectMeta: machinev1.ObjectMeta{Annotations: templateAnnotations},
+			},
+		},
+	}
+
+	machine, err := (&ReconcileMachineSet{}).createMachine(ms)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(machine.Annotations).To(Equal(map[string]string{
+		"foo":                           "bar",
+		nodeconfig.NodeLabelsAnnotation: "example.com/tier=gold",
+		nodeconfig.NodeTaintsAnnotation: "example.com/tier=gold:NoSchedule",
+	}))
+	// The template annotations must not be modified.
+	g.Expect(templateAnnotations).To(Equal(map[string]string{"foo": "bar"}))
+}
+
+// collidingClient generates the names of the first Machines created with a generateName from the names
+// of existing Machines, as the API server may do.
+type collidingClient struct {
+	client.Client
+	names []string
+}
+
+func (c *collidingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if obj.GetName() == "" && obj.GetGenerateName() != "" && len(c.names) > 0 {
+		obj.SetName(c.names[0])
+		c.names = c.names[1:]
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestCreateMachineObject(t *testing.T) {
+	existing := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "machineset1-taken",
+			Namespace: "default",
+			Labels:    map[string]string{"owner": "other"},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		machineName   string
+		collisions    []string
+		expectedError bool
+	}{
+		{
+			name:       "with a generated name used by another machine",
+			collisions: []string{existing.Name},
+		},
+		{
+			name:          "with generated names used by other machines on every attempt",
+			collisions:    []string{existing.Name, existing.Name, existing.Name, existing.Name, existing.Name},
+			expectedError: true,
+		},
+		{
+			name:          "with a name used by another machine",
+			machineName:   existing.Name,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(machinev1.AddToScheme(scheme.Scheme)).To(Succeed())
+
+			c := &collidingClient{
+				Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(existing.DeepCopy()).Build(),
+				names:  tc.collisions,
+			}
+			machine := &machinev1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:         tc.machineName,
+					GenerateName: "machineset1-",
+					Namespace:    "default",
+					Labels:       map[string]string{"owner": "machineset1"},
+				},
+			}
+
+			err := createMachineObject(c, machine)
+			if tc.expectedError {
+				g.Expect(apierrors.IsAlreadyExists(err)).To(BeTrue(), "expected an AlreadyExists error, got %v", err)
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(machine.Name).To(HavePrefix("machineset1-"))
+				g.Expect(machine.Name).ToNot(Equal(existing.Name))
+			}
+
+			// The Machine using the name is never overwritten.
+			got := &machinev1.Machine{}
+			g.Expect(c.Get(context.Background(), client.ObjectKeyFromObject(existing), got)).To(Succeed())
+			g.Expect(got.Labels).To(Equal(existing.Labels))
+
+			machines := &machinev1.MachineList{}
+			g.Expect(c.List(context.Background(), machines)).To(Succeed())
+			if tc.expectedError {
+				g.Expect(machines.Items).To(HaveLen(1))
+			} else {
+				g.Expect(machines.Items).To(HaveLen(2))
+			}
+		})
+	}
+}
@@ -20,8 +20,11 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strconv"
+	"strings"
 
 	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -31,18 +34,38 @@ const (
 
 	// DeleteNodeAnnotation marks nodes that will be given priority for deletion
 	// when a machineset scales down. This annotation is given top priority on all delete policies.
+	// A value of the form "priority-N" orders the annotated machines amongst themselves, machines
+	// with a higher N are deleted first. Any other non-empty value, such as "true", ranks above all priorities.
 	DeleteNodeAnnotation = "machine.openshift.io/delete-machine"
 
+	// deleteAnnotationPriorityPrefix is the prefix of DeleteNodeAnnotation values setting a priority.
+	deleteAnnotationPriorityPrefix = "priority-"
+
 	// oldDeleteNodeAnnotation is the previous version of the DeleteNodeAnnotation.
 	// This was changed so that the new version, compatible with the cluster api Kubernetes Autoscaler
 	// provider could be preferred.
 	oldDeleteNodeAnnotation = "machine.openshift.io/cluster-api-delete-machine"
 
-	mustDelete    deletePriority = 100.0
-	betterDelete  deletePriority = 50.0
-	preferDelete  deletePriority = 40.0
-	couldDelete   deletePriority = 20.0
-	mustNotDelete deletePriority = 0.0
+	// DeletePolicyAnnotation overrides the delete policy of a MachineSet.
+	// It accepts the values of spec.deletePolicy, as well as PreferInterruptible, which
+	// the MachineSet API does not support yet.
+	DeletePolicyAnnotation = "machine.openshift.io/delete-policy"
+
+	// ScaleDownDisabledAnnotation protects a Machine from being deleted when its MachineSet scales down,
+	// when set to "true". The MachineSet reports the scale down as blocked when it is not able to reach
+	// its replicas without deleting protected Machines.
+	ScaleDownDisabledAnnotation = "machine.openshift.io/scale-down-disabled"
+
+	// PreferInterruptibleMachineSetDeletePolicy deletes interruptible (spot) machines before
+	// any other machines, and otherwise behaves as the Random delete policy.
+	PreferInterruptibleMachineSetDeletePolicy machinev1.MachineSetDeletePolicy = "PreferInterruptible"
+
+	mustDelete          deletePriority = 100.0
+	betterDelete        deletePriority = 50.0
+	interruptibleDelete deletePriority = 45.0
+	preferDelete        deletePriority = 40.0
+	couldDelete         deletePriority = 20.0
+	mustNotDelete       deletePriority = 0.0
 
 	secondsPerTenDays float64 = 864000
 )
@@ -100,6 +123,29 @@ func randomDeletePolicy(machine *machinev1.Machine) deletePriority {
 	return couldDelete
 }
 
+// preferInterruptibleDeletePriority ranks interruptible machines below the machines which
+// should be deleted first by the random delete policy, but above all other machines.
+func preferInterruptibleDeletePriority(machine *machinev1.Machine) deletePriority {
+	priority := randomDeletePolicy(machine)
+	if priority >= betterDelete {
+		return priority
+	}
+	if isInterruptible(machine) {
+		return interruptibleDelete
+	}
+	return priority
+}
+
+// isInterruptible returns true if the machine runs on an interruptible instance.
+// The label is set by the machine controllers on the Machine or on its Node.
+func isInterruptible(machine *machinev1.Machine) bool {
+	if _, ok := machine.Labels[machinecontroller.MachineInterruptibleInstanceLabelName]; ok {
+		return true
+	}
+	_, ok := machine.Spec.ObjectMeta.Labels[machinecontroller.MachineInterruptibleInstanceLabelName]
+	return ok
+}
+
 type sortableMachines struct {
 	machines []*machinev1.Machine
 	priority deletePriorityFunc
@@ -108,10 +154,64 @@ type sortableMachines struct {
 func (m sortableMachines) Len() int      { return len(m.machines) }
 func (m sortableMachines) Swap(i, j int) { m.machines[i], m.machines[j] = m.machines[j], m.machines[i] }
 func (m sortableMachines) Less(i, j int) bool {
+	// Machines already being deleted are sorted first, followed by machines nominated for
+	// deletion by annotation in order of their annotation priority, before applying the delete policy.
+	iDeleting := m.machines[i].DeletionTimestamp != nil && !m.machines[i].DeletionTimestamp.IsZero()
+	jDeleting := m.machines[j].DeletionTimestamp != nil && !m.machines[j].DeletionTimestamp.IsZero()
+	if iDeleting != jDeleting {
+		return iDeleting
+	}
+
+	iPriority, iNominated := deleteAnnotationPriority(m.machines[i])
+	jPriority, jNominated := deleteAnnotationPriority(m.machines[j])
+	if iNominated != jNominated {
+		return iNominated
+	}
+	if iNominated && iPriority != jPriority {
+		return iPriority > jPriority // high to low
+	}
 	return m.priority(m.machines[j]) < m.priority(m.machines[i]) // high to low
 }
 
-func getMachinesToDeletePrioritized(filteredMachines []*machinev1.Machine, diff int, fun deletePriorityFunc) []*machinev1.Machine {
+// deleteAnnotationPriority returns whether the machine has been nominated for deletion by
+// annotation, and its priority amongst the other nominated machines.
+func deleteAnnotationPriority(machine *machinev1.Machine) (int64, bool) {
+	value := machine.ObjectMeta.Annotations[DeleteNodeAnnotation]
+	if value == "" {
+		value = machine.ObjectMeta.Annotations[oldDeleteNodeAnnotation]
+	}
+	if value == "" {
+		return 0, false
+	}
+
+	if strings.HasPrefix(value, deleteAnnotationPriorityPrefix) {
+		if priority, err := strconv.ParseInt(strings.TrimPrefix(value, deleteAnnotationPriorityPrefix), 10, 64); err == nil && priority >= 0 {
+			return priority, true
+		}
+	}
+
+	return math.MaxInt64, true
+}
+
+// isScaleDownDisabled returns true if the Machine must not be deleted when its MachineSet scales down.
+// Machines already being deleted are no longer protected.
+func isScaleDownDisabled(machine *machinev1.Machine) bool {
+	if machine.DeletionTimestamp != nil && !machine.DeletionTimestamp.IsZero() {
+		return false
+	}
+	return machine.Annotations[ScaleDownDisabledAnnotation] == "true"
+}
+
+// getMachinesToDeletePrioritized returns the diff Machines to delete first, leaving out the Machines protected
+// by the ScaleDownDisabledAnnotation. Fewer Machines are returned when too many of them are protected.
+func getMachinesToDeletePrioritized(machines []*machinev1.Machine, diff int, fun deletePriorityFunc) []*machinev1.Machine {
+	var filteredMachines []*machinev1.Machine
+	for _, machine := range machines {
+		if !isScaleDownDisabled(machine) {
+			filteredMachines = append(filteredMachines, machine)
+		}
+	}
+
 	if diff >= len(filteredMachines) {
 		return filteredMachines
 	} else if diff <= 0 {
@@ -128,17 +228,24 @@ func getMachinesToDeletePrioritized(filteredMachines []*machinev1.Machine, diff
 }
 
 func getDeletePriorityFunc(ms *machinev1.MachineSet) (deletePriorityFunc, error) {
+	policy := ms.Spec.DeletePolicy
+	if override, ok := ms.Annotations[DeletePolicyAnnotation]; ok {
+		policy = override
+	}
+
 	// Map the Spec.DeletePolicy value to the appropriate delete priority function
-	switch msdp := machinev1.MachineSetDeletePolicy(ms.Spec.DeletePolicy); msdp {
+	switch msdp := machinev1.MachineSetDeletePolicy(policy); msdp {
 	case machinev1.RandomMachineSetDeletePolicy:
 		return randomDeletePolicy, nil
 	case machinev1.NewestMachineSetDeletePolicy:
 		return newestDeletePriority, nil
 	case machinev1.OldestMachineSetDeletePolicy:
 		return oldestDeletePriority, nil
+	case PreferInterruptibleMachineSetDeletePolicy:
+		return preferInterruptibleDeletePriority, nil
 	case "":
 		return randomDeletePolicy, nil
 	default:
-		return nil, fmt.Errorf("unsupported delete policy %s, must be one of 'Random', 'Newest', or 'Oldest'", msdp)
+		return nil, fmt.Errorf("unsupported delete policy %s, must be one of 'Random', 'Newest', 'Oldest' or 'PreferInterruptible'", msdp)
 	}
 }
@@ -18,11 +18,14 @@ package machineset
 
 import (
 	"reflect"
+	"sort"
 	"testing"
 
 	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
 )
 
 func TestMachineToDelete(t *testing.T) {
@@ -356,3 +359,206 @@ func TestMachineOldestDelete(t *testing.T) {
 		}
 	}
 }
+
+func TestMachineDeleteAnnotationPriority(t *testing.T) {
+	now := metav1.Now()
+	deletingMachine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "deleting", DeletionTimestamp: &now}}
+	trueMachine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "true", Annotations: map[string]string{DeleteNodeAnnotation: "true"}}}
+	priority10Machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "priority-10", Annotations: map[string]string{DeleteNodeAnnotation: "priority-10"}}}
+	priority2Machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "priority-2", Annotations: map[string]string{oldDeleteNodeAnnotation: "priority-2"}}}
+	invalidPriorityMachine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "priority-x", Annotations: map[string]string{DeleteNodeAnnotation: "priority-x"}}}
+	failedMachine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "failed"}, Status: machinev1.MachineStatus{ErrorMessage: pointer.String("failed")}}
+	runningMachine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "running"}, Status: machinev1.MachineStatus{NodeRef: &corev1.ObjectReference{}}}
+
+	tests := []struct {
+		desc     string
+		machines []*machinev1.Machine
+		diff     int
+		expect   []*machinev1.Machine
+	}{
+		{
+			desc:     "annotated machines are deleted before failed machines",
+			diff:     1,
+			machines: []*machinev1.Machine{failedMachine, runningMachine, priority2Machine},
+			expect:   []*machinev1.Machine{priority2Machine},
+		},
+		{
+			desc:     "higher priorities are deleted first",
+			diff:     2,
+			machines: []*machinev1.Machine{runningMachine, priority2Machine, failedMachine, priority10Machine},
+			expect:   []*machinev1.Machine{priority10Machine, priority2Machine},
+		},
+		{
+			desc:     "values without a valid priority rank above all priorities",
+			diff:     3,
+			machines: []*machinev1.Machine{priority10Machine, trueMachine, runningMachine, invalidPriorityMachine},
+			expect:   []*machinev1.Machine{trueMachine, invalidPriorityMachine, priority10Machine},
+		},
+		{
+			desc:     "deleting machines are sorted before annotated machines",
+			diff:     2,
+			machines: []*machinev1.Machine{trueMachine, runningMachine, deletingMachine},
+			expect:   []*machinev1.Machine{deletingMachine, trueMachine},
+		},
+	}
+
+	for _, policy := range []machinev1.MachineSetDeletePolicy{
+		machinev1.RandomMachineSetDeletePolicy,
+		machinev1.NewestMachineSetDeletePolicy,
+		machinev1.OldestMachineSetDeletePolicy,
+	} {
+		deletePriorityFunc, err := getDeletePriorityFunc(&machinev1.MachineSet{Spec: machinev1.MachineSetSpec{DeletePolicy: string(policy)}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, test := range tests {
+			machines := append([]*machinev1.Machine{}, test.machines...)
+			result := getMachinesToDeletePrioritized(machines, test.diff, deletePriorityFunc)
+			if !reflect.DeepEqual(result, test.expect) {
+				var names []string
+				for _, m := range result {
+					names = append(names, m.Name)
+				}
+				t.Errorf("[policy=%s, case=%s] actual: %v", policy, test.desc, names)
+			}
+		}
+	}
+}
+
+func TestMachineScaleDownDisabled(t *testing.T) {
+	now := metav1.Now()
+	protected := map[string]string{ScaleDownDisabledAnnotation: "true"}
+	protectedMachine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "protected", Annotations: protected}}
+	protectedNominatedMachine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "protected-nominated", Annotations: map[string]string{
+		ScaleDownDisabledAnnotation: "true",
+		DeleteNodeAnnotation:        "true",
+	}}}
+	protectedDeletingMachine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "protected-deleting", Annotations: protected, DeletionTimestamp: &now}}
+	unprotectedMachine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "unprotected", Annotations: map[string]string{ScaleDownDisabledAnnotation: "false"}}}
+	runningMachine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "running"}, Status: machinev1.MachineStatus{NodeRef: &corev1.ObjectReference{}}}
+
+	tests := []struct {
+		desc     string
+		machines []*machinev1.Machine
+		diff     int
+		expect   []*machinev1.Machine
+	}{
+		{
+			desc:     "protected machines are never deleted",
+			diff:     1,
+			machines: []*machinev1.Machine{protectedMachine, runningMachine},
+			expect:   []*machinev1.Machine{runningMachine},
+		},
+		{
+			desc:     "protected machines are not deleted when nominated",
+			diff:     1,
+			machines: []*machinev1.Machine{protectedNominatedMachine, runningMachine},
+			expect:   []*machinev1.Machine{runningMachine},
+		},
+		{
+			desc:     "fewer machines are deleted when too many are protected",
+			diff:     3,
+			machines: []*machinev1.Machine{protectedMachine, unprotectedMachine, protectedNominatedMachine},
+			expect:   []*machinev1.Machine{unprotectedMachine},
+		},
+		{
+			desc:     "protected machines being deleted are counted",
+			diff:     1,
+			machines: []*machinev1.Machine{runningMachine, protectedDeletingMachine},
+			expect:   []*machinev1.Machine{protectedDeletingMachine},
+		},
+	}
+
+	for _, policy := range []machinev1.MachineSetDeletePolicy{
+		machinev1.RandomMachineSetDeletePolicy,
+		machinev1.NewestMachineSetDeletePolicy,
+		machinev1.OldestMachineSetDeletePolicy,
+	} {
+		deletePriorityFunc, err := getDeletePriorityFunc(&machinev1.MachineSet{Spec: machinev1.MachineSetSpec{DeletePolicy: string(policy)}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, test := range tests {
+			result := getMachinesToDeletePrioritized(test.machines, test.diff, deletePriorityFunc)
+			if !reflect.DeepEqual(result, test.expect) {
+				var names []string
+				for _, m := range result {
+					names = append(names, m.Name)
+				}
+				t.Errorf("[policy=%s, case=%s] actual: %v", policy, test.desc, names)
+			}
+		}
+	}
+}
+
+func TestMachinePreferInterruptibleDelete(t *testing.T) {
+	msg := "something wrong with the machine"
+	interruptibleLabels := map[string]string{machinecontroller.MachineInterruptibleInstanceLabelName: ""}
+
+	onDemandMachine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "on-demand"}, Status: machinev1.MachineStatus{NodeRef: &corev1.ObjectReference{}}}
+	onDemandNoNodeMachine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "on-demand-no-node"}}
+	interruptibleMachine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "interruptible", Labels: interruptibleLabels}, Status: machinev1.MachineStatus{NodeRef: &corev1.ObjectReference{}}}
+	interruptibleNodeLabelMachine := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "interruptible-node-label"},
+		Spec:       machinev1.MachineSpec{ObjectMeta: machinev1.ObjectMeta{Labels: interruptibleLabels}},
+		Status:     machinev1.MachineStatus{NodeRef: &corev1.ObjectReference{}},
+	}
+	failedMachine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "failed"}, Status: machinev1.MachineStatus{ErrorMessage: &msg}}
+
+	tests := []struct {
+		desc     string
+		machines []*machinev1.Machine
+		diff     int
+		expect   []string
+	}{
+		{
+			desc:     "interruptible machines are deleted before on-demand machines",
+			diff:     2,
+			machines: []*machinev1.Machine{onDemandMachine, interruptibleMachine, onDemandNoNodeMachine, interruptibleNodeLabelMachine},
+			expect:   []string{"interruptible", "interruptible-node-label"},
+		},
+		{
+			desc:     "failed machines are deleted before interruptible machines",
+			diff:     2,
+			machines: []*machinev1.Machine{interruptibleMachine, onDemandMachine, failedMachine},
+			expect:   []string{"failed", "interruptible"},
+		},
+		{
+			desc:     "on-demand machines without a node are deleted next",
+			diff:     2,
+			machines: []*machinev1.Machine{onDemandMachine, onDemandNoNodeMachine, interruptibleMachine},
+			expect:   []string{"interruptible", "on-demand-no-node"},
+		},
+	}
+
+	ms := &machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{DeletePolicyAnnotation: string(PreferInterruptibleMachineSetDeletePolicy)}}}
+	deletePriorityFunc, err := getDeletePriorityFunc(ms)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, test := range tests {
+		result := getMachinesToDeletePrioritized(append([]*machinev1.Machine{}, test.machines...), test.diff, deletePriorityFunc)
+		var names []string
+		for _, m := range result {
+			names = append(names, m.Name)
+		}
+		sort.Strings(names)
+		sort.Strings(test.expect)
+		if !reflect.DeepEqual(names, test.expect) {
+			t.Errorf("[case %s] expected: %v, actual: %v", test.desc, test.expect, names)
+		}
+	}
+}
+
+func TestGetDeletePriorityFuncAnnotation(t *testing.T) {
+	ms := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{DeletePolicyAnnotation: "Unknown"}},
+		Spec:       machinev1.MachineSetSpec{DeletePolicy: string(machinev1.OldestMachineSetDeletePolicy)},
+	}
+	if _, err := getDeletePriorityFunc(ms); err == nil {
+		t.Errorf("expected an error for an unsupported delete policy annotation")
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+const (
+	// OutOfDateReplicasAnnotation is the number of Machines of the MachineSet whose providerSpec differs from
+	// the providerSpec of its current machine template. Unlike the outdated replicas, it ignores the changes of
+	// the template which do not affect the instances, and covers the adopted Machines.
+	OutOfDateReplicasAnnotation = "machine.openshift.io/out-of-date-replicas"
+
+	// TemplateDriftedCondition is set on the Machines of a MachineSet, true when the providerSpec of the Machine
+	// differs from the providerSpec of the machine template of its MachineSet. The MachineSet only reports the
+	// drift, the Machines are not replaced.
+	TemplateDriftedCondition machinev1.ConditionType = "TemplateDrifted"
+
+	// ProviderSpecDriftedReason is used when the providerSpec of a Machine differs from its MachineSet template.
+	ProviderSpecDriftedReason = "ProviderSpecDrifted"
+)
+
+// providerSpecDrift returns the top level fields of the providerSpec of the Machine which differ from the
+// providerSpec of the machine template of the MachineSet, sorted by name. The failure domain stamped into
+// the providerSpec of the Machine is not a drift when it is one of the failure domains of the MachineSet.
+func providerSpecDrift(ms *machinev1.MachineSet, machine *machinev1.Machine) ([]string, error) {
+	actual, err := providerSpecAsMap(machine.Spec.ProviderSpec.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	// The template is compared as the Machine would have been created from it.
+	template := &machinev1.Machine{Spec: *ms.Spec.Template.Spec.DeepCopy()}
+	domains, err := getFailureDomains(ms)
+	if err != nil {
+		return nil, err
+	}
+	zone := getProviderSpecZone(actual)
+	for _, domain := range domains {
+		if domain.Zone == zone {
+			if err := setFailureDomain(template, domain); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	expected, err := providerSpecAsMap(template.Spec.ProviderSpec.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for field, value := range expected {
+		if !equality.Semantic.DeepEqual(value, actual[field]) {
+			fields = append(fields, field)
+		}
+	}
+	for field := range actual {
+		if _, ok := expected[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// reportTemplateDrift sets the TemplateDrifted condition of the Machines of the MachineSet.
+func (r *ReconcileMachineSet) reportTemplateDrift(ctx context.Context, ms *machinev1.MachineSet, machines []*machinev1.Machine) error {
+	var errs []error
+	for _, machine := range machines {
+		fields, err := providerSpecDrift(ms, machine)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to compare machine %s with the machine template: %w", machine.Name, err))
+			continue
+		}
+
+		original := machine.DeepCopy()
+		if len(fields) > 0 {
+			conditions.Set(machine, &machinev1.Condition{
+				Type:     TemplateDriftedCondition,
+				Status:   corev1.ConditionTrue,
+				Reason:   ProviderSpecDriftedReason,
+				Severity: machinev1.ConditionSeverityInfo,
+				Message:  fmt.Sprintf("ProviderSpec differs from the machine template of MachineSet %s in %s", ms.Name, strings.Join(fields, ", ")),
+			})
+		} else {
+			conditions.Set(machine, &machinev1.Condition{Type: TemplateDriftedCondition, Status: corev1.ConditionFalse})
+		}
+		if equality.Semantic.DeepEqual(original.Status.Conditions, machine.Status.Conditions) {
+			continue
+		}
+
+		// The conditions are also set by the machine controller, which must not be overwritten.
+		patch := client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})
+		if err := r.Client.Status().Patch(ctx, machine, patch); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to set %s condition of machine %s: %w", TemplateDriftedCondition, machine.Name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// setOutOfDateReplicas records the number of Machines of the MachineSet whose providerSpec drifted from the
+// machine template. The Machines which cannot be compared are reported by reportTemplateDrift.
+func setOutOfDateReplicas(ms *machinev1.MachineSet, machines []*machinev1.Machine) {
+	drifted := 0
+	for _, machine := range machines {
+		if fields, err := providerSpecDrift(ms, machine); err == nil && len(fields) > 0 {
+			drifted++
+		}
+	}
+	if ms.Annotations == nil {
+		ms.Annotations = make(map[string]string)
+	}
+	ms.Annotations[OutOfDateReplicasAnnotation] = strconv.Itoa(drifted)
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+func TestReportTemplateDrift(t *testing.T) {
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+
+	template := `{"kind":"AWSMachineProviderConfig","instanceType":"m6i.xlarge","placement":{"region":"us-east-1"}}`
+	newMachine := func(name, providerSpec string) *machinev1.Machine {
+		return &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: machinev1.MachineSpec{
+				ProviderSpec: machinev1.ProviderSpec{Value: &runtime.RawExtension{Raw: []byte(providerSpec)}},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name              string
+		failureDomains    string
+		machine           *machinev1.Machine
+		expectedCondition machinev1.Condition
+	}{
+		{
+			name:              "with a machine up to date",
+			machine:           newMachine("machine", template),
+			expectedCondition: machinev1.Condition{Type: TemplateDriftedCondition, Status: corev1.ConditionFalse},
+		},
+		{
+			name:    "with a machine of another instance type",
+			machine: newMachine("machine", `{"kind":"AWSMachineProviderConfig","instanceType":"m5.xlarge","placement":{"region":"us-east-1"},"spotMarketOptions":{}}`),
+			expectedCondition: machinev1.Condition{
+				Type:     TemplateDriftedCondition,
+				Status:   corev1.ConditionTrue,
+				Reason:   ProviderSpecDriftedReason,
+				Severity: machinev1.ConditionSeverityInfo,
+				Message:  "ProviderSpec differs from the machine template of MachineSet machineset in instanceType, spotMarketOptions",
+			},
+		},
+		{
+			name:              "with a machine in a failure domain of the MachineSet",
+			failureDomains:    "us-east-1a,us-east-1b/subnet-b",
+			machine:           newMachine("machine", `{"kind":"AWSMachineProviderConfig","instanceType":"m6i.xlarge","placement":{"region":"us-east-1","availabilityZone":"us-east-1b"},"subnet":{"id":"subnet-b"}}`),
+			expectedCondition: machinev1.Condition{Type: TemplateDriftedCondition, Status: corev1.ConditionFalse},
+		},
+		{
+			name:           "with a machine in another zone",
+			failureDomains: "us-east-1a,us-east-1b",
+			machine:        newMachine("machine", `{"kind":"AWSMachineProviderConfig","instanceType":"m6i.xlarge","placement":{"region":"us-east-1","availabilityZone":"us-east-1c"}}`),
+			expectedCondition: machinev1.Condition{
+				Type:     TemplateDriftedCondition,
+				Status:   corev1.ConditionTrue,
+				Reason:   ProviderSpecDriftedReason,
+				Severity: machinev1.ConditionSeverityInfo,
+				Message:  "ProviderSpec differs from the machine template of MachineSet machineset in placement",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "machineset", Namespace: "default"},
+				Spec: machinev1.MachineSetSpec{
+					Template: machinev1.MachineTemplateSpec{
+						Spec: machinev1.MachineSpec{
+							ProviderSpec: machinev1.ProviderSpec{Value: &runtime.RawExtension{Raw: []byte(template)}},
+						},
+					},
+				},
+			}
+			if tc.failureDomains != "" {
+				ms.Annotations = map[string]string{FailureDomainsAnnotation: tc.failureDomains}
+			}
+
+			r := &ReconcileMachineSet{
+				Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(tc.machine).Build(),
+			}
+			g.Expect(r.reportTemplateDrift(context.TODO(), ms, []*machinev1.Machine{tc.machine})).To(Succeed())
+
+			got := &machinev1.Machine{}
+			g.Expect(r.Get(context.TODO(), client.ObjectKeyFromObject(tc.machine), got)).To(Succeed())
+			condition := conditions.Get(got, TemplateDriftedCondition)
+			g.Expect(condition).ToNot(BeNil())
+			g.Expect(*condition).To(conditions.MatchCondition(tc.expectedCondition))
+
+			expectedOutOfDate := "0"
+			if tc.expectedCondition.Status == corev1.ConditionTrue {
+				expectedOutOfDate = "1"
+			}
+			setOutOfDateReplicas(ms, []*machinev1.Machine{tc.machine})
+			g.Expect(ms.Annotations).To(HaveKeyWithValue(OutOfDateReplicasAnnotation, expectedOutOfDate))
+		})
+	}
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// expectationsTimeout is the time after which unfulfilled expectations are considered expired.
+// This guards against a MachineSet being blocked forever if a watch event is missed.
+// It mirrors the ExpectationsTimeout used by the upstream ReplicaSet controller.
+const expectationsTimeout = 5 * time.Minute
+
+// machineSetExpectations records the number of Machine creations and the UIDs of
+// Machine deletions a MachineSet is waiting to observe.
+type machineSetExpectations struct {
+	add        int64
+	deleteUIDs sets.String
+	timestamp  time.Time
+}
+
+func (e *machineSetExpectations) fulfilled() bool {
+	return e.add <= 0 && e.deleteUIDs.Len() == 0
+}
+
+// uidTrackingExpectations is a simplified port of the UIDTrackingControllerExpectations
+// used by the upstream ReplicaSet controller.
+// Before creating or deleting Machines, the MachineSet controller records what it expects
+// to observe. Until the watch events for those Machines have been received, the cache
+// may be stale, and the controller must not act on the replica count again, otherwise it
+// may create or delete more Machines than required.
+type uidTrackingExpectations struct {
+	lock  sync.Mutex
+	store map[types.NamespacedName]*machineSetExpectations
+
+	// nowFunc is used to mock time in testing. It should be nil in production.
+	nowFunc func() time.Time
+}
+
+func newUIDTrackingExpectations() *uidTrackingExpectations {
+	return &uidTrackingExpectations{
+		store: make(map[types.NamespacedName]*machineSetExpectations),
+	}
+}
+
+func (u *uidTrackingExpectations) now() time.Time {
+	if u.nowFunc != nil {
+		return u.nowFunc()
+	}
+	return time.Now()
+}
+
+// SatisfiedExpectations returns true when all creations and deletions expected for the
+// MachineSet have been observed, when the expectations have expired, or when no
+// expectations have been recorded.
+func (u *uidTrackingExpectations) SatisfiedExpectations(key types.NamespacedName) bool {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	exp, ok := u.store[key]
+	if !ok {
+		return true
+	}
+
+	if exp.fulfilled() {
+		return true
+	}
+
+	if u.now().Sub(exp.timestamp) > expectationsTimeout {
+		klog.Warningf("%v: expectations expired, still waiting on %d creations and %d deletions", key, exp.add, exp.deleteUIDs.Len())
+		return true
+	}
+
+	klog.V(4).Infof("%v: waiting on %d creations and %d deletions", key, exp.add, exp.deleteUIDs.Len())
+	return false
+}
+
+// ExpectCreations records that count Machines are about to be created for the MachineSet.
+func (u *uidTrackingExpectations) ExpectCreations(key types.NamespacedName, count int) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.store[key] = &machineSetExpectations{
+		add:        int64(count),
+		deleteUIDs: sets.NewString(),
+		timestamp:  u.now(),
+	}
+}
+
+// CreationObserved lowers the creation expectations of the MachineSet by one.
+// It is called when a watch event for a new Machine is received, or when a creation fails.
+func (u *uidTrackingExpectations) CreationObserved(key types.NamespacedName) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if exp, ok := u.store[key]; ok && exp.add > 0 {
+		exp.add--
+	}
+}
+
+// ExpectDeletions records the UIDs of the Machines that are about to be deleted for the MachineSet.
+func (u *uidTrackingExpectations) ExpectDeletions(key types.NamespacedName, uids []string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.store[key] = &machineSetExpectations{
+		deleteUIDs: sets.NewString(uids...),
+		timestamp:  u.now(),
+	}
+}
+
+// DeletionObserved records that the deletion of the Machine with the given UID has been observed.
+// It is called when a watch event shows the Machine deleting, or when a deletion fails.
+func (u *uidTrackingExpectations) DeletionObserved(key types.NamespacedName, uid string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if exp, ok := u.store[key]; ok {
+		exp.deleteUIDs.Delete(uid)
+	}
+}
+
+// DeleteExpectations removes any expectations recorded for the MachineSet.
+func (u *uidTrackingExpectations) DeleteExpectations(key types.NamespacedName) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	delete(u.store, key)
+}
+
+// machineSetKeyForMachine returns the key of the MachineSet controlling the Machine, if any.
+func machineSetKeyForMachine(o client.Object) (types.NamespacedName, bool) {
+	ref := metav1.GetControllerOf(o)
+	if ref == nil || ref.Kind != controllerKind.Kind {
+		return types.NamespacedName{}, false
+	}
+
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil || gv.Group != controllerKind.Group {
+		return types.NamespacedName{}, false
+	}
+
+	return types.NamespacedName{Namespace: o.GetNamespace(), Name: ref.Name}, true
+}
+
+// machineExpectationsHandler enqueues the controlling MachineSet of a Machine, in the
+// same way as handler.EnqueueRequestForOwner, after recording the creation or deletion
+// of the Machine against the expectations of the MachineSet.
+type machineExpectationsHandler struct {
+	expectations *uidTrackingExpectations
+}
+
+var _ handler.EventHandler = &machineExpectationsHandler{}
+
+// Create implements handler.EventHandler.
+func (h *machineExpectationsHandler) Create(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+	key, ok := machineSetKeyForMachine(evt.Object)
+	if !ok {
+		return
+	}
+
+	if evt.Object.GetDeletionTimestamp() != nil {
+		// A Machine may be observed as deleting straight away when the cache resyncs.
+		h.expectations.DeletionObserved(key, string(evt.Object.GetUID()))
+	} else {
+		h.expectations.CreationObserved(key)
+	}
+	q.Add(reconcile.Request{NamespacedName: key})
+}
+
+// Update implements handler.EventHandler.
+func (h *machineExpectationsHandler) Update(evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	oldKey, oldOK := machineSetKeyForMachine(evt.ObjectOld)
+	newKey, newOK := machineSetKeyForMachine(evt.ObjectNew)
+
+	if newOK {
+		if evt.ObjectNew.GetDeletionTimestamp() != nil && evt.ObjectOld.GetDeletionTimestamp() == nil {
+			// Machines have finalizers, so a deletion is observed as soon as the
+			// deletion timestamp is set rather than when the object is removed.
+			h.expectations.DeletionObserved(newKey, string(evt.ObjectNew.GetUID()))
+		}
+		q.Add(reconcile.Request{NamespacedName: newKey})
+	}
+
+	if oldOK && (!newOK || oldKey != newKey) {
+		q.Add(reconcile.Request{NamespacedName: oldKey})
+	}
+}
+
+// Delete implements handler.EventHandler.
+func (h *machineExpectationsHandler) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	key, ok := machineSetKeyForMachine(evt.Object)
+	if !ok {
+		return
+	}
+
+	h.expectations.DeletionObserved(key, string(evt.Object.GetUID()))
+	q.Add(reconcile.Request{NamespacedName: key})
+}
+
+// Generic implements handler.EventHandler.
+func (h *machineExpectationsHandler) Generic(evt event.GenericEvent, q workqueue.RateLimitingInterface) {
+	if key, ok := machineSetKeyForMachine(evt.Object); ok {
+		q.Add(reconcile.Request{NamespacedName: key})
+	}
+}
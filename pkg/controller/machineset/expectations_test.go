@@ -0,0 +1,122 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestUIDTrackingExpectations(t *testing.T) {
+	key := types.NamespacedName{Namespace: "test", Name: "ms"}
+	now := time.Now()
+	exp := newUIDTrackingExpectations()
+	exp.nowFunc = func() time.Time { return now }
+
+	if !exp.SatisfiedExpectations(key) {
+		t.Fatal("expected expectations to be satisfied when none were recorded")
+	}
+
+	exp.ExpectCreations(key, 2)
+	if exp.SatisfiedExpectations(key) {
+		t.Fatal("expected expectations not to be satisfied with pending creations")
+	}
+
+	exp.CreationObserved(key)
+	if exp.SatisfiedExpectations(key) {
+		t.Fatal("expected expectations not to be satisfied with one pending creation")
+	}
+
+	exp.CreationObserved(key)
+	if !exp.SatisfiedExpectations(key) {
+		t.Fatal("expected expectations to be satisfied once all creations are observed")
+	}
+
+	exp.ExpectDeletions(key, []string{"a", "b"})
+	exp.DeletionObserved(key, "a")
+	exp.DeletionObserved(key, "a")
+	if exp.SatisfiedExpectations(key) {
+		t.Fatal("expected expectations not to be satisfied with a pending deletion")
+	}
+
+	now = now.Add(expectationsTimeout + time.Second)
+	if !exp.SatisfiedExpectations(key) {
+		t.Fatal("expected expectations to be satisfied once expired")
+	}
+
+	exp.ExpectDeletions(key, []string{"c"})
+	exp.DeleteExpectations(key)
+	if !exp.SatisfiedExpectations(key) {
+		t.Fatal("expected expectations to be satisfied once deleted")
+	}
+}
+
+func TestMachineExpectationsHandler(t *testing.T) {
+	key := types.NamespacedName{Namespace: "test", Name: "ms"}
+	controller := true
+	machine := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "machine",
+			Namespace: "test",
+			UID:       "machine-uid",
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: machinev1.SchemeGroupVersion.String(),
+					Kind:       "MachineSet",
+					Name:       "ms",
+					Controller: &controller,
+				},
+			},
+		},
+	}
+
+	exp := newUIDTrackingExpectations()
+	h := &machineExpectationsHandler{expectations: exp}
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+
+	exp.ExpectCreations(key, 1)
+	h.Create(event.CreateEvent{Object: machine}, q)
+	if !exp.SatisfiedExpectations(key) {
+		t.Error("expected the creation to be observed")
+	}
+	if q.Len() != 1 {
+		t.Errorf("expected the owning machineset to be enqueued, queue length: %d", q.Len())
+	}
+
+	exp.ExpectDeletions(key, []string{"machine-uid"})
+	deleting := machine.DeepCopy()
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+	h.Update(event.UpdateEvent{ObjectOld: machine, ObjectNew: deleting}, q)
+	if !exp.SatisfiedExpectations(key) {
+		t.Error("expected the deletion to be observed")
+	}
+
+	orphan := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "orphan", Namespace: "test"}}
+	exp.ExpectCreations(key, 1)
+	h.Create(event.CreateEvent{Object: orphan}, q)
+	if exp.SatisfiedExpectations(key) {
+		t.Error("expected creations of machines not owned by the machineset to be ignored")
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// FailureDomainsAnnotation lists the failure domains the Machines of a MachineSet are spread across,
+// as a comma separated list, e.g. "us-east-1a,us-east-1b,us-east-1c".
+// Each failure domain is a zone, optionally followed by a subnet for that zone, e.g. "us-east-1a/subnet-0123".
+// New Machines are created in the failure domain with the fewest Machines, and the failure domain is
+// stamped into the providerSpec of the Machine.
+// Subnets are supported on AWS, where they are the subnet ID, and on Azure, where they are the subnet name.
+const FailureDomainsAnnotation = "machine.openshift.io/failure-domains"
+
+// FailureDomain is a zone, and optionally a subnet, in which a Machine is created.
+type FailureDomain struct {
+	Zone   string
+	Subnet string
+}
+
+// String returns the failure domain as it is set in the FailureDomainsAnnotation.
+func (f FailureDomain) String() string {
+	if f.Subnet == "" {
+		return f.Zone
+	}
+	return f.Zone + "/" + f.Subnet
+}
+
+// getFailureDomains returns the failure domains of the MachineSet, or nil when none are set.
+func getFailureDomains(ms *machinev1.MachineSet) ([]FailureDomain, error) {
+	return ParseFailureDomains(ms.Annotations[FailureDomainsAnnotation])
+}
+
+// ParseFailureDomains parses the value of the FailureDomainsAnnotation, returning nil when it is empty.
+func ParseFailureDomains(value string) ([]FailureDomain, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	var domains []FailureDomain
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		zone, subnet, _ := strings.Cut(entry, "/")
+		if zone == "" || strings.Contains(subnet, "/") {
+			return nil, fmt.Errorf("invalid %s annotation %q: failure domains must be of the form zone or zone/subnet", FailureDomainsAnnotation, value)
+		}
+		domains = append(domains, FailureDomain{Zone: zone, Subnet: subnet})
+	}
+
+	return domains, nil
+}
+
+// failureDomainPicker chooses the failure domain of new Machines, spreading
+// the Machines of a MachineSet evenly across its failure domains.
+type failureDomainPicker struct {
+	domains []FailureDomain
+	counts  map[string]int
+}
+
+// newFailureDomainPicker returns a picker for the failure domains of the MachineSet, taking
+// into account the zones of the existing Machines.
+// It returns nil when the MachineSet has no failure domains.
+func newFailureDomainPicker(ms *machinev1.MachineSet, machines []*machinev1.Machine) (*failureDomainPicker, error) {
+	domains, err := getFailureDomains(ms)
+	if err != nil || domains == nil {
+		return nil, err
+	}
+
+	p := &failureDomainPicker{
+		domains: domains,
+		counts:  make(map[string]int),
+	}
+	for _, machine := range machines {
+		spec, err := providerSpecAsMap(machine.Spec.ProviderSpec.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get zone of machine %s: %w", machine.Name, err)
+		}
+		if zone := getProviderSpecZone(spec); zone != "" {
+			p.counts[zone]++
+		}
+	}
+
+	return p, nil
+}
+
+// next returns the failure domain with the fewest Machines, in the order of the annotation on ties,
+// and records a Machine against it.
+func (p *failureDomainPicker) next() FailureDomain {
+	best := p.domains[0]
+	for _, domain := range p.domains[1:] {
+		if p.counts[domain.Zone] < p.counts[best.Zone] {
+			best = domain
+		}
+	}
+	p.counts[best.Zone]++
+	return best
+}
+
+// setFailureDomain stamps the failure domain into the providerSpec of the Machine.
+func setFailureDomain(machine *machinev1.Machine, domain FailureDomain) error {
+	spec, err := providerSpecAsMap(machine.Spec.ProviderSpec.Value)
+	if err != nil {
+		return err
+	}
+
+	switch spec["kind"] {
+	case "AWSMachineProviderConfig":
+		placement, _ := spec["placement"].(map[string]interface{})
+		if placement == nil {
+			placement = map[string]interface{}{}
+		}
+		placement["availabilityZone"] = domain.Zone
+		spec["placement"] = placement
+		if domain.Subnet != "" {
+			spec["subnet"] = map[string]interface{}{"id": domain.Subnet}
+		}
+	case "AzureMachineProviderSpec":
+		spec["zone"] = domain.Zone
+		if domain.Subnet != "" {
+			spec["subnet"] = domain.Subnet
+		}
+	case "GCPMachineProviderSpec":
+		if domain.Subnet != "" {
+			return fmt.Errorf("failure domain %q: subnets are not supported on GCP", domain)
+		}
+		spec["zone"] = domain.Zone
+	default:
+		return fmt.Errorf("failure domains are not supported for providerSpec kind %q", spec["kind"])
+	}
+
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal providerSpec: %w", err)
+	}
+	// The providerSpec value is shared with the MachineSet template, replace it rather than modify it.
+	machine.Spec.ProviderSpec.Value = &runtime.RawExtension{Raw: raw}
+
+	return nil
+}
+
+// getProviderSpecZone returns the zone set in the providerSpec, if any.
+func getProviderSpecZone(spec map[string]interface{}) string {
+	switch spec["kind"] {
+	case "AWSMachineProviderConfig":
+		placement, _ := spec["placement"].(map[string]interface{})
+		zone, _ := placement["availabilityZone"].(string)
+		return zone
+	case "AzureMachineProviderSpec", "GCPMachineProviderSpec":
+		zone, _ := spec["zone"].(string)
+		return zone
+	default:
+		return ""
+	}
+}
+
+func providerSpecAsMap(providerSpec *runtime.RawExtension) (map[string]interface{}, error) {
+	spec := map[string]interface{}{}
+	if providerSpec == nil || len(providerSpec.Raw) == 0 {
+		return spec, nil
+	}
+	if err := json.Unmarshal(providerSpec.Raw, &spec); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal providerSpec: %w", err)
+	}
+	return spec, nil
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func machineWithProviderSpec(raw string) *machinev1.Machine {
+	return &machinev1.Machine{
+		Spec: machinev1.MachineSpec{
+			ProviderSpec: machinev1.ProviderSpec{Value: &runtime.RawExtension{Raw: []byte(raw)}},
+		},
+	}
+}
+
+func TestGetFailureDomains(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotation  string
+		expected    []FailureDomain
+		expectedErr bool
+	}{
+		{
+			name:     "with no annotation",
+			expected: nil,
+		},
+		{
+			name:       "with zones and subnets",
+			annotation: "us-east-1a, us-east-1b/subnet-1",
+			expected:   []FailureDomain{{Zone: "us-east-1a"}, {Zone: "us-east-1b", Subnet: "subnet-1"}},
+		},
+		{
+			name:        "with an empty zone",
+			annotation:  "us-east-1a,,us-east-1b",
+			expectedErr: true,
+		},
+		{
+			name:        "with too many separators",
+			annotation:  "us-east-1a/subnet-1/extra",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{FailureDomainsAnnotation: tc.annotation}}}
+			domains, err := getFailureDomains(ms)
+			if tc.expectedErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(domains).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestFailureDomainPicker(t *testing.T) {
+	g := NewWithT(t)
+
+	ms := &machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{FailureDomainsAnnotation: "a,b,c"}}}
+	machines := []*machinev1.Machine{
+		machineWithProviderSpec(`{"kind":"GCPMachineProviderSpec","zone":"a"}`),
+		machineWithProviderSpec(`{"kind":"GCPMachineProviderSpec","zone":"a"}`),
+		machineWithProviderSpec(`{"kind":"GCPMachineProviderSpec","zone":"c"}`),
+		machineWithProviderSpec(`{"kind":"GCPMachineProviderSpec","zone":"elsewhere"}`),
+	}
+
+	picker, err := newFailureDomainPicker(ms, machines)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	var zones []string
+	for i := 0; i < 5; i++ {
+		zones = append(zones, picker.next().Zone)
+	}
+	g.Expect(zones).To(Equal([]string{"b", "b", "c", "a", "b"}))
+
+	picker, err = newFailureDomainPicker(&machinev1.MachineSet{}, machines)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(picker).To(BeNil())
+}
+
+func TestSetFailureDomain(t *testing.T) {
+	testCases := []struct {
+		name         string
+		providerSpec string
+		domain       FailureDomain
+		expected     map[string]interface{}
+		expectedErr  bool
+	}{
+		{
+			name:         "on AWS",
+			providerSpec: `{"kind":"AWSMachineProviderConfig","instanceType":"m5.large","placement":{"region":"us-east-1","availabilityZone":"us-east-1a"}}`,
+			domain:       FailureDomain{Zone: "us-east-1b", Subnet: "subnet-1"},
+			expected: map[string]interface{}{
+				"kind":         "AWSMachineProviderConfig",
+				"instanceType": "m5.large",
+				"placement":    map[string]interface{}{"region": "us-east-1", "availabilityZone": "us-east-1b"},
+				"subnet":       map[string]interface{}{"id": "subnet-1"},
+			},
+		},
+		{
+			name:         "on Azure",
+			providerSpec: `{"kind":"AzureMachineProviderSpec","vmSize":"Standard_D4s_v3"}`,
+			domain:       FailureDomain{Zone: "2"},
+			expected: map[string]interface{}{
+				"kind":   "AzureMachineProviderSpec",
+				"vmSize": "Standard_D4s_v3",
+				"zone":   "2",
+			},
+		},
+		{
+			name:         "on GCP",
+			providerSpec: `{"kind":"GCPMachineProviderSpec","zone":"us-central1-a"}`,
+			domain:       FailureDomain{Zone: "us-central1-b"},
+			expected: map[string]interface{}{
+				"kind": "GCPMachineProviderSpec",
+				"zone": "us-central1-b",
+			},
+		},
+		{
+			name:         "with a subnet on GCP",
+			providerSpec: `{"kind":"GCPMachineProviderSpec","zone":"us-central1-a"}`,
+			domain:       FailureDomain{Zone: "us-central1-b", Subnet: "subnet-1"},
+			expectedErr:  true,
+		},
+		{
+			name:         "on an unsupported platform",
+			providerSpec: `{"kind":"VSphereMachineProviderSpec"}`,
+			domain:       FailureDomain{Zone: "a"},
+			expectedErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			machine := machineWithProviderSpec(tc.providerSpec)
+			original := machine.Spec.ProviderSpec.Value
+
+			err := setFailureDomain(machine, tc.domain)
+			if tc.expectedErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+
+			got := map[string]interface{}{}
+			g.Expect(json.Unmarshal(machine.Spec.ProviderSpec.Value.Raw, &got)).To(Succeed())
+			g.Expect(got).To(Equal(tc.expected))
+			// The original providerSpec, shared with the MachineSet, must not be modified.
+			g.Expect(string(original.Raw)).To(Equal(tc.providerSpec))
+		})
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/schedule"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// HibernateScheduleAnnotation is a cron expression at which the MachineSet is scaled to zero replicas.
+	HibernateScheduleAnnotation = "machine.openshift.io/hibernate-schedule"
+	// WakeUpScheduleAnnotation is a cron expression at which the MachineSet is scaled back to the
+	// number of replicas it had before hibernating.
+	WakeUpScheduleAnnotation = "machine.openshift.io/wakeup-schedule"
+	// HibernationTimeZoneAnnotation is the IANA time zone the schedules are evaluated in, e.g. "Europe/Prague".
+	// Schedules are evaluated in UTC when it is not set.
+	HibernationTimeZoneAnnotation = "machine.openshift.io/hibernation-timezone"
+	// HibernatedReplicasAnnotation records the number of replicas of a hibernated MachineSet.
+	// It is set by the controller, and removed when the replicas are restored.
+	HibernatedReplicasAnnotation = "machine.openshift.io/hibernated-replicas"
+
+	hibernationControllerName = "machineset_hibernation_controller"
+)
+
+// hibernationSchedule is the hibernation configuration of a MachineSet.
+type hibernationSchedule struct {
+	hibernate *schedule.Schedule
+	wakeUp    *schedule.Schedule
+	location  *time.Location
+}
+
+// getHibernationSchedule returns the hibernation schedule of the MachineSet, or nil when it has none.
+func getHibernationSchedule(ms *machinev1.MachineSet) (*hibernationSchedule, error) {
+	hibernateSpec, hasHibernate := ms.Annotations[HibernateScheduleAnnotation]
+	wakeUpSpec, hasWakeUp := ms.Annotations[WakeUpScheduleAnnotation]
+	if !hasHibernate && !hasWakeUp {
+		return nil, nil
+	}
+	if !hasHibernate || !hasWakeUp {
+		return nil, fmt.Errorf("both %s and %s annotations must be set", HibernateScheduleAnnotation, WakeUpScheduleAnnotation)
+	}
+
+	s := &hibernationSchedule{location: time.UTC}
+	var err error
+	if s.hibernate, err = schedule.Parse(hibernateSpec); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", HibernateScheduleAnnotation, err)
+	}
+	if s.wakeUp, err = schedule.Parse(wakeUpSpec); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", WakeUpScheduleAnnotation, err)
+	}
+	if tz, ok := ms.Annotations[HibernationTimeZoneAnnotation]; ok {
+		if s.location, err = time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", HibernationTimeZoneAnnotation, err)
+		}
+	}
+
+	return s, nil
+}
+
+// AddHibernation creates a new MachineSet hibernation Controller and adds it to the Manager.
+// The controller scales MachineSets to zero replicas, and back, following the schedules in their annotations.
+func AddHibernation(mgr manager.Manager, opts manager.Options) error {
+	r := &ReconcileHibernation{
+		Client:   mgr.GetClient(),
+		recorder: mgr.GetEventRecorderFor(hibernationControllerName),
+	}
+
+	c, err := controller.New(hibernationControllerName, mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	return c.Watch(&source.Kind{Type: &machinev1.MachineSet{}}, &handler.EnqueueRequestForObject{})
+}
+
+// ReconcileHibernation reconciles the replicas of MachineSets with a hibernation schedule.
+type ReconcileHibernation struct {
+	client.Client
+	recorder record.EventRecorder
+
+	// nowFunc is used to mock time in testing. It should be nil in production.
+	nowFunc func() time.Time
+}
+
+func (r *ReconcileHibernation) now() time.Time {
+	if r.nowFunc != nil {
+		return r.nowFunc()
+	}
+	return time.Now()
+}
+
+// Reconcile scales the MachineSet to zero replicas when the last hibernate time of its schedule is more
+// recent than the last wake up time, recording its replicas, and restores them otherwise.
+func (r *ReconcileHibernation) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	machineSet := &machinev1.MachineSet{}
+	if err := r.Get(ctx, request.NamespacedName, machineSet); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if machineSet.DeletionTimestamp != nil {
+		return reconcile.Result{}, nil
+	}
+
+	if managedBy, ok := machineSet.Annotations[ReplicasManagedByAnnotation]; ok {
+		klog.V(4).Infof("%v: replicas are managed by %q, ignoring hibernation schedule", machineSet.Name, managedBy)
+		return reconcile.Result{}, nil
+	}
+
+	hibernation, err := getHibernationSchedule(machineSet)
+	if err != nil {
+		// The MachineSet will be reconciled again once the annotations are fixed.
+		klog.Warningf("%v: %v", machineSet.Name, err)
+		r.recorder.Eventf(machineSet, corev1.EventTypeWarning, "InvalidHibernationSchedule", "%v", err)
+		return reconcile.Result{}, nil
+	}
+
+	now := r.now()
+	hibernate := false
+	var requeueAfter time.Duration
+	if hibernation != nil {
+		now = now.In(hibernation.location)
+		lastHibernate := hibernation.hibernate.Prev(now)
+		lastWakeUp := hibernation.wakeUp.Prev(now)
+		hibernate = !lastHibernate.IsZero() && lastHibernate.After(lastWakeUp)
+
+		// Requeue at the next time either schedule fires.
+		next := hibernation.hibernate.Next(now)
+		if wakeUp := hibernation.wakeUp.Next(now); next.IsZero() || (!wakeUp.IsZero() && wakeUp.Before(next)) {
+			next = wakeUp
+		}
+		if !next.IsZero() {
+			requeueAfter = next.Sub(now)
+		}
+	}
+
+	if err := r.syncHibernation(ctx, machineSet, hibernate); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// syncHibernation scales the MachineSet to zero replicas if it should be hibernating,
+// or restores its replicas if it should not be.
+func (r *ReconcileHibernation) syncHibernation(ctx context.Context, ms *machinev1.MachineSet, hibernate bool) error {
+	hibernatedReplicas, hibernated := ms.Annotations[HibernatedReplicasAnnotation]
+	if hibernate == hibernated {
+		return nil
+	}
+
+	patchBase := client.MergeFrom(ms.DeepCopy())
+	if hibernate {
+		var replicas int32
+		if ms.Spec.Replicas != nil {
+			replicas = *ms.Spec.Replicas
+		}
+		if ms.Annotations == nil {
+			ms.Annotations = make(map[string]string)
+		}
+		ms.Annotations[HibernatedReplicasAnnotation] = strconv.Itoa(int(replicas))
+		ms.Spec.Replicas = pointer.Int32(0)
+
+		klog.Infof("%v: hibernating, scaling from %d to 0 replicas", ms.Name, replicas)
+		r.recorder.Eventf(ms, corev1.EventTypeNormal, "Hibernating", "Scaling from %d to 0 replicas", replicas)
+	} else {
+		replicas, err := strconv.ParseInt(hibernatedReplicas, 10, 32)
+		if err != nil || replicas < 0 {
+			// Without the previous replicas the MachineSet is left as it is.
+			klog.Warningf("%v: invalid %s annotation %q, not restoring replicas", ms.Name, HibernatedReplicasAnnotation, hibernatedReplicas)
+			r.recorder.Eventf(ms, corev1.EventTypeWarning, "InvalidHibernatedReplicas", "Invalid %s annotation %q, not restoring replicas", HibernatedReplicasAnnotation, hibernatedReplicas)
+		} else {
+			ms.Spec.Replicas = pointer.Int32(int32(replicas))
+			klog.Infof("%v: waking up, restoring %d replicas", ms.Name, replicas)
+			r.recorder.Eventf(ms, corev1.EventTypeNormal, "WakingUp", "Restoring %d replicas", replicas)
+		}
+		delete(ms.Annotations, HibernatedReplicasAnnotation)
+	}
+
+	if err := r.Patch(ctx, ms, patchBase); err != nil {
+		return fmt.Errorf("failed to update hibernation of MachineSet %s: %w", ms.Name, err)
+	}
+	return nil
+}
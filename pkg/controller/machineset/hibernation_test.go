@@ -0,0 +1,163 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileHibernation(t *testing.T) {
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+
+	businessHours := map[string]string{
+		HibernateScheduleAnnotation: "0 19 * * mon-fri",
+		WakeUpScheduleAnnotation:    "0 7 * * mon-fri",
+	}
+	withAnnotations := func(base map[string]string, extra map[string]string) map[string]string {
+		annotations := map[string]string{}
+		for k, v := range base {
+			annotations[k] = v
+		}
+		for k, v := range extra {
+			annotations[k] = v
+		}
+		return annotations
+	}
+
+	// Wednesday.
+	evening := time.Date(2023, 3, 15, 20, 0, 0, 0, time.UTC)
+	morning := time.Date(2023, 3, 15, 8, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name                string
+		now                 time.Time
+		annotations         map[string]string
+		replicas            int32
+		expectedReplicas    int32
+		expectedAnnotations map[string]string
+		expectedRequeue     time.Duration
+	}{
+		{
+			name:                "without a schedule",
+			now:                 evening,
+			annotations:         map[string]string{},
+			replicas:            3,
+			expectedReplicas:    3,
+			expectedAnnotations: map[string]string{},
+		},
+		{
+			name:                "hibernates outside business hours",
+			now:                 evening,
+			annotations:         businessHours,
+			replicas:            3,
+			expectedReplicas:    0,
+			expectedAnnotations: withAnnotations(businessHours, map[string]string{HibernatedReplicasAnnotation: "3"}),
+			expectedRequeue:     11 * time.Hour,
+		},
+		{
+			name:                "stays hibernated",
+			now:                 evening,
+			annotations:         withAnnotations(businessHours, map[string]string{HibernatedReplicasAnnotation: "3"}),
+			replicas:            0,
+			expectedReplicas:    0,
+			expectedAnnotations: withAnnotations(businessHours, map[string]string{HibernatedReplicasAnnotation: "3"}),
+			expectedRequeue:     11 * time.Hour,
+		},
+		{
+			name:                "wakes up during business hours",
+			now:                 morning,
+			annotations:         withAnnotations(businessHours, map[string]string{HibernatedReplicasAnnotation: "3"}),
+			replicas:            0,
+			expectedReplicas:    3,
+			expectedAnnotations: businessHours,
+			expectedRequeue:     11 * time.Hour,
+		},
+		{
+			name:                "evaluates the schedule in the time zone",
+			now:                 morning,
+			annotations:         withAnnotations(businessHours, map[string]string{HibernationTimeZoneAnnotation: "America/Los_Angeles"}),
+			replicas:            3,
+			expectedReplicas:    0,
+			expectedAnnotations: withAnnotations(businessHours, map[string]string{HibernationTimeZoneAnnotation: "America/Los_Angeles", HibernatedReplicasAnnotation: "3"}),
+			expectedRequeue:     6 * time.Hour,
+		},
+		{
+			name:                "restores the replicas when the schedule is removed",
+			now:                 evening,
+			annotations:         map[string]string{HibernatedReplicasAnnotation: "3"},
+			replicas:            0,
+			expectedReplicas:    3,
+			expectedAnnotations: map[string]string{},
+		},
+		{
+			name:                "ignores an invalid schedule",
+			now:                 evening,
+			annotations:         map[string]string{HibernateScheduleAnnotation: "0 19 * * mon-fri"},
+			replicas:            3,
+			expectedReplicas:    3,
+			expectedAnnotations: map[string]string{HibernateScheduleAnnotation: "0 19 * * mon-fri"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "machineset1",
+					Namespace:   "default",
+					Annotations: tc.annotations,
+				},
+				Spec: machinev1.MachineSetSpec{Replicas: pointer.Int32(tc.replicas)},
+			}
+
+			r := &ReconcileHibernation{
+				Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ms).Build(),
+				recorder: record.NewFakeRecorder(32),
+				nowFunc:  func() time.Time { return tc.now },
+			}
+
+			result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(ms)})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(result.RequeueAfter).To(Equal(tc.expectedRequeue))
+
+			got := &machinev1.MachineSet{}
+			g.Expect(r.Get(context.Background(), client.ObjectKeyFromObject(ms), got)).To(Succeed())
+			g.Expect(*got.Spec.Replicas).To(Equal(tc.expectedReplicas))
+			if len(tc.expectedAnnotations) == 0 {
+				g.Expect(got.Annotations).To(BeEmpty())
+			} else {
+				g.Expect(got.Annotations).To(Equal(tc.expectedAnnotations))
+			}
+		})
+	}
+}
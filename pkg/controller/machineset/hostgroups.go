@@ -0,0 +1,74 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+)
+
+// hostGroupPicker chooses the host group of new Machines, spreading the Machines of a MachineSet
+// round-robin across its host groups.
+type hostGroupPicker struct {
+	groups []string
+	counts map[string]int
+}
+
+// newHostGroupPicker returns a picker for the host groups of the MachineSet, taking into account the host
+// groups of the existing Machines. It returns nil when the MachineSet has no host groups.
+func newHostGroupPicker(ms *machinev1.MachineSet, machines []*machinev1.Machine) *hostGroupPicker {
+	groups := annotations.GetHostGroups(ms)
+	if groups == nil {
+		return nil
+	}
+
+	p := &hostGroupPicker{
+		groups: groups,
+		counts: make(map[string]int),
+	}
+	for _, machine := range machines {
+		if _, group := annotations.GetHostAffinity(machine); group != "" {
+			p.counts[group]++
+		}
+	}
+	return p
+}
+
+// next returns the host group with the fewest Machines, in the order of the annotation on ties,
+// and records a Machine against it.
+func (p *hostGroupPicker) next() string {
+	best := p.groups[0]
+	for _, group := range p.groups[1:] {
+		if p.counts[group] < p.counts[best] {
+			best = group
+		}
+	}
+	p.counts[best]++
+	return best
+}
+
+// setHostGroup pins the Machine to the host group.
+func setHostGroup(machine *machinev1.Machine, group string) {
+	// The annotations are shared with the MachineSet template, replace them rather than modify them.
+	machineAnnotations := make(map[string]string, len(machine.Annotations)+1)
+	for k, v := range machine.Annotations {
+		machineAnnotations[k] = v
+	}
+	machineAnnotations[annotations.HostGroupAnnotation] = group
+	machine.Annotations = machineAnnotations
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+)
+
+func TestHostGroupPicker(t *testing.T) {
+	machineInGroup := func(group string) *machinev1.Machine {
+		return &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{annotations.HostGroupAnnotation: group}}}
+	}
+
+	testCases := []struct {
+		name       string
+		annotation string
+		machines   []*machinev1.Machine
+		expected   []string
+	}{
+		{
+			name:     "with no host groups",
+			expected: nil,
+		},
+		{
+			name:       "with no machines",
+			annotation: "group-a, group-b",
+			expected:   []string{"group-a", "group-b", "group-a", "group-b"},
+		},
+		{
+			name:       "with machines in some host groups",
+			annotation: "group-a,group-b,group-c",
+			machines: []*machinev1.Machine{
+				machineInGroup("group-a"),
+				machineInGroup("group-a"),
+				machineInGroup("group-b"),
+				machineInGroup("other"),
+				{},
+			},
+			expected: []string{"group-c", "group-b", "group-c", "group-a"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1.MachineSet{}
+			if tc.annotation != "" {
+				ms.Annotations = map[string]string{annotations.HostGroupsAnnotation: tc.annotation}
+			}
+
+			picker := newHostGroupPicker(ms, tc.machines)
+			if tc.expected == nil {
+				g.Expect(picker).To(BeNil())
+				return
+			}
+			var picked []string
+			for range tc.expected {
+				picked = append(picked, picker.next())
+			}
+			g.Expect(picked).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestSetHostGroup(t *testing.T) {
+	g := NewWithT(t)
+
+	templateAnnotations := map[string]string{"example.com/annotation": "value"}
+	machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Annotations: templateAnnotations}}
+
+	setHostGroup(machine, "group-a")
+	g.Expect(machine.Annotations).To(Equal(map[string]string{
+		"example.com/annotation":        "value",
+		annotations.HostGroupAnnotation: "group-a",
+	}))
+	// The annotations of the MachineSet template are left untouched.
+	g.Expect(templateAnnotations).To(HaveLen(1))
+}
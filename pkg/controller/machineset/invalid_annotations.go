@@ -0,0 +1,76 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"sync"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// invalidAnnotations tracks the errors of the invalid annotations of the MachineSets which have been reported, so
+// that an annotation rejected by the webhook, but set while the webhook was bypassed, is reported once rather than
+// on every reconcile. The zero value is ready to use.
+type invalidAnnotations struct {
+	lock sync.Mutex
+
+	// reported maps each MachineSet to the errors of its invalid annotations which have been reported.
+	reported map[types.NamespacedName]map[string]string
+}
+
+// shouldReport returns true the first time the annotation of the MachineSet is invalid with the error message.
+func (a *invalidAnnotations) shouldReport(key types.NamespacedName, annotation, message string) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.reported == nil {
+		a.reported = make(map[types.NamespacedName]map[string]string)
+	}
+	messages, ok := a.reported[key]
+	if !ok {
+		messages = make(map[string]string)
+		a.reported[key] = messages
+	}
+	if reported, ok := messages[annotation]; ok && reported == message {
+		return false
+	}
+	messages[annotation] = message
+	return true
+}
+
+// forget drops the reported annotations of the MachineSet.
+func (a *invalidAnnotations) forget(key types.NamespacedName) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	delete(a.reported, key)
+}
+
+// reportInvalidAnnotation logs the error of the invalid annotation of the MachineSet and records a Warning event with
+// the reason, once for each error, which tells the invalid value.
+func (r *ReconcileMachineSet) reportInvalidAnnotation(ms *machinev1.MachineSet, annotation, reason string, err error) {
+	key := types.NamespacedName{Namespace: ms.Namespace, Name: ms.Name}
+	if !r.invalidAnnotations.shouldReport(key, annotation, err.Error()) {
+		klog.V(4).Infof("%v: %v", ms.Name, err)
+		return
+	}
+	klog.Warningf("%v: %v", ms.Name, err)
+	r.recorder.Eventf(ms, corev1.EventTypeWarning, reason, "%v", err)
+}
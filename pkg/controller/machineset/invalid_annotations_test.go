@@ -0,0 +1,41 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestInvalidAnnotations(t *testing.T) {
+	g := NewWithT(t)
+
+	var a invalidAnnotations
+	key := types.NamespacedName{Namespace: "default", Name: "machineset1"}
+	other := types.NamespacedName{Namespace: "default", Name: "machineset2"}
+
+	g.Expect(a.shouldReport(key, ProvisioningTimeoutAnnotation, "soon")).To(BeTrue())
+	g.Expect(a.shouldReport(key, ProvisioningTimeoutAnnotation, "soon")).To(BeFalse())
+	g.Expect(a.shouldReport(other, ProvisioningTimeoutAnnotation, "soon")).To(BeTrue())
+	g.Expect(a.shouldReport(key, ProvisioningTimeoutAnnotation, "later")).To(BeTrue())
+
+	a.forget(key)
+	g.Expect(a.shouldReport(key, ProvisioningTimeoutAnnotation, "later")).To(BeTrue())
+	g.Expect(a.shouldReport(other, ProvisioningTimeoutAnnotation, "soon")).To(BeFalse())
+}
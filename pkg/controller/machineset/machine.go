@@ -18,6 +18,9 @@ package machineset
 
 import (
 	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
 
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -26,22 +29,97 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	// machineOwnerIndex indexes Machines by the UID of their controlling owner.
+	machineOwnerIndex = "machineOwnerIndex"
+
+	// machineSetSelectorIndex indexes MachineSets by a hash of each key/value pair
+	// in their selector's matchLabels, so that the MachineSets which may select a
+	// Machine can be found from the Machine's labels without listing every MachineSet.
+	machineSetSelectorIndex = "machineSetSelectorIndex"
+
+	// expressionsOnlySelectorHash is the index value used for MachineSets whose selector
+	// has no matchLabels. These cannot be narrowed down by label and are always considered.
+	expressionsOnlySelectorHash = "*"
+)
+
+// indexMachineByOwner returns the UID of the controller owner of the machine, if any.
+func indexMachineByOwner(object client.Object) []string {
+	machine, ok := object.(*machinev1.Machine)
+	if !ok {
+		klog.Warningf("Expected a machine for indexing field, got: %T", object)
+		return nil
+	}
+
+	if ref := metav1.GetControllerOf(machine); ref != nil {
+		return []string{string(ref.UID)}
+	}
+
+	return nil
+}
+
+// indexMachineSetBySelector returns the selector hashes for the matchLabels of the machineset.
+func indexMachineSetBySelector(object client.Object) []string {
+	machineSet, ok := object.(*machinev1.MachineSet)
+	if !ok {
+		klog.Warningf("Expected a machineset for indexing field, got: %T", object)
+		return nil
+	}
+
+	if len(machineSet.Spec.Selector.MatchLabels) == 0 {
+		return []string{expressionsOnlySelectorHash}
+	}
+
+	keys := make([]string, 0, len(machineSet.Spec.Selector.MatchLabels))
+	for k, v := range machineSet.Spec.Selector.MatchLabels {
+		keys = append(keys, selectorHash(k, v))
+	}
+
+	return keys
+}
+
+// selectorHash returns a stable, field selector safe representation of a single label pair.
+func selectorHash(key, value string) string {
+	hasher := fnv.New64a()
+	// Writes to a hash never return an error.
+	_, _ = hasher.Write([]byte(fmt.Sprintf("%s=%s", key, value)))
+	return fmt.Sprintf("%x", hasher.Sum64())
+}
+
 func (c *ReconcileMachineSet) getMachineSetsForMachine(m *machinev1.Machine) []*machinev1.MachineSet {
 	if len(m.Labels) == 0 {
 		klog.Warningf("No machine sets found for Machine %v because it has no labels", m.Name)
 		return nil
 	}
 
-	msList := &machinev1.MachineSetList{}
-	err := c.Client.List(context.Background(), msList, client.InNamespace(m.Namespace))
-	if err != nil {
-		klog.Errorf("Failed to list machine sets, %v", err)
-		return nil
+	indexValues := []string{expressionsOnlySelectorHash}
+	for k, v := range m.Labels {
+		indexValues = append(indexValues, selectorHash(k, v))
+	}
+
+	candidates := make(map[string]*machinev1.MachineSet)
+	for _, value := range indexValues {
+		msList := &machinev1.MachineSetList{}
+		err := c.Client.List(context.Background(), msList, client.InNamespace(m.Namespace), client.MatchingFields{machineSetSelectorIndex: value})
+		if err != nil {
+			klog.Errorf("Failed to list machine sets, %v", err)
+			return nil
+		}
+
+		for idx := range msList.Items {
+			candidates[msList.Items[idx].Name] = &msList.Items[idx]
+		}
+	}
+
+	names := make([]string, 0, len(candidates))
+	for name := range candidates {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
 	var mss []*machinev1.MachineSet
-	for idx := range msList.Items {
-		ms := &msList.Items[idx]
+	for _, name := range names {
+		ms := candidates[name]
 		if hasMatchingLabels(ms, m) {
 			mss = append(mss, ms)
 		}
@@ -50,6 +128,35 @@ func (c *ReconcileMachineSet) getMachineSetsForMachine(m *machinev1.Machine) []*
 	return mss
 }
 
+// getMachinesForMachineSet returns the machines in the machineset namespace which are either
+// controlled by the machineset or match its selector. Other machines can never be claimed by it.
+func (c *ReconcileMachineSet) getMachinesForMachineSet(ctx context.Context, machineSet *machinev1.MachineSet, selector labels.Selector) ([]*machinev1.Machine, error) {
+	owned := &machinev1.MachineList{}
+	if err := c.Client.List(ctx, owned, client.InNamespace(machineSet.Namespace), client.MatchingFields{machineOwnerIndex: string(machineSet.UID)}); err != nil {
+		return nil, err
+	}
+
+	matching := &machinev1.MachineList{}
+	if err := c.Client.List(ctx, matching, client.InNamespace(machineSet.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var machines []*machinev1.Machine
+	for _, list := range []*machinev1.MachineList{owned, matching} {
+		for idx := range list.Items {
+			machine := &list.Items[idx]
+			if seen[machine.Name] {
+				continue
+			}
+			seen[machine.Name] = true
+			machines = append(machines, machine)
+		}
+	}
+
+	return machines, nil
+}
+
 func hasMatchingLabels(machineSet *machinev1.MachineSet, machine *machinev1.Machine) bool {
 	selector, err := metav1.LabelSelectorAsSelector(&machineSet.Spec.Selector)
 	if err != nil {
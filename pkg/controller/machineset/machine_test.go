@@ -17,10 +17,15 @@ limitations under the License.
 package machineset
 
 import (
+	"context"
+	"reflect"
 	"testing"
 
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestHasMatchingLabels(t *testing.T) {
@@ -78,3 +83,114 @@ func TestHasMatchingLabels(t *testing.T) {
 		}
 	}
 }
+
+func TestGetMachineSetsForMachine(t *testing.T) {
+	withMatchLabels := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "withMatchLabels", Namespace: "test"},
+		Spec: machinev1.MachineSetSpec{
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+		},
+	}
+	withMatchExpressions := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "withMatchExpressions", Namespace: "test"},
+		Spec: machinev1.MachineSetSpec{
+			Selector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "foo", Operator: metav1.LabelSelectorOpExists},
+			}},
+		},
+	}
+	otherNamespace := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "otherNamespace", Namespace: "other"},
+		Spec: machinev1.MachineSetSpec{
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+		},
+	}
+	nonMatching := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "nonMatching", Namespace: "test"},
+		Spec: machinev1.MachineSetSpec{
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar", "baz": "qux"}},
+		},
+	}
+
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+
+	r := &ReconcileMachineSet{
+		Client: fake.NewClientBuilder().
+			WithRuntimeObjects(withMatchLabels, withMatchExpressions, otherNamespace, nonMatching).
+			WithIndex(&machinev1.MachineSet{}, machineSetSelectorIndex, indexMachineSetBySelector).
+			Build(),
+		scheme: scheme.Scheme,
+	}
+
+	machine := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "machine",
+			Namespace: "test",
+			Labels:    map[string]string{"foo": "bar"},
+		},
+	}
+
+	var got []string
+	for _, ms := range r.getMachineSetsForMachine(machine) {
+		got = append(got, ms.Name)
+	}
+
+	expected := []string{"withMatchExpressions", "withMatchLabels"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Got: %v, expected: %v", got, expected)
+	}
+}
+
+func TestGetMachinesForMachineSet(t *testing.T) {
+	ms := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "test", UID: "ms-uid"},
+		Spec: machinev1.MachineSetSpec{
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+		},
+	}
+	controller := true
+	owned := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "owned",
+			Namespace: "test",
+			OwnerReferences: []metav1.OwnerReference{
+				{Name: "ms", Kind: "MachineSet", UID: "ms-uid", Controller: &controller},
+			},
+		},
+	}
+	orphan := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "orphan", Namespace: "test", Labels: map[string]string{"foo": "bar"}},
+	}
+	unrelated := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "test", Labels: map[string]string{"foo": "baz"}},
+	}
+
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+
+	r := &ReconcileMachineSet{
+		Client: fake.NewClientBuilder().
+			WithRuntimeObjects(ms, owned, orphan, unrelated).
+			WithIndex(&machinev1.Machine{}, machineOwnerIndex, indexMachineByOwner).
+			Build(),
+		scheme: scheme.Scheme,
+	}
+
+	machines, err := r.getMachinesForMachineSet(context.Background(), ms, labels.SelectorFromSet(ms.Spec.Selector.MatchLabels))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, m := range machines {
+		got = append(got, m.Name)
+	}
+
+	expected := []string{"owned", "orphan"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Got: %v, expected: %v", got, expected)
+	}
+}
@@ -42,9 +42,10 @@ var _ = Describe("MachineSet Reconciler", func() {
 		k8sClient = mgr.GetClient()
 
 		By("Setting up a new reconciler")
-		reconciler := newReconciler(mgr)
+		reconciler, err := newReconciler(mgr, Options{})
+		Expect(err).NotTo(HaveOccurred())
 
-		err = add(mgr, reconciler, reconciler.MachineToMachineSets)
+		err = add(mgr, reconciler, reconciler.MachineToMachineSets, &machineExpectationsHandler{expectations: reconciler.expectations}, 1, nil)
 		Expect(err).NotTo(HaveOccurred())
 
 		var mgrCtx context.Context
@@ -0,0 +1,86 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+// ReplaceMissingInstancesAnnotation set to "false" opts a MachineSet out of the replacement of the Machines
+// whose instance disappeared, which are then left Failed until they are deleted.
+const ReplaceMissingInstancesAnnotation = "machine.openshift.io/replace-missing-instances"
+
+// instanceMissingSince returns the time at which the machine controller found that the instance backing
+// the Failed Machine had disappeared, and false if the Machine did not fail because of a missing instance.
+func instanceMissingSince(machine *machinev1.Machine) (time.Time, bool) {
+	if machine.Status.Phase == nil || *machine.Status.Phase != machinev1.PhaseFailed {
+		return time.Time{}, false
+	}
+	condition := conditions.Get(machine, machinev1.InstanceExistsCondition)
+	if condition == nil || condition.Status != corev1.ConditionFalse || condition.Reason != machinev1.InstanceMissingReason {
+		return time.Time{}, false
+	}
+	return condition.LastTransitionTime.Time, true
+}
+
+// deleteMachinesWithMissingInstance deletes the Machines whose instance has been missing for longer than the
+// grace period, and returns the remaining Machines so that the deleted Machines are replaced when syncing the
+// replicas, instead of Failed Machines satisfying the replicas.
+// It also returns the time after which the grace period of the next Machine will expire, if any.
+func (r *ReconcileMachineSet) deleteMachinesWithMissingInstance(ms *machinev1.MachineSet, machines []*machinev1.Machine) ([]*machinev1.Machine, time.Duration, error) {
+	if r.missingInstanceGracePeriod <= 0 || ms.Annotations[ReplaceMissingInstancesAnnotation] == "false" {
+		return machines, 0, nil
+	}
+
+	var remaining []*machinev1.Machine
+	var nextExpiry time.Duration
+	for _, machine := range machines {
+		missingSince, missing := instanceMissingSince(machine)
+		if !missing {
+			remaining = append(remaining, machine)
+			continue
+		}
+
+		missingFor := r.now().Sub(missingSince)
+		if missingFor < r.missingInstanceGracePeriod {
+			if next := r.missingInstanceGracePeriod - missingFor; nextExpiry == 0 || next < nextExpiry {
+				nextExpiry = next
+			}
+			remaining = append(remaining, machine)
+			continue
+		}
+
+		klog.Infof("%v: instance of machine %s has been missing for %v, longer than the grace period of %v, deleting",
+			ms.Name, machine.Name, missingFor.Round(time.Second), r.missingInstanceGracePeriod)
+		if err := r.Client.Delete(context.Background(), machine); err != nil && !apierrors.IsNotFound(err) {
+			return machines, 0, fmt.Errorf("failed to delete machine %s with missing instance: %w", machine.Name, err)
+		}
+		r.recorder.Eventf(ms, corev1.EventTypeNormal, "InstanceMissing",
+			"Deleted machine %s whose instance has been missing for longer than %v", machine.Name, r.missingInstanceGracePeriod)
+	}
+
+	return remaining, nextExpiry, nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeleteMachinesWithMissingInstance(t *testing.T) {
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	failed := machinev1.PhaseFailed
+	running := machinev1.PhaseRunning
+
+	newMachine := func(name string, phase *string, reason string, missingFor time.Duration) *machinev1.Machine {
+		m := &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Status: machinev1.MachineStatus{Phase: phase},
+		}
+		if reason != "" {
+			m.Status.Conditions = machinev1.Conditions{{
+				Type:               machinev1.InstanceExistsCondition,
+				Status:             corev1.ConditionFalse,
+				Reason:             reason,
+				LastTransitionTime: metav1.NewTime(now.Add(-missingFor)),
+			}}
+		}
+		return m
+	}
+
+	testCases := []struct {
+		name                 string
+		gracePeriod          time.Duration
+		annotations          map[string]string
+		machines             []*machinev1.Machine
+		expectedRemaining    []string
+		expectedDeleted      []string
+		expectedRequeueAfter time.Duration
+	}{
+		{
+			name: "without a grace period",
+			machines: []*machinev1.Machine{
+				newMachine("missing", &failed, machinev1.InstanceMissingReason, time.Hour),
+			},
+			expectedRemaining: []string{"missing"},
+		},
+		{
+			name:        "with a MachineSet opted out",
+			gracePeriod: 10 * time.Minute,
+			annotations: map[string]string{ReplaceMissingInstancesAnnotation: "false"},
+			machines: []*machinev1.Machine{
+				newMachine("missing", &failed, machinev1.InstanceMissingReason, time.Hour),
+			},
+			expectedRemaining: []string{"missing"},
+		},
+		{
+			name:        "with a grace period",
+			gracePeriod: 10 * time.Minute,
+			machines: []*machinev1.Machine{
+				newMachine("missing", &failed, machinev1.InstanceMissingReason, time.Hour),
+				newMachine("recently-missing", &failed, machinev1.InstanceMissingReason, 4*time.Minute),
+				newMachine("invalid", &failed, machinev1.InstanceNotCreatedReason, time.Hour),
+				newMachine("running", &running, "", 0),
+			},
+			expectedRemaining:    []string{"recently-missing", "invalid", "running"},
+			expectedDeleted:      []string{"missing"},
+			expectedRequeueAfter: 6 * time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "machineset1",
+					Namespace:   "default",
+					Annotations: tc.annotations,
+				},
+			}
+
+			builder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+			for _, m := range tc.machines {
+				builder = builder.WithObjects(m.DeepCopy())
+			}
+
+			r := &ReconcileMachineSet{
+				Client:                     builder.Build(),
+				scheme:                     scheme.Scheme,
+				recorder:                   record.NewFakeRecorder(32),
+				missingInstanceGracePeriod: tc.gracePeriod,
+				nowFunc:                    func() time.Time { return now },
+			}
+
+			remaining, requeueAfter, err := r.deleteMachinesWithMissingInstance(ms, tc.machines)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(requeueAfter).To(Equal(tc.expectedRequeueAfter))
+
+			var remainingNames []string
+			for _, m := range remaining {
+				remainingNames = append(remainingNames, m.Name)
+			}
+			g.Expect(remainingNames).To(Equal(tc.expectedRemaining))
+
+			for _, name := range tc.expectedDeleted {
+				err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: name}, &machinev1.Machine{})
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), "expected machine %s to be deleted", name)
+			}
+		})
+	}
+}
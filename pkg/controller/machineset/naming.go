@@ -0,0 +1,107 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/machine-api-operator/pkg/util/naming"
+)
+
+// maxNameLookups bounds how many names already used by Machines outside of the MachineSet are skipped
+// when naming a Machine, e.g. when the naming template does not use the name of the MachineSet.
+const maxNameLookups = 100
+
+// machineNamer names the new Machines of a MachineSet from its naming template.
+type machineNamer struct {
+	client   client.Reader
+	ms       *machinev1.MachineSet
+	template *naming.Template
+	// used are the names known to be used, by the Machines of the MachineSet and the Machines named so far.
+	used map[string]bool
+}
+
+// newMachineNamer returns a namer for the naming template of the MachineSet, taking into account the names
+// of its existing Machines. It returns nil when the MachineSet has no naming template.
+func newMachineNamer(c client.Reader, ms *machinev1.MachineSet, machines []*machinev1.Machine) (*machineNamer, error) {
+	text, ok := ms.Annotations[naming.MachineNameTemplateAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	template, err := naming.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &machineNamer{
+		client:   c,
+		ms:       ms,
+		template: template,
+		used:     make(map[string]bool, len(machines)),
+	}
+	for _, machine := range machines {
+		n.used[machine.Name] = true
+	}
+	return n, nil
+}
+
+// setName names the Machine with the lowest ordinal giving a name which is not used by another Machine.
+// The zone is read from the providerSpec of the Machine, it must be called once its failure domain is set.
+func (n *machineNamer) setName(ctx context.Context, machine *machinev1.Machine) error {
+	spec, err := providerSpecAsMap(machine.Spec.ProviderSpec.Value)
+	if err != nil {
+		return err
+	}
+	variables := naming.Variables{
+		MachineSet: n.ms.Name,
+		Namespace:  n.ms.Namespace,
+		Zone:       getProviderSpecZone(spec),
+	}
+
+	for lookups := 0; lookups < maxNameLookups; variables.Ordinal++ {
+		name, err := n.template.Render(variables)
+		if err != nil {
+			return err
+		}
+		if n.used[name] {
+			continue
+		}
+
+		// Machines outside of the MachineSet, or being deleted, may use the name too. Creating the Machine
+		// would then fail.
+		lookups++
+		err = n.client.Get(ctx, client.ObjectKey{Namespace: machine.Namespace, Name: name}, &machinev1.Machine{})
+		if err == nil {
+			n.used[name] = true
+			continue
+		}
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to check whether machine name %q is used: %w", name, err)
+		}
+
+		n.used[name] = true
+		machine.Name = name
+		machine.GenerateName = ""
+		return nil
+	}
+	return fmt.Errorf("failed to find an unused machine name after %d names used by other machines", maxNameLookups)
+}
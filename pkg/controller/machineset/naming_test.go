@@ -0,0 +1,127 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/machine-api-operator/pkg/util/naming"
+)
+
+func TestMachineNamer(t *testing.T) {
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+
+	newMachine := func(name, zone string) *machinev1.Machine {
+		return &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", GenerateName: "worker-"},
+			Spec: machinev1.MachineSpec{
+				ProviderSpec: machinev1.ProviderSpec{Value: &runtime.RawExtension{
+					Raw: []byte(`{"kind":"GCPMachineProviderSpec","zone":"` + zone + `"}`),
+				}},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		template      string
+		machines      []*machinev1.Machine
+		others        []*machinev1.Machine
+		zones         []string
+		expectedNames []string
+		expectedErr   string
+	}{
+		{
+			name:     "without a naming template",
+			machines: []*machinev1.Machine{newMachine("worker-abcde", "us-central1-a")},
+		},
+		{
+			name:          "with a naming template",
+			template:      "{{ .MachineSet }}-{{ .Zone }}-{{ .Ordinal }}",
+			zones:         []string{"us-central1-a", "us-central1-b"},
+			expectedNames: []string{"worker-us-central1-a-0", "worker-us-central1-b-0"},
+		},
+		{
+			name:          "with names used by the machines of the MachineSet",
+			template:      "{{ .MachineSet }}-{{ .Ordinal }}",
+			machines:      []*machinev1.Machine{newMachine("worker-0", "us-central1-a"), newMachine("worker-2", "us-central1-a")},
+			zones:         []string{"us-central1-a", "us-central1-a"},
+			expectedNames: []string{"worker-1", "worker-3"},
+		},
+		{
+			name:          "with a name used by another machine",
+			template:      "{{ .MachineSet }}-{{ .Ordinal }}",
+			others:        []*machinev1.Machine{newMachine("worker-0", "us-central1-a")},
+			zones:         []string{"us-central1-a"},
+			expectedNames: []string{"worker-1"},
+		},
+		{
+			name:        "with an invalid name",
+			template:    "{{ .MachineSet }}-{{ .Zone }}-{{ .Ordinal }}",
+			zones:       []string{"US_CENTRAL1"},
+			expectedErr: `invalid machine name "worker-US_CENTRAL1-0"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"}}
+			if tc.template != "" {
+				ms.Annotations = map[string]string{naming.MachineNameTemplateAnnotation: tc.template}
+			}
+			var objects []client.Object
+			for _, m := range append(tc.machines, tc.others...) {
+				objects = append(objects, m)
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()
+
+			namer, err := newMachineNamer(c, ms, tc.machines)
+			g.Expect(err).ToNot(HaveOccurred())
+			if tc.template == "" {
+				g.Expect(namer).To(BeNil())
+				return
+			}
+
+			var names []string
+			for _, zone := range tc.zones {
+				m := newMachine("", zone)
+				err := namer.setName(context.TODO(), m)
+				if tc.expectedErr != "" {
+					g.Expect(err).To(MatchError(ContainSubstring(tc.expectedErr)))
+					return
+				}
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(m.GenerateName).To(BeEmpty())
+				names = append(names, m.Name)
+			}
+			g.Expect(names).To(Equal(tc.expectedNames))
+		})
+	}
+}
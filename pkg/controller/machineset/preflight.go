@@ -0,0 +1,104 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// MachineSetPreflightFailedCondition is true when the machine template of the MachineSet failed the
+	// preflight checks run before creating Machines, and no Machines were created.
+	MachineSetPreflightFailedCondition machinev1.ConditionType = "PreflightFailed"
+
+	// PreflightFailedReason is used when the machine template of the MachineSet failed the preflight checks.
+	PreflightFailedReason = "PreflightFailed"
+)
+
+// errPreflightFailed is returned when the machine template of the MachineSet failed the preflight checks.
+var errPreflightFailed = errors.New("preflight checks failed")
+
+// MachineValidator validates Machines before they are created by the MachineSet controller.
+// It is implemented by the Machine validating webhook.
+type MachineValidator interface {
+	// ValidateMachine returns the warnings of the validation, along with an error when the Machine is invalid.
+	ValidateMachine(m *machinev1.Machine) ([]string, error)
+}
+
+// providerSpecSecrets holds the secret references common to the provider specs of all platforms.
+// Some platforms use a LocalObjectReference, which decodes into a SecretReference without a namespace.
+type providerSpecSecrets struct {
+	UserDataSecret    *corev1.SecretReference `json:"userDataSecret,omitempty"`
+	CredentialsSecret *corev1.SecretReference `json:"credentialsSecret,omitempty"`
+}
+
+// preflightMachine checks that a Machine built from the machine template of the MachineSet can
+// be created successfully: the secrets referenced by its provider spec must exist, and it must
+// pass the validation of the webhook when a validator is configured.
+// Machines failing these checks would otherwise only be created to end up in the Failed phase.
+func (r *ReconcileMachineSet) preflightMachine(machine *machinev1.Machine) error {
+	if machine.Spec.ProviderSpec.Value != nil && len(machine.Spec.ProviderSpec.Value.Raw) > 0 {
+		secrets := &providerSpecSecrets{}
+		if err := json.Unmarshal(machine.Spec.ProviderSpec.Value.Raw, secrets); err != nil {
+			return fmt.Errorf("%w: failed to decode providerSpec: %v", errPreflightFailed, err)
+		}
+
+		if err := r.checkSecretExists(machine.Namespace, "userDataSecret", secrets.UserDataSecret); err != nil {
+			return err
+		}
+		if err := r.checkSecretExists(machine.Namespace, "credentialsSecret", secrets.CredentialsSecret); err != nil {
+			return err
+		}
+	}
+
+	if r.machineValidator == nil {
+		return nil
+	}
+	if _, err := r.machineValidator.ValidateMachine(machine); err != nil {
+		return fmt.Errorf("%w: %v", errPreflightFailed, err)
+	}
+	return nil
+}
+
+// checkSecretExists returns an error when the secret referenced by the given provider spec field does not exist.
+// Secrets without a namespace are looked up in the namespace of the Machine.
+func (r *ReconcileMachineSet) checkSecretExists(namespace, fieldName string, ref *corev1.SecretReference) error {
+	if ref == nil || ref.Name == "" {
+		return nil
+	}
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+
+	key := client.ObjectKey{Namespace: namespace, Name: ref.Name}
+	exists, err := util.ObjectExists(context.Background(), r.Client, key, &corev1.Secret{}, r.fullSecrets)
+	if err != nil {
+		return fmt.Errorf("failed to get %s %s/%s: %w", fieldName, namespace, ref.Name, err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s %s/%s not found", errPreflightFailed, fieldName, namespace, ref.Name)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeMachineValidator struct {
+	err error
+}
+
+func (v fakeMachineValidator) ValidateMachine(m *machinev1.Machine) ([]string, error) {
+	return nil, v.err
+}
+
+func TestPreflightMachine(t *testing.T) {
+	userDataSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "worker-user-data", Namespace: "default"}}
+	credentialsSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "azure-cloud-credentials", Namespace: "openshift-machine-api"}}
+
+	testCases := []struct {
+		name          string
+		providerSpec  string
+		objects       []client.Object
+		validator     MachineValidator
+		expectedError string
+	}{
+		{
+			name:         "with existing secrets",
+			providerSpec: `{"userDataSecret":{"name":"worker-user-data"},"credentialsSecret":{"name":"azure-cloud-credentials","namespace":"openshift-machine-api"}}`,
+			objects:      []client.Object{userDataSecret, credentialsSecret},
+		},
+		{
+			name:          "with a missing user data secret",
+			providerSpec:  `{"userDataSecret":{"name":"worker-user-data"},"credentialsSecret":{"name":"azure-cloud-credentials","namespace":"openshift-machine-api"}}`,
+			objects:       []client.Object{credentialsSecret},
+			expectedError: "preflight checks failed: userDataSecret default/worker-user-data not found",
+		},
+		{
+			name:          "with a missing credentials secret in the namespace of the machine",
+			providerSpec:  `{"userDataSecret":{"name":"worker-user-data"},"credentialsSecret":{"name":"azure-cloud-credentials"}}`,
+			objects:       []client.Object{userDataSecret, credentialsSecret},
+			expectedError: "preflight checks failed: credentialsSecret default/azure-cloud-credentials not found",
+		},
+		{
+			name:         "without secret references",
+			providerSpec: `{}`,
+		},
+		{
+			name:          "with a provider spec rejected by the validator",
+			providerSpec:  `{}`,
+			validator:     fakeMachineValidator{err: errors.New("providerSpec.subnet: Required value")},
+			expectedError: "preflight checks failed: providerSpec.subnet: Required value",
+		},
+		{
+			name:         "with a provider spec accepted by the validator",
+			providerSpec: `{}`,
+			validator:    fakeMachineValidator{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := &ReconcileMachineSet{
+				Client:           fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(tc.objects...).Build(),
+				machineValidator: tc.validator,
+			}
+			machine := &machinev1.Machine{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+				Spec: machinev1.MachineSpec{
+					ProviderSpec: machinev1.ProviderSpec{Value: &runtime.RawExtension{Raw: []byte(tc.providerSpec)}},
+				},
+			}
+
+			err := r.preflightMachine(machine)
+			if tc.expectedError == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(MatchError(tc.expectedError))
+			g.Expect(errors.Is(err, errPreflightFailed)).To(BeTrue())
+		})
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+)
+
+// ProvisioningTimeoutAnnotation sets how long a Machine of the MachineSet may remain
+// provisioning before it is deleted and replaced, e.g. "30m".
+// When it is not set, Machines are never replaced because of a provisioning timeout.
+const ProvisioningTimeoutAnnotation = "machine.openshift.io/provisioning-timeout"
+
+// getProvisioningTimeout returns the provisioning timeout of the MachineSet, or zero when none is set.
+func getProvisioningTimeout(ms *machinev1.MachineSet) (time.Duration, error) {
+	value, ok := ms.Annotations[ProvisioningTimeoutAnnotation]
+	if !ok {
+		return 0, nil
+	}
+	return ParseProvisioningTimeout(value)
+}
+
+// ParseProvisioningTimeout parses the value of the ProvisioningTimeoutAnnotation.
+func ParseProvisioningTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation %q: %w", ProvisioningTimeoutAnnotation, value, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid %s annotation %q: must be greater than zero", ProvisioningTimeoutAnnotation, value)
+	}
+
+	return timeout, nil
+}
+
+// isMachineProvisioning returns true if the machine controller has not yet created
+// the instance backing the Machine.
+func isMachineProvisioning(machine *machinev1.Machine) bool {
+	return machine.Status.Phase == nil ||
+		*machine.Status.Phase == "" ||
+		*machine.Status.Phase == machinev1.PhaseProvisioning
+}
+
+// deleteStuckMachines deletes the Machines which have been provisioning for longer than the
+// provisioning timeout of the MachineSet, and returns the remaining Machines so that the
+// deleted Machines are replaced when syncing the replicas.
+// It also returns the time after which the next provisioning Machine will time out, if any.
+func (r *ReconcileMachineSet) deleteStuckMachines(ms *machinev1.MachineSet, machines []*machinev1.Machine) ([]*machinev1.Machine, time.Duration, error) {
+	timeout, err := getProvisioningTimeout(ms)
+	if err != nil {
+		// The webhook rejects invalid timeouts, the Machines are not replaced until the timeout is fixed.
+		r.reportInvalidAnnotation(ms, ProvisioningTimeoutAnnotation, "InvalidProvisioningTimeout", err)
+		return machines, 0, nil
+	}
+	if timeout == 0 {
+		return machines, 0, nil
+	}
+
+	var remaining []*machinev1.Machine
+	var nextTimeout time.Duration
+	for _, machine := range machines {
+		if !isMachineProvisioning(machine) {
+			remaining = append(remaining, machine)
+			continue
+		}
+
+		provisioningFor := r.now().Sub(machine.CreationTimestamp.Time)
+		if provisioningFor < timeout {
+			if next := timeout - provisioningFor; nextTimeout == 0 || next < nextTimeout {
+				nextTimeout = next
+			}
+			remaining = append(remaining, machine)
+			continue
+		}
+
+		klog.Infof("%v: machine %s has been provisioning for %v, longer than the timeout of %v, deleting",
+			ms.Name, machine.Name, provisioningFor.Round(time.Second), timeout)
+		if err := r.Client.Delete(context.Background(), machine); err != nil && !apierrors.IsNotFound(err) {
+			return machines, 0, fmt.Errorf("failed to delete machine %s stuck provisioning: %w", machine.Name, err)
+		}
+		r.recorder.Eventf(ms, corev1.EventTypeNormal, "ProvisioningTimeout",
+			"Deleted machine %s which did not finish provisioning within %v", machine.Name, timeout)
+	}
+
+	return remaining, nextTimeout, nil
+}
+
+// now is used to get the current time. If the reconciler nowFunc is not nil this will be used instead of time.Now().
+// This is only here so that tests can modify the time to check time based assertions.
+func (r *ReconcileMachineSet) now() time.Time {
+	if r.nowFunc != nil {
+		return r.nowFunc()
+	}
+	return time.Now()
+}
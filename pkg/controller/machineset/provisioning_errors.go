@@ -0,0 +1,185 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+const (
+	// MachineSetProvisioningFailedCondition is true while Machines of the MachineSet fail to get an instance,
+	// either because the cloud provider fails to create it or because it was terminated outside of the Machine API.
+	MachineSetProvisioningFailedCondition machinev1.ConditionType = "ProvisioningFailed"
+	// MachineSetDrainBlockedCondition is true while the drain of the nodes of deleting Machines of the MachineSet
+	// is blocked, by a pre-drain lifecycle hook or by a failing eviction.
+	MachineSetDrainBlockedCondition machinev1.ConditionType = "DrainBlocked"
+
+	// LastProvisioningErrorAnnotation holds the last provisioning error of the Machines of the MachineSet, as JSON.
+	// The MachineSet status does not have a field for it, so it is stored in this annotation, and kept once the
+	// Machine recovers or is replaced, so that the users watching MachineSets can see why capacity did not arrive.
+	LastProvisioningErrorAnnotation = "machine.openshift.io/last-provisioning-error"
+
+	// InstanceCreateFailedReason is used when the cloud provider fails to create the instances of Machines.
+	InstanceCreateFailedReason = machinecontroller.InstanceCreateFailedReason
+	// DrainBlockedReason is used when the drain of the nodes of Machines is blocked.
+	DrainBlockedReason = "DrainBlocked"
+)
+
+// ProvisioningError is a provisioning error of a Machine of a MachineSet.
+type ProvisioningError struct {
+	// Machine is the name of the Machine.
+	Machine string `json:"machine"`
+	// Reason is the reason of the error, e.g. InstanceCreateFailed or InstanceMissing.
+	Reason string `json:"reason"`
+	// Message is the message of the error.
+	Message string `json:"message"`
+	// Time is when the error was observed.
+	Time time.Time `json:"time"`
+}
+
+// getProvisioningError returns the provisioning error of the Machine, or nil when it has none.
+func getProvisioningError(machine *machinev1.Machine) *ProvisioningError {
+	instanceExists := conditions.Get(machine, machinev1.InstanceExistsCondition)
+	if instanceExists != nil && instanceExists.Status == corev1.ConditionFalse &&
+		(instanceExists.Reason == InstanceCreateFailedReason || instanceExists.Reason == machinev1.InstanceMissingReason) {
+		return &ProvisioningError{
+			Machine: machine.Name,
+			Reason:  instanceExists.Reason,
+			Message: instanceExists.Message,
+			Time:    instanceExists.LastTransitionTime.Time,
+		}
+	}
+
+	if machine.Status.Phase == nil || *machine.Status.Phase != machinev1.PhaseFailed || machine.Status.ErrorMessage == nil {
+		return nil
+	}
+	provisioningError := &ProvisioningError{
+		Machine: machine.Name,
+		Reason:  MachineFailedReason,
+		Message: *machine.Status.ErrorMessage,
+	}
+	if machine.Status.ErrorReason != nil {
+		provisioningError.Reason = string(*machine.Status.ErrorReason)
+	}
+	if machine.Status.LastUpdated != nil {
+		provisioningError.Time = machine.Status.LastUpdated.Time
+	}
+	return provisioningError
+}
+
+// getDrainBlocked returns why the drain of the node of the deleting Machine is blocked, or an empty string.
+func getDrainBlocked(machine *machinev1.Machine) string {
+	if machine.DeletionTimestamp.IsZero() {
+		return ""
+	}
+	if drained := conditions.Get(machine, machinev1.MachineDrained); drained != nil && drained.Status == corev1.ConditionFalse {
+		return drained.Message
+	}
+	if drainable := conditions.Get(machine, machinev1.MachineDrainable); drainable != nil && drainable.Status == corev1.ConditionFalse {
+		return drainable.Message
+	}
+	return ""
+}
+
+// setMachineEventConditions rolls the noteworthy events of the Machines of the MachineSet up into its
+// conditions and its last provisioning error.
+func setMachineEventConditions(ms *machinev1.MachineSet, filteredMachines []*machinev1.Machine) {
+	var provisioningErrors []*ProvisioningError
+	var drainsBlocked []string
+	for _, machine := range filteredMachines {
+		if provisioningError := getProvisioningError(machine); provisioningError != nil {
+			provisioningErrors = append(provisioningErrors, provisioningError)
+		}
+		if blocked := getDrainBlocked(machine); blocked != "" {
+			drainsBlocked = append(drainsBlocked, fmt.Sprintf("%s: %s", machine.Name, blocked))
+		}
+	}
+	sort.Strings(drainsBlocked)
+
+	if len(provisioningErrors) > 0 {
+		var messages []string
+		for _, provisioningError := range provisioningErrors {
+			messages = append(messages, fmt.Sprintf("%s: %s", provisioningError.Machine, provisioningError.Message))
+		}
+		sort.Strings(messages)
+		conditions.Set(ms, &machinev1.Condition{
+			Type:     MachineSetProvisioningFailedCondition,
+			Status:   corev1.ConditionTrue,
+			Severity: machinev1.ConditionSeverityWarning,
+			Reason:   provisioningErrors[0].Reason,
+			Message:  fmt.Sprintf("%d machines failed to provision: %s", len(provisioningErrors), strings.Join(messages, "; ")),
+		})
+	} else {
+		conditions.Set(ms, &machinev1.Condition{Type: MachineSetProvisioningFailedCondition, Status: corev1.ConditionFalse})
+	}
+
+	if len(drainsBlocked) > 0 {
+		conditions.Set(ms, &machinev1.Condition{
+			Type:     MachineSetDrainBlockedCondition,
+			Status:   corev1.ConditionTrue,
+			Severity: machinev1.ConditionSeverityWarning,
+			Reason:   DrainBlockedReason,
+			Message:  fmt.Sprintf("%d machines are blocked draining: %s", len(drainsBlocked), strings.Join(drainsBlocked, "; ")),
+		})
+	} else {
+		conditions.Set(ms, &machinev1.Condition{Type: MachineSetDrainBlockedCondition, Status: corev1.ConditionFalse})
+	}
+
+	setLastProvisioningError(ms, provisioningErrors)
+}
+
+// setLastProvisioningError records the latest of the provisioning errors, unless the MachineSet already
+// records a later one.
+func setLastProvisioningError(ms *machinev1.MachineSet, provisioningErrors []*ProvisioningError) {
+	var latest *ProvisioningError
+	for _, provisioningError := range provisioningErrors {
+		if latest == nil || provisioningError.Time.After(latest.Time) {
+			latest = provisioningError
+		}
+	}
+	if latest == nil {
+		return
+	}
+
+	if raw, ok := ms.Annotations[LastProvisioningErrorAnnotation]; ok {
+		last := &ProvisioningError{}
+		if err := json.Unmarshal([]byte(raw), last); err == nil && last.Time.After(latest.Time) {
+			return
+		}
+	}
+
+	raw, err := json.Marshal(latest)
+	if err != nil {
+		klog.Errorf("%v: could not encode last provisioning error: %v", ms.Name, err)
+		return
+	}
+	if ms.Annotations == nil {
+		ms.Annotations = make(map[string]string)
+	}
+	ms.Annotations[LastProvisioningErrorAnnotation] = string(raw)
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+)
+
+func TestSetMachineEventConditions(t *testing.T) {
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	failed := machinev1.PhaseFailed
+	provisioning := machinev1.PhaseProvisioning
+	running := machinev1.PhaseRunning
+	errorReason := machinev1.InvalidConfigurationMachineError
+	errorMessage := "instance type not available"
+
+	createFailed := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "create-failed"},
+		Status: machinev1.MachineStatus{
+			Phase: &provisioning,
+			Conditions: machinev1.Conditions{{
+				Type:               machinev1.InstanceExistsCondition,
+				Status:             corev1.ConditionFalse,
+				Reason:             InstanceCreateFailedReason,
+				Message:            "Failed to create instance: quota exceeded",
+				LastTransitionTime: metav1.NewTime(now.Add(-time.Minute)),
+			}},
+		},
+	}
+	instanceMissing := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "instance-missing"},
+		Status: machinev1.MachineStatus{
+			Phase: &failed,
+			Conditions: machinev1.Conditions{{
+				Type:               machinev1.InstanceExistsCondition,
+				Status:             corev1.ConditionFalse,
+				Reason:             machinev1.InstanceMissingReason,
+				Message:            "Instance not found on provider",
+				LastTransitionTime: metav1.NewTime(now.Add(-time.Hour)),
+			}},
+		},
+	}
+	machineFailed := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "failed"},
+		Status: machinev1.MachineStatus{
+			Phase:        &failed,
+			ErrorReason:  &errorReason,
+			ErrorMessage: &errorMessage,
+			LastUpdated:  &metav1.Time{Time: now},
+		},
+	}
+	drainBlocked := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "drain-blocked", DeletionTimestamp: &metav1.Time{Time: now}},
+		Status: machinev1.MachineStatus{
+			Phase: &running,
+			Conditions: machinev1.Conditions{{
+				Type:    machinev1.MachineDrained,
+				Status:  corev1.ConditionFalse,
+				Reason:  machinev1.MachineDrainError,
+				Message: "Drain failed: cannot evict pod as it would violate the pod's disruption budget",
+			}},
+		},
+	}
+	healthy := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "healthy"},
+		Status:     machinev1.MachineStatus{Phase: &running},
+	}
+
+	testCases := []struct {
+		name                      string
+		machines                  []*machinev1.Machine
+		lastProvisioningError     *ProvisioningError
+		expectedConditions        map[machinev1.ConditionType]machinev1.Condition
+		expectedProvisioningError *ProvisioningError
+	}{
+		{
+			name:     "with healthy machines",
+			machines: []*machinev1.Machine{healthy},
+			expectedConditions: map[machinev1.ConditionType]machinev1.Condition{
+				MachineSetProvisioningFailedCondition: {Type: MachineSetProvisioningFailedCondition, Status: corev1.ConditionFalse},
+				MachineSetDrainBlockedCondition:       {Type: MachineSetDrainBlockedCondition, Status: corev1.ConditionFalse},
+			},
+		},
+		{
+			name:     "with machines failing to provision",
+			machines: []*machinev1.Machine{healthy, instanceMissing, createFailed, machineFailed},
+			expectedConditions: map[machinev1.ConditionType]machinev1.Condition{
+				MachineSetProvisioningFailedCondition: {
+					Type:     MachineSetProvisioningFailedCondition,
+					Status:   corev1.ConditionTrue,
+					Severity: machinev1.ConditionSeverityWarning,
+					Reason:   machinev1.InstanceMissingReason,
+					Message: "3 machines failed to provision: create-failed: Failed to create instance: quota exceeded; " +
+						"failed: instance type not available; instance-missing: Instance not found on provider",
+				},
+				MachineSetDrainBlockedCondition: {Type: MachineSetDrainBlockedCondition, Status: corev1.ConditionFalse},
+			},
+			expectedProvisioningError: &ProvisioningError{
+				Machine: "failed",
+				Reason:  string(errorReason),
+				Message: errorMessage,
+				Time:    now,
+			},
+		},
+		{
+			name:     "with a later provisioning error already recorded",
+			machines: []*machinev1.Machine{createFailed},
+			lastProvisioningError: &ProvisioningError{
+				Machine: "deleted",
+				Reason:  InstanceCreateFailedReason,
+				Message: "Failed to create instance: capacity not available",
+				Time:    now,
+			},
+			expectedConditions: map[machinev1.ConditionType]machinev1.Condition{
+				MachineSetProvisioningFailedCondition: {
+					Type:     MachineSetProvisioningFailedCondition,
+					Status:   corev1.ConditionTrue,
+					Severity: machinev1.ConditionSeverityWarning,
+					Reason:   InstanceCreateFailedReason,
+					Message:  "1 machines failed to provision: create-failed: Failed to create instance: quota exceeded",
+				},
+				MachineSetDrainBlockedCondition: {Type: MachineSetDrainBlockedCondition, Status: corev1.ConditionFalse},
+			},
+			expectedProvisioningError: &ProvisioningError{
+				Machine: "deleted",
+				Reason:  InstanceCreateFailedReason,
+				Message: "Failed to create instance: capacity not available",
+				Time:    now,
+			},
+		},
+		{
+			name:     "with a machine blocked draining",
+			machines: []*machinev1.Machine{healthy, drainBlocked},
+			expectedConditions: map[machinev1.ConditionType]machinev1.Condition{
+				MachineSetProvisioningFailedCondition: {Type: MachineSetProvisioningFailedCondition, Status: corev1.ConditionFalse},
+				MachineSetDrainBlockedCondition: {
+					Type:     MachineSetDrainBlockedCondition,
+					Status:   corev1.ConditionTrue,
+					Severity: machinev1.ConditionSeverityWarning,
+					Reason:   DrainBlockedReason,
+					Message:  "1 machines are blocked draining: drain-blocked: Drain failed: cannot evict pod as it would violate the pod's disruption budget",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{Name: "machineset"}}
+			if tc.lastProvisioningError != nil {
+				raw, err := json.Marshal(tc.lastProvisioningError)
+				g.Expect(err).ToNot(HaveOccurred())
+				ms.Annotations = map[string]string{LastProvisioningErrorAnnotation: string(raw)}
+			}
+
+			setMachineEventConditions(ms, tc.machines)
+
+			for conditionType, expected := range tc.expectedConditions {
+				condition := conditions.Get(ms, conditionType)
+				g.Expect(condition).ToNot(BeNil(), "expected condition %s", conditionType)
+				g.Expect(*condition).To(conditions.MatchCondition(expected))
+			}
+
+			if tc.expectedProvisioningError == nil {
+				g.Expect(ms.Annotations).ToNot(HaveKey(LastProvisioningErrorAnnotation))
+				return
+			}
+			g.Expect(ms.Annotations).To(HaveKey(LastProvisioningErrorAnnotation))
+			provisioningError := &ProvisioningError{}
+			g.Expect(json.Unmarshal([]byte(ms.Annotations[LastProvisioningErrorAnnotation]), provisioningError)).To(Succeed())
+			g.Expect(provisioningError.Machine).To(Equal(tc.expectedProvisioningError.Machine))
+			g.Expect(provisioningError.Reason).To(Equal(tc.expectedProvisioningError.Reason))
+			g.Expect(provisioningError.Message).To(Equal(tc.expectedProvisioningError.Message))
+			g.Expect(provisioningError.Time.Equal(tc.expectedProvisioningError.Time)).To(BeTrue())
+		})
+	}
+}
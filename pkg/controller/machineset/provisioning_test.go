@@ -0,0 +1,163 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeleteStuckMachines(t *testing.T) {
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	provisioning := machinev1.PhaseProvisioning
+	running := machinev1.PhaseRunning
+
+	newMachine := func(name string, age time.Duration, phase *string) *machinev1.Machine {
+		return &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+			Status: machinev1.MachineStatus{Phase: phase},
+		}
+	}
+
+	testCases := []struct {
+		name                 string
+		annotations          map[string]string
+		machines             []*machinev1.Machine
+		expectedRemaining    []string
+		expectedDeleted      []string
+		expectedRequeueAfter time.Duration
+	}{
+		{
+			name: "without a provisioning timeout",
+			machines: []*machinev1.Machine{
+				newMachine("stuck", time.Hour, &provisioning),
+			},
+			expectedRemaining: []string{"stuck"},
+		},
+		{
+			name:        "with an invalid provisioning timeout",
+			annotations: map[string]string{ProvisioningTimeoutAnnotation: "soon"},
+			machines: []*machinev1.Machine{
+				newMachine("stuck", time.Hour, &provisioning),
+			},
+			expectedRemaining: []string{"stuck"},
+		},
+		{
+			name:        "with a provisioning timeout",
+			annotations: map[string]string{ProvisioningTimeoutAnnotation: "30m"},
+			machines: []*machinev1.Machine{
+				newMachine("stuck", time.Hour, &provisioning),
+				newMachine("stuck-without-phase", 31*time.Minute, nil),
+				newMachine("provisioning", 20*time.Minute, &provisioning),
+				newMachine("young", 25*time.Minute, nil),
+				newMachine("running", time.Hour, &running),
+			},
+			expectedRemaining:    []string{"provisioning", "young", "running"},
+			expectedDeleted:      []string{"stuck", "stuck-without-phase"},
+			expectedRequeueAfter: 5 * time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "machineset1",
+					Namespace:   "default",
+					Annotations: tc.annotations,
+				},
+			}
+
+			builder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+			for _, m := range tc.machines {
+				builder = builder.WithObjects(m.DeepCopy())
+			}
+
+			r := &ReconcileMachineSet{
+				Client:   builder.Build(),
+				scheme:   scheme.Scheme,
+				recorder: record.NewFakeRecorder(32),
+				nowFunc:  func() time.Time { return now },
+			}
+
+			remaining, requeueAfter, err := r.deleteStuckMachines(ms, tc.machines)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(requeueAfter).To(Equal(tc.expectedRequeueAfter))
+
+			var remainingNames []string
+			for _, m := range remaining {
+				remainingNames = append(remainingNames, m.Name)
+			}
+			g.Expect(remainingNames).To(Equal(tc.expectedRemaining))
+
+			for _, name := range tc.expectedDeleted {
+				err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: name}, &machinev1.Machine{})
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), "expected machine %s to be deleted", name)
+			}
+		})
+	}
+}
+
+func TestDeleteStuckMachinesReportsInvalidTimeoutOnce(t *testing.T) {
+	g := NewWithT(t)
+
+	ms := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "machineset1",
+			Namespace:   "default",
+			Annotations: map[string]string{ProvisioningTimeoutAnnotation: "soon"},
+		},
+	}
+	recorder := record.NewFakeRecorder(32)
+	r := &ReconcileMachineSet{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(),
+		scheme:   scheme.Scheme,
+		recorder: recorder,
+	}
+
+	for i := 0; i < 3; i++ {
+		_, _, err := r.deleteStuckMachines(ms, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	g.Expect(recorder.Events).To(HaveLen(1))
+	g.Expect(<-recorder.Events).To(HavePrefix("Warning InvalidProvisioningTimeout"))
+
+	ms.Annotations[ProvisioningTimeoutAnnotation] = "-5m"
+	_, _, err := r.deleteStuckMachines(ms, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(recorder.Events).To(HaveLen(1))
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// MachineCreationRateAnnotation limits the number of Machines a MachineSet creates per minute.
+	// Up to that number of Machines may be created at once, after which creations are spread over time.
+	MachineCreationRateAnnotation = "machine.openshift.io/machine-creation-rate"
+
+	// creationBackoffBase and creationBackoffMax bound the exponential backoff applied to
+	// the creations of a MachineSet when they are rate limited.
+	creationBackoffBase = 10 * time.Second
+	creationBackoffMax  = 10 * time.Minute
+)
+
+// rateLimitedMessages are fragments of the error messages returned by cloud provider APIs
+// when requests are being throttled.
+var rateLimitedMessages = []string{
+	"RequestLimitExceeded",          // AWS
+	"Rate exceeded",                 // AWS
+	"Throttling",                    // AWS
+	"TooManyRequests",               // Azure
+	"SubscriptionRequestsThrottled", // Azure
+	"rateLimitExceeded",             // GCP
+	"429 Too Many Requests",
+}
+
+// creationRateLimitedError is returned when Machine creations are deferred by the creation rate limiter.
+type creationRateLimitedError struct {
+	deferred   int
+	retryAfter time.Duration
+}
+
+func (e *creationRateLimitedError) Error() string {
+	return fmt.Sprintf("creation of %d machines deferred by rate limiting, retrying in %v", e.deferred, e.retryAfter)
+}
+
+// machineSetCreationLimiter is the creation rate limiting state of a single MachineSet.
+type machineSetCreationLimiter struct {
+	// limiter is nil when the MachineSet does not limit its creation rate.
+	limiter       *rate.Limiter
+	perMinute     int
+	failures      int
+	backoffExpiry time.Time
+}
+
+// creationRateLimiter limits the rate at which Machines are created, both for each MachineSet
+// and across all MachineSets, and backs off the creations of a MachineSet exponentially
+// when they are being rate limited.
+type creationRateLimiter struct {
+	lock sync.Mutex
+
+	// global is nil when the creation rate across MachineSets is not limited.
+	global      *rate.Limiter
+	machineSets map[types.NamespacedName]*machineSetCreationLimiter
+
+	// nowFunc is used to mock time in testing. It should be nil in production.
+	nowFunc func() time.Time
+}
+
+// newCreationRateLimiter returns a creationRateLimiter allowing qps creations per second
+// across all MachineSets, with bursts of up to burst creations.
+// A qps of zero or less does not limit the global creation rate.
+func newCreationRateLimiter(qps float64, burst int) *creationRateLimiter {
+	l := &creationRateLimiter{
+		machineSets: make(map[types.NamespacedName]*machineSetCreationLimiter),
+	}
+	if qps > 0 {
+		if burst < 1 {
+			burst = 1
+		}
+		l.global = rate.NewLimiter(rate.Limit(qps), burst)
+	}
+	return l
+}
+
+func (l *creationRateLimiter) now() time.Time {
+	if l.nowFunc != nil {
+		return l.nowFunc()
+	}
+	return time.Now()
+}
+
+// getMachineCreationRate returns the number of Machines the MachineSet may create per minute, or zero when unlimited.
+// An invalid annotation, rejected by the webhook, does not limit the creations.
+func getMachineCreationRate(ms *machinev1.MachineSet) int {
+	value, ok := ms.Annotations[MachineCreationRateAnnotation]
+	if !ok {
+		return 0
+	}
+
+	perMinute, err := ParseMachineCreationRate(value)
+	if err != nil {
+		klog.Warningf("%v: ignoring the creation rate: %v", ms.Name, err)
+		return 0
+	}
+	return perMinute
+}
+
+// ParseMachineCreationRate parses the value of the MachineCreationRateAnnotation.
+func ParseMachineCreationRate(value string) (int, error) {
+	perMinute, err := strconv.Atoi(value)
+	if err != nil || perMinute < 1 {
+		return 0, fmt.Errorf("invalid %s annotation %q: must be a positive integer", MachineCreationRateAnnotation, value)
+	}
+	return perMinute, nil
+}
+
+// machineSetLimiter returns the state of the MachineSet, updating its limiter when the creation rate has changed.
+// The lock must be held.
+func (l *creationRateLimiter) machineSetLimiter(key types.NamespacedName, perMinute int) *machineSetCreationLimiter {
+	state, ok := l.machineSets[key]
+	if !ok {
+		state = &machineSetCreationLimiter{}
+		l.machineSets[key] = state
+	}
+
+	if state.perMinute != perMinute {
+		state.perMinute = perMinute
+		state.limiter = nil
+		if perMinute > 0 {
+			state.limiter = rate.NewLimiter(rate.Limit(float64(perMinute)/60), perMinute)
+		}
+	}
+	return state
+}
+
+// allowedCreations returns how many of the wanted creations the MachineSet may make now, consuming them
+// from the limiters, and when to retry if not all of them are allowed.
+func (l *creationRateLimiter) allowedCreations(ms *machinev1.MachineSet, perMinute, want int) (int, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	state := l.machineSetLimiter(types.NamespacedName{Namespace: ms.Namespace, Name: ms.Name}, perMinute)
+	if now.Before(state.backoffExpiry) {
+		return 0, state.backoffExpiry.Sub(now)
+	}
+
+	for i := 0; i < want; i++ {
+		var reservations []*rate.Reservation
+		var delay time.Duration
+		for _, limiter := range []*rate.Limiter{state.limiter, l.global} {
+			if limiter == nil {
+				continue
+			}
+			reservation := limiter.ReserveN(now, 1)
+			reservations = append(reservations, reservation)
+			if d := reservation.DelayFrom(now); d > delay {
+				delay = d
+			}
+		}
+
+		if delay > 0 {
+			for _, reservation := range reservations {
+				reservation.CancelAt(now)
+			}
+			return i, delay
+		}
+	}
+
+	return want, 0
+}
+
+// recordRateLimited backs off the creations of the MachineSet exponentially.
+func (l *creationRateLimiter) recordRateLimited(key types.NamespacedName) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	state, ok := l.machineSets[key]
+	if !ok {
+		state = &machineSetCreationLimiter{}
+		l.machineSets[key] = state
+	}
+
+	now := l.now()
+	if now.Before(state.backoffExpiry) {
+		// Already backing off, do not escalate again until the backoff expires.
+		return state.backoffExpiry.Sub(now)
+	}
+
+	backoff := creationBackoffMax
+	if state.failures < 16 {
+		if d := creationBackoffBase << uint(state.failures); d < creationBackoffMax {
+			backoff = d
+		}
+	}
+	state.failures++
+	state.backoffExpiry = now.Add(backoff)
+	return backoff
+}
+
+// recordSuccess resets the backoff of the MachineSet.
+func (l *creationRateLimiter) recordSuccess(key types.NamespacedName) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if state, ok := l.machineSets[key]; ok {
+		state.failures = 0
+		state.backoffExpiry = time.Time{}
+	}
+}
+
+// forget removes the state of the MachineSet.
+func (l *creationRateLimiter) forget(key types.NamespacedName) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	delete(l.machineSets, key)
+}
+
+// isRateLimitedMessage returns true if the error message indicates a request was throttled.
+func isRateLimitedMessage(msg string) bool {
+	lower := strings.ToLower(msg)
+	for _, fragment := range rateLimitedMessages {
+		if strings.Contains(lower, strings.ToLower(fragment)) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRateLimitedMachines returns true if any Machine still provisioning reports that the
+// cloud provider is throttling its creation.
+func hasRateLimitedMachines(machines []*machinev1.Machine) bool {
+	for _, machine := range machines {
+		if isMachineProvisioning(machine) && machine.Status.ErrorMessage != nil && isRateLimitedMessage(*machine.Status.ErrorMessage) {
+			return true
+		}
+	}
+	return false
+}
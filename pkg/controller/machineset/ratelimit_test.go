@@ -0,0 +1,142 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+)
+
+func TestCreationRateLimiter(t *testing.T) {
+	ms := &machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{Name: "machineset1", Namespace: "default"}}
+	msKey := types.NamespacedName{Namespace: "default", Name: "machineset1"}
+	other := &machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{Name: "machineset2", Namespace: "default"}}
+
+	t.Run("without limits", func(t *testing.T) {
+		g := NewWithT(t)
+
+		l := newCreationRateLimiter(0, 0)
+		allowed, retryAfter := l.allowedCreations(ms, 0, 300)
+		g.Expect(allowed).To(Equal(300))
+		g.Expect(retryAfter).To(BeZero())
+	})
+
+	t.Run("with a per MachineSet limit", func(t *testing.T) {
+		g := NewWithT(t)
+
+		now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		l := newCreationRateLimiter(0, 0)
+		l.nowFunc = func() time.Time { return now }
+
+		allowed, retryAfter := l.allowedCreations(ms, 6, 300)
+		g.Expect(allowed).To(Equal(6))
+		g.Expect(retryAfter).To(Equal(10 * time.Second))
+
+		// Other MachineSets are not affected.
+		allowed, _ = l.allowedCreations(other, 0, 300)
+		g.Expect(allowed).To(Equal(300))
+
+		now = now.Add(20 * time.Second)
+		allowed, _ = l.allowedCreations(ms, 6, 300)
+		g.Expect(allowed).To(Equal(2))
+	})
+
+	t.Run("with a global limit", func(t *testing.T) {
+		g := NewWithT(t)
+
+		now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		l := newCreationRateLimiter(1, 5)
+		l.nowFunc = func() time.Time { return now }
+
+		allowed, retryAfter := l.allowedCreations(ms, 0, 3)
+		g.Expect(allowed).To(Equal(3))
+		g.Expect(retryAfter).To(BeZero())
+
+		allowed, retryAfter = l.allowedCreations(other, 0, 3)
+		g.Expect(allowed).To(Equal(2))
+		g.Expect(retryAfter).To(Equal(time.Second))
+	})
+
+	t.Run("with exponential backoff", func(t *testing.T) {
+		g := NewWithT(t)
+
+		now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		l := newCreationRateLimiter(0, 0)
+		l.nowFunc = func() time.Time { return now }
+
+		g.Expect(l.recordRateLimited(msKey)).To(Equal(creationBackoffBase))
+		// The backoff does not escalate until it expires.
+		g.Expect(l.recordRateLimited(msKey)).To(Equal(creationBackoffBase))
+
+		allowed, retryAfter := l.allowedCreations(ms, 0, 3)
+		g.Expect(allowed).To(BeZero())
+		g.Expect(retryAfter).To(Equal(creationBackoffBase))
+
+		now = now.Add(creationBackoffBase)
+		g.Expect(l.recordRateLimited(msKey)).To(Equal(2 * creationBackoffBase))
+
+		for i := 0; i < 10; i++ {
+			now = now.Add(creationBackoffMax)
+			l.recordRateLimited(msKey)
+		}
+		now = now.Add(creationBackoffMax)
+		g.Expect(l.recordRateLimited(msKey)).To(Equal(creationBackoffMax))
+
+		l.recordSuccess(msKey)
+		allowed, _ = l.allowedCreations(ms, 0, 3)
+		g.Expect(allowed).To(Equal(3))
+	})
+}
+
+func TestGetMachineCreationRate(t *testing.T) {
+	g := NewWithT(t)
+
+	ms := &machinev1.MachineSet{}
+	g.Expect(getMachineCreationRate(ms)).To(BeZero())
+
+	ms.Annotations = map[string]string{MachineCreationRateAnnotation: "20"}
+	g.Expect(getMachineCreationRate(ms)).To(Equal(20))
+
+	// An invalid creation rate does not limit the creations.
+	ms.Annotations = map[string]string{MachineCreationRateAnnotation: "0"}
+	g.Expect(getMachineCreationRate(ms)).To(BeZero())
+	_, err := ParseMachineCreationRate("0")
+	g.Expect(err).To(MatchError(ContainSubstring("must be a positive integer")))
+}
+
+func TestHasRateLimitedMachines(t *testing.T) {
+	g := NewWithT(t)
+
+	provisioning := machinev1.PhaseProvisioning
+	running := machinev1.PhaseRunning
+
+	g.Expect(hasRateLimitedMachines([]*machinev1.Machine{
+		{Status: machinev1.MachineStatus{Phase: &provisioning, ErrorMessage: pointer.String("RequestLimitExceeded: Request limit exceeded.")}},
+	})).To(BeTrue())
+	g.Expect(hasRateLimitedMachines([]*machinev1.Machine{
+		{Status: machinev1.MachineStatus{Phase: &provisioning, ErrorMessage: pointer.String("InsufficientInstanceCapacity")}},
+	})).To(BeFalse())
+	g.Expect(hasRateLimitedMachines([]*machinev1.Machine{
+		{Status: machinev1.MachineStatus{Phase: &running, ErrorMessage: pointer.String("Throttling: Rate exceeded")}},
+	})).To(BeFalse())
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ReplicaHistoryAnnotation holds the last changes of the replicas of the MachineSet, oldest first, as JSON.
+	// The MachineSet status does not have a field for it, so it is stored in this annotation, so that it is
+	// possible to tell whether the autoscaler, a user or a GitOps tool changed the replicas.
+	ReplicaHistoryAnnotation = "machine.openshift.io/replica-history"
+
+	// maxReplicaHistory is the number of replica changes kept in the ReplicaHistoryAnnotation.
+	maxReplicaHistory = 10
+)
+
+// ReplicaChange is a change of the replicas of a MachineSet.
+type ReplicaChange struct {
+	// Old is the number of replicas before the change, unset for the replicas the MachineSet was first observed with.
+	Old *int32 `json:"old,omitempty"`
+	// New is the number of replicas after the change.
+	New int32 `json:"new"`
+	// Manager is the field manager of the replicas, which made the change, e.g. cluster-autoscaler or kubectl-edit.
+	Manager string `json:"manager,omitempty"`
+	// Subresource is the subresource the change was made through, e.g. scale.
+	Subresource string `json:"subresource,omitempty"`
+	// Time is when the change was observed.
+	Time time.Time `json:"time"`
+}
+
+// String describes the change, e.g. "from 2 to 3 replicas by cluster-autoscaler".
+func (c ReplicaChange) String() string {
+	s := fmt.Sprintf("to %d replicas", c.New)
+	if c.Old != nil {
+		s = fmt.Sprintf("from %d %s", *c.Old, s)
+	}
+	if c.Manager != "" {
+		s = fmt.Sprintf("%s by %s", s, c.Manager)
+	}
+	if c.Subresource != "" {
+		s = fmt.Sprintf("%s through the %s subresource", s, c.Subresource)
+	}
+	return s
+}
+
+// getReplicaHistory returns the replica changes recorded in the ReplicaHistoryAnnotation of the MachineSet.
+func getReplicaHistory(ms *machinev1.MachineSet) ([]ReplicaChange, error) {
+	raw, ok := ms.Annotations[ReplicaHistoryAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var history []ReplicaChange
+	if err := json.Unmarshal([]byte(raw), &history); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", ReplicaHistoryAnnotation, err)
+	}
+	return history, nil
+}
+
+// getReplicasManager returns the field manager which last set the replicas of the MachineSet, with the
+// subresource it set them through. Several managers own the replicas when they applied the same value,
+// the latest of them is returned.
+func getReplicasManager(ms *machinev1.MachineSet) (metav1.ManagedFieldsEntry, bool) {
+	var manager metav1.ManagedFieldsEntry
+	var found bool
+	for _, entry := range ms.ManagedFields {
+		if entry.FieldsV1 == nil || !ownsReplicas(entry.FieldsV1.Raw) {
+			continue
+		}
+		if !found || manager.Time == nil || (entry.Time != nil && !entry.Time.Before(manager.Time)) {
+			manager, found = entry, true
+		}
+	}
+	return manager, found
+}
+
+// ownsReplicas returns true if the managed fields include spec.replicas.
+func ownsReplicas(fieldsV1 []byte) bool {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(fieldsV1, &fields); err != nil {
+		return false
+	}
+	spec := map[string]json.RawMessage{}
+	if err := json.Unmarshal(fields["f:spec"], &spec); err != nil {
+		return false
+	}
+	_, ok := spec["f:replicas"]
+	return ok
+}
+
+// setReplicaHistory records the change of the replicas of the MachineSet since the last recorded change,
+// and returns it. It returns nil when the replicas did not change.
+func setReplicaHistory(ms *machinev1.MachineSet, now time.Time) (*ReplicaChange, error) {
+	if ms.Spec.Replicas == nil {
+		return nil, nil
+	}
+	history, err := getReplicaHistory(ms)
+	if err != nil {
+		// The history cannot be extended, start a new one.
+		klog.Warningf("%v: %v, discarding the replica history", ms.Name, err)
+		history = nil
+	}
+
+	change := ReplicaChange{New: *ms.Spec.Replicas, Time: now}
+	if len(history) > 0 {
+		last := history[len(history)-1].New
+		if last == change.New {
+			return nil, nil
+		}
+		change.Old = &last
+	}
+	if manager, ok := getReplicasManager(ms); ok {
+		change.Manager = manager.Manager
+		change.Subresource = manager.Subresource
+	}
+
+	history = append(history, change)
+	if len(history) > maxReplicaHistory {
+		history = history[len(history)-maxReplicaHistory:]
+	}
+	raw, err := json.Marshal(history)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode replica history: %w", err)
+	}
+	if ms.Annotations == nil {
+		ms.Annotations = make(map[string]string)
+	}
+	ms.Annotations[ReplicaHistoryAnnotation] = string(raw)
+	return &change, nil
+}
+
+// recordReplicaChange records the change of the replicas of the MachineSet in its ReplicaHistoryAnnotation,
+// and emits an event for it.
+func (r *ReconcileMachineSet) recordReplicaChange(ctx context.Context, ms *machinev1.MachineSet) error {
+	original := ms.DeepCopy()
+	change, err := setReplicaHistory(ms, r.now())
+	if err != nil || change == nil {
+		return err
+	}
+	if err := r.Client.Patch(ctx, ms, client.MergeFrom(original)); err != nil {
+		return err
+	}
+
+	klog.V(3).Infof("%v: replicas changed %s", ms.Name, change)
+	r.recorder.Eventf(ms, corev1.EventTypeNormal, "ReplicasChanged", "Replicas changed %s", change)
+	return nil
+}
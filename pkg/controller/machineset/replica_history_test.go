@@ -0,0 +1,179 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func managedFields(manager, subresource string, t time.Time, fields string) metav1.ManagedFieldsEntry {
+	return metav1.ManagedFieldsEntry{
+		Manager:     manager,
+		Operation:   metav1.ManagedFieldsOperationUpdate,
+		Subresource: subresource,
+		Time:        &metav1.Time{Time: t},
+		FieldsType:  "FieldsV1",
+		FieldsV1:    &metav1.FieldsV1{Raw: []byte(fields)},
+	}
+}
+
+func TestSetReplicaHistory(t *testing.T) {
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	replicasFields := `{"f:spec":{"f:replicas":{}}}`
+	labelsFields := `{"f:metadata":{"f:labels":{}}}`
+
+	testCases := []struct {
+		name            string
+		replicas        int32
+		history         string
+		managedFields   []metav1.ManagedFieldsEntry
+		expectedChange  *ReplicaChange
+		expectedHistory string
+	}{
+		{
+			name:     "when the replicas are first observed",
+			replicas: 3,
+			managedFields: []metav1.ManagedFieldsEntry{
+				managedFields("argocd-controller", "", now.Add(-time.Hour), replicasFields),
+			},
+			expectedChange:  &ReplicaChange{New: 3, Manager: "argocd-controller", Time: now},
+			expectedHistory: `[{"new":3,"manager":"argocd-controller","time":"2026-10-16T08:00:00Z"}]`,
+		},
+		{
+			name:            "when the replicas did not change",
+			replicas:        3,
+			history:         `[{"new":3,"manager":"argocd-controller","time":"2026-10-16T07:00:00Z"}]`,
+			expectedHistory: `[{"new":3,"manager":"argocd-controller","time":"2026-10-16T07:00:00Z"}]`,
+		},
+		{
+			name:     "when the replicas are changed through the scale subresource",
+			replicas: 5,
+			history:  `[{"new":3,"manager":"argocd-controller","time":"2026-10-16T07:00:00Z"}]`,
+			managedFields: []metav1.ManagedFieldsEntry{
+				managedFields("argocd-controller", "", now.Add(-time.Hour), labelsFields),
+				managedFields("cluster-autoscaler", "scale", now.Add(-time.Minute), replicasFields),
+			},
+			expectedChange: &ReplicaChange{Old: pointer.Int32(3), New: 5, Manager: "cluster-autoscaler", Subresource: "scale", Time: now},
+			expectedHistory: `[{"new":3,"manager":"argocd-controller","time":"2026-10-16T07:00:00Z"},` +
+				`{"old":3,"new":5,"manager":"cluster-autoscaler","subresource":"scale","time":"2026-10-16T08:00:00Z"}]`,
+		},
+		{
+			name:     "with several managers of the replicas",
+			replicas: 2,
+			history:  `[{"new":3,"time":"2026-10-16T07:00:00Z"}]`,
+			managedFields: []metav1.ManagedFieldsEntry{
+				managedFields("kubectl-edit", "", now.Add(-time.Minute), replicasFields),
+				managedFields("argocd-controller", "", now.Add(-time.Hour), replicasFields),
+			},
+			expectedChange: &ReplicaChange{Old: pointer.Int32(3), New: 2, Manager: "kubectl-edit", Time: now},
+			expectedHistory: `[{"new":3,"time":"2026-10-16T07:00:00Z"},` +
+				`{"old":3,"new":2,"manager":"kubectl-edit","time":"2026-10-16T08:00:00Z"}]`,
+		},
+		{
+			name:            "with an invalid history",
+			replicas:        1,
+			history:         `not json`,
+			expectedChange:  &ReplicaChange{New: 1, Time: now},
+			expectedHistory: `[{"new":1,"time":"2026-10-16T08:00:00Z"}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker", ManagedFields: tc.managedFields},
+				Spec:       machinev1.MachineSetSpec{Replicas: pointer.Int32(tc.replicas)},
+			}
+			if tc.history != "" {
+				ms.Annotations = map[string]string{ReplicaHistoryAnnotation: tc.history}
+			}
+
+			change, err := setReplicaHistory(ms, now)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(change).To(Equal(tc.expectedChange))
+			g.Expect(ms.Annotations).To(HaveKeyWithValue(ReplicaHistoryAnnotation, tc.expectedHistory))
+		})
+	}
+}
+
+func TestSetReplicaHistoryLimit(t *testing.T) {
+	g := NewWithT(t)
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+
+	ms := &machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{Name: "worker"}}
+	for i := 0; i <= maxReplicaHistory; i++ {
+		ms.Spec.Replicas = pointer.Int32(int32(i))
+		_, err := setReplicaHistory(ms, now.Add(time.Duration(i)*time.Minute))
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+
+	history, err := getReplicaHistory(ms)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(history).To(HaveLen(maxReplicaHistory))
+	g.Expect(history[0].Old).To(HaveValue(BeEquivalentTo(0)))
+	g.Expect(history[maxReplicaHistory-1].New).To(BeEquivalentTo(maxReplicaHistory))
+}
+
+func TestRecordReplicaChange(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(machinev1.AddToScheme(scheme.Scheme)).To(Succeed())
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+
+	ms := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:          "worker",
+			Namespace:     "default",
+			Annotations:   map[string]string{ReplicaHistoryAnnotation: `[{"new":3,"time":"2026-10-16T07:00:00Z"}]`},
+			ManagedFields: []metav1.ManagedFieldsEntry{managedFields("cluster-autoscaler", "scale", now, `{"f:spec":{"f:replicas":{}}}`)},
+		},
+		Spec: machinev1.MachineSetSpec{Replicas: pointer.Int32(5)},
+	}
+	recorder := record.NewFakeRecorder(10)
+	r := &ReconcileMachineSet{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ms).Build(),
+		recorder: recorder,
+		nowFunc:  func() time.Time { return now },
+	}
+
+	g.Expect(r.recordReplicaChange(context.TODO(), ms)).To(Succeed())
+	got := &machinev1.MachineSet{}
+	g.Expect(r.Client.Get(context.TODO(), client.ObjectKeyFromObject(ms), got)).To(Succeed())
+	history, err := getReplicaHistory(got)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(history).To(HaveLen(2))
+	g.Expect(recorder.Events).To(Receive(Equal("Normal ReplicasChanged Replicas changed from 3 to 5 replicas by cluster-autoscaler through the scale subresource")))
+
+	// Nothing is recorded until the replicas change again.
+	g.Expect(r.recordReplicaChange(context.TODO(), got)).To(Succeed())
+	g.Expect(recorder.Events).ToNot(Receive())
+	history, err = getReplicaHistory(got)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(history).To(HaveLen(2))
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// MachineTemplateHashLabel is set on Machines created by a MachineSet to the hash of the
+	// machine template they were created from, in the same way as pod-template-hash for Pods.
+	// It tells which Machines were created from which revision of the template.
+	MachineTemplateHashLabel = "machine.openshift.io/machine-template-hash"
+
+	// RolloutPartitionAnnotation enables replacing the Machines of a MachineSet when its machine template changes.
+	// Machines are ordered from the oldest to the newest, and only the outdated Machines with an index greater
+	// than or equal to the partition are replaced, one at a time. A partition of 0 replaces all outdated Machines.
+	// Lowering the partition progressively lets a template change be tried on a subset of the Machines first.
+	RolloutPartitionAnnotation = "machine.openshift.io/rollout-partition"
+)
+
+// computeTemplateHash returns a hash of the machine template of the MachineSet.
+func computeTemplateHash(template *machinev1.MachineTemplateSpec) (string, error) {
+	raw, err := json.Marshal(template)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal machine template: %w", err)
+	}
+
+	hasher := fnv.New32a()
+	hasher.Write(raw)
+	return rand.SafeEncodeString(strconv.FormatUint(uint64(hasher.Sum32()), 10)), nil
+}
+
+// getRolloutPartition returns the rollout partition of the MachineSet, and whether it is set.
+func getRolloutPartition(ms *machinev1.MachineSet) (int, bool, error) {
+	value, ok := ms.Annotations[RolloutPartitionAnnotation]
+	if !ok {
+		return 0, false, nil
+	}
+
+	partition, err := ParseRolloutPartition(value)
+	if err != nil {
+		return 0, false, err
+	}
+	return partition, true, nil
+}
+
+// ParseRolloutPartition parses the value of the RolloutPartitionAnnotation.
+func ParseRolloutPartition(value string) (int, error) {
+	partition, err := strconv.Atoi(value)
+	if err != nil || partition < 0 {
+		return 0, fmt.Errorf("invalid %s annotation %q: must be a non-negative integer", RolloutPartitionAnnotation, value)
+	}
+	return partition, nil
+}
+
+// sortMachinesByAge returns a copy of the machines ordered from the oldest to the newest,
+// which determines the index of each Machine for the rollout partition.
+func sortMachinesByAge(machines []*machinev1.Machine) []*machinev1.Machine {
+	sorted := append([]*machinev1.Machine{}, machines...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].CreationTimestamp.Equal(&sorted[j].CreationTimestamp) {
+			return sorted[i].CreationTimestamp.Before(&sorted[j].CreationTimestamp)
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// rolloutMachines replaces an outdated Machine at or above the rollout partition of the MachineSet, if any.
+// Machines are replaced one at a time: a Machine is only deleted once the MachineSet has its desired
+// number of replicas and all of them have a Node.
+// The deleted Machine is left out of the returned Machines so that syncing the replicas replaces it.
+func (r *ReconcileMachineSet) rolloutMachines(ms *machinev1.MachineSet, machines []*machinev1.Machine) ([]*machinev1.Machine, error) {
+	partition, ok, err := getRolloutPartition(ms)
+	if err != nil {
+		// The webhook rejects invalid partitions, no Machines are replaced until the partition is fixed.
+		r.reportInvalidAnnotation(ms, RolloutPartitionAnnotation, "InvalidRolloutPartition", err)
+		return machines, nil
+	}
+	if !ok || ms.Spec.Replicas == nil || len(machines) != int(*ms.Spec.Replicas) {
+		return machines, nil
+	}
+
+	for _, machine := range machines {
+		if machine.Status.NodeRef == nil {
+			klog.V(4).Infof("%v: waiting for machine %s to get a node before continuing the rollout", ms.Name, machine.Name)
+			return machines, nil
+		}
+	}
+
+	hash, err := computeTemplateHash(&ms.Spec.Template)
+	if err != nil {
+		return machines, err
+	}
+
+	sorted := sortMachinesByAge(machines)
+	for i := partition; i < len(sorted); i++ {
+		machine := sorted[i]
+		if machine.Labels[MachineTemplateHashLabel] == hash {
+			continue
+		}
+
+		klog.Infof("%v: replacing machine %s at index %d which is not up to date with the machine template", ms.Name, machine.Name, i)
+		if err := r.Client.Delete(context.Background(), machine); err != nil && !apierrors.IsNotFound(err) {
+			return machines, fmt.Errorf("failed to delete outdated machine %s: %w", machine.Name, err)
+		}
+		r.recorder.Eventf(ms, corev1.EventTypeNormal, "RolloutMachine", "Deleted outdated machine %s to replace it", machine.Name)
+
+		var remaining []*machinev1.Machine
+		for _, m := range machines {
+			if m != machine {
+				remaining = append(remaining, m)
+			}
+		}
+		return remaining, nil
+	}
+
+	return machines, nil
+}
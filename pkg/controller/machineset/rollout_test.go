@@ -0,0 +1,236 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestComputeTemplateHash(t *testing.T) {
+	g := NewWithT(t)
+
+	template := &machinev1.MachineTemplateSpec{
+		Spec: machinev1.MachineSpec{
+			ProviderSpec: machinev1.ProviderSpec{Value: &runtime.RawExtension{Raw: []byte(`{"instanceType":"m5.large"}`)}},
+		},
+	}
+	hash, err := computeTemplateHash(template)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(hash).ToNot(BeEmpty())
+
+	again, err := computeTemplateHash(template.DeepCopy())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(again).To(Equal(hash))
+
+	template.Spec.ProviderSpec.Value.Raw = []byte(`{"instanceType":"m5.xlarge"}`)
+	changed, err := computeTemplateHash(template)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(changed).ToNot(Equal(hash))
+}
+
+func TestCreateMachineTemplateHash(t *testing.T) {
+	g := NewWithT(t)
+
+	ms := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "machineset1", Namespace: "default"},
+		Spec: machinev1.MachineSetSpec{
+			Template: machinev1.MachineTemplateSpec{
+				ObjectMeta: machinev1.ObjectMeta{Labels: map[string]string{"foo": "bar"}},
+			},
+		},
+	}
+	hash, err := computeTemplateHash(&ms.Spec.Template)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	machine, err := (&ReconcileMachineSet{}).createMachine(ms)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(machine.Labels).To(Equal(map[string]string{"foo": "bar", MachineTemplateHashLabel: hash}))
+	// The template labels must not be modified.
+	g.Expect(ms.Spec.Template.ObjectMeta.Labels).To(Equal(map[string]string{"foo": "bar"}))
+}
+
+func TestRolloutMachines(t *testing.T) {
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+
+	template := machinev1.MachineTemplateSpec{
+		ObjectMeta: machinev1.ObjectMeta{Labels: map[string]string{"foo": "bar"}},
+	}
+	hash, err := computeTemplateHash(&template)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	newMachine := func(name string, index int, templateHash string, hasNode bool) *machinev1.Machine {
+		m := &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(start.Add(time.Duration(index) * time.Hour)),
+				Labels:            map[string]string{MachineTemplateHashLabel: templateHash},
+			},
+		}
+		if hasNode {
+			m.Status.NodeRef = &corev1.ObjectReference{Name: name}
+		}
+		return m
+	}
+
+	testCases := []struct {
+		name              string
+		partition         *string
+		replicas          int32
+		machines          []*machinev1.Machine
+		expectedRemaining []string
+	}{
+		{
+			name:     "without a partition",
+			replicas: 2,
+			machines: []*machinev1.Machine{
+				newMachine("m0", 0, "old", true),
+				newMachine("m1", 1, "old", true),
+			},
+			expectedRemaining: []string{"m0", "m1"},
+		},
+		{
+			name:      "with an invalid partition",
+			partition: pointer.String("-1"),
+			replicas:  2,
+			machines: []*machinev1.Machine{
+				newMachine("m0", 0, "old", true),
+				newMachine("m1", 1, "old", true),
+			},
+			expectedRemaining: []string{"m0", "m1"},
+		},
+		{
+			name:      "replaces the first outdated machine at or above the partition",
+			partition: pointer.String("1"),
+			replicas:  3,
+			machines: []*machinev1.Machine{
+				newMachine("m2", 2, "old", true),
+				newMachine("m0", 0, "old", true),
+				newMachine("m1", 1, hash, true),
+			},
+			expectedRemaining: []string{"m0", "m1"},
+		},
+		{
+			name:      "does nothing when the machines below the partition are outdated",
+			partition: pointer.String("2"),
+			replicas:  3,
+			machines: []*machinev1.Machine{
+				newMachine("m0", 0, "old", true),
+				newMachine("m1", 1, "old", true),
+				newMachine("m2", 2, hash, true),
+			},
+			expectedRemaining: []string{"m0", "m1", "m2"},
+		},
+		{
+			name:      "waits for the replicas to be created",
+			partition: pointer.String("0"),
+			replicas:  3,
+			machines: []*machinev1.Machine{
+				newMachine("m0", 0, "old", true),
+				newMachine("m1", 1, "old", true),
+			},
+			expectedRemaining: []string{"m0", "m1"},
+		},
+		{
+			name:      "waits for all machines to have a node",
+			partition: pointer.String("0"),
+			replicas:  2,
+			machines: []*machinev1.Machine{
+				newMachine("m0", 0, "old", true),
+				newMachine("m1", 1, hash, false),
+			},
+			expectedRemaining: []string{"m0", "m1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "machineset1", Namespace: "default"},
+				Spec: machinev1.MachineSetSpec{
+					Replicas: pointer.Int32(tc.replicas),
+					Template: template,
+				},
+			}
+			if tc.partition != nil {
+				ms.Annotations = map[string]string{RolloutPartitionAnnotation: *tc.partition}
+			}
+
+			builder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+			for _, m := range tc.machines {
+				builder = builder.WithObjects(m.DeepCopy())
+			}
+			r := &ReconcileMachineSet{
+				Client:   builder.Build(),
+				scheme:   scheme.Scheme,
+				recorder: record.NewFakeRecorder(32),
+			}
+
+			remaining, err := r.rolloutMachines(ms, tc.machines)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			var names []string
+			for _, m := range sortMachinesByAge(remaining) {
+				names = append(names, m.Name)
+			}
+			g.Expect(names).To(Equal(tc.expectedRemaining))
+		})
+	}
+}
+
+func TestRolloutMachinesReportsInvalidPartitionOnce(t *testing.T) {
+	g := NewWithT(t)
+
+	ms := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "machineset1",
+			Namespace:   "default",
+			Annotations: map[string]string{RolloutPartitionAnnotation: "-1"},
+		},
+	}
+	recorder := record.NewFakeRecorder(32)
+	r := &ReconcileMachineSet{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(),
+		scheme:   scheme.Scheme,
+		recorder: recorder,
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := r.rolloutMachines(ms, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	g.Expect(recorder.Events).To(HaveLen(1))
+	g.Expect(<-recorder.Events).To(HavePrefix("Warning InvalidRolloutPartition"))
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// refreshDeletionCandidates re-reads the Machines of the MachineSet from the API server before the Machines to
+// delete are chosen. The autoscaler marks the Machines it has drained with the DeleteNodeAnnotation before
+// decreasing the replicas through the scale subresource, but the cache may observe the new replicas before the
+// annotations, in which case a different Machine than the drained one would be deleted.
+// The Machines which are gone or being deleted are left out, and lower the number of Machines to delete.
+func (r *ReconcileMachineSet) refreshDeletionCandidates(ctx context.Context, ms *machinev1.MachineSet, machines []*machinev1.Machine, diff int) ([]*machinev1.Machine, int, error) {
+	if r.apiReader == nil {
+		return machines, diff, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&ms.Spec.Selector)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse MachineSet %q label selector: %w", ms.Name, err)
+	}
+	machineList := &machinev1.MachineList{}
+	if err := r.apiReader.List(ctx, machineList, client.InNamespace(ms.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, 0, fmt.Errorf("failed to list machines: %w", err)
+	}
+	current := make(map[string]*machinev1.Machine, len(machineList.Items))
+	for i := range machineList.Items {
+		current[string(machineList.Items[i].UID)] = &machineList.Items[i]
+	}
+
+	var refreshed []*machinev1.Machine
+	for _, machine := range machines {
+		m, ok := current[string(machine.UID)]
+		if !ok || m.DeletionTimestamp != nil {
+			klog.V(3).Infof("%v: machine %s is already gone or being deleted", ms.Name, machine.Name)
+			diff--
+			continue
+		}
+		refreshed = append(refreshed, m)
+	}
+	return refreshed, diff, nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestScaleDownHonoursLatestDeleteAnnotations(t *testing.T) {
+	g := NewWithT(t)
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+
+	ms := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "machineset", Namespace: "default"},
+		Spec: machinev1.MachineSetSpec{
+			Replicas: pointer.Int32(2),
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+		},
+	}
+	newMachine := func(name string) *machinev1.Machine {
+		return &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				UID:         types.UID(name),
+				Labels:      map[string]string{"foo": "bar"},
+				Annotations: map[string]string{},
+			},
+		}
+	}
+
+	// The cache has not observed the annotation the autoscaler set on the drained machine yet.
+	var cached, current []*machinev1.Machine
+	for _, name := range []string{"machine-a", "machine-b", "machine-c", "machine-d"} {
+		cached = append(cached, newMachine(name))
+		current = append(current, newMachine(name))
+	}
+	current[1].Annotations[DeleteNodeAnnotation] = "true"
+	// A machine deleted meanwhile lowers the number of machines to delete.
+	now := metav1.Now()
+	current[3].DeletionTimestamp = &now
+	current[3].Finalizers = []string{machinev1.MachineFinalizer}
+
+	cachedBuilder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+	apiBuilder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+	for i := range cached {
+		cachedBuilder = cachedBuilder.WithObjects(cached[i].DeepCopy())
+		apiBuilder = apiBuilder.WithObjects(current[i].DeepCopy())
+	}
+
+	r := &ReconcileMachineSet{
+		Client:       cachedBuilder.Build(),
+		apiReader:    apiBuilder.Build(),
+		scheme:       scheme.Scheme,
+		recorder:     record.NewFakeRecorder(32),
+		expectations: newUIDTrackingExpectations(),
+	}
+
+	g.Expect(r.syncReplicas(ms, cached)).To(Succeed())
+
+	for _, m := range cached {
+		err := r.Get(context.Background(), client.ObjectKeyFromObject(m), &machinev1.Machine{})
+		if m.Name == "machine-b" {
+			g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), "expected machine %s to be deleted", m.Name)
+		} else {
+			g.Expect(err).ToNot(HaveOccurred(), "expected machine %s to be kept", m.Name)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+)
+
+const (
+	// StandbyReplicasAnnotation is the number of standby Machines the MachineSet keeps provisioned on top of
+	// its replicas. Standby Machines are not counted in the replicas of the MachineSet, and are promoted to
+	// replicas when the MachineSet scales up, before any new Machine is created. They are still owned by the
+	// MachineSet, the cluster autoscaler seeing more Machines than replicas in its node group.
+	StandbyReplicasAnnotation = "machine.openshift.io/standby-replicas"
+
+	// StandbyModeAnnotation is how the standby Machines of the MachineSet are kept, either StandbyModeCordoned
+	// (the default) or StandbyModePoweredOff.
+	StandbyModeAnnotation = "machine.openshift.io/standby-mode"
+
+	// StandbyModeCordoned keeps the standby Machines running, with their Nodes cordoned. They are promoted
+	// in seconds, by uncordoning their Nodes.
+	StandbyModeCordoned = "Cordoned"
+	// StandbyModePoweredOff also keeps the instances of the standby Machines powered off, saving their compute
+	// cost. They are promoted by powering them on. On the platforms which do not support the power state of
+	// Machines, they are kept running with their Nodes cordoned.
+	StandbyModePoweredOff = "PoweredOff"
+
+	// StandbyMachineAnnotation is set to "true" on the standby Machines of a MachineSet by the controller.
+	StandbyMachineAnnotation = "machine.openshift.io/standby"
+)
+
+// getStandbyReplicas returns the number of standby Machines of the MachineSet and how they are kept.
+func getStandbyReplicas(ms *machinev1.MachineSet) (int, string, error) {
+	value, ok := ms.Annotations[StandbyReplicasAnnotation]
+	if !ok {
+		return 0, "", nil
+	}
+	replicas, err := ParseStandbyReplicas(value)
+	if err != nil {
+		return 0, "", err
+	}
+	mode, err := ParseStandbyMode(ms.Annotations[StandbyModeAnnotation])
+	if err != nil {
+		return 0, "", err
+	}
+	return replicas, mode, nil
+}
+
+// ParseStandbyReplicas parses the value of the StandbyReplicasAnnotation.
+func ParseStandbyReplicas(value string) (int, error) {
+	replicas, err := strconv.Atoi(value)
+	if err != nil || replicas < 0 {
+		return 0, fmt.Errorf("invalid %s annotation %q: must be a non-negative integer", StandbyReplicasAnnotation, value)
+	}
+	return replicas, nil
+}
+
+// ParseStandbyMode parses the value of the StandbyModeAnnotation, defaulting to StandbyModeCordoned.
+func ParseStandbyMode(mode string) (string, error) {
+	switch mode {
+	case "", StandbyModeCordoned:
+		return StandbyModeCordoned, nil
+	case StandbyModePoweredOff:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid %s annotation %q: must be either %s or %s", StandbyModeAnnotation, mode, StandbyModeCordoned, StandbyModePoweredOff)
+	}
+}
+
+// isStandbyMachine returns true if the Machine is a standby Machine of its MachineSet.
+func isStandbyMachine(machine *machinev1.Machine) bool {
+	return machine.Annotations[StandbyMachineAnnotation] == "true"
+}
+
+// splitStandbyMachines separates the standby Machines of the MachineSet from its replicas.
+func splitStandbyMachines(machines []*machinev1.Machine) ([]*machinev1.Machine, []*machinev1.Machine) {
+	var replicas, standby []*machinev1.Machine
+	for _, machine := range machines {
+		if isStandbyMachine(machine) {
+			standby = append(standby, machine)
+		} else {
+			replicas = append(replicas, machine)
+		}
+	}
+	return replicas, standby
+}
+
+// sortStandbyMachinesByReadiness returns a copy of the standby Machines ordered from the readiest, the ones with
+// a Node, then the ones with an instance, to the least ready, each group from the oldest to the newest.
+func sortStandbyMachinesByReadiness(machines []*machinev1.Machine) []*machinev1.Machine {
+	readiness := func(machine *machinev1.Machine) int {
+		switch {
+		case machine.Status.NodeRef != nil:
+			return 0
+		case machine.Status.ProviderStatus != nil || (machine.Spec.ProviderID != nil && *machine.Spec.ProviderID != ""):
+			return 1
+		default:
+			return 2
+		}
+	}
+	sorted := sortMachinesByAge(machines)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return readiness(sorted[i]) < readiness(sorted[j])
+	})
+	return sorted
+}
+
+// promoteStandbyMachines turns the readiest standby Machines into replicas when the MachineSet has fewer
+// replicas than desired, by uncordoning or powering them on. It returns the replicas, including the promoted
+// Machines, and the remaining standby Machines.
+func (r *ReconcileMachineSet) promoteStandbyMachines(ctx context.Context, ms *machinev1.MachineSet, replicas, standby []*machinev1.Machine) ([]*machinev1.Machine, []*machinev1.Machine, error) {
+	if ms.Spec.Replicas == nil || len(standby) == 0 {
+		return replicas, standby, nil
+	}
+	missing := int(*ms.Spec.Replicas) - len(replicas)
+	if missing <= 0 {
+		return replicas, standby, nil
+	}
+
+	sorted := sortStandbyMachinesByReadiness(standby)
+	var promoted []string
+	for len(sorted) > 0 && len(promoted) < missing {
+		machine := sorted[0]
+		original := machine.DeepCopy()
+		delete(machine.Annotations, StandbyMachineAnnotation)
+		delete(machine.Annotations, machinecontroller.MachineDrainRequestedAnnotation)
+		delete(machine.Annotations, machinecontroller.MachinePowerStateAnnotation)
+		if err := r.Client.Patch(ctx, machine, client.MergeFrom(original)); err != nil {
+			if apierrors.IsNotFound(err) {
+				sorted = sorted[1:]
+				continue
+			}
+			return replicas, sorted, fmt.Errorf("failed to promote standby machine %s: %w", machine.Name, err)
+		}
+
+		replicas = append(replicas, machine)
+		sorted = sorted[1:]
+		promoted = append(promoted, machine.Name)
+	}
+
+	if len(promoted) > 0 {
+		klog.Infof("%v: promoted standby machines %s", ms.Name, strings.Join(promoted, ", "))
+		r.recorder.Eventf(ms, corev1.EventTypeNormal, "PromotedStandbyMachines", "Promoted standby machines %s", strings.Join(promoted, ", "))
+	}
+	return replicas, sorted, nil
+}
+
+// syncStandbyMachines creates or deletes standby Machines until the MachineSet has its number of standby
+// Machines. It must only be called once the MachineSet has its desired number of replicas.
+func (r *ReconcileMachineSet) syncStandbyMachines(ctx context.Context, ms *machinev1.MachineSet, replicas, standby []*machinev1.Machine) error {
+	desired, mode, err := getStandbyReplicas(ms)
+	if err != nil {
+		// The webhook rejects invalid standby annotations, the standby Machines are left alone until they are fixed.
+		r.reportInvalidAnnotation(ms, StandbyReplicasAnnotation, "InvalidStandbyReplicas", err)
+		return nil
+	}
+	msKey := client.ObjectKeyFromObject(ms)
+
+	if diff := len(standby) - desired; diff > 0 {
+		// The least ready standby Machines are deleted first.
+		sorted := sortStandbyMachinesByReadiness(standby)
+		toDelete := sorted[len(sorted)-diff:]
+		var uids []string
+		for _, machine := range toDelete {
+			uids = append(uids, string(machine.UID))
+		}
+		r.expectations.ExpectDeletions(msKey, uids)
+		for _, machine := range toDelete {
+			klog.Infof("%v: deleting standby machine %s", ms.Name, machine.Name)
+			if err := r.Client.Delete(ctx, machine); err != nil && !apierrors.IsNotFound(err) {
+				r.expectations.DeleteExpectations(msKey)
+				return fmt.Errorf("failed to delete standby machine %s: %w", machine.Name, err)
+			}
+		}
+		return nil
+	}
+
+	missing := desired - len(standby)
+	if missing <= 0 {
+		return nil
+	}
+
+	// Standby Machines are spread across the failure domains and the host groups, and named, along with the replicas.
+	all := append(append([]*machinev1.Machine{}, replicas...), standby...)
+	failureDomains, err := newFailureDomainPicker(ms, all)
+	if err != nil {
+		return err
+	}
+	namer, err := newMachineNamer(r.Client, ms, all)
+	if err != nil {
+		return err
+	}
+	hostGroups := newHostGroupPicker(ms, all)
+
+	r.expectations.ExpectCreations(msKey, missing)
+	for i := 0; i < missing; i++ {
+		machine, err := r.createMachine(ms)
+		if err == nil && failureDomains != nil {
+			err = setFailureDomain(machine, failureDomains.next())
+		}
+		if err == nil && hostGroups != nil {
+			setHostGroup(machine, hostGroups.next())
+		}
+		if err == nil && namer != nil {
+			err = namer.setName(ctx, machine)
+		}
+		if err != nil {
+			r.expectations.DeleteExpectations(msKey)
+			return fmt.Errorf("failed to build standby machine: %w", err)
+		}
+
+		// The annotations are shared with the MachineSet template, replace them rather than modify them.
+		annotations := make(map[string]string, len(machine.Annotations)+2)
+		for k, v := range machine.Annotations {
+			annotations[k] = v
+		}
+		annotations[StandbyMachineAnnotation] = "true"
+		// The Nodes of the standby Machines are cordoned in both modes, so that no workload lands on them
+		// when they cannot be powered off.
+		annotations[machinecontroller.MachineDrainRequestedAnnotation] = "true"
+		if mode == StandbyModePoweredOff {
+			annotations[machinecontroller.MachinePowerStateAnnotation] = string(machinecontroller.MachinePowerStateOff)
+		}
+		machine.Annotations = annotations
+
+		if err := createMachineObject(r.Client, machine); err != nil {
+			r.expectations.DeleteExpectations(msKey)
+			return fmt.Errorf("failed to create standby machine: %w", err)
+		}
+		klog.Infof("%v: created standby machine %s", ms.Name, machine.Name)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+)
+
+func newStandbyTestMachine(ms *machinev1.MachineSet, name string, age time.Duration, standby, hasNode bool) *machinev1.Machine {
+	m := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         ms.Namespace,
+			UID:               types.UID("uid-" + name),
+			CreationTimestamp: metav1.NewTime(time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC).Add(-age)),
+			Labels:            map[string]string{"foo": "bar"},
+			Annotations:       map[string]string{},
+			OwnerReferences:   []metav1.OwnerReference{*metav1.NewControllerRef(ms, controllerKind)},
+		},
+	}
+	if standby {
+		m.Annotations[StandbyMachineAnnotation] = "true"
+		m.Annotations[machinecontroller.MachineDrainRequestedAnnotation] = "true"
+	}
+	if hasNode {
+		m.Status.NodeRef = &corev1.ObjectReference{Name: name}
+	}
+	return m
+}
+
+func newStandbyTestMachineSet(replicas int32, annotations map[string]string) *machinev1.MachineSet {
+	return &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "machineset", Namespace: "default", UID: "machineset-uid", Annotations: annotations},
+		Spec: machinev1.MachineSetSpec{
+			Replicas: &replicas,
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			Template: machinev1.MachineTemplateSpec{
+				ObjectMeta: machinev1.ObjectMeta{
+					Labels:      map[string]string{"foo": "bar"},
+					Annotations: map[string]string{"template": "annotation"},
+				},
+			},
+		},
+	}
+}
+
+func TestPromoteStandbyMachines(t *testing.T) {
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+	g := NewWithT(t)
+
+	ms := newStandbyTestMachineSet(2, nil)
+	replica := newStandbyTestMachine(ms, "replica", 3*time.Hour, false, true)
+	provisioning := newStandbyTestMachine(ms, "standby-provisioning", 2*time.Hour, true, false)
+	ready := newStandbyTestMachine(ms, "standby-ready", time.Hour, true, true)
+
+	r := &ReconcileMachineSet{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(replica, provisioning, ready).Build(),
+		recorder: record.NewFakeRecorder(10),
+	}
+
+	replicas, standby := splitStandbyMachines([]*machinev1.Machine{replica, provisioning, ready})
+	replicas, standby, err := r.promoteStandbyMachines(context.TODO(), ms, replicas, standby)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// The standby Machine with a Node is promoted, although it is newer.
+	g.Expect(replicas).To(HaveLen(2))
+	g.Expect(replicas[1].Name).To(Equal("standby-ready"))
+	g.Expect(standby).To(ConsistOf(HaveField("Name", "standby-provisioning")))
+
+	promoted := &machinev1.Machine{}
+	g.Expect(r.Get(context.TODO(), client.ObjectKeyFromObject(ready), promoted)).To(Succeed())
+	g.Expect(promoted.Annotations).ToNot(HaveKey(StandbyMachineAnnotation))
+	g.Expect(promoted.Annotations).ToNot(HaveKey(machinecontroller.MachineDrainRequestedAnnotation))
+}
+
+func TestSyncStandbyMachines(t *testing.T) {
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+
+	testCases := []struct {
+		name                string
+		annotations         map[string]string
+		standby             []string
+		expectedStandby     int
+		expectedRemaining   []string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:            "without standby replicas",
+			expectedStandby: 0,
+		},
+		{
+			name:            "with missing standby machines",
+			annotations:     map[string]string{StandbyReplicasAnnotation: "2"},
+			expectedStandby: 2,
+			expectedAnnotations: map[string]string{
+				"template":               "annotation",
+				StandbyMachineAnnotation: "true",
+				machinecontroller.MachineDrainRequestedAnnotation: "true",
+			},
+		},
+		{
+			name:            "with powered off standby machines",
+			annotations:     map[string]string{StandbyReplicasAnnotation: "1", StandbyModeAnnotation: StandbyModePoweredOff},
+			expectedStandby: 1,
+			expectedAnnotations: map[string]string{
+				"template":               "annotation",
+				StandbyMachineAnnotation: "true",
+				machinecontroller.MachineDrainRequestedAnnotation: "true",
+				machinecontroller.MachinePowerStateAnnotation:     "Off",
+			},
+		},
+		{
+			name:              "with too many standby machines",
+			annotations:       map[string]string{StandbyReplicasAnnotation: "1"},
+			standby:           []string{"standby-ready", "standby-provisioning"},
+			expectedStandby:   1,
+			expectedRemaining: []string{"standby-ready"},
+		},
+		{
+			name:              "with an invalid number of standby machines",
+			annotations:       map[string]string{StandbyReplicasAnnotation: "-1"},
+			standby:           []string{"standby-ready"},
+			expectedStandby:   1,
+			expectedRemaining: []string{"standby-ready"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := newStandbyTestMachineSet(1, tc.annotations)
+			replica := newStandbyTestMachine(ms, "replica", 3*time.Hour, false, true)
+			objects := []client.Object{ms, replica}
+			var standby []*machinev1.Machine
+			for _, name := range tc.standby {
+				m := newStandbyTestMachine(ms, name, time.Hour, true, name == "standby-ready")
+				standby = append(standby, m)
+				objects = append(objects, m)
+			}
+
+			r := &ReconcileMachineSet{
+				Client:       applyClient{fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()},
+				recorder:     record.NewFakeRecorder(10),
+				expectations: newUIDTrackingExpectations(),
+			}
+			g.Expect(r.syncStandbyMachines(context.TODO(), ms, []*machinev1.Machine{replica}, standby)).To(Succeed())
+
+			machines := &machinev1.MachineList{}
+			g.Expect(r.List(context.TODO(), machines)).To(Succeed())
+			var standbyNames []string
+			for i := range machines.Items {
+				m := &machines.Items[i]
+				if !isStandbyMachine(m) {
+					continue
+				}
+				standbyNames = append(standbyNames, m.Name)
+				if tc.expectedAnnotations != nil {
+					g.Expect(m.Annotations).To(Equal(tc.expectedAnnotations))
+					g.Expect(metav1.IsControlledBy(m, ms)).To(BeTrue())
+				}
+			}
+			g.Expect(standbyNames).To(HaveLen(tc.expectedStandby))
+			if tc.expectedRemaining != nil {
+				g.Expect(standbyNames).To(ConsistOf(tc.expectedRemaining))
+			}
+			// The template of the MachineSet is left untouched.
+			g.Expect(ms.Spec.Template.Annotations).To(Equal(map[string]string{"template": "annotation"}))
+		})
+	}
+}
+
+func TestSyncStandbyMachinesReportsInvalidAnnotationsOnce(t *testing.T) {
+	g := NewWithT(t)
+
+	ms := newStandbyTestMachineSet(1, map[string]string{StandbyReplicasAnnotation: "1", StandbyModeAnnotation: "Hibernated"})
+	recorder := record.NewFakeRecorder(10)
+	r := &ReconcileMachineSet{
+		Client:       applyClient{fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ms).Build()},
+		recorder:     recorder,
+		expectations: newUIDTrackingExpectations(),
+	}
+
+	for i := 0; i < 3; i++ {
+		g.Expect(r.syncStandbyMachines(context.TODO(), ms, nil, nil)).To(Succeed())
+	}
+	g.Expect(recorder.Events).To(HaveLen(1))
+	g.Expect(<-recorder.Events).To(HavePrefix("Warning InvalidStandbyReplicas"))
+
+	// A different invalid annotation is reported again.
+	ms.Annotations[StandbyModeAnnotation] = StandbyModePoweredOff
+	ms.Annotations[StandbyReplicasAnnotation] = "many"
+	g.Expect(r.syncStandbyMachines(context.TODO(), ms, nil, nil)).To(Succeed())
+	g.Expect(recorder.Events).To(HaveLen(1))
+}
@@ -20,9 +20,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/klog/v2"
@@ -30,8 +35,29 @@ import (
 )
 
 const (
-	// The number of times we retry updating a MachineSet's status.
-	statusUpdateRetries = 1
+	// MachineSetScalingUpCondition is true while the MachineSet has fewer Machines than desired.
+	MachineSetScalingUpCondition machinev1.ConditionType = "ScalingUp"
+	// MachineSetScalingDownCondition is true while the MachineSet has more Machines than desired.
+	MachineSetScalingDownCondition machinev1.ConditionType = "ScalingDown"
+	// MachineSetScaleDownBlockedCondition is true when the MachineSet cannot scale down to its replicas without
+	// deleting Machines protected by the ScaleDownDisabledAnnotation.
+	MachineSetScaleDownBlockedCondition machinev1.ConditionType = "ScaleDownBlocked"
+	// MachineSetMachinesCreatedCondition is false when the MachineSet failed to create Machines.
+	MachineSetMachinesCreatedCondition machinev1.ConditionType = "MachinesCreated"
+	// MachineSetReplicaFailureCondition is true when Machines could not be created, or have failed.
+	MachineSetReplicaFailureCondition machinev1.ConditionType = "ReplicaFailure"
+
+	// UpdatedReplicasAnnotation is the number of Machines of the MachineSet created from its current machine template.
+	UpdatedReplicasAnnotation = "machine.openshift.io/updated-replicas"
+	// OutdatedReplicasAnnotation is the number of Machines of the MachineSet created from a previous machine template.
+	OutdatedReplicasAnnotation = "machine.openshift.io/outdated-replicas"
+
+	// MachineCreationFailedReason is used when the MachineSet failed to create Machines.
+	MachineCreationFailedReason = "MachineCreationFailed"
+	// ScaleDownDisabledReason is used when Machines of the MachineSet are protected from scaling down.
+	ScaleDownDisabledReason = "ScaleDownDisabled"
+	// MachineFailedReason is used when Machines of the MachineSet are in the Failed phase.
+	MachineFailedReason = "MachineFailed"
 )
 
 func (c *ReconcileMachineSet) calculateStatus(ms *machinev1.MachineSet, filteredMachines []*machinev1.Machine) machinev1.MachineSetStatus {
@@ -69,7 +95,7 @@ func (c *ReconcileMachineSet) calculateStatus(ms *machinev1.MachineSet, filtered
 	return newStatus
 }
 
-// updateMachineSetStatus attempts to update the Status.Replicas of the given MachineSet, with a single GET/PUT retry.
+// updateMachineSetStatus applies the status of the given MachineSet with server-side apply.
 func updateMachineSetStatus(c client.Client, ms *machinev1.MachineSet, newStatus machinev1.MachineSetStatus) (*machinev1.MachineSet, error) {
 	// This is the steady state. It happens when the MachineSet doesn't have any expectations, since
 	// we do a periodic relist every 30s. If the generations differ but the replicas are
@@ -83,42 +109,168 @@ func updateMachineSetStatus(c client.Client, ms *machinev1.MachineSet, newStatus
 	}
 
 	// Save the generation number we acted on, otherwise we might wrongfully indicate
-	// that we've seen a spec update when we retry.
-	// TODO: This can clobber an update if we allow multiple agents to write to the
-	// same status.
+	// that we've seen a spec update.
 	newStatus.ObservedGeneration = ms.Generation
 
-	var getErr, updateErr error
-	for i := 0; ; i++ {
-		var replicas int32
-		if ms.Spec.Replicas != nil {
-			replicas = *ms.Spec.Replicas
+	var replicas int32
+	if ms.Spec.Replicas != nil {
+		replicas = *ms.Spec.Replicas
+	}
+	klog.V(4).Infof(fmt.Sprintf("Updating status for %v: %s/%s, ", ms.Kind, ms.Namespace, ms.Name) +
+		fmt.Sprintf("replicas %d->%d (need %d), ", ms.Status.Replicas, newStatus.Replicas, replicas) +
+		fmt.Sprintf("fullyLabeledReplicas %d->%d, ", ms.Status.FullyLabeledReplicas, newStatus.FullyLabeledReplicas) +
+		fmt.Sprintf("readyReplicas %d->%d, ", ms.Status.ReadyReplicas, newStatus.ReadyReplicas) +
+		fmt.Sprintf("availableReplicas %d->%d, ", ms.Status.AvailableReplicas, newStatus.AvailableReplicas) +
+		fmt.Sprintf("sequence No: %v->%v", ms.Status.ObservedGeneration, newStatus.ObservedGeneration))
+
+	// Server-side apply does not require the latest resource version, so there is no
+	// conflict to retry on. Other failures are retried when the MachineSet is requeued.
+	if err := applyMachineSetStatus(c, ms, newStatus); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+// setMachineSetConditions sets the conditions of the MachineSet based on the Machines
+// observed at the start of the reconcile and the outcome of syncing the replicas.
+func setMachineSetConditions(ms *machinev1.MachineSet, filteredMachines []*machinev1.Machine, syncErr error) {
+	var replicas int32
+	if ms.Spec.Replicas != nil {
+		replicas = *ms.Spec.Replicas
+	}
+	current := int32(len(filteredMachines))
+
+	if current < replicas {
+		conditions.Set(ms, &machinev1.Condition{
+			Type:    MachineSetScalingUpCondition,
+			Status:  corev1.ConditionTrue,
+			Message: fmt.Sprintf("Scaling up from %d to %d replicas", current, replicas),
+		})
+	} else {
+		conditions.Set(ms, &machinev1.Condition{Type: MachineSetScalingUpCondition, Status: corev1.ConditionFalse})
+	}
+
+	if current > replicas {
+		conditions.Set(ms, &machinev1.Condition{
+			Type:    MachineSetScalingDownCondition,
+			Status:  corev1.ConditionTrue,
+			Message: fmt.Sprintf("Scaling down from %d to %d replicas", current, replicas),
+		})
+	} else {
+		conditions.Set(ms, &machinev1.Condition{Type: MachineSetScalingDownCondition, Status: corev1.ConditionFalse})
+	}
+
+	var protectedMachines []string
+	for _, machine := range filteredMachines {
+		if isScaleDownDisabled(machine) {
+			protectedMachines = append(protectedMachines, machine.Name)
 		}
-		klog.V(4).Infof(fmt.Sprintf("Updating status for %v: %s/%s, ", ms.Kind, ms.Namespace, ms.Name) +
-			fmt.Sprintf("replicas %d->%d (need %d), ", ms.Status.Replicas, newStatus.Replicas, replicas) +
-			fmt.Sprintf("fullyLabeledReplicas %d->%d, ", ms.Status.FullyLabeledReplicas, newStatus.FullyLabeledReplicas) +
-			fmt.Sprintf("readyReplicas %d->%d, ", ms.Status.ReadyReplicas, newStatus.ReadyReplicas) +
-			fmt.Sprintf("availableReplicas %d->%d, ", ms.Status.AvailableReplicas, newStatus.AvailableReplicas) +
-			fmt.Sprintf("sequence No: %v->%v", ms.Status.ObservedGeneration, newStatus.ObservedGeneration))
-
-		ms.Status = newStatus
-		updateErr = c.Status().Update(context.Background(), ms)
-		if updateErr == nil {
-			return ms, nil
+	}
+	sort.Strings(protectedMachines)
+
+	if current > replicas && int32(len(protectedMachines)) > replicas {
+		conditions.Set(ms, &machinev1.Condition{
+			Type:     MachineSetScaleDownBlockedCondition,
+			Status:   corev1.ConditionTrue,
+			Severity: machinev1.ConditionSeverityWarning,
+			Reason:   ScaleDownDisabledReason,
+			Message: fmt.Sprintf("Cannot scale down to %d replicas, %d machines are protected by the %s annotation: %s",
+				replicas, len(protectedMachines), ScaleDownDisabledAnnotation, strings.Join(protectedMachines, ", ")),
+		})
+	} else {
+		conditions.Set(ms, &machinev1.Condition{Type: MachineSetScaleDownBlockedCondition, Status: corev1.ConditionFalse})
+	}
+
+	var failedMachines []string
+	for _, machine := range filteredMachines {
+		if machine.Status.Phase != nil && *machine.Status.Phase == machinev1.PhaseFailed {
+			msg := machine.Name
+			if machine.Status.ErrorMessage != nil {
+				msg = fmt.Sprintf("%s: %s", machine.Name, *machine.Status.ErrorMessage)
+			}
+			failedMachines = append(failedMachines, msg)
 		}
-		// Stop retrying if we exceed statusUpdateRetries - the machineSet will be requeued with a rate limit.
-		if i >= statusUpdateRetries {
-			break
+	}
+	sort.Strings(failedMachines)
+
+	if errors.Is(syncErr, errPreflightFailed) {
+		conditions.Set(ms, &machinev1.Condition{
+			Type:     MachineSetPreflightFailedCondition,
+			Status:   corev1.ConditionTrue,
+			Severity: machinev1.ConditionSeverityError,
+			Reason:   PreflightFailedReason,
+			Message:  syncErr.Error(),
+		})
+	} else {
+		conditions.Set(ms, &machinev1.Condition{Type: MachineSetPreflightFailedCondition, Status: corev1.ConditionFalse})
+	}
+
+	switch {
+	case errors.Is(syncErr, errMachineCreationFailed), errors.Is(syncErr, errPreflightFailed):
+		reason := MachineCreationFailedReason
+		if errors.Is(syncErr, errPreflightFailed) {
+			reason = PreflightFailedReason
 		}
-		// Update the MachineSet with the latest resource version for the next poll
-		if getErr = c.Get(context.Background(), client.ObjectKey{Namespace: ms.Namespace, Name: ms.Name}, ms); getErr != nil {
-			// If the GET fails we can't trust status.Replicas anymore. This error
-			// is bound to be more interesting than the update failure.
-			return nil, getErr
+		conditions.MarkFalse(ms, MachineSetMachinesCreatedCondition, reason, machinev1.ConditionSeverityError, "%v", syncErr)
+		conditions.Set(ms, &machinev1.Condition{
+			Type:    MachineSetReplicaFailureCondition,
+			Status:  corev1.ConditionTrue,
+			Reason:  reason,
+			Message: syncErr.Error(),
+		})
+	case len(failedMachines) > 0:
+		conditions.MarkTrue(ms, MachineSetMachinesCreatedCondition)
+		conditions.Set(ms, &machinev1.Condition{
+			Type:    MachineSetReplicaFailureCondition,
+			Status:  corev1.ConditionTrue,
+			Reason:  MachineFailedReason,
+			Message: fmt.Sprintf("%d of %d machines have failed: %s", len(failedMachines), current, strings.Join(failedMachines, "; ")),
+		})
+	default:
+		conditions.MarkTrue(ms, MachineSetMachinesCreatedCondition)
+		conditions.Set(ms, &machinev1.Condition{Type: MachineSetReplicaFailureCondition, Status: corev1.ConditionFalse})
+	}
+}
+
+// setUpdatedReplicas records how many of the Machines of the MachineSet were created from its
+// current machine template, and how many are outdated.
+func setUpdatedReplicas(ms *machinev1.MachineSet, filteredMachines []*machinev1.Machine) error {
+	hash, err := computeTemplateHash(&ms.Spec.Template)
+	if err != nil {
+		return err
+	}
+
+	updated := 0
+	for _, machine := range filteredMachines {
+		if machine.Labels[MachineTemplateHashLabel] == hash {
+			updated++
 		}
 	}
 
-	return nil, updateErr
+	if ms.Annotations == nil {
+		ms.Annotations = make(map[string]string)
+	}
+	ms.Annotations[UpdatedReplicasAnnotation] = strconv.Itoa(updated)
+	ms.Annotations[OutdatedReplicasAnnotation] = strconv.Itoa(len(filteredMachines) - updated)
+	return nil
+}
+
+// updateMachineSetStatusAnnotations recalculates the conditions and the updated replicas of the
+// MachineSet, which are stored in annotations, and patches them when they have changed.
+func updateMachineSetStatusAnnotations(c client.Client, ms *machinev1.MachineSet, filteredMachines []*machinev1.Machine, syncErr error) error {
+	original := ms.DeepCopy()
+
+	setMachineSetConditions(ms, filteredMachines, syncErr)
+	setMachineEventConditions(ms, filteredMachines)
+	if err := setUpdatedReplicas(ms, filteredMachines); err != nil {
+		return err
+	}
+	setOutOfDateReplicas(ms, filteredMachines)
+	if equality.Semantic.DeepEqual(original.Annotations, ms.Annotations) {
+		return nil
+	}
+
+	return c.Patch(context.Background(), ms, client.MergeFrom(original))
 }
 
 func (c *ReconcileMachineSet) getMachineNode(machine *machinev1.Machine) (*corev1.Node, error) {
@@ -0,0 +1,224 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSetMachineSetConditions(t *testing.T) {
+	failedPhase := machinev1.PhaseFailed
+	failedMachine := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "failed"},
+		Status: machinev1.MachineStatus{
+			Phase:        &failedPhase,
+			ErrorMessage: pointer.String("instance type not available"),
+		},
+	}
+	runningMachine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "running"}}
+	protectedMachine := func(name string) *machinev1.Machine {
+		return &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{ScaleDownDisabledAnnotation: "true"}}}
+	}
+
+	testCases := []struct {
+		name     string
+		replicas int32
+		machines []*machinev1.Machine
+		syncErr  error
+		expected map[machinev1.ConditionType]machinev1.Condition
+	}{
+		{
+			name:     "with the desired number of machines",
+			replicas: 1,
+			machines: []*machinev1.Machine{runningMachine},
+			expected: map[machinev1.ConditionType]machinev1.Condition{
+				MachineSetScalingUpCondition:        {Status: corev1.ConditionFalse},
+				MachineSetScalingDownCondition:      {Status: corev1.ConditionFalse},
+				MachineSetScaleDownBlockedCondition: {Status: corev1.ConditionFalse},
+				MachineSetPreflightFailedCondition:  {Status: corev1.ConditionFalse},
+				MachineSetMachinesCreatedCondition:  {Status: corev1.ConditionTrue},
+				MachineSetReplicaFailureCondition:   {Status: corev1.ConditionFalse},
+			},
+		},
+		{
+			name:     "when scaling up",
+			replicas: 3,
+			machines: []*machinev1.Machine{runningMachine},
+			expected: map[machinev1.ConditionType]machinev1.Condition{
+				MachineSetScalingUpCondition:       {Status: corev1.ConditionTrue, Message: "Scaling up from 1 to 3 replicas"},
+				MachineSetScalingDownCondition:     {Status: corev1.ConditionFalse},
+				MachineSetMachinesCreatedCondition: {Status: corev1.ConditionTrue},
+				MachineSetReplicaFailureCondition:  {Status: corev1.ConditionFalse},
+			},
+		},
+		{
+			name:     "when scaling down",
+			replicas: 0,
+			machines: []*machinev1.Machine{runningMachine},
+			expected: map[machinev1.ConditionType]machinev1.Condition{
+				MachineSetScalingUpCondition:       {Status: corev1.ConditionFalse},
+				MachineSetScalingDownCondition:     {Status: corev1.ConditionTrue, Message: "Scaling down from 1 to 0 replicas"},
+				MachineSetMachinesCreatedCondition: {Status: corev1.ConditionTrue},
+				MachineSetReplicaFailureCondition:  {Status: corev1.ConditionFalse},
+			},
+		},
+		{
+			name:     "when scaling down with fewer protected machines than replicas",
+			replicas: 1,
+			machines: []*machinev1.Machine{runningMachine, protectedMachine("protected")},
+			expected: map[machinev1.ConditionType]machinev1.Condition{
+				MachineSetScalingDownCondition:      {Status: corev1.ConditionTrue, Message: "Scaling down from 2 to 1 replicas"},
+				MachineSetScaleDownBlockedCondition: {Status: corev1.ConditionFalse},
+			},
+		},
+		{
+			name:     "when scaling down is blocked by protected machines",
+			replicas: 1,
+			machines: []*machinev1.Machine{protectedMachine("protected-b"), runningMachine, protectedMachine("protected-a")},
+			expected: map[machinev1.ConditionType]machinev1.Condition{
+				MachineSetScalingDownCondition: {Status: corev1.ConditionTrue, Message: "Scaling down from 3 to 1 replicas"},
+				MachineSetScaleDownBlockedCondition: {
+					Status:   corev1.ConditionTrue,
+					Reason:   ScaleDownDisabledReason,
+					Severity: machinev1.ConditionSeverityWarning,
+					Message:  "Cannot scale down to 1 replicas, 2 machines are protected by the machine.openshift.io/scale-down-disabled annotation: protected-a, protected-b",
+				},
+			},
+		},
+		{
+			name:     "when machine creation fails",
+			replicas: 2,
+			machines: []*machinev1.Machine{runningMachine},
+			syncErr:  fmt.Errorf("%w: quota exceeded", errMachineCreationFailed),
+			expected: map[machinev1.ConditionType]machinev1.Condition{
+				MachineSetScalingUpCondition:       {Status: corev1.ConditionTrue, Message: "Scaling up from 1 to 2 replicas"},
+				MachineSetScalingDownCondition:     {Status: corev1.ConditionFalse},
+				MachineSetMachinesCreatedCondition: {Status: corev1.ConditionFalse, Reason: MachineCreationFailedReason, Severity: machinev1.ConditionSeverityError, Message: "failed to create machines: quota exceeded"},
+				MachineSetReplicaFailureCondition:  {Status: corev1.ConditionTrue, Reason: MachineCreationFailedReason, Message: "failed to create machines: quota exceeded"},
+			},
+		},
+		{
+			name:     "when the preflight checks fail",
+			replicas: 2,
+			machines: []*machinev1.Machine{runningMachine},
+			syncErr:  fmt.Errorf("%w: credentialsSecret default/aws-cloud-credentials not found", errPreflightFailed),
+			expected: map[machinev1.ConditionType]machinev1.Condition{
+				MachineSetScalingUpCondition:       {Status: corev1.ConditionTrue, Message: "Scaling up from 1 to 2 replicas"},
+				MachineSetScalingDownCondition:     {Status: corev1.ConditionFalse},
+				MachineSetPreflightFailedCondition: {Status: corev1.ConditionTrue, Reason: PreflightFailedReason, Severity: machinev1.ConditionSeverityError, Message: "preflight checks failed: credentialsSecret default/aws-cloud-credentials not found"},
+				MachineSetMachinesCreatedCondition: {Status: corev1.ConditionFalse, Reason: PreflightFailedReason, Severity: machinev1.ConditionSeverityError, Message: "preflight checks failed: credentialsSecret default/aws-cloud-credentials not found"},
+				MachineSetReplicaFailureCondition:  {Status: corev1.ConditionTrue, Reason: PreflightFailedReason, Message: "preflight checks failed: credentialsSecret default/aws-cloud-credentials not found"},
+			},
+		},
+		{
+			name:     "with a failed machine",
+			replicas: 2,
+			machines: []*machinev1.Machine{runningMachine, failedMachine},
+			syncErr:  errors.New("some other error"),
+			expected: map[machinev1.ConditionType]machinev1.Condition{
+				MachineSetScalingUpCondition:       {Status: corev1.ConditionFalse},
+				MachineSetScalingDownCondition:     {Status: corev1.ConditionFalse},
+				MachineSetMachinesCreatedCondition: {Status: corev1.ConditionTrue},
+				MachineSetReplicaFailureCondition:  {Status: corev1.ConditionTrue, Reason: MachineFailedReason, Message: "1 of 2 machines have failed: failed: instance type not available"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1.MachineSet{Spec: machinev1.MachineSetSpec{Replicas: pointer.Int32(tc.replicas)}}
+			setMachineSetConditions(ms, tc.machines, tc.syncErr)
+
+			for conditionType, expected := range tc.expected {
+				got := conditions.Get(ms, conditionType)
+				g.Expect(got).ToNot(BeNil(), "condition %s not set", conditionType)
+				g.Expect(got.Status).To(Equal(expected.Status), "condition %s", conditionType)
+				g.Expect(got.Reason).To(Equal(expected.Reason), "condition %s", conditionType)
+				g.Expect(got.Severity).To(Equal(expected.Severity), "condition %s", conditionType)
+				g.Expect(got.Message).To(Equal(expected.Message), "condition %s", conditionType)
+			}
+		})
+	}
+}
+
+func TestUpdateMachineSetStatusAnnotations(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(machinev1.AddToScheme(scheme.Scheme)).To(Succeed())
+
+	ms := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "machineset1", Namespace: "default"},
+		Spec:       machinev1.MachineSetSpec{Replicas: pointer.Int32(1)},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(ms).Build()
+
+	g.Expect(updateMachineSetStatusAnnotations(c, ms, nil, nil)).To(Succeed())
+
+	got := &machinev1.MachineSet{}
+	g.Expect(c.Get(context.Background(), client.ObjectKeyFromObject(ms), got)).To(Succeed())
+	g.Expect(conditions.Get(got, MachineSetScalingUpCondition)).ToNot(BeNil())
+	g.Expect(conditions.Get(got, MachineSetScalingUpCondition).Status).To(Equal(corev1.ConditionTrue))
+	g.Expect(got.Annotations).To(HaveKeyWithValue(UpdatedReplicasAnnotation, "0"))
+	g.Expect(got.Annotations).To(HaveKeyWithValue(OutdatedReplicasAnnotation, "0"))
+
+	// An update without changes does not write the MachineSet.
+	resourceVersion := got.ResourceVersion
+	g.Expect(updateMachineSetStatusAnnotations(c, got, nil, nil)).To(Succeed())
+	g.Expect(c.Get(context.Background(), client.ObjectKeyFromObject(ms), got)).To(Succeed())
+	g.Expect(got.ResourceVersion).To(Equal(resourceVersion))
+}
+
+func TestSetUpdatedReplicas(t *testing.T) {
+	g := NewWithT(t)
+
+	ms := &machinev1.MachineSet{
+		Spec: machinev1.MachineSetSpec{
+			Template: machinev1.MachineTemplateSpec{
+				ObjectMeta: machinev1.ObjectMeta{Labels: map[string]string{"foo": "bar"}},
+			},
+		},
+	}
+	hash, err := computeTemplateHash(&ms.Spec.Template)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	machines := []*machinev1.Machine{
+		{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{MachineTemplateHashLabel: hash}}},
+		{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{MachineTemplateHashLabel: hash}}},
+		{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{MachineTemplateHashLabel: "previous"}}},
+		{ObjectMeta: metav1.ObjectMeta{}},
+	}
+
+	g.Expect(setUpdatedReplicas(ms, machines)).To(Succeed())
+	g.Expect(ms.Annotations).To(Equal(map[string]string{
+		UpdatedReplicasAnnotation:  "2",
+		OutdatedReplicasAnnotation: "2",
+	}))
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// infrastructureAPIVersion is the API version of the infrastructure Machines and templates referenced by the mirrors.
+const infrastructureAPIVersion = "infrastructure.cluster.x-k8s.io/v1beta1"
+
+// infrastructureMachineKinds maps the kinds of the providerSpecs to the kinds of the Cluster API infrastructure Machines.
+// The kinds of their templates have the Template suffix.
+var infrastructureMachineKinds = map[string]string{
+	"AWSMachineProviderConfig":     "AWSMachine",
+	"AzureMachineProviderSpec":     "AzureMachine",
+	"GCPMachineProviderSpec":       "GCPMachine",
+	"VSphereMachineProviderSpec":   "VSphereMachine",
+	"OpenstackProviderSpec":        "OpenStackMachine",
+	"PowerVSMachineProviderConfig": "IBMPowerVSMachine",
+	"BareMetalMachineProviderSpec": "Metal3Machine",
+}
+
+// providerSpec holds the fields common to the providerSpecs.
+type providerSpec struct {
+	Kind           string                       `json:"kind"`
+	UserDataSecret *corev1.LocalObjectReference `json:"userDataSecret,omitempty"`
+	// UserData is the user data secret of the bare metal providerSpec.
+	UserData *corev1.SecretReference `json:"userData,omitempty"`
+}
+
+// parseProviderSpec returns the kind of the infrastructure Machine and the name of the user data secret of the providerSpec.
+func parseProviderSpec(spec machinev1.ProviderSpec) (string, string, error) {
+	if spec.Value == nil {
+		return "", "", fmt.Errorf("providerSpec is empty")
+	}
+	var ps providerSpec
+	if err := json.Unmarshal(spec.Value.Raw, &ps); err != nil {
+		return "", "", fmt.Errorf("could not parse providerSpec: %w", err)
+	}
+	kind, ok := infrastructureMachineKinds[ps.Kind]
+	if !ok {
+		return "", "", fmt.Errorf("providerSpec kind %q has no Cluster API infrastructure provider", ps.Kind)
+	}
+
+	var userDataSecret string
+	switch {
+	case ps.UserDataSecret != nil:
+		userDataSecret = ps.UserDataSecret.Name
+	case ps.UserData != nil:
+		userDataSecret = ps.UserData.Name
+	}
+	return kind, userDataSecret, nil
+}
+
+// setMachineSpec sets the mirrored fields of the spec of the Cluster API Machines mirroring a Machine API Machine
+// or MachineSet, leaving the fields defaulted by Cluster API alone.
+func setMachineSpec(machineSpec *clusterv1.MachineSpec, clusterName string, infrastructureRef corev1.ObjectReference, spec *machinev1.MachineSpec, userDataSecret string) {
+	machineSpec.ClusterName = clusterName
+	machineSpec.InfrastructureRef = infrastructureRef
+	machineSpec.ProviderID = spec.ProviderID
+	machineSpec.Bootstrap.DataSecretName = nil
+	if userDataSecret != "" {
+		machineSpec.Bootstrap.DataSecretName = pointer.String(userDataSecret)
+	}
+}
+
+// newInfrastructureRef returns the reference to the infrastructure Machine or template of the provider.
+func newInfrastructureRef(kind, namespace, name string) corev1.ObjectReference {
+	return corev1.ObjectReference{
+		APIVersion: infrastructureAPIVersion,
+		Kind:       kind,
+		Namespace:  namespace,
+		Name:       name,
+	}
+}
+
+// withClusterName returns a copy of the labels with the cluster name label, which the Cluster API controllers
+// use to find the Cluster of the resources.
+func withClusterName(labels map[string]string, clusterName string) map[string]string {
+	out := map[string]string{clusterv1.ClusterNameLabel: clusterName}
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
+
+// mergeStrings returns the existing map with the desired keys set.
+func mergeStrings(existing, desired map[string]string) map[string]string {
+	if len(desired) == 0 {
+		return existing
+	}
+	out := map[string]string{}
+	for k, v := range existing {
+		out[k] = v
+	}
+	for k, v := range desired {
+		out[k] = v
+	}
+	return out
+}
+
+// machineDeploymentSpecFromMachineSet sets the mirrored fields of the MachineDeployment from the MachineSet.
+func machineDeploymentSpecFromMachineSet(md *clusterv1.MachineDeployment, ms *machinev1.MachineSet, clusterName string) error {
+	kind, userDataSecret, err := parseProviderSpec(ms.Spec.Template.Spec.ProviderSpec)
+	if err != nil {
+		return err
+	}
+
+	md.Labels = mergeStrings(md.Labels, withClusterName(ms.Labels, clusterName))
+	md.Spec.ClusterName = clusterName
+	md.Spec.Replicas = pointer.Int32(pointer.Int32Deref(ms.Spec.Replicas, 1))
+
+	selector := ms.Spec.Selector.DeepCopy()
+	selector.MatchLabels = withClusterName(selector.MatchLabels, clusterName)
+	md.Spec.Selector = *selector
+
+	md.Spec.Template.ObjectMeta = clusterv1.ObjectMeta{
+		Labels:      withClusterName(ms.Spec.Template.Labels, clusterName),
+		Annotations: ms.Spec.Template.Annotations,
+	}
+	setMachineSpec(&md.Spec.Template.Spec, clusterName, newInfrastructureRef(kind+"Template", md.Namespace, ms.Name), &ms.Spec.Template.Spec, userDataSecret)
+	return nil
+}
+
+// machineDeploymentStatusFromMachineSet returns the status of the MachineDeployment mirroring the MachineSet.
+func machineDeploymentStatusFromMachineSet(md *clusterv1.MachineDeployment, ms *machinev1.MachineSet) clusterv1.MachineDeploymentStatus {
+	status := clusterv1.MachineDeploymentStatus{
+		ObservedGeneration:  md.Generation,
+		Selector:            metav1.FormatLabelSelector(&md.Spec.Selector),
+		Replicas:            ms.Status.Replicas,
+		UpdatedReplicas:     ms.Status.Replicas,
+		ReadyReplicas:       ms.Status.ReadyReplicas,
+		AvailableReplicas:   ms.Status.AvailableReplicas,
+		UnavailableReplicas: ms.Status.Replicas - ms.Status.AvailableReplicas,
+		Conditions:          md.Status.Conditions,
+	}
+
+	desired := pointer.Int32Deref(md.Spec.Replicas, 1)
+	switch {
+	case ms.Status.Replicas < desired:
+		status.Phase = string(clusterv1.MachineDeploymentPhaseScalingUp)
+	case ms.Status.Replicas > desired:
+		status.Phase = string(clusterv1.MachineDeploymentPhaseScalingDown)
+	case ms.Status.ReadyReplicas == desired:
+		status.Phase = string(clusterv1.MachineDeploymentPhaseRunning)
+	default:
+		status.Phase = md.Status.Phase
+	}
+	return status
+}
+
+// machineSetStatusFromMachineDeployment returns the status of the MachineSet mirroring the MachineDeployment.
+func machineSetStatusFromMachineDeployment(ms *machinev1.MachineSet, md *clusterv1.MachineDeployment) machinev1.MachineSetStatus {
+	status := *ms.Status.DeepCopy()
+	status.ObservedGeneration = ms.Generation
+	status.Replicas = md.Status.Replicas
+	status.FullyLabeledReplicas = md.Status.Replicas
+	status.ReadyReplicas = md.Status.ReadyReplicas
+	status.AvailableReplicas = md.Status.AvailableReplicas
+	return status
+}
+
+// machineSpecFromMachine sets the mirrored fields of the Cluster API Machine from the Machine API Machine.
+func machineSpecFromMachine(capiMachine *clusterv1.Machine, m *machinev1.Machine, clusterName string) error {
+	kind, userDataSecret, err := parseProviderSpec(m.Spec.ProviderSpec)
+	if err != nil {
+		return err
+	}
+
+	capiMachine.Labels = mergeStrings(capiMachine.Labels, withClusterName(m.Labels, clusterName))
+	setMachineSpec(&capiMachine.Spec, clusterName, newInfrastructureRef(kind, capiMachine.Namespace, m.Name), &m.Spec, userDataSecret)
+	return nil
+}
+
+// machinePhases maps the phases of the Cluster API Machines to the phases of the Machine API Machines.
+var machinePhases = map[clusterv1.MachinePhase]string{
+	clusterv1.MachinePhasePending:      machinev1.PhaseProvisioning,
+	clusterv1.MachinePhaseProvisioning: machinev1.PhaseProvisioning,
+	clusterv1.MachinePhaseProvisioned:  machinev1.PhaseProvisioned,
+	clusterv1.MachinePhaseRunning:      machinev1.PhaseRunning,
+	clusterv1.MachinePhaseDeleting:     machinev1.PhaseDeleting,
+	clusterv1.MachinePhaseDeleted:      machinev1.PhaseDeleting,
+	clusterv1.MachinePhaseFailed:       machinev1.PhaseFailed,
+}
+
+// capiMachineStatusFromMachine returns the status of the Cluster API Machine mirroring the Machine API Machine.
+func capiMachineStatusFromMachine(capiMachine *clusterv1.Machine, m *machinev1.Machine) clusterv1.MachineStatus {
+	status := *capiMachine.Status.DeepCopy()
+	status.NodeRef = m.Status.NodeRef
+	status.FailureMessage = m.Status.ErrorMessage
+	status.Addresses = nil
+	for _, address := range m.Status.Addresses {
+		status.Addresses = append(status.Addresses, clusterv1.MachineAddress{
+			Type:    clusterv1.MachineAddressType(address.Type),
+			Address: address.Address,
+		})
+	}
+	status.Phase = string(clusterv1.MachinePhaseUnknown)
+	if m.Status.Phase != nil {
+		// The phases of the Machine API are a subset of the phases of Cluster API.
+		status.Phase = *m.Status.Phase
+	}
+	status.InfrastructureReady = m.Spec.ProviderID != nil
+	status.BootstrapReady = capiMachine.Spec.Bootstrap.DataSecretName != nil
+	return status
+}
+
+// machineStatusFromCAPIMachine returns the status of the Machine API Machine mirroring the Cluster API Machine.
+func machineStatusFromCAPIMachine(m *machinev1.Machine, capiMachine *clusterv1.Machine) machinev1.MachineStatus {
+	status := *m.Status.DeepCopy()
+	status.NodeRef = capiMachine.Status.NodeRef
+	status.ErrorMessage = capiMachine.Status.FailureMessage
+	status.Addresses = nil
+	for _, address := range capiMachine.Status.Addresses {
+		status.Addresses = append(status.Addresses, corev1.NodeAddress{
+			Type:    corev1.NodeAddressType(address.Type),
+			Address: address.Address,
+		})
+	}
+	if phase, ok := machinePhases[clusterv1.MachinePhase(capiMachine.Status.Phase)]; ok {
+		status.Phase = pointer.String(phase)
+	}
+	return status
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	"k8s.io/utils/strings/slices"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ReconcileMachineMigration mirrors Machine API Machines into Cluster API Machines.
+type ReconcileMachineMigration struct {
+	syncer
+}
+
+// Reconcile syncs the Machine and its Cluster API Machine, from the one reconciled by its authoritative API to the other.
+func (r *ReconcileMachineMigration) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	machine := &machinev1.Machine{}
+	if err := r.client.Get(ctx, request.NamespacedName, machine); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, r.deletePausedMirror(ctx, request.Name, &clusterv1.Machine{})
+		}
+		return reconcile.Result{}, err
+	}
+
+	api, err := getAuthoritativeAPI(machine)
+	if err != nil {
+		// The Machine will be reconciled again once the annotation is fixed.
+		klog.Warningf("%v: %v", machine.Name, err)
+		r.warn(machine, "InvalidAuthoritativeAPI", err)
+		return reconcile.Result{}, nil
+	}
+
+	if api == annotations.AuthoritativeAPIClusterAPI {
+		err = r.syncFromCAPIMachine(ctx, machine)
+	} else {
+		err = r.syncToCAPIMachine(ctx, machine)
+	}
+	if err != nil {
+		klog.Errorf("%v: failed to sync Cluster API Machine: %v", machine.Name, err)
+		r.warn(machine, "FailedMirror", err)
+	}
+	return reconcile.Result{}, err
+}
+
+// syncToCAPIMachine mirrors the Machine into a paused Cluster API Machine.
+func (r *ReconcileMachineMigration) syncToCAPIMachine(ctx context.Context, machine *machinev1.Machine) error {
+	capiMachine := &clusterv1.Machine{}
+	found, err := r.getMirror(ctx, machine.Name, capiMachine)
+	if err != nil {
+		return err
+	}
+
+	// The instance is deleted by the Machine API controllers, the mirror only has to go.
+	if machine.DeletionTimestamp != nil {
+		if found && capiMachine.DeletionTimestamp == nil {
+			return client.IgnoreNotFound(r.capiClient.Delete(ctx, capiMachine))
+		}
+		return nil
+	}
+
+	if !found {
+		capiMachine = &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: machine.Name, Namespace: r.capiNamespace}}
+	}
+	original := capiMachine.DeepCopy()
+	if err := machineSpecFromMachine(capiMachine, machine, r.clusterName); err != nil {
+		// The Machine will be reconciled again once its providerSpec is fixed.
+		klog.Warningf("%v: cannot mirror Machine: %v", machine.Name, err)
+		r.warn(machine, "UnsupportedMirror", err)
+		return nil
+	}
+	setPaused(capiMachine, true)
+
+	if !found {
+		if err := r.capiClient.Create(ctx, capiMachine); err != nil {
+			return fmt.Errorf("could not create Cluster API Machine: %w", err)
+		}
+		klog.Infof("%v: created Cluster API Machine %s/%s", machine.Name, capiMachine.Namespace, capiMachine.Name)
+	} else if !equality.Semantic.DeepEqual(original, capiMachine) {
+		if err := r.capiClient.Update(ctx, capiMachine); err != nil {
+			return fmt.Errorf("could not update Cluster API Machine: %w", err)
+		}
+		klog.V(3).Infof("%v: updated Cluster API Machine %s/%s", machine.Name, capiMachine.Namespace, capiMachine.Name)
+	}
+
+	if recordMirrorUID(machine, capiMachine) {
+		if err := r.client.Update(ctx, machine); err != nil {
+			return fmt.Errorf("could not record the mirror of machine: %w", err)
+		}
+	}
+
+	status := capiMachineStatusFromMachine(capiMachine, machine)
+	if equality.Semantic.DeepEqual(capiMachine.Status, status) {
+		return nil
+	}
+	capiMachine.Status = status
+	if err := r.capiClient.Status().Update(ctx, capiMachine); err != nil {
+		return fmt.Errorf("could not update Cluster API Machine status: %w", err)
+	}
+	return nil
+}
+
+// syncFromCAPIMachine unpauses the Cluster API Machine and mirrors its provider ID and status into the Machine.
+// As the Machine API controllers skip the Machine, its finalizer is removed once the Cluster API Machine is gone.
+func (r *ReconcileMachineMigration) syncFromCAPIMachine(ctx context.Context, machine *machinev1.Machine) error {
+	capiMachine := &clusterv1.Machine{}
+	found, err := r.getMirror(ctx, machine.Name, capiMachine)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		if _, mirrored := machine.Annotations[MirrorUIDAnnotation]; !mirrored {
+			// The Machine will be reconciled again once the Cluster API Machine is created.
+			err := fmt.Errorf("Cluster API Machine %s/%s not found, the Machine must be mirrored before it is handed over to Cluster API", r.capiNamespace, machine.Name)
+			klog.Warningf("%v: %v", machine.Name, err)
+			r.warn(machine, "MirrorNotFound", err)
+			return nil
+		}
+		if machine.DeletionTimestamp == nil {
+			klog.Infof("%v: Cluster API Machine was deleted, deleting Machine", machine.Name)
+			return client.IgnoreNotFound(r.client.Delete(ctx, machine))
+		}
+		return r.removeFinalizer(ctx, machine)
+	}
+
+	// The instance is deleted by the Cluster API controllers, the Machine is released once its mirror is gone.
+	if machine.DeletionTimestamp != nil {
+		if capiMachine.DeletionTimestamp == nil {
+			return client.IgnoreNotFound(r.capiClient.Delete(ctx, capiMachine))
+		}
+		return nil
+	}
+
+	if setPaused(capiMachine, false) {
+		if err := r.capiClient.Update(ctx, capiMachine); err != nil {
+			return fmt.Errorf("could not unpause Cluster API Machine: %w", err)
+		}
+		klog.Infof("%v: handed Cluster API Machine %s/%s over to Cluster API", machine.Name, capiMachine.Namespace, capiMachine.Name)
+	}
+
+	updated := recordMirrorUID(machine, capiMachine)
+	if capiMachine.Spec.ProviderID != nil && pointer.StringDeref(machine.Spec.ProviderID, "") != *capiMachine.Spec.ProviderID {
+		machine.Spec.ProviderID = pointer.String(*capiMachine.Spec.ProviderID)
+		updated = true
+	}
+	if updated {
+		if err := r.client.Update(ctx, machine); err != nil {
+			return fmt.Errorf("could not update machine: %w", err)
+		}
+	}
+
+	status := machineStatusFromCAPIMachine(machine, capiMachine)
+	if equality.Semantic.DeepEqual(machine.Status, status) {
+		return nil
+	}
+	machine.Status = status
+	if err := r.client.Status().Update(ctx, machine); err != nil {
+		return fmt.Errorf("could not update machine status: %w", err)
+	}
+	return nil
+}
+
+// removeFinalizer removes the Machine API finalizer of a Machine whose instance was deleted by Cluster API.
+func (r *ReconcileMachineMigration) removeFinalizer(ctx context.Context, machine *machinev1.Machine) error {
+	if !slices.Contains(machine.Finalizers, machinev1.MachineFinalizer) {
+		return nil
+	}
+	var finalizers []string
+	for _, f := range machine.Finalizers {
+		if f != machinev1.MachineFinalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	machine.Finalizers = finalizers
+	if err := r.client.Update(ctx, machine); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	klog.Infof("%v: Cluster API Machine is gone, removed finalizer", machine.Name)
+	return nil
+}
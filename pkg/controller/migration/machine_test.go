@@ -0,0 +1,188 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newMachine(annotations map[string]string) *machinev1.Machine {
+	return &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "worker-a-1",
+			Namespace:   machineAPINamespace,
+			Labels:      map[string]string{"machine.openshift.io/cluster-api-machineset": "worker-a"},
+			Annotations: annotations,
+			Finalizers:  []string{machinev1.MachineFinalizer},
+		},
+		Spec: machinev1.MachineSpec{
+			ProviderID:   pointer.String("aws:///us-east-1a/i-0123"),
+			ProviderSpec: newProviderSpec("AWSMachineProviderConfig"),
+		},
+		Status: machinev1.MachineStatus{
+			Phase:     pointer.String(machinev1.PhaseRunning),
+			NodeRef:   &corev1.ObjectReference{Kind: "Node", Name: "ip-10-0-0-1"},
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}},
+		},
+	}
+}
+
+func newCAPIMachine(paused bool) *clusterv1.Machine {
+	m := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-a-1", Namespace: DefaultClusterAPINamespace, UID: "capi-machine-uid"},
+		Spec: clusterv1.MachineSpec{
+			ClusterName: clusterName,
+			ProviderID:  pointer.String("aws:///us-east-1a/i-4567"),
+		},
+		Status: clusterv1.MachineStatus{
+			Phase:     string(clusterv1.MachinePhaseProvisioned),
+			Addresses: clusterv1.MachineAddresses{{Type: clusterv1.MachineInternalIP, Address: "10.0.0.2"}},
+		},
+	}
+	setPaused(m, paused)
+	return m
+}
+
+func TestReconcileMachineMigration(t *testing.T) {
+	clusterAPI := map[string]string{annotations.AuthoritativeAPIAnnotation: annotations.AuthoritativeAPIClusterAPI}
+	mirrored := map[string]string{
+		annotations.AuthoritativeAPIAnnotation: annotations.AuthoritativeAPIClusterAPI,
+		MirrorUIDAnnotation:                    "capi-machine-uid",
+	}
+	deleting := func(m *machinev1.Machine) *machinev1.Machine {
+		m.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		return m
+	}
+
+	testCases := []struct {
+		name                  string
+		objects               []client.Object
+		expectMachine         func(*WithT, *machinev1.Machine)
+		expectCAPIMachine     func(*WithT, *clusterv1.Machine)
+		expectMachineNotFound bool
+	}{
+		{
+			name:    "mirrors a Machine API Machine into a paused Cluster API Machine",
+			objects: []client.Object{newMachine(nil)},
+			expectCAPIMachine: func(g *WithT, m *clusterv1.Machine) {
+				g.Expect(m.Annotations).To(HaveKey(clusterv1.PausedAnnotation))
+				g.Expect(m.Labels).To(HaveKeyWithValue(clusterv1.ClusterNameLabel, clusterName))
+				g.Expect(m.Labels).To(HaveKeyWithValue("machine.openshift.io/cluster-api-machineset", "worker-a"))
+				g.Expect(m.Spec.ProviderID).To(HaveValue(Equal("aws:///us-east-1a/i-0123")))
+				g.Expect(m.Spec.InfrastructureRef.Kind).To(Equal("AWSMachine"))
+				g.Expect(m.Status.Phase).To(Equal(string(clusterv1.MachinePhaseRunning)))
+				g.Expect(m.Status.NodeRef).To(HaveField("Name", "ip-10-0-0-1"))
+				g.Expect(m.Status.Addresses).To(ConsistOf(clusterv1.MachineAddress{Type: clusterv1.MachineInternalIP, Address: "10.0.0.1"}))
+			},
+			expectMachine: func(g *WithT, m *machinev1.Machine) {
+				g.Expect(m.Spec.ProviderID).To(HaveValue(Equal("aws:///us-east-1a/i-0123")))
+			},
+		},
+		{
+			name:    "updates the paused Cluster API Machine of a Machine API Machine",
+			objects: []client.Object{newMachine(nil), newCAPIMachine(true)},
+			expectCAPIMachine: func(g *WithT, m *clusterv1.Machine) {
+				g.Expect(m.Annotations).To(HaveKey(clusterv1.PausedAnnotation))
+				g.Expect(m.Spec.ProviderID).To(HaveValue(Equal("aws:///us-east-1a/i-0123")))
+				g.Expect(m.Status.Phase).To(Equal(string(clusterv1.MachinePhaseRunning)))
+			},
+			expectMachine: func(g *WithT, m *machinev1.Machine) {
+				g.Expect(m.Annotations).To(HaveKeyWithValue(MirrorUIDAnnotation, "capi-machine-uid"))
+			},
+		},
+		{
+			name:    "hands a Machine over to Cluster API",
+			objects: []client.Object{newMachine(clusterAPI), newCAPIMachine(true)},
+			expectCAPIMachine: func(g *WithT, m *clusterv1.Machine) {
+				g.Expect(m.Annotations).ToNot(HaveKey(clusterv1.PausedAnnotation))
+			},
+			expectMachine: func(g *WithT, m *machinev1.Machine) {
+				g.Expect(m.Annotations).To(HaveKeyWithValue(MirrorUIDAnnotation, "capi-machine-uid"))
+				g.Expect(m.Spec.ProviderID).To(HaveValue(Equal("aws:///us-east-1a/i-4567")))
+				g.Expect(m.Status.Phase).To(HaveValue(Equal(machinev1.PhaseProvisioned)))
+				g.Expect(m.Status.NodeRef).To(BeNil())
+				g.Expect(m.Status.Addresses).To(ConsistOf(corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.0.0.2"}))
+			},
+		},
+		{
+			name:    "deletes the Cluster API Machine of a Machine deleted while reconciled by Cluster API",
+			objects: []client.Object{deleting(newMachine(mirrored)), newCAPIMachine(false)},
+			expectMachine: func(g *WithT, m *machinev1.Machine) {
+				g.Expect(m.Finalizers).To(ConsistOf(machinev1.MachineFinalizer))
+			},
+		},
+		{
+			name:                  "releases a deleted Machine once its Cluster API Machine is gone",
+			objects:               []client.Object{deleting(newMachine(mirrored))},
+			expectMachineNotFound: true,
+		},
+		{
+			name:    "deletes the Machine when Cluster API deleted its mirror",
+			objects: []client.Object{newMachine(mirrored)},
+			expectMachine: func(g *WithT, m *machinev1.Machine) {
+				// The finalizer is removed once the deletion is observed.
+				g.Expect(m.DeletionTimestamp).ToNot(BeNil())
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := &ReconcileMachineMigration{syncer: newTestSyncer(t, tc.objects...)}
+			key := client.ObjectKey{Namespace: machineAPINamespace, Name: "worker-a-1"}
+			_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			capiMachine := &clusterv1.Machine{}
+			err = r.capiClient.Get(context.Background(), client.ObjectKey{Namespace: DefaultClusterAPINamespace, Name: "worker-a-1"}, capiMachine)
+			if tc.expectCAPIMachine == nil {
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), "expected the Cluster API Machine to be deleted")
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+				tc.expectCAPIMachine(g, capiMachine)
+			}
+
+			machine := &machinev1.Machine{}
+			err = r.client.Get(context.Background(), key, machine)
+			switch {
+			case tc.expectMachineNotFound && err == nil:
+				// The fake client does not delete objects whose last finalizer is removed.
+				g.Expect(machine.Finalizers).To(BeEmpty())
+			case tc.expectMachineNotFound:
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), "expected the Machine to be deleted")
+			default:
+				g.Expect(err).ToNot(HaveOccurred())
+				tc.expectMachine(g, machine)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ReconcileMachineSetMigration mirrors MachineSets into MachineDeployments.
+type ReconcileMachineSetMigration struct {
+	syncer
+}
+
+// Reconcile syncs the MachineSet and its MachineDeployment, from the one reconciled by its authoritative API to the other.
+func (r *ReconcileMachineSetMigration) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	machineSet := &machinev1.MachineSet{}
+	if err := r.client.Get(ctx, request.NamespacedName, machineSet); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, r.deletePausedMirror(ctx, request.Name, &clusterv1.MachineDeployment{})
+		}
+		return reconcile.Result{}, err
+	}
+
+	api, err := getAuthoritativeAPI(machineSet)
+	if err != nil {
+		// The MachineSet will be reconciled again once the annotation is fixed.
+		klog.Warningf("%v: %v", machineSet.Name, err)
+		r.warn(machineSet, "InvalidAuthoritativeAPI", err)
+		return reconcile.Result{}, nil
+	}
+
+	if err := r.propagateAuthoritativeAPI(ctx, machineSet); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if api == annotations.AuthoritativeAPIClusterAPI {
+		err = r.syncFromMachineDeployment(ctx, machineSet)
+	} else {
+		err = r.syncToMachineDeployment(ctx, machineSet)
+	}
+	if err != nil {
+		klog.Errorf("%v: failed to sync MachineDeployment: %v", machineSet.Name, err)
+		r.warn(machineSet, "FailedMirror", err)
+	}
+	return reconcile.Result{}, err
+}
+
+// propagateAuthoritativeAPI sets the authoritative API of the MachineSet on its Machines, so that they are
+// handed over with the MachineSet. Machines are left alone while the MachineSet does not set it.
+func (r *ReconcileMachineSetMigration) propagateAuthoritativeAPI(ctx context.Context, machineSet *machinev1.MachineSet) error {
+	api, ok := machineSet.Annotations[annotations.AuthoritativeAPIAnnotation]
+	if !ok {
+		return nil
+	}
+
+	machines := &machinev1.MachineList{}
+	if err := r.client.List(ctx, machines, client.InNamespace(machineSet.Namespace)); err != nil {
+		return fmt.Errorf("could not list machines: %w", err)
+	}
+	for i := range machines.Items {
+		machine := &machines.Items[i]
+		if !metav1.IsControlledBy(machine, machineSet) || machine.Annotations[annotations.AuthoritativeAPIAnnotation] == api {
+			continue
+		}
+		if machine.Annotations == nil {
+			machine.Annotations = map[string]string{}
+		}
+		machine.Annotations[annotations.AuthoritativeAPIAnnotation] = api
+		if err := r.client.Update(ctx, machine); err != nil {
+			return fmt.Errorf("could not set the authoritative API of machine %s: %w", machine.Name, err)
+		}
+		klog.Infof("%v: handed Machine %s over to %s", machineSet.Name, machine.Name, api)
+	}
+	return nil
+}
+
+// syncToMachineDeployment mirrors the MachineSet into a paused MachineDeployment.
+func (r *ReconcileMachineSetMigration) syncToMachineDeployment(ctx context.Context, machineSet *machinev1.MachineSet) error {
+	md := &clusterv1.MachineDeployment{}
+	found, err := r.getMirror(ctx, machineSet.Name, md)
+	if err != nil {
+		return err
+	}
+
+	if machineSet.DeletionTimestamp != nil {
+		if found && md.DeletionTimestamp == nil {
+			return client.IgnoreNotFound(r.capiClient.Delete(ctx, md))
+		}
+		return nil
+	}
+
+	if !found {
+		md = &clusterv1.MachineDeployment{ObjectMeta: metav1.ObjectMeta{Name: machineSet.Name, Namespace: r.capiNamespace}}
+	}
+	original := md.DeepCopy()
+	if err := machineDeploymentSpecFromMachineSet(md, machineSet, r.clusterName); err != nil {
+		// The MachineSet will be reconciled again once its providerSpec is fixed.
+		klog.Warningf("%v: cannot mirror MachineSet: %v", machineSet.Name, err)
+		r.warn(machineSet, "UnsupportedMirror", err)
+		return nil
+	}
+	setPaused(md, true)
+
+	if !found {
+		if err := r.capiClient.Create(ctx, md); err != nil {
+			return fmt.Errorf("could not create MachineDeployment: %w", err)
+		}
+		klog.Infof("%v: created MachineDeployment %s/%s", machineSet.Name, md.Namespace, md.Name)
+	} else if !equality.Semantic.DeepEqual(original, md) {
+		if err := r.capiClient.Update(ctx, md); err != nil {
+			return fmt.Errorf("could not update MachineDeployment: %w", err)
+		}
+		klog.V(3).Infof("%v: updated MachineDeployment %s/%s", machineSet.Name, md.Namespace, md.Name)
+	}
+
+	if recordMirrorUID(machineSet, md) {
+		if err := r.client.Update(ctx, machineSet); err != nil {
+			return fmt.Errorf("could not record the mirror of machineset: %w", err)
+		}
+	}
+
+	status := machineDeploymentStatusFromMachineSet(md, machineSet)
+	if equality.Semantic.DeepEqual(md.Status, status) {
+		return nil
+	}
+	md.Status = status
+	if err := r.capiClient.Status().Update(ctx, md); err != nil {
+		return fmt.Errorf("could not update MachineDeployment status: %w", err)
+	}
+	return nil
+}
+
+// syncFromMachineDeployment unpauses the MachineDeployment and mirrors its replicas and status into the MachineSet.
+func (r *ReconcileMachineSetMigration) syncFromMachineDeployment(ctx context.Context, machineSet *machinev1.MachineSet) error {
+	md := &clusterv1.MachineDeployment{}
+	found, err := r.getMirror(ctx, machineSet.Name, md)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		if _, mirrored := machineSet.Annotations[MirrorUIDAnnotation]; !mirrored {
+			// The MachineSet will be reconciled again once the MachineDeployment is created.
+			err := fmt.Errorf("MachineDeployment %s/%s not found, the MachineSet must be mirrored before it is handed over to Cluster API", r.capiNamespace, machineSet.Name)
+			klog.Warningf("%v: %v", machineSet.Name, err)
+			r.warn(machineSet, "MirrorNotFound", err)
+			return nil
+		}
+		if machineSet.DeletionTimestamp != nil {
+			return nil
+		}
+		klog.Infof("%v: MachineDeployment was deleted, deleting MachineSet", machineSet.Name)
+		return client.IgnoreNotFound(r.client.Delete(ctx, machineSet))
+	}
+
+	if machineSet.DeletionTimestamp != nil {
+		if md.DeletionTimestamp == nil {
+			return client.IgnoreNotFound(r.capiClient.Delete(ctx, md))
+		}
+		return nil
+	}
+
+	if setPaused(md, false) {
+		if err := r.capiClient.Update(ctx, md); err != nil {
+			return fmt.Errorf("could not unpause MachineDeployment: %w", err)
+		}
+		klog.Infof("%v: handed MachineDeployment %s/%s over to Cluster API", machineSet.Name, md.Namespace, md.Name)
+	}
+
+	updated := recordMirrorUID(machineSet, md)
+	if md.Spec.Replicas != nil && pointer.Int32Deref(machineSet.Spec.Replicas, 1) != *md.Spec.Replicas {
+		machineSet.Spec.Replicas = pointer.Int32(*md.Spec.Replicas)
+		updated = true
+	}
+	if updated {
+		if err := r.client.Update(ctx, machineSet); err != nil {
+			return fmt.Errorf("could not update machineset: %w", err)
+		}
+	}
+
+	status := machineSetStatusFromMachineDeployment(machineSet, md)
+	if equality.Semantic.DeepEqual(machineSet.Status, status) {
+		return nil
+	}
+	machineSet.Status = status
+	if err := r.client.Status().Update(ctx, machineSet); err != nil {
+		return fmt.Errorf("could not update machineset status: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newMachineSet(annotations map[string]string) *machinev1.MachineSet {
+	return &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "worker-a",
+			Namespace:   machineAPINamespace,
+			UID:         "machineset-uid",
+			Annotations: annotations,
+		},
+		Spec: machinev1.MachineSetSpec{
+			Replicas: pointer.Int32(3),
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{"machine.openshift.io/cluster-api-machineset": "worker-a"}},
+			Template: machinev1.MachineTemplateSpec{
+				ObjectMeta: machinev1.ObjectMeta{Labels: map[string]string{"machine.openshift.io/cluster-api-machineset": "worker-a"}},
+				Spec:       machinev1.MachineSpec{ProviderSpec: newProviderSpec("AWSMachineProviderConfig")},
+			},
+		},
+		Status: machinev1.MachineSetStatus{Replicas: 3, ReadyReplicas: 2, AvailableReplicas: 2},
+	}
+}
+
+func newMachineDeployment(paused bool) *clusterv1.MachineDeployment {
+	md := &clusterv1.MachineDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-a", Namespace: DefaultClusterAPINamespace, UID: "machinedeployment-uid"},
+		Spec:       clusterv1.MachineDeploymentSpec{ClusterName: clusterName, Replicas: pointer.Int32(5)},
+		Status:     clusterv1.MachineDeploymentStatus{Replicas: 4, ReadyReplicas: 4, AvailableReplicas: 3},
+	}
+	setPaused(md, paused)
+	return md
+}
+
+func TestReconcileMachineSetMigration(t *testing.T) {
+	clusterAPI := map[string]string{annotations.AuthoritativeAPIAnnotation: annotations.AuthoritativeAPIClusterAPI}
+	mirrored := map[string]string{
+		annotations.AuthoritativeAPIAnnotation: annotations.AuthoritativeAPIClusterAPI,
+		MirrorUIDAnnotation:                    "machinedeployment-uid",
+	}
+
+	testCases := []struct {
+		name             string
+		objects          []client.Object
+		expectMachineSet func(*WithT, *machinev1.MachineSet)
+		expectMD         func(*WithT, *clusterv1.MachineDeployment)
+		expectMDNotFound bool
+		expectMSNotFound bool
+	}{
+		{
+			name:    "mirrors a Machine API MachineSet into a paused MachineDeployment",
+			objects: []client.Object{newMachineSet(nil)},
+			expectMD: func(g *WithT, md *clusterv1.MachineDeployment) {
+				g.Expect(md.Annotations).To(HaveKey(clusterv1.PausedAnnotation))
+				g.Expect(md.Labels).To(HaveKeyWithValue(clusterv1.ClusterNameLabel, clusterName))
+				g.Expect(md.Spec.ClusterName).To(Equal(clusterName))
+				g.Expect(md.Spec.Replicas).To(HaveValue(BeEquivalentTo(3)))
+				g.Expect(md.Spec.Selector.MatchLabels).To(Equal(map[string]string{
+					"machine.openshift.io/cluster-api-machineset": "worker-a",
+					clusterv1.ClusterNameLabel:                    clusterName,
+				}))
+				g.Expect(md.Spec.Template.Labels).To(Equal(md.Spec.Selector.MatchLabels))
+				g.Expect(md.Spec.Template.Spec.InfrastructureRef.Kind).To(Equal("AWSMachineTemplate"))
+				g.Expect(md.Spec.Template.Spec.InfrastructureRef.Name).To(Equal("worker-a"))
+				g.Expect(md.Spec.Template.Spec.Bootstrap.DataSecretName).To(HaveValue(Equal("worker-user-data")))
+				g.Expect(md.Status.Replicas).To(BeEquivalentTo(3))
+				g.Expect(md.Status.ReadyReplicas).To(BeEquivalentTo(2))
+				g.Expect(md.Status.UnavailableReplicas).To(BeEquivalentTo(1))
+			},
+			expectMachineSet: func(g *WithT, ms *machinev1.MachineSet) {
+				g.Expect(ms.Spec.Replicas).To(HaveValue(BeEquivalentTo(3)))
+			},
+		},
+		{
+			name:    "updates the paused MachineDeployment of a Machine API MachineSet",
+			objects: []client.Object{newMachineSet(nil), newMachineDeployment(true)},
+			expectMD: func(g *WithT, md *clusterv1.MachineDeployment) {
+				g.Expect(md.Annotations).To(HaveKey(clusterv1.PausedAnnotation))
+				g.Expect(md.Spec.Replicas).To(HaveValue(BeEquivalentTo(3)))
+				g.Expect(md.Status.Replicas).To(BeEquivalentTo(3))
+			},
+			expectMachineSet: func(g *WithT, ms *machinev1.MachineSet) {
+				g.Expect(ms.Annotations).To(HaveKeyWithValue(MirrorUIDAnnotation, "machinedeployment-uid"))
+			},
+		},
+		{
+			name:    "hands a MachineSet over to Cluster API",
+			objects: []client.Object{newMachineSet(clusterAPI), newMachineDeployment(true)},
+			expectMD: func(g *WithT, md *clusterv1.MachineDeployment) {
+				g.Expect(md.Annotations).ToNot(HaveKey(clusterv1.PausedAnnotation))
+			},
+			expectMachineSet: func(g *WithT, ms *machinev1.MachineSet) {
+				g.Expect(ms.Annotations).To(HaveKeyWithValue(MirrorUIDAnnotation, "machinedeployment-uid"))
+				g.Expect(ms.Spec.Replicas).To(HaveValue(BeEquivalentTo(5)))
+				g.Expect(ms.Status.Replicas).To(BeEquivalentTo(4))
+				g.Expect(ms.Status.AvailableReplicas).To(BeEquivalentTo(3))
+			},
+		},
+		{
+			name:             "does not hand over a MachineSet which was never mirrored",
+			objects:          []client.Object{newMachineSet(clusterAPI)},
+			expectMDNotFound: true,
+			expectMachineSet: func(g *WithT, ms *machinev1.MachineSet) {
+				g.Expect(ms.Spec.Replicas).To(HaveValue(BeEquivalentTo(3)))
+			},
+		},
+		{
+			name:             "deletes the MachineSet when Cluster API deleted its MachineDeployment",
+			objects:          []client.Object{newMachineSet(mirrored)},
+			expectMDNotFound: true,
+			expectMSNotFound: true,
+		},
+		{
+			name:             "deletes the paused MachineDeployment of a deleted MachineSet",
+			objects:          []client.Object{newMachineDeployment(true)},
+			expectMDNotFound: true,
+			expectMSNotFound: true,
+		},
+		{
+			name:             "keeps the MachineDeployment reconciled by Cluster API of a deleted MachineSet",
+			objects:          []client.Object{newMachineDeployment(false)},
+			expectMSNotFound: true,
+			expectMD: func(g *WithT, md *clusterv1.MachineDeployment) {
+				g.Expect(md.Spec.Replicas).To(HaveValue(BeEquivalentTo(5)))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := &ReconcileMachineSetMigration{syncer: newTestSyncer(t, tc.objects...)}
+			key := client.ObjectKey{Namespace: machineAPINamespace, Name: "worker-a"}
+			_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			ms := &machinev1.MachineSet{}
+			err = r.client.Get(context.Background(), key, ms)
+			if tc.expectMSNotFound {
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), "expected the MachineSet to be deleted")
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+				tc.expectMachineSet(g, ms)
+			}
+
+			md := &clusterv1.MachineDeployment{}
+			err = r.capiClient.Get(context.Background(), client.ObjectKey{Namespace: DefaultClusterAPINamespace, Name: "worker-a"}, md)
+			if tc.expectMDNotFound {
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), "expected the MachineDeployment to be deleted")
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+				tc.expectMD(g, md)
+			}
+		})
+	}
+}
+
+func TestPropagateAuthoritativeAPI(t *testing.T) {
+	g := NewWithT(t)
+
+	machineSet := newMachineSet(map[string]string{annotations.AuthoritativeAPIAnnotation: annotations.AuthoritativeAPIClusterAPI})
+	owned := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{
+		Name:            "worker-a-1",
+		Namespace:       machineAPINamespace,
+		OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(machineSet, machinev1.SchemeGroupVersion.WithKind("MachineSet"))},
+	}}
+	notOwned := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "master-0", Namespace: machineAPINamespace}}
+
+	r := &ReconcileMachineSetMigration{syncer: newTestSyncer(t, machineSet, owned, notOwned)}
+	g.Expect(r.propagateAuthoritativeAPI(context.Background(), machineSet)).To(Succeed())
+
+	g.Expect(r.client.Get(context.Background(), client.ObjectKeyFromObject(owned), owned)).To(Succeed())
+	g.Expect(annotations.IsClusterAPIAuthoritative(owned)).To(BeTrue())
+	g.Expect(r.client.Get(context.Background(), client.ObjectKeyFromObject(notOwned), notOwned)).To(Succeed())
+	g.Expect(notOwned.Annotations).ToNot(HaveKey(annotations.AuthoritativeAPIAnnotation))
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package migration mirrors the Machine API resources into Cluster API resources, so that the Machines can be
+// handed over to the Cluster API controllers without recreating their instances.
+//
+// Each MachineSet is mirrored into a MachineDeployment, and each Machine into a Cluster API Machine, with the same
+// name in the Cluster API namespace. The authoritative-api annotation of the Machine API resource tells which
+// controllers reconcile the pair:
+//
+//   - MachineAPI (the default): the mirror is paused and its spec and status follow the Machine API resource.
+//     Deleting the Machine API resource deletes its mirror.
+//   - ClusterAPI: the Machine API controllers skip the resource, the mirror is unpaused and the replicas, provider ID
+//     and status of the Machine API resource follow the mirror. Deleting either of them deletes the pair, the
+//     instance being deleted by the Cluster API controllers.
+//
+// The providerSpec is not converted: the mirrors reference the infrastructure Machines and templates of the
+// provider with the same name, which the Cluster API infrastructure providers are responsible for creating.
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// DefaultClusterAPINamespace is the namespace of the Cluster API resources in OpenShift.
+	DefaultClusterAPINamespace = "openshift-cluster-api"
+
+	// MirrorUIDAnnotation records on the Machine API resource the UID of its mirror, so that a mirror deleted by
+	// the Cluster API controllers can be told apart from a mirror which was never created.
+	MirrorUIDAnnotation = "machine.openshift.io/cluster-api-mirror-uid"
+
+	machineSetControllerName = "machineset_migration_controller"
+	machineControllerName    = "machine_migration_controller"
+)
+
+// Options configures the migration controllers.
+type Options struct {
+	// MachineAPINamespace is the namespace of the Machine API resources.
+	MachineAPINamespace string
+	// ClusterAPINamespace is the namespace of the mirrors, DefaultClusterAPINamespace when empty.
+	ClusterAPINamespace string
+	// ClusterName is the name of the Cluster the mirrors belong to. It defaults to the infrastructure name of the cluster.
+	ClusterName string
+}
+
+// syncer holds what the MachineSet and Machine migration controllers share.
+type syncer struct {
+	// client reads and writes the Machine API resources.
+	client client.Client
+	// capiClient reads and writes the Cluster API resources.
+	capiClient client.Client
+	recorder   record.EventRecorder
+
+	clusterName   string
+	capiNamespace string
+}
+
+// AddWithOptions returns a function which adds the MachineSet and Machine migration controllers to the Manager.
+// The Cluster API resources are watched through a cache of their namespace, added to the Manager.
+func AddWithOptions(o Options) func(manager.Manager, manager.Options) error {
+	return func(mgr manager.Manager, opts manager.Options) error {
+		if o.MachineAPINamespace == "" {
+			return fmt.Errorf("the namespace of the Machine API resources is required to mirror them")
+		}
+		if o.ClusterAPINamespace == "" {
+			o.ClusterAPINamespace = DefaultClusterAPINamespace
+		}
+		if o.ClusterName == "" {
+			infra := &configv1.Infrastructure{}
+			if err := mgr.GetAPIReader().Get(context.Background(), client.ObjectKey{Name: "cluster"}, infra); err != nil {
+				return fmt.Errorf("could not fetch the infrastructure name: %w", err)
+			}
+			o.ClusterName = infra.Status.InfrastructureName
+		}
+
+		capiCache, err := cache.New(mgr.GetConfig(), cache.Options{
+			Scheme:    mgr.GetScheme(),
+			Mapper:    mgr.GetRESTMapper(),
+			Namespace: o.ClusterAPINamespace,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating the cluster api cache: %w", err)
+		}
+		if err := mgr.Add(capiCache); err != nil {
+			return err
+		}
+		capiClient, err := client.NewDelegatingClient(client.NewDelegatingClientInput{
+			CacheReader: capiCache,
+			Client:      mgr.GetClient(),
+		})
+		if err != nil {
+			return fmt.Errorf("error creating the cluster api client: %w", err)
+		}
+
+		s := syncer{
+			client:        mgr.GetClient(),
+			capiClient:    capiClient,
+			clusterName:   o.ClusterName,
+			capiNamespace: o.ClusterAPINamespace,
+		}
+		toMachineAPI := handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+			return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: o.MachineAPINamespace, Name: obj.GetName()}}}
+		})
+
+		machineSetSyncer := s
+		machineSetSyncer.recorder = mgr.GetEventRecorderFor(machineSetControllerName)
+		c, err := controller.New(machineSetControllerName, mgr, controller.Options{
+			Reconciler: &ReconcileMachineSetMigration{syncer: machineSetSyncer},
+		})
+		if err != nil {
+			return err
+		}
+		if err := c.Watch(&source.Kind{Type: &machinev1.MachineSet{}}, &handler.EnqueueRequestForObject{}); err != nil {
+			return err
+		}
+		if err := c.Watch(source.NewKindWithCache(&clusterv1.MachineDeployment{}, capiCache), toMachineAPI); err != nil {
+			return err
+		}
+
+		machineSyncer := s
+		machineSyncer.recorder = mgr.GetEventRecorderFor(machineControllerName)
+		c, err = controller.New(machineControllerName, mgr, controller.Options{
+			Reconciler: &ReconcileMachineMigration{syncer: machineSyncer},
+		})
+		if err != nil {
+			return err
+		}
+		if err := c.Watch(&source.Kind{Type: &machinev1.Machine{}}, &handler.EnqueueRequestForObject{}); err != nil {
+			return err
+		}
+		return c.Watch(source.NewKindWithCache(&clusterv1.Machine{}, capiCache), toMachineAPI)
+	}
+}
+
+// getAuthoritativeAPI returns the API reconciling the Machine API resource.
+func getAuthoritativeAPI(obj client.Object) (string, error) {
+	api, ok := obj.GetAnnotations()[annotations.AuthoritativeAPIAnnotation]
+	if !ok {
+		return annotations.AuthoritativeAPIMachineAPI, nil
+	}
+	switch api {
+	case annotations.AuthoritativeAPIMachineAPI, annotations.AuthoritativeAPIClusterAPI:
+		return api, nil
+	}
+	return "", fmt.Errorf("invalid %s annotation %q: must be either %s or %s", annotations.AuthoritativeAPIAnnotation, api,
+		annotations.AuthoritativeAPIMachineAPI, annotations.AuthoritativeAPIClusterAPI)
+}
+
+// getMirror fetches the mirror of the Machine API resource into obj, returning false when it does not exist.
+func (s *syncer) getMirror(ctx context.Context, name string, obj client.Object) (bool, error) {
+	if err := s.capiClient.Get(ctx, client.ObjectKey{Namespace: s.capiNamespace, Name: name}, obj); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// deletePausedMirror deletes the mirror of a Machine API resource which no longer exists, unless the mirror was
+// reconciled by the Cluster API controllers.
+func (s *syncer) deletePausedMirror(ctx context.Context, name string, obj client.Object) error {
+	found, err := s.getMirror(ctx, name, obj)
+	if err != nil || !found || !annotations.HasPausedAnnotation(obj) || obj.GetDeletionTimestamp() != nil {
+		return err
+	}
+	return client.IgnoreNotFound(s.capiClient.Delete(ctx, obj))
+}
+
+// recordMirrorUID records the UID of the mirror on the Machine API resource, returning whether it changed.
+func recordMirrorUID(obj, mirror client.Object) bool {
+	if obj.GetAnnotations()[MirrorUIDAnnotation] == string(mirror.GetUID()) {
+		return false
+	}
+	objAnnotations := obj.GetAnnotations()
+	if objAnnotations == nil {
+		objAnnotations = map[string]string{}
+	}
+	objAnnotations[MirrorUIDAnnotation] = string(mirror.GetUID())
+	obj.SetAnnotations(objAnnotations)
+	return true
+}
+
+// setPaused pauses or unpauses the mirror for the Cluster API controllers, returning whether it changed.
+func setPaused(mirror client.Object, paused bool) bool {
+	_, isPaused := mirror.GetAnnotations()[clusterv1.PausedAnnotation]
+	if isPaused == paused {
+		return false
+	}
+	mirrorAnnotations := mirror.GetAnnotations()
+	if paused {
+		if mirrorAnnotations == nil {
+			mirrorAnnotations = map[string]string{}
+		}
+		mirrorAnnotations[clusterv1.PausedAnnotation] = ""
+	} else {
+		delete(mirrorAnnotations, clusterv1.PausedAnnotation)
+	}
+	mirror.SetAnnotations(mirrorAnnotations)
+	return true
+}
+
+// warn records a warning event on the Machine API resource.
+func (s *syncer) warn(obj client.Object, reason string, err error) {
+	s.recorder.Eventf(obj, corev1.EventTypeWarning, reason, "%v", err)
+}
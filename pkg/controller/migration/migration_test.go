@@ -0,0 +1,147 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	machineAPINamespace = "openshift-machine-api"
+	clusterName         = "test-cluster-abcde"
+)
+
+func newTestSyncer(t *testing.T, objs ...client.Object) syncer {
+	scheme := runtime.NewScheme()
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+	if err := clusterv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("cannot add scheme: %v", err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return syncer{
+		client:        c,
+		capiClient:    c,
+		recorder:      record.NewFakeRecorder(10),
+		clusterName:   clusterName,
+		capiNamespace: DefaultClusterAPINamespace,
+	}
+}
+
+func newProviderSpec(kind string) machinev1.ProviderSpec {
+	return machinev1.ProviderSpec{Value: &runtime.RawExtension{
+		Raw: []byte(`{"kind":"` + kind + `","userDataSecret":{"name":"worker-user-data"}}`),
+	}}
+}
+
+func TestGetAuthoritativeAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		annotations   map[string]string
+		expectedAPI   string
+		expectedError string
+	}{
+		{
+			name:        "without the annotation",
+			expectedAPI: annotations.AuthoritativeAPIMachineAPI,
+		},
+		{
+			name:        "with Cluster API",
+			annotations: map[string]string{annotations.AuthoritativeAPIAnnotation: annotations.AuthoritativeAPIClusterAPI},
+			expectedAPI: annotations.AuthoritativeAPIClusterAPI,
+		},
+		{
+			name:          "with an invalid API",
+			annotations:   map[string]string{annotations.AuthoritativeAPIAnnotation: "Cluster"},
+			expectedError: `invalid machine.openshift.io/authoritative-api annotation "Cluster": must be either MachineAPI or ClusterAPI`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			api, err := getAuthoritativeAPI(&machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}})
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(api).To(Equal(tc.expectedAPI))
+		})
+	}
+}
+
+func TestParseProviderSpec(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		providerSpec           machinev1.ProviderSpec
+		expectedKind           string
+		expectedUserDataSecret string
+		expectedError          string
+	}{
+		{
+			name:                   "with an AWS providerSpec",
+			providerSpec:           newProviderSpec("AWSMachineProviderConfig"),
+			expectedKind:           "AWSMachine",
+			expectedUserDataSecret: "worker-user-data",
+		},
+		{
+			name: "with a bare metal providerSpec",
+			providerSpec: machinev1.ProviderSpec{Value: &runtime.RawExtension{
+				Raw: []byte(`{"kind":"BareMetalMachineProviderSpec","userData":{"name":"worker-user-data-managed"}}`),
+			}},
+			expectedKind:           "Metal3Machine",
+			expectedUserDataSecret: "worker-user-data-managed",
+		},
+		{
+			name:          "with an empty providerSpec",
+			expectedError: "providerSpec is empty",
+		},
+		{
+			name:          "with a providerSpec without a Cluster API provider",
+			providerSpec:  newProviderSpec("NutanixMachineProviderConfig"),
+			expectedError: `providerSpec kind "NutanixMachineProviderConfig" has no Cluster API infrastructure provider`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			kind, userDataSecret, err := parseProviderSpec(tc.providerSpec)
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(kind).To(Equal(tc.expectedKind))
+			g.Expect(userDataSecret).To(Equal(tc.expectedUserDataSecret))
+		})
+	}
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -16,14 +20,31 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/openshift/machine-api-operator/pkg/metrics"
+	"github.com/openshift/machine-api-operator/pkg/termination"
+	"github.com/openshift/machine-api-operator/pkg/util/nodeaddress"
+	"github.com/openshift/machine-api-operator/pkg/util/nodeconfig"
+	"github.com/openshift/machine-api-operator/pkg/util/tracing"
 )
 
 const (
-	machineAnnotationKey   = "machine.openshift.io/machine"
+	machineAnnotationKey   = termination.MachineAnnotationKey
 	machineInternalIPIndex = "machineInternalIPIndex"
 	machineProviderIDIndex = "machineProviderIDIndex"
 	nodeInternalIPIndex    = "nodeInternalIPIndex"
 	nodeProviderIDIndex    = "nodeProviderIDIndex"
+
+	machineInternalDNSIndex = "machineInternalDNSIndex"
+	nodeInternalDNSIndex    = "nodeInternalDNSIndex"
+
+	// managedTaintsAnnotationKey records the taints of the node added from the machine, by key and effect.
+	managedTaintsAnnotationKey = "machine.openshift.io/managed-taints"
+	// managedLabelsAnnotationKey records the labels of the node added from the node labels annotation of the machine, by key.
+	managedLabelsAnnotationKey = "machine.openshift.io/managed-labels"
+
+	// DefaultPropagatedPrefix is the default prefix of the Machine labels and annotations propagated to the Node.
+	DefaultPropagatedPrefix = "node-label.machine.openshift.io/"
 )
 
 // blank assignment to verify that ReconcileNodeLink implements reconcile.Reconciler
@@ -39,16 +60,38 @@ type ReconcileNodeLink struct {
 	listNodesByFieldFunc    func(key, value string) ([]corev1.Node, error)
 	listMachinesByFieldFunc func(key, value string) ([]machinev1.Machine, error)
 	nodeReadinessCache      map[string]bool
+	// propagatedPrefixes are the prefixes of the Machine labels and annotations kept in sync on the Node.
+	propagatedPrefixes []string
+	// unmatchedMachines and unmatchedNodes track the Machines and Nodes which could not be linked.
+	unmatchedMachines *unmatchedObjects
+	unmatchedNodes    *unmatchedObjects
+}
+
+// Options configures the Nodelink Controller.
+type Options struct {
+	// PropagatedPrefixes are the prefixes of the Machine labels and annotations which are continuously
+	// propagated to the Node, including their removal. The Node labels and annotations with these
+	// prefixes are owned by the Machine.
+	PropagatedPrefixes []string
+	// RateLimiter limits the requeues of the Nodes. The default rate limiter of the controllers is used when nil.
+	RateLimiter workqueue.RateLimiter
 }
 
 // Add creates a new Nodelink Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager, opts manager.Options) error {
-	reconciler, err := newReconciler(mgr)
-	if err != nil {
-		return fmt.Errorf("error building reconciler: %v", err)
+	return AddWithOptions(Options{PropagatedPrefixes: []string{DefaultPropagatedPrefix}})(mgr, opts)
+}
+
+// AddWithOptions returns a function which adds a new Nodelink Controller, configured with the given options, to the Manager.
+func AddWithOptions(o Options) func(manager.Manager, manager.Options) error {
+	return func(mgr manager.Manager, opts manager.Options) error {
+		reconciler, err := newReconciler(mgr, o)
+		if err != nil {
+			return fmt.Errorf("error building reconciler: %v", err)
+		}
+		return add(mgr, reconciler, reconciler.nodeRequestFromMachine, o.RateLimiter)
 	}
-	return add(mgr, reconciler, reconciler.nodeRequestFromMachine)
 }
 
 func indexNodeByProviderID(object client.Object) []string {
@@ -84,18 +127,50 @@ func indexNodeByInternalIP(object client.Object) []string {
 		return nil
 	}
 
+	// The IPs are normalized for the different spellings of the IPv6 addresses to match.
+	keys := nodeaddress.InternalIPs(node.Status.Addresses)
+	for _, ip := range keys {
+		klog.V(3).Infof("Adding internal IP %q for node %q to indexer", ip, node.GetName())
+	}
+
+	return keys
+}
+
+func indexMachineByInternalIP(object client.Object) []string {
+	machine, ok := object.(*machinev1.Machine)
+	if !ok {
+		klog.Warningf("Expected a machine for indexing field, got: %T", object)
+		return nil
+	}
+
+	// The IPs are normalized for the different spellings of the IPv6 addresses to match.
+	keys := nodeaddress.InternalIPs(machine.Status.Addresses)
+	for _, ip := range keys {
+		klog.V(3).Infof("Adding internal IP %q for machine %q to indexer", ip, machine.GetName())
+	}
+
+	return keys
+}
+
+func indexNodeByInternalDNS(object client.Object) []string {
+	node, ok := object.(*corev1.Node)
+	if !ok {
+		klog.Warningf("expected a node for indexing field, got: %T", object)
+		return nil
+	}
+
 	var keys []string
 	for _, a := range node.Status.Addresses {
-		if a.Type == corev1.NodeInternalIP {
+		if a.Type == corev1.NodeInternalDNS {
 			keys = append(keys, a.Address)
-			klog.V(3).Infof("Adding internal IP %q for node %q to indexer", a.Address, node.GetName())
+			klog.V(3).Infof("Adding internal DNS %q for node %q to indexer", a.Address, node.GetName())
 		}
 	}
 
 	return keys
 }
 
-func indexMachineByInternalIP(object client.Object) []string {
+func indexMachineByInternalDNS(object client.Object) []string {
 	machine, ok := object.(*machinev1.Machine)
 	if !ok {
 		klog.Warningf("Expected a machine for indexing field, got: %T", object)
@@ -104,9 +179,9 @@ func indexMachineByInternalIP(object client.Object) []string {
 
 	var keys []string
 	for _, a := range machine.Status.Addresses {
-		if a.Type == corev1.NodeInternalIP {
+		if a.Type == corev1.NodeInternalDNS {
 			keys = append(keys, a.Address)
-			klog.V(3).Infof("Adding internal IP %q for machine %q to indexer", a.Address, machine.GetName())
+			klog.V(3).Infof("Adding internal DNS %q for machine %q to indexer", a.Address, machine.GetName())
 		}
 	}
 
@@ -114,7 +189,7 @@ func indexMachineByInternalIP(object client.Object) []string {
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) (*ReconcileNodeLink, error) {
+func newReconciler(mgr manager.Manager, o Options) (*ReconcileNodeLink, error) {
 	// set convenient indexers
 	if err := mgr.GetCache().IndexField(context.TODO(),
 		&corev1.Node{},
@@ -148,8 +223,27 @@ func newReconciler(mgr manager.Manager) (*ReconcileNodeLink, error) {
 		return nil, fmt.Errorf("error setting index fields: %v", err)
 	}
 
+	if err := mgr.GetCache().IndexField(context.TODO(),
+		&corev1.Node{},
+		nodeInternalDNSIndex,
+		indexNodeByInternalDNS,
+	); err != nil {
+		return nil, fmt.Errorf("error setting index fields: %v", err)
+	}
+
+	if err := mgr.GetCache().IndexField(context.TODO(),
+		&machinev1.Machine{},
+		machineInternalDNSIndex,
+		indexMachineByInternalDNS,
+	); err != nil {
+		return nil, fmt.Errorf("error setting index fields: %v", err)
+	}
+
 	r := ReconcileNodeLink{
-		client: mgr.GetClient(),
+		client:             mgr.GetClient(),
+		propagatedPrefixes: o.PropagatedPrefixes,
+		unmatchedMachines:  newUnmatchedObjects(metrics.ObserveNodeLinkUnmatchedMachines),
+		unmatchedNodes:     newUnmatchedObjects(metrics.ObserveNodeLinkUnmatchedNodes),
 	}
 	r.nodeReadinessCache = make(map[string]bool)
 
@@ -161,9 +255,9 @@ func newReconciler(mgr manager.Manager) (*ReconcileNodeLink, error) {
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
-func add(mgr manager.Manager, r reconcile.Reconciler, mapFn handler.MapFunc) error {
+func add(mgr manager.Manager, r reconcile.Reconciler, mapFn handler.MapFunc, rateLimiter workqueue.RateLimiter) error {
 	// Create a new controller
-	c, err := controller.New("nodelink-controller", mgr, controller.Options{Reconciler: r})
+	c, err := controller.New("nodelink-controller", mgr, controller.Options{Reconciler: tracing.NewReconciler("nodelink-controller", r), RateLimiter: rateLimiter})
 	if err != nil {
 		return err
 	}
@@ -198,6 +292,7 @@ func (r *ReconcileNodeLink) Reconcile(ctx context.Context, request reconcile.Req
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			r.unmatchedNodes.set(request.Name, false)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -211,6 +306,8 @@ func (r *ReconcileNodeLink) Reconcile(ctx context.Context, request reconcile.Req
 		return reconcile.Result{}, fmt.Errorf("failed to find machine from node %q: %v", node.GetName(), err)
 	}
 
+	// Nodes being deleted are no longer expected to be linked.
+	r.unmatchedNodes.set(node.GetName(), machine == nil && node.DeletionTimestamp.IsZero())
 	if machine == nil {
 		klog.Warningf("Machine for node %q not found", node.GetName())
 		return reconcile.Result{}, nil
@@ -230,6 +327,7 @@ func (r *ReconcileNodeLink) Reconcile(ctx context.Context, request reconcile.Req
 		modNode.Annotations = map[string]string{}
 	}
 	modNode.Annotations[machineAnnotationKey] = fmt.Sprintf("%s/%s", machine.GetNamespace(), machine.GetName())
+	syncPropagatedMetadata(modNode.Annotations, machine.Annotations, r.propagatedPrefixes)
 	for k, v := range machine.Spec.Annotations {
 		klog.V(4).Infof("Copying annotation %s = %s", k, v)
 		modNode.Annotations[k] = v
@@ -239,12 +337,31 @@ func (r *ReconcileNodeLink) Reconcile(ctx context.Context, request reconcile.Req
 		modNode.Labels = map[string]string{}
 	}
 
+	syncPropagatedMetadata(modNode.Labels, machine.Labels, r.propagatedPrefixes)
+	syncLabelsToNode(modNode, machine)
 	for k, v := range machine.Spec.Labels {
 		klog.V(4).Infof("Copying label %s = %s", k, v)
 		modNode.Labels[k] = v
 	}
 
-	addTaintsToNode(modNode, machine)
+	syncTaintsToNode(modNode, machine)
+
+	// The machine of a node whose instance is about to be terminated is deleted, the machine of a node
+	// whose rebalance is recommended is deleted when the policy of its machineset is to drain it.
+	deleteMachine := false
+	if termination.IsNodeTerminating(modNode) {
+		addTerminatingTaint(modNode)
+		deleteMachine = true
+	} else if termination.IsNodeRebalanceRecommended(modNode) {
+		policy, err := r.rebalanceRecommendationPolicy(ctx, machine)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("error getting the rebalance recommendation policy of machine %q: %v", machine.GetName(), err)
+		}
+		if policy == termination.RebalanceRecommendationPolicyCordon || policy == termination.RebalanceRecommendationPolicyDrain {
+			modNode.Spec.Unschedulable = true
+		}
+		deleteMachine = policy == termination.RebalanceRecommendationPolicyDrain
+	}
 
 	if !reflect.DeepEqual(node, modNode) {
 		klog.V(3).Infof("Node %q has changed, updating", modNode.GetName())
@@ -253,6 +370,14 @@ func (r *ReconcileNodeLink) Reconcile(ctx context.Context, request reconcile.Req
 		}
 	}
 
+	// Deleting the machine drains the node before the instance is gone, and lets its machineset replace it.
+	if deleteMachine && machine.DeletionTimestamp.IsZero() {
+		klog.Infof("Node %q is terminating or its rebalance is recommended, deleting machine %q", node.GetName(), machine.GetName())
+		if err := r.client.Delete(context.Background(), machine); err != nil && !errors.IsNotFound(err) {
+			return reconcile.Result{}, fmt.Errorf("error deleting machine %q of node %q: %v", machine.GetName(), node.GetName(), err)
+		}
+	}
+
 	return reconcile.Result{}, nil
 }
 
@@ -275,6 +400,7 @@ func (r *ReconcileNodeLink) updateNodeRef(machine *machinev1.Machine, node *core
 
 	// if the nodeReadiness has changed the machine is updated so
 	// watchers can take action, e.g machine controller
+	linked := machine.Status.NodeRef == nil
 	machine.Status.NodeRef = &corev1.ObjectReference{
 		Kind: "Node",
 		Name: node.GetName(),
@@ -284,6 +410,9 @@ func (r *ReconcileNodeLink) updateNodeRef(machine *machinev1.Machine, node *core
 		return fmt.Errorf("error updating machine %q: %v", machine.GetName(), err)
 	}
 	r.nodeReadinessCache[node.GetName()] = nodeReady
+	if linked {
+		metrics.ObserveNodeLinkLinkDuration(now.Sub(machine.CreationTimestamp.Time))
+	}
 
 	klog.Infof("Successfully updated nodeRef for machine %q and node %q", machine.GetName(), node.GetName())
 	return nil
@@ -303,11 +432,15 @@ func (r *ReconcileNodeLink) nodeRequestFromMachine(o client.Object) []reconcile.
 		machine,
 	); err != nil {
 		klog.Errorf("No-op: Unable to retrieve machine %s/%s from store: %v", o.GetNamespace(), o.GetName(), err)
+		if errors.IsNotFound(err) {
+			r.unmatchedMachines.set(client.ObjectKeyFromObject(o).String(), false)
+		}
 		return []reconcile.Request{}
 	}
 
 	if machine.DeletionTimestamp != nil {
 		klog.V(3).Infof("No-op: Machine %q has a deletion timestamp", o.GetName())
+		r.unmatchedMachines.set(client.ObjectKeyFromObject(machine).String(), false)
 		return []reconcile.Request{}
 	}
 
@@ -317,6 +450,8 @@ func (r *ReconcileNodeLink) nodeRequestFromMachine(o client.Object) []reconcile.
 		klog.Errorf("No-op: Failed to find node for machine %q: %v", machine.GetName(), err)
 		return []reconcile.Request{}
 	}
+	// Machines are only expected to be linked once provisioned.
+	r.unmatchedMachines.set(client.ObjectKeyFromObject(machine).String(), node == nil && machineIsProvisioned(machine))
 	if node != nil {
 		return []reconcile.Request{
 			{
@@ -332,7 +467,7 @@ func (r *ReconcileNodeLink) nodeRequestFromMachine(o client.Object) []reconcile.
 	return []reconcile.Request{}
 }
 
-// findNodeFromMachine find a node from by providerID and fallback to find by IP
+// findNodeFromMachine find a node from by providerID and fallback to find by internal DNS, then by IP
 func (r *ReconcileNodeLink) findNodeFromMachine(machine *machinev1.Machine) (*corev1.Node, error) {
 	klog.V(3).Infof("Finding node from machine %q", machine.GetName())
 	node, err := r.findNodeFromMachineByProviderID(machine)
@@ -343,6 +478,14 @@ func (r *ReconcileNodeLink) findNodeFromMachine(machine *machinev1.Machine) (*co
 		return node, nil
 	}
 
+	node, err = r.findNodeFromMachineByInternalDNS(machine)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find node from machine %q by internal DNS: %v", machine.GetName(), err)
+	}
+	if node != nil {
+		return node, nil
+	}
+
 	node, err = r.findNodeFromMachineByIP(machine)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find node from machine %q by internal IP: %v", machine.GetName(), err)
@@ -376,35 +519,59 @@ func (r *ReconcileNodeLink) findNodeFromMachineByProviderID(machine *machinev1.M
 
 func (r *ReconcileNodeLink) findNodeFromMachineByIP(machine *machinev1.Machine) (*corev1.Node, error) {
 	klog.V(3).Infof("Finding node from machine %q by IP", machine.GetName())
-	var machineInternalAddress string
-	for _, a := range machine.Status.Addresses {
-		if a.Type == corev1.NodeInternalIP {
-			machineInternalAddress = a.Address
-			klog.V(3).Infof("Found internal IP for machine %q: %q", machine.GetName(), machineInternalAddress)
-			break
-		}
-	}
-
-	if machineInternalAddress == "" {
+	// Dual-stack machines have internal IPs of both families, the node may report either of them.
+	machineInternalIPs := nodeaddress.InternalIPs(machine.Status.Addresses)
+	if len(machineInternalIPs) == 0 {
 		klog.Warningf("not found internal IP for machine %q", machine.GetName())
 		return nil, nil
 	}
 
-	nodes, err := r.listNodesByFieldFunc(nodeInternalIPIndex, machineInternalAddress)
-	if err != nil {
-		return nil, fmt.Errorf("failed getting node list: %v", err)
+	for _, address := range machineInternalIPs {
+		nodes, err := r.listNodesByFieldFunc(nodeInternalIPIndex, address)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting node list: %v", err)
+		}
+
+		if len(nodes) > 1 {
+			return nil, fmt.Errorf("failed getting node: expected 1 node, got %v", len(nodes))
+		}
+
+		if len(nodes) == 1 {
+			klog.V(3).Infof("Found node %q for machine %q with internal IP %q", nodes[0].GetName(), machine.GetName(), address)
+			return nodes[0].DeepCopy(), nil
+		}
 	}
 
-	if len(nodes) > 1 {
-		return nil, fmt.Errorf("failed getting node: expected 1 node, got %v", len(nodes))
+	klog.V(3).Infof("Matching node not found for machine %q with internal IPs %v", machine.GetName(), machineInternalIPs)
+	return nil, nil
+}
+
+func (r *ReconcileNodeLink) findNodeFromMachineByInternalDNS(machine *machinev1.Machine) (*corev1.Node, error) {
+	klog.V(3).Infof("Finding node from machine %q by internal DNS", machine.GetName())
+	var machineInternalDNS []string
+	for _, a := range machine.Status.Addresses {
+		if a.Type == corev1.NodeInternalDNS {
+			machineInternalDNS = append(machineInternalDNS, a.Address)
+		}
 	}
 
-	if len(nodes) == 1 {
-		klog.V(3).Infof("Found node %q for machine %q with internal IP %q", nodes[0].GetName(), machine.GetName(), machineInternalAddress)
-		return nodes[0].DeepCopy(), nil
+	for _, address := range machineInternalDNS {
+		nodes, err := r.listNodesByFieldFunc(nodeInternalDNSIndex, address)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting node list: %v", err)
+		}
+
+		if len(nodes) > 1 {
+			return nil, fmt.Errorf("failed getting node: expected 1 node, got %v", len(nodes))
+		}
+
+		if len(nodes) == 1 {
+			klog.V(3).Infof("Found node %q for machine %q with internal DNS %q", nodes[0].GetName(), machine.GetName(), address)
+			return nodes[0].DeepCopy(), nil
+		}
 	}
 
-	klog.V(3).Infof("Matching node not found for machine %q with internal IP %q", machine.GetName(), machineInternalAddress)
+	klog.V(3).Infof("Matching node not found for machine %q with internal DNS %v", machine.GetName(), machineInternalDNS)
 	return nil, nil
 }
 
@@ -418,6 +585,14 @@ func (r *ReconcileNodeLink) findMachineFromNode(node *corev1.Node) (*machinev1.M
 		return machine, nil
 	}
 
+	machine, err = r.findMachineFromNodeByInternalDNS(node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find machine from node %q by internal DNS: %v", node.GetName(), err)
+	}
+	if machine != nil {
+		return machine, nil
+	}
+
 	machine, err = r.findMachineFromNodeByIP(node)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find machine from node %q by internal IP: %v", node.GetName(), err)
@@ -448,58 +623,274 @@ func (r *ReconcileNodeLink) findMachineFromNodeByProviderID(node *corev1.Node) (
 	return nil, nil
 }
 
-func (r *ReconcileNodeLink) findMachineFromNodeByIP(node *corev1.Node) (*machinev1.Machine, error) {
-	klog.V(3).Infof("Finding machine from node %q by IP", node.GetName())
-	var nodeInternalAddress string
+func (r *ReconcileNodeLink) findMachineFromNodeByInternalDNS(node *corev1.Node) (*machinev1.Machine, error) {
+	klog.V(3).Infof("Finding machine from node %q by internal DNS", node.GetName())
+	var nodeInternalDNS []string
 	for _, a := range node.Status.Addresses {
-		if a.Type == corev1.NodeInternalIP {
-			nodeInternalAddress = a.Address
-			klog.V(3).Infof("Found internal IP for node %q: %q", node.GetName(), nodeInternalAddress)
-			break
+		if a.Type == corev1.NodeInternalDNS {
+			nodeInternalDNS = append(nodeInternalDNS, a.Address)
+		}
+	}
+
+	for _, address := range nodeInternalDNS {
+		machines, err := r.listMachinesByFieldFunc(machineInternalDNSIndex, address)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting machine list: %v", err)
+		}
+
+		if len(machines) > 1 {
+			return nil, fmt.Errorf("failed getting machine: expected 1 machine, got %v", len(machines))
+		}
+
+		if len(machines) == 1 {
+			klog.V(3).Infof("Found machine %q for node %q with internal DNS %q", machines[0].GetName(), node.GetName(), address)
+			return machines[0].DeepCopy(), nil
 		}
 	}
 
-	if nodeInternalAddress == "" {
+	klog.V(3).Infof("Matching machine not found for node %q with internal DNS %v", node.GetName(), nodeInternalDNS)
+	return nil, nil
+}
+
+func (r *ReconcileNodeLink) findMachineFromNodeByIP(node *corev1.Node) (*machinev1.Machine, error) {
+	klog.V(3).Infof("Finding machine from node %q by IP", node.GetName())
+	// Dual-stack nodes have internal IPs of both families, the machine may report either of them.
+	nodeInternalIPs := nodeaddress.InternalIPs(node.Status.Addresses)
+	if len(nodeInternalIPs) == 0 {
 		klog.Warningf("Node %q has no internal IP", node.GetName())
 		return nil, nil
 	}
 
-	machines, err := r.listMachinesByFieldFunc(machineInternalIPIndex, nodeInternalAddress)
-	if err != nil {
-		return nil, fmt.Errorf("failed getting node list: %v", err)
+	for _, address := range nodeInternalIPs {
+		machines, err := r.listMachinesByFieldFunc(machineInternalIPIndex, address)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting machine list: %v", err)
+		}
+
+		if len(machines) > 1 {
+			return nil, fmt.Errorf("failed getting machine: expected 1 machine, got %v", len(machines))
+		}
+
+		if len(machines) == 1 {
+			klog.V(3).Infof("Found machine %q for node %q with internal IP %q", machines[0].GetName(), node.GetName(), address)
+			return machines[0].DeepCopy(), nil
+		}
 	}
 
-	if len(machines) > 1 {
-		return nil, fmt.Errorf("failed getting machine: expected 1 machine, got %v", len(machines))
+	klog.V(3).Infof("Matching machine not found for node %q with internal IPs %v", node.GetName(), nodeInternalIPs)
+	return nil, nil
+}
+
+// syncPropagatedMetadata makes the node labels or annotations with the propagated prefixes match
+// the ones of the machine, removing those which are no longer set on the machine.
+func syncPropagatedMetadata(nodeMetadata, machineMetadata map[string]string, prefixes []string) {
+	hasPropagatedPrefix := func(key string) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		}
+		return false
 	}
 
-	if len(machines) == 1 {
-		klog.V(3).Infof("Found machine %q for node %q with internal IP %q", machines[0].GetName(), node.GetName(), nodeInternalAddress)
-		return machines[0].DeepCopy(), nil
+	for k := range nodeMetadata {
+		if _, ok := machineMetadata[k]; !ok && hasPropagatedPrefix(k) {
+			klog.V(4).Infof("Removing propagated key %s", k)
+			delete(nodeMetadata, k)
+		}
+	}
+	for k, v := range machineMetadata {
+		if hasPropagatedPrefix(k) {
+			klog.V(4).Infof("Propagating %s = %s", k, v)
+			nodeMetadata[k] = v
+		}
 	}
+}
 
-	klog.V(3).Infof("Matching machine not found for node %q with internal IP %q", node.GetName(), nodeInternalAddress)
-	return nil, nil
+// syncLabelsToNode reconciles the labels of the node labels annotation of the machine onto the node, adding,
+// updating and removing them. Only the labels added from the annotation, recorded in the managed labels annotation
+// of the node, are removed.
+func syncLabelsToNode(node *corev1.Node, machine *machinev1.Machine) {
+	desired := map[string]string{}
+	if value, ok := machine.Annotations[nodeconfig.NodeLabelsAnnotation]; ok {
+		labels, err := nodeconfig.ParseLabels(value)
+		if err != nil {
+			klog.Warningf("Ignoring node labels of machine %q: %v", machine.GetName(), err)
+		} else {
+			desired = labels
+		}
+	}
+
+	if value := node.Annotations[managedLabelsAnnotationKey]; value != "" {
+		for _, key := range strings.Split(value, ",") {
+			if _, ok := desired[key]; !ok {
+				klog.V(4).Infof("Removing label %s from node %q, it was removed from machine %q", key, node.GetName(), machine.GetName())
+				delete(node.Labels, key)
+			}
+		}
+	}
+	if len(desired) == 0 {
+		delete(node.Annotations, managedLabelsAnnotationKey)
+		return
+	}
+
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	for k, v := range desired {
+		klog.V(4).Infof("Copying node label %s = %s", k, v)
+		node.Labels[k] = v
+	}
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	node.Annotations[managedLabelsAnnotationKey] = strings.Join(sets.StringKeySet(desired).List(), ",")
 }
 
-// addTaintsToNode adds taints from machine object to the node object
+// machineTaints returns the taints of the node of the machine, from its spec and its node taints annotation.
+// The taints of the spec take precedence over the taints of the annotation with the same key and effect.
+func machineTaints(machine *machinev1.Machine) []corev1.Taint {
+	value, ok := machine.Annotations[nodeconfig.NodeTaintsAnnotation]
+	if !ok {
+		return machine.Spec.Taints
+	}
+	annotationTaints, err := nodeconfig.ParseTaints(value)
+	if err != nil {
+		klog.Warningf("Ignoring node taints of machine %q: %v", machine.GetName(), err)
+		return machine.Spec.Taints
+	}
+
+	taints := append([]corev1.Taint{}, machine.Spec.Taints...)
+	seen := sets.NewString()
+	for _, taint := range machine.Spec.Taints {
+		seen.Insert(taintID(taint))
+	}
+	for _, taint := range annotationTaints {
+		if !seen.Has(taintID(taint)) {
+			taints = append(taints, taint)
+		}
+	}
+	return taints
+}
+
+// syncTaintsToNode reconciles the taints from machine object to the node object, adding, updating and removing them.
 // Taints are to be an authoritative list on the machine spec per cluster-api comments.
-// However, we believe many components can directly taint a node and there is no direct source of truth that should enforce a single writer of taints
-func addTaintsToNode(node *corev1.Node, machine *machinev1.Machine) {
-	for _, mTaint := range machine.Spec.Taints {
-		klog.V(4).Infof("Adding taint %v from machine %q to node %q", mTaint, machine.GetName(), node.GetName())
-		alreadyPresent := false
-		for _, nTaint := range node.Spec.Taints {
-			if nTaint.Key == mTaint.Key && nTaint.Effect == mTaint.Effect {
-				klog.V(4).Infof("Skipping to add machine taint, %v, to the node. Node already has a taint with same key and effect", mTaint)
-				alreadyPresent = true
-				break
+// However, we believe many components can directly taint a node and there is no direct source of truth that should enforce a single writer of taints,
+// so only the taints added from the machine, recorded in the managed taints annotation of the node, are updated or removed.
+// Server-side apply can't be used to scope the ownership of the taints, as the list of node taints is atomic.
+func syncTaintsToNode(node *corev1.Node, machine *machinev1.Machine) {
+	managed := sets.NewString()
+	value, tracked := node.Annotations[managedTaintsAnnotationKey]
+	if value != "" {
+		managed.Insert(strings.Split(value, ",")...)
+	}
+	taints := machineTaints(machine)
+	desired := map[string]corev1.Taint{}
+	for _, mTaint := range taints {
+		desired[taintID(mTaint)] = mTaint
+	}
+
+	owned := sets.NewString()
+	var nodeTaints []corev1.Taint
+	changed := false
+	for _, nTaint := range node.Spec.Taints {
+		id := taintID(nTaint)
+		mTaint, isDesired := desired[id]
+		switch {
+		case managed.Has(id) && !isDesired:
+			klog.V(4).Infof("Removing taint %v from node %q, it was removed from machine %q", nTaint, node.GetName(), machine.GetName())
+			changed = true
+			continue
+		case managed.Has(id) && isDesired:
+			if nTaint.Value != mTaint.Value {
+				klog.V(4).Infof("Updating taint %v from machine %q on node %q", mTaint, machine.GetName(), node.GetName())
+				nTaint.Value = mTaint.Value
+				changed = true
 			}
+			owned.Insert(id)
+		case isDesired && !tracked && nTaint.Value == mTaint.Value:
+			// The taints added from the machine before they were recorded in the annotation are adopted, when
+			// they are still on the machine. The ones already removed from the machine are left on the node.
+			klog.V(4).Infof("Adopting taint %v from machine %q on node %q", mTaint, machine.GetName(), node.GetName())
+			owned.Insert(id)
+		case isDesired:
+			klog.V(4).Infof("Skipping to add machine taint, %v, to the node. Node already has a taint with same key and effect", mTaint)
+		}
+		nodeTaints = append(nodeTaints, nTaint)
+		delete(desired, id)
+	}
+
+	for _, mTaint := range taints {
+		id := taintID(mTaint)
+		if _, ok := desired[id]; !ok {
+			continue
 		}
-		if !alreadyPresent {
-			node.Spec.Taints = append(node.Spec.Taints, mTaint)
+		klog.V(4).Infof("Adding taint %v from machine %q to node %q", mTaint, machine.GetName(), node.GetName())
+		nodeTaints = append(nodeTaints, mTaint)
+		owned.Insert(id)
+		delete(desired, id)
+		changed = true
+	}
+	if changed {
+		node.Spec.Taints = nodeTaints
+	}
+
+	if owned.Len() == 0 {
+		delete(node.Annotations, managedTaintsAnnotationKey)
+		return
+	}
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	node.Annotations[managedTaintsAnnotationKey] = strings.Join(owned.List(), ",")
+}
+
+// rebalanceRecommendationPolicy returns the rebalance recommendation policy set by the annotation of the machineset
+// of the machine, Ignore when the machine has no machineset or the annotation is unset or invalid.
+func (r *ReconcileNodeLink) rebalanceRecommendationPolicy(ctx context.Context, machine *machinev1.Machine) (termination.RebalanceRecommendationPolicy, error) {
+	owner := metav1.GetControllerOf(machine)
+	if owner == nil || owner.Kind != "MachineSet" {
+		return termination.RebalanceRecommendationPolicyIgnore, nil
+	}
+	machineSet := &machinev1.MachineSet{}
+	if err := r.client.Get(ctx, client.ObjectKey{Namespace: machine.GetNamespace(), Name: owner.Name}, machineSet); err != nil {
+		if errors.IsNotFound(err) {
+			return termination.RebalanceRecommendationPolicyIgnore, nil
+		}
+		return "", err
+	}
+
+	policy := termination.RebalanceRecommendationPolicy(machineSet.Annotations[termination.RebalanceRecommendationPolicyAnnotation])
+	switch policy {
+	case termination.RebalanceRecommendationPolicyIgnore, termination.RebalanceRecommendationPolicyCordon, termination.RebalanceRecommendationPolicyDrain:
+		return policy, nil
+	case "":
+		return termination.RebalanceRecommendationPolicyIgnore, nil
+	default:
+		klog.Warningf("Ignoring invalid %s annotation %q of machineset %q", termination.RebalanceRecommendationPolicyAnnotation, policy, machineSet.GetName())
+		return termination.RebalanceRecommendationPolicyIgnore, nil
+	}
+}
+
+// addTerminatingTaint adds the NoSchedule taint of the terminating nodes, so that no pods are scheduled on the node
+// while it is drained.
+func addTerminatingTaint(node *corev1.Node) {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == termination.TerminatingTaintKey && taint.Effect == corev1.TaintEffectNoSchedule {
+			return
 		}
 	}
+	now := metav1.Now()
+	node.Spec.Taints = append(node.Spec.Taints, corev1.Taint{
+		Key:       termination.TerminatingTaintKey,
+		Effect:    corev1.TaintEffectNoSchedule,
+		TimeAdded: &now,
+	})
+}
+
+// taintID identifies a taint by its key and effect, in the same way as the taints of a node are unique.
+func taintID(taint corev1.Taint) string {
+	return fmt.Sprintf("%s:%s", taint.Key, taint.Effect)
 }
 
 func (r *ReconcileNodeLink) listNodesByField(key, value string) ([]corev1.Node, error) {
@@ -535,3 +926,52 @@ func isNodeReady(node *corev1.Node) bool {
 	}
 	return false
 }
+
+// machineIsProvisioned returns true if the machine has been given a providerID or an address to be matched by.
+func machineIsProvisioned(machine *machinev1.Machine) bool {
+	return (machine.Spec.ProviderID != nil && *machine.Spec.ProviderID != "") || len(machine.Status.Addresses) > 0
+}
+
+// unmatchedObjects tracks the names of the objects which could not be linked,
+// reporting their number through the observe function whenever it changes.
+type unmatchedObjects struct {
+	mu      sync.Mutex
+	names   sets.String
+	observe func(int)
+}
+
+func newUnmatchedObjects(observe func(int)) *unmatchedObjects {
+	return &unmatchedObjects{
+		names:   sets.NewString(),
+		observe: observe,
+	}
+}
+
+// set records whether the named object is unmatched.
+func (u *unmatchedObjects) set(name string, unmatched bool) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if unmatched == u.names.Has(name) {
+		return
+	}
+	if unmatched {
+		u.names.Insert(name)
+	} else {
+		u.names.Delete(name)
+	}
+	u.observe(u.names.Len())
+}
+
+// len returns the number of unmatched objects.
+func (u *unmatchedObjects) len() int {
+	if u == nil {
+		return 0
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.names.Len()
+}
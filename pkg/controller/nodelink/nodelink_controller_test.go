@@ -11,12 +11,17 @@ import (
 
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/openshift/machine-api-operator/pkg/termination"
+	"github.com/openshift/machine-api-operator/pkg/util/nodeconfig"
 )
 
 func init() {
@@ -247,6 +252,30 @@ func TestFindMachineFromNodeByIP(t *testing.T) {
 			}, nil),
 			expected: nil,
 		},
+		{
+			machine: machine("dualStackInternalIPs", "test", []corev1.NodeAddress{
+				{
+					Type:    corev1.NodeInternalIP,
+					Address: "fd00::a",
+				},
+			}, nil, nil),
+			node: node("dualStackInternalIPs", "test", []corev1.NodeAddress{
+				{
+					Type:    corev1.NodeInternalIP,
+					Address: "10.0.0.1",
+				},
+				{
+					Type:    corev1.NodeInternalIP,
+					Address: "FD00::0A",
+				},
+			}, nil),
+			expected: machine("dualStackInternalIPs", "test", []corev1.NodeAddress{
+				{
+					Type:    corev1.NodeInternalIP,
+					Address: "fd00::a",
+				},
+			}, nil, nil),
+		},
 	}
 	for _, tc := range testCases {
 		r := newFakeReconciler(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(tc.machine).Build(), tc.machine, tc.node)
@@ -348,6 +377,30 @@ func TestFindNodeFromMachineByIP(t *testing.T) {
 			}, nil),
 			expected: nil,
 		},
+		{
+			machine: machine("dualStackInternalIPs", "test", []corev1.NodeAddress{
+				{
+					Type:    corev1.NodeInternalIP,
+					Address: "10.0.0.1",
+				},
+				{
+					Type:    corev1.NodeInternalIP,
+					Address: "fd00::a",
+				},
+			}, nil, nil),
+			node: node("dualStackInternalIPs", "test", []corev1.NodeAddress{
+				{
+					Type:    corev1.NodeInternalIP,
+					Address: "fd00::a",
+				},
+			}, nil),
+			expected: node("dualStackInternalIPs", "test", []corev1.NodeAddress{
+				{
+					Type:    corev1.NodeInternalIP,
+					Address: "fd00::a",
+				},
+			}, nil),
+		},
 	}
 	for _, tc := range testCases {
 		r := newFakeReconciler(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(tc.node).Build(), tc.machine, tc.node)
@@ -362,12 +415,15 @@ func TestFindNodeFromMachineByIP(t *testing.T) {
 	}
 }
 
-func TestAddTaintsToNode(t *testing.T) {
+func TestSyncTaintsToNode(t *testing.T) {
 	testCases := []struct {
 		description             string
 		nodeTaints              []corev1.Taint
+		managedTaints           string
 		machineTaints           []corev1.Taint
+		taintsAnnotation        string
 		expectedFinalNodeTaints []corev1.Taint
+		expectedManagedTaints   string
 	}{
 		{
 			description:             "no previous taint on node. Machine adds none",
@@ -380,6 +436,7 @@ func TestAddTaintsToNode(t *testing.T) {
 			nodeTaints:              []corev1.Taint{},
 			machineTaints:           []corev1.Taint{{Key: "dedicated", Value: "some-value", Effect: "NoSchedule"}},
 			expectedFinalNodeTaints: []corev1.Taint{{Key: "dedicated", Value: "some-value", Effect: "NoSchedule"}},
+			expectedManagedTaints:   "dedicated:NoSchedule",
 		},
 		{
 			description:   "already taint on node. Machine adds another",
@@ -387,6 +444,7 @@ func TestAddTaintsToNode(t *testing.T) {
 			machineTaints: []corev1.Taint{{Key: "dedicated", Value: "some-value", Effect: "NoSchedule"}},
 			expectedFinalNodeTaints: []corev1.Taint{{Key: "key1", Value: "some-value", Effect: "Schedule"},
 				{Key: "dedicated", Value: "some-value", Effect: "NoSchedule"}},
+			expectedManagedTaints: "dedicated:NoSchedule",
 		},
 		{
 			description:             "already taint on node. Machine adding same taint",
@@ -394,15 +452,172 @@ func TestAddTaintsToNode(t *testing.T) {
 			machineTaints:           []corev1.Taint{{Key: "key1", Value: "v2", Effect: "Schedule"}},
 			expectedFinalNodeTaints: []corev1.Taint{{Key: "key1", Value: "v1", Effect: "Schedule"}},
 		},
+		{
+			description:             "taint added from machine before the upgrade. Taint is adopted",
+			nodeTaints:              []corev1.Taint{{Key: "key1", Value: "v1", Effect: "NoSchedule"}, {Key: "other", Effect: "NoExecute"}},
+			machineTaints:           []corev1.Taint{{Key: "key1", Value: "v1", Effect: "NoSchedule"}},
+			expectedFinalNodeTaints: []corev1.Taint{{Key: "key1", Value: "v1", Effect: "NoSchedule"}, {Key: "other", Effect: "NoExecute"}},
+			expectedManagedTaints:   "key1:NoSchedule",
+		},
+		{
+			description:             "taint from machine on node. Machine updates its value",
+			nodeTaints:              []corev1.Taint{{Key: "key1", Value: "v1", Effect: "NoSchedule"}},
+			managedTaints:           "key1:NoSchedule",
+			machineTaints:           []corev1.Taint{{Key: "key1", Value: "v2", Effect: "NoSchedule"}},
+			expectedFinalNodeTaints: []corev1.Taint{{Key: "key1", Value: "v2", Effect: "NoSchedule"}},
+			expectedManagedTaints:   "key1:NoSchedule",
+		},
+		{
+			description: "taints from machine and others on node. Machine removes one",
+			nodeTaints: []corev1.Taint{{Key: "key1", Value: "v1", Effect: "NoSchedule"}, {Key: "other", Effect: "NoExecute"},
+				{Key: "key2", Effect: "NoSchedule"}},
+			managedTaints:           "key1:NoSchedule,key2:NoSchedule",
+			machineTaints:           []corev1.Taint{{Key: "key2", Effect: "NoSchedule"}},
+			expectedFinalNodeTaints: []corev1.Taint{{Key: "other", Effect: "NoExecute"}, {Key: "key2", Effect: "NoSchedule"}},
+			expectedManagedTaints:   "key2:NoSchedule",
+		},
+		{
+			description:             "taint from machine on node. Machine removes all",
+			nodeTaints:              []corev1.Taint{{Key: "key1", Value: "v1", Effect: "NoSchedule"}, {Key: "other", Effect: "NoExecute"}},
+			managedTaints:           "key1:NoSchedule",
+			expectedFinalNodeTaints: []corev1.Taint{{Key: "other", Effect: "NoExecute"}},
+		},
+		{
+			description:             "no previous taint on node. Machine annotation adds some",
+			nodeTaints:              []corev1.Taint{},
+			taintsAnnotation:        "infra=reserved:NoSchedule, gpu:PreferNoSchedule",
+			expectedFinalNodeTaints: []corev1.Taint{{Key: "gpu", Effect: "PreferNoSchedule"}, {Key: "infra", Value: "reserved", Effect: "NoSchedule"}},
+			expectedManagedTaints:   "gpu:PreferNoSchedule,infra:NoSchedule",
+		},
+		{
+			description:             "taint in machine spec and annotation. Machine spec takes precedence",
+			nodeTaints:              []corev1.Taint{},
+			machineTaints:           []corev1.Taint{{Key: "infra", Value: "spec", Effect: "NoSchedule"}},
+			taintsAnnotation:        "infra=annotation:NoSchedule",
+			expectedFinalNodeTaints: []corev1.Taint{{Key: "infra", Value: "spec", Effect: "NoSchedule"}},
+			expectedManagedTaints:   "infra:NoSchedule",
+		},
+		{
+			description:             "taint from machine annotation on node. Machine annotation is removed",
+			nodeTaints:              []corev1.Taint{{Key: "infra", Value: "reserved", Effect: "NoSchedule"}},
+			managedTaints:           "infra:NoSchedule",
+			taintsAnnotation:        "",
+			expectedFinalNodeTaints: nil,
+		},
 	}
 
 	for _, test := range testCases {
 		machine := machine("", "", nil, test.machineTaints, nil)
+		if test.taintsAnnotation != "" {
+			machine.Annotations = map[string]string{nodeconfig.NodeTaintsAnnotation: test.taintsAnnotation}
+		}
 		node := node("", "", nil, test.nodeTaints)
-		addTaintsToNode(node, machine)
+		if test.managedTaints != "" {
+			node.Annotations = map[string]string{managedTaintsAnnotationKey: test.managedTaints}
+		}
+		syncTaintsToNode(node, machine)
 		if !reflect.DeepEqual(node.Spec.Taints, test.expectedFinalNodeTaints) {
 			t.Errorf("Test case: %s. Expected: %v, got: %v", test.description, test.expectedFinalNodeTaints, node.Spec.Taints)
 		}
+		if node.Annotations[managedTaintsAnnotationKey] != test.expectedManagedTaints {
+			t.Errorf("Test case: %s. Expected managed taints: %q, got: %q", test.description, test.expectedManagedTaints, node.Annotations[managedTaintsAnnotationKey])
+		}
+	}
+}
+
+func TestSyncLabelsToNode(t *testing.T) {
+	testCases := []struct {
+		description           string
+		nodeLabels            map[string]string
+		managedLabels         string
+		labelsAnnotation      *string
+		expectedLabels        map[string]string
+		expectedManagedLabels string
+	}{
+		{
+			description:    "no node labels annotation",
+			nodeLabels:     map[string]string{"foo": "bar"},
+			expectedLabels: map[string]string{"foo": "bar"},
+		},
+		{
+			description:           "node labels are added to the node",
+			nodeLabels:            map[string]string{"foo": "bar"},
+			labelsAnnotation:      pointer.String("node-role.kubernetes.io/infra=, example.com/tier=gold"),
+			expectedLabels:        map[string]string{"foo": "bar", "node-role.kubernetes.io/infra": "", "example.com/tier": "gold"},
+			expectedManagedLabels: "example.com/tier,node-role.kubernetes.io/infra",
+		},
+		{
+			description:           "node labels removed from the annotation are removed from the node",
+			nodeLabels:            map[string]string{"foo": "bar", "node-role.kubernetes.io/infra": "", "example.com/tier": "gold"},
+			managedLabels:         "example.com/tier,node-role.kubernetes.io/infra",
+			labelsAnnotation:      pointer.String("example.com/tier=silver"),
+			expectedLabels:        map[string]string{"foo": "bar", "example.com/tier": "silver"},
+			expectedManagedLabels: "example.com/tier",
+		},
+		{
+			description:      "invalid node labels annotation",
+			nodeLabels:       map[string]string{"foo": "bar", "example.com/tier": "gold"},
+			managedLabels:    "example.com/tier",
+			labelsAnnotation: pointer.String("example.com/tier"),
+			expectedLabels:   map[string]string{"foo": "bar"},
+		},
+	}
+
+	for _, test := range testCases {
+		machine := machine("", "", nil, nil, nil)
+		if test.labelsAnnotation != nil {
+			machine.Annotations = map[string]string{nodeconfig.NodeLabelsAnnotation: *test.labelsAnnotation}
+		}
+		node := node("", "", nil, nil)
+		node.Labels = test.nodeLabels
+		node.Annotations = map[string]string{}
+		if test.managedLabels != "" {
+			node.Annotations[managedLabelsAnnotationKey] = test.managedLabels
+		}
+		syncLabelsToNode(node, machine)
+		if !reflect.DeepEqual(node.Labels, test.expectedLabels) {
+			t.Errorf("Test case: %s. Expected: %v, got: %v", test.description, test.expectedLabels, node.Labels)
+		}
+		if node.Annotations[managedLabelsAnnotationKey] != test.expectedManagedLabels {
+			t.Errorf("Test case: %s. Expected managed labels: %q, got: %q", test.description, test.expectedManagedLabels, node.Annotations[managedLabelsAnnotationKey])
+		}
+	}
+}
+
+func TestSyncPropagatedMetadata(t *testing.T) {
+	prefixes := []string{DefaultPropagatedPrefix, "example.com/"}
+
+	testCases := []struct {
+		description      string
+		nodeMetadata     map[string]string
+		machineMetadata  map[string]string
+		expectedMetadata map[string]string
+	}{
+		{
+			description:      "propagated keys are added to the node",
+			nodeMetadata:     map[string]string{"foo": "bar"},
+			machineMetadata:  map[string]string{"node-label.machine.openshift.io/rack": "r1", "example.com/zone": "z1", "other": "value"},
+			expectedMetadata: map[string]string{"foo": "bar", "node-label.machine.openshift.io/rack": "r1", "example.com/zone": "z1"},
+		},
+		{
+			description:      "propagated keys are updated on the node",
+			nodeMetadata:     map[string]string{"node-label.machine.openshift.io/rack": "r1"},
+			machineMetadata:  map[string]string{"node-label.machine.openshift.io/rack": "r2"},
+			expectedMetadata: map[string]string{"node-label.machine.openshift.io/rack": "r2"},
+		},
+		{
+			description:      "propagated keys removed from the machine are removed from the node",
+			nodeMetadata:     map[string]string{"foo": "bar", "node-label.machine.openshift.io/rack": "r1", "example.com/zone": "z1"},
+			machineMetadata:  map[string]string{"example.com/zone": "z1"},
+			expectedMetadata: map[string]string{"foo": "bar", "example.com/zone": "z1"},
+		},
+	}
+
+	for _, test := range testCases {
+		syncPropagatedMetadata(test.nodeMetadata, test.machineMetadata, prefixes)
+		if !reflect.DeepEqual(test.nodeMetadata, test.expectedMetadata) {
+			t.Errorf("Test case: %s. Expected: %v, got: %v", test.description, test.expectedMetadata, test.nodeMetadata)
+		}
 	}
 }
 
@@ -568,6 +783,121 @@ func TestReconcile(t *testing.T) {
 	}
 }
 
+func TestReconcileTerminatingNode(t *testing.T) {
+	m := machine("terminating", "terminating", nil, nil, nil)
+	n := node("terminating", "terminating", nil, nil)
+	n.Status.Conditions = append(n.Status.Conditions, corev1.NodeCondition{
+		Type:   termination.NodeConditionTerminating,
+		Status: corev1.ConditionTrue,
+		Reason: termination.TerminationRequestedReason,
+	})
+
+	r := newFakeReconciler(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(n, m).Build(), m, n)
+	if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(n)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	freshNode := &corev1.Node{}
+	if err := r.client.Get(ctx, client.ObjectKeyFromObject(n), freshNode); err != nil {
+		t.Fatalf("unexpected error getting node: %v", err)
+	}
+	var tainted bool
+	for _, taint := range freshNode.Spec.Taints {
+		if taint.Key == termination.TerminatingTaintKey && taint.Effect == corev1.TaintEffectNoSchedule {
+			tainted = true
+		}
+	}
+	if !tainted {
+		t.Errorf("expected node to have the %s taint, got: %v", termination.TerminatingTaintKey, freshNode.Spec.Taints)
+	}
+
+	err := r.client.Get(ctx, client.ObjectKeyFromObject(m), &machinev1.Machine{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected machine of the terminating node to be deleted, got: %v", err)
+	}
+}
+
+func TestReconcileRebalanceRecommendedNode(t *testing.T) {
+	testCases := []struct {
+		name                string
+		policy              string
+		expectedCordon      bool
+		expectedDeletion    bool
+		withoutMachineOwner bool
+	}{
+		{
+			name: "without a policy",
+		},
+		{
+			name:   "with the Ignore policy",
+			policy: "Ignore",
+		},
+		{
+			name:           "with the Cordon policy",
+			policy:         "Cordon",
+			expectedCordon: true,
+		},
+		{
+			name:             "with the Drain policy",
+			policy:           "Drain",
+			expectedCordon:   true,
+			expectedDeletion: true,
+		},
+		{
+			name:   "with an invalid policy",
+			policy: "Evict",
+		},
+		{
+			name:                "with the Drain policy on another machineset",
+			policy:              "Drain",
+			withoutMachineOwner: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			machineSet := &machinev1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "spot",
+					Namespace:   namespace,
+					Annotations: map[string]string{},
+				},
+			}
+			if tc.policy != "" {
+				machineSet.Annotations[termination.RebalanceRecommendationPolicyAnnotation] = tc.policy
+			}
+			m := machine("rebalance", "rebalance", nil, nil, nil)
+			if !tc.withoutMachineOwner {
+				m.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(machineSet, machinev1.GroupVersion.WithKind("MachineSet"))}
+			}
+			n := node("rebalance", "rebalance", nil, nil)
+			n.Status.Conditions = append(n.Status.Conditions, corev1.NodeCondition{
+				Type:   termination.NodeConditionRebalanceRecommended,
+				Status: corev1.ConditionTrue,
+				Reason: termination.RebalanceRecommendationReason,
+			})
+
+			r := newFakeReconciler(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(n, m, machineSet).Build(), m, n)
+			if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(n)}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			freshNode := &corev1.Node{}
+			if err := r.client.Get(ctx, client.ObjectKeyFromObject(n), freshNode); err != nil {
+				t.Fatalf("unexpected error getting node: %v", err)
+			}
+			if freshNode.Spec.Unschedulable != tc.expectedCordon {
+				t.Errorf("expected node unschedulable to be %v, got: %v", tc.expectedCordon, freshNode.Spec.Unschedulable)
+			}
+
+			err := r.client.Get(ctx, client.ObjectKeyFromObject(m), &machinev1.Machine{})
+			if tc.expectedDeletion != apierrors.IsNotFound(err) {
+				t.Errorf("expected machine deletion to be %v, got: %v", tc.expectedDeletion, err)
+			}
+		})
+	}
+}
+
 func TestIndexNodeByProviderID(t *testing.T) {
 	testCases := []struct {
 		object   client.Object
@@ -657,6 +987,19 @@ func TestIndexNodeByInternalIP(t *testing.T) {
 			}, nil),
 			expected: []string{"ip1", "ip2"},
 		},
+		{
+			object: node("dualStackInternalIPs", "test", []corev1.NodeAddress{
+				{
+					Type:    corev1.NodeInternalIP,
+					Address: "10.0.0.1",
+				},
+				{
+					Type:    corev1.NodeInternalIP,
+					Address: "FD00:0:0::0A",
+				},
+			}, nil),
+			expected: []string{"10.0.0.1", "fd00::a"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -702,6 +1045,19 @@ func TestIndexMachineByInternalIP(t *testing.T) {
 			}, nil, nil),
 			expected: []string{"ip1", "ip2"},
 		},
+		{
+			object: machine("dualStackInternalIPs", "test", []corev1.NodeAddress{
+				{
+					Type:    corev1.NodeInternalIP,
+					Address: "10.0.0.1",
+				},
+				{
+					Type:    corev1.NodeInternalIP,
+					Address: "FD00:0:0::0A",
+				},
+			}, nil, nil),
+			expected: []string{"10.0.0.1", "fd00::a"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -822,3 +1178,125 @@ func TestFindMachineFromNodeDoesNotPanicBZ1747246(t *testing.T) {
 		t.Errorf("expected error to contain %q, got %v", errmsg, err)
 	}
 }
+
+func TestFindNodeFromMachineByInternalDNS(t *testing.T) {
+	testCases := []struct {
+		machine  *machinev1.Machine
+		node     *corev1.Node
+		expected *corev1.Node
+	}{
+		{
+			machine: machine("noInternalDNS", "", nil, nil, nil),
+			node: node("anyInternalDNS", "", []corev1.NodeAddress{
+				{
+					Type:    corev1.NodeInternalDNS,
+					Address: "internalDNS",
+				},
+			}, nil),
+			expected: nil,
+		},
+		{
+			machine: machine("matchingInternalDNS", "", []corev1.NodeAddress{
+				{
+					Type:    corev1.NodeInternalDNS,
+					Address: "matchingInternalDNS",
+				},
+			}, nil, nil),
+			node: node("matchingInternalDNS", "", []corev1.NodeAddress{
+				{
+					Type:    corev1.NodeInternalDNS,
+					Address: "matchingInternalDNS",
+				},
+			}, nil),
+			expected: node("matchingInternalDNS", "", []corev1.NodeAddress{
+				{
+					Type:    corev1.NodeInternalDNS,
+					Address: "matchingInternalDNS",
+				},
+			}, nil),
+		},
+		{
+			machine: machine("nonMatchingInternalDNS", "", []corev1.NodeAddress{
+				{
+					Type:    corev1.NodeInternalDNS,
+					Address: "one DNS name",
+				},
+			}, nil, nil),
+			node: node("nonMatchingInternalDNS", "", []corev1.NodeAddress{
+				{
+					Type:    corev1.NodeInternalDNS,
+					Address: "a different DNS name",
+				},
+			}, nil),
+			expected: nil,
+		},
+	}
+	for _, tc := range testCases {
+		r := newFakeReconciler(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(tc.node).Build(), tc.machine, tc.node)
+		node, err := r.findNodeFromMachineByInternalDNS(tc.machine)
+		if err != nil {
+			t.Errorf("unexpected error finding node from machine by internal DNS: %v", err)
+		}
+		if !reflect.DeepEqual(node, tc.expected) {
+			t.Errorf("expected: %v, got: %v", tc.expected, node)
+		}
+	}
+}
+
+func TestFindMachineFromNodeByInternalDNS(t *testing.T) {
+	addresses := []corev1.NodeAddress{
+		{
+			Type:    corev1.NodeInternalDNS,
+			Address: "matchingInternalDNS",
+		},
+		{
+			Type:    corev1.NodeInternalIP,
+			Address: "internalIP",
+		},
+	}
+	m := machine("matchingInternalDNS", "", addresses, nil, nil)
+	n := node("matchingInternalDNS", "", addresses[:1], nil)
+
+	r := newFakeReconciler(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(m).Build(), m, n)
+	got, err := r.findMachineFromNodeByInternalDNS(n)
+	if err != nil {
+		t.Errorf("unexpected error finding machine from node by internal DNS: %v", err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("expected: %v, got: %v", m, got)
+	}
+
+	// The node has no internal IP, so it can only be matched by internal DNS.
+	got, err = r.findMachineFromNode(n)
+	if err != nil {
+		t.Errorf("unexpected error finding machine from node: %v", err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("expected: %v, got: %v", m, got)
+	}
+}
+
+func TestUnmatchedMachines(t *testing.T) {
+	unmatched := machine("unmatched", "unmatchedProviderID", nil, nil, nil)
+	notProvisioned := machine("notProvisioned", "", nil, nil, nil)
+	matched := machine("matched", "matchedProviderID", nil, nil, nil)
+	matchedNode := node("matched", "matchedProviderID", nil, nil)
+
+	r := newFakeReconciler(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(unmatched, notProvisioned, matched).Build(), matched, matchedNode)
+	var observed int
+	r.unmatchedMachines = newUnmatchedObjects(func(count int) { observed = count })
+
+	for _, m := range []*machinev1.Machine{unmatched, notProvisioned, matched} {
+		r.nodeRequestFromMachine(m)
+	}
+	if r.unmatchedMachines.len() != 1 || observed != 1 {
+		t.Errorf("expected 1 unmatched machine, got: %d, observed: %d", r.unmatchedMachines.len(), observed)
+	}
+
+	// The machine is no longer unmatched once its node joins.
+	r.buildFakeNodeIndexer(*node("unmatched", "unmatchedProviderID", nil, nil))
+	r.nodeRequestFromMachine(unmatched)
+	if r.unmatchedMachines.len() != 0 || observed != 0 {
+		t.Errorf("expected no unmatched machine, got: %d, observed: %d", r.unmatchedMachines.len(), observed)
+	}
+}
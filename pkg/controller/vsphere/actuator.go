@@ -184,3 +184,79 @@ func (a *Actuator) Delete(ctx context.Context, machine *machinev1.Machine) error
 	a.eventRecorder.Eventf(machine, corev1.EventTypeNormal, deleteEventAction, "Deleted machine %v", machine.GetName())
 	return scope.PatchMachine()
 }
+
+// SetPowerState powers the vm of a machine off or on, and is invoked by the machine controller
+// once the node of the machine has been drained.
+func (a *Actuator) SetPowerState(ctx context.Context, machine *machinev1.Machine, state machinecontroller.MachinePowerState) error {
+	klog.Infof("%s: actuator powering %s machine", machine.GetName(), state)
+	scope, err := newMachineScope(machineScopeParams{
+		Context:   ctx,
+		client:    a.client,
+		machine:   machine,
+		apiReader: a.apiReader,
+	})
+	if err != nil {
+		return fmt.Errorf(scopeFailFmt, machine.GetName(), err)
+	}
+	if err := newReconciler(scope).setPowerState(state); err != nil {
+		// Update machine and machine status in case it was modified
+		if err := scope.PatchMachine(); err != nil {
+			return err
+		}
+		return fmt.Errorf(reconcilerFailFmt, machine.GetName(), "power "+string(state), err)
+	}
+	return scope.PatchMachine()
+}
+
+// NeedsResize returns true if the number of CPUs or the memory of the providerSpec differ from the ones of the vm
+// of a machine.
+func (a *Actuator) NeedsResize(ctx context.Context, machine *machinev1.Machine) (bool, error) {
+	scope, err := newMachineScope(machineScopeParams{
+		Context:   ctx,
+		client:    a.client,
+		machine:   machine,
+		apiReader: a.apiReader,
+	})
+	if err != nil {
+		return false, fmt.Errorf(scopeFailFmt, machine.GetName(), err)
+	}
+	return newReconciler(scope).needsResize()
+}
+
+// Resize reconfigures the powered off vm of a machine with the number of CPUs and the memory of the providerSpec,
+// and is invoked by the machine controller between powering the vm off and on.
+func (a *Actuator) Resize(ctx context.Context, machine *machinev1.Machine) error {
+	klog.Infof("%s: actuator resizing machine", machine.GetName())
+	scope, err := newMachineScope(machineScopeParams{
+		Context:   ctx,
+		client:    a.client,
+		machine:   machine,
+		apiReader: a.apiReader,
+	})
+	if err != nil {
+		return fmt.Errorf(scopeFailFmt, machine.GetName(), err)
+	}
+	if err := newReconciler(scope).resize(); err != nil {
+		return fmt.Errorf(reconcilerFailFmt, machine.GetName(), "resize", err)
+	}
+	return nil
+}
+
+// Reboot reboots the powered on vm of a machine, and is invoked by the machine controller when a restart of the
+// machine is requested.
+func (a *Actuator) Reboot(ctx context.Context, machine *machinev1.Machine) error {
+	klog.Infof("%s: actuator rebooting machine", machine.GetName())
+	scope, err := newMachineScope(machineScopeParams{
+		Context:   ctx,
+		client:    a.client,
+		machine:   machine,
+		apiReader: a.apiReader,
+	})
+	if err != nil {
+		return fmt.Errorf(scopeFailFmt, machine.GetName(), err)
+	}
+	if err := newReconciler(scope).reboot(); err != nil {
+		return fmt.Errorf(reconcilerFailFmt, machine.GetName(), "reboot", err)
+	}
+	return nil
+}
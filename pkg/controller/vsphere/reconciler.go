@@ -30,6 +30,7 @@ import (
 	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	"github.com/openshift/machine-api-operator/pkg/controller/vsphere/session"
 	"github.com/openshift/machine-api-operator/pkg/metrics"
+	"github.com/openshift/machine-api-operator/pkg/util/nodeaddress"
 )
 
 const (
@@ -273,6 +274,156 @@ func (r *Reconciler) exists() (bool, error) {
 	return true, nil
 }
 
+// setPowerState powers the vm off or on, and waits for it to reach the requested power state.
+// The vm is powered off without shutting its guest down, its node has been drained by the machine controller.
+func (r *Reconciler) setPowerState(state machinecontroller.MachinePowerState) error {
+	vm, err := r.getVirtualMachine()
+	if err != nil {
+		return err
+	}
+
+	target := types.VirtualMachinePowerStatePoweredOn
+	if state == machinecontroller.MachinePowerStateOff {
+		target = types.VirtualMachinePowerStatePoweredOff
+	}
+
+	powerState, err := vm.getPowerState()
+	if err != nil {
+		return fmt.Errorf("%v: failed checking machine's power state: %w", r.machine.GetName(), err)
+	}
+	if powerState != target {
+		var taskRef string
+		if target == types.VirtualMachinePowerStatePoweredOn {
+			taskRef, err = powerOn(r.machineScope)
+		} else {
+			taskRef, err = vm.powerOffVM()
+		}
+		if err != nil {
+			return fmt.Errorf("%v: failed to power %s vm: %w", r.machine.GetName(), state, err)
+		}
+		if err := waitForTask(r.machineScope, taskRef); err != nil {
+			return fmt.Errorf("%v: failed to power %s vm: %w", r.machine.GetName(), state, err)
+		}
+
+		// Powering on through the datacenter can succeed without powering the vm on, e.g. when DRS did not attempt it.
+		if powerState, err = vm.getPowerState(); err != nil {
+			return fmt.Errorf("%v: failed checking machine's power state: %w", r.machine.GetName(), err)
+		}
+		if powerState != target {
+			return fmt.Errorf("%v: vm is %s after powering it %s", r.machine.GetName(), powerState, state)
+		}
+	}
+
+	if err := r.reconcilePowerStateAnnontation(vm); err != nil {
+		return err
+	}
+	return setProviderStatus("", conditionSuccess(), r.machineScope, vm)
+}
+
+// needsResize returns true if the number of CPUs or the memory of the providerSpec differ from the ones of the vm.
+func (r *Reconciler) needsResize() (bool, error) {
+	vm, err := r.getVirtualMachine()
+	if err != nil {
+		return false, err
+	}
+
+	spec, err := vm.getResizeSpec(r.providerSpec)
+	if err != nil {
+		return false, err
+	}
+	return spec != nil, nil
+}
+
+// resize reconfigures the powered off vm with the number of CPUs and the memory of the providerSpec.
+func (r *Reconciler) resize() error {
+	vm, err := r.getVirtualMachine()
+	if err != nil {
+		return err
+	}
+
+	powerState, err := vm.getPowerState()
+	if err != nil {
+		return fmt.Errorf("%v: failed checking machine's power state: %w", r.machine.GetName(), err)
+	}
+	if powerState != types.VirtualMachinePowerStatePoweredOff {
+		return fmt.Errorf("%v: vm is %s, it must be powered off to be resized", r.machine.GetName(), powerState)
+	}
+
+	spec, err := vm.getResizeSpec(r.providerSpec)
+	if err != nil {
+		return err
+	}
+	if spec == nil {
+		return nil
+	}
+
+	klog.Infof("%v: resizing vm to %d CPUs with %d cores per socket and %d MiB of memory", r.machine.GetName(),
+		spec.NumCPUs, spec.NumCoresPerSocket, spec.MemoryMB)
+	task, err := vm.Obj.Reconfigure(r.Context, *spec)
+	if err != nil {
+		return fmt.Errorf("%v: failed to reconfigure vm: %w", r.machine.GetName(), err)
+	}
+	if err := task.Wait(r.Context); err != nil {
+		return fmt.Errorf("%v: failed to reconfigure vm: %w", r.machine.GetName(), err)
+	}
+	return nil
+}
+
+// reboot reboots the guest of the powered on vm if VMware Tools are running in it, and resets the vm otherwise.
+func (r *Reconciler) reboot() error {
+	vm, err := r.getVirtualMachine()
+	if err != nil {
+		return err
+	}
+
+	powerState, err := vm.getPowerState()
+	if err != nil {
+		return fmt.Errorf("%v: failed checking machine's power state: %w", r.machine.GetName(), err)
+	}
+	if powerState != types.VirtualMachinePowerStatePoweredOn {
+		return fmt.Errorf("%v: vm is %s, it must be powered on to be rebooted", r.machine.GetName(), powerState)
+	}
+
+	var o mo.VirtualMachine
+	if err := vm.Obj.Properties(r.Context, vm.Ref, []string{"guest.toolsRunningStatus"}, &o); err != nil {
+		return fmt.Errorf("%v: failed checking VMware Tools status: %w", r.machine.GetName(), err)
+	}
+	if o.Guest != nil && o.Guest.ToolsRunningStatus == string(types.VirtualMachineToolsRunningStatusGuestToolsRunning) {
+		klog.Infof("%v: rebooting vm guest", r.machine.GetName())
+		if err := vm.Obj.RebootGuest(r.Context); err != nil {
+			return fmt.Errorf("%v: failed to reboot vm guest: %w", r.machine.GetName(), err)
+		}
+		return nil
+	}
+
+	klog.Infof("%v: VMware Tools are not running, resetting vm", r.machine.GetName())
+	task, err := vm.Obj.Reset(r.Context)
+	if err != nil {
+		return fmt.Errorf("%v: failed to reset vm: %w", r.machine.GetName(), err)
+	}
+	if err := task.Wait(r.Context); err != nil {
+		return fmt.Errorf("%v: failed to reset vm: %w", r.machine.GetName(), err)
+	}
+	return nil
+}
+
+// getVirtualMachine returns the vm of the machine.
+func (r *Reconciler) getVirtualMachine() (*virtualMachine, error) {
+	vmRef, err := findVM(r.machineScope)
+	if err != nil {
+		if !isNotFound(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("vm not found: %w", err)
+	}
+
+	return &virtualMachine{
+		Context: r.machineScope.Context,
+		Obj:     object.NewVirtualMachine(r.machineScope.session.Client.Client, vmRef),
+		Ref:     vmRef,
+	}, nil
+}
+
 func (r *Reconciler) delete() error {
 	if r.providerStatus.TaskRef != "" {
 		// TODO: We need to use a separate status field for the create and the
@@ -964,6 +1115,12 @@ func newVMFlagInfo() *types.VirtualMachineFlagInfo {
 	}
 }
 
+// waitForTask waits for the task to complete, and returns its error if it failed.
+func waitForTask(s *machineScope, taskRef string) error {
+	task := object.NewTask(s.session.Client.Client, types.ManagedObjectReference{Type: "Task", Value: taskRef})
+	return task.Wait(s.Context)
+}
+
 func taskIsFinished(task *mo.Task) (bool, error) {
 	if task == nil {
 		return true, nil
@@ -1136,6 +1293,41 @@ func (vm *virtualMachine) getPowerState() (types.VirtualMachinePowerState, error
 	}
 }
 
+// getResizeSpec returns the config spec setting the number of CPUs and the memory of the vm to the ones of the
+// providerSpec, or nil if they already match. The values unset in the providerSpec keep the ones of the vm, as
+// they kept the ones of the template when the vm was cloned.
+func (vm *virtualMachine) getResizeSpec(providerSpec *machinev1.VSphereMachineProviderSpec) (*types.VirtualMachineConfigSpec, error) {
+	var o mo.VirtualMachine
+	if err := vm.Obj.Properties(vm.Context, vm.Ref, []string{"config.hardware"}, &o); err != nil {
+		return nil, fmt.Errorf("error getting hardware information for vm %s: %w", vm.Ref.Value, err)
+	}
+	if o.Config == nil {
+		return nil, fmt.Errorf("vm %s has no config", vm.Ref.Value)
+	}
+	hardware := o.Config.Hardware
+
+	spec := &types.VirtualMachineConfigSpec{
+		NumCPUs:           hardware.NumCPU,
+		NumCoresPerSocket: hardware.NumCoresPerSocket,
+		MemoryMB:          int64(hardware.MemoryMB),
+	}
+	if providerSpec.NumCPUs != 0 {
+		spec.NumCPUs = providerSpec.NumCPUs
+		spec.NumCoresPerSocket = providerSpec.NumCPUs
+	}
+	if providerSpec.NumCoresPerSocket != 0 {
+		spec.NumCoresPerSocket = providerSpec.NumCoresPerSocket
+	}
+	if providerSpec.MemoryMiB != 0 {
+		spec.MemoryMB = providerSpec.MemoryMiB
+	}
+
+	if spec.NumCPUs == hardware.NumCPU && spec.NumCoresPerSocket == hardware.NumCoresPerSocket && spec.MemoryMB == int64(hardware.MemoryMB) {
+		return nil, nil
+	}
+	return spec, nil
+}
+
 // reconcileTags ensures that the required tags are present on the virtual machine, eg the Cluster ID
 // that is used by the installer on cluster deletion to ensure ther are no leaked resources.
 func (vm *virtualMachine) reconcileTags(ctx context.Context, sessionInstance *session.Session, machine *machinev1.Machine) error {
@@ -1267,8 +1459,9 @@ func (vm *virtualMachine) getNetworkStatusList(client *vim25.Client) ([]NetworkS
 				for _, i := range obj.Guest.Net {
 					klog.V(3).Infof("Getting network status: getting guest info: network: %+v", i)
 					if strings.EqualFold(nic.MacAddress, i.MacAddress) {
-						//TODO: sanitizeIPAddrs
-						netStatus.IPAddrs = i.IpAddress
+						// The guests report the link-local IPv6 addresses of all their interfaces, which do not
+						// identify the VM, along with the IPv4 and IPv6 addresses of dual-stack networks.
+						netStatus.IPAddrs = nodeaddress.SanitizeIPs(i.IpAddress)
 						netStatus.NetworkName = i.Network
 						netStatus.Connected = i.Connected
 					}
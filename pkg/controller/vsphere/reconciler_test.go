@@ -691,6 +691,202 @@ func TestGetPowerState(t *testing.T) {
 	}
 }
 
+func TestSetPowerState(t *testing.T) {
+	model, simSession, server := initSimulator(t)
+	defer model.Remove()
+	defer server.Close()
+
+	simulatorVM := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine)
+
+	getReconciler := func(name string) *Reconciler {
+		return newReconciler(&machineScope{
+			Context: context.TODO(),
+			machine: &machinev1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "test",
+				},
+			},
+			providerSpec:   &machinev1.VSphereMachineProviderSpec{},
+			session:        simSession,
+			providerStatus: &machinev1.VSphereMachineProviderStatus{},
+			client:         fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(),
+		})
+	}
+
+	testCases := []struct {
+		name          string
+		machineName   string
+		withoutDC     bool
+		state         machinecontroller.MachinePowerState
+		expectedState types.VirtualMachinePowerState
+		expectedError string
+	}{
+		{
+			name:          "powers the vm off",
+			machineName:   simulatorVM.Name,
+			state:         machinecontroller.MachinePowerStateOff,
+			expectedState: types.VirtualMachinePowerStatePoweredOff,
+		},
+		{
+			name:          "keeps the vm powered off",
+			machineName:   simulatorVM.Name,
+			state:         machinecontroller.MachinePowerStateOff,
+			expectedState: types.VirtualMachinePowerStatePoweredOff,
+		},
+		{
+			name:          "powers the vm on through the datacenter",
+			machineName:   simulatorVM.Name,
+			state:         machinecontroller.MachinePowerStateOn,
+			expectedState: types.VirtualMachinePowerStatePoweredOn,
+		},
+		{
+			name:          "powers the vm off and on without a datacenter",
+			machineName:   simulatorVM.Name,
+			withoutDC:     true,
+			state:         machinecontroller.MachinePowerStateOn,
+			expectedState: types.VirtualMachinePowerStatePoweredOn,
+		},
+		{
+			name:          "fails if the vm is not found",
+			machineName:   "missing",
+			state:         machinecontroller.MachinePowerStateOff,
+			expectedError: "vm not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			r := getReconciler(tc.machineName)
+			if tc.withoutDC {
+				g.Expect(getReconciler(tc.machineName).setPowerState(machinecontroller.MachinePowerStateOff)).To(Succeed())
+				datacenter := simSession.Datacenter
+				simSession.Datacenter = nil
+				defer func() { simSession.Datacenter = datacenter }()
+			}
+
+			err := r.setPowerState(tc.state)
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedError)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+
+			g.Expect(simulatorVM.Runtime.PowerState).To(Equal(tc.expectedState))
+			g.Expect(r.machine.Annotations).To(HaveKeyWithValue(machinecontroller.MachineInstanceStateAnnotationName, string(tc.expectedState)))
+			g.Expect(r.providerStatus.InstanceState).To(HaveValue(Equal(string(tc.expectedState))))
+		})
+	}
+}
+
+func TestResize(t *testing.T) {
+	g := NewWithT(t)
+	model, simSession, server := initSimulator(t)
+	defer model.Remove()
+	defer server.Close()
+
+	simulatorVM := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine)
+
+	getReconciler := func(providerSpec *machinev1.VSphereMachineProviderSpec) *Reconciler {
+		return newReconciler(&machineScope{
+			Context: context.TODO(),
+			machine: &machinev1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      simulatorVM.Name,
+					Namespace: "test",
+				},
+			},
+			providerSpec:   providerSpec,
+			session:        simSession,
+			providerStatus: &machinev1.VSphereMachineProviderStatus{},
+			client:         fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(),
+		})
+	}
+
+	unchanged := getReconciler(&machinev1.VSphereMachineProviderSpec{})
+	g.Expect(unchanged.needsResize()).To(BeFalse())
+
+	resized := getReconciler(&machinev1.VSphereMachineProviderSpec{
+		NumCPUs:           simulatorVM.Config.Hardware.NumCPU + 2,
+		NumCoresPerSocket: 1,
+		MemoryMiB:         int64(simulatorVM.Config.Hardware.MemoryMB) * 2,
+	})
+	g.Expect(resized.needsResize()).To(BeTrue())
+
+	g.Expect(resized.setPowerState(machinecontroller.MachinePowerStateOn)).To(Succeed())
+	g.Expect(resized.resize()).To(MatchError(ContainSubstring("it must be powered off to be resized")))
+
+	g.Expect(resized.setPowerState(machinecontroller.MachinePowerStateOff)).To(Succeed())
+	g.Expect(resized.resize()).To(Succeed())
+	g.Expect(simulatorVM.Config.Hardware.NumCPU).To(Equal(resized.providerSpec.NumCPUs))
+	g.Expect(simulatorVM.Config.Hardware.NumCoresPerSocket).To(Equal(resized.providerSpec.NumCoresPerSocket))
+	g.Expect(int64(simulatorVM.Config.Hardware.MemoryMB)).To(Equal(resized.providerSpec.MemoryMiB))
+	g.Expect(resized.needsResize()).To(BeFalse())
+	g.Expect(unchanged.needsResize()).To(BeFalse())
+}
+
+func TestReboot(t *testing.T) {
+	model, simSession, server := initSimulator(t)
+	defer model.Remove()
+	defer server.Close()
+
+	simulatorVM := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine)
+
+	testCases := []struct {
+		name               string
+		powerState         machinecontroller.MachinePowerState
+		toolsRunningStatus types.VirtualMachineToolsRunningStatus
+		expectedError      string
+	}{
+		{
+			name:               "reboots the guest if VMware Tools are running",
+			powerState:         machinecontroller.MachinePowerStateOn,
+			toolsRunningStatus: types.VirtualMachineToolsRunningStatusGuestToolsRunning,
+		},
+		{
+			name:               "resets the vm if VMware Tools are not running",
+			powerState:         machinecontroller.MachinePowerStateOn,
+			toolsRunningStatus: types.VirtualMachineToolsRunningStatusGuestToolsNotRunning,
+		},
+		{
+			name:               "fails if the vm is powered off",
+			powerState:         machinecontroller.MachinePowerStateOff,
+			toolsRunningStatus: types.VirtualMachineToolsRunningStatusGuestToolsNotRunning,
+			expectedError:      "it must be powered on to be rebooted",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			r := newReconciler(&machineScope{
+				Context: context.TODO(),
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      simulatorVM.Name,
+						Namespace: "test",
+					},
+				},
+				providerSpec:   &machinev1.VSphereMachineProviderSpec{},
+				session:        simSession,
+				providerStatus: &machinev1.VSphereMachineProviderStatus{},
+				client:         fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(),
+			})
+			g.Expect(r.setPowerState(tc.powerState)).To(Succeed())
+			simulatorVM.Guest.ToolsRunningStatus = string(tc.toolsRunningStatus)
+
+			err := r.reboot()
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedError)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(simulatorVM.Runtime.PowerState).To(Equal(types.VirtualMachinePowerStatePoweredOn))
+		})
+	}
+}
+
 func TestTaskIsFinished(t *testing.T) {
 	model, session, server := initSimulator(t)
 	defer model.Remove()
@@ -1120,7 +1316,7 @@ func TestReconcileNetwork(t *testing.T) {
 	defer server.Close()
 
 	managedObj := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine)
-	managedObj.Guest.Net[0].IpAddress = []string{"127.0.0.1"}
+	managedObj.Guest.Net[0].IpAddress = []string{"127.0.0.1", "fe80::250:56ff:fe8a:1", "FD00::0A"}
 	managedObjRef := object.NewVirtualMachine(session.Client.Client, managedObj.Reference()).Reference()
 
 	vm := &virtualMachine{
@@ -1139,6 +1335,10 @@ func TestReconcileNetwork(t *testing.T) {
 			Type:    corev1.NodeInternalIP,
 			Address: "127.0.0.1",
 		},
+		{
+			Type:    corev1.NodeInternalIP,
+			Address: "fd00::a",
+		},
 		{
 			Type:    corev1.NodeInternalDNS,
 			Address: vmName,
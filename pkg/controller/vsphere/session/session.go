@@ -20,7 +20,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -35,6 +39,9 @@ import (
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/soap"
 	"k8s.io/klog/v2"
+
+	"github.com/openshift/machine-api-operator/pkg/metrics"
+	"github.com/openshift/machine-api-operator/pkg/util/tracing"
 )
 
 var sessionCache = map[string]Session{}
@@ -43,6 +50,7 @@ var sessionMU sync.Mutex
 const (
 	managedObjectTypeTask = "Task"
 	clientTimeout         = 15 * time.Second
+	cloudAPIProvider      = "vsphere"
 )
 
 // Session is a vSphere session with a configured Finder.
@@ -66,9 +74,69 @@ func newClientWithTimeout(ctx context.Context, u *url.URL, insecure bool, timeou
 		return nil, err
 	}
 	client.Timeout = timeout
+	instrumentClient(client)
 	return client, nil
 }
 
+// instrumentClient records the requests of the client in the cloud API metrics and in the traces, named after their SOAP method.
+func instrumentClient(client *govmomi.Client) {
+	client.Client.RoundTripper = instrumentedRoundTripper{client.Client.RoundTripper}
+}
+
+// instrumentedRoundTripper records the SOAP requests in the cloud API metrics, and as spans of the current trace.
+type instrumentedRoundTripper struct {
+	soap.RoundTripper
+}
+
+func (rt instrumentedRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	method := soapMethod(req)
+	ctx, span := tracing.StartClient(ctx, "vsphere "+method,
+		tracing.String("cloud.provider", cloudAPIProvider),
+		tracing.String("rpc.method", method),
+	)
+	defer span.End()
+
+	err := rt.RoundTripper.RoundTrip(ctx, req, res)
+	code := responseCode(err)
+	span.SetAttributes(tracing.String("code", code))
+	span.RecordError(err)
+	metrics.ObserveCloudAPIRequest(cloudAPIProvider, method, code)
+	if code == strconv.Itoa(http.StatusTooManyRequests) {
+		metrics.ObserveCloudAPIThrottled(cloudAPIProvider, method)
+	}
+	return err
+}
+
+// responseCode returns the HTTP status code of the response to a SOAP request.
+func responseCode(err error) string {
+	if err == nil {
+		return strconv.Itoa(http.StatusOK)
+	}
+	// vCenter answers the failed requests with a fault.
+	if soap.IsSoapFault(err) || soap.IsVimFault(err) {
+		return strconv.Itoa(http.StatusInternalServerError)
+	}
+	// Other responses are returned as an url.Error whose error is the status of the response, e.g. 429 Too Many Requests.
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.Err != nil {
+		if code, _, ok := strings.Cut(urlErr.Err.Error(), " "); ok && len(code) == 3 {
+			if _, err := strconv.Atoi(code); err == nil {
+				return code
+			}
+		}
+	}
+	return metrics.CloudAPICodeError
+}
+
+// soapMethod returns the name of the SOAP method of a request body, e.g. CreateVM_Task for methods.CreateVM_TaskBody.
+func soapMethod(req soap.HasFault) string {
+	t := reflect.TypeOf(req)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return strings.TrimSuffix(t.Name(), "Body")
+}
+
 // GetOrCreate gets a cached session or creates a new one if one does not
 // already exist.
 func GetOrCreate(
@@ -21,11 +21,17 @@ import (
 
 	"context"
 	"crypto/tls"
+	"errors"
+	"net/url"
 	"testing"
 	"time"
 
+	dto "github.com/prometheus/client_model/go"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/soap"
+
+	"github.com/openshift/machine-api-operator/pkg/metrics"
 
 	_ "github.com/vmware/govmomi/vapi/simulator"
 )
@@ -311,3 +317,52 @@ func TestClientTimeout(t *testing.T) {
 		g.Expect(err.Error()).Should(ContainSubstring("context deadline exceeded"))
 	})
 }
+
+func TestCloudAPIMetrics(t *testing.T) {
+	g := NewWithT(t)
+	// The simulator logs in and creates a folder.
+	model, _, server := initSimulator(t)
+	defer model.Remove()
+	defer server.Close()
+
+	for _, operation := range []string{"Login", "CreateFolder"} {
+		metric := &dto.Metric{}
+		g.Expect(metrics.CloudAPIRequestsTotal.WithLabelValues(cloudAPIProvider, operation, "200").Write(metric)).To(Succeed())
+		g.Expect(metric.GetCounter().GetValue()).To(BeNumerically(">=", 1), "expected a request for %s", operation)
+	}
+}
+
+func TestResponseCode(t *testing.T) {
+	testCases := []struct {
+		name         string
+		err          error
+		expectedCode string
+	}{
+		{
+			name:         "without an error",
+			expectedCode: "200",
+		},
+		{
+			name:         "with a fault",
+			err:          soap.WrapSoapFault(&soap.Fault{String: "NotAuthenticated"}),
+			expectedCode: "500",
+		},
+		{
+			name:         "with a throttled request",
+			err:          &url.Error{Op: "POST", URL: "/sdk", Err: errors.New("429 Too Many Requests")},
+			expectedCode: "429",
+		},
+		{
+			name:         "with a network error",
+			err:          &url.Error{Op: "POST", URL: "/sdk", Err: errors.New("dial tcp 10.0.0.1:443: connect: connection refused")},
+			expectedCode: metrics.CloudAPICodeError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(responseCode(tc.err)).To(Equal(tc.expectedCode))
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/units"
+	"github.com/vmware/govmomi/vim25/mo"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/machine-api-operator/pkg/controller/vsphere/session"
+)
+
+// getProviderSpecSession returns a session to the vCenter of the workspace of the providerSpec.
+func getProviderSpecSession(ctx context.Context, c runtimeclient.Client, namespace string, spec *machinev1.VSphereMachineProviderSpec) (*session.Session, error) {
+	vSphereConfig, err := getVSphereConfig(c)
+	if err != nil {
+		klog.V(3).Infof("Failed to fetch vSphere config: %v", err)
+	}
+
+	user, password, err := getCredentialsSecret(c, namespace, *spec)
+	if err != nil {
+		return nil, fmt.Errorf("error getting credentials: %w", err)
+	}
+
+	server := fmt.Sprintf("%s:%s", spec.Workspace.Server, getPortFromConfig(vSphereConfig))
+	s, err := session.GetOrCreate(ctx, server, spec.Workspace.Datacenter, user, password, getInsecureFlagFromConfig(vSphereConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vSphere session: %w", err)
+	}
+	return s, nil
+}
+
+// objectLookup finds the vCenter object referenced by the value of a providerSpec field.
+type objectLookup struct {
+	fldPath *field.Path
+	value   string
+	find    func(context.Context, string) error
+}
+
+// ValidateProviderSpecObjects resolves the objects referenced by the providerSpec in vCenter: the template,
+// the folder, datastore and resource pool of the workspace, and the networks of the network devices.
+// It returns a field error for each object which does not exist, and an error when vCenter could not be queried.
+func ValidateProviderSpecObjects(ctx context.Context, c runtimeclient.Client, namespace string, spec *machinev1.VSphereMachineProviderSpec) ([]error, error) {
+	if spec.Workspace == nil {
+		return nil, nil
+	}
+
+	s, err := getProviderSpecSession(ctx, c, namespace, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	// notFound records the objects which do not exist, and returns the other lookup errors.
+	notFound := func(fldPath *field.Path, value string, err error) error {
+		var notFoundErr *find.NotFoundError
+		if err == nil {
+			return nil
+		}
+		if errors.As(err, &notFoundErr) {
+			errs = append(errs, field.NotFound(fldPath, value))
+			return nil
+		}
+		return fmt.Errorf("unable to find %s %q: %w", fldPath, value, err)
+	}
+
+	workspacePath := field.NewPath("providerSpec", "workspace")
+	lookups := []objectLookup{
+		{field.NewPath("providerSpec", "template"), spec.Template, func(ctx context.Context, name string) error {
+			_, err := s.Finder.VirtualMachine(ctx, name)
+			return err
+		}},
+		{workspacePath.Child("folder"), spec.Workspace.Folder, func(ctx context.Context, path string) error {
+			_, err := s.Finder.Folder(ctx, path)
+			return err
+		}},
+		{workspacePath.Child("datastore"), spec.Workspace.Datastore, func(ctx context.Context, path string) error {
+			_, err := s.Finder.Datastore(ctx, path)
+			return err
+		}},
+		{workspacePath.Child("resourcePool"), spec.Workspace.ResourcePool, func(ctx context.Context, path string) error {
+			_, err := s.Finder.ResourcePool(ctx, path)
+			return err
+		}},
+	}
+	for i, device := range spec.Network.Devices {
+		lookups = append(lookups, objectLookup{field.NewPath("providerSpec", "network", "devices").Index(i).Child("networkName"), device.NetworkName, func(ctx context.Context, name string) error {
+			_, err := s.Finder.Network(ctx, name)
+			return err
+		}})
+	}
+
+	for _, lookup := range lookups {
+		if lookup.value == "" {
+			continue
+		}
+		if err := notFound(lookup.fldPath, lookup.value, lookup.find(ctx, lookup.value)); err != nil {
+			return nil, err
+		}
+	}
+	return errs, nil
+}
+
+// EstimateProviderSpecCapacity estimates whether the workspace of the providerSpec has the capacity for one more
+// virtual machine: the free space of the datastore for the disk, and the memory left under the limit of the resource pool.
+// It returns a warning for each resource which may be exhausted, and an error when vCenter could not be queried.
+func EstimateProviderSpecCapacity(ctx context.Context, c runtimeclient.Client, namespace string, spec *machinev1.VSphereMachineProviderSpec) ([]string, error) {
+	if spec.Workspace == nil {
+		return nil, nil
+	}
+
+	s, err := getProviderSpecSession(ctx, c, namespace, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	if spec.Workspace.Datastore != "" && spec.DiskGiB > 0 {
+		ds, err := s.Finder.Datastore(ctx, spec.Workspace.Datastore)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find datastore %q: %w", spec.Workspace.Datastore, err)
+		}
+		var dsMo mo.Datastore
+		if err := ds.Properties(ctx, ds.Reference(), []string{"summary"}, &dsMo); err != nil {
+			return nil, fmt.Errorf("unable to get the summary of datastore %q: %w", spec.Workspace.Datastore, err)
+		}
+		required := int64(spec.DiskGiB) * 1024 * 1024 * 1024
+		if dsMo.Summary.FreeSpace < required {
+			warnings = append(warnings, fmt.Sprintf("providerSpec.workspace.datastore: datastore %s has %s free, the disk requires %s",
+				spec.Workspace.Datastore, units.ByteSize(dsMo.Summary.FreeSpace), units.ByteSize(required)))
+		}
+	}
+
+	if spec.Workspace.ResourcePool != "" && spec.MemoryMiB > 0 {
+		pool, err := s.Finder.ResourcePool(ctx, spec.Workspace.ResourcePool)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find resource pool %q: %w", spec.Workspace.ResourcePool, err)
+		}
+		var poolMo mo.ResourcePool
+		if err := pool.Properties(ctx, pool.Reference(), []string{"runtime"}, &poolMo); err != nil {
+			return nil, fmt.Errorf("unable to get the runtime of resource pool %q: %w", spec.Workspace.ResourcePool, err)
+		}
+		// The memory usage is reported in bytes, no limit is reported when the maximum usage is not set.
+		memory := poolMo.Runtime.Memory
+		required := spec.MemoryMiB * 1024 * 1024
+		if memory.MaxUsage > 0 && memory.MaxUsage-memory.OverallUsage < required {
+			warnings = append(warnings, fmt.Sprintf("providerSpec.workspace.resourcePool: resource pool %s has %s of memory left, the machine requires %s",
+				spec.Workspace.ResourcePool, units.ByteSize(memory.MaxUsage-memory.OverallUsage), units.ByteSize(required)))
+		}
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,226 @@
+package vsphere
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/vmware/govmomi/simulator"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/scheme"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const validationTestNamespace = "test"
+
+// newValidationTestEnv starts a vCenter simulator and returns a client with its credentials and configuration,
+// and a providerSpec referencing its objects.
+func newValidationTestEnv(t *testing.T) (runtimeclient.Client, func() *machinev1.VSphereMachineProviderSpec) {
+	model, _, server := initSimulator(t)
+	t.Cleanup(func() {
+		server.Close()
+		model.Remove()
+	})
+	host, port, err := net.SplitHostPort(server.URL.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	password, _ := server.URL.User.Password()
+	vm := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine)
+
+	credentialsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: validationTestNamespace,
+		},
+		Data: map[string][]byte{
+			fmt.Sprintf("%s.username", host): []byte(server.URL.User.Username()),
+			fmt.Sprintf("%s.password", host): []byte(password),
+		},
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "testName",
+			Namespace: openshiftConfigNamespace,
+		},
+		Data: map[string]string{
+			"testKey": fmt.Sprintf(testConfigFmt, port),
+		},
+	}
+
+	infra := &configv1.Infrastructure{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: globalInfrastuctureName,
+		},
+		Spec: configv1.InfrastructureSpec{
+			CloudConfig: configv1.ConfigMapFileReference{
+				Name: "testName",
+				Key:  "testKey",
+			},
+		},
+	}
+
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(credentialsSecret, configMap, infra).Build()
+
+	return client, func() *machinev1.VSphereMachineProviderSpec {
+		return &machinev1.VSphereMachineProviderSpec{
+			Template: vm.Name,
+			Workspace: &machinev1.Workspace{
+				Server:       host,
+				Datacenter:   "DC0",
+				Folder:       "/DC0/vm",
+				Datastore:    "LocalDS_0",
+				ResourcePool: "/DC0/host/DC0_C0/Resources",
+			},
+			CredentialsSecret: &corev1.LocalObjectReference{
+				Name: "test",
+			},
+			Network: machinev1.NetworkSpec{
+				Devices: []machinev1.NetworkDeviceSpec{{NetworkName: "VM Network"}},
+			},
+		}
+	}
+}
+
+func TestValidateProviderSpecObjects(t *testing.T) {
+	client, newSpec := newValidationTestEnv(t)
+
+	testCases := []struct {
+		name         string
+		modify       func(*machinev1.VSphereMachineProviderSpec)
+		expectedErrs []error
+		expectError  bool
+	}{
+		{
+			name: "with all objects found",
+		},
+		{
+			name: "with a missing template",
+			modify: func(spec *machinev1.VSphereMachineProviderSpec) {
+				spec.Template = "missing"
+			},
+			expectedErrs: []error{field.NotFound(field.NewPath("providerSpec", "template"), "missing")},
+		},
+		{
+			name: "with a missing datastore and network",
+			modify: func(spec *machinev1.VSphereMachineProviderSpec) {
+				spec.Workspace.Datastore = "missing"
+				spec.Network.Devices[0].NetworkName = "missing"
+			},
+			expectedErrs: []error{
+				field.NotFound(field.NewPath("providerSpec", "workspace", "datastore"), "missing"),
+				field.NotFound(field.NewPath("providerSpec", "network", "devices").Index(0).Child("networkName"), "missing"),
+			},
+		},
+		{
+			name: "with a missing resource pool",
+			modify: func(spec *machinev1.VSphereMachineProviderSpec) {
+				spec.Workspace.ResourcePool = "/DC0/host/DC0_C0/Resources/missing"
+			},
+			expectedErrs: []error{field.NotFound(field.NewPath("providerSpec", "workspace", "resourcePool"), "/DC0/host/DC0_C0/Resources/missing")},
+		},
+		{
+			name: "with missing credentials",
+			modify: func(spec *machinev1.VSphereMachineProviderSpec) {
+				spec.CredentialsSecret.Name = "missing"
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			spec := newSpec()
+			if tc.modify != nil {
+				tc.modify(spec)
+			}
+
+			errs, err := ValidateProviderSpecObjects(context.Background(), client, validationTestNamespace, spec)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(errs).To(Equal(tc.expectedErrs))
+		})
+	}
+}
+
+func TestEstimateProviderSpecCapacity(t *testing.T) {
+	client, newSpec := newValidationTestEnv(t)
+
+	// Limit the memory of the resource pools of the simulator to 8GiB, with 2GiB in use.
+	for _, obj := range simulator.Map.All("ResourcePool") {
+		pool := obj.(*simulator.ResourcePool)
+		pool.Runtime.Memory.MaxUsage = 8 * 1024 * 1024 * 1024
+		pool.Runtime.Memory.OverallUsage = 2 * 1024 * 1024 * 1024
+	}
+
+	testCases := []struct {
+		name             string
+		modify           func(*machinev1.VSphereMachineProviderSpec)
+		expectedWarnings []string
+		expectError      bool
+	}{
+		{
+			name: "with enough capacity",
+			modify: func(spec *machinev1.VSphereMachineProviderSpec) {
+				spec.DiskGiB = 1
+				spec.MemoryMiB = 4096
+			},
+		},
+		{
+			name: "with a disk larger than the free space of the datastore",
+			modify: func(spec *machinev1.VSphereMachineProviderSpec) {
+				spec.DiskGiB = 1 << 30
+			},
+			expectedWarnings: []string{"providerSpec.workspace.datastore: datastore LocalDS_0 has"},
+		},
+		{
+			name: "with more memory than left in the resource pool",
+			modify: func(spec *machinev1.VSphereMachineProviderSpec) {
+				spec.MemoryMiB = 8192
+			},
+			expectedWarnings: []string{"providerSpec.workspace.resourcePool: resource pool /DC0/host/DC0_C0/Resources has 6.0GB of memory left, the machine requires 8.0GB"},
+		},
+		{
+			name: "with a missing datastore",
+			modify: func(spec *machinev1.VSphereMachineProviderSpec) {
+				spec.DiskGiB = 1
+				spec.Workspace.Datastore = "missing"
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			spec := newSpec()
+			tc.modify(spec)
+
+			warnings, err := EstimateProviderSpecCapacity(context.Background(), client, validationTestNamespace, spec)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(warnings).To(HaveLen(len(tc.expectedWarnings)))
+			for i, warning := range tc.expectedWarnings {
+				g.Expect(warnings[i]).To(HavePrefix(warning))
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2026 The Machine API Operator authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CloudAPICodeError is the code of the requests which failed without a response, e.g. on a network error.
+const CloudAPICodeError = "error"
+
+// Metrics for use in the Machine controllers, to correlate slow provisioning with the rate limiting of the cloud provider
+var (
+	// CloudAPIRequestsTotal is a metric to count the requests made to the cloud provider API
+	CloudAPIRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mapi_cloud_api_requests_total",
+			Help: "Number of requests made to the cloud provider API, by operation and response code.",
+		}, []string{"provider", "operation", "code"},
+	)
+
+	// CloudAPIThrottledTotal is a metric to count the requests rate limited by the cloud provider API
+	CloudAPIThrottledTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mapi_cloud_api_throttled_total",
+			Help: "Number of requests rate limited by the cloud provider API, by operation.",
+		}, []string{"provider", "operation"},
+	)
+)
+
+// ObserveCloudAPIRequest increments the count of requests made to the cloud provider API
+func ObserveCloudAPIRequest(provider, operation, code string) {
+	CloudAPIRequestsTotal.With(prometheus.Labels{
+		"provider":  provider,
+		"operation": operation,
+		"code":      code,
+	}).Inc()
+}
+
+// ObserveCloudAPIThrottled increments the count of requests rate limited by the cloud provider API
+func ObserveCloudAPIThrottled(provider, operation string) {
+	CloudAPIThrottledTotal.With(prometheus.Labels{
+		"provider":  provider,
+		"operation": operation,
+	}).Inc()
+}
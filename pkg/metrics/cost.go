@@ -0,0 +1,169 @@
+/*
+Copyright 2026 The Machine API Operator authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machineinformers "github.com/openshift/client-go/machine/informers/externalversions/machine/v1beta1"
+	machinelisters "github.com/openshift/client-go/machine/listers/machine/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// PriceTableConfigMapName is the name of the ConfigMap holding the hourly price of the instance types,
+	// used to estimate the cost of the MachineSets.
+	PriceTableConfigMapName = "machine-api-price-table"
+
+	// priceTableKey holds the hourly price of the instance types as YAML or JSON, keyed by instance type.
+	priceTableKey = "prices"
+)
+
+var (
+	// MachineSetEstimatedHourlyCostDesc is the estimated hourly cost of the running replicas of a MachineSet.
+	MachineSetEstimatedHourlyCostDesc = prometheus.NewDesc("mapi_machineset_estimated_hourly_cost", "Estimated hourly cost of the replicas of the mapi managed Machineset, from the price of its instance type", []string{"machineset", "namespace", "instance_type"}, nil)
+
+	// instanceTypePaths are the paths of the instance type in the providerSpecs, keyed by kind.
+	instanceTypePaths = map[string][]string{
+		"AWSMachineProviderConfig":          {"instanceType"},
+		"AlibabaCloudMachineProviderConfig": {"instanceType"},
+		"AzureMachineProviderSpec":          {"vmSize"},
+		"GCPMachineProviderSpec":            {"machineType"},
+		"IBMCloudMachineProviderSpec":       {"profile"},
+		"OpenstackProviderSpec":             {"flavor"},
+	}
+)
+
+// MachineSetCostCollector is implementing prometheus.Collector interface.
+// It estimates the cost of the MachineSets from the price table ConfigMap, and collects nothing without it.
+type MachineSetCostCollector struct {
+	machineSetLister    machinelisters.MachineSetLister
+	configMapLister     corelisters.ConfigMapLister
+	namespace           string
+	priceTableNamespace string
+}
+
+func NewMachineSetCostCollector(machinesetInformer machineinformers.MachineSetInformer, configMapInformer coreinformers.ConfigMapInformer, namespace, priceTableNamespace string) *MachineSetCostCollector {
+	return &MachineSetCostCollector{
+		machineSetLister:    machinesetInformer.Lister(),
+		configMapLister:     configMapInformer.Lister(),
+		namespace:           namespace,
+		priceTableNamespace: priceTableNamespace,
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (cc *MachineSetCostCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- MachineSetEstimatedHourlyCostDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (cc *MachineSetCostCollector) Collect(ch chan<- prometheus.Metric) {
+	prices, err := cc.getPriceTable()
+	if err != nil {
+		klog.Errorf("Error estimating the cost of the MachineSets: %v", err)
+		MachineCollectorUp.With(prometheus.Labels{"kind": "mapi_machineset_estimated_hourly_cost"}).Set(float64(0))
+		return
+	}
+	if prices == nil {
+		return
+	}
+
+	machineSetList, err := cc.machineSetLister.MachineSets(cc.namespace).List(labels.Everything())
+	if err != nil {
+		MachineCollectorUp.With(prometheus.Labels{"kind": "mapi_machineset_estimated_hourly_cost"}).Set(float64(0))
+		return
+	}
+	MachineCollectorUp.With(prometheus.Labels{"kind": "mapi_machineset_estimated_hourly_cost"}).Set(float64(1))
+
+	for _, machineSet := range machineSetList {
+		instanceType, err := getInstanceType(machineSet)
+		if err != nil {
+			klog.V(4).Infof("Skipping the cost of MachineSet %s: %v", machineSet.Name, err)
+			continue
+		}
+		price, ok := prices[instanceType]
+		if !ok {
+			klog.V(4).Infof("Skipping the cost of MachineSet %s: no price for instance type %q", machineSet.Name, instanceType)
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			MachineSetEstimatedHourlyCostDesc,
+			prometheus.GaugeValue,
+			price*float64(machineSet.Status.Replicas),
+			machineSet.Name, machineSet.Namespace, instanceType,
+		)
+	}
+}
+
+// getPriceTable returns the hourly price of the instance types set in the price table ConfigMap, if any.
+func (cc *MachineSetCostCollector) getPriceTable() (map[string]float64, error) {
+	configMap, err := cc.configMapLister.ConfigMaps(cc.priceTableNamespace).Get(PriceTableConfigMapName)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not fetch price table configmap: %v", err)
+	}
+
+	value, ok := configMap.Data[priceTableKey]
+	if !ok {
+		return nil, nil
+	}
+	prices := map[string]float64{}
+	if err := yaml.UnmarshalStrict([]byte(value), &prices); err != nil {
+		return nil, fmt.Errorf("configmap %s: invalid %s: %v", PriceTableConfigMapName, priceTableKey, err)
+	}
+	for instanceType, price := range prices {
+		if price < 0 {
+			return nil, fmt.Errorf("configmap %s: invalid price %v for instance type %s, must not be negative", PriceTableConfigMapName, price, instanceType)
+		}
+	}
+	return prices, nil
+}
+
+// getInstanceType returns the instance type of the Machines of a MachineSet.
+func getInstanceType(machineSet *machinev1.MachineSet) (string, error) {
+	providerSpec := machineSet.Spec.Template.Spec.ProviderSpec.Value
+	if providerSpec == nil || len(providerSpec.Raw) == 0 {
+		return "", fmt.Errorf("providerSpec is empty")
+	}
+
+	spec := map[string]interface{}{}
+	if err := json.Unmarshal(providerSpec.Raw, &spec); err != nil {
+		return "", fmt.Errorf("error unmarshalling providerSpec: %v", err)
+	}
+	kind, _, _ := unstructured.NestedString(spec, "kind")
+	path, ok := instanceTypePaths[kind]
+	if !ok {
+		return "", fmt.Errorf("providerSpec kind %q has no instance type", kind)
+	}
+	instanceType, _, _ := unstructured.NestedString(spec, path...)
+	if instanceType == "" {
+		return "", fmt.Errorf("providerSpec has no instance type")
+	}
+	return instanceType, nil
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	fakemachine "github.com/openshift/client-go/machine/clientset/versioned/fake"
+	machineinformersfactory "github.com/openshift/client-go/machine/informers/externalversions"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+)
+
+const testNamespace = "openshift-machine-api"
+
+func newCostTestMachineSet(name, providerSpec string, replicas int32) *machinev1.MachineSet {
+	return &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		Spec: machinev1.MachineSetSpec{
+			Template: machinev1.MachineTemplateSpec{
+				Spec: machinev1.MachineSpec{ProviderSpec: machinev1.ProviderSpec{Value: &runtime.RawExtension{Raw: []byte(providerSpec)}}},
+			},
+		},
+		Status: machinev1.MachineSetStatus{Replicas: replicas},
+	}
+}
+
+func TestMachineSetCostCollector(t *testing.T) {
+	machineSets := []runtime.Object{
+		newCostTestMachineSet("aws", `{"kind":"AWSMachineProviderConfig","instanceType":"m6i.xlarge"}`, 3),
+		newCostTestMachineSet("azure", `{"kind":"AzureMachineProviderSpec","vmSize":"Standard_D4s_v3"}`, 2),
+		newCostTestMachineSet("unpriced", `{"kind":"GCPMachineProviderSpec","machineType":"n2-standard-4"}`, 1),
+		newCostTestMachineSet("vsphere", `{"kind":"VSphereMachineProviderSpec","numCPUs":4}`, 1),
+	}
+
+	testCases := []struct {
+		name          string
+		data          map[string]string
+		expectedCosts map[string]float64
+	}{
+		{
+			name: "without a price table",
+		},
+		{
+			name: "with a price table",
+			data: map[string]string{priceTableKey: `
+m6i.xlarge: 0.192
+Standard_D4s_v3: 0.2
+`},
+			expectedCosts: map[string]float64{"aws": 0.576, "azure": 0.4},
+		},
+		{
+			name: "with an invalid price table",
+			data: map[string]string{priceTableKey: `m6i.xlarge: -1`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var kubeObjects []runtime.Object
+			if tc.data != nil {
+				kubeObjects = append(kubeObjects, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: PriceTableConfigMapName, Namespace: testNamespace},
+					Data:       tc.data,
+				})
+			}
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			kubeInformers := informers.NewSharedInformerFactory(fakekube.NewSimpleClientset(kubeObjects...), time.Minute)
+			machineInformers := machineinformersfactory.NewSharedInformerFactory(fakemachine.NewSimpleClientset(machineSets...), time.Minute)
+			collector := NewMachineSetCostCollector(
+				machineInformers.Machine().V1beta1().MachineSets(),
+				kubeInformers.Core().V1().ConfigMaps(),
+				testNamespace, testNamespace)
+			kubeInformers.Start(stopCh)
+			machineInformers.Start(stopCh)
+			kubeInformers.WaitForCacheSync(stopCh)
+			machineInformers.WaitForCacheSync(stopCh)
+
+			ch := make(chan prometheus.Metric, len(machineSets))
+			collector.Collect(ch)
+			close(ch)
+
+			costs := map[string]float64{}
+			for metric := range ch {
+				m := &dto.Metric{}
+				g.Expect(metric.Write(m)).To(Succeed())
+				for _, label := range m.GetLabel() {
+					if label.GetName() == "machineset" {
+						costs[label.GetValue()] = m.GetGauge().GetValue()
+					}
+				}
+			}
+			if tc.expectedCosts == nil {
+				g.Expect(costs).To(BeEmpty())
+				return
+			}
+			g.Expect(costs).To(HaveLen(len(tc.expectedCosts)))
+			for name, cost := range tc.expectedCosts {
+				g.Expect(costs).To(HaveKeyWithValue(name, BeNumerically("~", cost, 1e-9)))
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2026 The Machine API Operator authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// deletionBlockedCondition is the condition set by the machine controller on the Machines whose deletion is blocked.
+const deletionBlockedCondition machinev1.ConditionType = "DeletionBlocked"
+
+// MachineDeletionBlockedDesc is the number of deleting Machines with a DeletionBlocked condition, by reason.
+var MachineDeletionBlockedDesc = prometheus.NewDesc("mapi_machine_deletion_blocked", "Number of Machines whose deletion is blocked, by what blocks it", []string{"reason"}, nil)
+
+// DeletionBlockedCollector is implementing prometheus.Collector interface.
+// It counts the deleting Machines by the reason of their DeletionBlocked condition.
+type DeletionBlockedCollector struct {
+	client client.Reader
+}
+
+func NewDeletionBlockedCollector(client client.Reader) *DeletionBlockedCollector {
+	return &DeletionBlockedCollector{client: client}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (dc *DeletionBlockedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- MachineDeletionBlockedDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (dc *DeletionBlockedCollector) Collect(ch chan<- prometheus.Metric) {
+	machineList := &machinev1.MachineList{}
+	if err := dc.client.List(context.Background(), machineList); err != nil {
+		klog.Errorf("Error listing Machines to count the blocked deletions: %v", err)
+		MachineCollectorUp.With(prometheus.Labels{"kind": "mapi_machine_deletion_blocked"}).Set(float64(0))
+		return
+	}
+	MachineCollectorUp.With(prometheus.Labels{"kind": "mapi_machine_deletion_blocked"}).Set(float64(1))
+
+	blocked := map[string]int{}
+	for _, machine := range machineList.Items {
+		if machine.DeletionTimestamp == nil {
+			continue
+		}
+		for _, condition := range machine.Status.Conditions {
+			if condition.Type == deletionBlockedCondition && condition.Status == corev1.ConditionTrue {
+				blocked[condition.Reason]++
+			}
+		}
+	}
+	for reason, count := range blocked {
+		ch <- prometheus.MustNewConstMetric(MachineDeletionBlockedDesc, prometheus.GaugeValue, float64(count), reason)
+	}
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeletionBlockedCollector(t *testing.T) {
+	g := NewWithT(t)
+
+	deleted := metav1.NewTime(time.Now().Add(-time.Hour))
+	newMachine := func(name string, deleting bool, conditions ...machinev1.Condition) client.Object {
+		machine := &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openshift-machine-api"},
+			Status:     machinev1.MachineStatus{Conditions: conditions},
+		}
+		if deleting {
+			machine.DeletionTimestamp = &deleted
+			machine.Finalizers = []string{machinev1.MachineFinalizer}
+		}
+		return machine
+	}
+	blocked := func(reason string) machinev1.Condition {
+		return machinev1.Condition{Type: deletionBlockedCondition, Status: corev1.ConditionTrue, Reason: reason}
+	}
+
+	scheme := runtime.NewScheme()
+	g.Expect(machinev1.AddToScheme(scheme)).To(Succeed())
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newMachine("running", false),
+		newMachine("deleting", true),
+		newMachine("drain-1", true, blocked("Drain")),
+		newMachine("drain-2", true, blocked("Drain")),
+		newMachine("provider-error", true, blocked("ProviderError")),
+	).Build()
+
+	ch := make(chan prometheus.Metric, 10)
+	NewDeletionBlockedCollector(fakeClient).Collect(ch)
+	close(ch)
+
+	counts := map[string]float64{}
+	for metric := range ch {
+		m := &dto.Metric{}
+		g.Expect(metric.Write(m)).To(Succeed())
+		g.Expect(m.GetLabel()).To(HaveLen(1))
+		counts[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+	}
+	g.Expect(counts).To(Equal(map[string]float64{
+		"Drain":         2,
+		"ProviderError": 1,
+	}))
+}
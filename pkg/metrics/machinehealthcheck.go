@@ -49,6 +49,30 @@ var (
 			Help: "Short circuit status for MachineHealthCheck (0=no, 1=yes)",
 		}, []string{"name", "namespace"},
 	)
+
+	// MachineHealthCheckRemediationsTotal is a Prometheus metric, which reports the number of remediations by MachineHealthChecks, by the reason the machines were unhealthy
+	MachineHealthCheckRemediationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mapi_mhc_remediations_total",
+			Help: "Number of remediations requested by MachineHealthChecks, by the reason the machines were unhealthy",
+		}, []string{"reason", "mhc", "namespace"},
+	)
+
+	// MachineHealthCheckUnhealthyMachines is a Prometheus metric, which reports the number of machines currently unhealthy for MachineHealthChecks
+	MachineHealthCheckUnhealthyMachines = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mapi_mhc_unhealthy_machines",
+			Help: "Number of not started or unhealthy machines covered by MachineHealthChecks",
+		}, []string{"mhc", "namespace"},
+	)
+
+	// MachineHealthCheckShortCircuited is a Prometheus metric, which reports when the named MachineHealthCheck is currently short-circuited (0=no, 1=yes)
+	MachineHealthCheckShortCircuited = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mapi_mhc_short_circuited",
+			Help: "Whether remediation by the MachineHealthCheck is short-circuited by maxUnhealthy (0=no, 1=yes)",
+		}, []string{"mhc", "namespace"},
+	)
 )
 
 func InitializeMachineHealthCheckMetrics() {
@@ -56,6 +80,9 @@ func InitializeMachineHealthCheckMetrics() {
 		MachineHealthCheckNodesCovered,
 		MachineHealthCheckRemediationSuccessTotal,
 		MachineHealthCheckShortCircuit,
+		MachineHealthCheckRemediationsTotal,
+		MachineHealthCheckUnhealthyMachines,
+		MachineHealthCheckShortCircuited,
 	)
 }
 
@@ -66,6 +93,32 @@ func DeleteMachineHealthCheckNodesCovered(name string, namespace string) {
 	})
 }
 
+func DeleteMachineHealthCheckUnhealthyMachines(name string, namespace string) {
+	MachineHealthCheckUnhealthyMachines.Delete(prometheus.Labels{
+		"mhc":       name,
+		"namespace": namespace,
+	})
+	MachineHealthCheckShortCircuited.Delete(prometheus.Labels{
+		"mhc":       name,
+		"namespace": namespace,
+	})
+}
+
+func ObserveMachineHealthCheckUnhealthyMachines(name string, namespace string, count int) {
+	MachineHealthCheckUnhealthyMachines.With(prometheus.Labels{
+		"mhc":       name,
+		"namespace": namespace,
+	}).Set(float64(count))
+}
+
+func ObserveMachineHealthCheckRemediation(name string, namespace string, reason string) {
+	MachineHealthCheckRemediationsTotal.With(prometheus.Labels{
+		"reason":    reason,
+		"mhc":       name,
+		"namespace": namespace,
+	}).Inc()
+}
+
 func ObserveMachineHealthCheckNodesCovered(name string, namespace string, count int) {
 	MachineHealthCheckNodesCovered.With(prometheus.Labels{
 		"name":      name,
@@ -85,6 +138,10 @@ func ObserveMachineHealthCheckShortCircuitDisabled(name string, namespace string
 		"name":      name,
 		"namespace": namespace,
 	}).Set(0)
+	MachineHealthCheckShortCircuited.With(prometheus.Labels{
+		"mhc":       name,
+		"namespace": namespace,
+	}).Set(0)
 }
 
 func ObserveMachineHealthCheckShortCircuitEnabled(name string, namespace string) {
@@ -92,4 +149,8 @@ func ObserveMachineHealthCheckShortCircuitEnabled(name string, namespace string)
 		"name":      name,
 		"namespace": namespace,
 	}).Set(1)
+	MachineHealthCheckShortCircuited.With(prometheus.Labels{
+		"mhc":       name,
+		"namespace": namespace,
+	}).Set(1)
 }
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMachineHealthCheckRemediationMetrics(t *testing.T) {
+	g := NewWithT(t)
+
+	value := func(metric prometheus.Metric) float64 {
+		m := &dto.Metric{}
+		g.Expect(metric.Write(m)).To(Succeed())
+		if m.GetCounter() != nil {
+			return m.GetCounter().GetValue()
+		}
+		return m.GetGauge().GetValue()
+	}
+	series := func(vec prometheus.Collector) int {
+		ch := make(chan prometheus.Metric, 10)
+		vec.Collect(ch)
+		close(ch)
+		return len(ch)
+	}
+
+	ObserveMachineHealthCheckRemediation("mhc", "namespace", "NodeStartupTimeout")
+	ObserveMachineHealthCheckRemediation("mhc", "namespace", "NodeStartupTimeout")
+	ObserveMachineHealthCheckRemediation("mhc", "namespace", "MachineFailed")
+	g.Expect(value(MachineHealthCheckRemediationsTotal.WithLabelValues("NodeStartupTimeout", "mhc", "namespace"))).To(Equal(2.0))
+	g.Expect(value(MachineHealthCheckRemediationsTotal.WithLabelValues("MachineFailed", "mhc", "namespace"))).To(Equal(1.0))
+
+	ObserveMachineHealthCheckUnhealthyMachines("mhc", "namespace", 3)
+	g.Expect(value(MachineHealthCheckUnhealthyMachines.WithLabelValues("mhc", "namespace"))).To(Equal(3.0))
+
+	ObserveMachineHealthCheckShortCircuitEnabled("mhc", "namespace")
+	g.Expect(value(MachineHealthCheckShortCircuited.WithLabelValues("mhc", "namespace"))).To(Equal(1.0))
+	g.Expect(value(MachineHealthCheckShortCircuit.WithLabelValues("mhc", "namespace"))).To(Equal(1.0))
+	ObserveMachineHealthCheckShortCircuitDisabled("mhc", "namespace")
+	g.Expect(value(MachineHealthCheckShortCircuited.WithLabelValues("mhc", "namespace"))).To(Equal(0.0))
+	g.Expect(value(MachineHealthCheckShortCircuit.WithLabelValues("mhc", "namespace"))).To(Equal(0.0))
+
+	// The gauges of a deleted MachineHealthCheck are removed, its remediations are kept.
+	DeleteMachineHealthCheckUnhealthyMachines("mhc", "namespace")
+	g.Expect(series(MachineHealthCheckUnhealthyMachines)).To(Equal(0))
+	g.Expect(series(MachineHealthCheckShortCircuited)).To(Equal(0))
+	g.Expect(series(MachineHealthCheckRemediationsTotal)).To(Equal(2))
+}
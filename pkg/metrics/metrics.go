@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"time"
+
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	machineinformers "github.com/openshift/client-go/machine/informers/externalversions/machine/v1beta1"
 	machinelisters "github.com/openshift/client-go/machine/listers/machine/v1beta1"
@@ -66,24 +68,46 @@ var (
 
 // Metrics for use in the Machine controller
 var (
-	// MachinePhaseTransitionSeconds is a metric to capute the time between a Machine being created and entering a particular phase
+	// MachinePhaseTransitionSeconds is a metric to capture the time a Machine spent in a phase before its transition to the next one,
+	// or between its creation and its first phase
 	MachinePhaseTransitionSeconds = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "mapi_machine_phase_transition_seconds",
-			Help:    "Number of seconds between Machine creation and Machine transition to a phase.",
-			Buckets: []float64{5, 10, 20, 30, 60, 90, 120, 180, 240, 300, 360, 480, 600},
-		}, []string{"phase"},
+			Help:    "Number of seconds between a Machine entering a phase, or its creation, and its transition to the next phase.",
+			Buckets: []float64{5, 10, 20, 30, 60, 90, 120, 180, 240, 300, 360, 480, 600, 900, 1200, 1800},
+		}, []string{"from", "to"},
+	)
+
+	// MachineLifecycleHookExpiredTotal is a metric to count the lifecycle hooks removed by the Machine controller after their timeout
+	MachineLifecycleHookExpiredTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mapi_machine_lifecycle_hook_expired_total",
+			Help: "Number of lifecycle hooks removed from deleting Machines after their timeout expired.",
+		}, []string{"namespace", "hook", "owner", "stage"},
 	)
 )
 
 func init() {
 	prometheus.MustRegister(MachineCollectorUp)
 	metrics.Registry.MustRegister(MachinePhaseTransitionSeconds)
+	metrics.Registry.MustRegister(MachineLifecycleHookExpiredTotal)
 	metrics.Registry.MustRegister(
 		failedInstanceCreateCount,
 		failedInstanceUpdateCount,
 		failedInstanceDeleteCount,
 	)
+	metrics.Registry.MustRegister(
+		CloudAPIRequestsTotal,
+		CloudAPIThrottledTotal,
+	)
+	metrics.Registry.MustRegister(
+		OrphanedProviderResources,
+		OrphanedProviderResourcesDeletedTotal,
+	)
+	metrics.Registry.MustRegister(
+		WebhookAdmissionsTotal,
+		WebhookAdmissionDurationSeconds,
+	)
 }
 
 // MachineCollector is implementing prometheus.Collector interface.
@@ -226,3 +250,21 @@ func RegisterFailedInstanceDelete(labels *MachineLabels) {
 		"reason":    labels.Reason,
 	}).Inc()
 }
+
+// ObserveMachinePhaseTransition records the time a Machine spent in a phase before its transition to the next one
+func ObserveMachinePhaseTransition(from, to string, duration time.Duration) {
+	MachinePhaseTransitionSeconds.With(prometheus.Labels{
+		"from": from,
+		"to":   to,
+	}).Observe(duration.Seconds())
+}
+
+// ObserveMachineLifecycleHookExpired increments the count of expired lifecycle hooks
+func ObserveMachineLifecycleHookExpired(namespace, hook, owner, stage string) {
+	MachineLifecycleHookExpiredTotal.With(prometheus.Labels{
+		"namespace": namespace,
+		"hook":      hook,
+		"owner":     owner,
+		"stage":     stage,
+	}).Inc()
+}
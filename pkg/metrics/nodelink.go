@@ -0,0 +1,75 @@
+/*
+Copyright 2026 The Machine API Operator authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	DefaultNodeLinkMetricsAddress = ":8084"
+)
+
+var (
+	// NodeLinkUnmatchedMachines is a Prometheus metric, which reports the number of provisioned machines without a matching node
+	NodeLinkUnmatchedMachines = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "mapi_nodelink_unmatched_machines",
+			Help: "Number of provisioned machines for which no matching node was found",
+		},
+	)
+
+	// NodeLinkUnmatchedNodes is a Prometheus metric, which reports the number of nodes without a matching machine
+	NodeLinkUnmatchedNodes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "mapi_nodelink_unmatched_nodes",
+			Help: "Number of nodes for which no matching machine was found",
+		},
+	)
+
+	// NodeLinkLinkDurationSeconds is a Prometheus metric, which reports the time between a machine being created and being linked to its node
+	NodeLinkLinkDurationSeconds = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "mapi_nodelink_link_duration_seconds",
+			Help:    "Number of seconds between Machine creation and the Machine being linked to its Node.",
+			Buckets: []float64{30, 60, 90, 120, 180, 240, 300, 360, 480, 600, 900, 1200, 1800},
+		},
+	)
+)
+
+func InitializeNodeLinkMetrics() {
+	metrics.Registry.MustRegister(
+		NodeLinkUnmatchedMachines,
+		NodeLinkUnmatchedNodes,
+		NodeLinkLinkDurationSeconds,
+	)
+}
+
+func ObserveNodeLinkUnmatchedMachines(count int) {
+	NodeLinkUnmatchedMachines.Set(float64(count))
+}
+
+func ObserveNodeLinkUnmatchedNodes(count int) {
+	NodeLinkUnmatchedNodes.Set(float64(count))
+}
+
+func ObserveNodeLinkLinkDuration(duration time.Duration) {
+	NodeLinkLinkDurationSeconds.Observe(duration.Seconds())
+}
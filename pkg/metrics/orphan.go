@@ -0,0 +1,40 @@
+/*
+Copyright 2026 The Machine API Operator authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics for use in the Machine controllers, to find the provider resources leaked outside of the Machines
+var (
+	// OrphanedProviderResources is a metric to report the provider resources of the cluster without a Machine
+	OrphanedProviderResources = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mapi_orphaned_provider_resources",
+			Help: "Number of provider resources tagged with the cluster ID without a Machine for longer than the orphan TTL, by kind.",
+		}, []string{"kind"},
+	)
+
+	// OrphanedProviderResourcesDeletedTotal is a metric to count the orphaned provider resources deleted by the Machine controller
+	OrphanedProviderResourcesDeletedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mapi_orphaned_provider_resources_deleted_total",
+			Help: "Number of orphaned provider resources deleted by the Machine controller, by kind.",
+		}, []string{"kind"},
+	)
+)
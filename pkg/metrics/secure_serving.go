@@ -0,0 +1,322 @@
+/*
+Copyright 2026 The Machine API Operator authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// DefaultMetricsAuthorizationNamespace is the namespace whose metrics subresource the clients must be allowed to get.
+	DefaultMetricsAuthorizationNamespace = "openshift-machine-api"
+
+	// disabledBindAddress disables the metrics server of the controller-runtime managers.
+	disabledBindAddress = "0"
+
+	secureServingShutdownTimeout = 10 * time.Second
+
+	// The results of the TokenReviews and SubjectAccessReviews are cached for the scrapes not to create reviews
+	// every few seconds, with the TTLs of the webhook authenticator and authorizer of the apiserver.
+	authCacheSize    = 1024
+	authenticatedTTL = 2 * time.Minute
+	authorizedTTL    = 5 * time.Minute
+	unauthorizedTTL  = 30 * time.Second
+)
+
+// SecureServingOptions configures the metrics servers to serve over TLS, and to only serve the clients
+// authenticated and authorized by the apiserver, as the kube-rbac-proxy sidecars do.
+type SecureServingOptions struct {
+	// CertDir is the directory of the tls.crt and tls.key files of the server. Metrics are served in plaintext when empty.
+	CertDir string
+	// ClientCAFile is the CA bundle verifying the client certificates. Only bearer tokens are accepted when empty.
+	ClientCAFile string
+	// AuthorizationNamespace is the namespace whose metrics subresource the clients must be allowed to get.
+	AuthorizationNamespace string
+}
+
+// AddFlags adds the flags of the secure serving options to the flag set.
+func (o *SecureServingOptions) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.CertDir, "metrics-cert-dir", "", "Directory of the tls.crt and tls.key files used to serve the metrics over TLS, to the clients authenticated and authorized by the apiserver. Metrics are served in plaintext when unset.")
+	fs.StringVar(&o.ClientCAFile, "metrics-client-ca-file", "", "CA bundle verifying the client certificates of the metrics clients. Only bearer tokens are authenticated when unset. Requires --metrics-cert-dir.")
+	fs.StringVar(&o.AuthorizationNamespace, "metrics-authorization-namespace", DefaultMetricsAuthorizationNamespace, "Namespace whose metrics subresource the metrics clients must be allowed to get. Requires --metrics-cert-dir.")
+}
+
+// Enabled returns true if the metrics are served over TLS.
+func (o *SecureServingOptions) Enabled() bool {
+	return o.CertDir != ""
+}
+
+// ManagerBindAddress returns the metrics bind address of a controller-runtime manager, which is disabled
+// when the metrics are served over TLS, as they are then served by the runnable of AddToManager.
+func (o *SecureServingOptions) ManagerBindAddress(address string) string {
+	if o.Enabled() {
+		return disabledBindAddress
+	}
+	return address
+}
+
+// AddToManager adds a server of the controller-runtime metrics over TLS on the address to the manager,
+// when the metrics are served over TLS.
+func (o *SecureServingOptions) AddToManager(mgr manager.Manager, address string) error {
+	if !o.Enabled() {
+		return nil
+	}
+	server, err := o.NewServer(mgr.GetConfig(), address, crmetrics.Registry)
+	if err != nil {
+		return err
+	}
+	return mgr.Add(server)
+}
+
+// NewServer returns a server of the metrics of the gatherer over TLS on the address.
+func (o *SecureServingOptions) NewServer(config *rest.Config, address string, gatherer prometheus.Gatherer) (*SecureServer, error) {
+	if !o.Enabled() {
+		return nil, errors.New("metrics cert dir is not set")
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the client of the metrics server: %v", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if o.ClientCAFile != "" {
+		pem, err := os.ReadFile(o.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading metrics client CA file: %v", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("metrics client CA file %s contains no certificate", o.ClientCAFile)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", newAuthHandler(client, o.AuthorizationNamespace, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})))
+
+	return &SecureServer{
+		address:   address,
+		certDir:   o.CertDir,
+		clientCAs: clientCAs,
+		handler:   mux,
+	}, nil
+}
+
+// SecureServer serves metrics over TLS. It implements the manager.Runnable interface.
+type SecureServer struct {
+	address   string
+	certDir   string
+	clientCAs *x509.CertPool
+	handler   http.Handler
+}
+
+// NeedLeaderElection implements the manager.LeaderElectionRunnable interface, the metrics are served by all the replicas.
+func (s *SecureServer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start serves the metrics until the context is done. The certificate is reloaded when it changes.
+func (s *SecureServer) Start(ctx context.Context) error {
+	watcher, err := certwatcher.New(filepath.Join(s.certDir, "tls.crt"), filepath.Join(s.certDir, "tls.key"))
+	if err != nil {
+		return fmt.Errorf("error loading metrics serving certificate: %v", err)
+	}
+	go func() {
+		if err := watcher.Start(ctx); err != nil {
+			klog.Errorf("Error watching metrics serving certificate: %v", err)
+		}
+	}()
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: watcher.GetCertificate,
+	}
+	if s.clientCAs != nil {
+		tlsConfig.ClientCAs = s.clientCAs
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %v", s.address, err)
+	}
+	server := &http.Server{
+		Handler:           s.handler,
+		ReadHeaderTimeout: 32 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), secureServingShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("Error shutting down metrics server: %v", err)
+		}
+	}()
+
+	klog.Infof("Serving metrics over TLS on %s", listener.Addr())
+	if err := server.Serve(tls.NewListener(listener, tlsConfig)); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// authHandler only serves the requests of the clients authenticated and allowed to get the metrics subresource
+// of the namespace, with the same attributes as the kube-rbac-proxy configuration.
+type authHandler struct {
+	client    kubernetes.Interface
+	namespace string
+	handler   http.Handler
+
+	// tokens caches the users of the authenticated bearer tokens, by hash of the token.
+	tokens *cache.LRUExpireCache
+	// decisions caches the authorization decisions, by user.
+	decisions *cache.LRUExpireCache
+}
+
+func newAuthHandler(client kubernetes.Interface, namespace string, handler http.Handler) *authHandler {
+	return &authHandler{
+		client:    client,
+		namespace: namespace,
+		handler:   handler,
+		tokens:    cache.NewLRUExpireCache(authCacheSize),
+		decisions: cache.NewLRUExpireCache(authCacheSize),
+	}
+}
+
+func (h *authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, err := h.authenticate(r)
+	if err != nil {
+		klog.V(3).Infof("Unauthenticated metrics request from %s: %v", r.RemoteAddr, err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	allowed, err := h.authorize(r.Context(), user)
+	if err != nil {
+		klog.Errorf("Error authorizing metrics request of %s: %v", user.Username, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		klog.V(3).Infof("Forbidden metrics request of %s", user.Username)
+		http.Error(w, fmt.Sprintf("Forbidden (user=%s, verb=get, resource=namespace, subresource=metrics)", user.Username), http.StatusForbidden)
+		return
+	}
+
+	h.handler.ServeHTTP(w, r)
+}
+
+// authenticate returns the user of the verified client certificate, or of the bearer token.
+func (h *authHandler) authenticate(r *http.Request) (*authenticationv1.UserInfo, error) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		cert := r.TLS.VerifiedChains[0][0]
+		return &authenticationv1.UserInfo{Username: cert.Subject.CommonName, Groups: cert.Subject.Organization}, nil
+	}
+
+	token, ok := bearerToken(r)
+	if !ok {
+		return nil, errors.New("no client certificate or bearer token")
+	}
+	key := sha256.Sum256([]byte(token))
+	if user, ok := h.tokens.Get(key); ok {
+		return user.(*authenticationv1.UserInfo), nil
+	}
+	review, err := h.client.AuthenticationV1().TokenReviews().Create(r.Context(), &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error reviewing token: %v", err)
+	}
+	if !review.Status.Authenticated {
+		return nil, fmt.Errorf("token is not authenticated: %s", review.Status.Error)
+	}
+	h.tokens.Add(key, &review.Status.User, authenticatedTTL)
+	return &review.Status.User, nil
+}
+
+// authorize returns true if the user is allowed to get the metrics subresource of the namespace.
+func (h *authHandler) authorize(ctx context.Context, user *authenticationv1.UserInfo) (bool, error) {
+	key, err := json.Marshal(user)
+	if err != nil {
+		return false, err
+	}
+	if allowed, ok := h.decisions.Get(string(key)); ok {
+		return allowed.(bool), nil
+	}
+
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	review, err := h.client.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   h.namespace,
+				Verb:        "get",
+				Version:     "v1",
+				Resource:    "namespace",
+				Subresource: "metrics",
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	ttl := authorizedTTL
+	if !review.Status.Allowed {
+		ttl = unauthorizedTTL
+	}
+	h.decisions.Add(string(key), review.Status.Allowed, ttl)
+	return review.Status.Allowed, nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestSecureServingOptionsManagerBindAddress(t *testing.T) {
+	g := NewWithT(t)
+
+	options := &SecureServingOptions{}
+	g.Expect(options.ManagerBindAddress(":8082")).To(Equal(":8082"))
+
+	options.CertDir = "/etc/tls/private"
+	g.Expect(options.ManagerBindAddress(":8082")).To(Equal("0"))
+}
+
+func TestAuthHandler(t *testing.T) {
+	serviceAccount := "system:serviceaccount:openshift-monitoring:prometheus-k8s"
+
+	testCases := []struct {
+		name               string
+		authorization      string
+		clientCert         *x509.Certificate
+		allowed            bool
+		expectedStatusCode int
+		expectedUser       string
+		expectedGroups     []string
+	}{
+		{
+			name:               "without credentials",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "with an invalid token",
+			authorization:      "Bearer invalid",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "with a token not allowed to get metrics",
+			authorization:      "Bearer valid",
+			expectedStatusCode: http.StatusForbidden,
+			expectedUser:       serviceAccount,
+			expectedGroups:     []string{"system:serviceaccounts"},
+		},
+		{
+			name:               "with a token allowed to get metrics",
+			authorization:      "Bearer valid",
+			allowed:            true,
+			expectedStatusCode: http.StatusOK,
+			expectedUser:       serviceAccount,
+			expectedGroups:     []string{"system:serviceaccounts"},
+		},
+		{
+			name:               "with a client certificate allowed to get metrics",
+			clientCert:         &x509.Certificate{Subject: pkix.Name{CommonName: serviceAccount, Organization: []string{"system:monitoring"}}},
+			allowed:            true,
+			expectedStatusCode: http.StatusOK,
+			expectedUser:       serviceAccount,
+			expectedGroups:     []string{"system:monitoring"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var review *authorizationv1.SubjectAccessReview
+			client := fake.NewSimpleClientset()
+			client.PrependReactor("create", "tokenreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				tokenReview := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+				if tokenReview.Spec.Token == "valid" {
+					tokenReview.Status.Authenticated = true
+					tokenReview.Status.User = authenticationv1.UserInfo{Username: serviceAccount, Groups: []string{"system:serviceaccounts"}}
+				}
+				return true, tokenReview, nil
+			})
+			client.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				review = action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+				review.Status.Allowed = tc.allowed
+				return true, review, nil
+			})
+
+			h := newAuthHandler(client, DefaultMetricsAuthorizationNamespace, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			if tc.clientCert != nil {
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tc.clientCert}}}
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			g.Expect(rec.Code).To(Equal(tc.expectedStatusCode))
+			if tc.expectedUser == "" {
+				g.Expect(review).To(BeNil())
+				return
+			}
+			g.Expect(review).ToNot(BeNil())
+			g.Expect(review.Spec.User).To(Equal(tc.expectedUser))
+			g.Expect(review.Spec.Groups).To(Equal(tc.expectedGroups))
+			g.Expect(review.Spec.ResourceAttributes).To(Equal(&authorizationv1.ResourceAttributes{
+				Namespace:   "openshift-machine-api",
+				Verb:        "get",
+				Version:     "v1",
+				Resource:    "namespace",
+				Subresource: "metrics",
+			}))
+		})
+	}
+}
+
+func TestAuthHandlerCachesReviews(t *testing.T) {
+	g := NewWithT(t)
+
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		tokenReview := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		tokenReview.Status.Authenticated = tokenReview.Spec.Token != "invalid"
+		tokenReview.Status.User = authenticationv1.UserInfo{Username: tokenReview.Spec.Token}
+		return true, tokenReview, nil
+	})
+	client.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		review.Status.Allowed = review.Spec.User == "allowed"
+		return true, review, nil
+	})
+	h := newAuthHandler(client, DefaultMetricsAuthorizationNamespace, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	scrape := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	reviews := func() int {
+		count := len(client.Actions())
+		client.ClearActions()
+		return count
+	}
+
+	// The token and the decision are reviewed once, whether the user is allowed or not.
+	g.Expect(scrape("allowed")).To(Equal(http.StatusOK))
+	g.Expect(reviews()).To(Equal(2))
+	g.Expect(scrape("allowed")).To(Equal(http.StatusOK))
+	g.Expect(reviews()).To(Equal(0))
+
+	g.Expect(scrape("denied")).To(Equal(http.StatusForbidden))
+	g.Expect(reviews()).To(Equal(2))
+	g.Expect(scrape("denied")).To(Equal(http.StatusForbidden))
+	g.Expect(reviews()).To(Equal(0))
+
+	// The tokens which are not authenticated are not cached.
+	g.Expect(scrape("invalid")).To(Equal(http.StatusUnauthorized))
+	g.Expect(scrape("invalid")).To(Equal(http.StatusUnauthorized))
+	g.Expect(reviews()).To(Equal(2))
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2026 The Machine API Operator authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultStuckMachineThreshold is the default duration after which the Machines in the Provisioning or Deleting
+// phase are reported as stuck, matching the alerts on the Machines without a Running phase.
+const DefaultStuckMachineThreshold = 60 * time.Minute
+
+var (
+	// MachineStuckInPhaseDesc is the number of Machines in the Provisioning or Deleting phase for longer than the threshold.
+	MachineStuckInPhaseDesc = prometheus.NewDesc("mapi_machine_stuck_in_phase", "Number of Machines in the Provisioning or Deleting phase for longer than the stuck machine threshold", []string{"phase"}, nil)
+
+	// stuckPhases are the phases in which the Machines are reported as stuck.
+	stuckPhases = []string{machinev1.PhaseProvisioning, machinev1.PhaseDeleting}
+)
+
+// StuckMachineCollector is implementing prometheus.Collector interface.
+// It counts the Machines stuck in the Provisioning or Deleting phase for longer than the threshold.
+type StuckMachineCollector struct {
+	client    client.Reader
+	threshold time.Duration
+
+	// nowFunc is used to mock time in testing. It should be nil in production.
+	nowFunc func() time.Time
+}
+
+func NewStuckMachineCollector(client client.Reader, threshold time.Duration) *StuckMachineCollector {
+	return &StuckMachineCollector{
+		client:    client,
+		threshold: threshold,
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (sc *StuckMachineCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- MachineStuckInPhaseDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (sc *StuckMachineCollector) Collect(ch chan<- prometheus.Metric) {
+	machineList := &machinev1.MachineList{}
+	if err := sc.client.List(context.Background(), machineList); err != nil {
+		klog.Errorf("Error listing Machines to count the stuck Machines: %v", err)
+		MachineCollectorUp.With(prometheus.Labels{"kind": "mapi_machine_stuck_in_phase"}).Set(float64(0))
+		return
+	}
+	MachineCollectorUp.With(prometheus.Labels{"kind": "mapi_machine_stuck_in_phase"}).Set(float64(1))
+
+	stuck := map[string]int{}
+	for i := range machineList.Items {
+		if phase, ok := sc.stuckPhase(&machineList.Items[i]); ok {
+			stuck[phase]++
+		}
+	}
+	for _, phase := range stuckPhases {
+		ch <- prometheus.MustNewConstMetric(MachineStuckInPhaseDesc, prometheus.GaugeValue, float64(stuck[phase]), phase)
+	}
+}
+
+// stuckPhase returns the phase of the Machine, if it is stuck in it. The Machines enter the Provisioning phase
+// when they are created, and the Deleting phase when their deletion is requested.
+func (sc *StuckMachineCollector) stuckPhase(machine *machinev1.Machine) (string, bool) {
+	phase := pointer.StringDeref(machine.Status.Phase, "")
+	var entered time.Time
+	switch {
+	case phase == machinev1.PhaseProvisioning:
+		entered = machine.CreationTimestamp.Time
+	case phase == machinev1.PhaseDeleting && machine.DeletionTimestamp != nil:
+		entered = machine.DeletionTimestamp.Time
+	default:
+		return "", false
+	}
+	return phase, sc.now().Sub(entered) > sc.threshold
+}
+
+func (sc *StuckMachineCollector) now() time.Time {
+	if sc.nowFunc != nil {
+		return sc.nowFunc()
+	}
+	return time.Now()
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestStuckMachineCollector(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Now()
+	newMachine := func(name, phase string, created time.Time, deleted *time.Time) client.Object {
+		machine := &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openshift-machine-api", CreationTimestamp: metav1.NewTime(created)},
+			Status:     machinev1.MachineStatus{Phase: pointer.String(phase)},
+		}
+		if deleted != nil {
+			machine.DeletionTimestamp = &metav1.Time{Time: *deleted}
+			machine.Finalizers = []string{machinev1.MachineFinalizer}
+		}
+		return machine
+	}
+	recentlyDeleted := now.Add(-10 * time.Minute)
+	longDeleted := now.Add(-2 * time.Hour)
+
+	scheme := runtime.NewScheme()
+	g.Expect(machinev1.AddToScheme(scheme)).To(Succeed())
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newMachine("provisioning", machinev1.PhaseProvisioning, now.Add(-10*time.Minute), nil),
+		newMachine("stuck-provisioning-1", machinev1.PhaseProvisioning, now.Add(-90*time.Minute), nil),
+		newMachine("stuck-provisioning-2", machinev1.PhaseProvisioning, now.Add(-3*time.Hour), nil),
+		newMachine("running", machinev1.PhaseRunning, now.Add(-3*time.Hour), nil),
+		newMachine("deleting", machinev1.PhaseDeleting, now.Add(-3*time.Hour), &recentlyDeleted),
+		newMachine("stuck-deleting", machinev1.PhaseDeleting, now.Add(-3*time.Hour), &longDeleted),
+	).Build()
+
+	collector := NewStuckMachineCollector(fakeClient, time.Hour)
+	collector.nowFunc = func() time.Time { return now }
+
+	ch := make(chan prometheus.Metric, 10)
+	collector.Collect(ch)
+	close(ch)
+
+	stuck := map[string]float64{}
+	for metric := range ch {
+		m := &dto.Metric{}
+		g.Expect(metric.Write(m)).To(Succeed())
+		g.Expect(m.GetLabel()).To(HaveLen(1))
+		stuck[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+	}
+	g.Expect(stuck).To(Equal(map[string]float64{
+		machinev1.PhaseProvisioning: 2,
+		machinev1.PhaseDeleting:     1,
+	}))
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2026 The Machine API Operator authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Decisions of the admission webhooks
+const (
+	WebhookDecisionAllowed = "allowed"
+	WebhookDecisionWarned  = "warned"
+	WebhookDecisionDenied  = "denied"
+	WebhookDecisionErrored = "errored"
+)
+
+// Metrics for use in the Machine API webhooks, so that the rejected requests show up on dashboards
+var (
+	// WebhookAdmissionsTotal is a metric to count the admission decisions of the webhooks
+	WebhookAdmissionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mapi_webhook_admissions_total",
+			Help: "Number of admission requests handled by the Machine API webhooks, by decision and reason.",
+		}, []string{"webhook", "decision", "reason"},
+	)
+
+	// WebhookAdmissionDurationSeconds is a metric to capture the time taken by the webhooks to handle the admission requests
+	WebhookAdmissionDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "mapi_webhook_admission_duration_seconds",
+			Help:    "Number of seconds taken by the Machine API webhooks to handle an admission request.",
+			Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		}, []string{"webhook", "decision"},
+	)
+)
+
+// ObserveWebhookAdmission records the decision of a webhook on an admission request, and the time it took
+func ObserveWebhookAdmission(webhook, decision, reason string, duration time.Duration) {
+	WebhookAdmissionsTotal.With(prometheus.Labels{
+		"webhook":  webhook,
+		"decision": decision,
+		"reason":   reason,
+	}).Inc()
+	WebhookAdmissionDurationSeconds.With(prometheus.Labels{
+		"webhook":  webhook,
+		"decision": decision,
+	}).Observe(duration.Seconds())
+}
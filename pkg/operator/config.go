@@ -24,6 +24,17 @@ type OperatorConfig struct {
 	Controllers     Controllers
 	Proxy           *configv1.Proxy
 	PlatformType    configv1.PlatformType
+	// Tuning overrides the resources and GOMAXPROCS of the operand containers.
+	Tuning operandTuning
+	// Replicas is the number of replicas of the machine-api-controllers deployment, 1 when unset.
+	Replicas *int32
+	// DisabledControllers are the controllers of the machine-api-controllers deployment which do not reconcile.
+	DisabledControllers []string
+	// ImageOverrides are the names in images.json of the images overridden by the image overrides ConfigMap.
+	ImageOverrides []string
+	// GuestNamespace is the namespace of the Machine API resources in the guest cluster,
+	// set when the operator runs in the hosted control plane mode.
+	GuestNamespace string
 }
 
 type Controllers struct {
@@ -111,16 +122,16 @@ func getProviderControllerFromImages(platform configv1.PlatformType, featureGate
 }
 
 // getTerminationHandlerFromImages returns the image to use for the Termination Handler DaemonSet
-// based on the platform provided.
+// based on the platform provided. The termination handler of GCP and Azure is built with the operator,
+// see usesBuiltinTerminationHandler.
 // Defaults to NoOp if not supported by the platform.
 func getTerminationHandlerFromImages(platform configv1.PlatformType, images Images) (string, error) {
+	if usesBuiltinTerminationHandler(platform) {
+		return getMachineAPIOperatorFromImages(images)
+	}
 	switch platform {
 	case configv1.AWSPlatformType:
 		return images.ClusterAPIControllerAWS, nil
-	case configv1.GCPPlatformType:
-		return images.ClusterAPIControllerGCP, nil
-	case configv1.AzurePlatformType:
-		return images.ClusterAPIControllerAzure, nil
 	case configv1.AlibabaCloudPlatformType:
 		return images.ClusterAPIControllerAlibaba, nil
 	default:
@@ -128,6 +139,19 @@ func getTerminationHandlerFromImages(platform configv1.PlatformType, images Imag
 	}
 }
 
+// usesBuiltinTerminationHandler returns true when the termination handler of the platform is the one of the
+// operator image, which polls the instance metadata service of the platform set by its --platform flag.
+func usesBuiltinTerminationHandler(platform configv1.PlatformType) bool {
+	return platform == configv1.GCPPlatformType || platform == configv1.AzurePlatformType
+}
+
+// usesBuiltinRebalanceRecommendationHandler returns true when the termination handler of the platform is the one
+// of the provider image, and the rebalance recommendations are polled by the termination handler of the operator
+// image alongside it.
+func usesBuiltinRebalanceRecommendationHandler(platform configv1.PlatformType) bool {
+	return platform == configv1.AWSPlatformType
+}
+
 func getMachineAPIOperatorFromImages(images Images) (string, error) {
 	if images.MachineAPIOperator == "" {
 		return "", fmt.Errorf("failed gettingMachineAPIOperator image. It is empty")
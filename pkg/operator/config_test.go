@@ -387,11 +387,11 @@ func TestGetTerminationHandlerFromImages(t *testing.T) {
 		},
 		{
 			provider:      configv1.AzurePlatformType,
-			expectedImage: expectedAzureImage,
+			expectedImage: expectedMachineAPIOperatorImage,
 		},
 		{
 			provider:      configv1.GCPPlatformType,
-			expectedImage: expectedGCPImage,
+			expectedImage: expectedMachineAPIOperatorImage,
 		},
 		{
 			provider:      kubemarkPlatform,
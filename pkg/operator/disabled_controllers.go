@@ -0,0 +1,82 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// disabledControllersConfigMapName is the name of the ConfigMap in the target namespace which disables
+	// individual controllers of the machine-api-controllers deployment.
+	disabledControllersConfigMapName = "machine-api-disabled-controllers"
+
+	// disabledControllersKey holds the list of the disabled controllers as YAML or JSON.
+	disabledControllersKey = "controllers"
+
+	// disabledControllerArg makes the controller binary serve its metrics, health and webhook endpoints
+	// without reconciling, so that the monitoring of the deployment is unaffected.
+	disabledControllerArg = "--controller-enabled=false"
+)
+
+// disableableControllers are the controllers which can be disabled. The machine-controller can not be
+// disabled, as Machines would no longer be created nor deleted.
+var disableableControllers = []string{
+	"machineset-controller",
+	"nodelink-controller",
+	"machine-healthcheck-controller",
+}
+
+func isDisabledControllersConfigMap(obj interface{}) bool {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	return ok && configMap.Name == disabledControllersConfigMapName
+}
+
+// getDisabledControllers returns the controllers disabled in the disabled controllers ConfigMap, if any.
+func (optr *Operator) getDisabledControllers() ([]string, error) {
+	configMap, err := optr.configMapLister.ConfigMaps(optr.namespace).Get(disabledControllersConfigMapName)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not fetch disabled controllers configmap: %v", err)
+	}
+	return parseDisabledControllers(configMap)
+}
+
+func parseDisabledControllers(configMap *corev1.ConfigMap) ([]string, error) {
+	value, ok := configMap.Data[disabledControllersKey]
+	if !ok {
+		return nil, nil
+	}
+
+	var controllers []string
+	if err := yaml.UnmarshalStrict([]byte(value), &controllers); err != nil {
+		return nil, fmt.Errorf("configmap %s: invalid %s: %v", disabledControllersConfigMapName, disabledControllersKey, err)
+	}
+
+	var disabled []string
+	for _, name := range controllers {
+		if !slices.Contains(disableableControllers, name) {
+			return nil, fmt.Errorf("configmap %s: unknown controller %q, must be one of %v", disabledControllersConfigMapName, name, disableableControllers)
+		}
+		if !slices.Contains(disabled, name) {
+			disabled = append(disabled, name)
+		}
+	}
+	sort.Strings(disabled)
+	return disabled, nil
+}
+
+// disableControllers appends the arg disabling the controller to the containers of the disabled controllers.
+// The containers share their args, which are copied before being changed.
+func disableControllers(containers []corev1.Container, disabled []string) {
+	for i := range containers {
+		if slices.Contains(disabled, containers[i].Name) {
+			containers[i].Args = append(append([]string{}, containers[i].Args...), disabledControllerArg)
+		}
+	}
+}
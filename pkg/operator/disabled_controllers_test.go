@@ -0,0 +1,86 @@
+package operator
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseDisabledControllers(t *testing.T) {
+	testCases := []struct {
+		name             string
+		data             map[string]string
+		expectedDisabled []string
+		expectedError    string
+	}{
+		{
+			name: "without controllers",
+			data: map[string]string{},
+		},
+		{
+			name: "with an empty list",
+			data: map[string]string{disabledControllersKey: `[]`},
+		},
+		{
+			name: "with controllers",
+			data: map[string]string{disabledControllersKey: `
+- machine-healthcheck-controller
+- machineset-controller
+- machine-healthcheck-controller
+`},
+			expectedDisabled: []string{"machine-healthcheck-controller", "machineset-controller"},
+		},
+		{
+			name:          "with an unknown controller",
+			data:          map[string]string{disabledControllersKey: `[machine-controller]`},
+			expectedError: `configmap machine-api-disabled-controllers: unknown controller "machine-controller", must be one of [machineset-controller nodelink-controller machine-healthcheck-controller]`,
+		},
+		{
+			name:          "with invalid YAML",
+			data:          map[string]string{disabledControllersKey: `machineset-controller: true`},
+			expectedError: "configmap machine-api-disabled-controllers: invalid controllers: error unmarshaling JSON: while decoding JSON: json: cannot unmarshal object into Go value of type []string",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			disabled, err := parseDisabledControllers(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: disabledControllersConfigMapName, Namespace: targetNamespace},
+				Data:       tc.data,
+			})
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(disabled).To(Equal(tc.expectedDisabled))
+		})
+	}
+}
+
+func TestDisableControllers(t *testing.T) {
+	g := NewWithT(t)
+
+	config := &OperatorConfig{
+		TargetNamespace:     targetNamespace,
+		Proxy:               &configv1.Proxy{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}},
+		DisabledControllers: []string{"machine-healthcheck-controller"},
+	}
+
+	containers := map[string]corev1.Container{}
+	for _, c := range newContainers(config, nil) {
+		containers[c.Name] = c
+	}
+
+	g.Expect(containers["machine-healthcheck-controller"].Args).To(ContainElement(disabledControllerArg))
+
+	// The other containers keep reconciling.
+	for _, name := range []string{"machineset-controller", "machine-controller", "nodelink-controller"} {
+		g.Expect(containers[name].Args).ToNot(ContainElement(disabledControllerArg), name)
+	}
+}
@@ -0,0 +1,72 @@
+package operator
+
+import (
+	"sort"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/utils/strings/slices"
+)
+
+const (
+	// featureVSphereDeepValidation validates vSphere providerSpecs against vCenter in the machine webhooks.
+	featureVSphereDeepValidation = "MachineAPIVSphereDeepValidation"
+	// featureDryRunEstimates estimates the capacity of the cloud for the Machines created with a server side dry run.
+	featureDryRunEstimates = "MachineAPIDryRunEstimates"
+	// featureMigration mirrors the MachineSets and Machines into Cluster API resources, to hand them over to Cluster API.
+	featureMigration = "MachineAPIMigration"
+)
+
+// techPreviewFeatureArgs are the experimental features of the Machine API, with the args enabling them in the containers
+// of the machine-api-controllers deployment. They are enabled by the TechPreviewNoUpgrade feature set, or by name
+// in the CustomNoUpgrade feature set.
+var techPreviewFeatureArgs = map[string]map[string][]string{
+	featureVSphereDeepValidation: {
+		"machineset-controller": {"--vsphere-deep-validation"},
+	},
+	featureDryRunEstimates: {
+		"machineset-controller": {"--webhook-dry-run-estimates"},
+	},
+	featureMigration: {
+		"machineset-controller": {"--capi-sync"},
+	},
+}
+
+// getTechPreviewFeatures returns whether each experimental feature of the Machine API is enabled by the feature gate.
+// All features are disabled when the feature gate does not exist.
+func getTechPreviewFeatures(featureGate *configv1.FeatureGate) map[string]bool {
+	features := map[string]bool{}
+	for feature := range techPreviewFeatureArgs {
+		features[feature] = false
+		if featureGate == nil {
+			continue
+		}
+
+		switch featureGate.Spec.FeatureSet {
+		case configv1.TechPreviewNoUpgrade:
+			features[feature] = true
+		case configv1.CustomNoUpgrade:
+			if custom := featureGate.Spec.CustomNoUpgrade; custom != nil {
+				features[feature] = slices.Contains(custom.Enabled, feature) && !slices.Contains(custom.Disabled, feature)
+			}
+		}
+	}
+	return features
+}
+
+// getFeatureArgs returns the args enabling the features in the container, in the order of the feature names.
+// Disabled features have no args, so that disabling a feature rolls the deployment back.
+func getFeatureArgs(container string, features map[string]bool) []string {
+	names := make([]string, 0, len(features))
+	for feature, enabled := range features {
+		if enabled {
+			names = append(names, feature)
+		}
+	}
+	sort.Strings(names)
+
+	var args []string
+	for _, feature := range names {
+		args = append(args, techPreviewFeatureArgs[feature][container]...)
+	}
+	return args
+}
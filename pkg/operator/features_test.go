@@ -0,0 +1,101 @@
+package operator
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetTechPreviewFeatures(t *testing.T) {
+	testCases := []struct {
+		name             string
+		featureGate      *configv1.FeatureGate
+		expectedFeatures map[string]bool
+	}{
+		{
+			name: "without a feature gate",
+			expectedFeatures: map[string]bool{
+				featureVSphereDeepValidation: false,
+				featureDryRunEstimates:       false,
+				featureMigration:             false,
+			},
+		},
+		{
+			name:        "with the default feature set",
+			featureGate: &configv1.FeatureGate{},
+			expectedFeatures: map[string]bool{
+				featureVSphereDeepValidation: false,
+				featureDryRunEstimates:       false,
+				featureMigration:             false,
+			},
+		},
+		{
+			name: "with the TechPreviewNoUpgrade feature set",
+			featureGate: &configv1.FeatureGate{
+				Spec: configv1.FeatureGateSpec{
+					FeatureGateSelection: configv1.FeatureGateSelection{FeatureSet: configv1.TechPreviewNoUpgrade},
+				},
+			},
+			expectedFeatures: map[string]bool{
+				featureVSphereDeepValidation: true,
+				featureDryRunEstimates:       true,
+				featureMigration:             true,
+			},
+		},
+		{
+			name: "with the CustomNoUpgrade feature set",
+			featureGate: &configv1.FeatureGate{
+				Spec: configv1.FeatureGateSpec{
+					FeatureGateSelection: configv1.FeatureGateSelection{
+						FeatureSet: configv1.CustomNoUpgrade,
+						CustomNoUpgrade: &configv1.CustomFeatureGates{
+							Enabled:  []string{featureVSphereDeepValidation, featureDryRunEstimates},
+							Disabled: []string{featureDryRunEstimates},
+						},
+					},
+				},
+			},
+			expectedFeatures: map[string]bool{
+				featureVSphereDeepValidation: true,
+				featureDryRunEstimates:       false,
+				featureMigration:             false,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(getTechPreviewFeatures(tc.featureGate)).To(Equal(tc.expectedFeatures))
+		})
+	}
+}
+
+func TestNewContainersFeatureArgs(t *testing.T) {
+	g := NewWithT(t)
+
+	config := &OperatorConfig{
+		TargetNamespace: targetNamespace,
+		Proxy:           &configv1.Proxy{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}},
+	}
+	containerArgs := func(features map[string]bool) map[string][]string {
+		args := map[string][]string{}
+		for _, c := range newContainers(config, features) {
+			args[c.Name] = c.Args
+		}
+		return args
+	}
+
+	disabled := containerArgs(getTechPreviewFeatures(nil))
+	enabled := containerArgs(map[string]bool{featureVSphereDeepValidation: true, featureDryRunEstimates: true, featureMigration: true})
+
+	g.Expect(enabled["machineset-controller"]).To(Equal(append(disabled["machineset-controller"], "--webhook-dry-run-estimates", "--capi-sync", "--vsphere-deep-validation")))
+	for _, name := range []string{"machine-controller", "nodelink-controller", "machine-healthcheck-controller"} {
+		g.Expect(enabled[name]).To(Equal(disabled[name]), "unexpected args for %s", name)
+	}
+
+	// Disabling the features rolls the args back.
+	g.Expect(containerArgs(map[string]bool{featureVSphereDeepValidation: false, featureDryRunEstimates: false, featureMigration: false})).To(Equal(disabled))
+}
@@ -0,0 +1,140 @@
+package operator
+
+import (
+	"fmt"
+	"net/url"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// guestKubeconfigSecretName is the name of the Secret in the target namespace holding the kubeconfig
+	// of the guest cluster, with which the operands reconcile the guest cluster in the hosted control plane mode.
+	guestKubeconfigSecretName = "machine-api-guest-kubeconfig"
+	guestKubeconfigKey        = "kubeconfig"
+	guestKubeconfigVolumeName = "guest-kubeconfig"
+	guestKubeconfigMountPath  = "/etc/kubernetes/guest"
+
+	// hostedWebhookCAConfigMapName is the name of the ConfigMap in the target namespace into which the service CA
+	// of the management cluster is injected, so that the guest cluster can verify the webhook server.
+	hostedWebhookCAConfigMapName = "machine-api-webhook-ca"
+	hostedWebhookCAKey           = "service-ca.crt"
+
+	// injectCABundleAnnotation makes the service CA operator inject its CA into the webhook configurations.
+	injectCABundleAnnotation = "service.beta.openshift.io/inject-cabundle"
+)
+
+// HostedControlPlane configures the operator to run in the namespace of a hosted control plane on a management
+// cluster. The operands are deployed on the management cluster and reconcile the Machines of the guest cluster,
+// in which the webhooks are registered. The termination handler is not deployed, as it runs on the guest nodes.
+type HostedControlPlane struct {
+	// GuestNamespace is the namespace of the Machine API resources in the guest cluster.
+	GuestNamespace string
+	// GuestKubeClient is the client of the guest cluster.
+	GuestKubeClient kubernetes.Interface
+}
+
+// EnableHostedControlPlane makes the operator manage the Machine API of a guest cluster from a hosted control plane.
+// The ClusterOperator, config and machine clients given to New must be clients of the guest cluster.
+func (optr *Operator) EnableHostedControlPlane(hcp HostedControlPlane) {
+	optr.hostedControlPlane = &hcp
+}
+
+// webhookClient returns the client of the cluster in which the webhooks are registered.
+func (optr *Operator) webhookClient() kubernetes.Interface {
+	if optr.hostedControlPlane != nil {
+		return optr.hostedControlPlane.GuestKubeClient
+	}
+	return optr.kubeClient
+}
+
+// machineNamespace returns the namespace of the Machine API resources.
+func (optr *Operator) machineNamespace() string {
+	if optr.hostedControlPlane != nil {
+		return optr.hostedControlPlane.GuestNamespace
+	}
+	return optr.namespace
+}
+
+func isHostedWebhookCAConfigMap(obj interface{}) bool {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	return ok && configMap.Name == hostedWebhookCAConfigMapName
+}
+
+// getHostedWebhookCABundle returns the service CA of the management cluster, which signs the webhook serving certificate.
+func (optr *Operator) getHostedWebhookCABundle() ([]byte, error) {
+	configMap, err := optr.configMapLister.ConfigMaps(optr.namespace).Get(hostedWebhookCAConfigMapName)
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("configmap %s not found, the service CA of the management cluster must be injected into it", hostedWebhookCAConfigMapName)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not fetch hosted webhook CA configmap: %v", err)
+	}
+
+	caBundle := configMap.Data[hostedWebhookCAKey]
+	if caBundle == "" {
+		return nil, fmt.Errorf("configmap %s: %s is empty, the service CA of the management cluster must be injected into it", hostedWebhookCAConfigMapName, hostedWebhookCAKey)
+	}
+	return []byte(caBundle), nil
+}
+
+// hostedWebhookOptions point the webhooks registered in the guest cluster at the webhook services of the management cluster.
+type hostedWebhookOptions struct {
+	serviceNamespace string
+	caBundle         []byte
+}
+
+// applyToClientConfig replaces the service reference of the client config by the URL of the service in the
+// management namespace, which the API server of the hosted control plane can resolve.
+func (o *hostedWebhookOptions) applyToClientConfig(clientConfig *admissionregistrationv1.WebhookClientConfig) {
+	if clientConfig.Service == nil {
+		return
+	}
+	port := int32(443)
+	if clientConfig.Service.Port != nil {
+		port = *clientConfig.Service.Port
+	}
+	u := url.URL{
+		Scheme: "https",
+		Host:   fmt.Sprintf("%s.%s.svc:%d", clientConfig.Service.Name, o.serviceNamespace, port),
+		Path:   pointer.StringDeref(clientConfig.Service.Path, ""),
+	}
+	clientConfig.Service = nil
+	clientConfig.URL = pointer.String(u.String())
+	clientConfig.CABundle = o.caBundle
+}
+
+// applyHostedControlPlane points the operand containers at the guest cluster.
+func applyHostedControlPlane(config *OperatorConfig, containers []corev1.Container) {
+	if config.GuestNamespace == "" {
+		return
+	}
+	for i := range containers {
+		c := &containers[i]
+		c.Args = append(append([]string{}, c.Args...),
+			fmt.Sprintf("--kubeconfig=%s/%s", guestKubeconfigMountPath, guestKubeconfigKey),
+			fmt.Sprintf("--leader-elect-resource-namespace=%s", config.GuestNamespace),
+		)
+		c.VolumeMounts = append(append([]corev1.VolumeMount{}, c.VolumeMounts...), corev1.VolumeMount{
+			Name:      guestKubeconfigVolumeName,
+			MountPath: guestKubeconfigMountPath,
+			ReadOnly:  true,
+		})
+	}
+}
+
+// newGuestKubeconfigVolume returns the volume of the kubeconfig of the guest cluster.
+func newGuestKubeconfigVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: guestKubeconfigVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName:  guestKubeconfigSecretName,
+				DefaultMode: pointer.Int32(420),
+			},
+		},
+	}
+}
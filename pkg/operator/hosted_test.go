@@ -0,0 +1,157 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/pointer"
+)
+
+const guestNamespace = "openshift-machine-api"
+
+func TestSyncWebhookConfigurationHosted(t *testing.T) {
+	testCases := []struct {
+		name          string
+		configMapData map[string]string
+		expectedError string
+	}{
+		{
+			name:          "without the webhook CA configmap",
+			expectedError: "configmap machine-api-webhook-ca not found, the service CA of the management cluster must be injected into it",
+		},
+		{
+			name:          "with an empty webhook CA",
+			configMapData: map[string]string{},
+			expectedError: "configmap machine-api-webhook-ca: service-ca.crt is empty, the service CA of the management cluster must be injected into it",
+		},
+		{
+			name:          "with the webhook CA",
+			configMapData: map[string]string{hostedWebhookCAKey: "service-ca"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var kubeObjects []runtime.Object
+			if tc.configMapData != nil {
+				kubeObjects = append(kubeObjects, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: hostedWebhookCAConfigMapName, Namespace: targetNamespace},
+					Data:       tc.configMapData,
+				})
+			}
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			optr, err := newFakeOperator(kubeObjects, nil, nil, "", stopCh)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(cache.WaitForCacheSync(stopCh, optr.configMapListerSynced)).To(BeTrue())
+
+			guestKubeClient := fakekube.NewSimpleClientset()
+			optr.EnableHostedControlPlane(HostedControlPlane{GuestNamespace: guestNamespace, GuestKubeClient: guestKubeClient})
+
+			err = optr.syncWebhookConfiguration(&OperatorConfig{PlatformType: configv1.AWSPlatformType})
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+
+			// The webhooks are only registered in the guest cluster.
+			managementWebhooks, err := optr.kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(context.Background(), metav1.ListOptions{})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(managementWebhooks.Items).To(BeEmpty())
+
+			validatingWebhookConfigurations, err := guestKubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(context.Background(), metav1.ListOptions{})
+			g.Expect(err).ToNot(HaveOccurred())
+			mutatingWebhookConfigurations, err := guestKubeClient.AdmissionregistrationV1().MutatingWebhookConfigurations().List(context.Background(), metav1.ListOptions{})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(validatingWebhookConfigurations.Items).To(HaveLen(1))
+			g.Expect(mutatingWebhookConfigurations.Items).To(HaveLen(1))
+
+			g.Expect(validatingWebhookConfigurations.Items[0].Annotations).ToNot(HaveKey(injectCABundleAnnotation))
+			for _, webhook := range validatingWebhookConfigurations.Items[0].Webhooks {
+				g.Expect(webhook.ClientConfig.Service).To(BeNil(), webhook.Name)
+				g.Expect(webhook.ClientConfig.URL).To(HaveValue(HavePrefix("https://machine-api-operator-webhook.test-namespace.svc:443/")), webhook.Name)
+				g.Expect(webhook.ClientConfig.CABundle).To(Equal([]byte("service-ca")), webhook.Name)
+			}
+			g.Expect(mutatingWebhookConfigurations.Items[0].Annotations).ToNot(HaveKey(injectCABundleAnnotation))
+			for _, webhook := range mutatingWebhookConfigurations.Items[0].Webhooks {
+				g.Expect(webhook.ClientConfig.Service).To(BeNil(), webhook.Name)
+				g.Expect(webhook.ClientConfig.URL).To(HaveValue(HavePrefix("https://machine-api-operator-webhook.test-namespace.svc:443/")), webhook.Name)
+				g.Expect(webhook.ClientConfig.CABundle).To(Equal([]byte("service-ca")), webhook.Name)
+			}
+		})
+	}
+}
+
+func TestHostedControlPlaneDeployment(t *testing.T) {
+	g := NewWithT(t)
+
+	config := &OperatorConfig{
+		TargetNamespace: targetNamespace,
+		Proxy:           &configv1.Proxy{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}},
+		GuestNamespace:  guestNamespace,
+	}
+
+	deployment := newDeployment(config, nil)
+	g.Expect(deployment.Namespace).To(Equal(targetNamespace))
+	g.Expect(deployment.Spec.Template.Spec.Volumes).To(ContainElement(newGuestKubeconfigVolume()))
+
+	for _, c := range newContainers(config, nil) {
+		g.Expect(c.Args).To(ContainElements(
+			"--namespace=openshift-machine-api",
+			"--kubeconfig=/etc/kubernetes/guest/kubeconfig",
+			"--leader-elect-resource-namespace=openshift-machine-api",
+		), c.Name)
+		g.Expect(c.VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      guestKubeconfigVolumeName,
+			MountPath: guestKubeconfigMountPath,
+			ReadOnly:  true,
+		}), c.Name)
+	}
+
+	// Without a guest namespace the operands manage the cluster they run in.
+	config.GuestNamespace = ""
+	g.Expect(newDeployment(config, nil).Spec.Template.Spec.Volumes).ToNot(ContainElement(HaveField("Name", guestKubeconfigVolumeName)))
+	for _, c := range newContainers(config, nil) {
+		g.Expect(c.Args).To(ContainElement("--namespace=" + targetNamespace))
+		g.Expect(c.Args).ToNot(ContainElement(HavePrefix("--kubeconfig")), c.Name)
+	}
+}
+
+func TestHostedWebhookClientConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	options := &hostedWebhookOptions{serviceNamespace: "clusters-guest", caBundle: []byte("service-ca")}
+	clientConfig := admissionWebhookClientConfig("machine-api-operator-webhook", nil, "/validate")
+	options.applyToClientConfig(&clientConfig)
+
+	g.Expect(clientConfig.Service).To(BeNil())
+	g.Expect(clientConfig.URL).To(HaveValue(Equal("https://machine-api-operator-webhook.clusters-guest.svc:443/validate")))
+	g.Expect(clientConfig.CABundle).To(Equal([]byte("service-ca")))
+
+	clientConfig = admissionWebhookClientConfig("machine-api-operator-machine-webhook", pointer.Int32(8443), "/mutate")
+	options.applyToClientConfig(&clientConfig)
+	g.Expect(clientConfig.URL).To(HaveValue(Equal("https://machine-api-operator-machine-webhook.clusters-guest.svc:8443/mutate")))
+}
+
+func admissionWebhookClientConfig(service string, port *int32, path string) admissionregistrationv1.WebhookClientConfig {
+	return admissionregistrationv1.WebhookClientConfig{
+		Service: &admissionregistrationv1.ServiceReference{
+			Namespace: guestNamespace,
+			Name:      service,
+			Port:      port,
+			Path:      pointer.String(path),
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package operator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// imageOverridesConfigMapName is the name of the ConfigMap in the target namespace which overrides the images
+	// of the operands, e.g. with the images of a mirror registry in disconnected clusters.
+	imageOverridesConfigMapName = "machine-api-image-overrides"
+
+	// imageOverridesKey holds the overridden images as JSON, in the format of the images.json file of the operator.
+	imageOverridesKey = "images.json"
+)
+
+// digestReferenceRegexp matches the image references pinned by a sha256 digest, e.g. registry.example.com:5000/ocp/release@sha256:<digest>.
+var digestReferenceRegexp = regexp.MustCompile(`^[a-z0-9]+([._:/-][a-zA-Z0-9_]+)*@sha256:[a-f0-9]{64}$`)
+
+func isImageOverridesConfigMap(obj interface{}) bool {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	return ok && configMap.Name == imageOverridesConfigMapName
+}
+
+// getImageOverrides returns the images set in the image overrides ConfigMap, if any.
+func (optr *Operator) getImageOverrides() (*Images, error) {
+	configMap, err := optr.configMapLister.ConfigMaps(optr.namespace).Get(imageOverridesConfigMapName)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not fetch image overrides configmap: %v", err)
+	}
+	return parseImageOverrides(configMap)
+}
+
+func parseImageOverrides(configMap *corev1.ConfigMap) (*Images, error) {
+	value, ok := configMap.Data[imageOverridesKey]
+	if !ok {
+		return nil, nil
+	}
+
+	overrides := &Images{}
+	if err := yaml.UnmarshalStrict([]byte(value), overrides); err != nil {
+		return nil, fmt.Errorf("configmap %s: invalid %s: %v", imageOverridesConfigMapName, imageOverridesKey, err)
+	}
+
+	images := overrides.byName()
+	names := make([]string, 0, len(images))
+	for name := range images {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !digestReferenceRegexp.MatchString(images[name]) {
+			return nil, fmt.Errorf("configmap %s: invalid image %q for %s, must be pinned by a sha256 digest", imageOverridesConfigMapName, images[name], name)
+		}
+	}
+	return overrides, nil
+}
+
+// byName returns the images which are set, keyed by their name in images.json.
+func (i *Images) byName() map[string]string {
+	images := map[string]string{}
+	value := reflect.ValueOf(i).Elem()
+	for n := 0; n < value.NumField(); n++ {
+		if image := value.Field(n).String(); image != "" {
+			images[jsonFieldName(value.Type().Field(n))] = image
+		}
+	}
+	return images
+}
+
+// override replaces the images with the ones which are set in the overrides,
+// and returns the names of the overridden images in images.json, sorted.
+func (i *Images) override(overrides *Images) []string {
+	images := reflect.ValueOf(i).Elem()
+	var names []string
+	for name, image := range overrides.byName() {
+		for n := 0; n < images.NumField(); n++ {
+			if jsonFieldName(images.Type().Field(n)) == name && images.Field(n).String() != image {
+				images.Field(n).SetString(image)
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// jsonFieldName returns the name of the field in JSON.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
@@ -0,0 +1,121 @@
+package operator
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	mirroredAWSController = "mirror.example.com:5000/ocp/aws-machine-controllers@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	mirroredKubeRBACProxy = "mirror.example.com:5000/ocp/kube-rbac-proxy@sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+)
+
+func TestParseImageOverrides(t *testing.T) {
+	testCases := []struct {
+		name              string
+		data              map[string]string
+		expectedOverrides *Images
+		expectedError     string
+	}{
+		{
+			name: "without images",
+			data: map[string]string{},
+		},
+		{
+			name: "with images pinned by digest",
+			data: map[string]string{imageOverridesKey: `{
+  "clusterAPIControllerAWS": "` + mirroredAWSController + `",
+  "kubeRBACProxy": "` + mirroredKubeRBACProxy + `"
+}`},
+			expectedOverrides: &Images{ClusterAPIControllerAWS: mirroredAWSController, KubeRBACProxy: mirroredKubeRBACProxy},
+		},
+		{
+			name:          "with an image pinned by tag",
+			data:          map[string]string{imageOverridesKey: `{"kubeRBACProxy": "mirror.example.com/ocp/kube-rbac-proxy:v4.13"}`},
+			expectedError: `configmap machine-api-image-overrides: invalid image "mirror.example.com/ocp/kube-rbac-proxy:v4.13" for kubeRBACProxy, must be pinned by a sha256 digest`,
+		},
+		{
+			name:          "with an unknown image",
+			data:          map[string]string{imageOverridesKey: `{"clusterAPIControllerFoo": "` + mirroredAWSController + `"}`},
+			expectedError: `configmap machine-api-image-overrides: invalid images.json: error unmarshaling JSON: while decoding JSON: json: unknown field "clusterAPIControllerFoo"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			overrides, err := parseImageOverrides(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: imageOverridesConfigMapName, Namespace: targetNamespace},
+				Data:       tc.data,
+			})
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(overrides).To(Equal(tc.expectedOverrides))
+		})
+	}
+}
+
+func TestMAOConfigWithImageOverrides(t *testing.T) {
+	g := NewWithT(t)
+
+	imagesJSONFile, err := createImagesJSONFromManifest()
+	g.Expect(err).ToNot(HaveOccurred())
+	defer os.Remove(imagesJSONFile)
+	images, err := getImagesFromJSONFile(imagesJSONFile)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	infra := &configv1.Infrastructure{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Status:     configv1.InfrastructureStatus{PlatformStatus: &configv1.PlatformStatus{Type: configv1.AWSPlatformType}},
+	}
+	proxy := &configv1.Proxy{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: imageOverridesConfigMapName, Namespace: targetNamespace},
+		Data: map[string]string{imageOverridesKey: `{
+  "clusterAPIControllerAWS": "` + mirroredAWSController + `",
+  "kubeRBACProxy": "` + mirroredKubeRBACProxy + `"
+}`},
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	optr, err := newFakeOperator([]runtime.Object{configMap}, []runtime.Object{infra, proxy}, nil, imagesJSONFile, stopCh)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cache.WaitForCacheSync(stopCh, optr.configMapListerSynced)).To(BeTrue())
+
+	config, err := optr.maoConfigFromInfrastructure()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(config.Controllers).To(Equal(Controllers{
+		Provider:           mirroredAWSController,
+		MachineSet:         images.MachineAPIOperator,
+		NodeLink:           images.MachineAPIOperator,
+		MachineHealthCheck: images.MachineAPIOperator,
+		KubeRBACProxy:      mirroredKubeRBACProxy,
+		TerminationHandler: mirroredAWSController,
+	}))
+	g.Expect(config.ImageOverrides).To(Equal([]string{"clusterAPIControllerAWS", "kubeRBACProxy"}))
+
+	// Invalid overrides leave the images alone, the operands are not updated until they are fixed.
+	configMap.Data[imageOverridesKey] = `{"kubeRBACProxy": "mirror.example.com/ocp/kube-rbac-proxy:latest"}`
+	optr, err = newFakeOperator([]runtime.Object{configMap}, []runtime.Object{infra, proxy}, nil, imagesJSONFile, stopCh)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cache.WaitForCacheSync(stopCh, optr.configMapListerSynced)).To(BeTrue())
+
+	config, err = optr.maoConfigFromInfrastructure()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(config.Controllers.KubeRBACProxy).To(Equal(images.KubeRBACProxy))
+	g.Expect(config.ImageOverrides).To(BeEmpty())
+	_, err = optr.getImageOverrides()
+	g.Expect(err).To(HaveOccurred())
+}
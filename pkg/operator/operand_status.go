@@ -0,0 +1,180 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/strings/slices"
+)
+
+// The operands reported with their own condition in the ClusterOperator status.
+const (
+	operandMachineController            = "MachineController"
+	operandMachineSetController         = "MachineSetController"
+	operandNodeLinkController           = "NodeLinkController"
+	operandMachineHealthCheckController = "MachineHealthCheckController"
+	operandWebhook                      = "Webhook"
+	operandTerminationHandler           = "TerminationHandler"
+)
+
+// operandConditionTypeSuffix is appended to the operand to form the type of its condition, e.g. MachineControllerDegraded.
+const operandConditionTypeSuffix = "Degraded"
+
+// The reasons of the operand conditions, in addition to the default set of reasons.
+const (
+	ReasonContainerFailing   StatusReason = "ContainerFailing"
+	ReasonControllerDisabled StatusReason = "ControllerDisabled"
+)
+
+// controllerOperands are the operands of the machine-api-controllers deployment, in the order of their conditions.
+var controllerOperands = []string{
+	operandMachineController,
+	operandMachineSetController,
+	operandNodeLinkController,
+	operandMachineHealthCheckController,
+	operandWebhook,
+}
+
+// operandContainers maps the containers of the operand pods to their operands.
+// The webhooks are served by the machineset-controller container.
+var operandContainers = map[string][]string{
+	"machine-controller":               {operandMachineController},
+	"machineset-controller":            {operandMachineSetController, operandWebhook},
+	"nodelink-controller":              {operandNodeLinkController},
+	"machine-healthcheck-controller":   {operandMachineHealthCheckController},
+	"termination-handler":              {operandTerminationHandler},
+	"rebalance-recommendation-handler": {operandTerminationHandler},
+}
+
+// failingContainerReasons are the reasons of the waiting containers which will not recover without intervention.
+var failingContainerReasons = []string{
+	"CrashLoopBackOff",
+	"ImagePullBackOff",
+	"ErrImagePull",
+	"CreateContainerConfigError",
+	"CreateContainerError",
+	"InvalidImageName",
+}
+
+// operandFailure is a failure of an operand found during a sync.
+type operandFailure struct {
+	reason  StatusReason
+	message string
+}
+
+// operandHealth collects the failures of each operand during a sync.
+type operandHealth map[string][]operandFailure
+
+func (h operandHealth) fail(reason StatusReason, message string, operands ...string) {
+	for _, operand := range operands {
+		h[operand] = append(h[operand], operandFailure{reason: reason, message: message})
+	}
+}
+
+// err returns an error listing the failing operands, or nil when all operands are healthy.
+func (h operandHealth) err(operands []string) error {
+	var messages []string
+	for _, operand := range operands {
+		for _, failure := range h[operand] {
+			messages = append(messages, fmt.Sprintf("%s: %s", operand, failure.message))
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return fmt.Errorf("degraded operands: %s", strings.Join(messages, "; "))
+}
+
+// conditions returns a <Operand>Degraded condition for each of the operands, so that the ClusterOperator tells
+// which operand is failing. The reason of a failing operand is the reason of its first failure.
+func (h operandHealth) conditions(operands []string, disabledControllers []string) []osconfigv1.ClusterOperatorStatusCondition {
+	var conds []osconfigv1.ClusterOperatorStatusCondition
+	for _, operand := range operands {
+		conditionType := osconfigv1.ClusterStatusConditionType(operand + operandConditionTypeSuffix)
+		failures := h[operand]
+		if len(failures) == 0 {
+			reason, message := ReasonAsExpected, ""
+			if isDisabledOperand(operand, disabledControllers) {
+				reason, message = ReasonControllerDisabled, fmt.Sprintf("configmap %s disables the controller", disabledControllersConfigMapName)
+			}
+			conds = append(conds, newClusterOperatorStatusCondition(conditionType, osconfigv1.ConditionFalse, string(reason), message))
+			continue
+		}
+
+		messages := make([]string, 0, len(failures))
+		for _, failure := range failures {
+			messages = append(messages, failure.message)
+		}
+		conds = append(conds, newClusterOperatorStatusCondition(conditionType, osconfigv1.ConditionTrue,
+			string(failures[0].reason), strings.Join(messages, "; ")))
+	}
+	return conds
+}
+
+// isDisabledOperand returns whether the controller of the operand is disabled.
+func isDisabledOperand(operand string, disabledControllers []string) bool {
+	for _, container := range disabledControllers {
+		if operands, ok := operandContainers[container]; ok && operands[0] == operand {
+			return true
+		}
+	}
+	return false
+}
+
+// getOperands returns the operands deployed for the config.
+func getOperands(config *OperatorConfig) []string {
+	operands := append([]string{}, controllerOperands...)
+	if config.Controllers.TerminationHandler != clusterAPIControllerNoOp {
+		operands = append(operands, operandTerminationHandler)
+	}
+	return operands
+}
+
+// checkOperandContainers records the containers of the operand pods which are failing to start.
+// Containers which are only starting or not ready yet are left to the rollout checks.
+func (optr *Operator) checkOperandContainers(config *OperatorConfig, health operandHealth) error {
+	pods, err := optr.kubeClient.CoreV1().Pods(config.TargetNamespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: "api=clusterapi",
+	})
+	if err != nil {
+		return fmt.Errorf("could not list operand pods: %v", err)
+	}
+
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			operands, ok := operandContainers[status.Name]
+			if !ok || !isFailingContainer(status) {
+				continue
+			}
+			message := fmt.Sprintf("container %s of pod %s is not running: %s", status.Name, pod.Name, status.State.Waiting.Reason)
+			if status.State.Waiting.Message != "" {
+				message = fmt.Sprintf("%s: %s", message, status.State.Waiting.Message)
+			}
+			klog.V(3).Info(message)
+			health.fail(ReasonContainerFailing, message, operands...)
+		}
+	}
+	return nil
+}
+
+func isFailingContainer(status corev1.ContainerStatus) bool {
+	return status.State.Waiting != nil && slices.Contains(failingContainerReasons, status.State.Waiting.Reason)
+}
+
+// statusOperands sets the conditions of the operands. It does not modify the aggregate conditions.
+func (optr *Operator) statusOperands(conds []osconfigv1.ClusterOperatorStatusCondition) error {
+	co, err := optr.getOrCreateClusterOperator()
+	if err != nil {
+		return err
+	}
+	klog.V(2).Info("Syncing status: operands")
+	return optr.syncStatus(co, conds)
+}
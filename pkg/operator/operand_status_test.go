@@ -0,0 +1,119 @@
+package operator
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newOperandPod(name string, statuses ...corev1.ContainerStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: targetNamespace,
+			Labels:    map[string]string{"api": "clusterapi", "k8s-app": "controller"},
+		},
+		Status: corev1.PodStatus{ContainerStatuses: statuses},
+	}
+}
+
+func waitingContainerStatus(name, reason string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:  name,
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}},
+	}
+}
+
+func TestCheckOperandContainers(t *testing.T) {
+	testCases := []struct {
+		name           string
+		pods           []runtime.Object
+		expectedHealth operandHealth
+	}{
+		{
+			name:           "without pods",
+			expectedHealth: operandHealth{},
+		},
+		{
+			name: "with running and starting containers",
+			pods: []runtime.Object{newOperandPod("controllers",
+				corev1.ContainerStatus{Name: "machine-controller", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+				waitingContainerStatus("nodelink-controller", "ContainerCreating"),
+			)},
+			expectedHealth: operandHealth{},
+		},
+		{
+			name: "with a crash looping machineset controller",
+			pods: []runtime.Object{newOperandPod("controllers",
+				waitingContainerStatus("machineset-controller", "CrashLoopBackOff"),
+				waitingContainerStatus("kube-rbac-proxy-machineset-mtrc", "CrashLoopBackOff"),
+			)},
+			expectedHealth: operandHealth{
+				operandMachineSetController: {{reason: ReasonContainerFailing, message: "container machineset-controller of pod controllers is not running: CrashLoopBackOff"}},
+				operandWebhook:              {{reason: ReasonContainerFailing, message: "container machineset-controller of pod controllers is not running: CrashLoopBackOff"}},
+			},
+		},
+		{
+			name: "with a termination handler failing to pull its image",
+			pods: []runtime.Object{newOperandPod("termination",
+				waitingContainerStatus("termination-handler", "ImagePullBackOff"),
+			)},
+			expectedHealth: operandHealth{
+				operandTerminationHandler: {{reason: ReasonContainerFailing, message: "container termination-handler of pod termination is not running: ImagePullBackOff"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			optr, err := newFakeOperator(tc.pods, nil, nil, "", stopCh)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			health := operandHealth{}
+			g.Expect(optr.checkOperandContainers(&OperatorConfig{TargetNamespace: targetNamespace}, health)).To(Succeed())
+			g.Expect(health).To(Equal(tc.expectedHealth))
+		})
+	}
+}
+
+func TestOperandHealthConditions(t *testing.T) {
+	g := NewWithT(t)
+
+	health := operandHealth{}
+	health.fail(ReasonSyncFailed, "error syncing deployment", operandMachineController, operandWebhook)
+	health.fail(ReasonContainerFailing, "container is not running", operandMachineController)
+
+	operands := getOperands(&OperatorConfig{Controllers: Controllers{TerminationHandler: clusterAPIControllerNoOp}})
+	conds := map[configv1.ClusterStatusConditionType]configv1.ClusterOperatorStatusCondition{}
+	for _, c := range health.conditions(operands, []string{"machine-healthcheck-controller"}) {
+		conds[c.Type] = c
+	}
+
+	g.Expect(conds).To(HaveLen(len(controllerOperands)))
+	g.Expect(conds["MachineControllerDegraded"]).To(SatisfyAll(
+		HaveField("Status", configv1.ConditionTrue),
+		HaveField("Reason", string(ReasonSyncFailed)),
+		HaveField("Message", "error syncing deployment; container is not running"),
+	))
+	g.Expect(conds["WebhookDegraded"]).To(HaveField("Status", configv1.ConditionTrue))
+	g.Expect(conds["MachineSetControllerDegraded"]).To(SatisfyAll(
+		HaveField("Status", configv1.ConditionFalse),
+		HaveField("Reason", string(ReasonAsExpected)),
+	))
+	g.Expect(conds["MachineHealthCheckControllerDegraded"]).To(SatisfyAll(
+		HaveField("Status", configv1.ConditionFalse),
+		HaveField("Reason", string(ReasonControllerDisabled)),
+	))
+
+	g.Expect(health.err(operands)).To(MatchError("degraded operands: MachineController: error syncing deployment; " +
+		"MachineController: container is not running; Webhook: error syncing deployment"))
+	g.Expect(operandHealth{}.err(operands)).To(Succeed())
+}
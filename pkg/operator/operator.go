@@ -21,9 +21,11 @@ import (
 	"k8s.io/client-go/dynamic"
 	admissioninformersv1 "k8s.io/client-go/informers/admissionregistration/v1"
 	appsinformersv1 "k8s.io/client-go/informers/apps/v1"
+	coreinformersv1 "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	admissionlisterv1 "k8s.io/client-go/listers/admissionregistration/v1"
 	appslisterv1 "k8s.io/client-go/listers/apps/v1"
+	corelisterv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -71,6 +73,9 @@ type Operator struct {
 	mutatingWebhookLister         admissionlisterv1.MutatingWebhookConfigurationLister
 	mutatingWebhookListerSynced   cache.InformerSynced
 
+	configMapLister       corelisterv1.ConfigMapLister
+	configMapListerSynced cache.InformerSynced
+
 	featureGateLister      configlistersv1.FeatureGateLister
 	featureGateCacheSynced cache.InformerSynced
 
@@ -79,6 +84,9 @@ type Operator struct {
 	operandVersions []osconfigv1.OperandVersion
 
 	generations []osoperatorv1.GenerationStatus
+
+	// hostedControlPlane is set when the operator manages a guest cluster from a hosted control plane.
+	hostedControlPlane *HostedControlPlane
 }
 
 // New returns a new machine config operator.
@@ -94,6 +102,7 @@ func New(
 	validatingWebhookInformer admissioninformersv1.ValidatingWebhookConfigurationInformer,
 	mutatingWebhookInformer admissioninformersv1.MutatingWebhookConfigurationInformer,
 	proxyInformer configinformersv1.ProxyInformer,
+	configMapInformer coreinformersv1.ConfigMapInformer,
 	kubeClient kubernetes.Interface,
 	osClient osclientset.Interface,
 	machineClient machineclientset.Interface,
@@ -141,6 +150,34 @@ func New(
 	if err != nil {
 		return nil, fmt.Errorf("error adding event handler to featuregates informer: %v", err)
 	}
+	_, err = proxyInformer.Informer().AddEventHandler(optr.eventHandler())
+	if err != nil {
+		return nil, fmt.Errorf("error adding event handler to proxies informer: %v", err)
+	}
+	_, err = configMapInformer.Informer().AddEventHandler(optr.eventHandlerSingleton(isWebhookConfigMap))
+	if err != nil {
+		return nil, fmt.Errorf("error adding event handler to configmaps informer: %v", err)
+	}
+	_, err = configMapInformer.Informer().AddEventHandler(optr.eventHandlerSingleton(isTuningConfigMap))
+	if err != nil {
+		return nil, fmt.Errorf("error adding event handler to configmaps informer: %v", err)
+	}
+	_, err = configMapInformer.Informer().AddEventHandler(optr.eventHandlerSingleton(isTrustedCAConfigMap))
+	if err != nil {
+		return nil, fmt.Errorf("error adding event handler to configmaps informer: %v", err)
+	}
+	_, err = configMapInformer.Informer().AddEventHandler(optr.eventHandlerSingleton(isDisabledControllersConfigMap))
+	if err != nil {
+		return nil, fmt.Errorf("error adding event handler to configmaps informer: %v", err)
+	}
+	_, err = configMapInformer.Informer().AddEventHandler(optr.eventHandlerSingleton(isHostedWebhookCAConfigMap))
+	if err != nil {
+		return nil, fmt.Errorf("error adding event handler to configmaps informer: %v", err)
+	}
+	_, err = configMapInformer.Informer().AddEventHandler(optr.eventHandlerSingleton(isImageOverridesConfigMap))
+	if err != nil {
+		return nil, fmt.Errorf("error adding event handler to configmaps informer: %v", err)
+	}
 
 	optr.config = config
 	optr.syncHandler = optr.sync
@@ -163,6 +200,9 @@ func New(
 	optr.featureGateLister = featureGateInformer.Lister()
 	optr.featureGateCacheSynced = featureGateInformer.Informer().HasSynced
 
+	optr.configMapLister = configMapInformer.Lister()
+	optr.configMapListerSynced = configMapInformer.Informer().HasSynced
+
 	return optr, nil
 }
 
@@ -180,7 +220,8 @@ func (optr *Operator) Run(workers int, stopCh <-chan struct{}) {
 		optr.deployListerSynced,
 		optr.daemonsetListerSynced,
 		optr.proxyListerSynced,
-		optr.featureGateCacheSynced) {
+		optr.featureGateCacheSynced,
+		optr.configMapListerSynced) {
 		klog.Error("Failed to sync caches")
 		return
 	}
@@ -381,6 +422,11 @@ func (optr *Operator) maoConfigFromInfrastructure() (*OperatorConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Invalid overrides are reported by syncAll, which leaves the operands alone until they are fixed.
+	var imageOverrides []string
+	if overrides, err := optr.getImageOverrides(); err == nil && overrides != nil {
+		imageOverrides = images.override(overrides)
+	}
 
 	featureGate, err := getFeatureGate(optr.featureGateLister)
 	if err != nil {
@@ -412,7 +458,7 @@ func (optr *Operator) maoConfigFromInfrastructure() (*OperatorConfig, error) {
 		return nil, err
 	}
 
-	return &OperatorConfig{
+	config := &OperatorConfig{
 		TargetNamespace: optr.namespace,
 		Proxy:           clusterWideProxy,
 		Controllers: Controllers{
@@ -423,6 +469,13 @@ func (optr *Operator) maoConfigFromInfrastructure() (*OperatorConfig, error) {
 			KubeRBACProxy:      kubeRBACProxy,
 			TerminationHandler: terminationHandlerImage,
 		},
-		PlatformType: provider,
-	}, nil
+		PlatformType:   provider,
+		ImageOverrides: imageOverrides,
+	}
+	if optr.hostedControlPlane != nil {
+		config.GuestNamespace = optr.hostedControlPlane.GuestNamespace
+		// The termination handler runs on the guest nodes, which the management cluster can not schedule on.
+		config.Controllers.TerminationHandler = clusterAPIControllerNoOp
+	}
+	return config, nil
 }
@@ -51,6 +51,7 @@ func newFakeOperator(kubeObjects, osObjects, machineObjects []runtime.Object, im
 	daemonsetInformer := kubeNamespacedSharedInformer.Apps().V1().DaemonSets()
 	mutatingWebhookInformer := kubeNamespacedSharedInformer.Admissionregistration().V1().MutatingWebhookConfigurations()
 	validatingWebhookInformer := kubeNamespacedSharedInformer.Admissionregistration().V1().ValidatingWebhookConfigurations()
+	configMapInformer := kubeNamespacedSharedInformer.Core().V1().ConfigMaps()
 
 	optr := &Operator{
 		kubeClient:                    kubeClient,
@@ -63,6 +64,7 @@ func newFakeOperator(kubeObjects, osObjects, machineObjects []runtime.Object, im
 		daemonsetLister:               daemonsetInformer.Lister(),
 		mutatingWebhookLister:         mutatingWebhookInformer.Lister(),
 		validatingWebhookLister:       validatingWebhookInformer.Lister(),
+		configMapLister:               configMapInformer.Lister(),
 		imagesFile:                    imagesFile,
 		namespace:                     targetNamespace,
 		eventRecorder:                 record.NewFakeRecorder(50),
@@ -74,6 +76,7 @@ func newFakeOperator(kubeObjects, osObjects, machineObjects []runtime.Object, im
 		cache:                         resourceapply.NewResourceCache(),
 		mutatingWebhookListerSynced:   mutatingWebhookInformer.Informer().HasSynced,
 		validatingWebhookListerSynced: validatingWebhookInformer.Informer().HasSynced,
+		configMapListerSynced:         configMapInformer.Informer().HasSynced,
 	}
 
 	configSharedInformer.Start(stopCh)
@@ -103,6 +106,7 @@ func TestOperatorSync_NoOp(t *testing.T) {
 		platform        openshiftv1.PlatformType
 		expectedNoop    bool
 		expectedMessage string
+		expectedReason  StatusReason
 	}{
 		{
 			platform:     openshiftv1.AWSPlatformType,
@@ -148,11 +152,13 @@ func TestOperatorSync_NoOp(t *testing.T) {
 			platform:        openshiftv1.NonePlatformType,
 			expectedNoop:    true,
 			expectedMessage: operatorStatusNoOpMessage,
+			expectedReason:  ReasonNoMachineSets,
 		},
 		{
 			platform:        "bad-platform",
 			expectedNoop:    true,
 			expectedMessage: operatorStatusNoOpMessage,
+			expectedReason:  ReasonNoMachineSets,
 		},
 	}
 
@@ -230,6 +236,10 @@ func TestOperatorSync_NoOp(t *testing.T) {
 					openshiftv1.OperatorDegraded:    openshiftv1.ConditionFalse,
 					openshiftv1.OperatorUpgradeable: openshiftv1.ConditionTrue,
 				}
+				// None of the operands are failing.
+				for _, operand := range append(controllerOperands, operandTerminationHandler) {
+					expectedConditions[openshiftv1.ClusterStatusConditionType(operand+operandConditionTypeSuffix)] = openshiftv1.ConditionFalse
+				}
 			}
 
 			o, err := optr.osClient.ConfigV1().ClusterOperators().Get(context.Background(), clusterOperatorName, metav1.GetOptions{})
@@ -241,6 +251,7 @@ func TestOperatorSync_NoOp(t *testing.T) {
 				// if expecting a Noop and the operator is available, then check to ensure that the proper message is displayed
 				if tc.expectedNoop && c.Type == openshiftv1.OperatorAvailable && c.Status == openshiftv1.ConditionTrue {
 					assert.Equal(t, tc.expectedMessage, c.Message)
+					assert.Equal(t, string(tc.expectedReason), c.Reason)
 				}
 				assert.Equal(t, expectedConditions[c.Type], c.Status, fmt.Sprintf("unexpected clusteroperator condition %s status", c.Type))
 			}
@@ -420,7 +431,7 @@ func TestMAOConfigFromInfrastructure(t *testing.T) {
 					MachineSet:         images.MachineAPIOperator,
 					NodeLink:           images.MachineAPIOperator,
 					MachineHealthCheck: images.MachineAPIOperator,
-					TerminationHandler: images.ClusterAPIControllerAzure,
+					TerminationHandler: images.MachineAPIOperator,
 					KubeRBACProxy:      images.KubeRBACProxy,
 				},
 				PlatformType: openshiftv1.AzurePlatformType,
@@ -458,7 +469,7 @@ func TestMAOConfigFromInfrastructure(t *testing.T) {
 					MachineSet:         images.MachineAPIOperator,
 					NodeLink:           images.MachineAPIOperator,
 					MachineHealthCheck: images.MachineAPIOperator,
-					TerminationHandler: images.ClusterAPIControllerGCP,
+					TerminationHandler: images.MachineAPIOperator,
 					KubeRBACProxy:      images.KubeRBACProxy,
 				},
 				PlatformType: openshiftv1.GCPPlatformType,
@@ -25,6 +25,9 @@ const (
 	ReasonInitializing StatusReason = "Initializing"
 	ReasonSyncing      StatusReason = "SyncingResources"
 	ReasonSyncFailed   StatusReason = "SyncingFailed"
+	// ReasonNoMachineSets is the reason of the Available condition on clusters without MachineSets,
+	// e.g. UPI clusters whose nodes are managed externally.
+	ReasonNoMachineSets StatusReason = "NoMachineSets"
 )
 
 const (
@@ -81,9 +84,9 @@ func (optr *Operator) statusProgressing() error {
 
 // statusAvailable sets the Available condition to True, with the given reason
 // and message, and sets both the Progressing and Degraded conditions to False.
-func (optr *Operator) statusAvailable(message string) error {
+func (optr *Operator) statusAvailable(reason StatusReason, message string) error {
 	conds := []osconfigv1.ClusterOperatorStatusCondition{
-		newClusterOperatorStatusCondition(osconfigv1.OperatorAvailable, osconfigv1.ConditionTrue, string(ReasonAsExpected), message),
+		newClusterOperatorStatusCondition(osconfigv1.OperatorAvailable, osconfigv1.ConditionTrue, string(reason), message),
 		newClusterOperatorStatusCondition(osconfigv1.OperatorProgressing, osconfigv1.ConditionFalse, string(ReasonAsExpected), ""),
 		newClusterOperatorStatusCondition(osconfigv1.OperatorDegraded, osconfigv1.ConditionFalse, string(ReasonAsExpected), ""),
 		operatorUpgradeable,
@@ -3,10 +3,12 @@ package operator
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -37,12 +39,15 @@ const (
 	machineExposeMetricsPort            = 8441
 	machineSetExposeMetricsPort         = 8442
 	machineHealthCheckExposeMetricsPort = 8444
+	nodeLinkExposeMetricsPort           = 8445
 	defaultMachineHealthPort            = 9440
 	defaultMachineSetHealthPort         = 9441
 	defaultMachineHealthCheckHealthPort = 9442
 	kubeRBACConfigName                  = "config"
 	certStoreName                       = "machine-api-controllers-tls"
 	externalTrustBundleConfigMapName    = "mao-trusted-ca"
+	trustedCAVolumeName                 = "trusted-ca"
+	trustedCAMountPath                  = "/etc/pki/ca-trust/extracted/pem"
 	hostKubeConfigPath                  = "/var/lib/kubelet/kubeconfig"
 	hostKubePKIPath                     = "/var/lib/kubelet/pki"
 	operatorStatusNoOpMessage           = "Cluster Machine API Operator is in NoOp mode"
@@ -72,7 +77,12 @@ func (optr *Operator) syncAll(config *OperatorConfig) (reconcile.Result, error)
 
 	if config.Controllers.Provider == clusterAPIControllerNoOp {
 		klog.V(3).Info("Provider is NoOp, skipping synchronisation")
-		if err := optr.statusAvailable(operatorStatusNoOpMessage); err != nil {
+		reason, err := optr.availableReason()
+		if err != nil {
+			klog.Errorf("Error listing MachineSets: %v", err)
+			return reconcile.Result{}, err
+		}
+		if err := optr.statusAvailable(reason, operatorStatusNoOpMessage); err != nil {
 			klog.Errorf("Error syncing ClusterOperatorStatus: %v", err)
 			return reconcile.Result{}, fmt.Errorf("error syncing ClusterOperatorStatus: %v", err)
 		}
@@ -80,21 +90,70 @@ func (optr *Operator) syncAll(config *OperatorConfig) (reconcile.Result, error)
 	}
 
 	errors := []error{}
+	health := operandHealth{}
 	// Sync webhook configuration
 	if err := optr.syncWebhookConfiguration(config); err != nil {
 		errors = append(errors, fmt.Errorf("error syncing machine API webhook configurations: %w", err))
+		health.fail(ReasonSyncFailed, fmt.Sprintf("error syncing webhook configurations: %v", err), operandWebhook)
 	}
 
-	if err := optr.syncClusterAPIController(config); err != nil {
-		errors = append(errors, fmt.Errorf("error syncing machine-api-controller: %w", err))
+	// The operands are not updated while their configuration is invalid, so that they keep their last valid configuration.
+	tuning, tuningErr := optr.getOperandTuning()
+	if tuningErr != nil {
+		errors = append(errors, fmt.Errorf("error syncing operand tuning: %w", tuningErr))
+	}
+	replicas, replicasErr := optr.getOperandReplicas()
+	if replicasErr != nil {
+		errors = append(errors, fmt.Errorf("error syncing operand replicas: %w", replicasErr))
+	}
+	disabledControllers, disabledErr := optr.getDisabledControllers()
+	if disabledErr != nil {
+		errors = append(errors, fmt.Errorf("error syncing disabled controllers: %w", disabledErr))
 	}
+	// The overrides are applied to the config, the error only tells whether the operands can be updated.
+	_, imagesErr := optr.getImageOverrides()
+	if imagesErr != nil {
+		errors = append(errors, fmt.Errorf("error syncing image overrides: %w", imagesErr))
+	}
+	if tuningErr == nil && replicasErr == nil && disabledErr == nil && imagesErr == nil {
+		config.Tuning = tuning
+		config.Replicas = replicas
+		config.DisabledControllers = disabledControllers
 
-	// Sync Termination Handler DaemonSet if supported
-	if config.Controllers.TerminationHandler != clusterAPIControllerNoOp {
-		if err := optr.syncTerminationHandler(config); err != nil {
-			errors = append(errors, fmt.Errorf("error syncing termination handler: %w", err))
+		if err := optr.syncClusterAPIController(config); err != nil {
+			errors = append(errors, fmt.Errorf("error syncing machine-api-controller: %w", err))
+			health.fail(ReasonSyncFailed, fmt.Sprintf("error syncing deployment machine-api-controllers: %v", err), controllerOperands...)
+		}
+
+		if err := optr.syncPodDisruptionBudget(config); err != nil {
+			errors = append(errors, fmt.Errorf("error syncing machine-api-controllers pod disruption budget: %w", err))
+			health.fail(ReasonSyncFailed, fmt.Sprintf("error syncing poddisruptionbudget machine-api-controllers: %v", err), controllerOperands...)
+		}
+
+		// Sync Termination Handler DaemonSet if supported
+		if config.Controllers.TerminationHandler != clusterAPIControllerNoOp {
+			if err := optr.syncTerminationHandler(config); err != nil {
+				errors = append(errors, fmt.Errorf("error syncing termination handler: %w", err))
+				health.fail(ReasonSyncFailed, fmt.Sprintf("error syncing daemonset %s: %v", machineAPITerminationHandler, err), operandTerminationHandler)
+			}
+		}
+	}
+
+	// Report the health of each operand, so that the ClusterOperator tells which operand is failing.
+	operands := getOperands(config)
+	if err := optr.checkOperandContainers(config, health); err != nil {
+		errors = append(errors, fmt.Errorf("error checking operand containers: %w", err))
+	}
+	// The sync errors are already reported, failing containers are reported on their own.
+	if len(errors) == 0 {
+		if err := health.err(operands); err != nil {
+			errors = append(errors, err)
 		}
 	}
+	if err := optr.statusOperands(health.conditions(operands, config.DisabledControllers)); err != nil {
+		klog.Errorf("Error syncing ClusterOperatorStatus: %v", err)
+		return reconcile.Result{}, fmt.Errorf("error syncing ClusterOperatorStatus: %v", err)
+	}
 
 	if len(errors) > 0 {
 		err := utilerrors.NewAggregate(errors)
@@ -135,7 +194,19 @@ func (optr *Operator) syncAll(config *OperatorConfig) (reconcile.Result, error)
 		return reconcile.Result{}, err
 	}
 
-	if initializing {
+	reason, err := optr.availableReason()
+	if err != nil {
+		if err := optr.statusDegraded(err.Error()); err != nil {
+			// Just log the error here.  We still want to
+			// return the outer error.
+			klog.Errorf("Error syncing ClusterOperatorStatus: %v", err)
+		}
+		klog.Errorf("Error listing MachineSets: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	// Without MachineSets, the worker nodes are not managed by the Machine API and there are no Machines to wait for.
+	if initializing && reason != ReasonNoMachineSets {
 		if err := optr.checkMinimumWorkerMachines(); err != nil {
 			if err := optr.statusDegraded(err.Error()); err != nil {
 				// Just log the error here.  We still want to
@@ -153,7 +224,13 @@ func (optr *Operator) syncAll(config *OperatorConfig) (reconcile.Result, error)
 	}
 
 	message := fmt.Sprintf("Cluster Machine API Operator is available at %s", optr.printOperandVersions())
-	if err := optr.statusAvailable(message); err != nil {
+	if len(config.DisabledControllers) > 0 {
+		message = fmt.Sprintf("%s, with the disabled controllers: %s", message, strings.Join(config.DisabledControllers, ", "))
+	}
+	if len(config.ImageOverrides) > 0 {
+		message = fmt.Sprintf("%s, with the overridden images: %s", message, strings.Join(config.ImageOverrides, ", "))
+	}
+	if err := optr.statusAvailable(reason, message); err != nil {
 		klog.Errorf("Error syncing ClusterOperatorStatus: %v", err)
 		return reconcile.Result{}, fmt.Errorf("error syncing ClusterOperatorStatus: %v", err)
 	}
@@ -185,16 +262,15 @@ func (optr *Operator) checkRolloutStatus(config *OperatorConfig) (reconcile.Resu
 }
 
 func (optr *Operator) syncClusterAPIController(config *OperatorConfig) error {
-	controllersDeployment := newDeployment(config, nil)
+	featureGate, err := getFeatureGate(optr.featureGateLister)
+	if err != nil {
+		return err
+	}
+	controllersDeployment := newDeployment(config, getTechPreviewFeatures(featureGate))
 
-	// we watch some resources so that our deployment will redeploy without explicitly and carefully ordered resource creation
-	inputHashes, err := resourcehash.MultipleObjectHashStringMapForObjectReferences(
-		context.TODO(),
-		optr.kubeClient,
-		resourcehash.NewObjectRef().ForConfigMap().InNamespace(config.TargetNamespace).Named(externalTrustBundleConfigMapName),
-	)
+	inputHashes, err := optr.getDependencyHashes(config)
 	if err != nil {
-		return fmt.Errorf("invalid dependency reference: %q", err)
+		return err
 	}
 	ensureDependecyAnnotations(inputHashes, controllersDeployment)
 
@@ -211,8 +287,28 @@ func (optr *Operator) syncClusterAPIController(config *OperatorConfig) error {
 	return nil
 }
 
+// syncPodDisruptionBudget keeps one replica of the machine-api-controllers deployment, which serves the webhooks,
+// available during voluntary disruptions. A single replica has no budget, so that it does not block node drains.
+func (optr *Operator) syncPodDisruptionBudget(config *OperatorConfig) error {
+	pdb := newPodDisruptionBudget(config)
+	recorder := events.NewLoggingEventRecorder(optr.name)
+	if pointer.Int32Deref(config.Replicas, 1) > 1 {
+		_, _, err := resourceapply.ApplyPodDisruptionBudget(context.TODO(), optr.kubeClient.PolicyV1(), recorder, pdb)
+		return err
+	}
+	_, _, err := resourceapply.DeletePodDisruptionBudget(context.TODO(), optr.kubeClient.PolicyV1(), recorder, pdb)
+	return err
+}
+
 func (optr *Operator) syncTerminationHandler(config *OperatorConfig) error {
 	terminationDaemonSet := newTerminationDaemonSet(config)
+
+	inputHashes, err := optr.getDependencyHashes(config)
+	if err != nil {
+		return err
+	}
+	ensureDaemonSetDependecyAnnotations(inputHashes, terminationDaemonSet)
+
 	expectedGeneration := resourcemerge.ExpectedDaemonSetGeneration(terminationDaemonSet, optr.generations)
 	ds, updated, err := resourceapply.ApplyDaemonSet(context.TODO(), optr.kubeClient.AppsV1(),
 		events.NewLoggingEventRecorder(optr.name), terminationDaemonSet, expectedGeneration)
@@ -226,27 +322,34 @@ func (optr *Operator) syncTerminationHandler(config *OperatorConfig) error {
 }
 
 func (optr *Operator) syncWebhookConfiguration(config *OperatorConfig) error {
-	if err := optr.syncMachineValidatingWebhook(); err != nil {
+	options, err := optr.getWebhookOptions()
+	if err != nil {
 		return err
 	}
-	if err := optr.syncMachineMutatingWebhook(); err != nil {
+	if err := optr.syncMachineValidatingWebhook(options); err != nil {
+		return err
+	}
+	if err := optr.syncMachineMutatingWebhook(options); err != nil {
 		return err
 	}
 	if config.PlatformType == v1.BareMetalPlatformType {
-		if err := optr.syncMetal3RemediationValidatingWebhook(); err != nil {
+		if err := optr.syncMetal3RemediationValidatingWebhook(options); err != nil {
 			return err
 		}
-		if err := optr.syncMetal3RemediationMutatingWebhook(); err != nil {
+		if err := optr.syncMetal3RemediationMutatingWebhook(options); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (optr *Operator) syncMachineValidatingWebhook() error {
-	validatingWebhook, updated, err := resourceapply.ApplyValidatingWebhookConfigurationImproved(context.TODO(), optr.kubeClient.AdmissionregistrationV1(),
+func (optr *Operator) syncMachineValidatingWebhook(options webhookOptions) error {
+	required := mapiwebhooks.NewMachineValidatingWebhookConfiguration()
+	options.applyToValidatingWebhookConfiguration(required)
+
+	validatingWebhook, updated, err := resourceapply.ApplyValidatingWebhookConfigurationImproved(context.TODO(), optr.webhookClient().AdmissionregistrationV1(),
 		events.NewLoggingEventRecorder(optr.name),
-		mapiwebhooks.NewMachineValidatingWebhookConfiguration(),
+		required,
 		optr.cache)
 	if err != nil {
 		return err
@@ -258,10 +361,13 @@ func (optr *Operator) syncMachineValidatingWebhook() error {
 	return nil
 }
 
-func (optr *Operator) syncMachineMutatingWebhook() error {
-	mutatingWebhook, updated, err := resourceapply.ApplyMutatingWebhookConfigurationImproved(context.TODO(), optr.kubeClient.AdmissionregistrationV1(),
+func (optr *Operator) syncMachineMutatingWebhook(options webhookOptions) error {
+	required := mapiwebhooks.NewMachineMutatingWebhookConfiguration()
+	options.applyToMutatingWebhookConfiguration(required)
+
+	mutatingWebhook, updated, err := resourceapply.ApplyMutatingWebhookConfigurationImproved(context.TODO(), optr.webhookClient().AdmissionregistrationV1(),
 		events.NewLoggingEventRecorder(optr.name),
-		mapiwebhooks.NewMachineMutatingWebhookConfiguration(),
+		required,
 		optr.cache)
 	if err != nil {
 		return err
@@ -275,10 +381,13 @@ func (optr *Operator) syncMachineMutatingWebhook() error {
 
 // Metal3Remediation(Templates) were backported from metal3, their CRDs and the
 // actual webhook implementation can be found in cluster-api-provider-baremetal
-func (optr *Operator) syncMetal3RemediationValidatingWebhook() error {
-	validatingWebhook, updated, err := resourceapply.ApplyValidatingWebhookConfigurationImproved(context.TODO(), optr.kubeClient.AdmissionregistrationV1(),
+func (optr *Operator) syncMetal3RemediationValidatingWebhook(options webhookOptions) error {
+	required := mapiwebhooks.NewMetal3RemediationValidatingWebhookConfiguration()
+	options.applyToValidatingWebhookConfiguration(required)
+
+	validatingWebhook, updated, err := resourceapply.ApplyValidatingWebhookConfigurationImproved(context.TODO(), optr.webhookClient().AdmissionregistrationV1(),
 		events.NewLoggingEventRecorder(optr.name),
-		mapiwebhooks.NewMetal3RemediationValidatingWebhookConfiguration(),
+		required,
 		optr.cache)
 	if err != nil {
 		return err
@@ -292,10 +401,13 @@ func (optr *Operator) syncMetal3RemediationValidatingWebhook() error {
 
 // Metal3Remediation(Templates) were backported from metal3, their CRDs and the
 // actual webhook implementation can be found in cluster-api-provider-baremetal
-func (optr *Operator) syncMetal3RemediationMutatingWebhook() error {
-	mutatingWebhook, updated, err := resourceapply.ApplyMutatingWebhookConfigurationImproved(context.TODO(), optr.kubeClient.AdmissionregistrationV1(),
+func (optr *Operator) syncMetal3RemediationMutatingWebhook(options webhookOptions) error {
+	required := mapiwebhooks.NewMetal3RemediationMutatingWebhookConfiguration()
+	options.applyToMutatingWebhookConfiguration(required)
+
+	mutatingWebhook, updated, err := resourceapply.ApplyMutatingWebhookConfigurationImproved(context.TODO(), optr.webhookClient().AdmissionregistrationV1(),
 		events.NewLoggingEventRecorder(optr.name),
-		mapiwebhooks.NewMetal3RemediationMutatingWebhookConfiguration(),
+		required,
 		optr.cache)
 	if err != nil {
 		return err
@@ -367,12 +479,24 @@ func (optr *Operator) checkDaemonSetRolloutStatus(resource *appsv1.DaemonSet) (r
 	return reconcile.Result{}, nil
 }
 
+// availableReason returns the reason of the Available condition, which tells whether the cluster has MachineSets.
+func (optr *Operator) availableReason() (StatusReason, error) {
+	machineSets, err := optr.machineClient.MachineV1beta1().MachineSets(optr.machineNamespace()).List(context.Background(), metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return "", fmt.Errorf("could not list MachineSets: %w", err)
+	}
+	if len(machineSets.Items) == 0 {
+		return ReasonNoMachineSets, nil
+	}
+	return ReasonAsExpected, nil
+}
+
 // checkMinimumWorkerMachines looks at the worker Machines in the cluster and checks if they are running.
 // If fewer than 2 worker Machines are Running, it will return an error.
 // This is used during initialization of the cluster to prevent the operator from being Available
 // until the minimum required number of worker Machines have started working correctly.
 func (optr *Operator) checkMinimumWorkerMachines() error {
-	machineSets, err := optr.machineClient.MachineV1beta1().MachineSets(optr.namespace).List(context.Background(), metav1.ListOptions{})
+	machineSets, err := optr.machineClient.MachineV1beta1().MachineSets(optr.machineNamespace()).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return fmt.Errorf("could not list MachineSets: %w", err)
 	}
@@ -406,7 +530,7 @@ func (optr *Operator) checkRunningMachineSetMachines(machineSet machinev1beta1.M
 		return 0, []string{}, fmt.Errorf("could not convert MachineSet label selector to selector: %w", err)
 	}
 
-	machines, err := optr.machineClient.MachineV1beta1().Machines(optr.namespace).List(context.Background(), metav1.ListOptions{
+	machines, err := optr.machineClient.MachineV1beta1().Machines(optr.machineNamespace()).List(context.Background(), metav1.ListOptions{
 		LabelSelector: selector.String(),
 	})
 	if err != nil {
@@ -429,8 +553,11 @@ func (optr *Operator) checkRunningMachineSetMachines(machineSet machinev1beta1.M
 }
 
 func newDeployment(config *OperatorConfig, features map[string]bool) *appsv1.Deployment {
-	replicas := int32(1)
+	replicas := pointer.Int32Deref(config.Replicas, 1)
 	template := newPodTemplateSpec(config, features)
+	if replicas > 1 {
+		template.Spec.TopologySpreadConstraints = newTopologySpreadConstraints(template.Labels)
+	}
 
 	return &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
@@ -457,6 +584,52 @@ func newDeployment(config *OperatorConfig, features map[string]bool) *appsv1.Dep
 	}
 }
 
+// newTopologySpreadConstraints spreads the replicas of the operand deployment across the zones, so that the webhooks
+// stay available during a zone outage, and across the control plane nodes. The zones are a preference, the control
+// plane nodes may not be evenly spread across them.
+func newTopologySpreadConstraints(labels map[string]string) []corev1.TopologySpreadConstraint {
+	return []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       corev1.LabelTopologyZone,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
+		},
+		{
+			MaxSkew:           1,
+			TopologyKey:       corev1.LabelHostname,
+			WhenUnsatisfiable: corev1.DoNotSchedule,
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
+		},
+	}
+}
+
+func newPodDisruptionBudget(config *OperatorConfig) *policyv1.PodDisruptionBudget {
+	maxUnavailable := intstr.FromInt(1)
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "machine-api-controllers",
+			Namespace: config.TargetNamespace,
+			Annotations: map[string]string{
+				maoOwnedAnnotation: "",
+			},
+			Labels: map[string]string{
+				"api":     "clusterapi",
+				"k8s-app": "controller",
+			},
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &maxUnavailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"api":     "clusterapi",
+					"k8s-app": "controller",
+				},
+			},
+		},
+	}
+}
+
 // List of the volumes needed by newKubeProxyContainer
 func newRBACConfigVolumes() []corev1.Volume {
 	var readOnly int32 = 420
@@ -481,21 +654,37 @@ func newRBACConfigVolumes() []corev1.Volume {
 				},
 			},
 		},
-		{
-			Name: "trusted-ca",
-			VolumeSource: corev1.VolumeSource{
-				ConfigMap: &corev1.ConfigMapVolumeSource{
-					Items: []corev1.KeyToPath{{Key: "ca-bundle.crt", Path: "tls-ca-bundle.pem"}},
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: externalTrustBundleConfigMapName,
-					},
-					Optional: pointer.Bool(true),
+		newTrustedCAVolume(),
+	}
+}
+
+// newTrustedCAVolume returns the volume of the trusted CA bundle of the cluster, which is injected into
+// the external trust bundle ConfigMap by the network operator when the cluster Proxy sets a trusted CA.
+func newTrustedCAVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: trustedCAVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				Items: []corev1.KeyToPath{{Key: "ca-bundle.crt", Path: "tls-ca-bundle.pem"}},
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: externalTrustBundleConfigMapName,
 				},
+				Optional: pointer.Bool(true),
 			},
 		},
 	}
 }
 
+// newTrustedCAVolumeMount mounts the trusted CA bundle over the system bundle of the container,
+// so that the operands trust the proxy and the cloud API endpoints signed by a custom CA.
+func newTrustedCAVolumeMount() corev1.VolumeMount {
+	return corev1.VolumeMount{
+		MountPath: trustedCAMountPath,
+		Name:      trustedCAVolumeName,
+		ReadOnly:  true,
+	}
+}
+
 func newPodTemplateSpec(config *OperatorConfig, features map[string]bool) *corev1.PodTemplateSpec {
 	containers := newContainers(config, features)
 	proxyContainers := newKubeProxyContainers(config.Controllers.KubeRBACProxy)
@@ -581,6 +770,9 @@ func newPodTemplateSpec(config *OperatorConfig, features map[string]bool) *corev
 		},
 	}
 	volumes = append(volumes, newRBACConfigVolumes()...)
+	if config.GuestNamespace != "" {
+		volumes = append(volumes, newGuestKubeconfigVolume())
+	}
 
 	return &corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
@@ -601,6 +793,8 @@ func newPodTemplateSpec(config *OperatorConfig, features map[string]bool) *corev
 	}
 }
 
+// getProxyArgs returns the env of the operands for the cluster-wide proxy. The status of the Proxy
+// holds the effective proxy configuration, the spec may not have been validated yet.
 func getProxyArgs(config *OperatorConfig) []corev1.EnvVar {
 	var envVars []corev1.EnvVar
 
@@ -610,13 +804,13 @@ func getProxyArgs(config *OperatorConfig) []corev1.EnvVar {
 	if config.Proxy.Status.HTTPProxy != "" {
 		envVars = append(envVars, corev1.EnvVar{
 			Name:  "HTTP_PROXY",
-			Value: config.Proxy.Spec.HTTPProxy,
+			Value: config.Proxy.Status.HTTPProxy,
 		})
 	}
 	if config.Proxy.Status.HTTPSProxy != "" {
 		envVars = append(envVars, corev1.EnvVar{
 			Name:  "HTTPS_PROXY",
-			Value: config.Proxy.Spec.HTTPSProxy,
+			Value: config.Proxy.Status.HTTPSProxy,
 		})
 	}
 	if config.Proxy.Status.NoProxy != "" {
@@ -629,18 +823,17 @@ func getProxyArgs(config *OperatorConfig) []corev1.EnvVar {
 }
 
 func newContainers(config *OperatorConfig, features map[string]bool) []corev1.Container {
-	resources := corev1.ResourceRequirements{
-		Requests: map[corev1.ResourceName]resource.Quantity{
-			corev1.ResourceMemory: resource.MustParse("20Mi"),
-			corev1.ResourceCPU:    resource.MustParse("10m"),
-		},
+	resources := defaultOperandResources()
+	watchNamespace := config.TargetNamespace
+	if config.GuestNamespace != "" {
+		watchNamespace = config.GuestNamespace
 	}
 	args := []string{
 		"--logtostderr=true",
 		"--v=3",
 		"--leader-elect=true",
 		"--leader-elect-lease-duration=120s",
-		fmt.Sprintf("--namespace=%s", config.TargetNamespace),
+		fmt.Sprintf("--namespace=%s", watchNamespace),
 	}
 
 	proxyEnvArgs := getProxyArgs(config)
@@ -680,6 +873,7 @@ func newContainers(config *OperatorConfig, features map[string]bool) []corev1.Co
 				},
 			},
 			VolumeMounts: []corev1.VolumeMount{
+				newTrustedCAVolumeMount(),
 				{
 					MountPath: "/etc/machine-api-operator/tls",
 					Name:      machineSetWebhookVolumeName,
@@ -728,11 +922,7 @@ func newContainers(config *OperatorConfig, features map[string]bool) []corev1.Co
 				},
 			},
 			VolumeMounts: []corev1.VolumeMount{
-				{
-					MountPath: "/etc/pki/ca-trust/extracted/pem",
-					Name:      "trusted-ca",
-					ReadOnly:  true,
-				},
+				newTrustedCAVolumeMount(),
 				{
 					MountPath: "/var/run/secrets/openshift/serviceaccount",
 					Name:      "bound-sa-token",
@@ -752,6 +942,9 @@ func newContainers(config *OperatorConfig, features map[string]bool) []corev1.Co
 			Args:      args,
 			Env:       proxyEnvArgs,
 			Resources: resources,
+			VolumeMounts: []corev1.VolumeMount{
+				newTrustedCAVolumeMount(),
+			},
 		},
 		{
 			Name:      "machine-healthcheck-controller",
@@ -760,6 +953,9 @@ func newContainers(config *OperatorConfig, features map[string]bool) []corev1.Co
 			Args:      args,
 			Env:       proxyEnvArgs,
 			Resources: resources,
+			VolumeMounts: []corev1.VolumeMount{
+				newTrustedCAVolumeMount(),
+			},
 			Ports: []corev1.ContainerPort{
 				{
 					Name:          "healthz",
@@ -784,14 +980,35 @@ func newContainers(config *OperatorConfig, features map[string]bool) []corev1.Co
 			},
 		},
 	}
+
+	// The containers share their args, copy them before appending the args of the experimental features.
+	for i := range containers {
+		if featureArgs := getFeatureArgs(containers[i].Name, features); len(featureArgs) > 0 {
+			containers[i].Args = append(append([]string{}, containers[i].Args...), featureArgs...)
+		}
+	}
+	disableControllers(containers, config.DisabledControllers)
+	applyHostedControlPlane(config, containers)
+	config.Tuning.apply(containers)
 	return containers
 }
 
+// defaultOperandResources returns the default resources of the operand containers.
+func defaultOperandResources() corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Requests: map[corev1.ResourceName]resource.Quantity{
+			corev1.ResourceMemory: resource.MustParse("20Mi"),
+			corev1.ResourceCPU:    resource.MustParse("10m"),
+		},
+	}
+}
+
 func newKubeProxyContainers(image string) []corev1.Container {
 	return []corev1.Container{
 		newKubeProxyContainer(image, "machineset-mtrc", metrics.DefaultMachineSetMetricsAddress, machineSetExposeMetricsPort),
 		newKubeProxyContainer(image, "machine-mtrc", metrics.DefaultMachineMetricsAddress, machineExposeMetricsPort),
 		newKubeProxyContainer(image, "mhc-mtrc", metrics.DefaultHealthCheckMetricsAddress, machineHealthCheckExposeMetricsPort),
+		newKubeProxyContainer(image, "nodelink-mtrc", metrics.DefaultNodeLinkMetricsAddress, nodeLinkExposeMetricsPort),
 	}
 }
 
@@ -907,6 +1124,7 @@ func newTerminationPodTemplateSpec(config *OperatorConfig) *corev1.PodTemplateSp
 						},
 					},
 				},
+				newTrustedCAVolume(),
 			},
 			Tolerations: []corev1.Toleration{
 				{
@@ -919,12 +1137,7 @@ func newTerminationPodTemplateSpec(config *OperatorConfig) *corev1.PodTemplateSp
 }
 
 func newTerminationContainers(config *OperatorConfig) []corev1.Container {
-	resources := corev1.ResourceRequirements{
-		Requests: map[corev1.ResourceName]resource.Quantity{
-			corev1.ResourceMemory: resource.MustParse("20Mi"),
-			corev1.ResourceCPU:    resource.MustParse("10m"),
-		},
-	}
+	resources := defaultOperandResources()
 	terminationArgs := []string{
 		"--logtostderr=true",
 		"--v=3",
@@ -932,10 +1145,13 @@ func newTerminationContainers(config *OperatorConfig) []corev1.Container {
 		fmt.Sprintf("--namespace=%s", config.TargetNamespace),
 		"--poll-interval-seconds=5",
 	}
+	if usesBuiltinTerminationHandler(config.PlatformType) {
+		terminationArgs = append(terminationArgs, fmt.Sprintf("--platform=%s", config.PlatformType))
+	}
 
 	proxyEnvArgs := getProxyArgs(config)
 
-	return []corev1.Container{
+	containers := []corev1.Container{
 		{
 			Name:      "termination-handler",
 			Image:     config.Controllers.TerminationHandler,
@@ -954,6 +1170,7 @@ func newTerminationContainers(config *OperatorConfig) []corev1.Container {
 			}),
 
 			VolumeMounts: []corev1.VolumeMount{
+				newTrustedCAVolumeMount(),
 				{
 					Name:      "kubeconfig",
 					MountPath: hostKubeConfigPath,
@@ -967,20 +1184,66 @@ func newTerminationContainers(config *OperatorConfig) []corev1.Container {
 			},
 		},
 	}
+	if usesBuiltinRebalanceRecommendationHandler(config.PlatformType) {
+		// The termination handler of the operator image, the one of the MachineSet controller, polls for the
+		// rebalance recommendations while the one of the provider handles the termination notices.
+		rebalance := *containers[0].DeepCopy()
+		rebalance.Name = "rebalance-recommendation-handler"
+		rebalance.Image = config.Controllers.MachineSet
+		rebalance.Args = append(rebalance.Args, fmt.Sprintf("--platform=%s", config.PlatformType), "--rebalance-recommendation-only")
+		containers = append(containers, rebalance)
+	}
+	config.Tuning.apply(containers)
+	return containers
+}
+
+func isTrustedCAConfigMap(obj interface{}) bool {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	return ok && configMap.Name == externalTrustBundleConfigMapName
+}
+
+// getDependencyHashes returns the hashes of the external dependencies of the operands.
+// We watch some resources so that the operands will redeploy without explicitly and carefully ordered resource creation.
+func (optr *Operator) getDependencyHashes(config *OperatorConfig) (map[string]string, error) {
+	inputHashes, err := resourcehash.MultipleObjectHashStringMapForObjectReferences(
+		context.TODO(),
+		optr.kubeClient,
+		resourcehash.NewObjectRef().ForConfigMap().InNamespace(config.TargetNamespace).Named(externalTrustBundleConfigMapName),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dependency reference: %q", err)
+	}
+	return inputHashes, nil
 }
 
 // ensureDependecyAnnotations uses inputHash map of external dependencies to force new generation of the deployment
 // triggering the Kubernetes rollout as defined when the inputHash changes by adding it annotation to the deployment object.
 func ensureDependecyAnnotations(inputHashes map[string]string, deployment *appsv1.Deployment) {
+	setDependencyAnnotations(inputHashes, &deployment.ObjectMeta, &deployment.Spec.Template)
+}
+
+// ensureDaemonSetDependecyAnnotations is ensureDependecyAnnotations for daemonsets.
+func ensureDaemonSetDependecyAnnotations(inputHashes map[string]string, daemonSet *appsv1.DaemonSet) {
+	setDependencyAnnotations(inputHashes, &daemonSet.ObjectMeta, &daemonSet.Spec.Template)
+}
+
+func setDependencyAnnotations(inputHashes map[string]string, objectMeta *metav1.ObjectMeta, template *corev1.PodTemplateSpec) {
+	if len(inputHashes) == 0 {
+		return
+	}
+	if objectMeta.Annotations == nil {
+		objectMeta.Annotations = map[string]string{}
+	}
+	// The pod templates share their common annotations, copy them before adding the hashes.
+	templateAnnotations := make(map[string]string, len(template.Annotations)+len(inputHashes))
+	for k, v := range template.Annotations {
+		templateAnnotations[k] = v
+	}
+	template.Annotations = templateAnnotations
+
 	for k, v := range inputHashes {
 		annotationKey := fmt.Sprintf("operator.openshift.io/dep-%s", k)
-		if deployment.Annotations == nil {
-			deployment.Annotations = map[string]string{}
-		}
-		if deployment.Spec.Template.Annotations == nil {
-			deployment.Spec.Template.Annotations = map[string]string{}
-		}
-		deployment.Annotations[annotationKey] = v
-		deployment.Spec.Template.Annotations[annotationKey] = v
+		objectMeta.Annotations[annotationKey] = v
+		template.Annotations[annotationKey] = v
 	}
 }
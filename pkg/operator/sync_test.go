@@ -1,6 +1,7 @@
 package operator
 
 import (
+	"context"
 	"errors"
 	"os"
 	"testing"
@@ -9,12 +10,17 @@ import (
 	. "github.com/onsi/gomega"
 	v1 "github.com/openshift/api/config/v1"
 	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/diff"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/pointer"
 )
 
 func TestCheckDeploymentRolloutStatus(t *testing.T) {
@@ -449,6 +455,41 @@ func TestCheckMinimumWorkerMachines(t *testing.T) {
 	}
 }
 
+func TestAvailableReason(t *testing.T) {
+	testCases := []struct {
+		name           string
+		machineSets    []runtime.Object
+		expectedReason StatusReason
+	}{
+		{
+			name:           "without MachineSets",
+			expectedReason: ReasonNoMachineSets,
+		},
+		{
+			name: "with MachineSets",
+			machineSets: []runtime.Object{&machinev1beta1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: targetNamespace},
+			}},
+			expectedReason: ReasonAsExpected,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			optr, err := newFakeOperator(nil, nil, tc.machineSets, "", stopCh)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			reason, err := optr.availableReason()
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(reason).To(Equal(tc.expectedReason))
+		})
+	}
+}
+
 func TestSyncWebhookConfiguration(t *testing.T) {
 
 	testCases := []struct {
@@ -501,3 +542,269 @@ func TestSyncWebhookConfiguration(t *testing.T) {
 		})
 	}
 }
+
+func TestSyncWebhookConfigurationOptions(t *testing.T) {
+	fail := admissionregistrationv1.Fail
+	ignore := admissionregistrationv1.Ignore
+
+	testCases := []struct {
+		name                      string
+		configMapData             map[string]string
+		expectedFailurePolicy     *admissionregistrationv1.FailurePolicyType
+		expectedNamespaceSelector *metav1.LabelSelector
+		expectedError             string
+	}{
+		{
+			name:                  "without webhook configmap",
+			expectedFailurePolicy: &ignore,
+		},
+		{
+			name:                  "with failurePolicy",
+			configMapData:         map[string]string{"failurePolicy": "Fail"},
+			expectedFailurePolicy: &fail,
+		},
+		{
+			name: "with namespaceSelector",
+			configMapData: map[string]string{"namespaceSelector": `matchExpressions:
+- key: machine.openshift.io/webhook-bypass
+  operator: DoesNotExist`},
+			expectedFailurePolicy: &ignore,
+			expectedNamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "machine.openshift.io/webhook-bypass", Operator: metav1.LabelSelectorOpDoesNotExist},
+				},
+			},
+		},
+		{
+			name:          "with invalid failurePolicy",
+			configMapData: map[string]string{"failurePolicy": "Never"},
+			expectedError: `configmap machine-api-webhook-config: invalid failurePolicy "Never", must be "Fail" or "Ignore"`,
+		},
+		{
+			name:          "with invalid namespaceSelector",
+			configMapData: map[string]string{"namespaceSelector": `{"matchExpressions": [{"key": "foo", "operator": "Equals"}]}`},
+			expectedError: "configmap machine-api-webhook-config: invalid namespaceSelector: \"Equals\" is not a valid label selector operator",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var kubeObjects []runtime.Object
+			if tc.configMapData != nil {
+				kubeObjects = append(kubeObjects, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      webhookConfigMapName,
+						Namespace: targetNamespace,
+					},
+					Data: tc.configMapData,
+				})
+			}
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			optr, err := newFakeOperator(kubeObjects, nil, nil, "", stopCh)
+			if err != nil {
+				t.Fatal(err)
+			}
+			g.Expect(cache.WaitForCacheSync(stopCh, optr.configMapListerSynced)).To(BeTrue())
+
+			err = optr.syncWebhookConfiguration(&OperatorConfig{PlatformType: v1.BareMetalPlatformType})
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+
+			validatingWebhookConfigurations, err := optr.kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(context.Background(), metav1.ListOptions{})
+			g.Expect(err).ToNot(HaveOccurred())
+			mutatingWebhookConfigurations, err := optr.kubeClient.AdmissionregistrationV1().MutatingWebhookConfigurations().List(context.Background(), metav1.ListOptions{})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(validatingWebhookConfigurations.Items).To(HaveLen(2))
+			g.Expect(mutatingWebhookConfigurations.Items).To(HaveLen(2))
+
+			for _, c := range validatingWebhookConfigurations.Items {
+				for _, webhook := range c.Webhooks {
+					g.Expect(webhook.FailurePolicy).To(Equal(tc.expectedFailurePolicy), webhook.Name)
+					g.Expect(webhook.NamespaceSelector).To(Equal(tc.expectedNamespaceSelector), webhook.Name)
+				}
+			}
+			for _, c := range mutatingWebhookConfigurations.Items {
+				for _, webhook := range c.Webhooks {
+					g.Expect(webhook.FailurePolicy).To(Equal(tc.expectedFailurePolicy), webhook.Name)
+					g.Expect(webhook.NamespaceSelector).To(Equal(tc.expectedNamespaceSelector), webhook.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestOperandProxyAndTrustedCA(t *testing.T) {
+	g := NewWithT(t)
+
+	config := &OperatorConfig{
+		TargetNamespace: targetNamespace,
+		Proxy: &v1.Proxy{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+			Spec: v1.ProxySpec{
+				HTTPProxy:  "http://unvalidated.example.com:3128",
+				HTTPSProxy: "https://unvalidated.example.com:3128",
+			},
+			Status: v1.ProxyStatus{
+				HTTPProxy:  "http://proxy.example.com:3128",
+				HTTPSProxy: "https://proxy.example.com:3128",
+				NoProxy:    ".cluster.local,.svc,10.0.0.0/16",
+			},
+		},
+	}
+
+	deployment := newDeployment(config, nil)
+	daemonSet := newTerminationDaemonSet(config)
+
+	for _, spec := range []corev1.PodSpec{deployment.Spec.Template.Spec, daemonSet.Spec.Template.Spec} {
+		g.Expect(spec.Volumes).To(ContainElement(newTrustedCAVolume()))
+	}
+
+	// The kube-rbac-proxy sidecars do not reach out of the cluster.
+	for _, c := range append(newContainers(config, nil), newTerminationContainers(config)...) {
+		g.Expect(c.VolumeMounts).To(ContainElement(newTrustedCAVolumeMount()), c.Name)
+		g.Expect(c.Env).To(ContainElements(
+			corev1.EnvVar{Name: "HTTP_PROXY", Value: "http://proxy.example.com:3128"},
+			corev1.EnvVar{Name: "HTTPS_PROXY", Value: "https://proxy.example.com:3128"},
+			corev1.EnvVar{Name: "NO_PROXY", Value: ".cluster.local,.svc,10.0.0.0/16"},
+		), c.Name)
+	}
+}
+
+func TestTerminationContainersPlatform(t *testing.T) {
+	testCases := []struct {
+		platform                 v1.PlatformType
+		expectedPlatform         bool
+		expectedRebalanceHandler bool
+	}{
+		{
+			platform: v1.AlibabaCloudPlatformType,
+		},
+		{
+			platform:                 v1.AWSPlatformType,
+			expectedRebalanceHandler: true,
+		},
+		{
+			platform:         v1.GCPPlatformType,
+			expectedPlatform: true,
+		},
+		{
+			platform:         v1.AzurePlatformType,
+			expectedPlatform: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.platform), func(t *testing.T) {
+			g := NewWithT(t)
+
+			config := &OperatorConfig{
+				TargetNamespace: targetNamespace,
+				PlatformType:    tc.platform,
+				Controllers:     Controllers{MachineSet: "mao-image", TerminationHandler: "termination-handler-image"},
+			}
+			containers := newTerminationContainers(config)
+			g.Expect(containers[0].Image).To(Equal("termination-handler-image"))
+			if tc.expectedPlatform {
+				g.Expect(containers[0].Args).To(ContainElement("--platform=" + string(tc.platform)))
+			} else {
+				g.Expect(containers[0].Args).ToNot(ContainElement(HavePrefix("--platform")))
+			}
+
+			if !tc.expectedRebalanceHandler {
+				g.Expect(containers).To(HaveLen(1))
+				return
+			}
+			g.Expect(containers).To(HaveLen(2))
+			g.Expect(containers[1].Name).To(Equal("rebalance-recommendation-handler"))
+			g.Expect(containers[1].Image).To(Equal("mao-image"))
+			g.Expect(containers[1].Args).To(ContainElements("--platform="+string(tc.platform), "--rebalance-recommendation-only"))
+		})
+	}
+}
+
+func TestEnsureDaemonSetDependecyAnnotations(t *testing.T) {
+	g := NewWithT(t)
+
+	config := &OperatorConfig{
+		TargetNamespace: targetNamespace,
+		Proxy:           &v1.Proxy{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}},
+	}
+	daemonSet := newTerminationDaemonSet(config)
+	ensureDaemonSetDependecyAnnotations(map[string]string{"dep-1": "dep-1-state-1"}, daemonSet)
+
+	g.Expect(daemonSet.Annotations).To(HaveKeyWithValue("operator.openshift.io/dep-dep-1", "dep-1-state-1"))
+	g.Expect(daemonSet.Spec.Template.Annotations).To(HaveKeyWithValue("operator.openshift.io/dep-dep-1", "dep-1-state-1"))
+	g.Expect(daemonSet.Spec.Template.Annotations).To(HaveKey("target.workload.openshift.io/management"))
+
+	// The annotations shared by the pod templates are left untouched.
+	g.Expect(commonPodTemplateAnnotations).ToNot(HaveKey("operator.openshift.io/dep-dep-1"))
+}
+
+func TestOperandReplicas(t *testing.T) {
+	testCases := []struct {
+		name              string
+		replicas          *int32
+		expectedReplicas  int32
+		expectSpread      bool
+		expectDisruptions bool
+	}{
+		{
+			name:             "with the default replicas",
+			expectedReplicas: 1,
+		},
+		{
+			name:             "with a single replica",
+			replicas:         pointer.Int32(1),
+			expectedReplicas: 1,
+		},
+		{
+			name:              "with several replicas",
+			replicas:          pointer.Int32(3),
+			expectedReplicas:  3,
+			expectSpread:      true,
+			expectDisruptions: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			// A budget left over from several replicas is removed when scaling down.
+			existing := newPodDisruptionBudget(&OperatorConfig{TargetNamespace: targetNamespace})
+			optr, err := newFakeOperator([]runtime.Object{existing}, nil, nil, "", stopCh)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			config := &OperatorConfig{TargetNamespace: targetNamespace, Replicas: tc.replicas}
+			deployment := newDeployment(config, nil)
+			g.Expect(deployment.Spec.Replicas).To(HaveValue(Equal(tc.expectedReplicas)))
+			if tc.expectSpread {
+				g.Expect(deployment.Spec.Template.Spec.TopologySpreadConstraints).To(ConsistOf(
+					HaveField("TopologyKey", corev1.LabelTopologyZone),
+					HaveField("TopologyKey", corev1.LabelHostname),
+				))
+			} else {
+				g.Expect(deployment.Spec.Template.Spec.TopologySpreadConstraints).To(BeEmpty())
+			}
+
+			g.Expect(optr.syncPodDisruptionBudget(config)).To(Succeed())
+			pdb, err := optr.kubeClient.PolicyV1().PodDisruptionBudgets(targetNamespace).Get(context.Background(), "machine-api-controllers", metav1.GetOptions{})
+			if tc.expectDisruptions {
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(pdb.Spec.MaxUnavailable).To(HaveValue(Equal(intstr.FromInt(1))))
+				g.Expect(pdb.Spec.Selector.MatchLabels).To(Equal(deployment.Spec.Selector.MatchLabels))
+			} else {
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), "expected the pod disruption budget to be deleted")
+			}
+		})
+	}
+}
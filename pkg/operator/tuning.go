@@ -0,0 +1,158 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/pointer"
+	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// tuningConfigMapName is the name of the ConfigMap in the target namespace which overrides
+	// the resources and GOMAXPROCS of the operand containers.
+	tuningConfigMapName = "machine-api-tuning-config"
+
+	// tuningContainersKey holds the tuning of the operand containers as YAML or JSON, keyed by container name.
+	tuningContainersKey = "containers"
+
+	// tuningReplicasKey holds the number of replicas of the machine-api-controllers deployment.
+	tuningReplicasKey = "replicas"
+)
+
+// tunableContainers are the operand containers which can be tuned.
+var tunableContainers = []string{
+	"machineset-controller",
+	"machine-controller",
+	"nodelink-controller",
+	"machine-healthcheck-controller",
+	"termination-handler",
+	"rebalance-recommendation-handler",
+}
+
+// containerTuning overrides the resources and GOMAXPROCS of an operand container.
+type containerTuning struct {
+	// Resources are merged into the default requests and limits of the container.
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// GOMAXPROCS limits the number of OS threads executing Go code in the container.
+	GOMAXPROCS *int `json:"gomaxprocs,omitempty"`
+}
+
+// operandTuning is the tuning of the operand containers, keyed by container name.
+type operandTuning map[string]containerTuning
+
+func isTuningConfigMap(obj interface{}) bool {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	return ok && configMap.Name == tuningConfigMapName
+}
+
+// getOperandTuning returns the operand tuning set in the tuning ConfigMap, if any.
+func (optr *Operator) getOperandTuning() (operandTuning, error) {
+	configMap, err := optr.configMapLister.ConfigMaps(optr.namespace).Get(tuningConfigMapName)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not fetch tuning configmap: %v", err)
+	}
+	return parseOperandTuning(configMap)
+}
+
+func parseOperandTuning(configMap *corev1.ConfigMap) (operandTuning, error) {
+	value, ok := configMap.Data[tuningContainersKey]
+	if !ok {
+		return nil, nil
+	}
+
+	tuning := operandTuning{}
+	if err := yaml.UnmarshalStrict([]byte(value), &tuning); err != nil {
+		return nil, fmt.Errorf("configmap %s: invalid %s: %v", tuningConfigMapName, tuningContainersKey, err)
+	}
+
+	names := make([]string, 0, len(tuning))
+	for name := range tuning {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !slices.Contains(tunableContainers, name) {
+			return nil, fmt.Errorf("configmap %s: unknown container %q, must be one of %v", tuningConfigMapName, name, tunableContainers)
+		}
+		if gomaxprocs := tuning[name].GOMAXPROCS; gomaxprocs != nil && *gomaxprocs < 1 {
+			return nil, fmt.Errorf("configmap %s: invalid gomaxprocs %d for container %s, must be at least 1", tuningConfigMapName, *gomaxprocs, name)
+		}
+		resources := tuning[name].mergeResources(defaultOperandResources())
+		for resourceName, limit := range resources.Limits {
+			if request, ok := resources.Requests[resourceName]; ok && request.Cmp(limit) > 0 {
+				return nil, fmt.Errorf("configmap %s: %s request %s of container %s exceeds its limit %s",
+					tuningConfigMapName, resourceName, request.String(), name, limit.String())
+			}
+		}
+	}
+	return tuning, nil
+}
+
+// getOperandReplicas returns the number of replicas of the operand deployment set in the tuning ConfigMap, if any.
+func (optr *Operator) getOperandReplicas() (*int32, error) {
+	configMap, err := optr.configMapLister.ConfigMaps(optr.namespace).Get(tuningConfigMapName)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not fetch tuning configmap: %v", err)
+	}
+	return parseOperandReplicas(configMap)
+}
+
+func parseOperandReplicas(configMap *corev1.ConfigMap) (*int32, error) {
+	value, ok := configMap.Data[tuningReplicasKey]
+	if !ok {
+		return nil, nil
+	}
+
+	replicas, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || replicas < 1 {
+		return nil, fmt.Errorf("configmap %s: invalid %s %q, must be a number of at least 1", tuningConfigMapName, tuningReplicasKey, value)
+	}
+	return pointer.Int32(int32(replicas)), nil
+}
+
+// mergeResources returns the default resources of a container with the tuned requests and limits.
+func (t containerTuning) mergeResources(defaults corev1.ResourceRequirements) corev1.ResourceRequirements {
+	resources := defaults.DeepCopy()
+	for name, quantity := range t.Resources.Requests {
+		if resources.Requests == nil {
+			resources.Requests = corev1.ResourceList{}
+		}
+		resources.Requests[name] = quantity
+	}
+	for name, quantity := range t.Resources.Limits {
+		if resources.Limits == nil {
+			resources.Limits = corev1.ResourceList{}
+		}
+		resources.Limits[name] = quantity
+	}
+	return *resources
+}
+
+// apply sets the tuning of the containers. The containers share their default resources and env,
+// which are copied before being changed.
+func (t operandTuning) apply(containers []corev1.Container) {
+	for i := range containers {
+		c := &containers[i]
+		tuning, ok := t[c.Name]
+		if !ok {
+			continue
+		}
+
+		c.Resources = tuning.mergeResources(c.Resources)
+		if tuning.GOMAXPROCS != nil {
+			c.Env = append(append([]corev1.EnvVar{}, c.Env...), corev1.EnvVar{
+				Name:  "GOMAXPROCS",
+				Value: strconv.Itoa(*tuning.GOMAXPROCS),
+			})
+		}
+	}
+}
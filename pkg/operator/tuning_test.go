@@ -0,0 +1,171 @@
+package operator
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestParseOperandTuning(t *testing.T) {
+	testCases := []struct {
+		name           string
+		data           map[string]string
+		expectedTuning operandTuning
+		expectedError  string
+	}{
+		{
+			name: "without containers",
+			data: map[string]string{},
+		},
+		{
+			name: "with resources and GOMAXPROCS",
+			data: map[string]string{tuningContainersKey: `
+machine-controller:
+  resources:
+    requests:
+      cpu: 50m
+    limits:
+      memory: 500Mi
+  gomaxprocs: 4
+`},
+			expectedTuning: operandTuning{
+				"machine-controller": {
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m")},
+						Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("500Mi")},
+					},
+					GOMAXPROCS: pointer.Int(4),
+				},
+			},
+		},
+		{
+			name:          "with an unknown container",
+			data:          map[string]string{tuningContainersKey: `kube-apiserver: {gomaxprocs: 1}`},
+			expectedError: `configmap machine-api-tuning-config: unknown container "kube-apiserver", must be one of [machineset-controller machine-controller nodelink-controller machine-healthcheck-controller termination-handler rebalance-recommendation-handler]`,
+		},
+		{
+			name:          "with an unknown field",
+			data:          map[string]string{tuningContainersKey: `machine-controller: {cpu: 1}`},
+			expectedError: `configmap machine-api-tuning-config: invalid containers: error unmarshaling JSON: while decoding JSON: json: unknown field "cpu"`,
+		},
+		{
+			name:          "with an invalid GOMAXPROCS",
+			data:          map[string]string{tuningContainersKey: `termination-handler: {gomaxprocs: 0}`},
+			expectedError: "configmap machine-api-tuning-config: invalid gomaxprocs 0 for container termination-handler, must be at least 1",
+		},
+		{
+			name:          "with a limit below the default request",
+			data:          map[string]string{tuningContainersKey: `nodelink-controller: {resources: {limits: {memory: 10Mi}}}`},
+			expectedError: "configmap machine-api-tuning-config: memory request 20Mi of container nodelink-controller exceeds its limit 10Mi",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			tuning, err := parseOperandTuning(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: tuningConfigMapName, Namespace: targetNamespace},
+				Data:       tc.data,
+			})
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(tuning).To(Equal(tc.expectedTuning))
+		})
+	}
+}
+
+func TestOperandTuningApply(t *testing.T) {
+	g := NewWithT(t)
+
+	config := &OperatorConfig{
+		TargetNamespace: targetNamespace,
+		Proxy:           &configv1.Proxy{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}},
+		Tuning: operandTuning{
+			"machine-controller": {
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m")},
+					Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("500Mi")},
+				},
+				GOMAXPROCS: pointer.Int(4),
+			},
+			"termination-handler": {GOMAXPROCS: pointer.Int(1)},
+		},
+	}
+
+	containers := map[string]corev1.Container{}
+	for _, c := range newContainers(config, nil) {
+		containers[c.Name] = c
+	}
+
+	g.Expect(containers["machine-controller"].Resources).To(Equal(corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("50m"),
+			corev1.ResourceMemory: resource.MustParse("20Mi"),
+		},
+		Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("500Mi")},
+	}))
+	g.Expect(containers["machine-controller"].Env).To(ContainElement(corev1.EnvVar{Name: "GOMAXPROCS", Value: "4"}))
+
+	// The other containers keep their defaults.
+	g.Expect(containers["machineset-controller"].Resources).To(Equal(defaultOperandResources()))
+	g.Expect(containers["machineset-controller"].Env).ToNot(ContainElement(HaveField("Name", "GOMAXPROCS")))
+
+	terminationContainers := newTerminationContainers(config)
+	g.Expect(terminationContainers[0].Resources).To(Equal(defaultOperandResources()))
+	g.Expect(terminationContainers[0].Env).To(ContainElement(corev1.EnvVar{Name: "GOMAXPROCS", Value: "1"}))
+}
+
+func TestParseOperandReplicas(t *testing.T) {
+	testCases := []struct {
+		name             string
+		data             map[string]string
+		expectedReplicas *int32
+		expectedError    string
+	}{
+		{
+			name: "without replicas",
+			data: map[string]string{},
+		},
+		{
+			name:             "with replicas",
+			data:             map[string]string{tuningReplicasKey: "3"},
+			expectedReplicas: pointer.Int32(3),
+		},
+		{
+			name:          "with no replicas",
+			data:          map[string]string{tuningReplicasKey: "0"},
+			expectedError: `configmap machine-api-tuning-config: invalid replicas "0", must be a number of at least 1`,
+		},
+		{
+			name:          "with invalid replicas",
+			data:          map[string]string{tuningReplicasKey: "two"},
+			expectedError: `configmap machine-api-tuning-config: invalid replicas "two", must be a number of at least 1`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			replicas, err := parseOperandReplicas(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: tuningConfigMapName, Namespace: targetNamespace},
+				Data:       tc.data,
+			})
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(replicas).To(Equal(tc.expectedReplicas))
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package operator
+
+import (
+	"fmt"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// webhookConfigMapName is the name of the ConfigMap in the target namespace which overrides
+	// the failurePolicy and namespaceSelector of the webhook configurations managed by the operator.
+	// It allows the webhooks to be bypassed, e.g. during disaster recovery.
+	webhookConfigMapName = "machine-api-webhook-config"
+
+	// webhookFailurePolicyKey holds the failurePolicy of the webhooks: Fail or Ignore.
+	webhookFailurePolicyKey = "failurePolicy"
+	// webhookNamespaceSelectorKey holds the namespaceSelector of the webhooks as a YAML or JSON label selector.
+	webhookNamespaceSelectorKey = "namespaceSelector"
+)
+
+// webhookOptions are the overrides of the webhook configurations managed by the operator.
+type webhookOptions struct {
+	failurePolicy     *admissionregistrationv1.FailurePolicyType
+	namespaceSelector *metav1.LabelSelector
+	// hosted points the webhooks at the management cluster in the hosted control plane mode.
+	hosted *hostedWebhookOptions
+}
+
+func isWebhookConfigMap(obj interface{}) bool {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	return ok && configMap.Name == webhookConfigMapName
+}
+
+// getWebhookOptions returns the webhook overrides set in the webhook ConfigMap, if any.
+func (optr *Operator) getWebhookOptions() (webhookOptions, error) {
+	options := webhookOptions{}
+	configMap, err := optr.configMapLister.ConfigMaps(optr.namespace).Get(webhookConfigMapName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return webhookOptions{}, fmt.Errorf("could not fetch webhook configmap: %v", err)
+	} else if err == nil {
+		if options, err = parseWebhookOptions(configMap); err != nil {
+			return webhookOptions{}, err
+		}
+	}
+
+	if optr.hostedControlPlane != nil {
+		caBundle, err := optr.getHostedWebhookCABundle()
+		if err != nil {
+			return webhookOptions{}, err
+		}
+		options.hosted = &hostedWebhookOptions{serviceNamespace: optr.namespace, caBundle: caBundle}
+	}
+	return options, nil
+}
+
+func parseWebhookOptions(configMap *corev1.ConfigMap) (webhookOptions, error) {
+	options := webhookOptions{}
+
+	if value, ok := configMap.Data[webhookFailurePolicyKey]; ok {
+		failurePolicy := admissionregistrationv1.FailurePolicyType(value)
+		if failurePolicy != admissionregistrationv1.Fail && failurePolicy != admissionregistrationv1.Ignore {
+			return webhookOptions{}, fmt.Errorf("configmap %s: invalid %s %q, must be %q or %q",
+				webhookConfigMapName, webhookFailurePolicyKey, value, admissionregistrationv1.Fail, admissionregistrationv1.Ignore)
+		}
+		options.failurePolicy = &failurePolicy
+	}
+
+	if value, ok := configMap.Data[webhookNamespaceSelectorKey]; ok {
+		namespaceSelector := &metav1.LabelSelector{}
+		if err := yaml.UnmarshalStrict([]byte(value), namespaceSelector); err != nil {
+			return webhookOptions{}, fmt.Errorf("configmap %s: invalid %s: %v", webhookConfigMapName, webhookNamespaceSelectorKey, err)
+		}
+		if _, err := metav1.LabelSelectorAsSelector(namespaceSelector); err != nil {
+			return webhookOptions{}, fmt.Errorf("configmap %s: invalid %s: %v", webhookConfigMapName, webhookNamespaceSelectorKey, err)
+		}
+		options.namespaceSelector = namespaceSelector
+	}
+
+	return options, nil
+}
+
+// applyToValidatingWebhookConfiguration sets the overrides on all the webhooks of the configuration.
+func (o webhookOptions) applyToValidatingWebhookConfiguration(c *admissionregistrationv1.ValidatingWebhookConfiguration) {
+	if o.hosted != nil {
+		delete(c.Annotations, injectCABundleAnnotation)
+	}
+	for i := range c.Webhooks {
+		if o.hosted != nil {
+			o.hosted.applyToClientConfig(&c.Webhooks[i].ClientConfig)
+		}
+		if o.failurePolicy != nil {
+			c.Webhooks[i].FailurePolicy = o.failurePolicy
+		}
+		if o.namespaceSelector != nil {
+			c.Webhooks[i].NamespaceSelector = o.namespaceSelector.DeepCopy()
+		}
+	}
+}
+
+// applyToMutatingWebhookConfiguration sets the overrides on all the webhooks of the configuration.
+func (o webhookOptions) applyToMutatingWebhookConfiguration(c *admissionregistrationv1.MutatingWebhookConfiguration) {
+	if o.hosted != nil {
+		delete(c.Annotations, injectCABundleAnnotation)
+	}
+	for i := range c.Webhooks {
+		if o.hosted != nil {
+			o.hosted.applyToClientConfig(&c.Webhooks[i].ClientConfig)
+		}
+		if o.failurePolicy != nil {
+			c.Webhooks[i].FailurePolicy = o.failurePolicy
+		}
+		if o.namespaceSelector != nil {
+			c.Webhooks[i].NamespaceSelector = o.namespaceSelector.DeepCopy()
+		}
+	}
+}
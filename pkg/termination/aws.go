@@ -0,0 +1,117 @@
+package termination
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	awsMetadataURL = "http://169.254.169.254/latest"
+
+	// awsTokenPath is the path of the session tokens of IMDSv2.
+	awsTokenPath = "/api/token"
+	// awsInstanceActionPath is the metadata of the two-minute interruption notice of a spot instance.
+	awsInstanceActionPath = "/meta-data/spot/instance-action"
+	// awsRebalancePath is the metadata of the rebalance recommendation of an instance at an elevated risk of interruption.
+	awsRebalancePath = "/meta-data/events/recommendations/rebalance"
+
+	awsTokenTTLSeconds = "21600"
+)
+
+// awsInstanceAction is the interruption notice of a spot instance.
+type awsInstanceAction struct {
+	// Action is either terminate, stop or hibernate.
+	Action string    `json:"action"`
+	Time   time.Time `json:"time"`
+}
+
+// awsPoller polls the EC2 instance metadata service for the interruption notice and the rebalance
+// recommendation of a spot instance.
+type awsPoller struct {
+	client *http.Client
+	url    string
+	token  string
+}
+
+func newAWSPoller() *awsPoller {
+	return &awsPoller{
+		client: &http.Client{Timeout: metadataTimeout},
+		url:    awsMetadataURL,
+	}
+}
+
+// Poll returns the notice once the spot instance is about to be interrupted, two minutes before the interruption.
+func (p *awsPoller) Poll(ctx context.Context) (*Notice, error) {
+	body, err := p.get(ctx, awsInstanceActionPath)
+	if err != nil {
+		if errors.Is(err, errMetadataNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	action := awsInstanceAction{}
+	if err := json.Unmarshal(body, &action); err != nil {
+		return nil, fmt.Errorf("error decoding the instance action: %w", err)
+	}
+	return &Notice{Deadline: action.Time}, nil
+}
+
+// PollRebalanceRecommendation returns true when EC2 recommends to rebalance the workloads off the spot instance.
+func (p *awsPoller) PollRebalanceRecommendation(ctx context.Context) (bool, error) {
+	_, err := p.get(ctx, awsRebalancePath)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, errMetadataNotFound):
+		// The recommendation is not found until it is issued.
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// get gets the metadata with a session token of IMDSv2, getting a new token when the token expired.
+func (p *awsPoller) get(ctx context.Context, path string) ([]byte, error) {
+	if p.token == "" {
+		if err := p.refreshToken(ctx); err != nil {
+			return nil, err
+		}
+	}
+	body, err := p.getWithToken(ctx, path)
+	if errors.Is(err, errMetadataUnauthorized) {
+		if err := p.refreshToken(ctx); err != nil {
+			return nil, err
+		}
+		return p.getWithToken(ctx, path)
+	}
+	return body, err
+}
+
+func (p *awsPoller) getWithToken(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", p.token)
+	return doMetadataRequest(p.client, req)
+}
+
+func (p *awsPoller) refreshToken(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, p.url+awsTokenPath, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", awsTokenTTLSeconds)
+
+	token, err := doMetadataRequest(p.client, req)
+	if err != nil {
+		return err
+	}
+	p.token = strings.TrimSpace(string(token))
+	return nil
+}
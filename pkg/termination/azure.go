@@ -0,0 +1,99 @@
+package termination
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	azureScheduledEventsURL = "http://169.254.169.254/metadata/scheduledevents?api-version=2020-07-01"
+	azureInstanceNameURL    = "http://169.254.169.254/metadata/instance/compute/name?api-version=2020-09-01&format=text"
+)
+
+// azureTerminationEvents are the types of the scheduled events after which the virtual machine is gone:
+// the eviction of a spot virtual machine and the deletion of a virtual machine of a scale set.
+var azureTerminationEvents = map[string]bool{
+	"Preempt":   true,
+	"Terminate": true,
+}
+
+// azureScheduledEvents is the response of the Azure Scheduled Events service.
+type azureScheduledEvents struct {
+	Events []azureScheduledEvent `json:"Events"`
+}
+
+type azureScheduledEvent struct {
+	EventType string   `json:"EventType"`
+	Resources []string `json:"Resources"`
+	// NotBefore is the time after which the event starts, in the RFC 1123 format, empty once it started.
+	NotBefore string `json:"NotBefore"`
+}
+
+// azurePoller polls the Azure Scheduled Events of the virtual machine for its eviction.
+type azurePoller struct {
+	client          *http.Client
+	eventsURL       string
+	instanceNameURL string
+	instanceName    string
+}
+
+func newAzurePoller() *azurePoller {
+	return &azurePoller{
+		client:          &http.Client{Timeout: metadataTimeout},
+		eventsURL:       azureScheduledEventsURL,
+		instanceNameURL: azureInstanceNameURL,
+	}
+}
+
+// Poll returns the notice once an event terminating the virtual machine is scheduled.
+func (p *azurePoller) Poll(ctx context.Context) (*Notice, error) {
+	if p.instanceName == "" {
+		name, err := p.get(ctx, p.instanceNameURL)
+		if err != nil {
+			return nil, fmt.Errorf("error getting the name of the virtual machine: %w", err)
+		}
+		p.instanceName = strings.TrimSpace(string(name))
+	}
+
+	body, err := p.get(ctx, p.eventsURL)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the scheduled events: %w", err)
+	}
+	events := azureScheduledEvents{}
+	if err := json.Unmarshal(body, &events); err != nil {
+		return nil, fmt.Errorf("error decoding the scheduled events: %w", err)
+	}
+
+	for _, event := range events.Events {
+		if !azureTerminationEvents[event.EventType] {
+			continue
+		}
+		for _, resource := range event.Resources {
+			if strings.EqualFold(resource, p.instanceName) {
+				return &Notice{Deadline: event.deadline()}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// deadline returns the time at which the event starts, now if it started or its time is invalid.
+func (e azureScheduledEvent) deadline() time.Time {
+	if notBefore, err := time.Parse(time.RFC1123, e.NotBefore); err == nil {
+		return notBefore
+	}
+	return time.Now()
+}
+
+func (p *azurePoller) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+	return doMetadataRequest(p.client, req)
+}
@@ -0,0 +1,47 @@
+package termination
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// gcpPreemptedURL is the metadata of a GCP instance which is TRUE once the instance is preempted.
+	gcpPreemptedURL = "http://metadata.google.internal/computeMetadata/v1/instance/preempted"
+
+	// gcpPreemptionNotice is the time between the preemption notice and the termination of the instance.
+	gcpPreemptionNotice = 30 * time.Second
+)
+
+// gcpPoller polls the GCP metadata server for the preemption of the instance.
+type gcpPoller struct {
+	client *http.Client
+	url    string
+}
+
+func newGCPPoller() *gcpPoller {
+	return &gcpPoller{
+		client: &http.Client{Timeout: metadataTimeout},
+		url:    gcpPreemptedURL,
+	}
+}
+
+// Poll returns the notice once the instance has been preempted, the instance is terminated 30 seconds later.
+func (p *gcpPoller) Poll(ctx context.Context) (*Notice, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	body, err := doMetadataRequest(p.client, req)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(string(body)) != "TRUE" {
+		return nil, nil
+	}
+	return &Notice{Deadline: time.Now().Add(gcpPreemptionNotice)}, nil
+}
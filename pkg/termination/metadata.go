@@ -0,0 +1,42 @@
+package termination
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const metadataTimeout = 5 * time.Second
+
+var (
+	// errMetadataNotFound is returned when the metadata service has no such metadata, e.g. no notice yet.
+	errMetadataNotFound = errors.New("metadata not found")
+	// errMetadataUnauthorized is returned when the metadata service rejects the credentials, e.g. an expired token.
+	errMetadataUnauthorized = errors.New("unauthorized by the metadata service")
+)
+
+// doMetadataRequest returns the body of the response of the metadata service to the request.
+func doMetadataRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+		return body, nil
+	case http.StatusNotFound:
+		return nil, errMetadataNotFound
+	case http.StatusUnauthorized:
+		return nil, errMetadataUnauthorized
+	default:
+		return nil, fmt.Errorf("unexpected status %s from %s", res.Status, req.URL)
+	}
+}
@@ -0,0 +1,247 @@
+// Package termination watches the instance metadata service of the cloud provider for the notice of the
+// termination of a spot or preemptible instance, and marks the node of the instance as terminating, so that
+// its workloads are drained before the instance is reclaimed.
+package termination
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// NodeConditionTerminating is the type of the condition of the node set when its instance is about to be terminated.
+	NodeConditionTerminating corev1.NodeConditionType = "Terminating"
+
+	// TerminationRequestedReason is the reason of the Terminating condition set by the termination handler.
+	TerminationRequestedReason = "TerminationRequested"
+
+	// TerminatingTaintKey is the key of the taint added to the nodes with the Terminating condition,
+	// so that no new pods are scheduled on them while they are drained.
+	TerminatingTaintKey = "machine.openshift.io/terminating"
+
+	// NodeConditionRebalanceRecommended is the type of the condition of the node set when the cloud provider
+	// recommends to move the workloads off its instance, which is at an elevated risk of interruption.
+	NodeConditionRebalanceRecommended corev1.NodeConditionType = "RebalanceRecommended"
+
+	// RebalanceRecommendationReason is the reason of the RebalanceRecommended condition set by the termination handler.
+	RebalanceRecommendationReason = "RebalanceRecommendation"
+
+	// MachineConditionTerminating is the type of the condition of the machine set by the machine controller when the
+	// node of the machine has the Terminating condition.
+	MachineConditionTerminating machinev1.ConditionType = "Terminating"
+
+	// InterruptionReceivedReason is the reason of the Terminating condition of the machine, and of the event
+	// recorded on the machine with the deadline of the termination.
+	InterruptionReceivedReason = "InterruptionReceived"
+
+	// MachineAnnotationKey is the annotation of the node with the namespace and name of its machine, set by
+	// the nodelink controller.
+	MachineAnnotationKey = "machine.openshift.io/machine"
+
+	// RebalanceRecommendationPolicyAnnotation is the annotation of a MachineSet setting how the nodes of its
+	// machines with the RebalanceRecommended condition are handled, see RebalanceRecommendationPolicy.
+	RebalanceRecommendationPolicyAnnotation = "machine.openshift.io/rebalance-recommendation-policy"
+)
+
+// RebalanceRecommendationPolicy is how the nodes with the RebalanceRecommended condition are handled.
+type RebalanceRecommendationPolicy string
+
+const (
+	// RebalanceRecommendationPolicyIgnore leaves the node alone until its instance is interrupted, the default.
+	RebalanceRecommendationPolicyIgnore RebalanceRecommendationPolicy = "Ignore"
+	// RebalanceRecommendationPolicyCordon cordons the node, so that no new pods are scheduled on it.
+	RebalanceRecommendationPolicyCordon RebalanceRecommendationPolicy = "Cordon"
+	// RebalanceRecommendationPolicyDrain cordons the node and deletes its machine, which drains the node
+	// and lets the MachineSet replace it long before the instance is interrupted.
+	RebalanceRecommendationPolicyDrain RebalanceRecommendationPolicy = "Drain"
+)
+
+// Notice is the notice of the termination of the instance.
+type Notice struct {
+	// Deadline is the time at which the instance is terminated.
+	Deadline time.Time
+}
+
+// Poller polls the instance metadata service for the notice of the termination of the instance.
+type Poller interface {
+	// Poll returns the termination notice once the instance is about to be terminated, nil until then.
+	Poll(ctx context.Context) (*Notice, error)
+}
+
+// RebalancePoller is implemented by the pollers of the platforms recommending to rebalance the workloads off
+// an instance at an elevated risk of interruption, ahead of the termination notice.
+type RebalancePoller interface {
+	// PollRebalanceRecommendation returns true when the rebalance of the workloads of the instance is recommended.
+	PollRebalanceRecommendation(ctx context.Context) (bool, error)
+}
+
+// NewPoller returns the poller of the termination notices of the platform.
+func NewPoller(platform configv1.PlatformType) (Poller, error) {
+	switch platform {
+	case configv1.AWSPlatformType:
+		return newAWSPoller(), nil
+	case configv1.GCPPlatformType:
+		return newGCPPoller(), nil
+	case configv1.AzurePlatformType:
+		return newAzurePoller(), nil
+	default:
+		return nil, fmt.Errorf("termination notices are not supported on platform %q", platform)
+	}
+}
+
+// NewRebalanceRecommendationPoller returns a poller of the rebalance recommendations of the platform, which never
+// reports a termination notice, for the platforms whose termination notices are handled by the provider.
+func NewRebalanceRecommendationPoller(platform configv1.PlatformType) (Poller, error) {
+	poller, err := NewPoller(platform)
+	if err != nil {
+		return nil, err
+	}
+	rebalancePoller, ok := poller.(RebalancePoller)
+	if !ok {
+		return nil, fmt.Errorf("rebalance recommendations are not supported on platform %q", platform)
+	}
+	return rebalanceRecommendationPoller{rebalancePoller}, nil
+}
+
+// rebalanceRecommendationPoller only polls for the rebalance recommendations.
+type rebalanceRecommendationPoller struct {
+	RebalancePoller
+}
+
+// Poll never returns a termination notice, the termination notices are handled by the termination handler of the provider.
+func (rebalanceRecommendationPoller) Poll(ctx context.Context) (*Notice, error) {
+	return nil, nil
+}
+
+// Handler marks the node as terminating once the poller reports the termination of its instance.
+type Handler struct {
+	client       kubernetes.Interface
+	poller       Poller
+	nodeName     string
+	pollInterval time.Duration
+}
+
+// NewHandler returns a handler marking the node when the poller reports the termination of its instance,
+// polling at the interval.
+func NewHandler(client kubernetes.Interface, poller Poller, nodeName string, pollInterval time.Duration) *Handler {
+	return &Handler{
+		client:       client,
+		poller:       poller,
+		nodeName:     nodeName,
+		pollInterval: pollInterval,
+	}
+}
+
+// Run polls for the termination notice until the node is marked as terminating or the context is done.
+// When the poller is a RebalancePoller, the node is also marked once the rebalance is recommended.
+// The errors of the poller are logged and the polling continues, the metadata service may be briefly unavailable.
+func (h *Handler) Run(ctx context.Context) error {
+	klog.Infof("Polling for the termination notice of the instance of node %q every %v", h.nodeName, h.pollInterval)
+	rebalancePoller, _ := h.poller.(RebalancePoller)
+	var notice *Notice
+	err := wait.PollImmediateUntilWithContext(ctx, h.pollInterval, func(ctx context.Context) (bool, error) {
+		if rebalancePoller != nil {
+			recommended, err := rebalancePoller.PollRebalanceRecommendation(ctx)
+			if err != nil {
+				klog.Errorf("Error polling for the rebalance recommendation: %v", err)
+			} else if recommended {
+				klog.Infof("The rebalance of the workloads of node %q is recommended, marking the node", h.nodeName)
+				if err := h.markNode(ctx, NodeConditionRebalanceRecommended, RebalanceRecommendationReason,
+					"The cloud provider recommends to rebalance the workloads off the instance, which is at an elevated risk of interruption"); err != nil {
+					klog.Errorf("Error marking node %q with the rebalance recommendation: %v", h.nodeName, err)
+				} else {
+					// The node is marked once, the condition is kept until the node is gone.
+					rebalancePoller = nil
+				}
+			}
+		}
+
+		var err error
+		notice, err = h.poller.Poll(ctx)
+		if err != nil {
+			klog.Errorf("Error polling for the termination notice: %v", err)
+			return false, nil
+		}
+		return notice != nil, nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	// The machine controller marks the machine of the node with the deadline from the condition of the node.
+	klog.Infof("The instance of node %q is terminated at %s, marking the node as terminating", h.nodeName, notice.Deadline.Format(time.RFC3339))
+	return wait.ExponentialBackoffWithContext(ctx, wait.Backoff{Duration: time.Second, Factor: 2, Steps: 5}, func() (bool, error) {
+		if err := h.markNode(ctx, NodeConditionTerminating, TerminationRequestedReason,
+			fmt.Sprintf("The cloud provider terminates the instance at %s", notice.Deadline.Format(time.RFC3339))); err != nil {
+			klog.Errorf("Error marking node %q as terminating: %v", h.nodeName, err)
+			return false, nil
+		}
+		return true, nil
+	})
+}
+
+// markNode sets a condition of the node. The node status is updated with the credentials of the kubelet,
+// which may only update the status of its own node, the node is tainted or cordoned by the nodelink controller.
+func (h *Handler) markNode(ctx context.Context, conditionType corev1.NodeConditionType, reason, message string) error {
+	now := metav1.Now()
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []corev1.NodeCondition{{
+				Type:               conditionType,
+				Status:             corev1.ConditionTrue,
+				Reason:             reason,
+				Message:            message,
+				LastHeartbeatTime:  now,
+				LastTransitionTime: now,
+			}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = h.client.CoreV1().Nodes().PatchStatus(ctx, h.nodeName, patch)
+	return err
+}
+
+// IsNodeTerminating returns true when the node has the Terminating condition.
+func IsNodeTerminating(node *corev1.Node) bool {
+	return hasNodeCondition(node, NodeConditionTerminating)
+}
+
+// GetNodeTerminatingCondition returns the Terminating condition of the node, nil unless it is true.
+// Its message has the deadline of the termination.
+func GetNodeTerminatingCondition(node *corev1.Node) *corev1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if c := &node.Status.Conditions[i]; c.Type == NodeConditionTerminating && c.Status == corev1.ConditionTrue {
+			return c
+		}
+	}
+	return nil
+}
+
+// IsNodeRebalanceRecommended returns true when the node has the RebalanceRecommended condition.
+func IsNodeRebalanceRecommended(node *corev1.Node) bool {
+	return hasNodeCondition(node, NodeConditionRebalanceRecommended)
+}
+
+func hasNodeCondition(node *corev1.Node, conditionType corev1.NodeConditionType) bool {
+	for _, c := range node.Status.Conditions {
+		if c.Type == conditionType {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,305 @@
+package termination
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGCPPoller(t *testing.T) {
+	testCases := []struct {
+		name                string
+		preempted           string
+		status              int
+		expectedTerminating bool
+		expectedError       bool
+	}{
+		{
+			name:      "when the instance is not preempted",
+			preempted: "FALSE",
+			status:    http.StatusOK,
+		},
+		{
+			name:                "when the instance is preempted",
+			preempted:           "TRUE",
+			status:              http.StatusOK,
+			expectedTerminating: true,
+		},
+		{
+			name:          "when the metadata server fails",
+			status:        http.StatusInternalServerError,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Metadata-Flavor") != "Google" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.preempted))
+			}))
+			defer server.Close()
+
+			poller := newGCPPoller()
+			poller.url = server.URL
+
+			notice, err := poller.Poll(context.Background())
+			if tc.expectedError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(notice != nil).To(Equal(tc.expectedTerminating))
+			if notice != nil {
+				g.Expect(notice.Deadline).To(BeTemporally("~", time.Now().Add(gcpPreemptionNotice), time.Second))
+			}
+		})
+	}
+}
+
+func TestAzurePoller(t *testing.T) {
+	testCases := []struct {
+		name                string
+		events              string
+		expectedTerminating bool
+		expectedDeadline    time.Time
+		expectedError       bool
+	}{
+		{
+			name:   "without scheduled events",
+			events: `{"DocumentIncarnation":0,"Events":[]}`,
+		},
+		{
+			name:                "with the preemption of the virtual machine",
+			events:              `{"DocumentIncarnation":1,"Events":[{"EventId":"1","EventType":"Preempt","ResourceType":"VirtualMachine","Resources":["worker-spot-1"],"EventStatus":"Scheduled","NotBefore":"Fri, 16 Oct 2026 08:00:30 GMT"}]}`,
+			expectedTerminating: true,
+			expectedDeadline:    time.Date(2026, 10, 16, 8, 0, 30, 0, time.UTC),
+		},
+		{
+			name:   "with the preemption of another virtual machine",
+			events: `{"DocumentIncarnation":1,"Events":[{"EventId":"1","EventType":"Preempt","ResourceType":"VirtualMachine","Resources":["worker-spot-2"],"EventStatus":"Scheduled"}]}`,
+		},
+		{
+			name:   "with the reboot of the virtual machine",
+			events: `{"DocumentIncarnation":1,"Events":[{"EventId":"1","EventType":"Reboot","ResourceType":"VirtualMachine","Resources":["worker-spot-1"],"EventStatus":"Scheduled"}]}`,
+		},
+		{
+			name:          "with invalid scheduled events",
+			events:        `{`,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/name", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("worker-spot-1\n"))
+			})
+			mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Metadata") != "true" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				_, _ = w.Write([]byte(tc.events))
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			poller := newAzurePoller()
+			poller.instanceNameURL = server.URL + "/name"
+			poller.eventsURL = server.URL + "/events"
+
+			notice, err := poller.Poll(context.Background())
+			if tc.expectedError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(notice != nil).To(Equal(tc.expectedTerminating))
+			if notice != nil {
+				g.Expect(notice.Deadline).To(BeTemporally("==", tc.expectedDeadline))
+			}
+			g.Expect(poller.instanceName).To(Equal("worker-spot-1"))
+		})
+	}
+}
+
+func TestAWSPoller(t *testing.T) {
+	testCases := []struct {
+		name                string
+		instanceAction      bool
+		rebalance           bool
+		expectedTerminating bool
+		expectedRebalance   bool
+	}{
+		{
+			name: "without notices",
+		},
+		{
+			name:              "with a rebalance recommendation",
+			rebalance:         true,
+			expectedRebalance: true,
+		},
+		{
+			name:                "with an interruption notice",
+			instanceAction:      true,
+			rebalance:           true,
+			expectedTerminating: true,
+			expectedRebalance:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			tokens := 0
+			metadata := func(exists bool, body string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					// The first token expires, so that the poller gets a new one.
+					if r.Header.Get("X-aws-ec2-metadata-token") != "token-2" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					if !exists {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					_, _ = w.Write([]byte(body))
+				}
+			}
+			mux := http.NewServeMux()
+			mux.HandleFunc(awsTokenPath, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPut || r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				tokens++
+				_, _ = w.Write([]byte(fmt.Sprintf("token-%d", tokens)))
+			})
+			mux.HandleFunc(awsInstanceActionPath, metadata(tc.instanceAction, `{"action": "terminate", "time": "2026-10-16T08:22:00Z"}`))
+			mux.HandleFunc(awsRebalancePath, metadata(tc.rebalance, `{"noticeTime": "2026-10-16T08:00:00Z"}`))
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			poller := newAWSPoller()
+			poller.url = server.URL
+
+			recommended, err := poller.PollRebalanceRecommendation(context.Background())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(recommended).To(Equal(tc.expectedRebalance))
+
+			notice, err := poller.Poll(context.Background())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(notice != nil).To(Equal(tc.expectedTerminating))
+			if notice != nil {
+				g.Expect(notice.Deadline).To(BeTemporally("==", time.Date(2026, 10, 16, 8, 22, 0, 0, time.UTC)))
+			}
+			g.Expect(tokens).To(Equal(2))
+		})
+	}
+}
+
+type fakeRebalancePoller struct {
+	fakePoller
+}
+
+func (p *fakeRebalancePoller) PollRebalanceRecommendation(context.Context) (bool, error) {
+	return true, nil
+}
+
+type fakePoller struct {
+	polls int
+}
+
+func (p *fakePoller) Poll(context.Context) (*Notice, error) {
+	p.polls++
+	if p.polls < 2 {
+		return nil, nil
+	}
+	return &Notice{Deadline: time.Date(2026, 10, 16, 8, 22, 0, 0, time.UTC)}, nil
+}
+
+func TestHandlerRun(t *testing.T) {
+	g := NewWithT(t)
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-spot-1"},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	client := fake.NewSimpleClientset(node)
+	poller := &fakePoller{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	g.Expect(NewHandler(client, poller, node.Name, 10*time.Millisecond).Run(ctx)).To(Succeed())
+	g.Expect(poller.polls).To(Equal(2))
+
+	updated, err := client.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(IsNodeTerminating(updated)).To(BeTrue())
+	g.Expect(IsNodeRebalanceRecommended(updated)).To(BeFalse())
+	// The deadline is reported on the node, the machine controller copies it onto the machine.
+	g.Expect(GetNodeTerminatingCondition(updated)).To(HaveField("Message", ContainSubstring("2026-10-16T08:22:00Z")))
+	// The other conditions of the node are kept.
+	g.Expect(updated.Status.Conditions).To(ContainElement(HaveField("Type", corev1.NodeReady)))
+}
+
+func TestHandlerRunWithRebalanceRecommendation(t *testing.T) {
+	g := NewWithT(t)
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-spot-1"}}
+	client := fake.NewSimpleClientset(node)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	g.Expect(NewHandler(client, &fakeRebalancePoller{}, node.Name, 10*time.Millisecond).Run(ctx)).To(Succeed())
+
+	updated, err := client.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(IsNodeRebalanceRecommended(updated)).To(BeTrue())
+	g.Expect(IsNodeTerminating(updated)).To(BeTrue())
+}
+
+func TestHandlerRunWithRebalanceRecommendationOnly(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := NewRebalanceRecommendationPoller(configv1.GCPPlatformType)
+	g.Expect(err).To(MatchError(`rebalance recommendations are not supported on platform "GCP"`))
+	poller, err := NewRebalanceRecommendationPoller(configv1.AWSPlatformType)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(poller).To(BeAssignableToTypeOf(rebalanceRecommendationPoller{}))
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-spot-1"}}
+	client := fake.NewSimpleClientset(node)
+
+	// The handler polls until the context is done, the termination notices are left to the provider.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	g.Expect(NewHandler(client, rebalanceRecommendationPoller{&fakeRebalancePoller{}}, node.Name, 10*time.Millisecond).Run(ctx)).To(Succeed())
+
+	updated, err := client.CoreV1().Nodes().Get(context.Background(), node.Name, metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(IsNodeRebalanceRecommended(updated)).To(BeTrue())
+	g.Expect(IsNodeTerminating(updated)).To(BeFalse())
+}
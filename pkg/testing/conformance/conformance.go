@@ -0,0 +1,389 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conformance is a provider-agnostic suite of checks of the Machine API, run against a live cluster.
+// It creates, scales and deletes Machines through MachineSets, and remediates them with MachineHealthChecks,
+// so that the authors of out-of-tree providers can check their providers the same way as the in-tree ones:
+//
+//	func TestConformance(t *testing.T) {
+//		c, err := conformance.NewClient()
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		conformance.Run(t, conformance.Config{Client: c})
+//	}
+//
+// The Machines are created from the template of an existing MachineSet, which must be able to create running
+// Machines in the cluster. They are deleted at the end of each scenario.
+//
+// The scenarios follow the flows of the Machine API operator controllers: the nodelink controller propagating
+// the labels and taints of the Machines to their Nodes, the MachineSet controller deleting the Machines
+// annotated by the cluster autoscaler first, the machine controller honouring the lifecycle hooks while
+// draining and deleting a Machine, and the MachineHealthCheck controller recording its remediations.
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const (
+	// DefaultNamespace is the namespace of the Machine API resources.
+	DefaultNamespace = "openshift-machine-api"
+
+	// DefaultTimeout is the time given to a Machine to be running, or to be deleted.
+	DefaultTimeout = 20 * time.Minute
+
+	// DefaultPollInterval is the interval between two checks of the resources of the cluster.
+	DefaultPollInterval = 10 * time.Second
+
+	// MachineSetLabel is the label of the Machines of the MachineSets created by the suite, set to the name of their MachineSet.
+	MachineSetLabel = "machine.openshift.io/conformance-machineset"
+
+	// NodeLabel is the label of the Machines of the MachineSets created by the suite, propagated to their Nodes.
+	NodeLabel = "machine.openshift.io/conformance"
+
+	// NodeTaintKey is the key of the taint of the Machines of the MachineSets created by the suite, propagated
+	// to their Nodes. Its effect is PreferNoSchedule, so that the workloads of the cluster can still run on them.
+	NodeTaintKey = "machine.openshift.io/conformance"
+
+	machineSetLabel   = "machine.openshift.io/cluster-api-machineset"
+	machineRoleLabel  = "machine.openshift.io/cluster-api-machine-role"
+	machineWorkerRole = "worker"
+)
+
+// Config configures the suite.
+type Config struct {
+	// Client is the client of the cluster under test.
+	Client client.Client
+
+	// Namespace is the namespace of the Machine API resources, DefaultNamespace by default.
+	Namespace string
+
+	// MachineSet is the name of the MachineSet whose template is copied into the MachineSets created
+	// by the suite. By default, the first worker MachineSet of the namespace is used.
+	MachineSet string
+
+	// Timeout is the time given to a Machine to be running, or to be deleted, DefaultTimeout by default.
+	Timeout time.Duration
+
+	// PollInterval is the interval between two checks of the resources of the cluster, DefaultPollInterval by default.
+	PollInterval time.Duration
+}
+
+// Scenario is a check of the suite.
+type Scenario struct {
+	// Name is the name of the subtest running the scenario.
+	Name string
+
+	// Run runs the scenario.
+	Run func(t *testing.T, f *Framework)
+}
+
+// Scenarios are the scenarios of the suite, in the order they are run.
+var Scenarios = []Scenario{
+	{Name: "create", Run: testCreate},
+	{Name: "scale", Run: testScale},
+	{Name: "delete", Run: testDelete},
+	{Name: "machinehealthcheck", Run: testMachineHealthCheck},
+}
+
+// NewClient returns a client of the cluster of the kubeconfig given with the --kubeconfig flag, the KUBECONFIG
+// environment variable or found in the home directory, able to read and write the Machine API resources.
+func NewClient() (client.Client, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error getting the kubeconfig: %w", err)
+	}
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	return client.New(cfg, client.Options{Scheme: scheme})
+}
+
+// Run runs the scenarios of the suite as subtests of t.
+func Run(t *testing.T, cfg Config) {
+	f := NewFramework(t, cfg)
+	for _, scenario := range Scenarios {
+		scenario := scenario
+		t.Run(scenario.Name, func(t *testing.T) {
+			scenario.Run(t, f)
+		})
+	}
+}
+
+// Framework holds the helpers the scenarios are written with, for providers to write their own scenarios.
+type Framework struct {
+	Config
+
+	// Template is the MachineSet whose template is copied into the MachineSets created by the suite.
+	Template *machinev1.MachineSet
+}
+
+// NewFramework returns the framework of the configuration, failing the test when the template MachineSet
+// cannot be found.
+func NewFramework(t *testing.T, cfg Config) *Framework {
+	t.Helper()
+
+	if cfg.Namespace == "" {
+		cfg.Namespace = DefaultNamespace
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = DefaultPollInterval
+	}
+
+	template, err := findTemplate(context.Background(), cfg.Client, cfg.Namespace, cfg.MachineSet)
+	if err != nil {
+		t.Fatalf("Error finding the template MachineSet: %v", err)
+	}
+	return &Framework{Config: cfg, Template: template}
+}
+
+// findTemplate returns the MachineSet of the name, or the first worker MachineSet of the namespace.
+func findTemplate(ctx context.Context, c client.Client, namespace, name string) (*machinev1.MachineSet, error) {
+	if name != "" {
+		ms := &machinev1.MachineSet{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, ms); err != nil {
+			return nil, err
+		}
+		return ms, nil
+	}
+
+	machineSets := &machinev1.MachineSetList{}
+	if err := c.List(ctx, machineSets, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	sort.Slice(machineSets.Items, func(i, j int) bool {
+		return machineSets.Items[i].Name < machineSets.Items[j].Name
+	})
+	for i := range machineSets.Items {
+		ms := &machineSets.Items[i]
+		if ms.Spec.Template.Labels[machineRoleLabel] == machineWorkerRole && ms.Labels[MachineSetLabel] == "" {
+			return ms, nil
+		}
+	}
+	return nil, fmt.Errorf("no worker MachineSet found in namespace %s", namespace)
+}
+
+// newMachineSet returns a MachineSet of the name with the template of the MachineSet, selecting its Machines
+// with the MachineSetLabel only, and adding the NodeLabel and the NodeTaintKey taint to their Nodes.
+func newMachineSet(template *machinev1.MachineSet, name string, replicas int32) *machinev1.MachineSet {
+	ms := &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: template.Namespace,
+			Labels:    map[string]string{MachineSetLabel: name},
+		},
+		Spec: machinev1.MachineSetSpec{
+			Replicas: &replicas,
+			Selector: metav1.LabelSelector{
+				MatchLabels: map[string]string{MachineSetLabel: name},
+			},
+			Template: *template.Spec.Template.DeepCopy(),
+		},
+	}
+	// The Machines must not match the selector of the template MachineSet, which would adopt them.
+	labels := map[string]string{}
+	for k, v := range ms.Spec.Template.Labels {
+		labels[k] = v
+	}
+	if _, ok := labels[machineSetLabel]; ok {
+		labels[machineSetLabel] = name
+	}
+	labels[MachineSetLabel] = name
+	ms.Spec.Template.Labels = labels
+
+	nodeLabels := map[string]string{}
+	for k, v := range ms.Spec.Template.Spec.Labels {
+		nodeLabels[k] = v
+	}
+	nodeLabels[NodeLabel] = name
+	ms.Spec.Template.Spec.Labels = nodeLabels
+	ms.Spec.Template.Spec.Taints = append(ms.Spec.Template.Spec.Taints, corev1.Taint{
+		Key:    NodeTaintKey,
+		Value:  name,
+		Effect: corev1.TaintEffectPreferNoSchedule,
+	})
+	return ms
+}
+
+// CreateMachineSet creates a MachineSet of the replicas from the template MachineSet. It is deleted, along with
+// its Machines, at the end of the test.
+func (f *Framework) CreateMachineSet(t *testing.T, replicas int32) *machinev1.MachineSet {
+	t.Helper()
+
+	name := fmt.Sprintf("%s-conformance-%s", f.Template.Name, utilrand.String(5))
+	ms := newMachineSet(f.Template, name, replicas)
+	if err := f.Client.Create(context.Background(), ms); err != nil {
+		t.Fatalf("Error creating MachineSet %s: %v", name, err)
+	}
+	t.Logf("Created MachineSet %s with %d replicas", name, replicas)
+
+	t.Cleanup(func() {
+		if err := f.Client.Delete(context.Background(), ms); err != nil && !apierrors.IsNotFound(err) {
+			t.Errorf("Error deleting MachineSet %s: %v", name, err)
+			return
+		}
+		f.WaitForMachines(t, ms, 0)
+	})
+	return ms
+}
+
+// ScaleMachineSet sets the replicas of the MachineSet.
+func (f *Framework) ScaleMachineSet(t *testing.T, ms *machinev1.MachineSet, replicas int32) {
+	t.Helper()
+
+	patch := client.MergeFrom(ms.DeepCopy())
+	ms.Spec.Replicas = &replicas
+	if err := f.Client.Patch(context.Background(), ms, patch); err != nil {
+		t.Fatalf("Error scaling MachineSet %s to %d replicas: %v", ms.Name, replicas, err)
+	}
+	t.Logf("Scaled MachineSet %s to %d replicas", ms.Name, replicas)
+}
+
+// PatchMachine patches the Machine with the changes made by the function.
+func (f *Framework) PatchMachine(t *testing.T, m *machinev1.Machine, mutate func(m *machinev1.Machine)) {
+	t.Helper()
+
+	patch := client.MergeFrom(m.DeepCopy())
+	mutate(m)
+	if err := f.Client.Patch(context.Background(), m, patch); err != nil {
+		t.Fatalf("Error patching machine %s: %v", m.Name, err)
+	}
+}
+
+// WaitForMachine waits for the assertions of the function to succeed on the Machine, and returns it.
+func (f *Framework) WaitForMachine(t *testing.T, m *machinev1.Machine, assert func(g gomega.Gomega, m *machinev1.Machine)) *machinev1.Machine {
+	t.Helper()
+
+	machine := &machinev1.Machine{}
+	gomega.NewWithT(t).Eventually(func(g gomega.Gomega) {
+		g.Expect(f.Client.Get(context.Background(), client.ObjectKeyFromObject(m), machine)).To(gomega.Succeed())
+		assert(g, machine)
+	}).WithTimeout(f.Timeout).WithPolling(f.PollInterval).Should(gomega.Succeed(), "Machine %s does not reach the expected state", m.Name)
+	return machine
+}
+
+// ListMachines returns the Machines of the MachineSet.
+func (f *Framework) ListMachines(ms *machinev1.MachineSet) ([]machinev1.Machine, error) {
+	machines := &machinev1.MachineList{}
+	if err := f.Client.List(context.Background(), machines, client.InNamespace(ms.Namespace), client.MatchingLabels{MachineSetLabel: ms.Name}); err != nil {
+		return nil, err
+	}
+	return machines.Items, nil
+}
+
+// WaitForMachines waits for the MachineSet to have the number of Machines, including the ones being deleted.
+func (f *Framework) WaitForMachines(t *testing.T, ms *machinev1.MachineSet, count int) {
+	t.Helper()
+
+	gomega.NewWithT(t).Eventually(func() ([]machinev1.Machine, error) {
+		return f.ListMachines(ms)
+	}).WithTimeout(f.Timeout).WithPolling(f.PollInterval).Should(gomega.HaveLen(count), "MachineSet %s does not have %d machines", ms.Name, count)
+}
+
+// WaitForRunningMachines waits for the MachineSet to have the number of Machines, all of them Running and linked
+// to a ready Node, and returns them.
+func (f *Framework) WaitForRunningMachines(t *testing.T, ms *machinev1.MachineSet, count int) []machinev1.Machine {
+	t.Helper()
+
+	var machines []machinev1.Machine
+	gomega.NewWithT(t).Eventually(func(g gomega.Gomega) {
+		var err error
+		machines, err = f.ListMachines(ms)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(machines).To(gomega.HaveLen(count))
+		for i := range machines {
+			g.Expect(f.checkRunning(&machines[i])).To(gomega.Succeed())
+		}
+	}).WithTimeout(f.Timeout).WithPolling(f.PollInterval).Should(gomega.Succeed(), "MachineSet %s does not have %d running machines", ms.Name, count)
+	return machines
+}
+
+// checkRunning returns an error unless the Machine is Running and linked to a ready Node.
+func (f *Framework) checkRunning(m *machinev1.Machine) error {
+	if m.DeletionTimestamp != nil {
+		return fmt.Errorf("machine %s is being deleted", m.Name)
+	}
+	if m.Status.Phase == nil || *m.Status.Phase != machinev1.PhaseRunning {
+		return fmt.Errorf("machine %s is not running", m.Name)
+	}
+	if m.Status.NodeRef == nil {
+		return fmt.Errorf("machine %s has no node", m.Name)
+	}
+
+	node, err := f.GetNode(m)
+	if err != nil {
+		return err
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+			return nil
+		}
+	}
+	return fmt.Errorf("node %s of machine %s is not ready", node.Name, m.Name)
+}
+
+// GetNode returns the Node of the Machine.
+func (f *Framework) GetNode(m *machinev1.Machine) (*corev1.Node, error) {
+	if m.Status.NodeRef == nil {
+		return nil, fmt.Errorf("machine %s has no node", m.Name)
+	}
+	node := &corev1.Node{}
+	if err := f.Client.Get(context.Background(), client.ObjectKey{Name: m.Status.NodeRef.Name}, node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// WaitForMachineDeleted waits for the Machine, and its Node if it had one, to be deleted.
+func (f *Framework) WaitForMachineDeleted(t *testing.T, m *machinev1.Machine) {
+	t.Helper()
+
+	g := gomega.NewWithT(t)
+	g.Eventually(func() bool {
+		err := f.Client.Get(context.Background(), client.ObjectKeyFromObject(m), &machinev1.Machine{})
+		return apierrors.IsNotFound(err)
+	}).WithTimeout(f.Timeout).WithPolling(f.PollInterval).Should(gomega.BeTrue(), "Machine %s is not deleted", m.Name)
+
+	if m.Status.NodeRef == nil {
+		return
+	}
+	g.Eventually(func() bool {
+		err := f.Client.Get(context.Background(), client.ObjectKey{Name: m.Status.NodeRef.Name}, &corev1.Node{})
+		return apierrors.IsNotFound(err)
+	}).WithTimeout(f.Timeout).WithPolling(f.PollInterval).Should(gomega.BeTrue(), "Node %s of machine %s is not deleted", m.Status.NodeRef.Name, m.Name)
+}
@@ -0,0 +1,133 @@
+package conformance
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func init() {
+	if err := machinev1.AddToScheme(scheme.Scheme); err != nil {
+		klog.Fatal(err)
+	}
+}
+
+var (
+	conformance  = flag.Bool("conformance", false, "Run the conformance suite against the cluster of the kubeconfig")
+	machineSet   = flag.String("conformance.machineset", "", "Name of the MachineSet whose template is used by the conformance suite")
+	namespace    = flag.String("conformance.namespace", DefaultNamespace, "Namespace of the Machine API resources")
+	timeout      = flag.Duration("conformance.timeout", DefaultTimeout, "Time given to a machine to be running, or to be deleted")
+	pollInterval = flag.Duration("conformance.poll-interval", DefaultPollInterval, "Interval between two checks of the cluster")
+)
+
+// TestConformance runs the suite against a live cluster, e.g. with
+//
+//	go test ./pkg/testing/conformance/ -run TestConformance -v -timeout 3h -args -conformance -kubeconfig ~/.kube/config
+func TestConformance(t *testing.T) {
+	if !*conformance {
+		t.Skip("The conformance suite runs against a live cluster, enable it with the -conformance flag")
+	}
+
+	c, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Run(t, Config{
+		Client:       c,
+		Namespace:    *namespace,
+		MachineSet:   *machineSet,
+		Timeout:      *timeout,
+		PollInterval: *pollInterval,
+	})
+}
+
+func workerMachineSet(name string, labels map[string]string) *machinev1.MachineSet {
+	templateLabels := map[string]string{
+		machineRoleLabel: machineWorkerRole,
+		machineSetLabel:  name,
+	}
+	return &machinev1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: DefaultNamespace, Labels: labels},
+		Spec: machinev1.MachineSetSpec{
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{machineSetLabel: name}},
+			Template: machinev1.MachineTemplateSpec{
+				ObjectMeta: machinev1.ObjectMeta{Labels: templateLabels},
+			},
+		},
+	}
+}
+
+func TestFindTemplate(t *testing.T) {
+	g := NewWithT(t)
+
+	infra := workerMachineSet("a-infra", nil)
+	infra.Spec.Template.Labels[machineRoleLabel] = "infra"
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		infra,
+		workerMachineSet("b-worker-conformance-x1y2z", map[string]string{MachineSetLabel: "b-worker-conformance-x1y2z"}),
+		workerMachineSet("d-worker", nil),
+		workerMachineSet("c-worker", nil),
+	).Build()
+
+	// The first worker MachineSet is used by default, leaving out the ones created by the suite.
+	ms, err := findTemplate(context.TODO(), c, DefaultNamespace, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(ms.Name).To(Equal("c-worker"))
+
+	ms, err = findTemplate(context.TODO(), c, DefaultNamespace, "a-infra")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(ms.Name).To(Equal("a-infra"))
+
+	_, err = findTemplate(context.TODO(), c, "other", "")
+	g.Expect(err).To(MatchError("no worker MachineSet found in namespace other"))
+}
+
+func TestNewMachineSet(t *testing.T) {
+	g := NewWithT(t)
+
+	template := workerMachineSet("worker", nil)
+	ms := newMachineSet(template, "worker-conformance-x1y2z", 2)
+
+	g.Expect(ms.Namespace).To(Equal(DefaultNamespace))
+	g.Expect(ms.Spec.Replicas).To(HaveValue(BeEquivalentTo(2)))
+	g.Expect(ms.Spec.Selector.MatchLabels).To(Equal(map[string]string{MachineSetLabel: "worker-conformance-x1y2z"}))
+	g.Expect(ms.Spec.Template.Labels).To(Equal(map[string]string{
+		machineRoleLabel: machineWorkerRole,
+		machineSetLabel:  "worker-conformance-x1y2z",
+		MachineSetLabel:  "worker-conformance-x1y2z",
+	}))
+
+	g.Expect(ms.Spec.Template.Spec.Labels).To(Equal(map[string]string{NodeLabel: "worker-conformance-x1y2z"}))
+	g.Expect(ms.Spec.Template.Spec.Taints).To(ConsistOf(corev1.Taint{
+		Key:    NodeTaintKey,
+		Value:  "worker-conformance-x1y2z",
+		Effect: corev1.TaintEffectPreferNoSchedule,
+	}))
+
+	// The template MachineSet is left untouched, and does not select the new Machines.
+	g.Expect(template.Spec.Template.Labels).To(HaveKeyWithValue(machineSetLabel, "worker"))
+	g.Expect(template.Spec.Template.Labels).ToNot(HaveKey(MachineSetLabel))
+	g.Expect(template.Spec.Template.Spec.Labels).To(BeEmpty())
+	g.Expect(template.Spec.Template.Spec.Taints).To(BeEmpty())
+}
+
+func TestFrameworkDefaults(t *testing.T) {
+	g := NewWithT(t)
+
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(workerMachineSet("worker", nil)).Build()
+	f := NewFramework(t, Config{Client: c, PollInterval: time.Second})
+
+	g.Expect(f.Namespace).To(Equal(DefaultNamespace))
+	g.Expect(f.Timeout).To(Equal(DefaultTimeout))
+	g.Expect(f.PollInterval).To(Equal(time.Second))
+	g.Expect(f.Template.Name).To(Equal("worker"))
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/controller/machinehealthcheck"
+	"github.com/openshift/machine-api-operator/pkg/controller/machineset"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// machineAnnotationKey is the annotation of the Nodes set by the nodelink controller to their Machine.
+	machineAnnotationKey = "machine.openshift.io/machine"
+
+	// unhealthyConditionType is the Node condition set by the MachineHealthCheck scenario to make a Node unhealthy,
+	// the way node-problem-detector reports the problems of a Node with conditions the kubelet does not own.
+	unhealthyConditionType corev1.NodeConditionType = "MachineAPIConformanceUnhealthy"
+
+	// lifecycleHookOwner is the owner of the lifecycle hooks set by the delete scenario.
+	lifecycleHookOwner = "machine-api-conformance"
+)
+
+// testCreate checks that a Machine is created for a MachineSet, gets an instance and is linked to its Node,
+// which gets the labels and taints of the Machine from the nodelink controller.
+func testCreate(t *testing.T, f *Framework) {
+	ms := f.CreateMachineSet(t, 1)
+	machines := f.WaitForRunningMachines(t, ms, 1)
+
+	g := gomega.NewWithT(t)
+	m := machines[0]
+	g.Expect(m.Spec.ProviderID).ToNot(gomega.BeNil(), "Machine %s has no providerID", m.Name)
+	g.Expect(m.Status.Addresses).ToNot(gomega.BeEmpty(), "Machine %s has no addresses", m.Name)
+	for _, conditionType := range []machinev1.ConditionType{machinev1.MachineDrainable, machinev1.MachineTerminable} {
+		condition := conditions.Get(&m, conditionType)
+		g.Expect(condition).ToNot(gomega.BeNil(), "Machine %s has no %s condition", m.Name, conditionType)
+		g.Expect(condition.Status).To(gomega.Equal(corev1.ConditionTrue), "Machine %s is not %s", m.Name, conditionType)
+	}
+
+	node, err := f.GetNode(&m)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(node.Spec.ProviderID).To(gomega.Equal(*m.Spec.ProviderID), "Node %s has a different providerID than machine %s", node.Name, m.Name)
+	g.Expect(node.Annotations).To(gomega.HaveKeyWithValue(machineAnnotationKey, fmt.Sprintf("%s/%s", m.Namespace, m.Name)),
+		"Node %s is not linked to machine %s", node.Name, m.Name)
+	g.Expect(node.Labels).To(gomega.HaveKeyWithValue(NodeLabel, ms.Name), "Node %s does not have the labels of machine %s", node.Name, m.Name)
+	g.Expect(node.Spec.Taints).To(gomega.ContainElement(gomega.And(
+		gomega.HaveField("Key", NodeTaintKey),
+		gomega.HaveField("Value", ms.Name),
+		gomega.HaveField("Effect", corev1.TaintEffectPreferNoSchedule),
+	)), "Node %s does not have the taints of machine %s", node.Name, m.Name)
+
+	// The MachineSet controller counts the Machines whose Node is ready.
+	g.Eventually(func() (int32, error) {
+		err := f.Client.Get(context.Background(), client.ObjectKeyFromObject(ms), ms)
+		return ms.Status.ReadyReplicas, err
+	}).WithTimeout(f.Timeout).WithPolling(f.PollInterval).Should(gomega.BeEquivalentTo(1), "MachineSet %s does not have a ready replica", ms.Name)
+}
+
+// testScale checks that the Machines of a MachineSet are created and deleted as it is scaled up and down, and that
+// the Machine annotated for deletion, as the cluster autoscaler does, is deleted first.
+func testScale(t *testing.T, f *Framework) {
+	ms := f.CreateMachineSet(t, 1)
+	f.WaitForRunningMachines(t, ms, 1)
+
+	f.ScaleMachineSet(t, ms, 2)
+	machines := f.WaitForRunningMachines(t, ms, 2)
+
+	deleted, kept := machines[0], machines[1]
+	f.PatchMachine(t, &deleted, func(m *machinev1.Machine) {
+		if m.Annotations == nil {
+			m.Annotations = map[string]string{}
+		}
+		m.Annotations[machineset.DeleteNodeAnnotation] = "true"
+	})
+	t.Logf("Annotated machine %s for deletion", deleted.Name)
+
+	f.ScaleMachineSet(t, ms, 1)
+	f.WaitForMachineDeleted(t, &deleted)
+	remaining := f.WaitForRunningMachines(t, ms, 1)
+	gomega.NewWithT(t).Expect(remaining[0].Name).To(gomega.Equal(kept.Name), "Machine %s is deleted instead of machine %s", kept.Name, deleted.Name)
+
+	f.ScaleMachineSet(t, ms, 0)
+	f.WaitForMachines(t, ms, 0)
+}
+
+// testDelete checks that a deleted Machine of a MachineSet is drained once its pre-drain hook is removed, that its
+// instance is deleted once its pre-terminate hook is removed, and that it is deleted along with its Node and replaced.
+func testDelete(t *testing.T, f *Framework) {
+	ms := f.CreateMachineSet(t, 1)
+	m := f.WaitForRunningMachines(t, ms, 1)[0]
+
+	f.PatchMachine(t, &m, func(m *machinev1.Machine) {
+		m.Spec.LifecycleHooks.PreDrain = append(m.Spec.LifecycleHooks.PreDrain, machinev1.LifecycleHook{Name: "Conformance", Owner: lifecycleHookOwner})
+		m.Spec.LifecycleHooks.PreTerminate = append(m.Spec.LifecycleHooks.PreTerminate, machinev1.LifecycleHook{Name: "Conformance", Owner: lifecycleHookOwner})
+	})
+	if err := f.Client.Delete(context.Background(), &m); err != nil {
+		t.Fatalf("Error deleting machine %s: %v", m.Name, err)
+	}
+
+	// The pre-drain hook blocks the drain of the Node.
+	deleting := f.WaitForMachine(t, &m, func(g gomega.Gomega, m *machinev1.Machine) {
+		g.Expect(m.Status.Phase).To(gomega.HaveValue(gomega.Equal(machinev1.PhaseDeleting)))
+		g.Expect(conditions.Get(m, machinev1.MachineDrainable)).To(gomega.HaveField("Reason", machinev1.MachineHookPresent))
+	})
+	node, err := f.GetNode(deleting)
+	if err != nil {
+		t.Fatalf("Error getting the node of machine %s: %v", m.Name, err)
+	}
+	gomega.NewWithT(t).Expect(node.Spec.Unschedulable).To(gomega.BeFalse(), "Node %s of machine %s is cordoned before its pre-drain hook is removed", node.Name, m.Name)
+
+	// The pre-terminate hook blocks the deletion of the instance once the Node is drained.
+	f.PatchMachine(t, deleting, func(m *machinev1.Machine) {
+		m.Spec.LifecycleHooks.PreDrain = removeLifecycleHook(m.Spec.LifecycleHooks.PreDrain, lifecycleHookOwner)
+	})
+	t.Logf("Removed the pre-drain hook of machine %s", m.Name)
+	drained := f.WaitForMachine(t, &m, func(g gomega.Gomega, m *machinev1.Machine) {
+		g.Expect(conditions.Get(m, machinev1.MachineDrained)).To(gomega.HaveField("Status", corev1.ConditionTrue))
+		g.Expect(conditions.Get(m, machinev1.MachineTerminable)).To(gomega.HaveField("Reason", machinev1.MachineHookPresent))
+	})
+	node, err = f.GetNode(drained)
+	if err != nil {
+		t.Fatalf("Error getting the node of machine %s: %v", m.Name, err)
+	}
+	gomega.NewWithT(t).Expect(node.Spec.Unschedulable).To(gomega.BeTrue(), "Node %s of drained machine %s is not cordoned", node.Name, m.Name)
+
+	f.PatchMachine(t, drained, func(m *machinev1.Machine) {
+		m.Spec.LifecycleHooks.PreTerminate = removeLifecycleHook(m.Spec.LifecycleHooks.PreTerminate, lifecycleHookOwner)
+	})
+	t.Logf("Removed the pre-terminate hook of machine %s", m.Name)
+	f.WaitForMachineDeleted(t, &m)
+
+	replacement := f.WaitForRunningMachines(t, ms, 1)[0]
+	gomega.NewWithT(t).Expect(replacement.Name).ToNot(gomega.Equal(m.Name), "Machine %s is not replaced", m.Name)
+}
+
+// removeLifecycleHook returns the lifecycle hooks without the ones of the owner.
+func removeLifecycleHook(hooks []machinev1.LifecycleHook, owner string) []machinev1.LifecycleHook {
+	var kept []machinev1.LifecycleHook
+	for _, hook := range hooks {
+		if hook.Owner != owner {
+			kept = append(kept, hook)
+		}
+	}
+	return kept
+}
+
+// testMachineHealthCheck checks that a Machine whose Node is unhealthy is remediated by a MachineHealthCheck,
+// that is deleted and replaced, and that the remediation is recorded on the MachineHealthCheck.
+func testMachineHealthCheck(t *testing.T, f *Framework) {
+	ms := f.CreateMachineSet(t, 1)
+	m := f.WaitForRunningMachines(t, ms, 1)[0]
+
+	maxUnhealthy := intstr.FromString("100%")
+	mhc := &machinev1.MachineHealthCheck{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ms.Name,
+			Namespace: ms.Namespace,
+		},
+		Spec: machinev1.MachineHealthCheckSpec{
+			Selector: metav1.LabelSelector{
+				MatchLabels: map[string]string{MachineSetLabel: ms.Name},
+			},
+			UnhealthyConditions: []machinev1.UnhealthyCondition{{
+				Type:    unhealthyConditionType,
+				Status:  corev1.ConditionTrue,
+				Timeout: metav1.Duration{Duration: time.Second},
+			}},
+			MaxUnhealthy: &maxUnhealthy,
+		},
+	}
+	if err := f.Client.Create(context.Background(), mhc); err != nil {
+		t.Fatalf("Error creating MachineHealthCheck %s: %v", mhc.Name, err)
+	}
+	t.Cleanup(func() {
+		if err := f.Client.Delete(context.Background(), mhc); err != nil {
+			t.Errorf("Error deleting MachineHealthCheck %s: %v", mhc.Name, err)
+		}
+	})
+
+	// The MachineHealthCheck controller covers the Machine before it is unhealthy.
+	g := gomega.NewWithT(t)
+	g.Eventually(func(g gomega.Gomega) {
+		g.Expect(f.Client.Get(context.Background(), client.ObjectKeyFromObject(mhc), mhc)).To(gomega.Succeed())
+		g.Expect(mhc.Status.ExpectedMachines).To(gomega.Equal(pointer.Int(1)))
+		g.Expect(mhc.Status.CurrentHealthy).To(gomega.Equal(pointer.Int(1)))
+	}).WithTimeout(f.Timeout).WithPolling(f.PollInterval).Should(gomega.Succeed(), "MachineHealthCheck %s does not cover machine %s", mhc.Name, m.Name)
+
+	// The kubelet leaves the conditions it does not own on its Node.
+	node, err := f.GetNode(&m)
+	if err != nil {
+		t.Fatalf("Error getting the node of machine %s: %v", m.Name, err)
+	}
+	patch := client.MergeFrom(node.DeepCopy())
+	node.Status.Conditions = append(node.Status.Conditions, corev1.NodeCondition{
+		Type:               unhealthyConditionType,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             "Conformance",
+		Message:            "Set by the Machine API conformance suite to trigger the remediation of the machine",
+	})
+	if err := f.Client.Status().Patch(context.Background(), node, patch); err != nil {
+		t.Fatalf("Error making node %s unhealthy: %v", node.Name, err)
+	}
+	t.Logf("Made node %s of machine %s unhealthy", node.Name, m.Name)
+
+	f.WaitForMachineDeleted(t, &m)
+	replacement := f.WaitForRunningMachines(t, ms, 1)[0]
+	g.Expect(replacement.Name).ToNot(gomega.Equal(m.Name), "Machine %s is not remediated", m.Name)
+
+	g.Expect(f.Client.Get(context.Background(), client.ObjectKeyFromObject(mhc), mhc)).To(gomega.Succeed())
+	history := &machinehealthcheck.RemediationHistory{}
+	g.Expect(json.Unmarshal([]byte(mhc.Annotations[machinehealthcheck.RemediationHistoryAnnotation]), history)).To(gomega.Succeed(),
+		"MachineHealthCheck %s has no remediation history", mhc.Name)
+	g.Expect(history.RecentRemediations).To(gomega.ContainElement(gomega.And(
+		gomega.HaveField("Machine", m.Name),
+		gomega.HaveField("Reason", fmt.Sprintf("%s: %s=%s", machinehealthcheck.UnhealthyNodeReason, unhealthyConditionType, corev1.ConditionTrue)),
+		gomega.HaveField("Outcome", machinehealthcheck.RemediationSucceeded),
+	)), "The remediation of machine %s is not recorded on MachineHealthCheck %s", m.Name, mhc.Name)
+}
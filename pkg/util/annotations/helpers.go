@@ -2,6 +2,8 @@
 package annotations
 
 import (
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -10,6 +12,46 @@ const (
 	// from processing it.
 	// TODO: move this annotation to the openshift/api package
 	PausedAnnotation = "cluster.x-k8s.io/paused"
+
+	// MachinePausedAnnotation is the Machine API equivalent of PausedAnnotation, either of them pauses the object.
+	MachinePausedAnnotation = "machine.openshift.io/paused"
+
+	// AuthoritativeAPIAnnotation is set on Machines and MachineSets mirrored into Cluster API resources, to the API
+	// whose controllers reconcile the resources, either AuthoritativeAPIMachineAPI (the default) or AuthoritativeAPIClusterAPI.
+	AuthoritativeAPIAnnotation = "machine.openshift.io/authoritative-api"
+
+	// AuthoritativeAPIMachineAPI makes the Machine API controllers reconcile the resource.
+	AuthoritativeAPIMachineAPI = "MachineAPI"
+	// AuthoritativeAPIClusterAPI makes the Cluster API controllers reconcile the mirror of the resource.
+	AuthoritativeAPIClusterAPI = "ClusterAPI"
+
+	// ExternalMachineAnnotation set to "true" marks a Machine representing a pre-existing node, provisioned outside of
+	// the Machine API. No cloud provider calls are made for the Machine, its lifecycle is limited to linking its node,
+	// draining it, and the decommission acknowledged with DecommissionedAnnotation.
+	ExternalMachineAnnotation = "machine.openshift.io/external"
+
+	// DecommissionedAnnotation set to "true" on an external Machine being deleted acknowledges that its node has been
+	// decommissioned, letting the deletion of the Machine and of its Node complete.
+	DecommissionedAnnotation = "machine.openshift.io/decommissioned"
+
+	// AllowInPlaceResizeAnnotation set to "true" lets the machine controller resize the instance of a Machine when
+	// its instance type changes, by draining its node, powering the instance off, resizing it and powering it back on.
+	// Otherwise the change of the instance type only applies to the instances created afterwards.
+	AllowInPlaceResizeAnnotation = "machine.openshift.io/allow-in-place-resize"
+
+	// DedicatedHostAnnotation requests the instance of a Machine to be placed on a dedicated host, set to the ID of
+	// the host in the cloud provider, e.g. the host ID on AWS or the resource ID of the host on Azure. The placement
+	// is up to the actuator of the platform, the webhooks reject the annotation unless the host affinity is allowed.
+	DedicatedHostAnnotation = "machine.openshift.io/dedicated-host"
+
+	// HostGroupAnnotation requests the instance of a Machine to be placed in a group of dedicated hosts, the cloud
+	// provider choosing the host in the group, set to the ID of the group, e.g. the ARN of the host resource group
+	// on AWS or the resource ID of the host group on Azure. It cannot be set along with DedicatedHostAnnotation.
+	HostGroupAnnotation = "machine.openshift.io/host-group"
+
+	// HostGroupsAnnotation lists the host groups the Machines of a MachineSet are spread across, as a comma separated
+	// list. New Machines are assigned, with HostGroupAnnotation, to the host group with the fewest Machines, round-robin.
+	HostGroupsAnnotation = "machine.openshift.io/host-groups"
 )
 
 // IsPaused returns true if the Cluster is paused or the object has the `paused` annotation.
@@ -17,9 +59,47 @@ func IsPaused(o metav1.Object) bool {
 	return HasPausedAnnotation(o)
 }
 
-// HasPausedAnnotation returns true if the object has the `paused` annotation.
+// HasPausedAnnotation returns true if the object has either of the `paused` annotations.
 func HasPausedAnnotation(o metav1.Object) bool {
-	return hasAnnotation(o, PausedAnnotation)
+	return hasAnnotation(o, PausedAnnotation) || hasAnnotation(o, MachinePausedAnnotation)
+}
+
+// IsClusterAPIAuthoritative returns true if the Machine API resource is reconciled by the Cluster API controllers,
+// in which case the Machine API controllers must leave it alone.
+func IsClusterAPIAuthoritative(o metav1.Object) bool {
+	return o.GetAnnotations()[AuthoritativeAPIAnnotation] == AuthoritativeAPIClusterAPI
+}
+
+// IsExternalMachine returns true if the Machine represents a pre-existing node, provisioned outside of the Machine API.
+func IsExternalMachine(o metav1.Object) bool {
+	return o.GetAnnotations()[ExternalMachineAnnotation] == "true"
+}
+
+// IsDecommissioned returns true if the decommission of the node of the external Machine has been acknowledged.
+func IsDecommissioned(o metav1.Object) bool {
+	return o.GetAnnotations()[DecommissionedAnnotation] == "true"
+}
+
+// IsInPlaceResizeAllowed returns true if the instance of the Machine can be resized in place.
+func IsInPlaceResizeAllowed(o metav1.Object) bool {
+	return o.GetAnnotations()[AllowInPlaceResizeAnnotation] == "true"
+}
+
+// GetHostAffinity returns the dedicated host and the host group the Machine is pinned to, if any.
+func GetHostAffinity(o metav1.Object) (string, string) {
+	annotations := o.GetAnnotations()
+	return annotations[DedicatedHostAnnotation], annotations[HostGroupAnnotation]
+}
+
+// GetHostGroups returns the host groups the Machines of the MachineSet are spread across, or nil when none are set.
+func GetHostGroups(o metav1.Object) []string {
+	var groups []string
+	for _, group := range strings.Split(o.GetAnnotations()[HostGroupsAnnotation], ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	return groups
 }
 
 // hasAnnotation returns true if the object has the specified annotation.
@@ -0,0 +1,196 @@
+package util
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// newCachedObjects returns the objects of the kinds whose cache can be filtered by selectors.
+var newCachedObjects = map[string]func() client.Object{
+	"Machine":            func() client.Object { return &machinev1.Machine{} },
+	"MachineSet":         func() client.Object { return &machinev1.MachineSet{} },
+	"MachineHealthCheck": func() client.Object { return &machinev1.MachineHealthCheck{} },
+	"ConfigMap":          func() client.Object { return &corev1.ConfigMap{} },
+	"Node":               func() client.Object { return &corev1.Node{} },
+	"Secret":             func() client.Object { return &corev1.Secret{} },
+}
+
+var (
+	// machineAPIKinds are the kinds selected by the label selector of the cache options.
+	machineAPIKinds = []string{"Machine", "MachineSet", "MachineHealthCheck"}
+	// selectableKinds are the kinds selected by the selectors of the cache options keyed by kind.
+	selectableKinds = []string{"ConfigMap", "Node", "Secret"}
+)
+
+// ParseNamespaces returns the namespaces of a comma separated list, none when the list is empty.
+func ParseNamespaces(list string) []string {
+	var namespaces []string
+	seen := map[string]bool{}
+	for _, namespace := range strings.Split(list, ",") {
+		namespace = strings.TrimSpace(namespace)
+		if namespace == "" || seen[namespace] {
+			continue
+		}
+		seen[namespace] = true
+		namespaces = append(namespaces, namespace)
+	}
+	return namespaces
+}
+
+// CacheOptions filters the resources cached by a manager.
+type CacheOptions struct {
+	// Namespaces are the namespaces cached, all of them when there is none.
+	Namespaces []string
+	// LabelSelector selects the Machines, MachineSets and MachineHealthChecks cached, all of them when empty.
+	LabelSelector string
+	// ObjectLabelSelectors and ObjectFieldSelectors select the ConfigMaps, Nodes and Secrets cached, by kind.
+	ObjectLabelSelectors KindSelectors
+	ObjectFieldSelectors KindSelectors
+}
+
+// AddFlags adds the flags of the selectors of the cache options to the flag set.
+func (o *CacheOptions) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.LabelSelector, "cache-label-selector", "", "Label selector of the Machines, MachineSets and MachineHealthChecks that the controller caches and reconciles. If unspecified, the controller caches all of them.")
+	fs.Var(&o.ObjectLabelSelectors, "cache-object-label-selector", "Label selector of the objects of a kind that the controller caches, as <kind>:<selector>, e.g. Secret:machine.openshift.io/owned. Can be repeated for the kinds "+strings.Join(selectableKinds, ", ")+". The objects not matching the selector are not found by the controller.")
+	fs.Var(&o.ObjectFieldSelectors, "cache-object-field-selector", "Field selector of the objects of a kind that the controller caches, as <kind>:<selector>, e.g. Secret:type=Opaque. Can be repeated for the kinds "+strings.Join(selectableKinds, ", ")+". The objects not matching the selector are not found by the controller.")
+}
+
+// KindSelectors are selectors keyed by kind, set by a repeated flag of <kind>:<selector> values, or of
+// values separated by semicolons, which the selectors do not contain.
+type KindSelectors map[string]string
+
+// String implements the flag.Value interface.
+func (s *KindSelectors) String() string {
+	var values []string
+	for kind, selector := range *s {
+		values = append(values, kind+":"+selector)
+	}
+	sort.Strings(values)
+	return strings.Join(values, ";")
+}
+
+// Set implements the flag.Value interface.
+func (s *KindSelectors) Set(value string) error {
+	for _, value := range strings.Split(value, ";") {
+		kind, selector, ok := strings.Cut(value, ":")
+		if !ok || selector == "" {
+			return fmt.Errorf("invalid selector %q: expected <kind>:<selector>", value)
+		}
+		if !sets.NewString(selectableKinds...).Has(kind) {
+			return fmt.Errorf("invalid selector %q: kind must be one of %s", value, strings.Join(selectableKinds, ", "))
+		}
+		if *s == nil {
+			*s = KindSelectors{}
+		}
+		(*s)[kind] = selector
+	}
+	return nil
+}
+
+// ConfigureCache restricts the cache of the manager options to the namespaces and the objects selected by the
+// cache options. The objects which are not cached are not found by the clients of the manager reading from the cache.
+func ConfigureCache(opts *manager.Options, cacheOpts CacheOptions) error {
+	selectors, err := cacheOpts.selectorsByKind()
+	if err != nil {
+		return err
+	}
+
+	newCache := cache.New
+	switch len(cacheOpts.Namespaces) {
+	case 0:
+	case 1:
+		opts.Namespace = cacheOpts.Namespaces[0]
+	default:
+		newCache = cache.MultiNamespacedCacheBuilder(cacheOpts.Namespaces)
+	}
+	if len(selectors) == 0 {
+		opts.NewCache = newCache
+		return nil
+	}
+
+	opts.NewCache = func(config *rest.Config, o cache.Options) (cache.Cache, error) {
+		// The selectors are keyed by the kinds of the scheme, the commands only add the Machine API types
+		// to the scheme after the cache is created.
+		if err := machinev1.AddToScheme(o.Scheme); err != nil {
+			return nil, err
+		}
+		o.SelectorsByObject = cache.SelectorsByObject{}
+		for kind, selector := range selectors {
+			o.SelectorsByObject[newCachedObjects[kind]()] = selector
+		}
+		return newCache(config, o)
+	}
+	return nil
+}
+
+// selectorsByKind returns the selectors of the cached objects, keyed by kind.
+func (o CacheOptions) selectorsByKind() (map[string]cache.ObjectSelector, error) {
+	selectors := map[string]cache.ObjectSelector{}
+	if o.LabelSelector != "" {
+		selector, err := labels.Parse(o.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache label selector %q: %v", o.LabelSelector, err)
+		}
+		for _, kind := range machineAPIKinds {
+			selectors[kind] = cache.ObjectSelector{Label: selector}
+		}
+	}
+	for kind, s := range o.ObjectLabelSelectors {
+		selector, err := labels.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache label selector %q of kind %s: %v", s, kind, err)
+		}
+		objectSelector := selectors[kind]
+		objectSelector.Label = selector
+		selectors[kind] = objectSelector
+	}
+	for kind, s := range o.ObjectFieldSelectors {
+		selector, err := fields.ParseSelector(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache field selector %q of kind %s: %v", s, kind, err)
+		}
+		objectSelector := selectors[kind]
+		objectSelector.Field = selector
+		selectors[kind] = objectSelector
+	}
+	return selectors, nil
+}
+
+// ObjectExists returns whether the object of the key exists, obj only giving its kind. Unless fullObject is set,
+// only the metadata of the object is got, so that a cached client watches the metadata of the objects of the kind
+// rather than caching the full objects. The object selectors of the cache options apply to both.
+func ObjectExists(ctx context.Context, c client.Client, key client.ObjectKey, obj client.Object, fullObject bool) (bool, error) {
+	if !fullObject {
+		gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+		if err != nil {
+			return false, err
+		}
+		metadata := &metav1.PartialObjectMetadata{}
+		metadata.SetGroupVersionKind(gvk)
+		obj = metadata
+	}
+
+	if err := c.Get(ctx, key, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,164 @@
+package util
+
+import (
+	"context"
+	"flag"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+func TestParseNamespaces(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(ParseNamespaces("")).To(BeEmpty())
+	g.Expect(ParseNamespaces("openshift-machine-api")).To(Equal([]string{"openshift-machine-api"}))
+	g.Expect(ParseNamespaces(" tenant-a, tenant-b,,tenant-a ")).To(Equal([]string{"tenant-a", "tenant-b"}))
+}
+
+func TestConfigureCache(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		namespaces           []string
+		labelSelector        string
+		objectLabelSelectors KindSelectors
+		objectFieldSelectors KindSelectors
+		expectedNamespace    string
+		expectedError        string
+	}{
+		{
+			name: "with all the namespaces",
+		},
+		{
+			name:              "with a namespace",
+			namespaces:        []string{"openshift-machine-api"},
+			expectedNamespace: "openshift-machine-api",
+		},
+		{
+			name:       "with namespaces",
+			namespaces: []string{"tenant-a", "tenant-b"},
+		},
+		{
+			name:          "with namespaces and a label selector",
+			namespaces:    []string{"tenant-a", "tenant-b"},
+			labelSelector: "tenant in (a,b)",
+		},
+		{
+			name:              "with a namespace and a label selector",
+			namespaces:        []string{"openshift-machine-api"},
+			labelSelector:     "tenant=a",
+			expectedNamespace: "openshift-machine-api",
+		},
+		{
+			name:                 "with selectors of the secrets and nodes",
+			namespaces:           []string{"tenant-a", "tenant-b"},
+			labelSelector:        "tenant in (a,b)",
+			objectLabelSelectors: KindSelectors{"Secret": "machine.openshift.io/owned"},
+			objectFieldSelectors: KindSelectors{"Secret": "type=Opaque", "Node": "metadata.name=worker-0"},
+		},
+		{
+			name:                 "with an invalid field selector",
+			objectFieldSelectors: KindSelectors{"Secret": "type"},
+			expectedError:        `invalid cache field selector "type" of kind Secret`,
+		},
+		{
+			name:          "with an invalid label selector",
+			labelSelector: "tenant in a",
+			expectedError: `invalid cache label selector "tenant in a"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			opts := manager.Options{}
+			err := ConfigureCache(&opts, CacheOptions{
+				Namespaces:           tc.namespaces,
+				LabelSelector:        tc.labelSelector,
+				ObjectLabelSelectors: tc.objectLabelSelectors,
+				ObjectFieldSelectors: tc.objectFieldSelectors,
+			})
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedError)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(opts.Namespace).To(Equal(tc.expectedNamespace))
+			g.Expect(opts.NewCache).ToNot(BeNil())
+
+			// The cache is created with a scheme without the Machine API types, as by the managers.
+			scheme := runtime.NewScheme()
+			g.Expect(corev1.AddToScheme(scheme)).To(Succeed())
+			mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{machinev1.SchemeGroupVersion, corev1.SchemeGroupVersion})
+			for _, kind := range []string{"Machine", "MachineSet", "MachineHealthCheck"} {
+				mapper.Add(machinev1.SchemeGroupVersion.WithKind(kind), meta.RESTScopeNamespace)
+			}
+			mapper.Add(corev1.SchemeGroupVersion.WithKind("Secret"), meta.RESTScopeNamespace)
+			mapper.Add(corev1.SchemeGroupVersion.WithKind("Node"), meta.RESTScopeRoot)
+			c, err := opts.NewCache(&rest.Config{Host: "https://localhost:6443"}, cache.Options{
+				Scheme:    scheme,
+				Mapper:    mapper,
+				Namespace: opts.Namespace,
+			})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(c).ToNot(BeNil())
+		})
+	}
+}
+
+func TestKindSelectors(t *testing.T) {
+	g := NewWithT(t)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	options := &CacheOptions{}
+	options.AddFlags(fs)
+	g.Expect(fs.Parse([]string{
+		"-cache-object-label-selector=Secret:machine.openshift.io/owned",
+		"-cache-object-label-selector=ConfigMap:app in (machine-api);Node:node-role.kubernetes.io/worker",
+		"-cache-object-field-selector=Secret:type=Opaque",
+	})).To(Succeed())
+	g.Expect(options.ObjectLabelSelectors).To(Equal(KindSelectors{
+		"ConfigMap": "app in (machine-api)",
+		"Node":      "node-role.kubernetes.io/worker",
+		"Secret":    "machine.openshift.io/owned",
+	}))
+	g.Expect(options.ObjectLabelSelectors.String()).To(Equal("ConfigMap:app in (machine-api);Node:node-role.kubernetes.io/worker;Secret:machine.openshift.io/owned"))
+	g.Expect(options.ObjectFieldSelectors).To(Equal(KindSelectors{"Secret": "type=Opaque"}))
+
+	selectors := KindSelectors{}
+	g.Expect(selectors.Set("Machine:tenant=a")).To(MatchError(ContainSubstring("kind must be one of ConfigMap, Node, Secret")))
+	g.Expect(selectors.Set("machine.openshift.io/owned")).To(MatchError(ContainSubstring("expected <kind>:<selector>")))
+	g.Expect(selectors.Set("Secret:")).To(MatchError(ContainSubstring("expected <kind>:<selector>")))
+}
+
+func TestObjectExists(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-user-data", Namespace: "openshift-machine-api"},
+		Data:       map[string][]byte{"userData": []byte("{}")},
+	}
+	c := fake.NewClientBuilder().WithObjects(secret).Build()
+
+	for _, fullObject := range []bool{false, true} {
+		g := NewWithT(t)
+
+		exists, err := ObjectExists(context.TODO(), c, client.ObjectKeyFromObject(secret), &corev1.Secret{}, fullObject)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(exists).To(BeTrue())
+
+		exists, err = ObjectExists(context.TODO(), c, client.ObjectKey{Name: "missing", Namespace: secret.Namespace}, &corev1.Secret{}, fullObject)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(exists).To(BeFalse())
+	}
+}
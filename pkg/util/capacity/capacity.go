@@ -0,0 +1,154 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package capacity derives the compute capacity of a machine from its providerSpec.
+// It is used to expose the scale-from-zero annotations consumed by the cluster autoscaler.
+// https://github.com/openshift/enhancements/pull/186
+package capacity
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// CPUKey is the annotation holding the number of vCPUs of the machines in a MachineSet.
+	CPUKey = "machine.openshift.io/vCPU"
+	// MemoryKey is the annotation holding the memory, in MiB, of the machines in a MachineSet.
+	MemoryKey = "machine.openshift.io/memoryMb"
+	// GPUKey is the annotation holding the number of GPUs of the machines in a MachineSet.
+	GPUKey = "machine.openshift.io/GPU"
+	// LabelsKey is the annotation holding the comma-separated node labels of the machines in a MachineSet,
+	// e.g. their kubernetes.io/arch label.
+	LabelsKey = "capacity.cluster-autoscaler.kubernetes.io/labels"
+
+	awsProviderSpecKind     = "AWSMachineProviderConfig"
+	azureProviderSpecKind   = "AzureMachineProviderSpec"
+	gcpProviderSpecKind     = "GCPMachineProviderSpec"
+	vsphereProviderSpecKind = "VSphereMachineProviderSpec"
+)
+
+// gcpCustomMachineTypeRegex matches GCP custom machine types, e.g. custom-4-16384 or n2-custom-8-32768.
+var gcpCustomMachineTypeRegex = regexp.MustCompile(`^(?:[a-z0-9]+-)?custom-([0-9]+)-([0-9]+)(?:-ext)?$`)
+
+// Capacity describes the resources of a single machine.
+type Capacity struct {
+	CPU      int64
+	MemoryMb int64
+	GPU      int64
+	// Arch is the CPU architecture, as reported by the kubernetes.io/arch node label.
+	Arch string
+}
+
+// Annotations returns the scale-from-zero annotations describing the capacity.
+func (c *Capacity) Annotations() map[string]string {
+	return map[string]string{
+		CPUKey:    strconv.FormatInt(c.CPU, 10),
+		MemoryKey: strconv.FormatInt(c.MemoryMb, 10),
+		GPUKey:    strconv.FormatInt(c.GPU, 10),
+	}
+}
+
+// ArchLabel returns the kubernetes.io/arch node label of the machines, in the format of the LabelsKey annotation.
+// It returns an empty string when the architecture is not known.
+func (c *Capacity) ArchLabel() string {
+	if c.Arch == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s=%s", corev1.LabelArchStable, c.Arch)
+}
+
+// FromProviderSpec returns the capacity of the machine described by the providerSpec.
+// It returns nil, without an error, when the platform or the instance type is not known,
+// in which case the capacity must be provided by other means.
+func FromProviderSpec(providerSpec *runtime.RawExtension) (*Capacity, error) {
+	if providerSpec == nil || len(providerSpec.Raw) == 0 {
+		return nil, nil
+	}
+
+	typeMeta := &metav1.TypeMeta{}
+	if err := json.Unmarshal(providerSpec.Raw, typeMeta); err != nil {
+		return nil, fmt.Errorf("error unmarshalling providerSpec: %v", err)
+	}
+
+	switch typeMeta.Kind {
+	case awsProviderSpecKind:
+		spec := &machinev1.AWSMachineProviderConfig{}
+		if err := json.Unmarshal(providerSpec.Raw, spec); err != nil {
+			return nil, fmt.Errorf("error unmarshalling providerSpec: %v", err)
+		}
+		return lookup(awsInstanceTypes, spec.InstanceType), nil
+	case azureProviderSpecKind:
+		spec := &machinev1.AzureMachineProviderSpec{}
+		if err := json.Unmarshal(providerSpec.Raw, spec); err != nil {
+			return nil, fmt.Errorf("error unmarshalling providerSpec: %v", err)
+		}
+		return lookup(azureVMSizes, spec.VMSize), nil
+	case gcpProviderSpecKind:
+		spec := &machinev1.GCPMachineProviderSpec{}
+		if err := json.Unmarshal(providerSpec.Raw, spec); err != nil {
+			return nil, fmt.Errorf("error unmarshalling providerSpec: %v", err)
+		}
+		return gcpCapacity(spec), nil
+	case vsphereProviderSpecKind:
+		spec := &machinev1.VSphereMachineProviderSpec{}
+		if err := json.Unmarshal(providerSpec.Raw, spec); err != nil {
+			return nil, fmt.Errorf("error unmarshalling providerSpec: %v", err)
+		}
+		if spec.NumCPUs == 0 || spec.MemoryMiB == 0 {
+			return nil, nil
+		}
+		return &Capacity{CPU: int64(spec.NumCPUs), MemoryMb: spec.MemoryMiB, Arch: archAMD64}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func lookup(table map[string]Capacity, instanceType string) *Capacity {
+	c, ok := table[instanceType]
+	if !ok {
+		return nil
+	}
+	return &c
+}
+
+func gcpCapacity(spec *machinev1.GCPMachineProviderSpec) *Capacity {
+	c := lookup(gcpMachineTypes, spec.MachineType)
+	if c == nil {
+		matches := gcpCustomMachineTypeRegex.FindStringSubmatch(spec.MachineType)
+		if matches == nil {
+			return nil
+		}
+		// The regex guarantees both values are numeric.
+		cpu, _ := strconv.ParseInt(matches[1], 10, 64)
+		memory, _ := strconv.ParseInt(matches[2], 10, 64)
+		c = &Capacity{CPU: cpu, MemoryMb: memory, Arch: archAMD64}
+	}
+
+	// GPUs attached to the instance are additional to any built in to the machine type.
+	for _, gpu := range spec.GPUs {
+		c.GPU += int64(gpu.Count)
+	}
+
+	return c
+}
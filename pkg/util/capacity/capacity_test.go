@@ -0,0 +1,132 @@
+package capacity
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func rawExtension(t *testing.T, kind string, spec interface{}) *runtime.RawExtension {
+	t.Helper()
+
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatalf("failed to marshal providerSpec: %v", err)
+	}
+
+	// Inject the kind, the providerSpec structs embed TypeMeta.
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		t.Fatalf("failed to unmarshal providerSpec: %v", err)
+	}
+	obj["kind"] = kind
+	if raw, err = json.Marshal(obj); err != nil {
+		t.Fatalf("failed to marshal providerSpec: %v", err)
+	}
+
+	return &runtime.RawExtension{Raw: raw}
+}
+
+func TestFromProviderSpec(t *testing.T) {
+	testCases := []struct {
+		name         string
+		providerSpec func(t *testing.T) *runtime.RawExtension
+		expected     *Capacity
+	}{
+		{
+			name:         "with no providerSpec",
+			providerSpec: func(*testing.T) *runtime.RawExtension { return nil },
+			expected:     nil,
+		},
+		{
+			name: "with a known AWS instance type",
+			providerSpec: func(t *testing.T) *runtime.RawExtension {
+				return rawExtension(t, awsProviderSpecKind, &machinev1.AWSMachineProviderConfig{InstanceType: "m6g.xlarge"})
+			},
+			expected: &Capacity{CPU: 4, MemoryMb: 16384, Arch: archARM64},
+		},
+		{
+			name: "with an unknown AWS instance type",
+			providerSpec: func(t *testing.T) *runtime.RawExtension {
+				return rawExtension(t, awsProviderSpecKind, &machinev1.AWSMachineProviderConfig{InstanceType: "unknown.large"})
+			},
+			expected: nil,
+		},
+		{
+			name: "with a known Azure VM size with GPUs",
+			providerSpec: func(t *testing.T) *runtime.RawExtension {
+				return rawExtension(t, azureProviderSpecKind, &machinev1.AzureMachineProviderSpec{VMSize: "Standard_NC12s_v3"})
+			},
+			expected: &Capacity{CPU: 12, MemoryMb: 229376, GPU: 2, Arch: archAMD64},
+		},
+		{
+			name: "with a known GCP machine type and attached GPUs",
+			providerSpec: func(t *testing.T) *runtime.RawExtension {
+				return rawExtension(t, gcpProviderSpecKind, &machinev1.GCPMachineProviderSpec{
+					MachineType: "n1-standard-4",
+					GPUs:        []machinev1.GCPGPUConfig{{Count: 2, Type: "nvidia-tesla-t4"}},
+				})
+			},
+			expected: &Capacity{CPU: 4, MemoryMb: 15360, GPU: 2, Arch: archAMD64},
+		},
+		{
+			name: "with a GCP custom machine type",
+			providerSpec: func(t *testing.T) *runtime.RawExtension {
+				return rawExtension(t, gcpProviderSpecKind, &machinev1.GCPMachineProviderSpec{MachineType: "n2-custom-6-20480"})
+			},
+			expected: &Capacity{CPU: 6, MemoryMb: 20480, Arch: archAMD64},
+		},
+		{
+			name: "with a vSphere providerSpec",
+			providerSpec: func(t *testing.T) *runtime.RawExtension {
+				return rawExtension(t, vsphereProviderSpecKind, &machinev1.VSphereMachineProviderSpec{NumCPUs: 8, MemoryMiB: 32768})
+			},
+			expected: &Capacity{CPU: 8, MemoryMb: 32768, Arch: archAMD64},
+		},
+		{
+			name: "with an unknown platform",
+			providerSpec: func(t *testing.T) *runtime.RawExtension {
+				return &runtime.RawExtension{Raw: []byte(`{"kind":"OpenstackProviderSpec","flavor":"m1.large"}`)}
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c, err := FromProviderSpec(tc.providerSpec(t))
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(c).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestFromProviderSpecInvalid(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := FromProviderSpec(&runtime.RawExtension{Raw: []byte(`{"kind":`)})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestAnnotations(t *testing.T) {
+	g := NewWithT(t)
+
+	c := &Capacity{CPU: 4, MemoryMb: 16384, GPU: 1}
+	g.Expect(c.Annotations()).To(Equal(map[string]string{
+		CPUKey:    "4",
+		MemoryKey: "16384",
+		GPUKey:    "1",
+	}))
+}
+
+func TestArchLabel(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect((&Capacity{CPU: 2, MemoryMb: 8192, Arch: archARM64}).ArchLabel()).To(Equal("kubernetes.io/arch=arm64"))
+	g.Expect((&Capacity{CPU: 2, MemoryMb: 8192}).ArchLabel()).To(BeEmpty())
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacity
+
+const (
+	archAMD64 = "amd64"
+	archARM64 = "arm64"
+)
+
+// The following tables list the capacity of commonly used instance types.
+// They are not exhaustive; instance types which are not listed are left for the
+// user, or a provider specific controller, to annotate.
+
+var awsInstanceTypes = map[string]Capacity{
+	"t3.medium":    {CPU: 2, MemoryMb: 4096, Arch: archAMD64},
+	"t3.large":     {CPU: 2, MemoryMb: 8192, Arch: archAMD64},
+	"t3.xlarge":    {CPU: 4, MemoryMb: 16384, Arch: archAMD64},
+	"t3.2xlarge":   {CPU: 8, MemoryMb: 32768, Arch: archAMD64},
+	"m4.large":     {CPU: 2, MemoryMb: 8192, Arch: archAMD64},
+	"m4.xlarge":    {CPU: 4, MemoryMb: 16384, Arch: archAMD64},
+	"m4.2xlarge":   {CPU: 8, MemoryMb: 32768, Arch: archAMD64},
+	"m4.4xlarge":   {CPU: 16, MemoryMb: 65536, Arch: archAMD64},
+	"m5.large":     {CPU: 2, MemoryMb: 8192, Arch: archAMD64},
+	"m5.xlarge":    {CPU: 4, MemoryMb: 16384, Arch: archAMD64},
+	"m5.2xlarge":   {CPU: 8, MemoryMb: 32768, Arch: archAMD64},
+	"m5.4xlarge":   {CPU: 16, MemoryMb: 65536, Arch: archAMD64},
+	"m5.8xlarge":   {CPU: 32, MemoryMb: 131072, Arch: archAMD64},
+	"m5.12xlarge":  {CPU: 48, MemoryMb: 196608, Arch: archAMD64},
+	"m5.16xlarge":  {CPU: 64, MemoryMb: 262144, Arch: archAMD64},
+	"m5.24xlarge":  {CPU: 96, MemoryMb: 393216, Arch: archAMD64},
+	"m6i.large":    {CPU: 2, MemoryMb: 8192, Arch: archAMD64},
+	"m6i.xlarge":   {CPU: 4, MemoryMb: 16384, Arch: archAMD64},
+	"m6i.2xlarge":  {CPU: 8, MemoryMb: 32768, Arch: archAMD64},
+	"m6i.4xlarge":  {CPU: 16, MemoryMb: 65536, Arch: archAMD64},
+	"m6g.large":    {CPU: 2, MemoryMb: 8192, Arch: archARM64},
+	"m6g.xlarge":   {CPU: 4, MemoryMb: 16384, Arch: archARM64},
+	"m6g.2xlarge":  {CPU: 8, MemoryMb: 32768, Arch: archARM64},
+	"m6g.4xlarge":  {CPU: 16, MemoryMb: 65536, Arch: archARM64},
+	"c5.large":     {CPU: 2, MemoryMb: 4096, Arch: archAMD64},
+	"c5.xlarge":    {CPU: 4, MemoryMb: 8192, Arch: archAMD64},
+	"c5.2xlarge":   {CPU: 8, MemoryMb: 16384, Arch: archAMD64},
+	"c5.4xlarge":   {CPU: 16, MemoryMb: 32768, Arch: archAMD64},
+	"r5.large":     {CPU: 2, MemoryMb: 16384, Arch: archAMD64},
+	"r5.xlarge":    {CPU: 4, MemoryMb: 32768, Arch: archAMD64},
+	"r5.2xlarge":   {CPU: 8, MemoryMb: 65536, Arch: archAMD64},
+	"r5.4xlarge":   {CPU: 16, MemoryMb: 131072, Arch: archAMD64},
+	"p3.2xlarge":   {CPU: 8, MemoryMb: 62464, GPU: 1, Arch: archAMD64},
+	"p3.8xlarge":   {CPU: 32, MemoryMb: 249856, GPU: 4, Arch: archAMD64},
+	"p3.16xlarge":  {CPU: 64, MemoryMb: 499712, GPU: 8, Arch: archAMD64},
+	"g4dn.xlarge":  {CPU: 4, MemoryMb: 16384, GPU: 1, Arch: archAMD64},
+	"g4dn.2xlarge": {CPU: 8, MemoryMb: 32768, GPU: 1, Arch: archAMD64},
+	"g4dn.4xlarge": {CPU: 16, MemoryMb: 65536, GPU: 1, Arch: archAMD64},
+	"g4dn.8xlarge": {CPU: 32, MemoryMb: 131072, GPU: 1, Arch: archAMD64},
+}
+
+var azureVMSizes = map[string]Capacity{
+	"Standard_D2s_v3":   {CPU: 2, MemoryMb: 8192, Arch: archAMD64},
+	"Standard_D4s_v3":   {CPU: 4, MemoryMb: 16384, Arch: archAMD64},
+	"Standard_D8s_v3":   {CPU: 8, MemoryMb: 32768, Arch: archAMD64},
+	"Standard_D16s_v3":  {CPU: 16, MemoryMb: 65536, Arch: archAMD64},
+	"Standard_D32s_v3":  {CPU: 32, MemoryMb: 131072, Arch: archAMD64},
+	"Standard_D2s_v5":   {CPU: 2, MemoryMb: 8192, Arch: archAMD64},
+	"Standard_D4s_v5":   {CPU: 4, MemoryMb: 16384, Arch: archAMD64},
+	"Standard_D8s_v5":   {CPU: 8, MemoryMb: 32768, Arch: archAMD64},
+	"Standard_D16s_v5":  {CPU: 16, MemoryMb: 65536, Arch: archAMD64},
+	"Standard_D4ps_v5":  {CPU: 4, MemoryMb: 16384, Arch: archARM64},
+	"Standard_D8ps_v5":  {CPU: 8, MemoryMb: 32768, Arch: archARM64},
+	"Standard_E4s_v3":   {CPU: 4, MemoryMb: 32768, Arch: archAMD64},
+	"Standard_E8s_v3":   {CPU: 8, MemoryMb: 65536, Arch: archAMD64},
+	"Standard_E16s_v3":  {CPU: 16, MemoryMb: 131072, Arch: archAMD64},
+	"Standard_F4s_v2":   {CPU: 4, MemoryMb: 8192, Arch: archAMD64},
+	"Standard_F8s_v2":   {CPU: 8, MemoryMb: 16384, Arch: archAMD64},
+	"Standard_F16s_v2":  {CPU: 16, MemoryMb: 32768, Arch: archAMD64},
+	"Standard_NC6s_v3":  {CPU: 6, MemoryMb: 114688, GPU: 1, Arch: archAMD64},
+	"Standard_NC12s_v3": {CPU: 12, MemoryMb: 229376, GPU: 2, Arch: archAMD64},
+	"Standard_NC24s_v3": {CPU: 24, MemoryMb: 458752, GPU: 4, Arch: archAMD64},
+}
+
+var gcpMachineTypes = map[string]Capacity{
+	"e2-standard-2":  {CPU: 2, MemoryMb: 8192, Arch: archAMD64},
+	"e2-standard-4":  {CPU: 4, MemoryMb: 16384, Arch: archAMD64},
+	"e2-standard-8":  {CPU: 8, MemoryMb: 32768, Arch: archAMD64},
+	"e2-standard-16": {CPU: 16, MemoryMb: 65536, Arch: archAMD64},
+	"n1-standard-1":  {CPU: 1, MemoryMb: 3840, Arch: archAMD64},
+	"n1-standard-2":  {CPU: 2, MemoryMb: 7680, Arch: archAMD64},
+	"n1-standard-4":  {CPU: 4, MemoryMb: 15360, Arch: archAMD64},
+	"n1-standard-8":  {CPU: 8, MemoryMb: 30720, Arch: archAMD64},
+	"n1-standard-16": {CPU: 16, MemoryMb: 61440, Arch: archAMD64},
+	"n1-standard-32": {CPU: 32, MemoryMb: 122880, Arch: archAMD64},
+	"n2-standard-2":  {CPU: 2, MemoryMb: 8192, Arch: archAMD64},
+	"n2-standard-4":  {CPU: 4, MemoryMb: 16384, Arch: archAMD64},
+	"n2-standard-8":  {CPU: 8, MemoryMb: 32768, Arch: archAMD64},
+	"n2-standard-16": {CPU: 16, MemoryMb: 65536, Arch: archAMD64},
+	"n2-standard-32": {CPU: 32, MemoryMb: 131072, Arch: archAMD64},
+	"n2d-standard-4": {CPU: 4, MemoryMb: 16384, Arch: archAMD64},
+	"n2d-standard-8": {CPU: 8, MemoryMb: 32768, Arch: archAMD64},
+	"t2a-standard-4": {CPU: 4, MemoryMb: 16384, Arch: archARM64},
+	"t2a-standard-8": {CPU: 8, MemoryMb: 32768, Arch: archARM64},
+	"a2-highgpu-1g":  {CPU: 12, MemoryMb: 87040, GPU: 1, Arch: archAMD64},
+	"a2-highgpu-2g":  {CPU: 24, MemoryMb: 174080, GPU: 2, Arch: archAMD64},
+	"a2-highgpu-4g":  {CPU: 48, MemoryMb: 348160, GPU: 4, Arch: archAMD64},
+}
@@ -173,6 +173,41 @@ func TestSet(t *testing.T) {
 	}
 }
 
+func TestDelete(t *testing.T) {
+	a := TrueCondition("a")
+	b := TrueCondition("b")
+
+	tests := []struct {
+		name string
+		to   *machinev1.MachineHealthCheck
+		t    machinev1.ConditionType
+		want machinev1.Conditions
+	}{
+		{
+			name: "Delete removes the condition",
+			to:   setterWithConditions(a, b),
+			t:    "a",
+			want: conditionList(b),
+		},
+		{
+			name: "Delete ignores missing conditions",
+			to:   setterWithConditions(a, b),
+			t:    "c",
+			want: conditionList(a, b),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			Delete(tt.to, tt.t)
+
+			g.Expect(tt.to.Status.Conditions).To(haveSameConditionsOf(tt.want))
+		})
+	}
+}
+
 func TestSetLastTransitionTime(t *testing.T) {
 	x := metav1.Date(2012, time.January, 1, 12, 15, 30, 5e8, time.UTC)
 
@@ -272,3 +307,20 @@ func (matcher *ConditionsMatcher) FailureMessage(actual interface{}) (message st
 func (matcher *ConditionsMatcher) NegatedFailureMessage(actual interface{}) (message string) {
 	return format.Message(actual, "not to have the same conditions of", matcher.Expected)
 }
+
+func TestMachineSetConditions(t *testing.T) {
+	g := NewWithT(t)
+
+	ms := &machinev1.MachineSet{}
+	g.Expect(Get(ms, "conditionBaz")).To(BeNil())
+
+	MarkTrue(ms, "conditionBaz")
+	g.Expect(ms.Annotations).To(HaveKey(MachineSetConditionsAnnotation))
+	g.Expect(Get(ms, "conditionBaz")).To(haveSameStateOf(TrueCondition("conditionBaz")))
+
+	MarkFalse(ms, "conditionBaz", "reason falseInfo1", machinev1.ConditionSeverityInfo, "message falseInfo1")
+	g.Expect(Get(ms, "conditionBaz")).To(haveSameStateOf(FalseCondition("conditionBaz", "reason falseInfo1", machinev1.ConditionSeverityInfo, "message falseInfo1")))
+
+	ms.Annotations[MachineSetConditionsAnnotation] = "not json"
+	g.Expect(Get(ms, "conditionBaz")).To(BeNil())
+}
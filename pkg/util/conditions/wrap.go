@@ -1,6 +1,8 @@
 package conditions
 
 import (
+	"encoding/json"
+
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 )
 
@@ -27,3 +29,40 @@ func (m *MachineHealthCheckWrapper) GetConditions() machinev1.Conditions {
 func (m *MachineHealthCheckWrapper) SetConditions(conditions machinev1.Conditions) {
 	m.Status.Conditions = conditions
 }
+
+// MachineSetConditionsAnnotation holds the conditions of a MachineSet.
+// The MachineSet status does not have a conditions field, so they are stored
+// as JSON in this annotation until the API provides one.
+const MachineSetConditionsAnnotation = "machine.openshift.io/conditions"
+
+type MachineSetWrapper struct {
+	*machinev1.MachineSet
+}
+
+func (m *MachineSetWrapper) GetConditions() machinev1.Conditions {
+	raw, ok := m.Annotations[MachineSetConditionsAnnotation]
+	if !ok {
+		return nil
+	}
+
+	conditions := machinev1.Conditions{}
+	if err := json.Unmarshal([]byte(raw), &conditions); err != nil {
+		// The annotation is owned by the controller, treat malformed values as unset
+		// so that they are overwritten with the next update.
+		return nil
+	}
+	return conditions
+}
+
+func (m *MachineSetWrapper) SetConditions(conditions machinev1.Conditions) {
+	raw, err := json.Marshal(conditions)
+	if err != nil {
+		// Conditions only contain plain fields, this cannot happen.
+		panic(err)
+	}
+
+	if m.Annotations == nil {
+		m.Annotations = make(map[string]string)
+	}
+	m.Annotations[MachineSetConditionsAnnotation] = string(raw)
+}
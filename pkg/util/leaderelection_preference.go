@@ -0,0 +1,297 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// PreferredLeaderCandidateAnnotation is set on the lease by the preferred candidates waiting for a leader
+	// which is not preferred, to the time they last asked it to step down.
+	PreferredLeaderCandidateAnnotation = "machine.openshift.io/preferred-leader-candidate"
+
+	// preferredIdentitySuffix marks the identities of the preferred candidates, so that the candidates know
+	// whether the leader is preferred from the lease.
+	preferredIdentitySuffix = "_preferred"
+
+	inClusterNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// LeaderElectionPreference prefers the leader election candidates running on the preferred nodes, the nodes
+// matching a selector or running a kube-apiserver, to reduce the cross zone API traffic and the failover time.
+// The candidates which are not preferred wait an extra lease duration before acquiring an expired lease, and
+// a leader which is not preferred steps down once a preferred candidate is waiting for the lease.
+type LeaderElectionPreference struct {
+	// NodeSelector is the label selector of the preferred nodes.
+	NodeSelector string
+	// PreferAPIServerNodes prefers the nodes running one of the kube-apiservers of the kubernetes service.
+	PreferAPIServerNodes bool
+}
+
+// AddFlags adds the flags of the leader election preference to the flag set.
+func (p *LeaderElectionPreference) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&p.NodeSelector, "leader-elect-preferred-node-selector", "", "Label selector of the nodes whose leader election candidates are preferred, e.g. node-role.kubernetes.io/master. Requires the NODE_NAME environment variable, or the permission to get the pod of the candidate.")
+	fs.BoolVar(&p.PreferAPIServerNodes, "leader-elect-prefer-apiserver-nodes", false, "Prefer the leader election candidates running on a node of one of the kube-apiservers of the kubernetes service. Requires the NODE_NAME environment variable, or the permission to get the pod of the candidate.")
+}
+
+// Enabled returns true if some candidates are preferred.
+func (p *LeaderElectionPreference) Enabled() bool {
+	return p.NodeSelector != "" || p.PreferAPIServerNodes
+}
+
+// NewResourceLock returns the lease lock of the leader election of the manager options, preferring the candidates
+// running on the preferred nodes. Whether the candidate is preferred is decided once, when the lock is created.
+func (p *LeaderElectionPreference) NewResourceLock(config *rest.Config, options manager.Options) (resourcelock.Interface, error) {
+	if options.LeaderElectionID == "" {
+		return nil, errors.New("LeaderElectionID must be configured")
+	}
+	if options.LeaseDuration == nil {
+		return nil, errors.New("LeaseDuration must be configured")
+	}
+	namespace := options.LeaderElectionNamespace
+	if namespace == "" {
+		data, err := os.ReadFile(inClusterNamespacePath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find leader election namespace: %v", err)
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	config = rest.CopyConfig(config)
+	rest.AddUserAgent(config, "leader-election")
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	nodeName, err := candidateNodeName(ctx, client, namespace, hostname)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find the node of the leader election candidate: %v", err)
+	}
+	preferred, err := p.isPreferred(ctx, client, nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find whether node %s is preferred for leader election: %v", nodeName, err)
+	}
+	klog.Infof("Leader election candidate on node %s is preferred: %t", nodeName, preferred)
+
+	return newPreferredLeaseLock(client.CoordinationV1(), namespace, options.LeaderElectionID, hostname+"_"+string(uuid.NewUUID()), preferred, *options.LeaseDuration), nil
+}
+
+// candidateNodeName returns the node of the candidate, from the NODE_NAME environment variable set with the
+// downward API, or from its pod, whose name is the hostname.
+func candidateNodeName(ctx context.Context, client kubernetes.Interface, namespace, hostname string) (string, error) {
+	if nodeName := os.Getenv("NODE_NAME"); nodeName != "" {
+		return nodeName, nil
+	}
+	pod, err := client.CoreV1().Pods(namespace).Get(ctx, hostname, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if pod.Spec.NodeName == "" {
+		return "", fmt.Errorf("pod %s/%s is not scheduled", namespace, hostname)
+	}
+	return pod.Spec.NodeName, nil
+}
+
+// isPreferred returns true if the node matches the node selector, or runs a kube-apiserver of the kubernetes service.
+func (p *LeaderElectionPreference) isPreferred(ctx context.Context, client kubernetes.Interface, nodeName string) (bool, error) {
+	node, err := client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	if p.NodeSelector != "" {
+		selector, err := labels.Parse(p.NodeSelector)
+		if err != nil {
+			return false, fmt.Errorf("invalid node selector %q: %v", p.NodeSelector, err)
+		}
+		if selector.Matches(labels.Set(node.Labels)) {
+			return true, nil
+		}
+	}
+	if !p.PreferAPIServerNodes {
+		return false, nil
+	}
+
+	// The kube-apiservers run on the host network, the addresses of the kubernetes service are the ones of their nodes.
+	endpoints, err := client.CoreV1().Endpoints(metav1.NamespaceDefault).Get(ctx, "kubernetes", metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	apiServerAddresses := sets.NewString()
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			apiServerAddresses.Insert(address.IP)
+		}
+	}
+	for _, address := range node.Status.Addresses {
+		if (address.Type == corev1.NodeInternalIP || address.Type == corev1.NodeExternalIP) && apiServerAddresses.Has(address.Address) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// preferredLeaseLock is a lease lock which defers the acquisition of the lease by a candidate which is not
+// preferred, and steps down such a leader when a preferred candidate is waiting.
+type preferredLeaseLock struct {
+	resourcelock.Interface
+
+	leases        coordinationv1client.LeaseInterface
+	name          string
+	preferred     bool
+	leaseDuration time.Duration
+	now           func() time.Time
+
+	mu sync.Mutex
+	// observed is the last record of the lease, and observedTime when it was first observed.
+	observed     *resourcelock.LeaderElectionRecord
+	observedRaw  []byte
+	observedTime time.Time
+	// askedHolder is the leader last asked to step down, and askedTime when it was last asked.
+	askedHolder string
+	askedTime   time.Time
+}
+
+func newPreferredLeaseLock(client coordinationv1client.LeasesGetter, namespace, name, identity string, preferred bool, leaseDuration time.Duration) *preferredLeaseLock {
+	if preferred {
+		identity += preferredIdentitySuffix
+	}
+	return &preferredLeaseLock{
+		Interface: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Client:     client,
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		leases:        client.Leases(namespace),
+		name:          name,
+		preferred:     preferred,
+		leaseDuration: leaseDuration,
+		now:           time.Now,
+	}
+}
+
+// Get returns the record of the lease. A preferred candidate waiting for a leader which is not preferred
+// asks it to step down.
+func (l *preferredLeaseLock) Get(ctx context.Context) (*resourcelock.LeaderElectionRecord, []byte, error) {
+	record, raw, err := l.Interface.Get(ctx)
+	if err != nil {
+		return record, raw, err
+	}
+
+	l.mu.Lock()
+	if l.observed == nil || !bytes.Equal(l.observedRaw, raw) {
+		l.observed = record
+		l.observedRaw = raw
+		l.observedTime = l.now()
+	}
+	ask := l.preferred && record.HolderIdentity != "" && record.HolderIdentity != l.Identity() &&
+		!isPreferredIdentity(record.HolderIdentity) && l.shouldAsk(record.HolderIdentity)
+	l.mu.Unlock()
+
+	if ask {
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, PreferredLeaderCandidateAnnotation, l.now().UTC().Format(time.RFC3339))
+		if _, err := l.leases.Patch(ctx, l.name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			klog.Warningf("Unable to ask leader %s to step down for a preferred candidate: %v", record.HolderIdentity, err)
+			return record, raw, nil
+		}
+		l.mu.Lock()
+		l.askedHolder = record.HolderIdentity
+		l.askedTime = l.now()
+		l.mu.Unlock()
+	}
+	return record, raw, nil
+}
+
+// shouldAsk returns true if the leader was not asked to step down yet, or was last asked more than half a lease
+// duration ago, so that the lease is not patched every retry period while the annotation is still fresh.
+// It must be called with the lock held.
+func (l *preferredLeaseLock) shouldAsk(holder string) bool {
+	return l.askedHolder != holder || l.now().Sub(l.askedTime) >= l.leaseDuration/2
+}
+
+// Update acquires or renews the lease. A candidate which is not preferred only acquires the lease an extra
+// lease duration after the preferred candidates could, and does not renew it while a preferred candidate is waiting.
+func (l *preferredLeaseLock) Update(ctx context.Context, record resourcelock.LeaderElectionRecord) error {
+	if l.preferred {
+		return l.Interface.Update(ctx, record)
+	}
+
+	l.mu.Lock()
+	acquiring := l.observed == nil || l.observed.HolderIdentity != l.Identity()
+	deferred := false
+	if acquiring && l.observed != nil {
+		wait := l.leaseDuration
+		if l.observed.HolderIdentity != "" {
+			// The lease is only expired after a lease duration.
+			wait += l.leaseDuration
+		}
+		deferred = l.now().Sub(l.observedTime) < wait
+	}
+	l.mu.Unlock()
+
+	if deferred {
+		return errors.New("deferring the acquisition of the lease to the preferred candidates")
+	}
+	if !acquiring {
+		waiting, err := l.preferredCandidateWaiting(ctx)
+		if err != nil {
+			return err
+		}
+		if waiting {
+			klog.Infof("Stepping down for a preferred leader election candidate")
+			return errors.New("not renewing the lease, a preferred candidate is waiting for it")
+		}
+	}
+	return l.Interface.Update(ctx, record)
+}
+
+// preferredCandidateWaiting returns true if a preferred candidate asked to step down in the last lease duration.
+func (l *preferredLeaseLock) preferredCandidateWaiting(ctx context.Context) (bool, error) {
+	lease, err := l.leases.Get(ctx, l.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	value, ok := lease.Annotations[PreferredLeaderCandidateAnnotation]
+	if !ok {
+		return false, nil
+	}
+	askedAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		klog.Warningf("Ignoring invalid %s annotation %q: %v", PreferredLeaderCandidateAnnotation, value, err)
+		return false, nil
+	}
+	return l.now().Sub(askedAt) < l.leaseDuration, nil
+}
+
+func isPreferredIdentity(identity string) bool {
+	return strings.HasSuffix(identity, preferredIdentitySuffix)
+}
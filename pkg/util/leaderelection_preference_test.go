@@ -0,0 +1,168 @@
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/utils/pointer"
+)
+
+func TestLeaderElectionPreferenceIsPreferred(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "master-0", Labels: map[string]string{"node-role.kubernetes.io/master": ""}},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeHostName, Address: "master-0"},
+			{Type: corev1.NodeInternalIP, Address: "10.0.0.10"},
+		}},
+	}
+	endpoints := func(ips ...string) *corev1.Endpoints {
+		e := &corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "kubernetes"}}
+		subset := corev1.EndpointSubset{}
+		for _, ip := range ips {
+			subset.Addresses = append(subset.Addresses, corev1.EndpointAddress{IP: ip})
+		}
+		e.Subsets = []corev1.EndpointSubset{subset}
+		return e
+	}
+
+	testCases := []struct {
+		name       string
+		preference LeaderElectionPreference
+		endpoints  *corev1.Endpoints
+		expected   bool
+	}{
+		{
+			name:       "with a matching node selector",
+			preference: LeaderElectionPreference{NodeSelector: "node-role.kubernetes.io/master"},
+			expected:   true,
+		},
+		{
+			name:       "with a node selector not matching",
+			preference: LeaderElectionPreference{NodeSelector: "node-role.kubernetes.io/infra"},
+			expected:   false,
+		},
+		{
+			name:       "on the node of a kube-apiserver",
+			preference: LeaderElectionPreference{PreferAPIServerNodes: true},
+			endpoints:  endpoints("10.0.0.11", "10.0.0.10"),
+			expected:   true,
+		},
+		{
+			name:       "on another node than the kube-apiservers",
+			preference: LeaderElectionPreference{PreferAPIServerNodes: true},
+			endpoints:  endpoints("10.0.0.11", "10.0.0.12"),
+			expected:   false,
+		},
+		{
+			name:       "with a node selector not matching on the node of a kube-apiserver",
+			preference: LeaderElectionPreference{NodeSelector: "node-role.kubernetes.io/infra", PreferAPIServerNodes: true},
+			endpoints:  endpoints("10.0.0.10"),
+			expected:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			client := fake.NewSimpleClientset(node)
+			if tc.endpoints != nil {
+				g.Expect(client.Tracker().Add(tc.endpoints)).To(Succeed())
+			}
+			preferred, err := tc.preference.isPreferred(context.Background(), client, node.Name)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(preferred).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestPreferredLeaseLock(t *testing.T) {
+	const (
+		namespace     = "openshift-machine-api"
+		name          = "cluster-api-provider-machineset-leader"
+		leaseDuration = 120 * time.Second
+	)
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	client := fake.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       pointer.String("old-leader"),
+			LeaseDurationSeconds: pointer.Int32(120),
+			RenewTime:            &metav1.MicroTime{Time: now},
+		},
+	})
+	newLock := func(identity string, preferred bool) *preferredLeaseLock {
+		l := newPreferredLeaseLock(client.CoordinationV1(), namespace, name, identity, preferred, leaseDuration)
+		l.now = func() time.Time { return now }
+		return l
+	}
+	preferredCandidate := newLock("master-0", true)
+	candidate := newLock("worker-0", false)
+	g.Expect(preferredCandidate.Identity()).To(Equal("master-0_preferred"))
+	g.Expect(candidate.Identity()).To(Equal("worker-0"))
+
+	record := func(l resourcelock.Interface) resourcelock.LeaderElectionRecord {
+		return resourcelock.LeaderElectionRecord{HolderIdentity: l.Identity(), LeaseDurationSeconds: 120}
+	}
+
+	// The old leader stops renewing the lease, which expires after a lease duration.
+	_, _, err := candidate.Get(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	now = now.Add(leaseDuration + time.Second)
+	g.Expect(candidate.Update(ctx, record(candidate))).ToNot(Succeed(), "expected the candidate to defer to the preferred candidates")
+
+	// Without a preferred candidate, the candidate acquires the lease after another lease duration.
+	now = now.Add(leaseDuration)
+	g.Expect(candidate.Update(ctx, record(candidate))).To(Succeed())
+
+	// The leader renews the lease while no preferred candidate is waiting.
+	_, _, err = candidate.Get(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(candidate.Update(ctx, record(candidate))).To(Succeed())
+
+	// A preferred candidate asks the leader to step down.
+	_, _, err = preferredCandidate.Get(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	lease, err := client.CoordinationV1().Leases(namespace).Get(ctx, name, metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(lease.Annotations).To(HaveKeyWithValue(PreferredLeaderCandidateAnnotation, now.Format(time.RFC3339)))
+
+	// The lease is not patched again while the request to step down is still fresh.
+	client.ClearActions()
+	_, _, err = preferredCandidate.Get(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, action := range client.Actions() {
+		g.Expect(action.GetVerb()).ToNot(Equal("patch"))
+	}
+
+	_, _, err = candidate.Get(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(candidate.Update(ctx, record(candidate))).ToNot(Succeed(), "expected the leader to step down for the preferred candidate")
+
+	// The preferred candidate acquires the lease once it expired.
+	now = now.Add(leaseDuration + time.Second)
+	_, _, err = preferredCandidate.Get(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(preferredCandidate.Update(ctx, record(preferredCandidate))).To(Succeed())
+
+	// The preferred leader is not asked to step down.
+	_, _, err = candidate.Get(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	now = now.Add(time.Minute)
+	_, _, err = preferredCandidate.Get(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(preferredCandidate.Update(ctx, record(preferredCandidate))).To(Succeed())
+	lease, err = client.CoordinationV1().Leases(namespace).Get(ctx, name, metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(*lease.Spec.HolderIdentity).To(Equal("master-0_preferred"))
+}
@@ -0,0 +1,156 @@
+// Package logging configures the format of the logs of the Machine API commands.
+package logging
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"k8s.io/klog/v2"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// FormatText is the default klog text format.
+	FormatText = "text"
+	// FormatJSON is a structured JSON format, with one object per line.
+	FormatJSON = "json"
+)
+
+// Options configures the format of the logs.
+type Options struct {
+	Format string
+}
+
+// AddFlags adds the flags of the logging options to the flag set.
+func (o *Options) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Format, "logging-format", FormatText, fmt.Sprintf("Format of the logs, either %s or %s.", FormatText, FormatJSON))
+}
+
+// Setup configures klog and the controller-runtime loggers with the format. With the JSON format,
+// the logs of klog are written by the JSON logger, which keeps the verbosity set by the -v flag.
+func (o *Options) Setup() error {
+	switch o.Format {
+	case FormatText, "":
+		return nil
+	case FormatJSON:
+		level := zap.NewAtomicLevelAt(zapLevel(verbosity()))
+		jsonLevel.Store(&level)
+		logger := newJSONLogger(os.Stderr, level)
+		klog.SetLogger(logr.New(newKlogSink(logger.GetSink())))
+		ctrllog.SetLogger(logger)
+		return nil
+	default:
+		return fmt.Errorf("invalid logging format %q: must be either %s or %s", o.Format, FormatText, FormatJSON)
+	}
+}
+
+// NewJSONLogger returns a logger writing JSON objects with the ts, level, logger, caller and msg keys,
+// followed by the key and values of the log entry, up to the verbosity.
+func NewJSONLogger(w io.Writer, verbosity int) logr.Logger {
+	return newJSONLogger(w, zap.NewAtomicLevelAt(zapLevel(verbosity)))
+}
+
+func newJSONLogger(w io.Writer, level zap.AtomicLevel) logr.Logger {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "ts"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoderConfig.EncodeDuration = zapcore.StringDurationEncoder
+
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(w), level)
+	return zapr.NewLogger(zap.New(core, zap.AddCaller()))
+}
+
+// SetVerbosity changes the verbosity of the logs, as set by the -v flag, while running.
+func SetVerbosity(verbosity int) error {
+	var level klog.Level
+	if err := level.Set(strconv.Itoa(verbosity)); err != nil {
+		return err
+	}
+	if l := jsonLevel.Load(); l != nil {
+		l.SetLevel(zapLevel(verbosity))
+	}
+	return nil
+}
+
+// jsonLevel is the level of the JSON logger set up by Setup, nil with the text format.
+var jsonLevel atomic.Pointer[zap.AtomicLevel]
+
+// zapLevel returns the zap level of a logr verbosity, which is its opposite.
+func zapLevel(verbosity int) zapcore.Level {
+	return zapcore.Level(-verbosity)
+}
+
+// NewLogConstructor returns the constructor of the loggers of a controller reconciling a kind, whose
+// log entries have the controller and namespace keys, and the name of the reconciled object keyed by kind,
+// e.g. machine or machineset.
+func NewLogConstructor(logger logr.Logger, controller, kind string) func(*reconcile.Request) logr.Logger {
+	logger = logger.WithValues("controller", controller)
+	return func(req *reconcile.Request) logr.Logger {
+		if req == nil {
+			return logger
+		}
+		return logger.WithValues("namespace", req.Namespace, kind, req.Name)
+	}
+}
+
+// klogSink trims the newline which klog appends to the messages of its printf style functions.
+type klogSink struct {
+	logr.LogSink
+}
+
+func newKlogSink(sink logr.LogSink) logr.LogSink {
+	// The sink adds a frame between the caller and the wrapped sink.
+	if callDepthSink, ok := sink.(logr.CallDepthLogSink); ok {
+		sink = callDepthSink.WithCallDepth(1)
+	}
+	return &klogSink{LogSink: sink}
+}
+
+// Init does not initialize the wrapped sink again, it was initialized by its logger.
+func (s *klogSink) Init(logr.RuntimeInfo) {}
+
+func (s *klogSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.LogSink.Info(level, strings.TrimSuffix(msg, "\n"), keysAndValues...)
+}
+
+func (s *klogSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.LogSink.Error(err, strings.TrimSuffix(msg, "\n"), keysAndValues...)
+}
+
+func (s *klogSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &klogSink{LogSink: s.LogSink.WithValues(keysAndValues...)}
+}
+
+func (s *klogSink) WithName(name string) logr.LogSink {
+	return &klogSink{LogSink: s.LogSink.WithName(name)}
+}
+
+func (s *klogSink) WithCallDepth(depth int) logr.LogSink {
+	if callDepthSink, ok := s.LogSink.(logr.CallDepthLogSink); ok {
+		return &klogSink{LogSink: callDepthSink.WithCallDepth(depth)}
+	}
+	return s
+}
+
+// verbosity returns the verbosity set by the -v flag of klog, if any.
+func verbosity() int {
+	f := flag.Lookup("v")
+	if f == nil {
+		return 0
+	}
+	v, err := strconv.Atoi(f.Value.String())
+	if err != nil {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestSetup(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect((&Options{Format: FormatText}).Setup()).To(Succeed())
+	g.Expect((&Options{Format: "yaml"}).Setup()).To(MatchError(`invalid logging format "yaml": must be either text or json`))
+}
+
+func TestJSONLogger(t *testing.T) {
+	g := NewWithT(t)
+
+	buf := &bytes.Buffer{}
+	logger := NewJSONLogger(buf, 2)
+	logConstructor := NewLogConstructor(logger, "machine-controller", "machine")
+
+	logConstructor(&reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "openshift-machine-api", Name: "worker-a"}}).
+		V(2).Info("Reconciling Machine", "phase", "Provisioning")
+	logConstructor(nil).V(3).Info("Not logged above the verbosity")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	g.Expect(lines).To(HaveLen(1))
+
+	entry := map[string]interface{}{}
+	g.Expect(json.Unmarshal([]byte(lines[0]), &entry)).To(Succeed())
+	g.Expect(entry).To(HaveKey("ts"))
+	g.Expect(entry).To(HaveKey("caller"))
+	g.Expect(entry).To(HaveKeyWithValue("msg", "Reconciling Machine"))
+	g.Expect(entry).To(HaveKeyWithValue("controller", "machine-controller"))
+	g.Expect(entry).To(HaveKeyWithValue("namespace", "openshift-machine-api"))
+	g.Expect(entry).To(HaveKeyWithValue("machine", "worker-a"))
+	g.Expect(entry).To(HaveKeyWithValue("phase", "Provisioning"))
+}
+
+func TestKlogSink(t *testing.T) {
+	g := NewWithT(t)
+
+	buf := &bytes.Buffer{}
+	logger := logr.New(newKlogSink(NewJSONLogger(buf, 0).GetSink()))
+	logger.Info("Formatted by klog\n")
+
+	entry := map[string]interface{}{}
+	g.Expect(json.Unmarshal(buf.Bytes(), &entry)).To(Succeed())
+	g.Expect(entry).To(HaveKeyWithValue("msg", "Formatted by klog"))
+	g.Expect(entry).To(HaveKeyWithValue("caller", HavePrefix("logging/logging_test.go:")))
+}
@@ -0,0 +1,97 @@
+// Package naming generates the names of the Machines of MachineSets from naming templates, instead of
+// the name of the MachineSet followed by a random suffix.
+package naming
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// MachineNameTemplateAnnotation is set on a MachineSet to a Go template naming its new Machines,
+// e.g. "{{ .MachineSet }}-{{ .Zone }}-{{ .Ordinal }}". The variables are the fields of Variables.
+// The template must use the ordinal, so that each Machine gets a distinct name, and the names must be
+// valid DNS labels, as they become the hostnames of the instances on most platforms.
+const MachineNameTemplateAnnotation = "machine.openshift.io/machine-name-template"
+
+// Variables are the variables available in the naming templates.
+type Variables struct {
+	// MachineSet is the name of the MachineSet.
+	MachineSet string
+	// Namespace is the namespace of the MachineSet.
+	Namespace string
+	// Zone is the zone of the Machine, from the failure domain it is created in or its providerSpec.
+	// It is empty on the platforms without zones.
+	Zone string
+	// Ordinal is the lowest non-negative integer giving a name which is not used by another Machine.
+	Ordinal int
+}
+
+// Template is a parsed naming template.
+type Template struct {
+	tmpl *template.Template
+}
+
+// Parse parses the naming template. It fails when the template does not use the ordinal.
+func Parse(text string) (*Template, error) {
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid machine name template %q: %v", text, err)
+	}
+	t := &Template{tmpl: tmpl}
+
+	first, err := t.execute(Variables{Ordinal: 0})
+	if err != nil {
+		return nil, fmt.Errorf("invalid machine name template %q: %v", text, err)
+	}
+	second, err := t.execute(Variables{Ordinal: 1})
+	if err != nil {
+		return nil, fmt.Errorf("invalid machine name template %q: %v", text, err)
+	}
+	if first == second {
+		return nil, fmt.Errorf("invalid machine name template %q: must use {{ .Ordinal }} to give each machine a distinct name", text)
+	}
+	return t, nil
+}
+
+// Render returns the name of a Machine with the given variables. It fails when the name is not a valid DNS label.
+func (t *Template) Render(v Variables) (string, error) {
+	name, err := t.execute(v)
+	if err != nil {
+		return "", err
+	}
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return "", fmt.Errorf("invalid machine name %q: %s", name, strings.Join(errs, ", "))
+	}
+	return name, nil
+}
+
+// Validate checks that the naming template gives valid names to the Machines of the MachineSet in each of the
+// zones, up to the given number of Machines.
+func Validate(text string, machineSet, namespace string, zones []string, machines int) error {
+	t, err := Parse(text)
+	if err != nil {
+		return err
+	}
+	if len(zones) == 0 {
+		zones = []string{""}
+	}
+	for _, zone := range zones {
+		// The longest name is the one with the highest ordinal.
+		if _, err := t.Render(Variables{MachineSet: machineSet, Namespace: namespace, Zone: zone, Ordinal: machines}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Template) execute(v Variables) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, v); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
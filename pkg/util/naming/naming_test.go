@@ -0,0 +1,94 @@
+package naming
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		template    string
+		expectedErr string
+	}{
+		{template: "{{ .MachineSet }}-{{ .Zone }}-{{ .Ordinal }}"},
+		{template: `{{ .MachineSet }}-{{ printf "%03d" .Ordinal }}`},
+		{
+			template:    "{{ .MachineSet }}-{{ .Zone }}",
+			expectedErr: `invalid machine name template "{{ .MachineSet }}-{{ .Zone }}": must use {{ .Ordinal }} to give each machine a distinct name`,
+		},
+		{
+			template:    "{{ .Hostname }}-{{ .Ordinal }}",
+			expectedErr: `invalid machine name template "{{ .Hostname }}-{{ .Ordinal }}": template: name:1:3: executing "name" at <.Hostname>: can't evaluate field Hostname in type naming.Variables`,
+		},
+		{
+			template:    "{{ .MachineSet",
+			expectedErr: `invalid machine name template "{{ .MachineSet": template: name:1: unclosed action`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := Parse(tc.template)
+			if tc.expectedErr != "" {
+				g.Expect(err).To(MatchError(tc.expectedErr))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	g := NewWithT(t)
+
+	tmpl, err := Parse("{{ .MachineSet }}-{{ .Zone }}-{{ .Ordinal }}")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	name, err := tmpl.Render(Variables{MachineSet: "worker", Zone: "us-east-1a", Ordinal: 2})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(name).To(Equal("worker-us-east-1a-2"))
+
+	_, err = tmpl.Render(Variables{MachineSet: "worker", Zone: "US_EAST", Ordinal: 2})
+	g.Expect(err).To(MatchError(ContainSubstring(`invalid machine name "worker-US_EAST-2": a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-'`)))
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		machineSet  string
+		zones       []string
+		expectedErr string
+	}{
+		{
+			name:       "with short names",
+			machineSet: "worker",
+			zones:      []string{"us-east-1a", "us-east-1b"},
+		},
+		{
+			name:       "without zones",
+			machineSet: "worker",
+		},
+		{
+			name:        "with names too long in a zone",
+			machineSet:  "worker-with-a-long-name-for-the-purpose-of-this-test",
+			zones:       []string{"z1", "us-east-1a"},
+			expectedErr: `invalid machine name "worker-with-a-long-name-for-the-purpose-of-this-test-us-east-1a-999": must be no more than 63 characters`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := Validate("{{ .MachineSet }}-{{ .Zone }}-{{ .Ordinal }}", tc.machineSet, "openshift-machine-api", tc.zones, 999)
+			if tc.expectedErr != "" {
+				g.Expect(err).To(MatchError(tc.expectedErr))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// Package nodeaddress handles the IPv4 and IPv6 addresses of Machines and Nodes, so that single stack IPv4,
+// single stack IPv6 and dual-stack Machines are matched to their Nodes by any of their addresses.
+package nodeaddress
+
+import (
+	"net"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NormalizeIP returns the canonical form of an IP address, e.g. fd00::a for FD00:0:0::0A, for the different
+// spellings of an IPv6 address to compare equal. Addresses which are not IPs are returned unchanged.
+func NormalizeIP(address string) string {
+	if ip := net.ParseIP(address); ip != nil {
+		return ip.String()
+	}
+	return address
+}
+
+// InternalIPs returns the normalized internal IPs of the addresses, of both families, in their order and without duplicates.
+func InternalIPs(addresses []corev1.NodeAddress) []string {
+	var ips []string
+	seen := map[string]bool{}
+	for _, a := range addresses {
+		if a.Type != corev1.NodeInternalIP {
+			continue
+		}
+		ip := NormalizeIP(a.Address)
+		if ip == "" || seen[ip] {
+			continue
+		}
+		seen[ip] = true
+		ips = append(ips, ip)
+	}
+	return ips
+}
+
+// SanitizeIPs returns the normalized IPs, of both families, which identify a Machine, in their order and without
+// duplicates. The link-local addresses, which every IPv6 interface has, are dropped along with the invalid ones.
+func SanitizeIPs(addresses []string) []string {
+	var ips []string
+	seen := map[string]bool{}
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		if ip == nil || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+			continue
+		}
+		if normalized := ip.String(); !seen[normalized] {
+			seen[normalized] = true
+			ips = append(ips, normalized)
+		}
+	}
+	return ips
+}
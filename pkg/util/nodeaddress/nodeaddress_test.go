@@ -0,0 +1,66 @@
+package nodeaddress
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNormalizeIP(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(NormalizeIP("10.0.0.1")).To(Equal("10.0.0.1"))
+	g.Expect(NormalizeIP("FD00:0:0::0A")).To(Equal("fd00::a"))
+	g.Expect(NormalizeIP("::ffff:10.0.0.1")).To(Equal("10.0.0.1"))
+	g.Expect(NormalizeIP("ip-10-0-0-1.ec2.internal")).To(Equal("ip-10-0-0-1.ec2.internal"))
+}
+
+func TestInternalIPs(t *testing.T) {
+	g := NewWithT(t)
+
+	addresses := []corev1.NodeAddress{
+		{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+		{Type: corev1.NodeExternalIP, Address: "203.0.113.1"},
+		{Type: corev1.NodeInternalIP, Address: "FD00::A"},
+		{Type: corev1.NodeInternalDNS, Address: "ip-10-0-0-1.ec2.internal"},
+		{Type: corev1.NodeInternalIP, Address: "fd00::a"},
+	}
+	g.Expect(InternalIPs(addresses)).To(Equal([]string{"10.0.0.1", "fd00::a"}))
+	g.Expect(InternalIPs(nil)).To(BeEmpty())
+}
+
+func TestSanitizeIPs(t *testing.T) {
+	testCases := []struct {
+		name      string
+		addresses []string
+		expected  []string
+	}{
+		{
+			name:      "with IPv4 addresses",
+			addresses: []string{"10.0.0.1", "10.0.1.1"},
+			expected:  []string{"10.0.0.1", "10.0.1.1"},
+		},
+		{
+			name:      "with IPv6 addresses",
+			addresses: []string{"fe80::250:56ff:fe8a:1", "FD00::0A"},
+			expected:  []string{"fd00::a"},
+		},
+		{
+			name:      "with dual-stack addresses",
+			addresses: []string{"10.0.0.1", "fe80::250:56ff:fe8a:1", "169.254.0.1", "fd00::a", "10.0.0.1"},
+			expected:  []string{"10.0.0.1", "fd00::a"},
+		},
+		{
+			name:      "with invalid addresses",
+			addresses: []string{"", "::", "not an IP"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(SanitizeIPs(tc.addresses)).To(Equal(tc.expected))
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Package nodeconfig parses the labels and taints set on MachineSets for the Nodes of their Machines.
+// The MachineSet controller copies them into the Machines it creates, and the nodelink controller
+// reconciles them onto the Nodes of the Machines.
+package nodeconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// NodeLabelsAnnotation lists the labels of the Nodes of the Machines, as a comma separated list of
+	// key=value pairs, e.g. "node-role.kubernetes.io/infra=,example.com/tier=gold".
+	NodeLabelsAnnotation = "machine.openshift.io/node-labels"
+
+	// NodeTaintsAnnotation lists the taints of the Nodes of the Machines, as a comma separated list of
+	// key[=value]:effect taints, e.g. "node-role.kubernetes.io/infra=reserved:NoSchedule".
+	NodeTaintsAnnotation = "machine.openshift.io/node-taints"
+)
+
+// Annotations are the annotations copied from MachineSets into their Machines.
+var Annotations = []string{NodeLabelsAnnotation, NodeTaintsAnnotation}
+
+// ParseLabels parses the value of the node labels annotation.
+func ParseLabels(value string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid node label %q: must be of the form key=value", entry)
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid node label key %q: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(val); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid node label value %q: %s", val, strings.Join(errs, ", "))
+		}
+		if _, ok := labels[key]; ok {
+			return nil, fmt.Errorf("duplicate node label key %q", key)
+		}
+		labels[key] = val
+	}
+	return labels, nil
+}
+
+// ParseTaints parses the value of the node taints annotation. The taints are sorted by key and effect.
+func ParseTaints(value string) ([]corev1.Taint, error) {
+	var taints []corev1.Taint
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		keyValue, effect, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid node taint %q: must be of the form key[=value]:effect", entry)
+		}
+		taint := corev1.Taint{Effect: corev1.TaintEffect(effect)}
+		taint.Key, taint.Value, _ = strings.Cut(keyValue, "=")
+
+		switch taint.Effect {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return nil, fmt.Errorf("invalid node taint %q: effect must be one of %s, %s or %s", entry,
+				corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute)
+		}
+		if errs := validation.IsQualifiedName(taint.Key); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid node taint key %q: %s", taint.Key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(taint.Value); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid node taint value %q: %s", taint.Value, strings.Join(errs, ", "))
+		}
+
+		// The taints of a Node are unique by key and effect.
+		id := taint.Key + ":" + string(taint.Effect)
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate node taint %q", id)
+		}
+		seen[id] = true
+		taints = append(taints, taint)
+	}
+
+	sort.Slice(taints, func(i, j int) bool {
+		if taints[i].Key != taints[j].Key {
+			return taints[i].Key < taints[j].Key
+		}
+		return taints[i].Effect < taints[j].Effect
+	})
+	return taints, nil
+}
@@ -0,0 +1,76 @@
+package nodeconfig
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseLabels(t *testing.T) {
+	testCases := []struct {
+		value       string
+		expected    map[string]string
+		expectedErr string
+	}{
+		{value: "", expected: map[string]string{}},
+		{
+			value:    "node-role.kubernetes.io/infra=, example.com/tier=gold",
+			expected: map[string]string{"node-role.kubernetes.io/infra": "", "example.com/tier": "gold"},
+		},
+		{value: "example.com/tier", expectedErr: `invalid node label "example.com/tier": must be of the form key=value`},
+		{value: "example.com/tier=gold,example.com/tier=silver", expectedErr: `duplicate node label key "example.com/tier"`},
+		{value: "-tier=gold", expectedErr: `invalid node label key "-tier": name part must consist of alphanumeric characters`},
+		{value: "tier=gold silver", expectedErr: `invalid node label value "gold silver": a valid label must be an empty string or consist of alphanumeric characters`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			g := NewWithT(t)
+
+			labels, err := ParseLabels(tc.value)
+			if tc.expectedErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(labels).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestParseTaints(t *testing.T) {
+	testCases := []struct {
+		value       string
+		expected    []corev1.Taint
+		expectedErr string
+	}{
+		{value: "", expected: nil},
+		{
+			value: "node-role.kubernetes.io/infra=reserved:NoSchedule, gpu:PreferNoSchedule,gpu:NoExecute",
+			expected: []corev1.Taint{
+				{Key: "gpu", Effect: corev1.TaintEffectNoExecute},
+				{Key: "gpu", Effect: corev1.TaintEffectPreferNoSchedule},
+				{Key: "node-role.kubernetes.io/infra", Value: "reserved", Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+		{value: "gpu=true", expectedErr: `invalid node taint "gpu=true": must be of the form key[=value]:effect`},
+		{value: "gpu:Schedule", expectedErr: `invalid node taint "gpu:Schedule": effect must be one of NoSchedule, PreferNoSchedule or NoExecute`},
+		{value: "gpu=a:NoSchedule,gpu=b:NoSchedule", expectedErr: `duplicate node taint "gpu:NoSchedule"`},
+		{value: ":NoSchedule", expectedErr: `invalid node taint key "": name part must be non-empty`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			g := NewWithT(t)
+
+			taints, err := ParseTaints(tc.value)
+			if tc.expectedErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(taints).To(Equal(tc.expected))
+		})
+	}
+}
@@ -0,0 +1,205 @@
+// Package profiling serves the pprof profiles and the state of the work queues of the Machine API commands,
+// to diagnose memory leaks and hot reconcile loops in production.
+package profiling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// WorkQueuesPath is the path of the state of the work queues.
+	WorkQueuesPath = "/debug/workqueues"
+
+	shutdownTimeout = 10 * time.Second
+)
+
+// Options configures the profiling server.
+type Options struct {
+	// BindAddress is the address of the profiling server. Profiling is disabled when empty.
+	BindAddress string
+}
+
+// AddFlags adds the flags of the profiling options to the flag set.
+func (o *Options) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.BindAddress, "profiling-bind-address", "", "Address, e.g. 127.0.0.1:6060, on which the net/http/pprof profiles are served under /debug/pprof/, and the depths of the work queues of the controllers under "+WorkQueuesPath+". The endpoints are not authenticated. Profiling is disabled when unset.")
+}
+
+// Enabled returns true if the profiling server is enabled.
+func (o *Options) Enabled() bool {
+	return o.BindAddress != ""
+}
+
+// AddToManager adds the profiling server to the manager, when profiling is enabled.
+func (o *Options) AddToManager(mgr manager.Manager) error {
+	if !o.Enabled() {
+		return nil
+	}
+	return mgr.Add(o.NewServer())
+}
+
+// NewServer returns the profiling server on the bind address.
+func (o *Options) NewServer() *Server {
+	return &Server{address: o.BindAddress, handler: NewHandler(crmetrics.Registry)}
+}
+
+// NewHandler returns the handler of the pprof profiles, and of the state of the work queues whose metrics
+// are gathered by the gatherer. The work queues of both the controller-runtime controllers and the client-go
+// controllers register their metrics in the controller-runtime registry.
+func NewHandler(gatherer prometheus.Gatherer) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle(WorkQueuesPath, &workQueuesHandler{gatherer: gatherer})
+	return mux
+}
+
+// Server serves the profiling handler. It implements the manager.Runnable interface.
+type Server struct {
+	address string
+	handler http.Handler
+}
+
+// NeedLeaderElection implements the manager.LeaderElectionRunnable interface, all the replicas are profiled.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Start serves the profiling handler until the context is done.
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Handler:           s.handler,
+		ReadHeaderTimeout: 32 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("Error shutting down profiling server: %v", err)
+		}
+	}()
+
+	klog.Infof("Serving profiles on %s", listener.Addr())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// WorkQueue is the state of a work queue.
+type WorkQueue struct {
+	Name string `json:"name"`
+	// Depth is the number of items waiting to be processed.
+	Depth float64 `json:"depth"`
+	// Adds is the number of items added since the start, which grows quickly in a hot reconcile loop.
+	Adds float64 `json:"adds"`
+	// Retries is the number of items requeued with a rate limit since the start.
+	Retries float64 `json:"retries"`
+	// UnfinishedWorkSeconds is the time the items in progress have been processed for.
+	UnfinishedWorkSeconds float64 `json:"unfinishedWorkSeconds"`
+	// LongestRunningProcessorSeconds is the time the longest item in progress has been processed for.
+	LongestRunningProcessorSeconds float64 `json:"longestRunningProcessorSeconds"`
+}
+
+// workQueuesHandler serves the state of the work queues as a JSON list, sorted by name.
+type workQueuesHandler struct {
+	gatherer prometheus.Gatherer
+}
+
+func (h *workQueuesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	queues, err := gatherWorkQueues(h.gatherer)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(queues); err != nil {
+		klog.Errorf("Error writing work queues: %v", err)
+	}
+}
+
+// gatherWorkQueues returns the state of the work queues from the metrics of the client-go work queues.
+func gatherWorkQueues(gatherer prometheus.Gatherer) ([]WorkQueue, error) {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	queues := map[string]*WorkQueue{}
+	for _, family := range families {
+		var field func(*WorkQueue) *float64
+		switch family.GetName() {
+		case "workqueue_depth":
+			field = func(q *WorkQueue) *float64 { return &q.Depth }
+		case "workqueue_adds_total":
+			field = func(q *WorkQueue) *float64 { return &q.Adds }
+		case "workqueue_retries_total":
+			field = func(q *WorkQueue) *float64 { return &q.Retries }
+		case "workqueue_unfinished_work_seconds":
+			field = func(q *WorkQueue) *float64 { return &q.UnfinishedWorkSeconds }
+		case "workqueue_longest_running_processor_seconds":
+			field = func(q *WorkQueue) *float64 { return &q.LongestRunningProcessorSeconds }
+		default:
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			name := labelValue(metric, "name")
+			q, ok := queues[name]
+			if !ok {
+				q = &WorkQueue{Name: name}
+				queues[name] = q
+			}
+			*field(q) = value(metric)
+		}
+	}
+
+	result := make([]WorkQueue, 0, len(queues))
+	for _, q := range queues {
+		result = append(result, *q)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func labelValue(metric *dto.Metric, name string) string {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
+
+func value(metric *dto.Metric) float64 {
+	switch {
+	case metric.Gauge != nil:
+		return metric.GetGauge().GetValue()
+	case metric.Counter != nil:
+		return metric.GetCounter().GetValue()
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,50 @@
+package profiling
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestHandler(t *testing.T) {
+	g := NewWithT(t)
+
+	registry := prometheus.NewRegistry()
+	depth := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "workqueue_depth"}, []string{"name"})
+	adds := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "workqueue_adds_total"}, []string{"name"})
+	retries := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "workqueue_retries_total"}, []string{"name"})
+	longestRunningProcessor := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "workqueue_longest_running_processor_seconds"}, []string{"name"})
+	other := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mapi_machine_items"}, []string{"name"})
+	registry.MustRegister(depth, adds, retries, longestRunningProcessor, other)
+
+	depth.WithLabelValues("machineset-controller").Set(3)
+	adds.WithLabelValues("machineset-controller").Add(1200)
+	retries.WithLabelValues("machineset-controller").Add(40)
+	longestRunningProcessor.WithLabelValues("machineset-controller").Set(12.5)
+	depth.WithLabelValues("machine-controller").Set(0)
+	adds.WithLabelValues("machine-controller").Add(7)
+	other.WithLabelValues("other").Set(1)
+
+	handler := NewHandler(registry)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, WorkQueuesPath, nil))
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+	g.Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+
+	var queues []WorkQueue
+	g.Expect(json.Unmarshal(rec.Body.Bytes(), &queues)).To(Succeed())
+	g.Expect(queues).To(Equal([]WorkQueue{
+		{Name: "machine-controller", Adds: 7},
+		{Name: "machineset-controller", Depth: 3, Adds: 1200, Retries: 40, LongestRunningProcessorSeconds: 12.5},
+	}))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+	g.Expect(rec.Body.String()).To(ContainSubstring("goroutine"))
+}
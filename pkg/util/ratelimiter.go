@@ -0,0 +1,189 @@
+/*
+Copyright 2026 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"golang.org/x/time/rate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultRateLimiterBaseDelay and DefaultRateLimiterMaxDelay are the bounds of the exponential backoff of
+	// the requeues of an object, as in the default rate limiter of the controllers.
+	DefaultRateLimiterBaseDelay = 5 * time.Millisecond
+	DefaultRateLimiterMaxDelay  = 1000 * time.Second
+
+	// The overall rate limit of the requeues, and the API server client rate limit, default to the defaults of
+	// the controllers and clients for small fleets, and grow with the number of Machines up to a cap, so that
+	// a requeue storm of a large fleet does not take hours to drain.
+	minRateLimiterQPS        = 10
+	maxRateLimiterQPS        = 100
+	minRateLimiterBucketSize = 100
+	maxRateLimiterBucketSize = 5000
+	minAPIQPS                = 20
+	maxAPIQPS                = 200
+
+	// machinesPerRateLimiterQPS is the number of Machines for each requeue per second, so that the Machines
+	// can all be requeued within a minute.
+	machinesPerRateLimiterQPS = 60
+	// machinesPerAPIQPS is the number of Machines for each request per second to the API server.
+	machinesPerAPIQPS = 30
+
+	// machineListPageSize is the page size of the list of the Machines counted for the adaptive defaults.
+	machineListPageSize = 500
+)
+
+// RateLimiterOptions tunes the rate limiter of the requeues of the controllers, and the rate limit of the
+// requests to the API server. The zero values are defaulted from the number of Machines.
+type RateLimiterOptions struct {
+	// BaseDelay and MaxDelay are the bounds of the exponential backoff of the requeues of an object.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// QPS and BucketSize limit the overall rate of the requeues of a controller.
+	QPS        float64
+	BucketSize int
+	// APIQPS and APIBurst limit the rate of the requests to the API server.
+	APIQPS   float64
+	APIBurst int
+}
+
+// AddFlags adds the flags of the rate limiter options to the flag set.
+func (o *RateLimiterOptions) AddFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&o.BaseDelay, "rate-limiter-base-delay", DefaultRateLimiterBaseDelay, "The delay of the first requeue of an object failing to reconcile, doubled for each further failure.")
+	fs.DurationVar(&o.MaxDelay, "rate-limiter-max-delay", DefaultRateLimiterMaxDelay, "The maximum delay of the requeues of an object failing to reconcile.")
+	fs.Float64Var(&o.QPS, "rate-limiter-qps", 0, fmt.Sprintf("The number of objects that may be requeued per second across all objects. Defaults to one per %d machines, between %d and %d, when zero.", machinesPerRateLimiterQPS, minRateLimiterQPS, maxRateLimiterQPS))
+	fs.IntVar(&o.BucketSize, "rate-limiter-bucket-size", 0, fmt.Sprintf("The number of objects that may be requeued at once across all objects. Defaults to the number of machines, between %d and %d, when zero.", minRateLimiterBucketSize, maxRateLimiterBucketSize))
+	fs.Float64Var(&o.APIQPS, "kube-api-qps", 0, fmt.Sprintf("The number of requests per second to the API server. Defaults to one per %d machines, between %d and %d, when zero.", machinesPerAPIQPS, minAPIQPS, maxAPIQPS))
+	fs.IntVar(&o.APIBurst, "kube-api-burst", 0, "The number of requests to the API server that may be sent at once. Defaults to one and a half times kube-api-qps when zero.")
+}
+
+// Validate returns an error if the options are invalid.
+func (o *RateLimiterOptions) Validate() error {
+	switch {
+	case o.BaseDelay <= 0:
+		return fmt.Errorf("invalid rate-limiter-base-delay %v: must be positive", o.BaseDelay)
+	case o.MaxDelay < o.BaseDelay:
+		return fmt.Errorf("invalid rate-limiter-max-delay %v: must be at least rate-limiter-base-delay", o.MaxDelay)
+	case o.QPS < 0:
+		return fmt.Errorf("invalid rate-limiter-qps %v: must not be negative", o.QPS)
+	case o.BucketSize < 0:
+		return fmt.Errorf("invalid rate-limiter-bucket-size %d: must not be negative", o.BucketSize)
+	case o.APIQPS < 0:
+		return fmt.Errorf("invalid kube-api-qps %v: must not be negative", o.APIQPS)
+	case o.APIBurst < 0:
+		return fmt.Errorf("invalid kube-api-burst %d: must not be negative", o.APIBurst)
+	}
+	return nil
+}
+
+// adaptive returns true if some of the options are defaulted from the number of Machines.
+func (o *RateLimiterOptions) adaptive() bool {
+	return o.QPS == 0 || o.BucketSize == 0 || o.APIQPS == 0 || o.APIBurst == 0
+}
+
+// Default sets the zero options from the number of Machines.
+func (o *RateLimiterOptions) Default(machines int) {
+	if o.QPS == 0 {
+		o.QPS = clamp(float64(machines)/machinesPerRateLimiterQPS, minRateLimiterQPS, maxRateLimiterQPS)
+	}
+	if o.BucketSize == 0 {
+		o.BucketSize = int(clamp(float64(machines), minRateLimiterBucketSize, maxRateLimiterBucketSize))
+	}
+	if o.APIQPS == 0 {
+		o.APIQPS = clamp(float64(machines)/machinesPerAPIQPS, minAPIQPS, maxAPIQPS)
+	}
+	if o.APIBurst == 0 {
+		o.APIBurst = int(math.Ceil(o.APIQPS * 1.5))
+	}
+}
+
+func clamp(value, min, max float64) float64 {
+	return math.Min(math.Max(value, min), max)
+}
+
+// Setup validates the options, defaults them from the number of Machines in the namespaces, all of them when
+// there is none, and applies the rate limit of the requests to the API server to the config. The options are
+// defaulted as for a small fleet when the Machines cannot be counted.
+func (o *RateLimiterOptions) Setup(ctx context.Context, config *rest.Config, namespaces []string) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	machines := 0
+	if o.adaptive() {
+		var err error
+		if machines, err = countMachines(ctx, config, namespaces); err != nil {
+			klog.Warningf("Failed to count the machines for the default rate limits, defaulting them for a small fleet: %v", err)
+		} else {
+			klog.Infof("Defaulting the rate limits for %d machines", machines)
+		}
+	}
+	o.Default(machines)
+	klog.Infof("Rate limiting the requeues to %v per second, with a bucket of %d and a backoff from %v to %v, and the API requests to %v per second, with a burst of %d",
+		o.QPS, o.BucketSize, o.BaseDelay, o.MaxDelay, o.APIQPS, o.APIBurst)
+
+	config.QPS = float32(o.APIQPS)
+	config.Burst = o.APIBurst
+	return nil
+}
+
+// NewRateLimiter returns the rate limiter of the requeues of a controller. Each controller must get its own.
+func (o *RateLimiterOptions) NewRateLimiter() workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(o.BaseDelay, o.MaxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(o.QPS), o.BucketSize)},
+	)
+}
+
+// countMachines returns the number of Machines in the namespaces, all of them when there is none.
+// Only their metadata is listed, a page at a time.
+func countMachines(ctx context.Context, config *rest.Config, namespaces []string) (int, error) {
+	c, err := client.New(config, client.Options{})
+	if err != nil {
+		return 0, err
+	}
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	count := 0
+	for _, namespace := range namespaces {
+		list := &metav1.PartialObjectMetadataList{}
+		list.SetGroupVersionKind(machinev1.GroupVersion.WithKind("MachineList"))
+		for {
+			if err := c.List(ctx, list, client.InNamespace(namespace), client.Limit(machineListPageSize), client.Continue(list.Continue)); err != nil {
+				return 0, fmt.Errorf("failed to list machines: %w", err)
+			}
+			count += len(list.Items)
+			if list.Continue == "" {
+				break
+			}
+		}
+	}
+	return count, nil
+}
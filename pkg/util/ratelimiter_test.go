@@ -0,0 +1,111 @@
+package util
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRateLimiterOptionsDefault(t *testing.T) {
+	testCases := []struct {
+		name     string
+		options  RateLimiterOptions
+		machines int
+		expected RateLimiterOptions
+	}{
+		{
+			name:     "for a small fleet",
+			machines: 30,
+			expected: RateLimiterOptions{QPS: 10, BucketSize: 100, APIQPS: 20, APIBurst: 30},
+		},
+		{
+			name:     "for a large fleet",
+			machines: 3000,
+			expected: RateLimiterOptions{QPS: 50, BucketSize: 3000, APIQPS: 100, APIBurst: 150},
+		},
+		{
+			name:     "for a very large fleet",
+			machines: 60000,
+			expected: RateLimiterOptions{QPS: 100, BucketSize: 5000, APIQPS: 200, APIBurst: 300},
+		},
+		{
+			name:     "with options set",
+			options:  RateLimiterOptions{QPS: 5, APIQPS: 40},
+			machines: 3000,
+			expected: RateLimiterOptions{QPS: 5, BucketSize: 3000, APIQPS: 40, APIBurst: 60},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			o := tc.options
+			o.Default(tc.machines)
+			g.Expect(o).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestRateLimiterOptionsValidate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		args          []string
+		expectedError string
+	}{
+		{
+			name: "with the defaults",
+		},
+		{
+			name: "with valid flags",
+			args: []string{"-rate-limiter-base-delay=1s", "-rate-limiter-max-delay=5m", "-rate-limiter-qps=20", "-kube-api-qps=50", "-kube-api-burst=80"},
+		},
+		{
+			name:          "with a max delay below the base delay",
+			args:          []string{"-rate-limiter-base-delay=1m", "-rate-limiter-max-delay=1s"},
+			expectedError: "invalid rate-limiter-max-delay 1s: must be at least rate-limiter-base-delay",
+		},
+		{
+			name:          "with a negative API QPS",
+			args:          []string{"-kube-api-qps=-1"},
+			expectedError: "invalid kube-api-qps -1: must not be negative",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			o := &RateLimiterOptions{}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			o.AddFlags(fs)
+			g.Expect(fs.Parse(tc.args)).To(Succeed())
+
+			err := o.Validate()
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
+
+func TestRateLimiterOptionsNewRateLimiter(t *testing.T) {
+	g := NewWithT(t)
+
+	o := &RateLimiterOptions{BaseDelay: time.Second, MaxDelay: 4 * time.Second, QPS: 100, BucketSize: 100}
+	limiter := o.NewRateLimiter()
+
+	// The requeues of an object back off exponentially, up to the max delay.
+	g.Expect(limiter.When("a")).To(Equal(time.Second))
+	g.Expect(limiter.When("a")).To(Equal(2 * time.Second))
+	g.Expect(limiter.When("a")).To(Equal(4 * time.Second))
+	g.Expect(limiter.When("a")).To(Equal(4 * time.Second))
+	g.Expect(limiter.When("b")).To(Equal(time.Second))
+
+	limiter.Forget("a")
+	g.Expect(limiter.NumRequeues("a")).To(BeZero())
+}
@@ -0,0 +1,97 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		spec        string
+		expectedErr bool
+	}{
+		{spec: "* * * * *"},
+		{spec: "0 19 * * mon-fri"},
+		{spec: "*/15 8-18/2 1,15 jan-jun 0,7"},
+		{spec: "0 19 * *", expectedErr: true},
+		{spec: "60 * * * *", expectedErr: true},
+		{spec: "* * 0 * *", expectedErr: true},
+		{spec: "* * * 13 *", expectedErr: true},
+		{spec: "* * * * 8", expectedErr: true},
+		{spec: "*/0 * * * *", expectedErr: true},
+		{spec: "5-1 * * * *", expectedErr: true},
+		{spec: "a * * * *", expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := Parse(tc.spec)
+			if tc.expectedErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestNextAndPrev(t *testing.T) {
+	// Wednesday.
+	now := time.Date(2023, 3, 15, 12, 30, 45, 0, time.UTC)
+
+	testCases := []struct {
+		spec         string
+		expectedNext time.Time
+		expectedPrev time.Time
+	}{
+		{
+			spec:         "* * * * *",
+			expectedNext: time.Date(2023, 3, 15, 12, 31, 0, 0, time.UTC),
+			expectedPrev: time.Date(2023, 3, 15, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			spec:         "0 19 * * mon-fri",
+			expectedNext: time.Date(2023, 3, 15, 19, 0, 0, 0, time.UTC),
+			expectedPrev: time.Date(2023, 3, 14, 19, 0, 0, 0, time.UTC),
+		},
+		{
+			spec:         "0 7 * * 1",
+			expectedNext: time.Date(2023, 3, 20, 7, 0, 0, 0, time.UTC),
+			expectedPrev: time.Date(2023, 3, 13, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			spec:         "*/20 * * * *",
+			expectedNext: time.Date(2023, 3, 15, 12, 40, 0, 0, time.UTC),
+			expectedPrev: time.Date(2023, 3, 15, 12, 20, 0, 0, time.UTC),
+		},
+		{
+			// Day of month and day of week are combined with OR when both are restricted.
+			spec:         "0 0 1 * sun",
+			expectedNext: time.Date(2023, 3, 19, 0, 0, 0, 0, time.UTC),
+			expectedPrev: time.Date(2023, 3, 12, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			spec:         "0 0 1 jan *",
+			expectedNext: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			expectedPrev: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			spec: "0 0 30 feb *",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			g := NewWithT(t)
+
+			s, err := Parse(tc.spec)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(s.Next(now)).To(Equal(tc.expectedNext))
+			g.Expect(s.Prev(now)).To(Equal(tc.expectedPrev))
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// tracesPath is the path of the traces on an OTLP/HTTP endpoint.
+	tracesPath = "/v1/traces"
+
+	defaultQueueSize     = 2048
+	defaultBatchSize     = 512
+	defaultFlushInterval = 5 * time.Second
+	exportTimeout        = 10 * time.Second
+
+	// instrumentationScope is the name of the instrumentation of the exported spans.
+	instrumentationScope = "github.com/openshift/machine-api-operator"
+)
+
+// Options configures the export of the traces.
+type Options struct {
+	// Endpoint is the URL of the OTLP/HTTP endpoint of an OpenTelemetry collector. Tracing is disabled when empty.
+	Endpoint string
+
+	exporter *Exporter
+}
+
+// AddFlags adds the flags of the tracing options to the flag set.
+func (o *Options) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Endpoint, "tracing-endpoint", "", "URL of the OTLP/HTTP endpoint of an OpenTelemetry collector, e.g. http://otel-collector:4318, to which the traces of the reconciliations, and of the calls to the cloud provider and the API server, are exported. Tracing is disabled when unset.")
+}
+
+// Setup enables tracing when the endpoint is set: the spans are recorded, the API server calls made with
+// the config are traced, and the spans are exported as the service by the runnable added by AddToManager.
+func (o *Options) Setup(serviceName string, config *rest.Config) error {
+	if o.Endpoint == "" {
+		return nil
+	}
+	e, err := NewExporter(o.Endpoint, serviceName)
+	if err != nil {
+		return err
+	}
+	o.exporter = e
+	exporter.Store(e)
+	config.Wrap(WrapTransport)
+	klog.Infof("Exporting traces to %s", e.url)
+	return nil
+}
+
+// AddToManager adds the exporter of the spans to the manager, when tracing is enabled.
+func (o *Options) AddToManager(mgr manager.Manager) error {
+	if o.exporter == nil {
+		return nil
+	}
+	return mgr.Add(o.exporter)
+}
+
+// Exporter exports the spans to an OTLP/HTTP endpoint in batches. It implements the manager.Runnable interface.
+type Exporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+
+	queue         chan *Span
+	batchSize     int
+	flushInterval time.Duration
+}
+
+// NewExporter returns an exporter of the spans of the service to the OTLP/HTTP endpoint.
+func NewExporter(endpoint, serviceName string) (*Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tracing endpoint %q: %v", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid tracing endpoint %q: scheme must be either http or https", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = tracesPath
+	}
+	return &Exporter{
+		url:           u.String(),
+		serviceName:   serviceName,
+		client:        &http.Client{Timeout: exportTimeout},
+		queue:         make(chan *Span, defaultQueueSize),
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+	}, nil
+}
+
+// NeedLeaderElection implements the manager.LeaderElectionRunnable interface, the spans are exported by all the replicas.
+func (e *Exporter) NeedLeaderElection() bool {
+	return false
+}
+
+// Start exports the queued spans in batches until the context is done, then exports the remaining spans.
+func (e *Exporter) Start(ctx context.Context) error {
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= e.batchSize {
+				e.export(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			e.export(batch)
+			batch = nil
+		case <-ctx.Done():
+			for {
+				select {
+				case span := <-e.queue:
+					batch = append(batch, span)
+				default:
+					e.export(batch)
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// enqueue queues an ended span for export. The span is dropped when the queue is full, rather than
+// blocking the reconciliations while the collector is unavailable.
+func (e *Exporter) enqueue(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+		klog.V(4).Infof("Dropping span %s, the export queue is full", span.name)
+	}
+}
+
+func (e *Exporter) export(spans []*Span) {
+	if len(spans) == 0 {
+		return
+	}
+	if err := e.post(spans); err != nil {
+		klog.Errorf("Error exporting %d spans: %v", len(spans), err)
+	}
+}
+
+func (e *Exporter) post(spans []*Span) error {
+	body, err := json.Marshal(e.newExportRequest(spans))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.New(res.Status)
+	}
+	return nil
+}
+
+// The types of the OTLP/HTTP JSON encoding of the export requests of traces.
+type (
+	exportRequest struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}
+
+	resourceSpans struct {
+		Resource   resource     `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+
+	resource struct {
+		Attributes []keyValue `json:"attributes"`
+	}
+
+	scopeSpans struct {
+		Scope scope      `json:"scope"`
+		Spans []spanJSON `json:"spans"`
+	}
+
+	scope struct {
+		Name string `json:"name"`
+	}
+
+	spanJSON struct {
+		TraceID           string     `json:"traceId"`
+		SpanID            string     `json:"spanId"`
+		ParentSpanID      string     `json:"parentSpanId,omitempty"`
+		Name              string     `json:"name"`
+		Kind              SpanKind   `json:"kind"`
+		StartTimeUnixNano string     `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+		Attributes        []keyValue `json:"attributes,omitempty"`
+		Status            status     `json:"status"`
+	}
+
+	status struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+
+	keyValue struct {
+		Key   string   `json:"key"`
+		Value anyValue `json:"value"`
+	}
+
+	anyValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+		BoolValue   *bool   `json:"boolValue,omitempty"`
+	}
+)
+
+// statusCodeError is the OTLP status code of the spans which failed.
+const statusCodeError = 2
+
+func (e *Exporter) newExportRequest(spans []*Span) exportRequest {
+	encoded := make([]spanJSON, 0, len(spans))
+	for _, span := range spans {
+		encoded = append(encoded, encodeSpan(span))
+	}
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: []keyValue{encodeAttribute(String("service.name", e.serviceName))}},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: instrumentationScope}, Spans: encoded}},
+	}}}
+}
+
+func encodeSpan(span *Span) spanJSON {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	encoded := spanJSON{
+		TraceID:           span.traceID.String(),
+		SpanID:            span.spanID.String(),
+		Name:              span.name,
+		Kind:              span.kind,
+		StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+	}
+	if span.parentID != (spanID{}) {
+		encoded.ParentSpanID = span.parentID.String()
+	}
+	for _, attribute := range span.attributes {
+		encoded.Attributes = append(encoded.Attributes, encodeAttribute(attribute))
+	}
+	if span.err != nil {
+		encoded.Status = status{Code: statusCodeError, Message: span.err.Error()}
+	}
+	return encoded
+}
+
+func encodeAttribute(attribute Attribute) keyValue {
+	var value anyValue
+	switch v := attribute.Value.(type) {
+	case string:
+		value.StringValue = &v
+	case int:
+		i := strconv.Itoa(v)
+		value.IntValue = &i
+	case bool:
+		value.BoolValue = &v
+	default:
+		s := strings.TrimSpace(fmt.Sprint(v))
+		value.StringValue = &s
+	}
+	return keyValue{Key: attribute.Key, Value: value}
+}
@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// WrapTransport traces the requests made during a traced operation, e.g. the API server calls
+// made during a reconciliation. It can be used as the rest.Config WrapTransport.
+func WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return &tracingRoundTripper{next: rt}
+}
+
+type tracingRoundTripper struct {
+	next http.RoundTripper
+}
+
+func (rt *tracingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	_, span := StartClient(req.Context(), "HTTP "+req.Method,
+		String("http.method", req.Method),
+		String("http.target", req.URL.Path),
+		String("net.peer.name", req.URL.Hostname()),
+	)
+	if span == nil {
+		return rt.next.RoundTrip(req)
+	}
+	defer span.End()
+
+	res, err := rt.next.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		return res, err
+	}
+	span.SetAttributes(Int("http.status_code", res.StatusCode))
+	if res.StatusCode >= http.StatusInternalServerError {
+		span.RecordError(errors.New(res.Status))
+	}
+	return res, nil
+}
+
+// NewReconciler returns a reconciler recording a span for each reconciliation of the reconciler of the controller,
+// the parent of the spans of the calls made during the reconciliation.
+func NewReconciler(controller string, r reconcile.Reconciler) reconcile.Reconciler {
+	return &tracingReconciler{controller: controller, next: r}
+}
+
+type tracingReconciler struct {
+	controller string
+	next       reconcile.Reconciler
+}
+
+func (r *tracingReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	ctx, span := Start(ctx, r.controller+" Reconcile",
+		String("controller", r.controller),
+		String("namespace", req.Namespace),
+		String("name", req.Name),
+	)
+	defer span.End()
+
+	result, err := r.next.Reconcile(ctx, req)
+	span.RecordError(err)
+	if result.Requeue || result.RequeueAfter > 0 {
+		span.SetAttributes(Bool("requeue", true), String("requeue_after", result.RequeueAfter.String()))
+	}
+	return result, err
+}
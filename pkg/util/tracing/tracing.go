@@ -0,0 +1,186 @@
+// Package tracing records the spans of the reconciliations of the controllers, and of the calls to the cloud
+// providers and to the API server made by them, and exports them to an OpenTelemetry collector with OTLP/HTTP.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpanKind is the kind of a span, as defined by OpenTelemetry.
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindClient   SpanKind = 3
+)
+
+// Attribute is an attribute of a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+type (
+	traceID [16]byte
+	spanID  [8]byte
+)
+
+func (id traceID) String() string { return hex.EncodeToString(id[:]) }
+func (id spanID) String() string  { return hex.EncodeToString(id[:]) }
+
+// Span is an operation of a trace. The methods of a nil Span do nothing, which is the span
+// returned when tracing is disabled.
+type Span struct {
+	traceID  traceID
+	spanID   spanID
+	parentID spanID
+	name     string
+	kind     SpanKind
+	start    time.Time
+
+	mu         sync.Mutex
+	end        time.Time
+	attributes []Attribute
+	err        error
+	ended      bool
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes = append(s.attributes, attributes...)
+}
+
+// RecordError sets the status of the span to an error, when the error is not nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// End ends the span and queues it for export. Ending a span again does nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if e := exporter.Load(); e != nil {
+		e.enqueue(s)
+	}
+}
+
+// TraceID returns the ID of the trace of the span, in hex.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.traceID.String()
+}
+
+type spanContextKey struct{}
+
+// SpanFromContext returns the current span of the context, if any.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// Enabled returns true if the spans are recorded.
+func Enabled() bool {
+	return exporter.Load() != nil
+}
+
+// Start starts a span of an internal operation, which is the child of the current span of the context, if any.
+// It returns a nil span when tracing is disabled.
+func Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	return start(ctx, name, SpanKindInternal, attributes)
+}
+
+// StartClient starts a span of a call to a remote service, which is the child of the current span of the context.
+// It returns a nil span when tracing is disabled or when the context has no span, so that only the calls made
+// during a traced operation are recorded, rather than e.g. the watches of the informers.
+func StartClient(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	if SpanFromContext(ctx) == nil {
+		return ctx, nil
+	}
+	return start(ctx, name, SpanKindClient, attributes)
+}
+
+func start(ctx context.Context, name string, kind SpanKind, attributes []Attribute) (context.Context, *Span) {
+	if !Enabled() {
+		return ctx, nil
+	}
+	span := &Span{
+		spanID:     newSpanID(),
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: attributes,
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		span.traceID = newTraceID()
+	}
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+func newTraceID() traceID {
+	var id traceID
+	randomID(id[:])
+	return id
+}
+
+func newSpanID() spanID {
+	var id spanID
+	randomID(id[:])
+	return id
+}
+
+// randomID fills the ID with random bytes, it is never all zeros which is an invalid ID.
+func randomID(id []byte) {
+	if _, err := rand.Read(id); err != nil {
+		panic(fmt.Sprintf("error generating span ID: %v", err))
+	}
+	id[len(id)-1] |= 1
+}
+
+// exporter is the exporter of the ended spans, nil when tracing is disabled.
+var exporter atomic.Pointer[Exporter]
@@ -0,0 +1,152 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// enable records the spans with the exporter until the end of the test.
+func enable(t *testing.T, e *Exporter) {
+	exporter.Store(e)
+	t.Cleanup(func() { exporter.Store(nil) })
+}
+
+func TestSpansDisabled(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, span := Start(context.Background(), "reconcile")
+	g.Expect(span).To(BeNil())
+	g.Expect(SpanFromContext(ctx)).To(BeNil())
+
+	// The methods of a nil span do nothing.
+	span.SetAttributes(String("key", "value"))
+	span.RecordError(errors.New("error"))
+	span.End()
+	g.Expect(span.TraceID()).To(BeEmpty())
+}
+
+func TestStartClient(t *testing.T) {
+	g := NewWithT(t)
+
+	e, err := NewExporter("http://localhost:4318", "test")
+	g.Expect(err).ToNot(HaveOccurred())
+	enable(t, e)
+
+	_, span := StartClient(context.Background(), "HTTP GET")
+	g.Expect(span).To(BeNil(), "expected no span without a parent span")
+
+	ctx, parent := Start(context.Background(), "reconcile")
+	g.Expect(parent).ToNot(BeNil())
+	_, span = StartClient(ctx, "HTTP GET")
+	g.Expect(span).ToNot(BeNil())
+	g.Expect(span.kind).To(Equal(SpanKindClient))
+	g.Expect(span.traceID).To(Equal(parent.traceID))
+	g.Expect(span.parentID).To(Equal(parent.spanID))
+}
+
+func TestNewExporter(t *testing.T) {
+	testCases := []struct {
+		endpoint    string
+		expectedURL string
+		expectedErr bool
+	}{
+		{endpoint: "http://otel-collector:4318", expectedURL: "http://otel-collector:4318/v1/traces"},
+		{endpoint: "https://otel-collector:4318/", expectedURL: "https://otel-collector:4318/v1/traces"},
+		{endpoint: "http://otel-collector:4318/custom/traces", expectedURL: "http://otel-collector:4318/custom/traces"},
+		{endpoint: "otel-collector:4317", expectedErr: true},
+		{endpoint: "grpc://otel-collector:4317", expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.endpoint, func(t *testing.T) {
+			g := NewWithT(t)
+
+			e, err := NewExporter(tc.endpoint, "test")
+			if tc.expectedErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(e.url).To(Equal(tc.expectedURL))
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	g := NewWithT(t)
+
+	requests := make(chan exportRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		body, err := io.ReadAll(r.Body)
+		if err != nil || r.URL.Path != tracesPath || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req exportRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests <- req
+	}))
+	defer server.Close()
+
+	e, err := NewExporter(server.URL, "machine-controller")
+	g.Expect(err).ToNot(HaveOccurred())
+	enable(t, e)
+
+	reconciler := NewReconciler("machine-controller", reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		_, span := StartClient(ctx, "vsphere CreateVM_Task", String("cloud.provider", "vsphere"))
+		span.RecordError(errors.New("insufficient resources"))
+		span.End()
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}))
+	_, err = reconciler.Reconcile(context.Background(), reconcile.Request{})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// The queued spans are exported when the exporter stops.
+	g.Expect(e.Start(ctx)).To(Succeed())
+
+	var req exportRequest
+	g.Eventually(requests).Should(Receive(&req))
+	g.Expect(req.ResourceSpans).To(HaveLen(1))
+	g.Expect(req.ResourceSpans[0].Resource.Attributes).To(ConsistOf(encodeAttribute(String("service.name", "machine-controller"))))
+	g.Expect(req.ResourceSpans[0].ScopeSpans).To(HaveLen(1))
+	g.Expect(req.ResourceSpans[0].ScopeSpans[0].Scope.Name).To(Equal(instrumentationScope))
+
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	g.Expect(spans).To(HaveLen(2))
+	// The child span ends first.
+	child, parent := spans[0], spans[1]
+
+	g.Expect(parent.Name).To(Equal("machine-controller Reconcile"))
+	g.Expect(parent.Kind).To(Equal(SpanKindInternal))
+	g.Expect(parent.ParentSpanID).To(BeEmpty())
+	g.Expect(parent.TraceID).To(HaveLen(32))
+	g.Expect(parent.SpanID).To(HaveLen(16))
+	g.Expect(parent.Status).To(Equal(status{}))
+	g.Expect(parent.Attributes).To(ContainElements(
+		encodeAttribute(String("controller", "machine-controller")),
+		encodeAttribute(Bool("requeue", true)),
+		encodeAttribute(String("requeue_after", "1m0s")),
+	))
+
+	g.Expect(child.Name).To(Equal("vsphere CreateVM_Task"))
+	g.Expect(child.Kind).To(Equal(SpanKindClient))
+	g.Expect(child.TraceID).To(Equal(parent.TraceID))
+	g.Expect(child.ParentSpanID).To(Equal(parent.SpanID))
+	g.Expect(child.Status).To(Equal(status{Code: statusCodeError, Message: "insufficient resources"}))
+	g.Expect(child.Attributes).To(ConsistOf(encodeAttribute(String("cloud.provider", "vsphere"))))
+}
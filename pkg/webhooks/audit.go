@@ -0,0 +1,70 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// The keys of the audit annotations added to the admission responses. The API server prefixes them with the name
+// of the webhook, e.g. validation.machine.machine.openshift.io/instance-type, in the audit events of the requests.
+const (
+	// auditDefaultedAnnotation lists the sorted JSON pointers of the fields set by a defaulting webhook, separated by commas.
+	auditDefaultedAnnotation = "defaulted"
+
+	// auditWarningsAnnotation lists the warnings of an admitted request, as a JSON array.
+	auditWarningsAnnotation = "warnings"
+
+	// auditInstanceTypeAnnotation records the change of the instance type of an existing Machine, e.g. "m6i.xlarge -> m6i.2xlarge".
+	auditInstanceTypeAnnotation = "instance-type"
+)
+
+// audited returns the response with audit annotations recording the fields it defaulted, the warnings the
+// request was admitted with and the change of the instance type, if any, so that the changes of the Machine
+// specs are captured by the audit logs of the cluster.
+func audited(res admission.Response, instanceTypeChange string) admission.Response {
+	auditAnnotations := map[string]string{}
+
+	if len(res.Patches) > 0 {
+		paths := make([]string, 0, len(res.Patches))
+		for _, patch := range res.Patches {
+			paths = append(paths, patch.Path)
+		}
+		sort.Strings(paths)
+		auditAnnotations[auditDefaultedAnnotation] = strings.Join(paths, ",")
+	}
+	if len(res.Warnings) > 0 {
+		// Warnings are plain text, which may contain commas, a JSON array keeps them apart.
+		warnings, err := json.Marshal(res.Warnings)
+		if err == nil {
+			auditAnnotations[auditWarningsAnnotation] = string(warnings)
+		}
+	}
+	if instanceTypeChange != "" {
+		auditAnnotations[auditInstanceTypeAnnotation] = instanceTypeChange
+	}
+
+	if len(auditAnnotations) == 0 {
+		return res
+	}
+	res.AuditAnnotations = auditAnnotations
+	return res
+}
+
+// auditInstanceTypeChange returns the change of the instance type of an existing Machine, to be recorded in the
+// audit annotations, or an empty string.
+func auditInstanceTypeChange(platformStatus *osconfigv1.PlatformStatus, m, oldM *machinev1beta1.Machine) string {
+	if platformStatus == nil {
+		return ""
+	}
+	_, oldInstanceType, instanceType, changed := instanceTypeChange(platformStatus.Type, m, oldM)
+	if !changed {
+		return ""
+	}
+	return fmt.Sprintf("%s -> %s", oldInstanceType, instanceType)
+}
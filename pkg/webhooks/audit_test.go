@@ -0,0 +1,76 @@
+package webhooks
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	osconfigv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestAudited(t *testing.T) {
+	testCases := []struct {
+		name                     string
+		response                 admission.Response
+		instanceTypeChange       string
+		expectedAuditAnnotations map[string]string
+	}{
+		{
+			name:     "allowed",
+			response: admission.Allowed("Machine valid"),
+		},
+		{
+			name:     "defaulted",
+			response: admission.PatchResponseFromRaw([]byte(`{"spec":{}}`), []byte(`{"spec":{"ami":"ami-1"},"status":{}}`)),
+			expectedAuditAnnotations: map[string]string{
+				auditDefaultedAnnotation: "/spec/ami,/status",
+			},
+		},
+		{
+			name:               "allowed with warnings and a new instance type",
+			response:           admission.Allowed("Machine valid").WithWarnings("providerSpec.subnet: deprecated, use subnets", "providerSpec.ami: deprecated"),
+			instanceTypeChange: "m6i.xlarge -> m6i.2xlarge",
+			expectedAuditAnnotations: map[string]string{
+				auditWarningsAnnotation:     `["providerSpec.subnet: deprecated, use subnets","providerSpec.ami: deprecated"]`,
+				auditInstanceTypeAnnotation: "m6i.xlarge -> m6i.2xlarge",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			res := audited(tc.response, tc.instanceTypeChange)
+			if tc.expectedAuditAnnotations == nil {
+				g.Expect(res.AuditAnnotations).To(BeNil())
+				return
+			}
+			g.Expect(res.AuditAnnotations).To(Equal(tc.expectedAuditAnnotations))
+		})
+	}
+}
+
+func TestAuditInstanceTypeChange(t *testing.T) {
+	machineWithProviderSpec := func(providerSpec string) *machinev1beta1.Machine {
+		return &machinev1beta1.Machine{
+			Spec: machinev1beta1.MachineSpec{
+				ProviderSpec: machinev1beta1.ProviderSpec{
+					Value: &kruntime.RawExtension{Raw: []byte(providerSpec)},
+				},
+			},
+		}
+	}
+	g := NewWithT(t)
+
+	aws := &osconfigv1.PlatformStatus{Type: osconfigv1.AWSPlatformType}
+	m := machineWithProviderSpec(`{"instanceType": "m6i.2xlarge"}`)
+	oldM := machineWithProviderSpec(`{"instanceType": "m6i.xlarge"}`)
+
+	g.Expect(auditInstanceTypeChange(aws, m, oldM)).To(Equal("m6i.xlarge -> m6i.2xlarge"))
+	g.Expect(auditInstanceTypeChange(aws, m, nil)).To(BeEmpty())
+	g.Expect(auditInstanceTypeChange(aws, m, m)).To(BeEmpty())
+	g.Expect(auditInstanceTypeChange(nil, m, oldM)).To(BeEmpty())
+}
@@ -0,0 +1,163 @@
+package webhooks
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/strings/slices"
+)
+
+const (
+	azureHyperVGenerationV1 = "V1"
+	azureHyperVGenerationV2 = "V2"
+
+	// azureAcceleratedNetworkingMinVCPUs is the number of vCPUs a VM size needs for accelerated networking.
+	azureAcceleratedNetworkingMinVCPUs = 2
+)
+
+// azureVMSizeRegexp splits a VM size such as Standard_D4s_v3 or Standard_M64-32ms into its family,
+// its vCPUs, its constrained vCPUs, its additive features and its version.
+var azureVMSizeRegexp = regexp.MustCompile(`^(?i:standard)_([A-Za-z]+?)(\d+)(?:-(\d+))?([A-Za-z]*)(?:_([vV]\d+))?$`)
+
+// azureVMSeriesCapabilities are the capabilities of the VM sizes of an Azure series.
+type azureVMSeriesCapabilities struct {
+	acceleratedNetworking bool
+	ultraSSD              bool
+	// hyperVGenerations are the image generations supported by the series, all when empty.
+	hyperVGenerations []string
+}
+
+// azureVMSeries are the capabilities of the common Azure VM series, keyed by family, additive features and version,
+// e.g. Dsv3 for Standard_D4s_v3. VM sizes of other series are not checked, as the cloud may support them at any time.
+var azureVMSeries = map[string]azureVMSeriesCapabilities{
+	"Av2":   {hyperVGenerations: []string{azureHyperVGenerationV1}},
+	"Bs":    {},
+	"Bms":   {},
+	"Bls":   {},
+	"Dv2":   {acceleratedNetworking: true},
+	"DSv2":  {acceleratedNetworking: true},
+	"Dv3":   {acceleratedNetworking: true},
+	"Dsv3":  {acceleratedNetworking: true, ultraSSD: true},
+	"Dv4":   {acceleratedNetworking: true},
+	"Dsv4":  {acceleratedNetworking: true, ultraSSD: true},
+	"Ddv4":  {acceleratedNetworking: true},
+	"Ddsv4": {acceleratedNetworking: true, ultraSSD: true},
+	"Dav4":  {acceleratedNetworking: true},
+	"Dasv4": {acceleratedNetworking: true, ultraSSD: true},
+	"Dv5":   {acceleratedNetworking: true},
+	"Dsv5":  {acceleratedNetworking: true, ultraSSD: true},
+	"Ddv5":  {acceleratedNetworking: true},
+	"Ddsv5": {acceleratedNetworking: true, ultraSSD: true},
+	"Dasv5": {acceleratedNetworking: true, ultraSSD: true},
+	"Ev3":   {acceleratedNetworking: true},
+	"Esv3":  {acceleratedNetworking: true, ultraSSD: true},
+	"Ev4":   {acceleratedNetworking: true},
+	"Esv4":  {acceleratedNetworking: true, ultraSSD: true},
+	"Edsv4": {acceleratedNetworking: true, ultraSSD: true},
+	"Easv4": {acceleratedNetworking: true, ultraSSD: true},
+	"Ev5":   {acceleratedNetworking: true},
+	"Esv5":  {acceleratedNetworking: true, ultraSSD: true},
+	"Edsv5": {acceleratedNetworking: true, ultraSSD: true},
+	"Easv5": {acceleratedNetworking: true, ultraSSD: true},
+	"Fsv2":  {acceleratedNetworking: true, ultraSSD: true},
+	"Lsv2":  {acceleratedNetworking: true, ultraSSD: true},
+	"Lsv3":  {acceleratedNetworking: true, ultraSSD: true},
+	"Ms":    {acceleratedNetworking: true, ultraSSD: true},
+	"Mms":   {acceleratedNetworking: true, ultraSSD: true},
+	"Msv2":  {acceleratedNetworking: true, ultraSSD: true, hyperVGenerations: []string{azureHyperVGenerationV2}},
+	"Mmsv2": {acceleratedNetworking: true, ultraSSD: true, hyperVGenerations: []string{azureHyperVGenerationV2}},
+}
+
+// azureVMSize is a VM size parsed into its series and vCPUs.
+type azureVMSize struct {
+	series string
+	vCPUs  int
+}
+
+// parseAzureVMSize parses the VM size, returning false when it does not follow the Azure naming conventions.
+func parseAzureVMSize(vmSize string) (azureVMSize, bool) {
+	match := azureVMSizeRegexp.FindStringSubmatch(vmSize)
+	if match == nil {
+		return azureVMSize{}, false
+	}
+	vCPUs, err := strconv.Atoi(match[2])
+	if err != nil {
+		return azureVMSize{}, false
+	}
+	// Constrained vCPU sizes only expose the constrained number of vCPUs.
+	if match[3] != "" {
+		if vCPUs, err = strconv.Atoi(match[3]); err != nil {
+			return azureVMSize{}, false
+		}
+	}
+	return azureVMSize{
+		series: strings.ToUpper(match[1]) + strings.ToLower(match[4]) + strings.ToLower(match[5]),
+		vCPUs:  vCPUs,
+	}, true
+}
+
+// azureImageHyperVGeneration returns the field identifying the image and the Hyper-V generation of the image,
+// when the resource ID or the SKU of the image states it.
+func azureImageHyperVGeneration(image machinev1beta1.Image) (*field.Path, string, string) {
+	fldPath, name := field.NewPath("providerSpec", "image", "resourceID"), image.ResourceID
+	if name == "" {
+		fldPath, name = field.NewPath("providerSpec", "image", "sku"), image.SKU
+	}
+	switch {
+	case strings.Contains(strings.ToLower(name), "gen2"):
+		return fldPath, name, azureHyperVGenerationV2
+	case strings.Contains(strings.ToLower(name), "gen1"):
+		return fldPath, name, azureHyperVGenerationV1
+	}
+	return fldPath, name, ""
+}
+
+// validateAzureVMSizeCapabilities checks the features requested by the providerSpec against the capabilities
+// of its VM size, which would otherwise only be rejected by Azure when the VM is created.
+func validateAzureVMSizeCapabilities(providerSpec *machinev1beta1.AzureMachineProviderSpec) []error {
+	size, ok := parseAzureVMSize(providerSpec.VMSize)
+	if !ok {
+		return nil
+	}
+	capabilities, ok := azureVMSeries[size.series]
+	if !ok {
+		return nil
+	}
+
+	var errs []error
+	if providerSpec.AcceleratedNetworking {
+		fldPath := field.NewPath("providerSpec", "acceleratedNetworking")
+		if !capabilities.acceleratedNetworking {
+			errs = append(errs, field.Invalid(fldPath, providerSpec.AcceleratedNetworking,
+				fmt.Sprintf("accelerated networking is not supported by the %s series of vmSize %s", size.series, providerSpec.VMSize)))
+		} else if size.vCPUs < azureAcceleratedNetworkingMinVCPUs {
+			errs = append(errs, field.Invalid(fldPath, providerSpec.AcceleratedNetworking,
+				fmt.Sprintf("accelerated networking requires at least %d vCPUs, vmSize %s has %d", azureAcceleratedNetworkingMinVCPUs, providerSpec.VMSize, size.vCPUs)))
+		}
+	}
+
+	if !capabilities.ultraSSD {
+		if providerSpec.UltraSSDCapability == machinev1beta1.AzureUltraSSDCapabilityEnabled {
+			errs = append(errs, field.Invalid(field.NewPath("providerSpec", "ultraSSDCapability"), providerSpec.UltraSSDCapability,
+				fmt.Sprintf("ultra SSDs are not supported by the %s series of vmSize %s", size.series, providerSpec.VMSize)))
+		}
+		for i, disk := range providerSpec.DataDisks {
+			if disk.ManagedDisk.StorageAccountType == machinev1beta1.StorageAccountUltraSSDLRS {
+				errs = append(errs, field.Invalid(field.NewPath("providerSpec", "dataDisks").Index(i).Child("managedDisk", "storageAccountType"), disk.ManagedDisk.StorageAccountType,
+					fmt.Sprintf("ultra SSDs are not supported by the %s series of vmSize %s", size.series, providerSpec.VMSize)))
+			}
+		}
+	}
+
+	fldPath, image, generation := azureImageHyperVGeneration(providerSpec.Image)
+	if generation != "" && len(capabilities.hyperVGenerations) > 0 && !slices.Contains(capabilities.hyperVGenerations, generation) {
+		errs = append(errs, field.Invalid(fldPath, image,
+			fmt.Sprintf("vmSize %s only supports Hyper-V generation %s images, the image is generation %s", providerSpec.VMSize, strings.Join(capabilities.hyperVGenerations, ","), generation)))
+	}
+
+	return errs
+}
@@ -0,0 +1,127 @@
+package webhooks
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+)
+
+func TestParseAzureVMSize(t *testing.T) {
+	testCases := []struct {
+		vmSize       string
+		expectedSize azureVMSize
+		expectedOk   bool
+	}{
+		{vmSize: "Standard_D4s_v3", expectedSize: azureVMSize{series: "Dsv3", vCPUs: 4}, expectedOk: true},
+		{vmSize: "Standard_D4s_V3", expectedSize: azureVMSize{series: "Dsv3", vCPUs: 4}, expectedOk: true},
+		{vmSize: "standard_ds2_v2", expectedSize: azureVMSize{series: "DSv2", vCPUs: 2}, expectedOk: true},
+		{vmSize: "Standard_B2ms", expectedSize: azureVMSize{series: "Bms", vCPUs: 2}, expectedOk: true},
+		{vmSize: "Standard_M64-32ms", expectedSize: azureVMSize{series: "Mms", vCPUs: 32}, expectedOk: true},
+		{vmSize: "Standard_E64is_v3", expectedSize: azureVMSize{series: "Eisv3", vCPUs: 64}, expectedOk: true},
+		{vmSize: "Basic_A1"},
+		{vmSize: "vmSize"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.vmSize, func(t *testing.T) {
+			g := NewWithT(t)
+
+			size, ok := parseAzureVMSize(tc.vmSize)
+			g.Expect(ok).To(Equal(tc.expectedOk))
+			g.Expect(size).To(Equal(tc.expectedSize))
+		})
+	}
+}
+
+func TestValidateAzureVMSizeCapabilities(t *testing.T) {
+	testCases := []struct {
+		name           string
+		providerSpec   machinev1beta1.AzureMachineProviderSpec
+		expectedErrors []string
+	}{
+		{
+			name: "with supported features",
+			providerSpec: machinev1beta1.AzureMachineProviderSpec{
+				VMSize:                "Standard_D4s_v3",
+				AcceleratedNetworking: true,
+				UltraSSDCapability:    machinev1beta1.AzureUltraSSDCapabilityEnabled,
+				Image:                 machinev1beta1.Image{ResourceID: "/resourceGroups/rg/providers/Microsoft.Compute/galleries/gallery/images/cluster-gen2/versions/latest"},
+			},
+		},
+		{
+			name: "with an unknown series",
+			providerSpec: machinev1beta1.AzureMachineProviderSpec{
+				VMSize:                "Standard_X4s_v9",
+				AcceleratedNetworking: true,
+				UltraSSDCapability:    machinev1beta1.AzureUltraSSDCapabilityEnabled,
+			},
+		},
+		{
+			name: "with accelerated networking on a series which does not support it",
+			providerSpec: machinev1beta1.AzureMachineProviderSpec{
+				VMSize:                "Standard_B2ms",
+				AcceleratedNetworking: true,
+			},
+			expectedErrors: []string{"providerSpec.acceleratedNetworking: Invalid value: true: accelerated networking is not supported by the Bms series of vmSize Standard_B2ms"},
+		},
+		{
+			name: "with accelerated networking on a single vCPU",
+			providerSpec: machinev1beta1.AzureMachineProviderSpec{
+				VMSize:                "Standard_DS1_v2",
+				AcceleratedNetworking: true,
+			},
+			expectedErrors: []string{"providerSpec.acceleratedNetworking: Invalid value: true: accelerated networking requires at least 2 vCPUs, vmSize Standard_DS1_v2 has 1"},
+		},
+		{
+			name: "with ultra SSDs on a series which does not support them",
+			providerSpec: machinev1beta1.AzureMachineProviderSpec{
+				VMSize:             "Standard_D4_v3",
+				UltraSSDCapability: machinev1beta1.AzureUltraSSDCapabilityEnabled,
+				DataDisks: []machinev1beta1.DataDisk{
+					{ManagedDisk: machinev1beta1.DataDiskManagedDiskParameters{StorageAccountType: machinev1beta1.StorageAccountPremiumLRS}},
+					{ManagedDisk: machinev1beta1.DataDiskManagedDiskParameters{StorageAccountType: machinev1beta1.StorageAccountUltraSSDLRS}},
+				},
+			},
+			expectedErrors: []string{
+				"providerSpec.ultraSSDCapability: Invalid value: \"Enabled\": ultra SSDs are not supported by the Dv3 series of vmSize Standard_D4_v3",
+				"providerSpec.dataDisks[1].managedDisk.storageAccountType: Invalid value: \"UltraSSD_LRS\": ultra SSDs are not supported by the Dv3 series of vmSize Standard_D4_v3",
+			},
+		},
+		{
+			name: "with a generation 2 image on a generation 1 series",
+			providerSpec: machinev1beta1.AzureMachineProviderSpec{
+				VMSize: "Standard_A4_v2",
+				Image:  machinev1beta1.Image{Publisher: "redhat", Offer: "rh-ocp-worker", SKU: "rh-ocp-worker-gen2", Version: "4.13"},
+			},
+			expectedErrors: []string{"providerSpec.image.sku: Invalid value: \"rh-ocp-worker-gen2\": vmSize Standard_A4_v2 only supports Hyper-V generation V1 images, the image is generation V2"},
+		},
+		{
+			name: "with a generation 1 image on a generation 2 series",
+			providerSpec: machinev1beta1.AzureMachineProviderSpec{
+				VMSize: "Standard_M208ms_v2",
+				Image:  machinev1beta1.Image{ResourceID: "/resourceGroups/rg/providers/Microsoft.Compute/images/rhcos-gen1"},
+			},
+			expectedErrors: []string{"providerSpec.image.resourceID: Invalid value: \"/resourceGroups/rg/providers/Microsoft.Compute/images/rhcos-gen1\": vmSize Standard_M208ms_v2 only supports Hyper-V generation V2 images, the image is generation V1"},
+		},
+		{
+			name: "with an image of an unknown generation",
+			providerSpec: machinev1beta1.AzureMachineProviderSpec{
+				VMSize: "Standard_M208ms_v2",
+				Image:  machinev1beta1.Image{ResourceID: "/resourceGroups/rg/providers/Microsoft.Compute/images/rhcos"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var errs []string
+			for _, err := range validateAzureVMSizeCapabilities(&tc.providerSpec) {
+				errs = append(errs, err.Error())
+			}
+			g.Expect(errs).To(Equal(tc.expectedErrors))
+		})
+	}
+}
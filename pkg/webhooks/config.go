@@ -15,6 +15,7 @@ const (
 	DefaultMachineValidatingHookPath                   = "/validate-machine-openshift-io-v1beta1-machine"
 	DefaultMachineSetMutatingHookPath                  = "/mutate-machine-openshift-io-v1beta1-machineset"
 	DefaultMachineSetValidatingHookPath                = "/validate-machine-openshift-io-v1beta1-machineset"
+	DefaultMachineHealthCheckValidatingHookPath        = "/validate-machine-openshift-io-v1beta1-machinehealthcheck"
 	DefaultMetal3RemediationMutatingHookPath           = "/mutate-infrastructure-cluster-x-k8s-io-v1beta1-metal3remediation"
 	DefaultMetal3RemediationValidatingHookPath         = "/validate-infrastructure-cluster-x-k8s-io-v1beta1-metal3remediation"
 	DefaultMetal3RemediationTemplateMutatingHookPath   = "/mutate-infrastructure-cluster-x-k8s-io-v1beta1-metal3remediationtemplate"
@@ -38,7 +39,8 @@ var (
 	webhookSideEffects   = admissionregistrationv1.SideEffectClassNone
 )
 
-// NewMachineValidatingWebhookConfiguration creates a validation webhook configuration with configured Machine and MachineSet webhooks
+// NewMachineValidatingWebhookConfiguration creates a validation webhook configuration with configured Machine, MachineSet
+// and MachineHealthCheck webhooks
 func NewMachineValidatingWebhookConfiguration() *admissionregistrationv1.ValidatingWebhookConfiguration {
 	validatingWebhookConfiguration := &admissionregistrationv1.ValidatingWebhookConfiguration{
 		ObjectMeta: metav1.ObjectMeta{
@@ -50,6 +52,7 @@ func NewMachineValidatingWebhookConfiguration() *admissionregistrationv1.Validat
 		Webhooks: []admissionregistrationv1.ValidatingWebhook{
 			MachineValidatingWebhook(),
 			MachineSetValidatingWebhook(),
+			MachineHealthCheckValidatingWebhook(),
 		},
 	}
 
@@ -85,6 +88,7 @@ func MachineValidatingWebhook() admissionregistrationv1.ValidatingWebhook {
 				Operations: []admissionregistrationv1.OperationType{
 					admissionregistrationv1.Create,
 					admissionregistrationv1.Update,
+					admissionregistrationv1.Delete,
 				},
 			},
 		},
@@ -123,6 +127,38 @@ func MachineSetValidatingWebhook() admissionregistrationv1.ValidatingWebhook {
 	}
 }
 
+// MachineHealthCheckValidatingWebhook returns validating webhooks for machineHealthCheck to populate the configuration
+func MachineHealthCheckValidatingWebhook() admissionregistrationv1.ValidatingWebhook {
+	serviceReference := admissionregistrationv1.ServiceReference{
+		Namespace: defaultWebhookServiceNamespace,
+		Name:      defaultWebhookServiceName,
+		Path:      pointer.String(DefaultMachineHealthCheckValidatingHookPath),
+		Port:      pointer.Int32(defaultWebhookServicePort),
+	}
+	return admissionregistrationv1.ValidatingWebhook{
+		AdmissionReviewVersions: []string{"v1"},
+		Name:                    "validation.machinehealthcheck.machine.openshift.io",
+		FailurePolicy:           &webhookFailurePolicy,
+		SideEffects:             &webhookSideEffects,
+		ClientConfig: admissionregistrationv1.WebhookClientConfig{
+			Service: &serviceReference,
+		},
+		Rules: []admissionregistrationv1.RuleWithOperations{
+			{
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{machinev1beta1.GroupName},
+					APIVersions: []string{machinev1beta1.SchemeGroupVersion.Version},
+					Resources:   []string{"machinehealthchecks"},
+				},
+				Operations: []admissionregistrationv1.OperationType{
+					admissionregistrationv1.Create,
+					admissionregistrationv1.Update,
+				},
+			},
+		},
+	}
+}
+
 // NewMetal3RemediationValidatingWebhookConfiguration creates a validation webhook configuration with configured
 // metal3remediation(template) webhooks. Metal3Remediation(Templates) were backported from metal3, their CRDs and the
 // actual webhook implementation can be found in cluster-api-provider-baremetal
@@ -0,0 +1,101 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// deprecatedProviderSpecField is a providerSpec field which is deprecated and will be rejected in a future release.
+type deprecatedProviderSpecField struct {
+	path   []string
+	detail string
+}
+
+// deprecatedInstanceTypes are the instance types of a platform which are to be retired by the cloud provider,
+// matched either by the prefix of their series or by name.
+type deprecatedInstanceTypes struct {
+	path     []string
+	prefixes []string
+	names    sets.String
+	detail   string
+}
+
+var deprecatedProviderSpecFields = map[osconfigv1.PlatformType][]deprecatedProviderSpecField{
+	osconfigv1.AWSPlatformType: {
+		{path: []string{"keyName"}, detail: "SSH keys should be configured through the user data secret"},
+	},
+	osconfigv1.GCPPlatformType: {
+		{path: []string{"preemptible"}, detail: "preemptible VMs are being replaced by Spot VMs on GCP"},
+	},
+}
+
+var deprecatedProviderSpecInstanceTypes = map[osconfigv1.PlatformType]deprecatedInstanceTypes{
+	osconfigv1.AWSPlatformType: {
+		path:     []string{"instanceType"},
+		prefixes: []string{"c1.", "c3.", "c4.", "m1.", "m2.", "m3.", "m4.", "r3.", "r4.", "t1.", "t2.", "i2.", "g2."},
+		detail:   "previous generation instance types may no longer be available, use a current generation instance type",
+	},
+	osconfigv1.AzurePlatformType: {
+		path:     []string{"vmSize"},
+		prefixes: []string{"Basic_A"},
+		names:    sets.NewString("Standard_A0", "Standard_A1", "Standard_A2", "Standard_A3", "Standard_A4", "Standard_A5", "Standard_A6", "Standard_A7", "Standard_A8", "Standard_A9", "Standard_A10", "Standard_A11"),
+		detail:   "Av1 series VM sizes are retired by Azure, use a current VM size",
+	},
+}
+
+// deprecatedProviderSpecWarnings returns admission warnings for the deprecated fields and instance types
+// set in the providerSpec of the Machine. Unchanged providerSpecs are not warned about again, so that
+// updates from the controllers do not produce warnings.
+func deprecatedProviderSpecWarnings(platform osconfigv1.PlatformType, m, oldM *machinev1beta1.Machine) []string {
+	if m.Spec.ProviderSpec.Value == nil || len(m.Spec.ProviderSpec.Value.Raw) == 0 {
+		return nil
+	}
+	if oldM != nil && equality.Semantic.DeepEqual(m.Spec.ProviderSpec, oldM.Spec.ProviderSpec) {
+		return nil
+	}
+
+	providerSpec := map[string]interface{}{}
+	if err := json.Unmarshal(m.Spec.ProviderSpec.Value.Raw, &providerSpec); err != nil {
+		// Invalid providerSpecs are reported by the platform validation.
+		return nil
+	}
+
+	var warnings []string
+	for _, deprecated := range deprecatedProviderSpecFields[platform] {
+		if _, found, _ := unstructured.NestedFieldNoCopy(providerSpec, deprecated.path...); found {
+			warnings = append(warnings, fmt.Sprintf("%s: field is deprecated and will be rejected in a future release: %s",
+				field.NewPath("providerSpec", deprecated.path...), deprecated.detail))
+		}
+	}
+
+	if deprecated, ok := deprecatedProviderSpecInstanceTypes[platform]; ok {
+		instanceType, _, _ := unstructured.NestedString(providerSpec, deprecated.path...)
+		if instanceType != "" && deprecated.matches(instanceType) {
+			warnings = append(warnings, fmt.Sprintf("%s: %s is deprecated and will be rejected in a future release: %s",
+				field.NewPath("providerSpec", deprecated.path...), instanceType, deprecated.detail))
+		}
+	}
+
+	return warnings
+}
+
+// matches returns true if the instance type is deprecated.
+func (d deprecatedInstanceTypes) matches(instanceType string) bool {
+	if d.names.Has(instanceType) {
+		return true
+	}
+	for _, prefix := range d.prefixes {
+		if strings.HasPrefix(instanceType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package webhooks
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	osconfigv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestDeprecatedProviderSpecWarnings(t *testing.T) {
+	machineWithProviderSpec := func(providerSpec string) *machinev1beta1.Machine {
+		return &machinev1beta1.Machine{
+			Spec: machinev1beta1.MachineSpec{
+				ProviderSpec: machinev1beta1.ProviderSpec{
+					Value: &kruntime.RawExtension{Raw: []byte(providerSpec)},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name             string
+		platform         osconfigv1.PlatformType
+		providerSpec     string
+		oldProviderSpec  string
+		expectedWarnings []string
+	}{
+		{
+			name:         "with a current AWS providerSpec",
+			platform:     osconfigv1.AWSPlatformType,
+			providerSpec: `{"instanceType": "m6i.xlarge"}`,
+		},
+		{
+			name:         "with a deprecated AWS field and instance type",
+			platform:     osconfigv1.AWSPlatformType,
+			providerSpec: `{"instanceType": "m4.large", "keyName": "ssh"}`,
+			expectedWarnings: []string{
+				"providerSpec.keyName: field is deprecated and will be rejected in a future release: SSH keys should be configured through the user data secret",
+				"providerSpec.instanceType: m4.large is deprecated and will be rejected in a future release: previous generation instance types may no longer be available, use a current generation instance type",
+			},
+		},
+		{
+			name:            "with an unchanged deprecated AWS providerSpec",
+			platform:        osconfigv1.AWSPlatformType,
+			providerSpec:    `{"instanceType": "m4.large"}`,
+			oldProviderSpec: `{"instanceType": "m4.large"}`,
+		},
+		{
+			name:            "with a changed deprecated AWS providerSpec",
+			platform:        osconfigv1.AWSPlatformType,
+			providerSpec:    `{"instanceType": "t2.micro"}`,
+			oldProviderSpec: `{"instanceType": "m4.large"}`,
+			expectedWarnings: []string{
+				"providerSpec.instanceType: t2.micro is deprecated and will be rejected in a future release: previous generation instance types may no longer be available, use a current generation instance type",
+			},
+		},
+		{
+			name:         "with a deprecated GCP field",
+			platform:     osconfigv1.GCPPlatformType,
+			providerSpec: `{"machineType": "n1-standard-4", "preemptible": true}`,
+			expectedWarnings: []string{
+				"providerSpec.preemptible: field is deprecated and will be rejected in a future release: preemptible VMs are being replaced by Spot VMs on GCP",
+			},
+		},
+		{
+			name:         "with a retired Azure VM size",
+			platform:     osconfigv1.AzurePlatformType,
+			providerSpec: `{"vmSize": "Standard_A2"}`,
+			expectedWarnings: []string{
+				"providerSpec.vmSize: Standard_A2 is deprecated and will be rejected in a future release: Av1 series VM sizes are retired by Azure, use a current VM size",
+			},
+		},
+		{
+			name:         "with a current Azure VM size of the A series",
+			platform:     osconfigv1.AzurePlatformType,
+			providerSpec: `{"vmSize": "Standard_A2_v2"}`,
+		},
+		{
+			name:         "with a platform without deprecations",
+			platform:     osconfigv1.VSpherePlatformType,
+			providerSpec: `{"keyName": "ssh", "instanceType": "m4.large"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var oldM *machinev1beta1.Machine
+			if tc.oldProviderSpec != "" {
+				oldM = machineWithProviderSpec(tc.oldProviderSpec)
+			}
+			warnings := deprecatedProviderSpecWarnings(tc.platform, machineWithProviderSpec(tc.providerSpec), oldM)
+			g.Expect(warnings).To(Equal(tc.expectedWarnings))
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/machine-api-operator/pkg/controller/vsphere"
+)
+
+const defaultDryRunEstimateTimeout = 5 * time.Second
+
+// dryRunEstimator estimates whether the cloud has the capacity to create the Machine, returning warnings
+// for the resources which may be exhausted, and an error when the cloud could not be queried.
+type dryRunEstimator func(ctx context.Context, m *machinev1beta1.Machine) ([]string, error)
+
+// EnableDryRunEstimates makes the validation of dry run Machine creations estimate whether the cloud has the capacity
+// for the Machine, returning warnings for the resources which may be exhausted. Only vSphere supports the estimates.
+func (a *admissionHandler) EnableDryRunEstimates() {
+	switch a.platformStatus.Type {
+	case osconfigv1.VSpherePlatformType:
+		a.dryRunEstimator = func(ctx context.Context, m *machinev1beta1.Machine) ([]string, error) {
+			providerSpec := new(machinev1beta1.VSphereMachineProviderSpec)
+			if err := unmarshalInto(m, providerSpec); err != nil {
+				return nil, err
+			}
+			return vsphere.EstimateProviderSpecCapacity(ctx, a.client, m.GetNamespace(), providerSpec)
+		}
+	default:
+		klog.Infof("Dry run capacity estimates are not supported on platform %s", a.platformStatus.Type)
+	}
+}
+
+// estimateDryRun returns the warnings of the dry run estimator for the Machine. Estimates which can not be
+// completed in time are reported as a warning, as dry runs are never rejected for lack of capacity.
+func (a *admissionHandler) estimateDryRun(ctx context.Context, m *machinev1beta1.Machine) []string {
+	ctx, cancel := context.WithTimeout(ctx, defaultDryRunEstimateTimeout)
+	defer cancel()
+
+	warnings, err := a.dryRunEstimator(ctx, m)
+	if err != nil {
+		klog.Warningf("%s: failed to estimate the capacity for the Machine: %v", m.GetName(), err)
+		return []string{fmt.Sprintf("capacity for the Machine could not be estimated: %v", err)}
+	}
+	return warnings
+}
@@ -0,0 +1,98 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestDryRunEstimates(t *testing.T) {
+	testCases := []struct {
+		name             string
+		operation        admissionv1.Operation
+		dryRun           *bool
+		estimateWarnings []string
+		estimateErr      error
+		expectedWarnings []string
+	}{
+		{
+			name:             "with a dry run creation",
+			operation:        admissionv1.Create,
+			dryRun:           pointer.Bool(true),
+			estimateWarnings: []string{"not enough capacity"},
+			expectedWarnings: []string{"not enough capacity"},
+		},
+		{
+			name:             "with a dry run creation which could not be estimated",
+			operation:        admissionv1.Create,
+			dryRun:           pointer.Bool(true),
+			estimateErr:      errors.New("connection refused"),
+			expectedWarnings: []string{"capacity for the Machine could not be estimated: connection refused"},
+		},
+		{
+			name:             "with a creation",
+			operation:        admissionv1.Create,
+			dryRun:           pointer.Bool(false),
+			estimateWarnings: []string{"not enough capacity"},
+		},
+		{
+			name:             "with a dry run update",
+			operation:        admissionv1.Update,
+			dryRun:           pointer.Bool(true),
+			estimateWarnings: []string{"not enough capacity"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			decoder, err := admission.NewDecoder(scheme.Scheme)
+			g.Expect(err).ToNot(HaveOccurred())
+			h := createMachineValidator(plainInfra, fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(), plainDNS)
+			g.Expect(h.InjectDecoder(decoder)).To(Succeed())
+
+			estimated := false
+			h.dryRunEstimator = func(ctx context.Context, m *machinev1beta1.Machine) ([]string, error) {
+				estimated = true
+				_, hasDeadline := ctx.Deadline()
+				g.Expect(hasDeadline).To(BeTrue())
+				return tc.estimateWarnings, tc.estimateErr
+			}
+
+			m := &machinev1beta1.Machine{
+				TypeMeta:   metav1.TypeMeta{Kind: "Machine", APIVersion: machinev1beta1.SchemeGroupVersion.String()},
+				ObjectMeta: metav1.ObjectMeta{Name: "machine", Namespace: "default"},
+			}
+			raw, err := json.Marshal(m)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			req := admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: tc.operation,
+					DryRun:    tc.dryRun,
+					Object:    kruntime.RawExtension{Raw: raw},
+				},
+			}
+			if tc.operation == admissionv1.Update {
+				req.OldObject = kruntime.RawExtension{Raw: raw}
+			}
+
+			resp := h.Handle(context.Background(), req)
+			g.Expect(resp.Allowed).To(BeTrue(), "unexpected response: %v", resp.Result)
+			g.Expect(resp.Warnings).To(Equal(tc.expectedWarnings))
+			g.Expect(estimated).To(Equal(tc.expectedWarnings != nil))
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+)
+
+var (
+	// awsDedicatedHostIDRegex matches the IDs of the dedicated hosts on AWS, e.g. h-0123456789abcdef0.
+	awsDedicatedHostIDRegex = regexp.MustCompile(`^h-([0-9a-f]{8}|[0-9a-f]{17})$`)
+	// awsHostGroupARNRegex matches the ARNs of the host resource groups on AWS.
+	awsHostGroupARNRegex = regexp.MustCompile(`^arn:aws(-[a-z]+)*:resource-groups:[a-z0-9-]+:[0-9]{12}:group/[a-zA-Z0-9._-]+$`)
+	// azureDedicatedHostIDRegex matches the resource IDs of the dedicated hosts on Azure.
+	azureDedicatedHostIDRegex = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.Compute/hostGroups/[^/]+/hosts/[^/]+$`)
+	// azureHostGroupIDRegex matches the resource IDs of the host groups on Azure.
+	azureHostGroupIDRegex = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.Compute/hostGroups/[^/]+$`)
+)
+
+// AllowHostAffinity makes the validation admit the annotations pinning Machines to dedicated hosts or host groups,
+// for the machine controllers which place the instances on them. They are rejected otherwise.
+func (a *admissionHandler) AllowHostAffinity() {
+	a.allowHostAffinity = true
+}
+
+// rejectHostAffinity rejects the annotations pinning Machines to dedicated hosts or host groups, when the machine
+// controllers of the cluster do not place the instances on them. The annotations are the ones found at the path,
+// those left unchanged since the old annotations are not rejected, so that the existing objects can still be updated.
+func rejectHostAffinity(objAnnotations, oldAnnotations map[string]string, path *field.Path) []error {
+	var errs []error
+	for _, annotation := range []string{annotations.DedicatedHostAnnotation, annotations.HostGroupAnnotation, annotations.HostGroupsAnnotation} {
+		value, ok := objAnnotations[annotation]
+		if oldValue, oldOk := oldAnnotations[annotation]; ok && (!oldOk || value != oldValue) {
+			errs = append(errs, field.Forbidden(path.Key(annotation), "dedicated hosts are not supported by the machine controller of this cluster"))
+		}
+	}
+	return errs
+}
+
+// validateHostAffinity validates the dedicated host or the host group a Machine is pinned to, along with
+// the providerSpec of the Machine. The annotations are the ones of the Machine, found at the path.
+func validateHostAffinity(platform osconfigv1.PlatformType, machineAnnotations map[string]string, providerSpec *machinev1beta1.ProviderSpec, path *field.Path) ([]string, []error) {
+	host, group := machineAnnotations[annotations.DedicatedHostAnnotation], machineAnnotations[annotations.HostGroupAnnotation]
+	if host == "" && group == "" {
+		return nil, nil
+	}
+
+	var errs []error
+	if host != "" && group != "" {
+		errs = append(errs, field.Forbidden(path.Key(annotations.HostGroupAnnotation), fmt.Sprintf("cannot be set along with the %s annotation", annotations.DedicatedHostAnnotation)))
+	}
+	if host != "" {
+		errs = append(errs, validateDedicatedHost(platform, host, path.Key(annotations.DedicatedHostAnnotation))...)
+	}
+	if group != "" {
+		errs = append(errs, validateHostGroup(platform, group, path.Key(annotations.HostGroupAnnotation))...)
+	}
+	warnings, placementErrs := validateHostPlacement(platform, providerSpec)
+	return warnings, append(errs, placementErrs...)
+}
+
+// validateHostGroups validates the host groups the Machines of the MachineSet are spread across. The Machine
+// template must not pin the Machines itself, as the host group of each Machine is chosen by the MachineSet.
+func validateHostGroups(platform osconfigv1.PlatformType, ms *machinev1beta1.MachineSet) ([]string, []error) {
+	groups := annotations.GetHostGroups(ms)
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	path := field.NewPath("metadata", "annotations").Key(annotations.HostGroupsAnnotation)
+	var errs []error
+	template := ms.Spec.Template.Annotations
+	if template[annotations.DedicatedHostAnnotation] != "" || template[annotations.HostGroupAnnotation] != "" {
+		errs = append(errs, field.Forbidden(path, fmt.Sprintf("cannot be set along with the %s or %s annotations of the machine template", annotations.DedicatedHostAnnotation, annotations.HostGroupAnnotation)))
+	}
+	seen := map[string]bool{}
+	for _, group := range groups {
+		if seen[group] {
+			errs = append(errs, field.Duplicate(path, group))
+			continue
+		}
+		seen[group] = true
+		errs = append(errs, validateHostGroup(platform, group, path)...)
+	}
+	warnings, placementErrs := validateHostPlacement(platform, &ms.Spec.Template.Spec.ProviderSpec)
+	return warnings, append(errs, placementErrs...)
+}
+
+// validateDedicatedHost validates the format of the ID of a dedicated host on the platforms supporting them.
+func validateDedicatedHost(platform osconfigv1.PlatformType, host string, path *field.Path) []error {
+	switch platform {
+	case osconfigv1.AWSPlatformType:
+		if !awsDedicatedHostIDRegex.MatchString(host) {
+			return []error{field.Invalid(path, host, "must be the ID of an AWS dedicated host, e.g. h-0123456789abcdef0")}
+		}
+	case osconfigv1.AzurePlatformType:
+		if !azureDedicatedHostIDRegex.MatchString(host) {
+			return []error{field.Invalid(path, host, "must be the resource ID of an Azure dedicated host, e.g. /subscriptions/<subscription>/resourceGroups/<group>/providers/Microsoft.Compute/hostGroups/<host-group>/hosts/<host>")}
+		}
+	}
+	return nil
+}
+
+// validateHostGroup validates the format of the ID of a host group on the platforms supporting them.
+func validateHostGroup(platform osconfigv1.PlatformType, group string, path *field.Path) []error {
+	switch platform {
+	case osconfigv1.AWSPlatformType:
+		if !awsHostGroupARNRegex.MatchString(group) {
+			return []error{field.Invalid(path, group, "must be the ARN of an AWS host resource group, e.g. arn:aws:resource-groups:us-east-1:123456789012:group/<name>")}
+		}
+	case osconfigv1.AzurePlatformType:
+		if !azureHostGroupIDRegex.MatchString(group) {
+			return []error{field.Invalid(path, group, "must be the resource ID of an Azure host group, e.g. /subscriptions/<subscription>/resourceGroups/<group>/providers/Microsoft.Compute/hostGroups/<host-group>")}
+		}
+	}
+	return nil
+}
+
+// validateHostPlacement validates that the providerSpec of a Machine pinned to a dedicated host or a host group
+// can be placed on it. It warns on the platforms which do not support the dedicated hosts.
+func validateHostPlacement(platform osconfigv1.PlatformType, providerSpec *machinev1beta1.ProviderSpec) ([]string, []error) {
+	switch platform {
+	case osconfigv1.AWSPlatformType:
+		path := []string{"placement", "tenancy"}
+		if tenancy := providerSpecString(providerSpec, path); tenancy != string(machinev1beta1.HostTenancy) {
+			return nil, []error{field.Invalid(field.NewPath("providerSpec", path...), tenancy,
+				fmt.Sprintf("must be %s for machines pinned to a dedicated host or a host group", machinev1beta1.HostTenancy))}
+		}
+	case osconfigv1.AzurePlatformType:
+		if providerSpecHasField(providerSpec, "spotVMOptions") {
+			return nil, []error{field.Forbidden(field.NewPath("providerSpec", "spotVMOptions"), "spot VMs cannot run on a dedicated host or a host group")}
+		}
+	default:
+		return []string{fmt.Sprintf("dedicated hosts are not supported on platform %s, the %s and %s annotations are ignored",
+			platform, annotations.DedicatedHostAnnotation, annotations.HostGroupAnnotation)}, nil
+	}
+	return nil, nil
+}
+
+// providerSpecHasField returns true if the top level field is set, and not null, in the providerSpec.
+func providerSpecHasField(providerSpec *machinev1beta1.ProviderSpec, name string) bool {
+	if providerSpec.Value == nil || len(providerSpec.Value.Raw) == 0 {
+		return false
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(providerSpec.Value.Raw, &values); err != nil {
+		// Invalid providerSpecs are reported by the platform validation.
+		return false
+	}
+	return values[name] != nil
+}
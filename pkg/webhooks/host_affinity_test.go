@@ -0,0 +1,226 @@
+package webhooks
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	osconfigv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+)
+
+func TestValidateHostAffinity(t *testing.T) {
+	const (
+		awsHost       = "h-0123456789abcdef0"
+		awsGroup      = "arn:aws:resource-groups:us-east-1:123456789012:group/dedicated-hosts"
+		azureHost     = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/hostGroups/hg/hosts/host-1"
+		azureGroup    = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/hostGroups/hg"
+		awsHostSpec   = `{"placement": {"tenancy": "host"}}`
+		awsSharedSpec = `{"placement": {"tenancy": "default"}}`
+	)
+
+	testCases := []struct {
+		name             string
+		platform         osconfigv1.PlatformType
+		host             string
+		group            string
+		providerSpec     string
+		expectedWarnings []string
+		expectedErrs     []string
+	}{
+		{
+			name:         "without host affinity",
+			platform:     osconfigv1.AWSPlatformType,
+			providerSpec: awsSharedSpec,
+		},
+		{
+			name:         "with an AWS dedicated host",
+			platform:     osconfigv1.AWSPlatformType,
+			host:         awsHost,
+			providerSpec: awsHostSpec,
+		},
+		{
+			name:         "with an AWS host group",
+			platform:     osconfigv1.AWSPlatformType,
+			group:        awsGroup,
+			providerSpec: awsHostSpec,
+		},
+		{
+			name:         "with an invalid AWS dedicated host and shared tenancy",
+			platform:     osconfigv1.AWSPlatformType,
+			host:         "host-1",
+			providerSpec: awsSharedSpec,
+			expectedErrs: []string{
+				`metadata.annotations[machine.openshift.io/dedicated-host]: Invalid value: "host-1": must be the ID of an AWS dedicated host, e.g. h-0123456789abcdef0`,
+				`providerSpec.placement.tenancy: Invalid value: "default": must be host for machines pinned to a dedicated host or a host group`,
+			},
+		},
+		{
+			name:         "with both a dedicated host and a host group",
+			platform:     osconfigv1.AWSPlatformType,
+			host:         awsHost,
+			group:        awsGroup,
+			providerSpec: awsHostSpec,
+			expectedErrs: []string{
+				"metadata.annotations[machine.openshift.io/host-group]: Forbidden: cannot be set along with the machine.openshift.io/dedicated-host annotation",
+			},
+		},
+		{
+			name:         "with an Azure dedicated host",
+			platform:     osconfigv1.AzurePlatformType,
+			host:         azureHost,
+			providerSpec: `{"vmSize": "Standard_D4s_v3"}`,
+		},
+		{
+			name:         "with an invalid Azure host group and spot VMs",
+			platform:     osconfigv1.AzurePlatformType,
+			group:        azureHost,
+			providerSpec: `{"spotVMOptions": {}}`,
+			expectedErrs: []string{
+				`metadata.annotations[machine.openshift.io/host-group]: Invalid value: "` + azureHost + `": must be the resource ID of an Azure host group, e.g. /subscriptions/<subscription>/resourceGroups/<group>/providers/Microsoft.Compute/hostGroups/<host-group>`,
+				"providerSpec.spotVMOptions: Forbidden: spot VMs cannot run on a dedicated host or a host group",
+			},
+		},
+		{
+			name:     "with a host group on GCP",
+			platform: osconfigv1.GCPPlatformType,
+			group:    "sole-tenant-group",
+			expectedWarnings: []string{
+				"dedicated hosts are not supported on platform GCP, the machine.openshift.io/dedicated-host and machine.openshift.io/host-group annotations are ignored",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			machineAnnotations := map[string]string{}
+			if tc.host != "" {
+				machineAnnotations[annotations.DedicatedHostAnnotation] = tc.host
+			}
+			if tc.group != "" {
+				machineAnnotations[annotations.HostGroupAnnotation] = tc.group
+			}
+			providerSpec := &machinev1beta1.ProviderSpec{Value: &kruntime.RawExtension{Raw: []byte(tc.providerSpec)}}
+
+			warnings, errs := validateHostAffinity(tc.platform, machineAnnotations, providerSpec, field.NewPath("metadata", "annotations"))
+			g.Expect(warnings).To(Equal(tc.expectedWarnings))
+			if tc.expectedErrs == nil {
+				g.Expect(errs).To(BeEmpty())
+				return
+			}
+			g.Expect(errs).To(HaveLen(len(tc.expectedErrs)))
+			for i, err := range errs {
+				g.Expect(err.Error()).To(Equal(tc.expectedErrs[i]))
+			}
+		})
+	}
+}
+
+func TestValidateHostGroups(t *testing.T) {
+	const groupA = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/hostGroups/hg-a"
+
+	testCases := []struct {
+		name                string
+		hostGroups          string
+		templateAnnotations map[string]string
+		expectedErrs        []string
+	}{
+		{
+			name:       "with host groups",
+			hostGroups: groupA + ", /subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/hostGroups/hg-b",
+		},
+		{
+			name:       "with duplicated and invalid host groups",
+			hostGroups: groupA + "," + groupA + ",hg-c",
+			expectedErrs: []string{
+				`metadata.annotations[machine.openshift.io/host-groups]: Duplicate value: "` + groupA + `"`,
+				`metadata.annotations[machine.openshift.io/host-groups]: Invalid value: "hg-c": must be the resource ID of an Azure host group, e.g. /subscriptions/<subscription>/resourceGroups/<group>/providers/Microsoft.Compute/hostGroups/<host-group>`,
+			},
+		},
+		{
+			name:                "with a host group in the machine template",
+			hostGroups:          groupA,
+			templateAnnotations: map[string]string{annotations.HostGroupAnnotation: groupA},
+			expectedErrs: []string{
+				"metadata.annotations[machine.openshift.io/host-groups]: Forbidden: cannot be set along with the machine.openshift.io/dedicated-host or machine.openshift.io/host-group annotations of the machine template",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1beta1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{annotations.HostGroupsAnnotation: tc.hostGroups}},
+			}
+			ms.Spec.Template.Annotations = tc.templateAnnotations
+			ms.Spec.Template.Spec.ProviderSpec.Value = &kruntime.RawExtension{Raw: []byte(`{"vmSize": "Standard_D4s_v3"}`)}
+
+			warnings, errs := validateHostGroups(osconfigv1.AzurePlatformType, ms)
+			g.Expect(warnings).To(BeEmpty())
+			g.Expect(errs).To(HaveLen(len(tc.expectedErrs)))
+			for i, err := range errs {
+				g.Expect(err.Error()).To(Equal(tc.expectedErrs[i]))
+			}
+		})
+	}
+}
+
+func TestRejectHostAffinity(t *testing.T) {
+	const group = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/hostGroups/hg"
+
+	testCases := []struct {
+		name           string
+		annotations    map[string]string
+		oldAnnotations map[string]string
+		expectedErrs   []string
+	}{
+		{
+			name:        "without host affinity",
+			annotations: map[string]string{"foo": "bar"},
+		},
+		{
+			name: "with a dedicated host and host groups",
+			annotations: map[string]string{
+				annotations.DedicatedHostAnnotation: "h-0123456789abcdef0",
+				annotations.HostGroupsAnnotation:    group,
+			},
+			expectedErrs: []string{
+				"metadata.annotations[machine.openshift.io/dedicated-host]: Forbidden: dedicated hosts are not supported by the machine controller of this cluster",
+				"metadata.annotations[machine.openshift.io/host-groups]: Forbidden: dedicated hosts are not supported by the machine controller of this cluster",
+			},
+		},
+		{
+			name:           "with an unchanged host group",
+			annotations:    map[string]string{annotations.HostGroupAnnotation: group},
+			oldAnnotations: map[string]string{annotations.HostGroupAnnotation: group},
+		},
+		{
+			name:           "with a changed host group",
+			annotations:    map[string]string{annotations.HostGroupAnnotation: group + "-b"},
+			oldAnnotations: map[string]string{annotations.HostGroupAnnotation: group},
+			expectedErrs: []string{
+				"metadata.annotations[machine.openshift.io/host-group]: Forbidden: dedicated hosts are not supported by the machine controller of this cluster",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			errs := rejectHostAffinity(tc.annotations, tc.oldAnnotations, field.NewPath("metadata", "annotations"))
+			g.Expect(errs).To(HaveLen(len(tc.expectedErrs)))
+			for i, err := range errs {
+				g.Expect(err.Error()).To(Equal(tc.expectedErrs[i]))
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
+)
+
+// providerSpecInstanceTypePaths are the paths of the instance type in the providerSpecs of the platforms.
+var providerSpecInstanceTypePaths = map[osconfigv1.PlatformType][]string{
+	osconfigv1.AWSPlatformType:   {"instanceType"},
+	osconfigv1.AzurePlatformType: {"vmSize"},
+	osconfigv1.GCPPlatformType:   {"machineType"},
+}
+
+// inPlaceResizeWarnings returns an admission warning when the instance type of an existing Machine changes,
+// telling whether the instance is resized in place or the change only applies to the instances created afterwards.
+func inPlaceResizeWarnings(platform osconfigv1.PlatformType, m, oldM *machinev1beta1.Machine) []string {
+	fieldPath, oldInstanceType, instanceType, changed := instanceTypeChange(platform, m, oldM)
+	if !changed {
+		return nil
+	}
+
+	if annotations.IsInPlaceResizeAllowed(m) {
+		return []string{fmt.Sprintf("%s: changed from %s to %s, the node will be drained and the instance powered off to be resized",
+			fieldPath, oldInstanceType, instanceType)}
+	}
+	return []string{fmt.Sprintf("%s: changed from %s to %s, the change does not apply to the existing instance unless the %s annotation is set to \"true\"",
+		fieldPath, oldInstanceType, instanceType, annotations.AllowInPlaceResizeAnnotation)}
+}
+
+// instanceTypeChange returns the path of the instance type in the providerSpec, with the old and the new instance
+// types, when the instance type of an existing Machine changes.
+func instanceTypeChange(platform osconfigv1.PlatformType, m, oldM *machinev1beta1.Machine) (*field.Path, string, string, bool) {
+	path, ok := providerSpecInstanceTypePaths[platform]
+	if !ok || oldM == nil {
+		return nil, "", "", false
+	}
+
+	instanceType := providerSpecString(&m.Spec.ProviderSpec, path)
+	oldInstanceType := providerSpecString(&oldM.Spec.ProviderSpec, path)
+	if instanceType == oldInstanceType || instanceType == "" || oldInstanceType == "" {
+		return nil, "", "", false
+	}
+	return field.NewPath("providerSpec", path...), oldInstanceType, instanceType, true
+}
+
+// providerSpecString returns the string field of the providerSpec at the path, or an empty string.
+func providerSpecString(providerSpec *machinev1beta1.ProviderSpec, path []string) string {
+	if providerSpec.Value == nil || len(providerSpec.Value.Raw) == 0 {
+		return ""
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(providerSpec.Value.Raw, &values); err != nil {
+		// Invalid providerSpecs are reported by the platform validation.
+		return ""
+	}
+	value, _, _ := unstructured.NestedString(values, path...)
+	return value
+}
@@ -0,0 +1,83 @@
+package webhooks
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	osconfigv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestInPlaceResizeWarnings(t *testing.T) {
+	machineWithProviderSpec := func(providerSpec string, annotations map[string]string) *machinev1beta1.Machine {
+		return &machinev1beta1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+			Spec: machinev1beta1.MachineSpec{
+				ProviderSpec: machinev1beta1.ProviderSpec{
+					Value: &kruntime.RawExtension{Raw: []byte(providerSpec)},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name             string
+		platform         osconfigv1.PlatformType
+		providerSpec     string
+		oldProviderSpec  string
+		annotations      map[string]string
+		expectedWarnings []string
+	}{
+		{
+			name:         "with a new machine",
+			platform:     osconfigv1.AWSPlatformType,
+			providerSpec: `{"instanceType": "m6i.xlarge"}`,
+		},
+		{
+			name:            "with an unchanged instance type",
+			platform:        osconfigv1.AWSPlatformType,
+			providerSpec:    `{"instanceType": "m6i.xlarge"}`,
+			oldProviderSpec: `{"instanceType": "m6i.xlarge"}`,
+		},
+		{
+			name:            "with a changed instance type",
+			platform:        osconfigv1.AWSPlatformType,
+			providerSpec:    `{"instanceType": "m6i.2xlarge"}`,
+			oldProviderSpec: `{"instanceType": "m6i.xlarge"}`,
+			expectedWarnings: []string{
+				"providerSpec.instanceType: changed from m6i.xlarge to m6i.2xlarge, the change does not apply to the existing instance unless the machine.openshift.io/allow-in-place-resize annotation is set to \"true\"",
+			},
+		},
+		{
+			name:            "with a changed VM size allowed to be resized in place",
+			platform:        osconfigv1.AzurePlatformType,
+			providerSpec:    `{"vmSize": "Standard_D8s_v3"}`,
+			oldProviderSpec: `{"vmSize": "Standard_D4s_v3"}`,
+			annotations:     map[string]string{"machine.openshift.io/allow-in-place-resize": "true"},
+			expectedWarnings: []string{
+				"providerSpec.vmSize: changed from Standard_D4s_v3 to Standard_D8s_v3, the node will be drained and the instance powered off to be resized",
+			},
+		},
+		{
+			name:            "with a platform without instance types",
+			platform:        osconfigv1.VSpherePlatformType,
+			providerSpec:    `{"numCPUs": 8}`,
+			oldProviderSpec: `{"numCPUs": 4}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var oldM *machinev1beta1.Machine
+			if tc.oldProviderSpec != "" {
+				oldM = machineWithProviderSpec(tc.oldProviderSpec, nil)
+			}
+			warnings := inPlaceResizeWarnings(tc.platform, machineWithProviderSpec(tc.providerSpec, tc.annotations), oldM)
+			g.Expect(warnings).To(Equal(tc.expectedWarnings))
+		})
+	}
+}
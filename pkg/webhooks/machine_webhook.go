@@ -10,8 +10,10 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/google/uuid"
+	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kruntime "k8s.io/apimachinery/pkg/runtime"
@@ -21,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
 	"k8s.io/utils/strings/slices"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -31,6 +34,9 @@ import (
 	machinev1 "github.com/openshift/api/machine/v1"
 	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
 	osclientset "github.com/openshift/client-go/config/clientset/versioned"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-operator/pkg/util"
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
 	"github.com/openshift/machine-api-operator/pkg/util/lifecyclehooks"
 )
 
@@ -167,49 +173,105 @@ const (
 	powerVSSystemTypeE980           = "e980"
 )
 
+// MachineDeletionProtectedAnnotation protects a Machine from deletion when set to "true".
+// Requests to delete a protected Machine are denied, unless they come from one of the
+// users allowed to delete them, e.g. when a MachineSet is scaled down.
+const MachineDeletionProtectedAnnotation = "machine.openshift.io/deletion-protected"
+
+// DefaultDeletionProtectionAllowedUsers are the users allowed to delete protected Machines by default,
+// the Machine API controllers and the garbage collector.
+var DefaultDeletionProtectionAllowedUsers = []string{
+	"system:serviceaccount:openshift-machine-api:machine-api-controllers",
+	"system:serviceaccount:kube-system:generic-garbage-collector",
+}
+
+const (
+	machineRoleLabel  = "machine.openshift.io/cluster-api-machine-role"
+	machineMasterRole = "master"
+
+	// defaultMinControlPlaneMachines is the number of running control plane Machines which must remain
+	// after deleting a control plane Machine.
+	defaultMinControlPlaneMachines = 1
+)
+
 // GCP Confidential VM supports Compute Engine machine types in the following series:
 // reference: https://cloud.google.com/compute/confidential-vm/docs/os-and-machine-type#machine-type
 var gcpConfidentialComputeSupportedMachineSeries = []string{"n2d", "c2d"}
 
-func secretExists(c client.Client, name, namespace string) (bool, error) {
+func secretExists(config *admissionConfig, name, namespace string) (bool, error) {
 	key := client.ObjectKey{
 		Name:      name,
 		Namespace: namespace,
 	}
-	obj := &corev1.Secret{}
+	return util.ObjectExists(context.Background(), config.client, key, &corev1.Secret{}, config.fullSecrets)
+}
 
-	if err := c.Get(context.Background(), key, obj); err != nil {
-		if apierrors.IsNotFound(err) {
-			return false, nil
-		}
-		return false, err
-	}
-	return true, nil
+// RejectMissingSecrets makes the validation reject providerSpecs referencing a user data or credentials secret
+// which does not exist, instead of warning about them.
+func (a *admissionHandler) RejectMissingSecrets() {
+	a.rejectMissingSecrets = true
+}
+
+// GetFullSecrets makes the validation get the full secrets referenced by the providerSpecs, rather than only their
+// metadata, e.g. when a provider reads the same secrets through the cache of the client, so that they are not
+// cached twice.
+func (a *admissionHandler) GetFullSecrets() {
+	a.fullSecrets = true
+}
+
+// DefaultAWSMetadataServiceAuthentication makes the defaulting set the metadata service authentication of AWS providerSpecs
+// which do not set it, e.g. to Required to enforce IMDSv2 on new Machines.
+func (a *admissionHandler) DefaultAWSMetadataServiceAuthentication(authentication machinev1beta1.MetadataServiceAuthentication) {
+	a.awsMetadataServiceAuthentication = authentication
+}
+
+// forProviderSpecUpdate returns the config validating an update of the providerSpec, or its creation when the old
+// providerSpec is nil. Missing secrets are only rejected, and vSphere providerSpecs only validated against vCenter,
+// when the providerSpec is created or changed, so that the resources whose secrets or vCenter objects were removed
+// can still be updated, e.g. by the controllers, without a round trip to vCenter for each update.
+func (c *admissionConfig) forProviderSpecUpdate(providerSpec, oldProviderSpec *machinev1beta1.ProviderSpec) *admissionConfig {
+	if (!c.rejectMissingSecrets && c.vsphereDeepValidator == nil) || oldProviderSpec == nil || !equality.Semantic.DeepEqual(providerSpec, oldProviderSpec) {
+		return c
+	}
+	config := *c
+	config.rejectMissingSecrets = false
+	config.vsphereDeepValidator = nil
+	return &config
+}
+
+func credentialsSecretExists(config *admissionConfig, name, namespace string) ([]string, []error) {
+	return secretReferenceExists(config, "credentialsSecret", "CredentialsSecret", name, namespace)
+}
+
+func userDataSecretExists(config *admissionConfig, name, namespace string) ([]string, []error) {
+	return secretReferenceExists(config, "userDataSecret", "UserDataSecret", name, namespace)
 }
 
-func credentialsSecretExists(c client.Client, name, namespace string) []string {
-	secretExists, err := secretExists(c, name, namespace)
+// secretReferenceExists checks that the secret referenced by the providerSpec exists, as a machine referencing
+// a missing secret would otherwise be stuck in Provisioning. A missing secret is reported as a warning, or as an
+// error when missing secrets are rejected. Failures to get the secret are always reported as warnings.
+func secretReferenceExists(config *admissionConfig, fieldName, kind, name, namespace string) ([]string, []error) {
+	fldPath := field.NewPath("providerSpec", fieldName)
+	secretExists, err := secretExists(config, name, namespace)
 	if err != nil {
 		return []string{
 			field.Invalid(
-				field.NewPath("providerSpec", "credentialsSecret"),
+				fldPath,
 				name,
-				fmt.Sprintf("failed to get credentialsSecret: %v", err),
+				fmt.Sprintf("failed to get %s: %v", fieldName, err),
 			).Error(),
-		}
+		}, nil
 	}
 
 	if !secretExists {
-		return []string{
-			field.Invalid(
-				field.NewPath("providerSpec", "credentialsSecret"),
-				name,
-				"not found. Expected CredentialsSecret to exist",
-			).Error(),
+		notFound := field.Invalid(fldPath, name, fmt.Sprintf("not found. Expected %s to exist", kind))
+		if config.rejectMissingSecrets {
+			return nil, []error{notFound}
 		}
+		return []string{notFound.Error()}, nil
 	}
 
-	return []string{}
+	return []string{}, nil
 }
 
 func getInfra() (*osconfigv1.Infrastructure, error) {
@@ -252,6 +314,24 @@ type admissionConfig struct {
 	platformStatus  *osconfigv1.PlatformStatus
 	dnsDisconnected bool
 	client          client.Client
+
+	// rejectMissingSecrets rejects providerSpecs referencing secrets which do not exist, instead of warning about them.
+	rejectMissingSecrets bool
+
+	// fullSecrets gets the full secrets referenced by the providerSpecs, rather than only their metadata.
+	fullSecrets bool
+
+	// awsMetadataServiceAuthentication is the metadata service authentication defaulted in AWS providerSpecs which do not set it.
+	awsMetadataServiceAuthentication machinev1beta1.MetadataServiceAuthentication
+
+	// vsphereDeepValidator validates vSphere providerSpecs against vCenter when deep validation is enabled.
+	vsphereDeepValidator *vsphereDeepValidator
+
+	// dryRunEstimator estimates the capacity for the Machines created in dry run requests when the estimates are enabled.
+	dryRunEstimator dryRunEstimator
+
+	// allowHostAffinity admits the annotations pinning Machines to dedicated hosts or host groups, instead of rejecting them.
+	allowHostAffinity bool
 }
 
 type admissionHandler struct {
@@ -271,6 +351,12 @@ func (a *admissionHandler) InjectDecoder(d *admission.Decoder) error {
 // https://godoc.org/github.com/kubernetes-sigs/controller-runtime/pkg/webhook/admission#Handler
 type machineValidatorHandler struct {
 	*admissionHandler
+
+	// minControlPlaneMachines is the number of running control plane Machines which must remain after a deletion.
+	minControlPlaneMachines int
+
+	// deletionProtectionAllowedUsers are the users allowed to delete the Machines protected from deletion.
+	deletionProtectionAllowedUsers []string
 }
 
 // machineDefaulterHandler defaults Machine API resources.
@@ -307,9 +393,23 @@ func createMachineValidator(infra *osconfigv1.Infrastructure, client client.Clie
 			admissionConfig:   admissionConfig,
 			webhookOperations: getMachineValidatorOperation(infra.Status.PlatformStatus.Type),
 		},
+		minControlPlaneMachines:        defaultMinControlPlaneMachines,
+		deletionProtectionAllowedUsers: DefaultDeletionProtectionAllowedUsers,
 	}
 }
 
+// SetMinControlPlaneMachines sets the number of running control plane Machines which must remain
+// after deleting a control plane Machine.
+func (h *machineValidatorHandler) SetMinControlPlaneMachines(n int) {
+	h.minControlPlaneMachines = n
+}
+
+// SetDeletionProtectionAllowedUsers sets the users allowed to delete the Machines protected by the
+// MachineDeletionProtectedAnnotation, replacing DefaultDeletionProtectionAllowedUsers.
+func (h *machineValidatorHandler) SetDeletionProtectionAllowedUsers(users []string) {
+	h.deletionProtectionAllowedUsers = users
+}
+
 func getMachineValidatorOperation(platform osconfigv1.PlatformType) machineAdmissionFn {
 	switch platform {
 	case osconfigv1.AWSPlatformType:
@@ -392,11 +492,42 @@ func (h *machineValidatorHandler) validateMachine(m, oldM *machinev1beta1.Machin
 	}
 
 	errs := validateMachineLifecycleHooks(m, oldM)
+	errs = append(errs, validateNodeConfigAnnotations(m.Annotations, field.NewPath("metadata", "annotations"))...)
+	errs = append(errs, validateNodeStartupTimeoutAnnotation(m.Annotations, field.NewPath("metadata", "annotations"))...)
+
+	// External machines represent pre-existing nodes, they have no providerSpec to validate.
+	if annotations.IsExternalMachine(m) {
+		errs = append(errs, validateExternalMachine(m)...)
+		if len(errs) > 0 {
+			return false, nil, utilerrors.NewAggregate(errs)
+		}
+		return true, nil, nil
+	}
 
-	ok, warnings, err := h.webhookOperations(m, h.admissionConfig)
+	var oldProviderSpec *machinev1beta1.ProviderSpec
+	if oldM != nil {
+		oldProviderSpec = &oldM.Spec.ProviderSpec
+	}
+	ok, warnings, err := h.webhookOperations(m, h.admissionConfig.forProviderSpecUpdate(&m.Spec.ProviderSpec, oldProviderSpec))
 	if !ok {
 		errs = append(errs, err.Errors()...)
 	}
+	if h.platformStatus != nil {
+		warnings = append(warnings, deprecatedProviderSpecWarnings(h.platformStatus.Type, m, oldM)...)
+		warnings = append(warnings, inPlaceResizeWarnings(h.platformStatus.Type, m, oldM)...)
+		if h.allowHostAffinity {
+			hostWarnings, hostErrs := validateHostAffinity(h.platformStatus.Type, m.Annotations, &m.Spec.ProviderSpec, field.NewPath("metadata", "annotations"))
+			warnings = append(warnings, hostWarnings...)
+			errs = append(errs, hostErrs...)
+		}
+	}
+	if !h.allowHostAffinity {
+		var oldAnnotations map[string]string
+		if oldM != nil {
+			oldAnnotations = oldM.Annotations
+		}
+		errs = append(errs, rejectHostAffinity(m.Annotations, oldAnnotations, field.NewPath("metadata", "annotations"))...)
+	}
 
 	if len(errs) > 0 {
 		return false, warnings, utilerrors.NewAggregate(errs)
@@ -404,8 +535,100 @@ func (h *machineValidatorHandler) validateMachine(m, oldM *machinev1beta1.Machin
 	return true, warnings, nil
 }
 
+// validateNodeStartupTimeoutAnnotation checks the node startup timeout of the Machines, which is otherwise only
+// reported in the logs of the Machine controller.
+func validateNodeStartupTimeoutAnnotation(objectAnnotations map[string]string, path *field.Path) []error {
+	value, ok := objectAnnotations[machinecontroller.NodeStartupTimeoutAnnotation]
+	if !ok {
+		return nil
+	}
+	if err := machinecontroller.ValidateNodeStartupTimeout(value); err != nil {
+		return []error{field.Invalid(path.Key(machinecontroller.NodeStartupTimeoutAnnotation), value, err.Error())}
+	}
+	return nil
+}
+
+// ValidateMachine validates a Machine which is about to be created, in the same way as the webhook would.
+// It returns the warnings of the validation, along with an error when the Machine is invalid.
+func (h *machineValidatorHandler) ValidateMachine(m *machinev1beta1.Machine) ([]string, error) {
+	ok, warnings, errs := h.validateMachine(m, nil)
+	if !ok {
+		return warnings, errs
+	}
+	return warnings, nil
+}
+
+// validateMachineDeletion denies the deletion of Machines protected by the
+// MachineDeletionProtectedAnnotation, unless it is requested by an allowed user.
+func (h *machineValidatorHandler) validateMachineDeletion(m *machinev1beta1.Machine, username string) error {
+	if m.Annotations[MachineDeletionProtectedAnnotation] != "true" {
+		return nil
+	}
+	if slices.Contains(h.deletionProtectionAllowedUsers, username) {
+		return nil
+	}
+	return field.Forbidden(
+		field.NewPath("metadata", "annotations", MachineDeletionProtectedAnnotation),
+		fmt.Sprintf("machine %s is protected from deletion, remove the annotation to delete it", m.GetName()),
+	)
+}
+
+// validateControlPlaneMachineDeletion denies the deletion of a running control plane Machine
+// when it would leave fewer than minControlPlaneMachines running control plane Machines.
+// It returns the reason of the denial, or an error when the control plane Machines can not be listed.
+func (h *machineValidatorHandler) validateControlPlaneMachineDeletion(ctx context.Context, m *machinev1beta1.Machine) (*field.Error, error) {
+	if m.Labels[machineRoleLabel] != machineMasterRole || !isRunningMachine(m) {
+		return nil, nil
+	}
+
+	machines := &machinev1beta1.MachineList{}
+	if err := h.client.List(ctx, machines, client.InNamespace(m.Namespace), client.MatchingLabels{machineRoleLabel: machineMasterRole}); err != nil {
+		return nil, fmt.Errorf("unable to list control plane machines: %w", err)
+	}
+
+	remaining := 0
+	for i := range machines.Items {
+		if machines.Items[i].Name != m.Name && isRunningMachine(&machines.Items[i]) {
+			remaining++
+		}
+	}
+	if remaining < h.minControlPlaneMachines {
+		return field.Forbidden(
+			field.NewPath("metadata", "labels", machineRoleLabel),
+			fmt.Sprintf("deleting control plane machine %s would leave %d running control plane machines, at least %d are required", m.GetName(), remaining, h.minControlPlaneMachines),
+		), nil
+	}
+	return nil, nil
+}
+
+// isRunningMachine returns true if the Machine is running and not being deleted.
+func isRunningMachine(m *machinev1beta1.Machine) bool {
+	return m.DeletionTimestamp == nil && m.Status.Phase != nil && *m.Status.Phase == machinev1beta1.PhaseRunning
+}
+
 // Handle handles HTTP requests for admission webhook servers.
 func (h *machineValidatorHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation == admissionv1.Delete {
+		m := &machinev1beta1.Machine{}
+		if err := h.decoder.DecodeRaw(req.OldObject, m); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+
+		klog.V(3).Infof("Validate webhook called for Machine deletion: %s", m.GetName())
+
+		if err := h.validateMachineDeletion(m, req.UserInfo.Username); err != nil {
+			return denied(err, nil)
+		}
+		denial, err := h.validateControlPlaneMachineDeletion(ctx, m)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if denial != nil {
+			return denied(denial, nil)
+		}
+		return admission.Allowed("Machine deletion allowed")
+	}
+
 	m := &machinev1beta1.Machine{}
 
 	if err := h.decoder.Decode(req, m); err != nil {
@@ -427,10 +650,14 @@ func (h *machineValidatorHandler) Handle(ctx context.Context, req admission.Requ
 
 	ok, warnings, errs := h.validateMachine(m, oldM)
 	if !ok {
-		return admission.Denied(errs.Error()).WithWarnings(warnings...)
+		return denied(errs, warnings)
 	}
 
-	return admission.Allowed("Machine valid").WithWarnings(warnings...)
+	if req.Operation == admissionv1.Create && req.DryRun != nil && *req.DryRun && h.dryRunEstimator != nil {
+		warnings = append(warnings, h.estimateDryRun(ctx, m)...)
+	}
+
+	return audited(admission.Allowed("Machine valid").WithWarnings(warnings...), auditInstanceTypeChange(h.platformStatus, m, oldM))
 }
 
 // Handle handles HTTP requests for admission webhook servers.
@@ -454,16 +681,22 @@ func (h *machineDefaulterHandler) Handle(ctx context.Context, req admission.Requ
 		m.Labels[machinev1beta1.MachineClusterIDLabel] = h.clusterID
 	}
 
-	ok, warnings, errs := h.webhookOperations(m, h.admissionConfig)
-	if !ok {
-		return admission.Denied(errs.Error()).WithWarnings(warnings...)
+	var warnings []string
+	// External machines represent pre-existing nodes, they have no providerSpec to default.
+	if !annotations.IsExternalMachine(m) {
+		var ok bool
+		var errs utilerrors.Aggregate
+		ok, warnings, errs = h.webhookOperations(m, h.admissionConfig)
+		if !ok {
+			return denied(errs, warnings)
+		}
 	}
 
 	marshaledMachine, err := json.Marshal(m)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err).WithWarnings(warnings...)
 	}
-	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledMachine).WithWarnings(warnings...)
+	return audited(admission.PatchResponseFromRaw(req.Object.Raw, marshaledMachine).WithWarnings(warnings...), "")
 }
 
 type awsDefaulter struct {
@@ -502,6 +735,10 @@ func (a awsDefaulter) defaultAWS(m *machinev1beta1.Machine, config *admissionCon
 		providerSpec.CredentialsSecret = &corev1.LocalObjectReference{Name: defaultAWSCredentialsSecret}
 	}
 
+	if providerSpec.MetadataServiceOptions.Authentication == "" {
+		providerSpec.MetadataServiceOptions.Authentication = config.awsMetadataServiceAuthentication
+	}
+
 	rawBytes, err := json.Marshal(providerSpec)
 	if err != nil {
 		errs = append(errs, err)
@@ -612,6 +849,10 @@ func validateAWS(m *machinev1beta1.Machine, config *admissionConfig) (bool, []st
 				"expected providerSpec.userDataSecret to be populated",
 			),
 		)
+	} else {
+		secretWarnings, secretErrs := userDataSecretExists(config, providerSpec.UserDataSecret.Name, m.GetNamespace())
+		warnings = append(warnings, secretWarnings...)
+		errs = append(errs, secretErrs...)
 	}
 
 	if providerSpec.CredentialsSecret == nil {
@@ -623,7 +864,9 @@ func validateAWS(m *machinev1beta1.Machine, config *admissionConfig) (bool, []st
 			),
 		)
 	} else {
-		warnings = append(warnings, credentialsSecretExists(config.client, providerSpec.CredentialsSecret.Name, m.GetNamespace())...)
+		secretWarnings, secretErrs := credentialsSecretExists(config, providerSpec.CredentialsSecret.Name, m.GetNamespace())
+		warnings = append(warnings, secretWarnings...)
+		errs = append(errs, secretErrs...)
 	}
 
 	if providerSpec.Subnet.ARN == nil && providerSpec.Subnet.ID == nil && providerSpec.Subnet.Filters == nil {
@@ -654,6 +897,16 @@ func validateAWS(m *machinev1beta1.Machine, config *admissionConfig) (bool, []st
 		)
 	}
 
+	if providerSpec.Placement.Tenancy == machinev1beta1.HostTenancy && providerSpec.SpotMarketOptions != nil {
+		errs = append(
+			errs,
+			field.Forbidden(
+				field.NewPath("providerSpec", "spotMarketOptions"),
+				fmt.Sprintf("spot instances can not be launched on dedicated hosts, spotMarketOptions can not be used with tenancy %s", machinev1beta1.HostTenancy),
+			),
+		)
+	}
+
 	duplicatedTags := getDuplicatedTags(providerSpec.Tags)
 	if len(duplicatedTags) > 0 {
 		warnings = append(warnings, fmt.Sprintf("providerSpec.tags: duplicated tag names (%s): only the first value will be used.", strings.Join(duplicatedTags, ",")))
@@ -808,6 +1061,15 @@ func validateAzure(m *machinev1beta1.Machine, config *admissionConfig) (bool, []
 		errs = append(errs, field.Required(field.NewPath("providerSpec", "userDataSecret"), "userDataSecret must be provided"))
 	} else if providerSpec.UserDataSecret.Name == "" {
 		errs = append(errs, field.Required(field.NewPath("providerSpec", "userDataSecret", "name"), "name must be provided"))
+	} else {
+		// The user data secret defaults to the namespace of the Machine.
+		namespace := providerSpec.UserDataSecret.Namespace
+		if namespace == "" {
+			namespace = m.GetNamespace()
+		}
+		secretWarnings, secretErrs := userDataSecretExists(config, providerSpec.UserDataSecret.Name, namespace)
+		warnings = append(warnings, secretWarnings...)
+		errs = append(errs, secretErrs...)
 	}
 
 	if providerSpec.CredentialsSecret == nil {
@@ -820,7 +1082,9 @@ func validateAzure(m *machinev1beta1.Machine, config *admissionConfig) (bool, []
 			errs = append(errs, field.Required(field.NewPath("providerSpec", "credentialsSecret", "name"), "name must be provided"))
 		}
 		if providerSpec.CredentialsSecret.Name != "" && providerSpec.CredentialsSecret.Namespace != "" {
-			warnings = append(warnings, credentialsSecretExists(config.client, providerSpec.CredentialsSecret.Name, providerSpec.CredentialsSecret.Namespace)...)
+			secretWarnings, secretErrs := credentialsSecretExists(config, providerSpec.CredentialsSecret.Name, providerSpec.CredentialsSecret.Namespace)
+			warnings = append(warnings, secretWarnings...)
+			errs = append(errs, secretErrs...)
 		}
 	}
 
@@ -855,6 +1119,8 @@ func validateAzure(m *machinev1beta1.Machine, config *admissionConfig) (bool, []
 
 	errs = append(errs, validateAzureDataDisks(m.Name, providerSpec, field.NewPath("providerSpec", "dataDisks"))...)
 
+	errs = append(errs, validateAzureVMSizeCapabilities(providerSpec)...)
+
 	errs = append(errs, validateAzureDiagnostics(providerSpec.Diagnostics, field.NewPath("providerSpec", "diagnostics"))...)
 
 	if isAzureGovCloud(config.platformStatus) && providerSpec.SpotVMOptions != nil {
@@ -1070,6 +1336,10 @@ func validateGCP(m *machinev1beta1.Machine, config *admissionConfig) (bool, []st
 	} else {
 		if providerSpec.UserDataSecret.Name == "" {
 			errs = append(errs, field.Required(field.NewPath("providerSpec", "userDataSecret", "name"), "name must be provided"))
+		} else {
+			secretWarnings, secretErrs := userDataSecretExists(config, providerSpec.UserDataSecret.Name, m.GetNamespace())
+			warnings = append(warnings, secretWarnings...)
+			errs = append(errs, secretErrs...)
 		}
 	}
 
@@ -1079,7 +1349,9 @@ func validateGCP(m *machinev1beta1.Machine, config *admissionConfig) (bool, []st
 		if providerSpec.CredentialsSecret.Name == "" {
 			errs = append(errs, field.Required(field.NewPath("providerSpec", "credentialsSecret", "name"), "name must be provided"))
 		} else {
-			warnings = append(warnings, credentialsSecretExists(config.client, providerSpec.CredentialsSecret.Name, m.GetNamespace())...)
+			secretWarnings, secretErrs := credentialsSecretExists(config, providerSpec.CredentialsSecret.Name, m.GetNamespace())
+			warnings = append(warnings, secretWarnings...)
+			errs = append(errs, secretErrs...)
 		}
 	}
 
@@ -1109,10 +1381,19 @@ func validateShieldedInstanceConfig(providerSpec *machinev1beta1.GCPMachineProvi
 				providerSpec.ShieldedInstanceConfig.VirtualizedTrustedPlatformModule,
 				fmt.Sprintf("virtualizedTrustedPlatformModule must be either %s or %s.", machinev1beta1.VirtualizedTrustedPlatformModulePolicyEnabled, machinev1beta1.VirtualizedTrustedPlatformModulePolicyDisabled)))
 		}
-		if providerSpec.ShieldedInstanceConfig.VirtualizedTrustedPlatformModule == machinev1beta1.VirtualizedTrustedPlatformModulePolicyDisabled && providerSpec.ShieldedInstanceConfig.IntegrityMonitoring != machinev1beta1.IntegrityMonitoringPolicyDisabled {
-			errs = append(errs, field.Invalid(field.NewPath("providerSpec", "shieldedInstanceConfig", "virtualizedTrustedPlatformModule"),
-				providerSpec.ShieldedInstanceConfig.VirtualizedTrustedPlatformModule,
-				fmt.Sprintf("integrityMonitoring requires virtualizedTrustedPlatformModule %s.", machinev1beta1.VirtualizedTrustedPlatformModulePolicyEnabled)))
+		if providerSpec.ShieldedInstanceConfig.VirtualizedTrustedPlatformModule == machinev1beta1.VirtualizedTrustedPlatformModulePolicyDisabled {
+			switch providerSpec.ShieldedInstanceConfig.IntegrityMonitoring {
+			case machinev1beta1.IntegrityMonitoringPolicyDisabled:
+			case "":
+				errs = append(errs, field.Invalid(field.NewPath("providerSpec", "shieldedInstanceConfig", "virtualizedTrustedPlatformModule"),
+					providerSpec.ShieldedInstanceConfig.VirtualizedTrustedPlatformModule,
+					fmt.Sprintf("integrityMonitoring defaults to %s and requires virtualizedTrustedPlatformModule %s, set integrityMonitoring to %s to disable virtualizedTrustedPlatformModule.",
+						machinev1beta1.IntegrityMonitoringPolicyEnabled, machinev1beta1.VirtualizedTrustedPlatformModulePolicyEnabled, machinev1beta1.IntegrityMonitoringPolicyDisabled)))
+			default:
+				errs = append(errs, field.Invalid(field.NewPath("providerSpec", "shieldedInstanceConfig", "virtualizedTrustedPlatformModule"),
+					providerSpec.ShieldedInstanceConfig.VirtualizedTrustedPlatformModule,
+					fmt.Sprintf("integrityMonitoring requires virtualizedTrustedPlatformModule %s.", machinev1beta1.VirtualizedTrustedPlatformModulePolicyEnabled)))
+			}
 		}
 	}
 	return errs
@@ -1121,20 +1402,30 @@ func validateShieldedInstanceConfig(providerSpec *machinev1beta1.GCPMachineProvi
 func validateGCPConfidentialComputing(providerSpec *machinev1beta1.GCPMachineProviderSpec) (errs []error) {
 	switch providerSpec.ConfidentialCompute {
 	case machinev1beta1.ConfidentialComputePolicyEnabled:
-		// Check on host maintenance
-		if providerSpec.OnHostMaintenance != machinev1beta1.TerminateHostMaintenanceType {
+		// Confidential VMs can not be live migrated, onHostMaintenance defaults to Migrate on GCP
+		switch providerSpec.OnHostMaintenance {
+		case machinev1beta1.TerminateHostMaintenanceType:
+		case "":
+			errs = append(errs, field.Required(field.NewPath("providerSpec", "onHostMaintenance"),
+				fmt.Sprintf("ConfidentialCompute require OnHostMaintenance to be set to %s, it defaults to %s which is not supported by confidential VMs", machinev1beta1.TerminateHostMaintenanceType, machinev1beta1.MigrateHostMaintenanceType)))
+		default:
 			errs = append(errs, field.Invalid(field.NewPath("providerSpec", "onHostMaintenance"),
 				providerSpec.OnHostMaintenance,
 				fmt.Sprintf("ConfidentialCompute require OnHostMaintenance to be set to %s, the current value is: %s", machinev1beta1.TerminateHostMaintenanceType, providerSpec.OnHostMaintenance)))
 		}
-		// Check machine series supports confidential computing
+		// Check machine series supports confidential computing, a missing machineType is reported on its own
 		machineSeries := strings.Split(providerSpec.MachineType, "-")[0]
-		if !slices.Contains(gcpConfidentialComputeSupportedMachineSeries, machineSeries) {
+		if providerSpec.MachineType != "" && !slices.Contains(gcpConfidentialComputeSupportedMachineSeries, machineSeries) {
 			errs = append(errs, field.Invalid(field.NewPath("providerSpec", "machineType"),
 				providerSpec.MachineType,
 				fmt.Sprintf("ConfidentialCompute require machine type in the following series: %s", strings.Join(gcpConfidentialComputeSupportedMachineSeries, `,`))),
 			)
 		}
+		// GPUs can not be attached to the machine series supporting confidential computing
+		if len(providerSpec.GPUs) != 0 {
+			errs = append(errs, field.Forbidden(field.NewPath("providerSpec", "gpus"),
+				fmt.Sprintf("GPUs are not supported with ConfidentialCompute, machine types in the following series can not have GPUs attached: %s", strings.Join(gcpConfidentialComputeSupportedMachineSeries, `,`))))
+		}
 	case machinev1beta1.ConfidentialComputePolicyDisabled, "":
 	default:
 		errs = append(errs, field.Invalid(field.NewPath("providerSpec", "confidentialCompute"),
@@ -1314,6 +1605,10 @@ func validateVSphere(m *machinev1beta1.Machine, config *admissionConfig) (bool,
 	} else {
 		if providerSpec.UserDataSecret.Name == "" {
 			errs = append(errs, field.Required(field.NewPath("providerSpec", "userDataSecret", "name"), "name must be provided"))
+		} else {
+			secretWarnings, secretErrs := userDataSecretExists(config, providerSpec.UserDataSecret.Name, m.GetNamespace())
+			warnings = append(warnings, secretWarnings...)
+			errs = append(errs, secretErrs...)
 		}
 	}
 
@@ -1323,10 +1618,19 @@ func validateVSphere(m *machinev1beta1.Machine, config *admissionConfig) (bool,
 		if providerSpec.CredentialsSecret.Name == "" {
 			errs = append(errs, field.Required(field.NewPath("providerSpec", "credentialsSecret", "name"), "name must be provided"))
 		} else {
-			warnings = append(warnings, credentialsSecretExists(config.client, providerSpec.CredentialsSecret.Name, m.GetNamespace())...)
+			secretWarnings, secretErrs := credentialsSecretExists(config, providerSpec.CredentialsSecret.Name, m.GetNamespace())
+			warnings = append(warnings, secretWarnings...)
+			errs = append(errs, secretErrs...)
 		}
 	}
 
+	// Only resolve the objects of otherwise valid providerSpecs in vCenter.
+	if len(errs) == 0 && config.vsphereDeepValidator != nil {
+		deepWarnings, deepErrs := config.vsphereDeepValidator.validate(m.GetNamespace(), providerSpec)
+		warnings = append(warnings, deepWarnings...)
+		errs = append(errs, deepErrs...)
+	}
+
 	if len(errs) > 0 {
 		return false, warnings, utilerrors.NewAggregate(errs)
 	}
@@ -1440,11 +1744,15 @@ func validateNutanix(m *machinev1beta1.Machine, config *admissionConfig) (bool,
 		}
 	}
 
-	if providerSpec.VCPUSockets < minNutanixCPUSockets {
+	if providerSpec.VCPUSockets < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("providerSpec", "vcpuSockets"), providerSpec.VCPUSockets, "vcpuSockets cannot be negative"))
+	} else if providerSpec.VCPUSockets < minNutanixCPUSockets {
 		warnings = append(warnings, fmt.Sprintf("providerSpec.vcpuSockets: %d is missing or less than the minimum value (%d): nodes may not boot correctly", providerSpec.VCPUSockets, minNutanixCPUSockets))
 	}
 
-	if providerSpec.VCPUsPerSocket < minNutanixCPUPerSocket {
+	if providerSpec.VCPUsPerSocket < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("providerSpec", "vcpusPerSocket"), providerSpec.VCPUsPerSocket, "vcpusPerSocket cannot be negative"))
+	} else if providerSpec.VCPUsPerSocket < minNutanixCPUPerSocket {
 		warnings = append(warnings, fmt.Sprintf("providerSpec.vcpusPerSocket: %d is missing or less than the minimum value (%d): nodes may not boot correctly", providerSpec.VCPUsPerSocket, minNutanixCPUPerSocket))
 	}
 
@@ -1453,7 +1761,9 @@ func validateNutanix(m *machinev1beta1.Machine, config *admissionConfig) (bool,
 		errs = append(errs, err)
 		return false, warnings, utilerrors.NewAggregate(errs)
 	}
-	if providerSpec.MemorySize.Cmp(minNutanixMemory) < 0 {
+	if providerSpec.MemorySize.Sign() < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("providerSpec", "memorySize"), providerSpec.MemorySize.String(), "memorySize cannot be negative"))
+	} else if providerSpec.MemorySize.Cmp(minNutanixMemory) < 0 {
 		warnings = append(warnings, fmt.Sprintf("providerSpec.memorySize: %d is missing or less than the recommended minimum value (%d): nodes may not boot correctly", providerSpec.MemorySize.Value()/(1024*1024), minNutanixMemoryMiB))
 	}
 
@@ -1462,7 +1772,9 @@ func validateNutanix(m *machinev1beta1.Machine, config *admissionConfig) (bool,
 		errs = append(errs, err)
 		return false, warnings, utilerrors.NewAggregate(errs)
 	}
-	if providerSpec.SystemDiskSize.Cmp(minNutanixDiskSize) < 0 {
+	if providerSpec.SystemDiskSize.Sign() < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("providerSpec", "systemDiskSize"), providerSpec.SystemDiskSize.String(), "systemDiskSize cannot be negative"))
+	} else if providerSpec.SystemDiskSize.Cmp(minNutanixDiskSize) < 0 {
 		warnings = append(warnings, fmt.Sprintf("providerSpec.systemDiskSize: %d is missing or less than the recommended minimum (%d): nodes may fail to start if disk size is too low", providerSpec.SystemDiskSize.Value()/(1024*1024*1024), minNutanixDiskGiB))
 	}
 
@@ -1471,6 +1783,10 @@ func validateNutanix(m *machinev1beta1.Machine, config *admissionConfig) (bool,
 	} else {
 		if providerSpec.UserDataSecret.Name == "" {
 			errs = append(errs, field.Required(field.NewPath("providerSpec", "userDataSecret", "name"), "name must be provided"))
+		} else {
+			secretWarnings, secretErrs := userDataSecretExists(config, providerSpec.UserDataSecret.Name, m.GetNamespace())
+			warnings = append(warnings, secretWarnings...)
+			errs = append(errs, secretErrs...)
 		}
 	}
 
@@ -1480,7 +1796,9 @@ func validateNutanix(m *machinev1beta1.Machine, config *admissionConfig) (bool,
 		if providerSpec.CredentialsSecret.Name == "" {
 			errs = append(errs, field.Required(field.NewPath("providerSpec", "credentialsSecret", "name"), "name must be provided"))
 		} else {
-			warnings = append(warnings, credentialsSecretExists(config.client, providerSpec.CredentialsSecret.Name, m.GetNamespace())...)
+			secretWarnings, secretErrs := credentialsSecretExists(config, providerSpec.CredentialsSecret.Name, m.GetNamespace())
+			warnings = append(warnings, secretWarnings...)
+			errs = append(errs, secretErrs...)
 		}
 	}
 
@@ -1526,6 +1844,9 @@ func validateNutanixResourceIdentifier(resource string, identifier machinev1.Nut
 		if identifier.UUID == nil || *identifier.UUID == "" {
 			return field.Required(parentPath.Child(resource).Child("uuid"), fmt.Sprintf("%s UUID must be provided", resource))
 		}
+		if _, err := uuid.Parse(*identifier.UUID); err != nil {
+			return field.Invalid(parentPath.Child(resource).Child("uuid"), *identifier.UUID, fmt.Sprintf("%s UUID must be a valid UUID", resource))
+		}
 	} else {
 		return field.Invalid(parentPath.Child(resource).Child("type"), identifier.Type, fmt.Sprintf("%s type must be one of %s or %s", resource, machinev1.NutanixIdentifierName, machinev1.NutanixIdentifierUUID))
 	}
@@ -1554,6 +1875,19 @@ func isAzureGovCloud(platformStatus *osconfigv1.PlatformStatus) bool {
 		platformStatus.Azure.CloudName != osconfigv1.AzurePublicCloud
 }
 
+// validateExternalMachine validates a machine representing a pre-existing node, which is linked to the node by its
+// providerID.
+func validateExternalMachine(m *machinev1beta1.Machine) []error {
+	var errs []error
+	if pointer.StringDeref(m.Spec.ProviderID, "") == "" {
+		errs = append(errs, field.Required(field.NewPath("spec", "providerID"), "providerID is required for external machines, it links the machine to its node"))
+	}
+	if m.Spec.ProviderSpec.Value != nil {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "providerSpec", "value"), "external machines are not provisioned by a provider"))
+	}
+	return errs
+}
+
 func validateMachineLifecycleHooks(m, oldM *machinev1beta1.Machine) []error {
 	var errs []error
 
@@ -1718,6 +2052,10 @@ func validatePowerVS(m *machinev1beta1.Machine, config *admissionConfig) (bool,
 	} else {
 		if providerSpec.UserDataSecret.Name == "" {
 			errs = append(errs, field.Required(field.NewPath("providerSpec", "userDataSecret", "name"), "providerSpec.userDataSecret.name must be provided"))
+		} else {
+			secretWarnings, secretErrs := userDataSecretExists(config, providerSpec.UserDataSecret.Name, m.GetNamespace())
+			warnings = append(warnings, secretWarnings...)
+			errs = append(errs, secretErrs...)
 		}
 	}
 
@@ -1727,7 +2065,9 @@ func validatePowerVS(m *machinev1beta1.Machine, config *admissionConfig) (bool,
 		if providerSpec.CredentialsSecret.Name == "" {
 			errs = append(errs, field.Required(field.NewPath("providerSpec", "credentialsSecret", "name"), "providerSpec.credentialsSecret.name must be provided"))
 		} else {
-			warnings = append(warnings, credentialsSecretExists(config.client, providerSpec.CredentialsSecret.Name, m.GetNamespace())...)
+			secretWarnings, secretErrs := credentialsSecretExists(config, providerSpec.CredentialsSecret.Name, m.GetNamespace())
+			warnings = append(warnings, secretWarnings...)
+			errs = append(errs, secretErrs...)
 		}
 	}
 
@@ -1747,6 +2087,9 @@ func validatePowerVSResourceIdentifiers(serviceInstance machinev1.PowerVSResourc
 		if serviceInstance.ID == nil {
 			errs = append(errs, field.Required(parentPath.Child("id"),
 				fmt.Sprintf("%s identifier is specified as ID but the value is nil", resourceType)))
+		} else if _, err := uuid.Parse(*serviceInstance.ID); err != nil {
+			errs = append(errs, field.Invalid(parentPath.Child("id"), *serviceInstance.ID,
+				fmt.Sprintf("%s identifier is specified as ID but the value is not a valid UUID", resourceType)))
 		}
 	case machinev1.PowerVSResourceTypeName:
 		if serviceInstance.Name == nil {
@@ -1762,6 +2105,9 @@ func validatePowerVSResourceIdentifiers(serviceInstance machinev1.PowerVSResourc
 		if serviceInstance.RegEx == nil {
 			errs = append(errs, field.Required(parentPath.Child("regex"),
 				fmt.Sprintf("%s identifier is specified as Regex but the value is nil", resourceType)))
+		} else if _, err := regexp.Compile(*serviceInstance.RegEx); err != nil {
+			errs = append(errs, field.Invalid(parentPath.Child("regex"), *serviceInstance.RegEx,
+				fmt.Sprintf("%s identifier is specified as Regex but the value is not a valid regular expression: %v", resourceType, err)))
 		}
 	case "":
 		errs = append(errs, field.Required(parentPath,
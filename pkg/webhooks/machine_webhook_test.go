@@ -3,7 +3,9 @@ package webhooks
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"reflect"
 	"runtime"
 	"testing"
@@ -12,6 +14,8 @@ import (
 	osconfigv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1"
 	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -19,6 +23,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/utils/pointer"
@@ -28,7 +33,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/machine-api-operator/pkg/util/annotations"
 )
 
 var (
@@ -854,7 +862,7 @@ func TestMachineCreation(t *testing.T) {
 				Object: &machinev1.PowerVSMachineProviderConfig{
 					ServiceInstance: machinev1.PowerVSResource{
 						Type: machinev1.PowerVSResourceTypeID,
-						ID:   pointer.String("TestServiceInstanceID"),
+						ID:   pointer.String("a8b3e9d2-3f51-4c6e-9a7d-1b2c3d4e5f60"),
 					},
 					KeyPairName: "TestKeyPair",
 					Image: machinev1.PowerVSResource{
@@ -992,7 +1000,7 @@ func TestMachineCreation(t *testing.T) {
 					},
 					Network: machinev1.PowerVSResource{
 						Type: machinev1.PowerVSResourceTypeID,
-						ID:   pointer.String("TestNetworkID"),
+						ID:   pointer.String("6d2f4e1a-8b3c-4d5e-9f60-7a8b9c0d1e2f"),
 					},
 				},
 			},
@@ -2020,6 +2028,23 @@ func TestValidateAWSProviderSpec(t *testing.T) {
 			expectedOk:    false,
 			expectedError: "providerSpec.tenancy: Invalid value: \"invalid\": Invalid providerSpec.tenancy, the only allowed options are: default, dedicated, host",
 		},
+		{
+			testCase: "with spot instances on dedicated instances",
+			modifySpec: func(p *machinev1beta1.AWSMachineProviderConfig) {
+				p.Placement.Tenancy = machinev1beta1.DedicatedTenancy
+				p.SpotMarketOptions = &machinev1beta1.SpotMarketOptions{}
+			},
+			expectedOk: true,
+		},
+		{
+			testCase: "fail with spot instances on dedicated hosts",
+			modifySpec: func(p *machinev1beta1.AWSMachineProviderConfig) {
+				p.Placement.Tenancy = machinev1beta1.HostTenancy
+				p.SpotMarketOptions = &machinev1beta1.SpotMarketOptions{}
+			},
+			expectedOk:    false,
+			expectedError: "providerSpec.spotMarketOptions: Forbidden: spot instances can not be launched on dedicated hosts, spotMarketOptions can not be used with tenancy host",
+		},
 		{
 			testCase: "with no iam instance profile",
 			modifySpec: func(p *machinev1beta1.AWSMachineProviderConfig) {
@@ -2262,12 +2287,13 @@ func TestDefaultAWSProviderSpec(t *testing.T) {
 		arch = defaultAWSARMInstanceType
 	}
 	testCases := []struct {
-		testCase             string
-		providerSpec         *machinev1beta1.AWSMachineProviderConfig
-		expectedProviderSpec *machinev1beta1.AWSMachineProviderConfig
-		expectedError        string
-		expectedOk           bool
-		expectedWarnings     []string
+		testCase                      string
+		metadataServiceAuthentication machinev1beta1.MetadataServiceAuthentication
+		providerSpec                  *machinev1beta1.AWSMachineProviderConfig
+		expectedProviderSpec          *machinev1beta1.AWSMachineProviderConfig
+		expectedError                 string
+		expectedOk                    bool
+		expectedWarnings              []string
 	}{
 		{
 			testCase: "it defaults Region, InstanceType, UserDataSecret and CredentialsSecret",
@@ -2290,6 +2316,51 @@ func TestDefaultAWSProviderSpec(t *testing.T) {
 			expectedError:    "",
 			expectedWarnings: nil,
 		},
+		{
+			testCase:                      "it defaults the metadata service authentication when configured",
+			metadataServiceAuthentication: machinev1beta1.MetadataServiceAuthenticationRequired,
+			providerSpec: &machinev1beta1.AWSMachineProviderConfig{
+				InstanceType:      arch,
+				UserDataSecret:    &corev1.LocalObjectReference{Name: defaultUserDataSecret},
+				CredentialsSecret: &corev1.LocalObjectReference{Name: defaultAWSCredentialsSecret},
+			},
+			expectedProviderSpec: &machinev1beta1.AWSMachineProviderConfig{
+				InstanceType:      arch,
+				UserDataSecret:    &corev1.LocalObjectReference{Name: defaultUserDataSecret},
+				CredentialsSecret: &corev1.LocalObjectReference{Name: defaultAWSCredentialsSecret},
+				Placement: machinev1beta1.Placement{
+					Region: "region",
+				},
+				MetadataServiceOptions: machinev1beta1.MetadataServiceOptions{
+					Authentication: machinev1beta1.MetadataServiceAuthenticationRequired,
+				},
+			},
+			expectedOk: true,
+		},
+		{
+			testCase:                      "it keeps the metadata service authentication set by the user",
+			metadataServiceAuthentication: machinev1beta1.MetadataServiceAuthenticationRequired,
+			providerSpec: &machinev1beta1.AWSMachineProviderConfig{
+				InstanceType:      arch,
+				UserDataSecret:    &corev1.LocalObjectReference{Name: defaultUserDataSecret},
+				CredentialsSecret: &corev1.LocalObjectReference{Name: defaultAWSCredentialsSecret},
+				MetadataServiceOptions: machinev1beta1.MetadataServiceOptions{
+					Authentication: machinev1beta1.MetadataServiceAuthenticationOptional,
+				},
+			},
+			expectedProviderSpec: &machinev1beta1.AWSMachineProviderConfig{
+				InstanceType:      arch,
+				UserDataSecret:    &corev1.LocalObjectReference{Name: defaultUserDataSecret},
+				CredentialsSecret: &corev1.LocalObjectReference{Name: defaultAWSCredentialsSecret},
+				Placement: machinev1beta1.Placement{
+					Region: "region",
+				},
+				MetadataServiceOptions: machinev1beta1.MetadataServiceOptions{
+					Authentication: machinev1beta1.MetadataServiceAuthenticationOptional,
+				},
+			},
+			expectedOk: true,
+		},
 	}
 
 	platformStatus := &osconfigv1.PlatformStatus{
@@ -2302,6 +2373,8 @@ func TestDefaultAWSProviderSpec(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.testCase, func(t *testing.T) {
+			h.DefaultAWSMetadataServiceAuthentication(tc.metadataServiceAuthentication)
+
 			m := &machinev1beta1.Machine{}
 			rawBytes, err := json.Marshal(tc.providerSpec)
 			if err != nil {
@@ -3252,15 +3325,83 @@ func TestValidateGCPProviderSpec(t *testing.T) {
 			expectedOk:    false,
 			expectedError: "providerSpec.shieldedInstanceConfig.virtualizedTrustedPlatformModule: Invalid value: \"Disabled\": integrityMonitoring requires virtualizedTrustedPlatformModule Enabled.",
 		},
+		{
+			testCase: "with virtualizedTrustedPlatformModule disabled while integrityMonitoring is not set",
+			modifySpec: func(p *machinev1beta1.GCPMachineProviderSpec) {
+				p.ShieldedInstanceConfig = machinev1beta1.GCPShieldedInstanceConfig{
+					VirtualizedTrustedPlatformModule: machinev1beta1.VirtualizedTrustedPlatformModulePolicyDisabled,
+				}
+			},
+			expectedOk:    false,
+			expectedError: "providerSpec.shieldedInstanceConfig.virtualizedTrustedPlatformModule: Invalid value: \"Disabled\": integrityMonitoring defaults to Enabled and requires virtualizedTrustedPlatformModule Enabled, set integrityMonitoring to Disabled to disable virtualizedTrustedPlatformModule.",
+		},
+		{
+			testCase: "with virtualizedTrustedPlatformModule and integrityMonitoring disabled",
+			modifySpec: func(p *machinev1beta1.GCPMachineProviderSpec) {
+				p.ShieldedInstanceConfig = machinev1beta1.GCPShieldedInstanceConfig{
+					VirtualizedTrustedPlatformModule: machinev1beta1.VirtualizedTrustedPlatformModulePolicyDisabled,
+					IntegrityMonitoring:              machinev1beta1.IntegrityMonitoringPolicyDisabled,
+				}
+			},
+			expectedOk: true,
+		},
 		{
 			testCase: "with ConfidentialCompute",
 			modifySpec: func(p *machinev1beta1.GCPMachineProviderSpec) {
 				p.ConfidentialCompute = machinev1beta1.ConfidentialComputePolicyEnabled
 				p.OnHostMaintenance = machinev1beta1.TerminateHostMaintenanceType
 				p.MachineType = "n2d-standard-4"
+				p.GPUs = nil
+			},
+			expectedOk: true,
+		},
+		{
+			testCase: "with ConfidentialCompute and shieldedInstanceConfig",
+			modifySpec: func(p *machinev1beta1.GCPMachineProviderSpec) {
+				p.ConfidentialCompute = machinev1beta1.ConfidentialComputePolicyEnabled
+				p.OnHostMaintenance = machinev1beta1.TerminateHostMaintenanceType
+				p.MachineType = "c2d-standard-4"
+				p.GPUs = nil
+				p.ShieldedInstanceConfig = machinev1beta1.GCPShieldedInstanceConfig{
+					SecureBoot:                       machinev1beta1.SecureBootPolicyEnabled,
+					IntegrityMonitoring:              machinev1beta1.IntegrityMonitoringPolicyEnabled,
+					VirtualizedTrustedPlatformModule: machinev1beta1.VirtualizedTrustedPlatformModulePolicyEnabled,
+				}
 			},
 			expectedOk: true,
 		},
+		{
+			testCase: "with ConfidentialCompute enabled while onHostMaintenance is not set",
+			modifySpec: func(p *machinev1beta1.GCPMachineProviderSpec) {
+				p.ConfidentialCompute = machinev1beta1.ConfidentialComputePolicyEnabled
+				p.OnHostMaintenance = ""
+				p.MachineType = "n2d-standard-4"
+				p.GPUs = nil
+			},
+			expectedOk:    false,
+			expectedError: "providerSpec.onHostMaintenance: Required value: ConfidentialCompute require OnHostMaintenance to be set to Terminate, it defaults to Migrate which is not supported by confidential VMs",
+		},
+		{
+			testCase: "with ConfidentialCompute enabled and GPUs",
+			modifySpec: func(p *machinev1beta1.GCPMachineProviderSpec) {
+				p.ConfidentialCompute = machinev1beta1.ConfidentialComputePolicyEnabled
+				p.OnHostMaintenance = machinev1beta1.TerminateHostMaintenanceType
+				p.MachineType = "n2d-standard-4"
+			},
+			expectedOk:    false,
+			expectedError: "providerSpec.gpus: Forbidden: GPUs are not supported with ConfidentialCompute, machine types in the following series can not have GPUs attached: n2d,c2d",
+		},
+		{
+			testCase: "with ConfidentialCompute enabled and no machineType",
+			modifySpec: func(p *machinev1beta1.GCPMachineProviderSpec) {
+				p.ConfidentialCompute = machinev1beta1.ConfidentialComputePolicyEnabled
+				p.OnHostMaintenance = machinev1beta1.TerminateHostMaintenanceType
+				p.MachineType = ""
+				p.GPUs = nil
+			},
+			expectedOk:    false,
+			expectedError: "providerSpec.machineType: Required value: machineType should be set to one of the supported GCP machine types",
+		},
 		{
 			testCase: "with ConfidentialCompute invalid value",
 			modifySpec: func(p *machinev1beta1.GCPMachineProviderSpec) {
@@ -3286,6 +3427,7 @@ func TestValidateGCPProviderSpec(t *testing.T) {
 				p.ConfidentialCompute = machinev1beta1.ConfidentialComputePolicyEnabled
 				p.OnHostMaintenance = machinev1beta1.TerminateHostMaintenanceType
 				p.MachineType = "e2-standard-4"
+				p.GPUs = nil
 			},
 			expectedOk:    false,
 			expectedError: "providerSpec.machineType: Invalid value: \"e2-standard-4\": ConfidentialCompute require machine type in the following series: n2d,c2d",
@@ -4318,6 +4460,22 @@ func TestValidatePowerVSProviderSpec(t *testing.T) {
 			expectedOk:    false,
 			expectedError: "providerSpec.image: Invalid value: \"RegEx\": image identifier is specified as RegEx but only ID and Name are valid resource identifiers",
 		},
+		{
+			testCase: "with an image ID which is not a UUID",
+			modifySpec: func(p *machinev1.PowerVSMachineProviderConfig) {
+				p.Image = machinev1.PowerVSResource{Type: machinev1.PowerVSResourceTypeID, ID: pointer.String("rhcos-image")}
+			},
+			expectedOk:    false,
+			expectedError: "providerSpec.image.id: Invalid value: \"rhcos-image\": image identifier is specified as ID but the value is not a valid UUID",
+		},
+		{
+			testCase: "with an invalid regex for network",
+			modifySpec: func(p *machinev1.PowerVSMachineProviderConfig) {
+				p.Network = machinev1.PowerVSResource{Type: machinev1.PowerVSResourceTypeRegEx, RegEx: pointer.String("^DHCP[")}
+			},
+			expectedOk:    false,
+			expectedError: "providerSpec.network.regex: Invalid value: \"^DHCP[\": network identifier is specified as Regex but the value is not a valid regular expression: error parsing regexp: missing closing ]: `[`",
+		},
 		{
 			testCase: "with no Network",
 			modifySpec: func(p *machinev1.PowerVSMachineProviderConfig) {
@@ -4443,7 +4601,13 @@ func TestValidatePowerVSProviderSpec(t *testing.T) {
 			Namespace: namespace.Name,
 		},
 	}
-	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(secret).Build()
+	userDataSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      defaultUserDataSecret,
+			Namespace: namespace.Name,
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(secret, userDataSecret).Build()
 	infra := plainInfra.DeepCopy()
 	infra.Status.InfrastructureName = "clusterID"
 	infra.Status.PlatformStatus.Type = osconfigv1.PowerVSPlatformType
@@ -4655,6 +4819,39 @@ func TestValidateNutanixProviderSpec(t *testing.T) {
 			expectedError:    "",
 			expectedWarnings: []string{"providerSpec.systemDiskSize: 10 is missing or less than the recommended minimum (20): nodes may fail to start if disk size is too low"},
 		},
+		{
+			testCase: "with negative CPU sockets provided",
+			modifySpec: func(p *machinev1.NutanixMachineProviderConfig) {
+				p.VCPUSockets = -1
+			},
+			expectedOk:    false,
+			expectedError: "providerSpec.vcpuSockets: Invalid value: -1: vcpuSockets cannot be negative",
+		},
+		{
+			testCase: "with negative memory provided",
+			modifySpec: func(p *machinev1.NutanixMachineProviderConfig) {
+				p.MemorySize = resource.MustParse("-4Gi")
+			},
+			expectedOk:    false,
+			expectedError: "providerSpec.memorySize: Invalid value: \"-4Gi\": memorySize cannot be negative",
+		},
+		{
+			testCase: "with a subnet UUID which is not a UUID",
+			modifySpec: func(p *machinev1.NutanixMachineProviderConfig) {
+				p.Subnets = []machinev1.NutanixResourceIdentifier{
+					{Type: machinev1.NutanixIdentifierUUID, UUID: pointer.String("subnet-1")},
+				}
+			},
+			expectedOk:    false,
+			expectedError: "providerSpec.subnet.uuid: Invalid value: \"subnet-1\": subnet UUID must be a valid UUID",
+		},
+		{
+			testCase: "with a valid cluster UUID",
+			modifySpec: func(p *machinev1.NutanixMachineProviderConfig) {
+				p.Cluster = machinev1.NutanixResourceIdentifier{Type: machinev1.NutanixIdentifierUUID, UUID: pointer.String("0005b0f1-8f43-a0f2-02b7-3cecef193712")}
+			},
+			expectedOk: true,
+		},
 		{
 			testCase: "with no subnets provided",
 			modifySpec: func(p *machinev1.NutanixMachineProviderConfig) {
@@ -4721,7 +4918,13 @@ func TestValidateNutanixProviderSpec(t *testing.T) {
 			Namespace: namespace.Name,
 		},
 	}
-	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(secret).Build()
+	userDataSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      defaultUserDataSecret,
+			Namespace: namespace.Name,
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(secret, userDataSecret).Build()
 	infra := plainInfra.DeepCopy()
 	infra.Status.InfrastructureName = "clusterID"
 	infra.Status.PlatformStatus.Type = osconfigv1.NutanixPlatformType
@@ -4778,3 +4981,413 @@ func TestValidateNutanixProviderSpec(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateMachine(t *testing.T) {
+	g := NewWithT(t)
+
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	infra := plainInfra.DeepCopy()
+	infra.Status.InfrastructureName = "clusterID"
+	infra.Status.PlatformStatus.Type = osconfigv1.AWSPlatformType
+	h := createMachineValidator(infra, c, plainDNS)
+
+	providerSpec := &machinev1beta1.AWSMachineProviderConfig{
+		AMI:               machinev1beta1.AWSResourceReference{ID: pointer.String("ami")},
+		Placement:         machinev1beta1.Placement{Region: "region"},
+		InstanceType:      "m5.large",
+		UserDataSecret:    &corev1.LocalObjectReference{Name: "secret"},
+		CredentialsSecret: &corev1.LocalObjectReference{Name: "secret"},
+	}
+	raw, err := json.Marshal(providerSpec)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	m := &machinev1beta1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+		Spec: machinev1beta1.MachineSpec{
+			ProviderSpec: machinev1beta1.ProviderSpec{Value: &kruntime.RawExtension{Raw: raw}},
+		},
+	}
+
+	warnings, err := h.ValidateMachine(m)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(warnings).To(ContainElement(ContainSubstring("Expected CredentialsSecret to exist")))
+
+	providerSpec.InstanceType = ""
+	raw, err = json.Marshal(providerSpec)
+	g.Expect(err).ToNot(HaveOccurred())
+	m.Spec.ProviderSpec.Value.Raw = raw
+
+	_, err = h.ValidateMachine(m)
+	g.Expect(err).To(MatchError(ContainSubstring("providerSpec.instanceType")))
+}
+
+func TestValidateExternalMachine(t *testing.T) {
+	g := NewWithT(t)
+
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	infra := plainInfra.DeepCopy()
+	infra.Status.InfrastructureName = "clusterID"
+	infra.Status.PlatformStatus.Type = osconfigv1.AWSPlatformType
+	h := createMachineValidator(infra, c, plainDNS)
+
+	m := &machinev1beta1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Annotations: map[string]string{annotations.ExternalMachineAnnotation: "true"},
+		},
+	}
+
+	_, err := h.ValidateMachine(m)
+	g.Expect(err).To(MatchError(ContainSubstring("spec.providerID: Required value")))
+
+	m.Spec.ProviderID = pointer.String("baremetal:///worker-0")
+	warnings, err := h.ValidateMachine(m)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(warnings).To(BeEmpty())
+
+	m.Spec.ProviderSpec.Value = &kruntime.RawExtension{Raw: []byte("{}")}
+	_, err = h.ValidateMachine(m)
+	g.Expect(err).To(MatchError(ContainSubstring("spec.providerSpec.value: Forbidden")))
+}
+
+func TestValidateMachineMissingSecrets(t *testing.T) {
+	userDataNotFound := "providerSpec.userDataSecret: Invalid value: \"user-data\": not found. Expected UserDataSecret to exist"
+	credentialsNotFound := "providerSpec.credentialsSecret: Invalid value: \"credentials\": not found. Expected CredentialsSecret to exist"
+
+	testCases := []struct {
+		name                 string
+		secrets              []string
+		rejectMissingSecrets bool
+		expectedWarnings     []string
+		expectedError        string
+	}{
+		{
+			name:    "with both secrets",
+			secrets: []string{"user-data", "credentials"},
+		},
+		{
+			name:             "with missing secrets",
+			expectedWarnings: []string{userDataNotFound, credentialsNotFound},
+		},
+		{
+			name:                 "with missing secrets rejected",
+			secrets:              []string{"credentials"},
+			rejectMissingSecrets: true,
+			expectedError:        userDataNotFound,
+		},
+		{
+			name:                 "with both secrets and missing secrets rejected",
+			secrets:              []string{"user-data", "credentials"},
+			rejectMissingSecrets: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var objects []kruntime.Object
+			for _, name := range tc.secrets {
+				objects = append(objects, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}})
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(objects...).Build()
+			infra := plainInfra.DeepCopy()
+			infra.Status.InfrastructureName = "clusterID"
+			infra.Status.PlatformStatus.Type = osconfigv1.AWSPlatformType
+			h := createMachineValidator(infra, c, plainDNS)
+			if tc.rejectMissingSecrets {
+				h.RejectMissingSecrets()
+			}
+
+			providerSpec := &machinev1beta1.AWSMachineProviderConfig{
+				AMI:                machinev1beta1.AWSResourceReference{ID: pointer.String("ami")},
+				Placement:          machinev1beta1.Placement{Region: "region"},
+				InstanceType:       "m5.large",
+				IAMInstanceProfile: &machinev1beta1.AWSResourceReference{ID: pointer.String("profileID")},
+				UserDataSecret:     &corev1.LocalObjectReference{Name: "user-data"},
+				CredentialsSecret:  &corev1.LocalObjectReference{Name: "credentials"},
+				Subnet:             machinev1beta1.AWSResourceReference{ID: pointer.String("subnet")},
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "AWSMachineProviderConfig",
+					APIVersion: "machine.openshift.io/v1beta1",
+				},
+			}
+			raw, err := json.Marshal(providerSpec)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			m := &machinev1beta1.Machine{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+				Spec: machinev1beta1.MachineSpec{
+					ProviderSpec: machinev1beta1.ProviderSpec{Value: &kruntime.RawExtension{Raw: raw}},
+				},
+			}
+
+			ok, warnings, errs := h.validateMachine(m, nil)
+			if tc.expectedError != "" {
+				g.Expect(ok).To(BeFalse())
+				g.Expect(errs).To(MatchError(tc.expectedError))
+			} else {
+				g.Expect(ok).To(BeTrue(), "%v", errs)
+			}
+			g.Expect(warnings).To(ConsistOf(tc.expectedWarnings))
+
+			// Updates which do not change the providerSpec are not rejected.
+			updated := m.DeepCopy()
+			updated.Labels = map[string]string{"foo": "bar"}
+			ok, _, errs = h.validateMachine(updated, m)
+			g.Expect(ok).To(BeTrue(), "%v", errs)
+		})
+	}
+}
+
+func TestMachineDeletionProtection(t *testing.T) {
+	testCases := []struct {
+		name          string
+		annotations   map[string]string
+		username      string
+		allowedUsers  []string
+		expectAllowed bool
+	}{
+		{
+			name:          "with an unprotected machine",
+			username:      "system:admin",
+			expectAllowed: true,
+		},
+		{
+			name:          "with a protected machine",
+			annotations:   map[string]string{MachineDeletionProtectedAnnotation: "true"},
+			username:      "system:admin",
+			expectAllowed: false,
+		},
+		{
+			name:          "with deletion protection disabled",
+			annotations:   map[string]string{MachineDeletionProtectedAnnotation: "false"},
+			username:      "system:admin",
+			expectAllowed: true,
+		},
+		{
+			name:          "with a protected machine deleted by the machine controllers",
+			annotations:   map[string]string{MachineDeletionProtectedAnnotation: "true"},
+			username:      "system:serviceaccount:openshift-machine-api:machine-api-controllers",
+			expectAllowed: true,
+		},
+		{
+			name:          "with a protected machine deleted by a configured allowed user",
+			annotations:   map[string]string{MachineDeletionProtectedAnnotation: "true"},
+			username:      "system:serviceaccount:autoscaler:cluster-autoscaler",
+			allowedUsers:  []string{"system:serviceaccount:autoscaler:cluster-autoscaler"},
+			expectAllowed: true,
+		},
+		{
+			name:          "with a protected machine deleted by the machine controllers not allowed anymore",
+			annotations:   map[string]string{MachineDeletionProtectedAnnotation: "true"},
+			username:      "system:serviceaccount:openshift-machine-api:machine-api-controllers",
+			allowedUsers:  []string{"system:serviceaccount:autoscaler:cluster-autoscaler"},
+			expectAllowed: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			decoder, err := admission.NewDecoder(scheme.Scheme)
+			g.Expect(err).ToNot(HaveOccurred())
+			h := createMachineValidator(plainInfra, fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(), plainDNS)
+			g.Expect(h.InjectDecoder(decoder)).To(Succeed())
+			if tc.allowedUsers != nil {
+				h.SetDeletionProtectionAllowedUsers(tc.allowedUsers)
+			}
+
+			m := &machinev1beta1.Machine{
+				TypeMeta:   metav1.TypeMeta{Kind: "Machine", APIVersion: machinev1beta1.SchemeGroupVersion.String()},
+				ObjectMeta: metav1.ObjectMeta{Name: "machine", Namespace: "default", Annotations: tc.annotations},
+			}
+			raw, err := json.Marshal(m)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			resp := h.Handle(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: admissionv1.Delete,
+					OldObject: kruntime.RawExtension{Raw: raw},
+					UserInfo:  authenticationv1.UserInfo{Username: tc.username},
+				},
+			})
+			g.Expect(resp.Allowed).To(Equal(tc.expectAllowed), "unexpected response: %v", resp.Result)
+		})
+	}
+}
+
+func TestControlPlaneMachineDeletion(t *testing.T) {
+	running := machinev1beta1.PhaseRunning
+	provisioned := machinev1beta1.PhaseProvisioned
+	masterLabels := map[string]string{machineRoleLabel: machineMasterRole}
+	newMachine := func(name string, labels map[string]string, phase *string, deleting bool) *machinev1beta1.Machine {
+		m := &machinev1beta1.Machine{
+			TypeMeta:   metav1.TypeMeta{Kind: "Machine", APIVersion: machinev1beta1.SchemeGroupVersion.String()},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+			Status:     machinev1beta1.MachineStatus{Phase: phase},
+		}
+		if deleting {
+			now := metav1.Now()
+			m.DeletionTimestamp = &now
+			m.Finalizers = []string{machinev1beta1.MachineFinalizer}
+		}
+		return m
+	}
+
+	testCases := []struct {
+		name                    string
+		machine                 *machinev1beta1.Machine
+		others                  []*machinev1beta1.Machine
+		minControlPlaneMachines int
+		listErr                 error
+		expectAllowed           bool
+		expectCode              int32
+	}{
+		{
+			name:          "with the last running control plane machine",
+			machine:       newMachine("master-0", masterLabels, &running, false),
+			expectAllowed: false,
+		},
+		{
+			name:    "with another running control plane machine",
+			machine: newMachine("master-0", masterLabels, &running, false),
+			others: []*machinev1beta1.Machine{
+				newMachine("master-1", masterLabels, &running, false),
+			},
+			expectAllowed: true,
+		},
+		{
+			name:    "with the other control plane machines not running or being deleted",
+			machine: newMachine("master-0", masterLabels, &running, false),
+			others: []*machinev1beta1.Machine{
+				newMachine("master-1", masterLabels, &provisioned, false),
+				newMachine("master-2", masterLabels, &running, true),
+				newMachine("worker-0", nil, &running, false),
+			},
+			expectAllowed: false,
+		},
+		{
+			name:    "with fewer running control plane machines than the configured minimum",
+			machine: newMachine("master-0", masterLabels, &running, false),
+			others: []*machinev1beta1.Machine{
+				newMachine("master-1", masterLabels, &running, false),
+			},
+			minControlPlaneMachines: 2,
+			expectAllowed:           false,
+		},
+		{
+			name:          "with a control plane machine which is not running",
+			machine:       newMachine("master-0", masterLabels, &provisioned, false),
+			expectAllowed: true,
+		},
+		{
+			name:          "with a control plane machine already being deleted",
+			machine:       newMachine("master-0", masterLabels, &running, true),
+			expectAllowed: true,
+		},
+		{
+			name:          "with a worker machine",
+			machine:       newMachine("worker-0", nil, &running, false),
+			expectAllowed: true,
+		},
+		{
+			name:          "when the control plane machines can not be listed",
+			machine:       newMachine("master-0", masterLabels, &running, false),
+			listErr:       errors.New("connection refused"),
+			expectAllowed: false,
+			expectCode:    http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			objs := []client.Object{tc.machine}
+			for _, m := range tc.others {
+				objs = append(objs, m)
+			}
+			var c client.Client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objs...).Build()
+			if tc.listErr != nil {
+				c = &listErrorClient{Client: c, err: tc.listErr}
+			}
+
+			decoder, err := admission.NewDecoder(scheme.Scheme)
+			g.Expect(err).ToNot(HaveOccurred())
+			h := createMachineValidator(plainInfra, c, plainDNS)
+			if tc.minControlPlaneMachines != 0 {
+				h.SetMinControlPlaneMachines(tc.minControlPlaneMachines)
+			}
+			g.Expect(h.InjectDecoder(decoder)).To(Succeed())
+
+			raw, err := json.Marshal(tc.machine)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			resp := h.Handle(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: admissionv1.Delete,
+					OldObject: kruntime.RawExtension{Raw: raw},
+					UserInfo:  authenticationv1.UserInfo{Username: "system:admin"},
+				},
+			})
+			g.Expect(resp.Allowed).To(Equal(tc.expectAllowed), "unexpected response: %v", resp.Result)
+			if tc.expectCode != 0 {
+				g.Expect(resp.Result.Code).To(Equal(tc.expectCode))
+			}
+		})
+	}
+}
+
+// listErrorClient fails to list objects.
+type listErrorClient struct {
+	client.Client
+	err error
+}
+
+func (c *listErrorClient) List(context.Context, client.ObjectList, ...client.ListOption) error {
+	return c.err
+}
+
+func TestValidateNodeStartupTimeoutAnnotation(t *testing.T) {
+	testCases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedErrors []string
+	}{
+		{
+			name: "without node startup timeout",
+		},
+		{
+			name:        "with a valid node startup timeout",
+			annotations: map[string]string{"machine.openshift.io/node-startup-timeout": "20m"},
+		},
+		{
+			name:        "with an invalid node startup timeout",
+			annotations: map[string]string{"machine.openshift.io/node-startup-timeout": "20"},
+			expectedErrors: []string{
+				`metadata.annotations[machine.openshift.io/node-startup-timeout]: Invalid value: "20": time: missing unit in duration "20"`,
+			},
+		},
+		{
+			name:        "with a negative node startup timeout",
+			annotations: map[string]string{"machine.openshift.io/node-startup-timeout": "-20m"},
+			expectedErrors: []string{
+				`metadata.annotations[machine.openshift.io/node-startup-timeout]: Invalid value: "-20m": timeout must be greater than zero`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			errs := validateNodeStartupTimeoutAnnotation(tc.annotations, field.NewPath("metadata", "annotations"))
+			g.Expect(errs).To(HaveLen(len(tc.expectedErrors)))
+			for i, err := range errs {
+				g.Expect(err.Error()).To(Equal(tc.expectedErrors[i]))
+			}
+		})
+	}
+}
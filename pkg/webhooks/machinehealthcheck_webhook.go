@@ -0,0 +1,255 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/machine-api-operator/pkg/controller/machinehealthcheck"
+)
+
+// minNodeStartupTimeout is the shortest nodeStartupTimeout accepted, other than zero which disables it.
+// The timeout runs from the status.lastUpdated of the Machine, which the Machine controller updates when it
+// reconciles the Machine, every 30s while the instance is provisioned (requeueAfter in pkg/controller/machine).
+// Shorter timeouts would remediate Machines between two of their reconciliations, before their provisioning
+// progress is recorded. It also matches the minimum enforced by Cluster API for MachineHealthChecks.
+const minNodeStartupTimeout = 30 * time.Second
+
+// machineHealthCheckValidatorHandler validates MachineHealthCheck API resources.
+// implements type Handler interface.
+// https://godoc.org/github.com/kubernetes-sigs/controller-runtime/pkg/webhook/admission#Handler
+type machineHealthCheckValidatorHandler struct {
+	client  client.Client
+	decoder *admission.Decoder
+}
+
+// NewMachineHealthCheckValidator returns a new machineHealthCheckValidatorHandler.
+func NewMachineHealthCheckValidator(client client.Client) *machineHealthCheckValidatorHandler {
+	return &machineHealthCheckValidatorHandler{client: client}
+}
+
+// InjectDecoder injects the decoder.
+func (h *machineHealthCheckValidatorHandler) InjectDecoder(d *admission.Decoder) error {
+	h.decoder = d
+	return nil
+}
+
+// Handle handles HTTP requests for admission webhook servers.
+func (h *machineHealthCheckValidatorHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	mhc := &machinev1beta1.MachineHealthCheck{}
+
+	if err := h.decoder.Decode(req, mhc); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var oldMHC *machinev1beta1.MachineHealthCheck
+	if len(req.OldObject.Raw) > 0 {
+		// oldMHC must only be initialised if there is an old object (ie on UPDATE or DELETE).
+		oldMHC = &machinev1beta1.MachineHealthCheck{}
+		if err := h.decoder.DecodeRaw(req.OldObject, oldMHC); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+
+	klog.V(3).Infof("Validate webhook called for MachineHealthCheck: %s", mhc.GetName())
+
+	ok, warnings, errs := h.validateMachineHealthCheck(ctx, mhc, oldMHC)
+	if !ok {
+		return denied(errs, warnings)
+	}
+
+	return admission.Allowed("MachineHealthCheck valid").WithWarnings(warnings...)
+}
+
+func (h *machineHealthCheckValidatorHandler) validateMachineHealthCheck(ctx context.Context, mhc, oldMHC *machinev1beta1.MachineHealthCheck) (bool, []string, utilerrors.Aggregate) {
+	var errs []error
+	var warnings []string
+
+	selectorPath := field.NewPath("spec", "selector")
+	if len(mhc.Spec.Selector.MatchLabels) == 0 && len(mhc.Spec.Selector.MatchExpressions) == 0 {
+		errs = append(errs, field.Required(selectorPath, "selector must not be empty, it would match all machines"))
+	} else if _, err := metav1.LabelSelectorAsSelector(&mhc.Spec.Selector); err != nil {
+		errs = append(errs, field.Invalid(selectorPath, mhc.Spec.Selector, fmt.Sprintf("invalid selector: %v", err)))
+	} else if oldMHC == nil || !reflect.DeepEqual(mhc.Spec.Selector, oldMHC.Spec.Selector) {
+		// Only check for overlaps when the selector changes, so that MachineHealthChecks
+		// created before this validation existed can still be updated.
+		overlapErrs, err := h.validateSelectorOverlap(ctx, mhc)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("unable to check for overlapping MachineHealthChecks: %v", err))
+		}
+		errs = append(errs, overlapErrs...)
+	}
+
+	if mhc.Spec.MaxUnhealthy != nil {
+		errs = append(errs, validateMaxUnhealthy(mhc.Spec.MaxUnhealthy, field.NewPath("spec", "maxUnhealthy"))...)
+	}
+
+	if mhc.Spec.NodeStartupTimeout != nil {
+		timeout := mhc.Spec.NodeStartupTimeout.Duration
+		if timeout != 0 && timeout < minNodeStartupTimeout {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "nodeStartupTimeout"), timeout.String(),
+				fmt.Sprintf("must be at least %v, or 0 to disable it", minNodeStartupTimeout)))
+		}
+	}
+
+	if value, ok := mhc.Annotations[machinehealthcheck.UnhealthyTaintsAnnotation]; ok {
+		if err := machinehealthcheck.ValidateUnhealthyTaints(value); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("metadata", "annotations").Key(machinehealthcheck.UnhealthyTaintsAnnotation), value, err.Error()))
+		}
+	}
+
+	if value, ok := mhc.Annotations[machinehealthcheck.MaintenanceWindowsAnnotation]; ok {
+		if err := machinehealthcheck.ValidateMaintenanceWindows(value); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("metadata", "annotations").Key(machinehealthcheck.MaintenanceWindowsAnnotation), value, err.Error()))
+		}
+	}
+
+	if value, ok := mhc.Annotations[machinehealthcheck.RemediationStrategyAnnotation]; ok {
+		if err := machinehealthcheck.ValidateRemediationStrategy(value); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("metadata", "annotations").Key(machinehealthcheck.RemediationStrategyAnnotation), value, err.Error()))
+		}
+	}
+
+	if value, ok := mhc.Annotations[machinehealthcheck.MaxRebootsAnnotation]; ok {
+		if err := machinehealthcheck.ValidateMaxReboots(value); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("metadata", "annotations").Key(machinehealthcheck.MaxRebootsAnnotation), value, err.Error()))
+		}
+	}
+
+	if value, ok := mhc.Annotations[machinehealthcheck.UnhealthyRangeAnnotation]; ok {
+		if err := machinehealthcheck.ValidateUnhealthyRange(value); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("metadata", "annotations").Key(machinehealthcheck.UnhealthyRangeAnnotation), value, err.Error()))
+		}
+	}
+
+	if len(errs) > 0 {
+		return false, warnings, utilerrors.NewAggregate(errs)
+	}
+	return true, warnings, nil
+}
+
+// validateSelectorOverlap rejects selectors that may select the same Machines as another
+// MachineHealthCheck in the namespace, as both would then try to remediate them.
+func (h *machineHealthCheckValidatorHandler) validateSelectorOverlap(ctx context.Context, mhc *machinev1beta1.MachineHealthCheck) ([]error, error) {
+	mhcs := &machinev1beta1.MachineHealthCheckList{}
+	if err := h.client.List(ctx, mhcs, client.InNamespace(mhc.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for i := range mhcs.Items {
+		other := &mhcs.Items[i]
+		if other.Name == mhc.Name || !other.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		overlap, err := selectorsOverlap(mhc.Spec.Selector, other.Spec.Selector)
+		if err != nil {
+			klog.Warningf("Unable to compare the selector of MachineHealthCheck %s/%s: %v", other.Namespace, other.Name, err)
+			continue
+		}
+		if overlap {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "selector"), mhc.Spec.Selector,
+				fmt.Sprintf("selector overlaps with the selector of MachineHealthCheck %s", other.Name)))
+		}
+	}
+	return errs, nil
+}
+
+// selectorsOverlap returns true if there is a set of labels matched by both selectors.
+func selectorsOverlap(a, b metav1.LabelSelector) (bool, error) {
+	selectorA, err := metav1.LabelSelectorAsSelector(&a)
+	if err != nil {
+		return false, err
+	}
+	selectorB, err := metav1.LabelSelectorAsSelector(&b)
+	if err != nil {
+		return false, err
+	}
+
+	requirementsA, _ := selectorA.Requirements()
+	requirementsB, _ := selectorB.Requirements()
+
+	byKey := map[string][]labels.Requirement{}
+	for _, r := range append(requirementsA, requirementsB...) {
+		byKey[r.Key()] = append(byKey[r.Key()], r)
+	}
+
+	for _, requirements := range byKey {
+		if !requirementsSatisfiable(requirements) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// requirementsSatisfiable returns true if a value, or the absence of the label, satisfies
+// all of the requirements on a single label key.
+func requirementsSatisfiable(requirements []labels.Requirement) bool {
+	var allowed sets.String
+	excluded := sets.NewString()
+	mustExist, mustNotExist := false, false
+
+	for _, r := range requirements {
+		switch r.Operator() {
+		case selection.In, selection.Equals, selection.DoubleEquals:
+			mustExist = true
+			if allowed == nil {
+				allowed = sets.NewString(r.Values().List()...)
+			} else {
+				allowed = allowed.Intersection(r.Values())
+			}
+		case selection.NotIn, selection.NotEquals:
+			excluded.Insert(r.Values().List()...)
+		case selection.Exists:
+			mustExist = true
+		case selection.DoesNotExist:
+			mustNotExist = true
+		default:
+			// Label selectors do not support other operators, assume they may overlap.
+			return true
+		}
+	}
+
+	if mustExist && mustNotExist {
+		return false
+	}
+	if allowed != nil {
+		return allowed.Difference(excluded).Len() > 0
+	}
+	// Any value not excluded, or the absence of the label, satisfies the requirements.
+	return true
+}
+
+// validateMaxUnhealthy checks that maxUnhealthy is a non-negative number or percentage.
+func validateMaxUnhealthy(maxUnhealthy *intstr.IntOrString, fldPath *field.Path) []error {
+	value := maxUnhealthy.IntValue()
+	if maxUnhealthy.Type == intstr.String {
+		var err error
+		value, err = strconv.Atoi(strings.TrimSuffix(maxUnhealthy.StrVal, "%"))
+		if err != nil {
+			return []error{field.Invalid(fldPath, maxUnhealthy.String(), "must be an integer or a percentage")}
+		}
+	}
+
+	if value < 0 {
+		return []error{field.Invalid(fldPath, maxUnhealthy.String(), "must not be negative")}
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package webhooks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestValidateMachineHealthCheck(t *testing.T) {
+	existing := &machinev1beta1.MachineHealthCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "workers", Namespace: "openshift-machine-api"},
+		Spec: machinev1beta1.MachineHealthCheckSpec{
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{"machine.openshift.io/cluster-api-machine-role": "worker"}},
+		},
+	}
+
+	workerSelector := metav1.LabelSelector{MatchLabels: map[string]string{"machine.openshift.io/cluster-api-machine-role": "worker"}}
+	infraSelector := metav1.LabelSelector{MatchLabels: map[string]string{"machine.openshift.io/cluster-api-machine-role": "infra"}}
+
+	testCases := []struct {
+		name          string
+		mhcName       string
+		annotations   map[string]string
+		spec          machinev1beta1.MachineHealthCheckSpec
+		oldSpec       *machinev1beta1.MachineHealthCheckSpec
+		expectedError string
+	}{
+		{
+			name: "with a valid MachineHealthCheck",
+			spec: machinev1beta1.MachineHealthCheckSpec{
+				Selector:           infraSelector,
+				MaxUnhealthy:       &intstr.IntOrString{Type: intstr.String, StrVal: "40%"},
+				NodeStartupTimeout: &metav1.Duration{Duration: 10 * time.Minute},
+			},
+		},
+		{
+			name:          "with an empty selector",
+			spec:          machinev1beta1.MachineHealthCheckSpec{},
+			expectedError: "spec.selector: Required value: selector must not be empty, it would match all machines",
+		},
+		{
+			name: "with an invalid selector",
+			spec: machinev1beta1.MachineHealthCheckSpec{
+				Selector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "foo", Operator: "Bogus"}}},
+			},
+			expectedError: `"Bogus" is not a valid label selector operator`,
+		},
+		{
+			name:          "with a selector overlapping an existing MachineHealthCheck",
+			spec:          machinev1beta1.MachineHealthCheckSpec{Selector: workerSelector},
+			expectedError: "selector overlaps with the selector of MachineHealthCheck workers",
+		},
+		{
+			name:    "when updating the MachineHealthCheck with the overlapping selector",
+			mhcName: "workers",
+			spec:    machinev1beta1.MachineHealthCheckSpec{Selector: workerSelector},
+			oldSpec: &machinev1beta1.MachineHealthCheckSpec{Selector: infraSelector},
+		},
+		{
+			name:    "when updating a MachineHealthCheck without changing its overlapping selector",
+			spec:    machinev1beta1.MachineHealthCheckSpec{Selector: workerSelector, MaxUnhealthy: &intstr.IntOrString{Type: intstr.Int, IntVal: 1}},
+			oldSpec: &machinev1beta1.MachineHealthCheckSpec{Selector: workerSelector},
+		},
+		{
+			name: "with a negative maxUnhealthy",
+			spec: machinev1beta1.MachineHealthCheckSpec{
+				Selector:     infraSelector,
+				MaxUnhealthy: &intstr.IntOrString{Type: intstr.Int, IntVal: -1},
+			},
+			expectedError: "spec.maxUnhealthy: Invalid value: \"-1\": must not be negative",
+		},
+		{
+			name: "with a negative maxUnhealthy percentage",
+			spec: machinev1beta1.MachineHealthCheckSpec{
+				Selector:     infraSelector,
+				MaxUnhealthy: &intstr.IntOrString{Type: intstr.String, StrVal: "-10%"},
+			},
+			expectedError: "spec.maxUnhealthy: Invalid value: \"-10%\": must not be negative",
+		},
+		{
+			name: "with an invalid maxUnhealthy",
+			spec: machinev1beta1.MachineHealthCheckSpec{
+				Selector:     infraSelector,
+				MaxUnhealthy: &intstr.IntOrString{Type: intstr.String, StrVal: "most"},
+			},
+			expectedError: "spec.maxUnhealthy: Invalid value: \"most\": must be an integer or a percentage",
+		},
+		{
+			name: "with a short nodeStartupTimeout",
+			spec: machinev1beta1.MachineHealthCheckSpec{
+				Selector:           infraSelector,
+				NodeStartupTimeout: &metav1.Duration{Duration: 10 * time.Second},
+			},
+			expectedError: "spec.nodeStartupTimeout: Invalid value: \"10s\": must be at least 30s, or 0 to disable it",
+		},
+		{
+			name: "with a disabled nodeStartupTimeout",
+			spec: machinev1beta1.MachineHealthCheckSpec{
+				Selector:           infraSelector,
+				NodeStartupTimeout: &metav1.Duration{},
+			},
+		},
+		{
+			name:        "with valid unhealthy taints",
+			annotations: map[string]string{"machine.openshift.io/unhealthy-taints": `[{"key": "KernelDeadlock", "effect": "NoExecute", "timeout": "5m"}]`},
+			spec:        machinev1beta1.MachineHealthCheckSpec{Selector: infraSelector},
+		},
+		{
+			name:          "with unhealthy taints without key",
+			annotations:   map[string]string{"machine.openshift.io/unhealthy-taints": `[{"effect": "NoExecute", "timeout": "5m"}]`},
+			spec:          machinev1beta1.MachineHealthCheckSpec{Selector: infraSelector},
+			expectedError: "taint 0: key must be set",
+		},
+		{
+			name:        "with valid maintenance windows",
+			annotations: map[string]string{"machine.openshift.io/maintenance-windows": `[{"schedule": "0 22 * * 1-5", "duration": "6h", "timeZone": "Europe/Prague"}]`},
+			spec:        machinev1beta1.MachineHealthCheckSpec{Selector: infraSelector},
+		},
+		{
+			name:          "with maintenance windows with an invalid schedule",
+			annotations:   map[string]string{"machine.openshift.io/maintenance-windows": `[{"schedule": "never", "duration": "1h"}]`},
+			spec:          machinev1beta1.MachineHealthCheckSpec{Selector: infraSelector},
+			expectedError: `window 0: invalid cron expression "never"`,
+		},
+		{
+			name:          "with maintenance windows with an unknown time zone",
+			annotations:   map[string]string{"machine.openshift.io/maintenance-windows": `[{"schedule": "0 22 * * *", "duration": "1h", "timeZone": "Mars/Olympus"}]`},
+			spec:          machinev1beta1.MachineHealthCheckSpec{Selector: infraSelector},
+			expectedError: "window 0: unknown time zone Mars/Olympus",
+		},
+		{
+			name:        "with the Reboot remediation strategy",
+			annotations: map[string]string{"machine.openshift.io/remediation-strategy": "Reboot", "machine.openshift.io/max-reboots": "2"},
+			spec:        machinev1beta1.MachineHealthCheckSpec{Selector: infraSelector},
+		},
+		{
+			name:          "with an unknown remediation strategy",
+			annotations:   map[string]string{"machine.openshift.io/remediation-strategy": "Restart"},
+			spec:          machinev1beta1.MachineHealthCheckSpec{Selector: infraSelector},
+			expectedError: `must be "external-baremetal" or "Reboot"`,
+		},
+		{
+			name:          "with a negative max reboots",
+			annotations:   map[string]string{"machine.openshift.io/remediation-strategy": "Reboot", "machine.openshift.io/max-reboots": "-1"},
+			spec:          machinev1beta1.MachineHealthCheckSpec{Selector: infraSelector},
+			expectedError: "must be a non-negative integer",
+		},
+		{
+			name:        "with a valid unhealthy range",
+			annotations: map[string]string{"machine.openshift.io/unhealthy-range": "[1-5]"},
+			spec:        machinev1beta1.MachineHealthCheckSpec{Selector: infraSelector},
+		},
+		{
+			name:          "with an unhealthy range which is not a range",
+			annotations:   map[string]string{"machine.openshift.io/unhealthy-range": "1-5"},
+			spec:          machinev1beta1.MachineHealthCheckSpec{Selector: infraSelector},
+			expectedError: "expected a range of the form [min-max]",
+		},
+		{
+			name:          "with an unhealthy range whose min is greater than its max",
+			annotations:   map[string]string{"machine.openshift.io/unhealthy-range": "[5-1]"},
+			spec:          machinev1beta1.MachineHealthCheckSpec{Selector: infraSelector},
+			expectedError: "min must not be greater than max",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(existing.DeepCopy()).Build()
+			h := NewMachineHealthCheckValidator(c)
+
+			name := tc.mhcName
+			if name == "" {
+				name = "test"
+			}
+			mhc := &machinev1beta1.MachineHealthCheck{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: existing.Namespace, Annotations: tc.annotations},
+				Spec:       tc.spec,
+			}
+			var oldMHC *machinev1beta1.MachineHealthCheck
+			if tc.oldSpec != nil {
+				oldMHC = mhc.DeepCopy()
+				oldMHC.Spec = *tc.oldSpec
+			}
+
+			ok, _, errs := h.validateMachineHealthCheck(context.Background(), mhc, oldMHC)
+			if tc.expectedError == "" {
+				g.Expect(ok).To(BeTrue())
+				g.Expect(errs).To(BeNil())
+				return
+			}
+			g.Expect(ok).To(BeFalse())
+			g.Expect(errs).To(MatchError(ContainSubstring(tc.expectedError)))
+		})
+	}
+}
+
+func TestSelectorsOverlap(t *testing.T) {
+	testCases := []struct {
+		name     string
+		a        metav1.LabelSelector
+		b        metav1.LabelSelector
+		expected bool
+	}{
+		{
+			name:     "with equal labels",
+			a:        metav1.LabelSelector{MatchLabels: map[string]string{"role": "worker"}},
+			b:        metav1.LabelSelector{MatchLabels: map[string]string{"role": "worker"}},
+			expected: true,
+		},
+		{
+			name:     "with conflicting labels",
+			a:        metav1.LabelSelector{MatchLabels: map[string]string{"role": "worker"}},
+			b:        metav1.LabelSelector{MatchLabels: map[string]string{"role": "infra"}},
+			expected: false,
+		},
+		{
+			name:     "with different label keys",
+			a:        metav1.LabelSelector{MatchLabels: map[string]string{"role": "worker"}},
+			b:        metav1.LabelSelector{MatchLabels: map[string]string{"zone": "a"}},
+			expected: true,
+		},
+		{
+			name: "with intersecting In expressions",
+			a: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "zone", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+			}},
+			b: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "zone", Operator: metav1.LabelSelectorOpIn, Values: []string{"b", "c"}},
+			}},
+			expected: true,
+		},
+		{
+			name: "with an In expression excluded by a NotIn expression",
+			a: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "zone", Operator: metav1.LabelSelectorOpIn, Values: []string{"a"}},
+			}},
+			b: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "zone", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"a"}},
+			}},
+			expected: false,
+		},
+		{
+			name: "with Exists and DoesNotExist expressions",
+			a: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "spot", Operator: metav1.LabelSelectorOpExists},
+			}},
+			b: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "spot", Operator: metav1.LabelSelectorOpDoesNotExist},
+			}},
+			expected: false,
+		},
+		{
+			name: "with NotIn and DoesNotExist expressions",
+			a: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "spot", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"true"}},
+			}},
+			b: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "spot", Operator: metav1.LabelSelectorOpDoesNotExist},
+			}},
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			overlap, err := selectorsOverlap(tc.a, tc.b)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(overlap).To(Equal(tc.expected))
+
+			overlap, err = selectorsOverlap(tc.b, tc.a)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(overlap).To(Equal(tc.expected))
+		})
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"strings"
 
 	osconfigv1 "github.com/openshift/api/config/v1"
 	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -17,8 +19,20 @@ import (
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/machine-api-operator/pkg/controller/machinehealthcheck"
+	"github.com/openshift/machine-api-operator/pkg/controller/machineset"
+	"github.com/openshift/machine-api-operator/pkg/util/capacity"
+	"github.com/openshift/machine-api-operator/pkg/util/naming"
 )
 
+// AllowSelectorChangeAnnotation set to "true" on a MachineSet allows to change its selector, which is otherwise
+// immutable. It is a break-glass setting: the Machines which no longer match the new selector are orphaned.
+const AllowSelectorChangeAnnotation = "machine.openshift.io/allow-selector-change"
+
+// maxNamedMachines is the number of Machines for which the names given by a naming template are validated.
+const maxNamedMachines = 999
+
 // machineSetValidatorHandler validates MachineSet API resources.
 // implements type Handler interface.
 // https://godoc.org/github.com/kubernetes-sigs/controller-runtime/pkg/webhook/admission#Handler
@@ -104,10 +118,15 @@ func (h *machineSetValidatorHandler) Handle(ctx context.Context, req admission.R
 
 	ok, warnings, errs := h.validateMachineSet(ms, oldMS)
 	if !ok {
-		return admission.Denied(errs.Error()).WithWarnings(warnings...)
+		return denied(errs, warnings)
 	}
 
-	return admission.Allowed("MachineSet valid").WithWarnings(warnings...)
+	var instanceTypeChange string
+	if oldMS != nil {
+		instanceTypeChange = auditInstanceTypeChange(h.platformStatus,
+			&machinev1beta1.Machine{Spec: ms.Spec.Template.Spec}, &machinev1beta1.Machine{Spec: oldMS.Spec.Template.Spec})
+	}
+	return audited(admission.Allowed("MachineSet valid").WithWarnings(warnings...), instanceTypeChange)
 }
 
 // Handle handles HTTP requests for admission webhook servers.
@@ -122,14 +141,14 @@ func (h *machineSetDefaulterHandler) Handle(ctx context.Context, req admission.R
 
 	ok, warnings, errs := h.defaultMachineSet(ms)
 	if !ok {
-		return admission.Denied(errs.Error()).WithWarnings(warnings...)
+		return denied(errs, warnings)
 	}
 
 	marshaledMachineSet, err := json.Marshal(ms)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err).WithWarnings(warnings...)
 	}
-	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledMachineSet).WithWarnings(warnings...)
+	return audited(admission.PatchResponseFromRaw(req.Object.Raw, marshaledMachineSet).WithWarnings(warnings...), "")
 }
 
 func (h *machineSetValidatorHandler) validateMachineSet(ms, oldMS *machinev1beta1.MachineSet) (bool, []string, utilerrors.Aggregate) {
@@ -142,10 +161,38 @@ func (h *machineSetValidatorHandler) validateMachineSet(ms, oldMS *machinev1beta
 		},
 		Spec: ms.Spec.Template.Spec,
 	}
-	ok, warnings, err := h.webhookOperations(m, h.admissionConfig)
+	var oldProviderSpec *machinev1beta1.ProviderSpec
+	if oldMS != nil {
+		oldProviderSpec = &oldMS.Spec.Template.Spec.ProviderSpec
+	}
+	ok, warnings, err := h.webhookOperations(m, h.admissionConfig.forProviderSpecUpdate(&m.Spec.ProviderSpec, oldProviderSpec))
 	if !ok {
 		errs = append(errs, err.Errors()...)
 	}
+	warnings = append(warnings, selectorChangeWarnings(ms, oldMS)...)
+	if h.platformStatus != nil {
+		var oldM *machinev1beta1.Machine
+		if oldMS != nil {
+			oldM = &machinev1beta1.Machine{Spec: oldMS.Spec.Template.Spec}
+		}
+		warnings = append(warnings, deprecatedProviderSpecWarnings(h.platformStatus.Type, m, oldM)...)
+		if h.allowHostAffinity {
+			hostWarnings, hostErrs := validateHostAffinity(h.platformStatus.Type, ms.Spec.Template.Annotations, &m.Spec.ProviderSpec, field.NewPath("spec", "template", "metadata", "annotations"))
+			warnings = append(warnings, hostWarnings...)
+			errs = append(errs, hostErrs...)
+			hostGroupWarnings, hostGroupErrs := validateHostGroups(h.platformStatus.Type, ms)
+			warnings = append(warnings, hostGroupWarnings...)
+			errs = append(errs, hostGroupErrs...)
+		}
+	}
+	if !h.allowHostAffinity {
+		var oldAnnotations, oldTemplateAnnotations map[string]string
+		if oldMS != nil {
+			oldAnnotations, oldTemplateAnnotations = oldMS.Annotations, oldMS.Spec.Template.Annotations
+		}
+		errs = append(errs, rejectHostAffinity(ms.Annotations, oldAnnotations, field.NewPath("metadata", "annotations"))...)
+		errs = append(errs, rejectHostAffinity(ms.Spec.Template.Annotations, oldTemplateAnnotations, field.NewPath("spec", "template", "metadata", "annotations"))...)
+	}
 
 	if len(errs) > 0 {
 		return false, warnings, utilerrors.NewAggregate(errs)
@@ -163,14 +210,50 @@ func (h *machineSetDefaulterHandler) defaultMachineSet(ms *machinev1beta1.Machin
 
 	// Restore the defaulted template
 	ms.Spec.Template.Spec = m.Spec
+	defaultCapacityAnnotations(ms)
 	return true, warnings, nil
 }
 
+// defaultCapacityAnnotations sets the scale-from-zero annotations used by the cluster autoscaler from
+// the known capacity of the instance type of the MachineSet. Annotations set by the user are preserved.
+func defaultCapacityAnnotations(ms *machinev1beta1.MachineSet) {
+	c, err := capacity.FromProviderSpec(ms.Spec.Template.Spec.ProviderSpec.Value)
+	if err != nil || c == nil {
+		klog.V(4).Infof("%s: unable to determine capacity from providerSpec, skipping scale from zero annotations: %v", ms.GetName(), err)
+		return
+	}
+
+	if ms.Annotations == nil {
+		ms.Annotations = map[string]string{}
+	}
+	for k, v := range c.Annotations() {
+		if _, ok := ms.Annotations[k]; !ok {
+			ms.Annotations[k] = v
+		}
+	}
+
+	archLabel := c.ArchLabel()
+	if archLabel == "" {
+		return
+	}
+	nodeLabels := ms.Annotations[capacity.LabelsKey]
+	if nodeLabels == "" {
+		ms.Annotations[capacity.LabelsKey] = archLabel
+		return
+	}
+	for _, label := range strings.Split(nodeLabels, ",") {
+		if strings.HasPrefix(strings.TrimSpace(label), corev1.LabelArchStable+"=") {
+			return
+		}
+	}
+	ms.Annotations[capacity.LabelsKey] = nodeLabels + "," + archLabel
+}
+
 // validateMachineSetSpec is used to validate any changes to the MachineSet spec outside of
 // the providerSpec. Eg it can be used to verify changes to the selector.
 func validateMachineSetSpec(ms, oldMS *machinev1beta1.MachineSet) []error {
 	var errs []error
-	if oldMS != nil && !reflect.DeepEqual(ms.Spec.Selector, oldMS.Spec.Selector) {
+	if isSelectorChanged(ms, oldMS) && ms.Annotations[AllowSelectorChangeAnnotation] != "true" {
 		errs = append(errs, field.Forbidden(field.NewPath("spec", "selector"), "selector is immutable"))
 	}
 
@@ -182,5 +265,79 @@ func validateMachineSetSpec(ms, oldMS *machinev1beta1.MachineSet) []error {
 		errs = append(errs, field.Invalid(field.NewPath("spec", "template", "metadata", "labels"), ms.Spec.Template.Labels, "`selector` does not match template `labels`"))
 	}
 
+	domains, err := machineset.ParseFailureDomains(ms.Annotations[machineset.FailureDomainsAnnotation])
+	if err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "annotations").Key(machineset.FailureDomainsAnnotation), ms.Annotations[machineset.FailureDomainsAnnotation], err.Error()))
+	}
+
+	if text, ok := ms.Annotations[naming.MachineNameTemplateAnnotation]; ok {
+		if err := naming.Validate(text, ms.Name, ms.Namespace, failureDomainZones(domains), maxNamedMachines); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("metadata", "annotations", naming.MachineNameTemplateAnnotation), text, err.Error()))
+		}
+	}
+
+	errs = append(errs, validateNodeConfigAnnotations(ms.Annotations, field.NewPath("metadata", "annotations"))...)
+	errs = append(errs, validateNodeStartupTimeoutAnnotation(ms.Spec.Template.Annotations, field.NewPath("spec", "template", "metadata", "annotations"))...)
+
+	if value, ok := ms.Annotations[machineset.MachineCreationRateAnnotation]; ok {
+		if _, err := machineset.ParseMachineCreationRate(value); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("metadata", "annotations").Key(machineset.MachineCreationRateAnnotation), value, err.Error()))
+		}
+	}
+
+	if value, ok := ms.Annotations[machineset.ProvisioningTimeoutAnnotation]; ok {
+		if _, err := machineset.ParseProvisioningTimeout(value); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("metadata", "annotations").Key(machineset.ProvisioningTimeoutAnnotation), value, err.Error()))
+		}
+	}
+
+	if value, ok := ms.Annotations[machineset.RolloutPartitionAnnotation]; ok {
+		if _, err := machineset.ParseRolloutPartition(value); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("metadata", "annotations").Key(machineset.RolloutPartitionAnnotation), value, err.Error()))
+		}
+	}
+
+	if value, ok := ms.Annotations[machineset.StandbyReplicasAnnotation]; ok {
+		if _, err := machineset.ParseStandbyReplicas(value); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("metadata", "annotations").Key(machineset.StandbyReplicasAnnotation), value, err.Error()))
+		}
+	}
+	if value, ok := ms.Annotations[machineset.StandbyModeAnnotation]; ok {
+		if _, err := machineset.ParseStandbyMode(value); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("metadata", "annotations").Key(machineset.StandbyModeAnnotation), value, err.Error()))
+		}
+	}
+
+	if value, ok := ms.Annotations[machinehealthcheck.MachineHealthCheckOverridesAnnotation]; ok {
+		if err := machinehealthcheck.ValidateMachineHealthCheckOverrides(value); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("metadata", "annotations").Key(machinehealthcheck.MachineHealthCheckOverridesAnnotation), value, err.Error()))
+		}
+	}
+
 	return errs
 }
+
+// isSelectorChanged returns true if the selector of the MachineSet is changed by the update.
+func isSelectorChanged(ms, oldMS *machinev1beta1.MachineSet) bool {
+	return oldMS != nil && !reflect.DeepEqual(ms.Spec.Selector, oldMS.Spec.Selector)
+}
+
+// selectorChangeWarnings returns an admission warning when the selector of the MachineSet is changed
+// with the AllowSelectorChangeAnnotation, telling that the Machines no longer matching it are orphaned.
+func selectorChangeWarnings(ms, oldMS *machinev1beta1.MachineSet) []string {
+	if !isSelectorChanged(ms, oldMS) || ms.Annotations[AllowSelectorChangeAnnotation] != "true" {
+		return nil
+	}
+	return []string{fmt.Sprintf("spec.selector: changed with the %s annotation, the machines which no longer match the selector are orphaned and no longer managed by the MachineSet",
+		AllowSelectorChangeAnnotation)}
+}
+
+// failureDomainZones returns the zones of the failure domains of the MachineSet, the names of its Machines
+// are validated in each of them.
+func failureDomainZones(domains []machineset.FailureDomain) []string {
+	var zones []string
+	for _, domain := range domains {
+		zones = append(zones, domain.Zone)
+	}
+	return zones
+}
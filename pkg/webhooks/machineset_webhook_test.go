@@ -2,6 +2,7 @@ package webhooks
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -282,7 +283,7 @@ func TestMachineSetCreation(t *testing.T) {
 				Object: &machinev1.PowerVSMachineProviderConfig{
 					ServiceInstance: machinev1.PowerVSResource{
 						Type: machinev1.PowerVSResourceTypeID,
-						ID:   pointer.String("TestServiceInstanceID"),
+						ID:   pointer.String("a8b3e9d2-3f51-4c6e-9a7d-1b2c3d4e5f60"),
 					},
 					KeyPairName: "TestKeyPair",
 					Image: machinev1.PowerVSResource{
@@ -420,7 +421,7 @@ func TestMachineSetCreation(t *testing.T) {
 					},
 					Network: machinev1.PowerVSResource{
 						Type: machinev1.PowerVSResourceTypeID,
-						ID:   pointer.String("TestNetworkID"),
+						ID:   pointer.String("6d2f4e1a-8b3c-4d5e-9f60-7a8b9c0d1e2f"),
 					},
 				},
 			},
@@ -1251,3 +1252,447 @@ func TestMachineSetUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaultMachineSetCapacityAnnotations(t *testing.T) {
+	testCases := []struct {
+		name                string
+		instanceType        string
+		annotations         map[string]string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:         "with the default instance type",
+			instanceType: "",
+			expectedAnnotations: map[string]string{
+				"machine.openshift.io/vCPU":                        "2",
+				"machine.openshift.io/memoryMb":                    "8192",
+				"machine.openshift.io/GPU":                         "0",
+				"capacity.cluster-autoscaler.kubernetes.io/labels": "kubernetes.io/arch=amd64",
+			},
+		},
+		{
+			name:         "with an arm64 instance type",
+			instanceType: "m6g.xlarge",
+			expectedAnnotations: map[string]string{
+				"machine.openshift.io/vCPU":                        "4",
+				"machine.openshift.io/memoryMb":                    "16384",
+				"machine.openshift.io/GPU":                         "0",
+				"capacity.cluster-autoscaler.kubernetes.io/labels": "kubernetes.io/arch=arm64",
+			},
+		},
+		{
+			name:         "with annotations set by the user",
+			instanceType: "m6g.xlarge",
+			annotations: map[string]string{
+				"machine.openshift.io/vCPU":                        "3",
+				"capacity.cluster-autoscaler.kubernetes.io/labels": "node-role.kubernetes.io/infra=",
+			},
+			expectedAnnotations: map[string]string{
+				"machine.openshift.io/vCPU":                        "3",
+				"machine.openshift.io/memoryMb":                    "16384",
+				"machine.openshift.io/GPU":                         "0",
+				"capacity.cluster-autoscaler.kubernetes.io/labels": "node-role.kubernetes.io/infra=,kubernetes.io/arch=arm64",
+			},
+		},
+		{
+			name:         "with the architecture set by the user",
+			instanceType: "m6g.xlarge",
+			annotations: map[string]string{
+				"capacity.cluster-autoscaler.kubernetes.io/labels": "kubernetes.io/arch=amd64",
+			},
+			expectedAnnotations: map[string]string{
+				"machine.openshift.io/vCPU":                        "4",
+				"machine.openshift.io/memoryMb":                    "16384",
+				"machine.openshift.io/GPU":                         "0",
+				"capacity.cluster-autoscaler.kubernetes.io/labels": "kubernetes.io/arch=amd64",
+			},
+		},
+		{
+			name:                "with an unknown instance type",
+			instanceType:        "unknown.large",
+			annotations:         map[string]string{"foo": "bar"},
+			expectedAnnotations: map[string]string{"foo": "bar"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			providerSpec := &machinev1beta1.AWSMachineProviderConfig{
+				TypeMeta:     metav1.TypeMeta{Kind: "AWSMachineProviderConfig"},
+				InstanceType: tc.instanceType,
+			}
+			ms := &machinev1beta1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "machineset",
+					Annotations: tc.annotations,
+				},
+				Spec: machinev1beta1.MachineSetSpec{
+					Template: machinev1beta1.MachineTemplateSpec{
+						Spec: machinev1beta1.MachineSpec{
+							ProviderSpec: machinev1beta1.ProviderSpec{
+								Value: &runtime.RawExtension{Object: providerSpec},
+							},
+						},
+					},
+				},
+			}
+			raw, err := json.Marshal(providerSpec)
+			g.Expect(err).ToNot(HaveOccurred())
+			ms.Spec.Template.Spec.ProviderSpec.Value.Raw = raw
+
+			machineSetDefaulter := createMachineSetDefaulter(&osconfigv1.PlatformStatus{
+				Type: osconfigv1.AWSPlatformType,
+				AWS:  &osconfigv1.AWSPlatformStatus{Region: "region"},
+			}, "clusterID")
+			ok, _, errs := machineSetDefaulter.defaultMachineSet(ms)
+			g.Expect(ok).To(BeTrue(), "%v", errs)
+			g.Expect(ms.Annotations).To(Equal(tc.expectedAnnotations))
+		})
+	}
+}
+
+func TestValidateMachineSetNameTemplate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		annotations   map[string]string
+		expectedError string
+	}{
+		{
+			name: "without a naming template",
+		},
+		{
+			name: "with a valid naming template",
+			annotations: map[string]string{
+				"machine.openshift.io/machine-name-template": "{{ .MachineSet }}-{{ .Zone }}-{{ .Ordinal }}",
+				"machine.openshift.io/failure-domains":       "us-east-1a,us-east-1b/subnet-b",
+			},
+		},
+		{
+			name: "with a naming template without the ordinal",
+			annotations: map[string]string{
+				"machine.openshift.io/machine-name-template": "{{ .MachineSet }}-{{ .Zone }}",
+			},
+			expectedError: "must use {{ .Ordinal }} to give each machine a distinct name",
+		},
+		{
+			name: "with a naming template giving names which are too long",
+			annotations: map[string]string{
+				"machine.openshift.io/machine-name-template": "{{ .MachineSet }}-{{ .Namespace }}-{{ .Zone }}-{{ .Ordinal }}",
+				"machine.openshift.io/failure-domains":       "us-east-1a",
+			},
+			expectedError: `invalid machine name "worker-openshift-machine-api-namespace-with-a-long-name-us-east-1a-999": must be no more than 63 characters`,
+		},
+		{
+			name: "with invalid failure domains",
+			annotations: map[string]string{
+				"machine.openshift.io/failure-domains": "us-east-1a,/subnet-b",
+			},
+			expectedError: "failure domains must be of the form zone or zone/subnet",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1beta1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "worker",
+					Namespace:   "openshift-machine-api-namespace-with-a-long-name",
+					Annotations: tc.annotations,
+				},
+			}
+			errs := validateMachineSetSpec(ms, nil)
+			if tc.expectedError != "" {
+				g.Expect(errs).To(ConsistOf(MatchError(ContainSubstring(tc.expectedError))))
+			} else {
+				g.Expect(errs).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestValidateMachineSetSelectorChange(t *testing.T) {
+	newMachineSet := func(selector map[string]string, annotations map[string]string) *machinev1beta1.MachineSet {
+		return &machinev1beta1.MachineSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "openshift-machine-api", Annotations: annotations},
+			Spec: machinev1beta1.MachineSetSpec{
+				Selector: metav1.LabelSelector{MatchLabels: selector},
+				Template: machinev1beta1.MachineTemplateSpec{
+					ObjectMeta: machinev1beta1.ObjectMeta{Labels: map[string]string{"machineset": "worker", "pool": "blue"}},
+				},
+			},
+		}
+	}
+	oldMS := newMachineSet(map[string]string{"machineset": "worker"}, nil)
+	allowed := map[string]string{AllowSelectorChangeAnnotation: "true"}
+
+	testCases := []struct {
+		name             string
+		ms               *machinev1beta1.MachineSet
+		expectedErrors   []string
+		expectedWarnings []string
+	}{
+		{
+			name: "with an unchanged selector",
+			ms:   newMachineSet(map[string]string{"machineset": "worker"}, nil),
+		},
+		{
+			name:           "with a changed selector",
+			ms:             newMachineSet(map[string]string{"pool": "blue"}, nil),
+			expectedErrors: []string{"spec.selector: Forbidden: selector is immutable"},
+		},
+		{
+			name: "with a changed selector allowed by the annotation",
+			ms:   newMachineSet(map[string]string{"pool": "blue"}, allowed),
+			expectedWarnings: []string{
+				"spec.selector: changed with the machine.openshift.io/allow-selector-change annotation, the machines which no longer match the selector are orphaned and no longer managed by the MachineSet",
+			},
+		},
+		{
+			name: "with a changed selector allowed by the annotation, not matching the template labels",
+			ms:   newMachineSet(map[string]string{"pool": "green"}, allowed),
+			expectedErrors: []string{
+				"spec.template.metadata.labels: Invalid value: map[string]string{\"machineset\":\"worker\", \"pool\":\"blue\"}: `selector` does not match template `labels`",
+			},
+			expectedWarnings: []string{
+				"spec.selector: changed with the machine.openshift.io/allow-selector-change annotation, the machines which no longer match the selector are orphaned and no longer managed by the MachineSet",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			errs := validateMachineSetSpec(tc.ms, oldMS)
+			g.Expect(errs).To(HaveLen(len(tc.expectedErrors)))
+			for i, err := range errs {
+				g.Expect(err.Error()).To(Equal(tc.expectedErrors[i]))
+			}
+			g.Expect(selectorChangeWarnings(tc.ms, oldMS)).To(Equal(tc.expectedWarnings))
+		})
+	}
+}
+
+func TestValidateMachineSetMachineHealthCheckOverrides(t *testing.T) {
+	testCases := []struct {
+		name          string
+		overrides     string
+		expectedError string
+	}{
+		{
+			name:      "with valid overrides",
+			overrides: `{"nodeStartupTimeout": "30m", "unhealthyConditions": [{"type": "Ready", "status": "Unknown", "timeout": "15m"}]}`,
+		},
+		{
+			name:          "with invalid JSON",
+			overrides:     `30m`,
+			expectedError: "invalid character 'm' after top-level value",
+		},
+		{
+			name:          "with a negative node startup timeout",
+			overrides:     `{"nodeStartupTimeout": "-30m"}`,
+			expectedError: "nodeStartupTimeout must not be negative",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1beta1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "worker",
+					Namespace:   "openshift-machine-api",
+					Annotations: map[string]string{"machine.openshift.io/machine-health-check-overrides": tc.overrides},
+				},
+			}
+			errs := validateMachineSetSpec(ms, nil)
+			if tc.expectedError != "" {
+				g.Expect(errs).To(ConsistOf(MatchError(ContainSubstring(tc.expectedError))))
+			} else {
+				g.Expect(errs).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestValidateMachineSetCreationRate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		rate          string
+		expectedError string
+	}{
+		{
+			name: "with a valid creation rate",
+			rate: "20",
+		},
+		{
+			name:          "with a zero creation rate",
+			rate:          "0",
+			expectedError: "must be a positive integer",
+		},
+		{
+			name:          "with a creation rate which is not a number",
+			rate:          "fast",
+			expectedError: "must be a positive integer",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1beta1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "worker",
+					Namespace:   "openshift-machine-api",
+					Annotations: map[string]string{"machine.openshift.io/machine-creation-rate": tc.rate},
+				},
+			}
+			errs := validateMachineSetSpec(ms, nil)
+			if tc.expectedError != "" {
+				g.Expect(errs).To(ConsistOf(MatchError(ContainSubstring(tc.expectedError))))
+			} else {
+				g.Expect(errs).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestValidateMachineSetProvisioningTimeout(t *testing.T) {
+	testCases := []struct {
+		name          string
+		timeout       string
+		expectedError string
+	}{
+		{
+			name:    "with a valid provisioning timeout",
+			timeout: "30m",
+		},
+		{
+			name:          "with a negative provisioning timeout",
+			timeout:       "-30m",
+			expectedError: "must be greater than zero",
+		},
+		{
+			name:          "with a provisioning timeout which is not a duration",
+			timeout:       "soon",
+			expectedError: "invalid duration",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1beta1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "worker",
+					Namespace:   "openshift-machine-api",
+					Annotations: map[string]string{"machine.openshift.io/provisioning-timeout": tc.timeout},
+				},
+			}
+			errs := validateMachineSetSpec(ms, nil)
+			if tc.expectedError != "" {
+				g.Expect(errs).To(ConsistOf(MatchError(ContainSubstring(tc.expectedError))))
+			} else {
+				g.Expect(errs).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestValidateMachineSetRolloutPartition(t *testing.T) {
+	testCases := []struct {
+		name          string
+		partition     string
+		expectedError string
+	}{
+		{
+			name:      "with a valid rollout partition",
+			partition: "0",
+		},
+		{
+			name:          "with a negative rollout partition",
+			partition:     "-1",
+			expectedError: "must be a non-negative integer",
+		},
+		{
+			name:          "with a rollout partition which is not a number",
+			partition:     "half",
+			expectedError: "must be a non-negative integer",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1beta1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "worker",
+					Namespace:   "openshift-machine-api",
+					Annotations: map[string]string{"machine.openshift.io/rollout-partition": tc.partition},
+				},
+			}
+			errs := validateMachineSetSpec(ms, nil)
+			if tc.expectedError != "" {
+				g.Expect(errs).To(ConsistOf(MatchError(ContainSubstring(tc.expectedError))))
+			} else {
+				g.Expect(errs).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestValidateMachineSetStandbyReplicas(t *testing.T) {
+	testCases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedErrors []string
+	}{
+		{
+			name: "with standby machines powered off",
+			annotations: map[string]string{
+				"machine.openshift.io/standby-replicas": "2",
+				"machine.openshift.io/standby-mode":     "PoweredOff",
+			},
+		},
+		{
+			name:           "with a negative number of standby machines",
+			annotations:    map[string]string{"machine.openshift.io/standby-replicas": "-2"},
+			expectedErrors: []string{"must be a non-negative integer"},
+		},
+		{
+			name: "with an invalid number of standby machines and standby mode",
+			annotations: map[string]string{
+				"machine.openshift.io/standby-replicas": "two",
+				"machine.openshift.io/standby-mode":     "Hibernated",
+			},
+			expectedErrors: []string{"must be a non-negative integer", "must be either Cordoned or PoweredOff"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ms := &machinev1beta1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "worker",
+					Namespace:   "openshift-machine-api",
+					Annotations: tc.annotations,
+				},
+			}
+			errs := validateMachineSetSpec(ms, nil)
+			g.Expect(errs).To(HaveLen(len(tc.expectedErrors)))
+			for i, expectedError := range tc.expectedErrors {
+				g.Expect(errs[i]).To(MatchError(ContainSubstring(expectedError)))
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/machine-api-operator/pkg/metrics"
+)
+
+// unknownReason is the reason of the denials which are not caused by a field error.
+const unknownReason = "Unknown"
+
+// fieldIndexRegexp matches the indexes and keys of a field path, which are left out of the reasons to keep them bounded.
+var fieldIndexRegexp = regexp.MustCompile(`\[[^]]*\]`)
+
+// instrumentedHandler records the decisions of an admission handler, and the time it took to make them.
+type instrumentedHandler struct {
+	name    string
+	handler admission.Handler
+}
+
+// NewInstrumentedHandler returns an admission handler recording the decisions of the handler in the webhook metrics,
+// labeled with the name of the webhook.
+func NewInstrumentedHandler(name string, handler admission.Handler) admission.Handler {
+	return &instrumentedHandler{name: name, handler: handler}
+}
+
+// InjectDecoder injects the decoder into the instrumented handler.
+func (h *instrumentedHandler) InjectDecoder(d *admission.Decoder) error {
+	_, err := admission.InjectDecoderInto(d, h.handler)
+	return err
+}
+
+// Handle handles HTTP requests for admission webhook servers.
+func (h *instrumentedHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	start := time.Now()
+	res := h.handler.Handle(ctx, req)
+	decision, reason := admissionDecision(res)
+	metrics.ObserveWebhookAdmission(h.name, decision, reason, time.Since(start))
+	return res
+}
+
+// admissionDecision returns the decision of an admission response and its reason. The reason of a denial
+// is the field of its first cause, the reason of an error is its HTTP code, and allowed requests have none.
+func admissionDecision(res admission.Response) (string, string) {
+	switch {
+	case res.Allowed && len(res.Warnings) > 0:
+		return metrics.WebhookDecisionWarned, ""
+	case res.Allowed:
+		return metrics.WebhookDecisionAllowed, ""
+	case res.Result != nil && res.Result.Code != 0 && res.Result.Code != http.StatusForbidden:
+		return metrics.WebhookDecisionErrored, strconv.Itoa(int(res.Result.Code))
+	case res.Result != nil && res.Result.Details != nil && len(res.Result.Details.Causes) > 0:
+		cause := res.Result.Details.Causes[0]
+		return metrics.WebhookDecisionDenied, fieldIndexRegexp.ReplaceAllString(cause.Field, "") + ": " + string(cause.Type)
+	default:
+		return metrics.WebhookDecisionDenied, unknownReason
+	}
+}
+
+// denied returns a response denying the request, with the field errors of the aggregate as its causes.
+func denied(err error, warnings []string) admission.Response {
+	res := admission.Denied(err.Error()).WithWarnings(warnings...)
+
+	errs := []error{err}
+	var agg utilerrors.Aggregate
+	if errors.As(err, &agg) {
+		errs = utilerrors.Flatten(agg).Errors()
+	}
+	var causes []metav1.StatusCause
+	for _, err := range errs {
+		var fieldErr *field.Error
+		if errors.As(err, &fieldErr) {
+			causes = append(causes, metav1.StatusCause{
+				Type:    metav1.CauseType(fieldErr.Type),
+				Message: fieldErr.ErrorBody(),
+				Field:   fieldErr.Field,
+			})
+		}
+	}
+	if len(causes) > 0 {
+		res.Result.Details = &metav1.StatusDetails{Causes: causes}
+	}
+	return res
+}
@@ -0,0 +1,127 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	dto "github.com/prometheus/client_model/go"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/machine-api-operator/pkg/metrics"
+)
+
+type fakeHandler struct {
+	decoder  *admission.Decoder
+	response admission.Response
+}
+
+func (h *fakeHandler) InjectDecoder(d *admission.Decoder) error {
+	h.decoder = d
+	return nil
+}
+
+func (h *fakeHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	return h.response
+}
+
+func TestAdmissionDecision(t *testing.T) {
+	testCases := []struct {
+		name             string
+		response         admission.Response
+		expectedDecision string
+		expectedReason   string
+	}{
+		{
+			name:             "allowed",
+			response:         admission.Allowed("Machine valid"),
+			expectedDecision: metrics.WebhookDecisionAllowed,
+		},
+		{
+			name:             "allowed with warnings",
+			response:         admission.Allowed("Machine valid").WithWarnings("providerSpec.subnet: deprecated"),
+			expectedDecision: metrics.WebhookDecisionWarned,
+		},
+		{
+			name:             "patched",
+			response:         admission.PatchResponseFromRaw([]byte(`{}`), []byte(`{"a":"b"}`)),
+			expectedDecision: metrics.WebhookDecisionAllowed,
+		},
+		{
+			name: "denied by field errors",
+			response: denied(utilerrors.NewAggregate([]error{
+				field.Required(field.NewPath("providerSpec", "blockDevices").Index(1).Child("ebs", "volumeSize"), "volumeSize is required"),
+				field.Invalid(field.NewPath("providerSpec", "instanceType"), "", "instanceType is required"),
+			}), []string{"providerSpec.subnet: deprecated"}),
+			expectedDecision: metrics.WebhookDecisionDenied,
+			expectedReason:   "providerSpec.blockDevices.ebs.volumeSize: FieldValueRequired",
+		},
+		{
+			name:             "denied by another error",
+			response:         denied(errors.New("machine is protected"), nil),
+			expectedDecision: metrics.WebhookDecisionDenied,
+			expectedReason:   unknownReason,
+		},
+		{
+			name:             "errored",
+			response:         admission.Errored(http.StatusBadRequest, errors.New("couldn't decode")),
+			expectedDecision: metrics.WebhookDecisionErrored,
+			expectedReason:   "400",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			decision, reason := admissionDecision(tc.response)
+			g.Expect(decision).To(Equal(tc.expectedDecision))
+			g.Expect(reason).To(Equal(tc.expectedReason))
+		})
+	}
+}
+
+func TestDeniedCauses(t *testing.T) {
+	g := NewWithT(t)
+
+	errs := utilerrors.NewAggregate([]error{
+		field.Required(field.NewPath("providerSpec", "instanceType"), "expected providerSpec.instanceType to be populated"),
+		errors.New("not a field error"),
+	})
+	res := denied(errs, []string{"warning"})
+	g.Expect(res.Allowed).To(BeFalse())
+	g.Expect(res.Warnings).To(ConsistOf("warning"))
+	g.Expect(res.Result.Code).To(BeEquivalentTo(http.StatusForbidden))
+	g.Expect(res.Result.Details.Causes).To(HaveLen(1))
+	g.Expect(res.Result.Details.Causes[0].Field).To(Equal("providerSpec.instanceType"))
+	g.Expect(res.Result.Details.Causes[0].Message).To(Equal("Required value: expected providerSpec.instanceType to be populated"))
+}
+
+func TestInstrumentedHandler(t *testing.T) {
+	g := NewWithT(t)
+
+	inner := &fakeHandler{response: denied(field.Forbidden(field.NewPath("spec", "providerSpec"), "forbidden"), nil)}
+	h := NewInstrumentedHandler("machine-validator", inner)
+
+	decoder, err := admission.NewDecoder(scheme.Scheme)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = admission.InjectDecoderInto(decoder, h)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(inner.decoder).To(Equal(decoder))
+
+	counter := metrics.WebhookAdmissionsTotal.WithLabelValues("machine-validator", metrics.WebhookDecisionDenied, "spec.providerSpec: FieldValueForbidden")
+	before := &dto.Metric{}
+	g.Expect(counter.Write(before)).To(Succeed())
+
+	res := h.Handle(context.Background(), admission.Request{})
+	g.Expect(res).To(Equal(inner.response))
+
+	after := &dto.Metric{}
+	g.Expect(counter.Write(after)).To(Succeed())
+	g.Expect(after.GetCounter().GetValue() - before.GetCounter().GetValue()).To(BeEquivalentTo(1))
+}
@@ -0,0 +1,24 @@
+package webhooks
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/openshift/machine-api-operator/pkg/util/nodeconfig"
+)
+
+// validateNodeConfigAnnotations validates the annotations setting the labels and taints of the Nodes,
+// found at the path.
+func validateNodeConfigAnnotations(objectAnnotations map[string]string, path *field.Path) []error {
+	var errs []error
+	if value, ok := objectAnnotations[nodeconfig.NodeLabelsAnnotation]; ok {
+		if _, err := nodeconfig.ParseLabels(value); err != nil {
+			errs = append(errs, field.Invalid(path.Key(nodeconfig.NodeLabelsAnnotation), value, err.Error()))
+		}
+	}
+	if value, ok := objectAnnotations[nodeconfig.NodeTaintsAnnotation]; ok {
+		if _, err := nodeconfig.ParseTaints(value); err != nil {
+			errs = append(errs, field.Invalid(path.Key(nodeconfig.NodeTaintsAnnotation), value, err.Error()))
+		}
+	}
+	return errs
+}
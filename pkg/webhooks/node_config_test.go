@@ -0,0 +1,50 @@
+package webhooks
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidateNodeConfigAnnotations(t *testing.T) {
+	testCases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedErrors []string
+	}{
+		{
+			name: "without node labels and taints",
+		},
+		{
+			name: "with valid node labels and taints",
+			annotations: map[string]string{
+				"machine.openshift.io/node-labels": "node-role.kubernetes.io/infra=,example.com/tier=gold",
+				"machine.openshift.io/node-taints": "node-role.kubernetes.io/infra=reserved:NoSchedule",
+			},
+		},
+		{
+			name: "with invalid node labels and taints",
+			annotations: map[string]string{
+				"machine.openshift.io/node-labels": "example.com/tier",
+				"machine.openshift.io/node-taints": "example.com/tier=gold:Schedule",
+			},
+			expectedErrors: []string{
+				`metadata.annotations[machine.openshift.io/node-labels]: Invalid value: "example.com/tier": invalid node label "example.com/tier": must be of the form key=value`,
+				`metadata.annotations[machine.openshift.io/node-taints]: Invalid value: "example.com/tier=gold:Schedule": invalid node taint "example.com/tier=gold:Schedule": effect must be one of NoSchedule, PreferNoSchedule or NoExecute`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			errs := validateNodeConfigAnnotations(tc.annotations, field.NewPath("metadata", "annotations"))
+			g.Expect(errs).To(HaveLen(len(tc.expectedErrors)))
+			for i, err := range errs {
+				g.Expect(err.Error()).To(Equal(tc.expectedErrors[i]))
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// selfSignedCertSecretName is the name of the Secret holding the self-signed CA and serving certificate,
+	// so that all the replicas of the webhook server share them.
+	selfSignedCertSecretName = "machine-api-operator-webhook-self-signed-cert"
+
+	selfSignedCACertKey   = "ca.crt"
+	selfSignedCAKeyKey    = "ca.key"
+	selfSignedCABundleKey = "ca-bundle.crt"
+
+	selfSignedCAValidity   = 365 * 24 * time.Hour
+	selfSignedCertValidity = 90 * 24 * time.Hour
+
+	// selfSignedCertCheckInterval is how often the certificates are checked for rotation and
+	// the CA bundle of the webhook configurations is checked.
+	selfSignedCertCheckInterval = 10 * time.Minute
+)
+
+// SelfSignedCertificates bootstraps and rotates a self-signed CA and the serving certificate of the webhook server,
+// and injects the CA bundle into the webhook configurations. It replaces the service-ca operator
+// when running outside of OpenShift.
+type SelfSignedCertificates struct {
+	certDir   string
+	client    client.Client
+	reader    client.Reader
+	namespace string
+	service   string
+
+	validatingWebhookConfigurations []string
+	mutatingWebhookConfigurations   []string
+
+	now func() time.Time
+}
+
+// NewSelfSignedCertificates returns the self-signed certificates of the machine-api webhook server,
+// written to certDir. The reader is used so that the certificates can be bootstrapped before the cache is started.
+func NewSelfSignedCertificates(certDir string, c client.Client, reader client.Reader) *SelfSignedCertificates {
+	return &SelfSignedCertificates{
+		certDir:                         certDir,
+		client:                          c,
+		reader:                          reader,
+		namespace:                       defaultWebhookServiceNamespace,
+		service:                         defaultWebhookServiceName,
+		validatingWebhookConfigurations: []string{machineWebhookConfigurationName},
+		mutatingWebhookConfigurations:   []string{machineWebhookConfigurationName},
+		now:                             time.Now,
+	}
+}
+
+// Start periodically rotates the certificates until the context is done. It implements manager.Runnable.
+func (s *SelfSignedCertificates) Start(ctx context.Context) error {
+	ticker := time.NewTicker(selfSignedCertCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.Ensure(ctx); err != nil {
+				klog.Errorf("Failed to ensure self-signed webhook certificates: %v", err)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false as every replica of the webhook server needs the certificates.
+func (s *SelfSignedCertificates) NeedLeaderElection() bool {
+	return false
+}
+
+// Ensure generates the certificates if they do not exist or are about to expire, writes them to the
+// cert dir and injects the CA bundle into the webhook configurations.
+func (s *SelfSignedCertificates) Ensure(ctx context.Context) error {
+	secret, err := s.ensureSecret(ctx)
+	if err != nil {
+		return fmt.Errorf("could not ensure secret %s/%s: %w", s.namespace, selfSignedCertSecretName, err)
+	}
+	if err := s.writeCertificates(secret); err != nil {
+		return fmt.Errorf("could not write certificates to %s: %w", s.certDir, err)
+	}
+	if err := s.injectCABundle(ctx, secret.Data[selfSignedCABundleKey]); err != nil {
+		return fmt.Errorf("could not inject CA bundle: %w", err)
+	}
+	return nil
+}
+
+// ensureSecret returns the Secret holding the certificates, rotating them when needed.
+// Concurrent rotations by other replicas are retried on the updated Secret.
+func (s *SelfSignedCertificates) ensureSecret(ctx context.Context) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	retriable := func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}
+	err := retry.OnError(retry.DefaultRetry, retriable, func() error {
+		secret = &corev1.Secret{}
+		err := s.reader.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: selfSignedCertSecretName}, secret)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		exists := err == nil
+
+		data, rotated, err := s.rotate(secret.Data)
+		if err != nil {
+			return err
+		}
+		if !rotated {
+			return nil
+		}
+
+		secret.Data = data
+		if exists {
+			klog.Infof("Rotating self-signed webhook certificates in secret %s/%s", s.namespace, selfSignedCertSecretName)
+			return s.client.Update(ctx, secret)
+		}
+		klog.Infof("Creating self-signed webhook certificates in secret %s/%s", s.namespace, selfSignedCertSecretName)
+		secret.ObjectMeta = metav1.ObjectMeta{Namespace: s.namespace, Name: selfSignedCertSecretName}
+		secret.Type = corev1.SecretTypeTLS
+		return s.client.Create(ctx, secret)
+	})
+	return secret, err
+}
+
+// rotate returns new certificates when the CA or the serving certificate are missing, invalid or about to expire.
+// The CA bundle keeps the previous CAs until they expire, so that clients trusting them are not disrupted.
+func (s *SelfSignedCertificates) rotate(data map[string][]byte) (map[string][]byte, bool, error) {
+	now := s.now()
+
+	ca, caKey, caErr := parseCertificate(data[selfSignedCACertKey], data[selfSignedCAKeyKey])
+	caValid := caErr == nil && ca.IsCA && !needsRotation(ca, now)
+	serving, _, servingErr := parseCertificate(data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey])
+	servingValid := servingErr == nil && caValid && !needsRotation(serving, now) &&
+		serving.CheckSignatureFrom(ca) == nil && serving.VerifyHostname(s.dnsNames()[0]) == nil
+	if caValid && servingValid && len(data[selfSignedCABundleKey]) > 0 {
+		return data, false, nil
+	}
+
+	var err error
+	if !caValid {
+		ca, caKey, err = newSelfSignedCA(fmt.Sprintf("%s-ca@%d", s.service, now.Unix()), now)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	servingPEM, servingKeyPEM, err := newServingCertificate(ca, caKey, s.dnsNames(), now)
+	if err != nil {
+		return nil, false, err
+	}
+	caPEM, caKeyPEM, err := encodeCertificate(ca, caKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	bundle := [][]byte{caPEM}
+	if previous, err := cert.ParseCertsPEM(data[selfSignedCABundleKey]); err == nil {
+		for _, c := range previous {
+			if now.Before(c.NotAfter) && !c.Equal(ca) {
+				bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: cert.CertificateBlockType, Bytes: c.Raw}))
+			}
+		}
+	}
+
+	return map[string][]byte{
+		selfSignedCACertKey:     caPEM,
+		selfSignedCAKeyKey:      caKeyPEM,
+		selfSignedCABundleKey:   bytes.Join(bundle, nil),
+		corev1.TLSCertKey:       servingPEM,
+		corev1.TLSPrivateKeyKey: servingKeyPEM,
+	}, true, nil
+}
+
+// writeCertificates writes the serving certificate to the cert dir, where it is reloaded by the webhook server.
+func (s *SelfSignedCertificates) writeCertificates(secret *corev1.Secret) error {
+	if err := os.MkdirAll(s.certDir, 0700); err != nil {
+		return err
+	}
+	for _, name := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		path := filepath.Join(s.certDir, name)
+		if existing, err := os.ReadFile(filepath.Clean(path)); err == nil && bytes.Equal(existing, secret.Data[name]) {
+			continue
+		}
+		// Write to a temporary file first so the webhook server never reads a partial certificate.
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, secret.Data[name], 0600); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// injectCABundle sets the CA bundle of the webhooks pointing at the webhook service.
+// Webhook configurations which do not exist yet are injected on the next check.
+func (s *SelfSignedCertificates) injectCABundle(ctx context.Context, caBundle []byte) error {
+	for _, name := range s.validatingWebhookConfigurations {
+		c := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		if err := s.reader.Get(ctx, client.ObjectKey{Name: name}, c); err != nil {
+			if apierrors.IsNotFound(err) {
+				klog.V(3).Infof("ValidatingWebhookConfiguration %s not found, skipping CA bundle injection", name)
+				continue
+			}
+			return err
+		}
+		updated := false
+		for i := range c.Webhooks {
+			updated = s.setCABundle(&c.Webhooks[i].ClientConfig, caBundle) || updated
+		}
+		if updated {
+			if err := s.client.Update(ctx, c); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, name := range s.mutatingWebhookConfigurations {
+		c := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		if err := s.reader.Get(ctx, client.ObjectKey{Name: name}, c); err != nil {
+			if apierrors.IsNotFound(err) {
+				klog.V(3).Infof("MutatingWebhookConfiguration %s not found, skipping CA bundle injection", name)
+				continue
+			}
+			return err
+		}
+		updated := false
+		for i := range c.Webhooks {
+			updated = s.setCABundle(&c.Webhooks[i].ClientConfig, caBundle) || updated
+		}
+		if updated {
+			if err := s.client.Update(ctx, c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setCABundle sets the CA bundle of the client config if it points at the webhook service, returning true if it changed.
+func (s *SelfSignedCertificates) setCABundle(clientConfig *admissionregistrationv1.WebhookClientConfig, caBundle []byte) bool {
+	if clientConfig.Service == nil || clientConfig.Service.Namespace != s.namespace || clientConfig.Service.Name != s.service {
+		return false
+	}
+	if bytes.Equal(clientConfig.CABundle, caBundle) {
+		return false
+	}
+	clientConfig.CABundle = caBundle
+	return true
+}
+
+func (s *SelfSignedCertificates) dnsNames() []string {
+	return []string{
+		fmt.Sprintf("%s.%s.svc", s.service, s.namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", s.service, s.namespace),
+	}
+}
+
+// needsRotation returns true once less than a third of the validity of the certificate is left.
+func needsRotation(c *x509.Certificate, now time.Time) bool {
+	validity := c.NotAfter.Sub(c.NotBefore)
+	return now.After(c.NotAfter.Add(-validity / 3))
+}
+
+func parseCertificate(certPEM, keyPEM []byte) (*x509.Certificate, crypto.Signer, error) {
+	certs, err := cert.ParseCertsPEM(certPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := keyutil.ParsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return certs[0], signer, nil
+}
+
+func encodeCertificate(c *x509.Certificate, key crypto.Signer) ([]byte, []byte, error) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: cert.CertificateBlockType, Bytes: c.Raw}),
+		pem.EncodeToMemory(&pem.Block{Type: keyutil.PrivateKeyBlockType, Bytes: keyDER}), nil
+}
+
+func newSelfSignedCA(commonName string, now time.Time) (*x509.Certificate, crypto.Signer, error) {
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	return newCertificate(template, nil, nil)
+}
+
+func newServingCertificate(ca *x509.Certificate, caKey crypto.Signer, dnsNames []string, now time.Time) ([]byte, []byte, error) {
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:              dnsNames,
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedCertValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	c, key, err := newCertificate(template, ca, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodeCertificate(c, key)
+}
+
+// newCertificate creates a certificate from the template signed by the parent, or self-signed if the parent is nil.
+func newCertificate(template, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+	if err != nil {
+		return nil, nil, err
+	}
+	template.SerialNumber = serial
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, key, nil
+}
@@ -0,0 +1,95 @@
+package webhooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/cert"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSelfSignedCertificates(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		NewMachineValidatingWebhookConfiguration(),
+		NewMachineMutatingWebhookConfiguration(),
+	).Build()
+	certDir := t.TempDir()
+	now := time.Now()
+	certs := NewSelfSignedCertificates(certDir, c, c)
+	certs.now = func() time.Time { return now }
+
+	ensure := func() *corev1.Secret {
+		g.Expect(certs.Ensure(ctx)).To(Succeed())
+
+		secret := &corev1.Secret{}
+		g.Expect(c.Get(ctx, client.ObjectKey{Namespace: defaultWebhookServiceNamespace, Name: selfSignedCertSecretName}, secret)).To(Succeed())
+
+		for _, name := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+			data, err := os.ReadFile(filepath.Join(certDir, name))
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(data).To(Equal(secret.Data[name]))
+		}
+
+		validating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		g.Expect(c.Get(ctx, client.ObjectKey{Name: machineWebhookConfigurationName}, validating)).To(Succeed())
+		for _, webhook := range validating.Webhooks {
+			g.Expect(webhook.ClientConfig.CABundle).To(Equal(secret.Data[selfSignedCABundleKey]))
+		}
+		mutating := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		g.Expect(c.Get(ctx, client.ObjectKey{Name: machineWebhookConfigurationName}, mutating)).To(Succeed())
+		for _, webhook := range mutating.Webhooks {
+			g.Expect(webhook.ClientConfig.CABundle).To(Equal(secret.Data[selfSignedCABundleKey]))
+		}
+		return secret
+	}
+
+	// The certificates are generated and the serving certificate is signed by the CA for the webhook service.
+	initial := ensure()
+	ca, _, err := parseCertificate(initial.Data[selfSignedCACertKey], initial.Data[selfSignedCAKeyKey])
+	g.Expect(err).ToNot(HaveOccurred())
+	serving, _, err := parseCertificate(initial.Data[corev1.TLSCertKey], initial.Data[corev1.TLSPrivateKeyKey])
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(serving.CheckSignatureFrom(ca)).To(Succeed())
+	g.Expect(serving.DNSNames).To(ConsistOf(
+		"machine-api-operator-webhook.openshift-machine-api.svc",
+		"machine-api-operator-webhook.openshift-machine-api.svc.cluster.local",
+	))
+
+	// Valid certificates are kept.
+	g.Expect(ensure().Data).To(Equal(initial.Data))
+
+	// The serving certificate is rotated before it expires, with the same CA.
+	now = now.Add(selfSignedCertValidity * 3 / 4)
+	rotated := ensure()
+	g.Expect(rotated.Data[corev1.TLSCertKey]).ToNot(Equal(initial.Data[corev1.TLSCertKey]))
+	g.Expect(rotated.Data[selfSignedCACertKey]).To(Equal(initial.Data[selfSignedCACertKey]))
+	g.Expect(rotated.Data[selfSignedCABundleKey]).To(Equal(initial.Data[selfSignedCABundleKey]))
+
+	// The CA is rotated before it expires, and the previous CA is kept in the bundle.
+	now = now.Add(selfSignedCAValidity * 3 / 4)
+	rotated = ensure()
+	g.Expect(rotated.Data[selfSignedCACertKey]).ToNot(Equal(initial.Data[selfSignedCACertKey]))
+	bundle, err := cert.ParseCertsPEM(rotated.Data[selfSignedCABundleKey])
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(bundle).To(HaveLen(2))
+	g.Expect(bundle[1].Equal(ca)).To(BeTrue())
+
+	// Expired CAs are dropped from the bundle.
+	now = now.Add(selfSignedCAValidity * 3 / 4)
+	rotated = ensure()
+	bundle, err = cert.ParseCertsPEM(rotated.Data[selfSignedCABundleKey])
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(bundle).To(HaveLen(2))
+	g.Expect(bundle[1].Equal(ca)).To(BeFalse())
+}
@@ -0,0 +1,118 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/machine-api-operator/pkg/controller/vsphere"
+)
+
+const (
+	defaultVSphereDeepValidationTimeout  = 5 * time.Second
+	defaultVSphereDeepValidationCacheTTL = 5 * time.Minute
+)
+
+// VSphereDeepValidationOptions configures the deep validation of vSphere providerSpecs, which resolves
+// the template, folder, datastore, resource pool and networks they reference in vCenter.
+type VSphereDeepValidationOptions struct {
+	// Timeout bounds the time spent resolving the objects of a providerSpec in vCenter during admission.
+	Timeout time.Duration
+	// CacheTTL is how long the objects resolved for a providerSpec are cached.
+	CacheTTL time.Duration
+}
+
+// vsphereObjectsResolver resolves the objects referenced by the providerSpec in vCenter, returning
+// the errors for the objects which do not exist, and an error when vCenter could not be queried.
+type vsphereObjectsResolver func(ctx context.Context, namespace string, spec *machinev1beta1.VSphereMachineProviderSpec) ([]error, error)
+
+type vsphereDeepValidationResult struct {
+	errs    []error
+	expires time.Time
+}
+
+// vsphereDeepValidator validates vSphere providerSpecs against vCenter, caching the results by the referenced objects.
+type vsphereDeepValidator struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+	resolve  vsphereObjectsResolver
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]vsphereDeepValidationResult
+}
+
+func newVSphereDeepValidator(o VSphereDeepValidationOptions, resolve vsphereObjectsResolver) *vsphereDeepValidator {
+	if o.Timeout <= 0 {
+		o.Timeout = defaultVSphereDeepValidationTimeout
+	}
+	if o.CacheTTL <= 0 {
+		o.CacheTTL = defaultVSphereDeepValidationCacheTTL
+	}
+	return &vsphereDeepValidator{
+		timeout:  o.Timeout,
+		cacheTTL: o.CacheTTL,
+		resolve:  resolve,
+		now:      time.Now,
+		cache:    map[string]vsphereDeepValidationResult{},
+	}
+}
+
+// EnableVSphereDeepValidation makes the validation of vSphere providerSpecs reject the ones referencing objects
+// which do not exist in vCenter. When vCenter can not be queried in time, a warning is returned instead.
+func (a *admissionHandler) EnableVSphereDeepValidation(o VSphereDeepValidationOptions) {
+	a.vsphereDeepValidator = newVSphereDeepValidator(o, func(ctx context.Context, namespace string, spec *machinev1beta1.VSphereMachineProviderSpec) ([]error, error) {
+		return vsphere.ValidateProviderSpecObjects(ctx, a.client, namespace, spec)
+	})
+}
+
+// validate returns the errors for the objects referenced by the providerSpec which do not exist in vCenter.
+func (v *vsphereDeepValidator) validate(namespace string, spec *machinev1beta1.VSphereMachineProviderSpec) ([]string, []error) {
+	key := vsphereDeepValidationKey(namespace, spec)
+	now := v.now()
+
+	v.mu.Lock()
+	result, ok := v.cache[key]
+	v.mu.Unlock()
+	if ok && now.Before(result.expires) {
+		return nil, result.errs
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), v.timeout)
+	defer cancel()
+	errs, err := v.resolve(ctx, namespace, spec)
+	if err != nil {
+		klog.Warningf("Failed to validate vSphere providerSpec against vCenter: %v", err)
+		return []string{fmt.Sprintf("providerSpec: could not be validated against vCenter: %v", err)}, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for k, r := range v.cache {
+		if !now.Before(r.expires) {
+			delete(v.cache, k)
+		}
+	}
+	v.cache[key] = vsphereDeepValidationResult{errs: errs, expires: now.Add(v.cacheTTL)}
+	return nil, errs
+}
+
+// vsphereDeepValidationKey identifies the vCenter objects referenced by the providerSpec.
+func vsphereDeepValidationKey(namespace string, spec *machinev1beta1.VSphereMachineProviderSpec) string {
+	parts := []string{namespace, spec.Template}
+	if spec.CredentialsSecret != nil {
+		parts = append(parts, spec.CredentialsSecret.Name)
+	}
+	if spec.Workspace != nil {
+		parts = append(parts, spec.Workspace.Server, spec.Workspace.Datacenter, spec.Workspace.Folder, spec.Workspace.Datastore, spec.Workspace.ResourcePool)
+	}
+	for _, device := range spec.Network.Devices {
+		parts = append(parts, device.NetworkName)
+	}
+	return strings.Join(parts, "\x00")
+}
@@ -0,0 +1,109 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestVSphereDeepValidator(t *testing.T) {
+	spec := &machinev1beta1.VSphereMachineProviderSpec{
+		Template: "template",
+		Workspace: &machinev1beta1.Workspace{
+			Server:     "vcenter",
+			Datacenter: "dc",
+			Datastore:  "datastore",
+		},
+	}
+	notFound := field.NotFound(field.NewPath("providerSpec", "template"), "template")
+
+	testCases := []struct {
+		testCase         string
+		resolveErrs      []error
+		resolveErr       error
+		expectedWarnings []string
+		expectedErrs     []error
+		expectCached     bool
+	}{
+		{
+			testCase:     "with all objects found",
+			expectCached: true,
+		},
+		{
+			testCase:     "with a missing object",
+			resolveErrs:  []error{notFound},
+			expectedErrs: []error{notFound},
+			expectCached: true,
+		},
+		{
+			testCase:         "with vCenter not answering in time",
+			resolveErr:       context.DeadlineExceeded,
+			expectedWarnings: []string{"providerSpec: could not be validated against vCenter: context deadline exceeded"},
+		},
+		{
+			testCase:         "with vCenter returning an error",
+			resolveErr:       errors.New("connection refused"),
+			expectedWarnings: []string{"providerSpec: could not be validated against vCenter: connection refused"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testCase, func(t *testing.T) {
+			g := NewWithT(t)
+
+			calls := 0
+			v := newVSphereDeepValidator(VSphereDeepValidationOptions{Timeout: time.Second, CacheTTL: time.Minute},
+				func(ctx context.Context, _ string, _ *machinev1beta1.VSphereMachineProviderSpec) ([]error, error) {
+					calls++
+					_, hasDeadline := ctx.Deadline()
+					g.Expect(hasDeadline).To(BeTrue())
+					return tc.resolveErrs, tc.resolveErr
+				})
+			now := time.Now()
+			v.now = func() time.Time { return now }
+
+			warnings, errs := v.validate("default", spec)
+			g.Expect(warnings).To(Equal(tc.expectedWarnings))
+			g.Expect(errs).To(Equal(tc.expectedErrs))
+
+			warnings, errs = v.validate("default", spec)
+			g.Expect(warnings).To(Equal(tc.expectedWarnings))
+			g.Expect(errs).To(Equal(tc.expectedErrs))
+			if tc.expectCached {
+				g.Expect(calls).To(Equal(1))
+			} else {
+				g.Expect(calls).To(Equal(2))
+			}
+
+			// Other namespaces and expired entries are resolved again.
+			v.validate("other", spec)
+			now = now.Add(time.Minute)
+			v.validate("default", spec)
+			if tc.expectCached {
+				g.Expect(calls).To(Equal(3))
+			} else {
+				g.Expect(calls).To(Equal(4))
+			}
+		})
+	}
+}
+
+func TestVSphereDeepValidationOnProviderSpecUpdate(t *testing.T) {
+	g := NewWithT(t)
+
+	config := &admissionConfig{vsphereDeepValidator: newVSphereDeepValidator(VSphereDeepValidationOptions{}, nil)}
+	providerSpec := &machinev1beta1.ProviderSpec{Value: &kruntime.RawExtension{Raw: []byte(`{"template": "template"}`)}}
+	changed := &machinev1beta1.ProviderSpec{Value: &kruntime.RawExtension{Raw: []byte(`{"template": "other"}`)}}
+
+	// The providerSpecs are validated against vCenter on creation, and when they change.
+	g.Expect(config.forProviderSpecUpdate(providerSpec, nil).vsphereDeepValidator).ToNot(BeNil())
+	g.Expect(config.forProviderSpecUpdate(providerSpec, changed).vsphereDeepValidator).ToNot(BeNil())
+	g.Expect(config.forProviderSpecUpdate(providerSpec, providerSpec.DeepCopy()).vsphereDeepValidator).To(BeNil())
+	g.Expect(config.vsphereDeepValidator).ToNot(BeNil())
+}